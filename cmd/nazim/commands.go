@@ -0,0 +1,80 @@
+package main
+
+// commands lists the commands in the order usage shows them.
+var commands = []string{
+	"add", "pipeline", "list", "status", "info", "which", "edit", "remove", "enable", "disable", "run", "start", "stop",
+	"reset-failures", "logs", "events", "analyze", "stats", "doctor", "check", "test", "exec", "backup", "restore", "rollback", "apply", "generate",
+	"export", "export-unit", "import", "import-cron", "import-tasks", "adopt", "sync-config", "purge", "history", "config", "completion", "version", "help",
+}
+
+// serviceFlags are the flags describing a service, taken by add, edit, and test.
+var serviceFlags = []string{
+	"name", "command", "args", "arg", "workdir", "on-startup", "on-logon", "interval", "offset", "schedule", "tz",
+	"if-changed", "blackout", "watch-path", "on-event", "long-running", "system", "from-unit", "steps", "on-step-failure", "parallel-group", "max-parallel", "startup-delay", "priority", "catch-up",
+	"no-verify", "portable", "elevated", "umask", "sandbox", "network", "target", "notify-desktop", "debug-env", "singleton-lock",
+	"slow-threshold", "daily-budget", "timeout", "shell", "max-log-lines-per-run", "log-timestamps", "log-level", "no-log", "on-failure", "fail-pattern",
+	"success-pattern", "max-consecutive-failures", "description", "owner", "label",
+}
+
+// globalFlags are taken by every command.
+var globalFlags = []string{"config-dir", "data-dir", "state-dir", "lang", "verbose", "quiet", "no-color", "yes", "strict", "no-update-check", "help"}
+
+// commandFlags lists the flags each command takes besides the global ones.
+var commandFlags = map[string][]string{
+	"add":          serviceFlags,
+	"pipeline":     serviceFlags,
+	"edit":         append([]string{"clear-args", "clear-workdir", "clear-interval", "no-startup", "no-debug-env", "no-system", "no-elevated", "no-notify-desktop", "no-catch-up", "force-platform"}, serviceFlags...),
+	"test":         serviceFlags,
+	"list":         {"wide", "tree", "group-by", "grace", "check"},
+	"status":       {"output"},
+	"info":         {"output"},
+	"logs":         {"stream", "tail", "since", "all"},
+	"events":       {"follow", "output", "tail"},
+	"analyze":      {"min-cluster"},
+	"stats":        {"output", "grace"},
+	"doctor":       {"accept", "grace", "notify", "enable-linger"},
+	"check":        {"format", "grace"},
+	"exec":         {"catch-up", "repeat", "poll"},
+	"backup":       {"to"},
+	"run":          {"force"},
+	"start":        {"force"},
+	"restore":      {"force", "force-platform"},
+	"rollback":     {"to", "pin", "list"},
+	"apply":        {"stagger"},
+	"generate":     {"template", "matrix", "no-verify"},
+	"export":       {"to", "portable"},
+	"export-unit":  {"to"},
+	"import":       {"select", "stagger"},
+	"import-cron":  {"user", "file", "select", "stagger"},
+	"import-tasks": {"file", "folder", "select", "take-over", "stagger"},
+	"adopt":        {"select"},
+	"sync-config":  {"repo", "machine-branch", "prefer"},
+	"purge":        {"keep-config"},
+	"history":      {"service", "older-than", "format", "since", "until", "to"},
+	"config":       {"passphrase-from", "keychain"},
+	"version":      {"check"},
+}
+
+// nameCommands take the name of a configured service as their argument.
+var nameCommands = map[string]bool{
+	"status": true, "info": true, "which": true, "edit": true, "remove": true, "enable": true,
+	"disable": true, "run": true, "start": true, "stop": true, "reset-failures": true, "logs": true, "events": true, "test": true, "exec": true, "doctor": true, "check": true, "export-unit": true, "rollback": true,
+}
+
+// shortFlags maps the one-letter flags to the long ones they stand for.
+var shortFlags = map[string]string{
+	"n": "name", "c": "command", "a": "args", "w": "workdir", "i": "interval",
+	"v": "verbose", "q": "quiet", "y": "yes", "h": "help",
+}
+
+// flagsOf returns the long names of the flags command takes, including the global ones.
+func flagsOf(command string) []string {
+	names := append([]string{}, commandFlags[command]...)
+	return append(names, globalFlags...)
+}
+
+// takesArguments reports whether command takes arguments after its flags.
+func takesArguments(command string) bool {
+	return nameCommands[command] || command == "restore" || command == "apply" || command == "history" || command == "config" || command == "pipeline" ||
+		command == "export" || command == "import"
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/gitsync"
+	"github.com/calilkhalil/nazim/internal/i18n"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// The completion scripts hand the words typed so far to `nazim __complete`,
+// which prints the candidates for the last one, one per line.
+const (
+	bashCompletion = `# nazim bash completion
+_nazim() {
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur="${COMP_WORDS[COMP_CWORD]}" words=("${COMP_WORDS[@]}") cword=$COMP_CWORD
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($(command nazim __complete "${words[@]:1:cword}" 2>/dev/null))
+    if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+        compopt -o nospace
+    fi
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+complete -F _nazim nazim
+`
+
+	zshCompletion = `#compdef nazim
+_nazim() {
+    local -a candidates dirs
+    candidates=("${(@f)$(command nazim __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    dirs=(${(M)candidates:#*/})
+    candidates=(${candidates:#*/})
+    (( ${#dirs} )) && compadd -U -S '' -- "${dirs[@]}"
+    (( ${#candidates} )) && compadd -U -- "${candidates[@]}"
+}
+compdef _nazim nazim
+`
+
+	fishCompletion = `# nazim fish completion
+function __nazim_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    command nazim __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c nazim -f -a '(__nazim_complete)'
+`
+)
+
+func handleCompletion(cmdArgs []string, stdout, stderr io.Writer) int {
+	if len(cmdArgs) != 1 {
+		fmt.Fprintf(stderr, "nazim: usage: nazim completion bash|zsh|fish\n")
+		return exitError
+	}
+	switch cmdArgs[0] {
+	case "bash":
+		fmt.Fprint(stdout, bashCompletion)
+	case "zsh":
+		fmt.Fprint(stdout, zshCompletion)
+	case "fish":
+		fmt.Fprint(stdout, fishCompletion)
+	default:
+		fmt.Fprintf(stderr, "nazim: unsupported shell %q, use bash, zsh, or fish\n", cmdArgs[0])
+		return exitError
+	}
+	return exitOK
+}
+
+// handleComplete prints the completions of the last of words, the arguments
+// typed so far. Errors are not reported, since they would end up on the prompt.
+func handleComplete(words []string, stdout io.Writer) int {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	for _, candidate := range complete(words, completionServices(words)) {
+		fmt.Fprintln(stdout, candidate)
+	}
+	return exitOK
+}
+
+// completionServices returns the services of the config the words point to
+// with --config-dir, or of the default one.
+func completionServices(words []string) []*service.Service {
+	var cfg *config.Config
+	var err error
+	if dir := flagValue(words, "config-dir"); dir != "" {
+		cfg, err = config.NewAt(dir)
+	} else {
+		cfg, err = config.New()
+	}
+	if err != nil {
+		return nil
+	}
+	services := cfg.ListServices()
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+// flagValue returns the value given to a string flag among words.
+func flagValue(words []string, name string) string {
+	for i, word := range words {
+		switch {
+		case word == "--"+name && i+1 < len(words):
+			return words[i+1]
+		case strings.HasPrefix(word, "--"+name+"="):
+			return strings.TrimPrefix(word, "--"+name+"=")
+		}
+	}
+	return ""
+}
+
+// complete returns the candidates for the last of words, which may be empty.
+func complete(words []string, services []*service.Service) []string {
+	cur := words[len(words)-1]
+	if len(words) == 1 {
+		if strings.HasPrefix(cur, "-") {
+			return matching(cur, longFlags(globalFlags))
+		}
+		return matching(cur, commands)
+	}
+	command := words[0]
+	fs := newFlagSet(&Flags{})
+
+	if strings.HasPrefix(cur, "-") {
+		// --flag=value completes the value
+		if name, value, ok := strings.Cut(strings.TrimLeft(cur, "-"), "="); ok {
+			prefix := cur[:len(cur)-len(value)]
+			var candidates []string
+			for _, candidate := range flagValues(command, flagName(name), value, services) {
+				candidates = append(candidates, prefix+candidate)
+			}
+			return candidates
+		}
+		return matching(cur, longFlags(flagsOf(command)))
+	}
+
+	if len(words) > 2 {
+		if prev := words[len(words)-2]; strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") {
+			name := flagName(strings.TrimLeft(prev, "-"))
+			if f := fs.Lookup(name); f != nil && !isBoolFlag(f) {
+				return flagValues(command, name, cur, services)
+			}
+		}
+	}
+
+	switch {
+	case command == "history" && positionals(words[1:len(words)-1], fs) == 0:
+		return matching(cur, []string{"prune", "export"})
+	case command == "config" && positionals(words[1:len(words)-1], fs) == 0:
+		return matching(cur, []string{"encrypt", "decrypt"})
+	case command == "pipeline" && positionals(words[1:len(words)-1], fs) == 0:
+		return matching(cur, []string{"add"})
+	case command == "completion" && positionals(words[1:len(words)-1], fs) == 0:
+		return matching(cur, []string{"bash", "zsh", "fish"})
+	case command == "restore" || command == "apply" || command == "import":
+		return pathCandidates(cur, false)
+	case nameCommands[command] || command == "export":
+		return matching(cur, serviceNames(services))
+	}
+	return nil
+}
+
+// flagValues returns the values suggested for a flag of command, starting with cur.
+func flagValues(command, name, cur string, services []*service.Service) []string {
+	var values []string
+	switch name {
+	case "interval":
+		values = []string{"30s", "1m", "5m", "15m", "30m", "1h", "6h", "12h", "1d"}
+	case "offset":
+		values = []string{"0m", "5m", "15m", "30m"}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "stagger":
+		values = []string{"auto", "1m", "5m", "10m"}
+	case "startup-delay":
+		values = []string{"30s", "1m", "2m", "5m"}
+	case "slow-threshold":
+		values = []string{"1m", "5m", "10m", "30m", "1h"}
+	case "daily-budget":
+		values = []string{"15m", "30m", "1h", "2h"}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "timeout":
+		values = []string{"5m", "10m", "30m", "1h"}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "since":
+		values = []string{"30m", "1h", "8h", "1d", "7d"}
+	case "older-than":
+		values = []string{"7d", "30d", "90d"}
+	case "schedule":
+		values = []string{"daily", "weekdays", "weekends", "every"}
+	case "blackout":
+		values = []string{"sat,sun", "mon..fri", "22:00..06:00"}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "tz":
+		values = []string{"UTC", "Europe/London", "Europe/Lisbon", "America/New_York", "America/Sao_Paulo", "Asia/Tokyo"}
+		if command == "edit" {
+			values = append(values, "local")
+		}
+	case "priority":
+		values = []string{"high", "normal", "low"}
+	case "shell":
+		values = []string{service.ShellPwsh, "sh", "bash", "zsh"}
+		if runtime.GOOS == "windows" {
+			values = []string{service.ShellCmd, service.ShellPowerShell, service.ShellPwsh}
+		}
+	case "stream":
+		values = []string{"stdout", "stderr"}
+	case "output":
+		values = []string{"text", "json"}
+	case "format":
+		values = []string{"csv", "json"}
+		if command == "check" {
+			values = []string{"nagios", "json"}
+		}
+	case "lang":
+		values = i18n.Languages
+	case "select":
+		values = []string{"all"}
+	case "tail":
+		values = []string{"20", "50", "100"}
+	case "group-by":
+		values = []string{"type", "namespace", "label"}
+		for _, key := range labelKeys(services) {
+			values = append(values, "label:"+key)
+		}
+	case "min-cluster":
+		values = []string{"2", "3", "5"}
+	case "grace":
+		values = []string{"1.5", "2", "3"}
+	case "prefer":
+		values = []string{gitsync.PreferLocal, gitsync.PreferRemote}
+	case "max-consecutive-failures":
+		values = []string{"3", "5"}
+		if command == "edit" {
+			values = append(values, "0")
+		}
+	case "max-log-lines-per-run":
+		values = []string{"1000", "5000"}
+		if command == "edit" {
+			values = append(values, "0")
+		}
+	case "log-timestamps":
+		values = append([]string(nil), service.LogTimestampFormats...)
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "log-level":
+		values = service.LogLevels
+	case "umask":
+		values = []string{"022", "027", "077"}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "sandbox":
+		values = append([]string(nil), service.SandboxLevels...)
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "network":
+		values = []string{service.NetworkNone}
+		if command == "edit" {
+			values = append(values, "default")
+		}
+	case "passphrase-from":
+		values = []string{"env:", "file:"}
+	case "target":
+		values = []string{service.TargetWindowsHost}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "on-event":
+		values = []string{"Log=System;Source=Service Control Manager;ID=7036", "Log=Application;Source=", "Log=System;ID="}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "fail-pattern", "success-pattern":
+		if command == "edit" {
+			values = []string{"none"}
+		}
+	case "on-failure":
+		values = []string{"disable-self-after:3"}
+		for _, name := range serviceNames(services) {
+			values = append(values, "run:"+name)
+		}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "steps", "parallel-group":
+		// Each service is completed in turn, after the ones already given;
+		// pipelines and groups can't be nested
+		given := cur[:strings.LastIndex(cur, ",")+1]
+		for _, svc := range services {
+			if !svc.RunsServices() {
+				values = append(values, given+svc.Name)
+			}
+		}
+	case "max-parallel":
+		values = []string{"2", "4", "8"}
+		if command == "edit" {
+			values = append(values, "0")
+		}
+	case "on-step-failure":
+		values = []string{service.StepFailureAbort, service.StepFailureContinue}
+		if command == "edit" {
+			values = append(values, "none")
+		}
+	case "template":
+		// Only templates that added services are known from them
+		seen := make(map[string]bool)
+		for _, svc := range services {
+			if svc.Template != "" && !seen[svc.Template] {
+				seen[svc.Template] = true
+				values = append(values, svc.Template)
+			}
+		}
+	case "label":
+		values = labelValues(services)
+	case "owner":
+		seen := make(map[string]bool)
+		for _, svc := range services {
+			if svc.Owner != "" && !seen[svc.Owner] {
+				seen[svc.Owner] = true
+				values = append(values, svc.Owner)
+			}
+		}
+	case "name":
+		if command != "add" && command != "pipeline" {
+			values = serviceNames(services)
+		}
+	case "service":
+		values = serviceNames(services)
+	case "workdir", "config-dir", "data-dir", "state-dir":
+		return pathCandidates(cur, true)
+	case "to":
+		if command == "rollback" {
+			return nil // A version, such as v2
+		}
+		return pathCandidates(cur, command == "export-unit")
+	case "file", "if-changed", "watch-path":
+		return pathCandidates(cur, false)
+	case "from-unit":
+		if command == "edit" {
+			values = []string{"none"}
+		}
+		return append(matching(cur, values), pathCandidates(cur, false)...)
+	case "command":
+		if command == "add" {
+			values = []string{"write", "edit"}
+		}
+		return append(matching(cur, values), pathCandidates(cur, false)...)
+	}
+	return matching(cur, values)
+}
+
+// labelValues returns the labels set on services, as key=value pairs.
+func labelValues(services []*service.Service) []string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, svc := range services {
+		for _, pair := range svc.LabelPairs() {
+			if !seen[pair] {
+				seen[pair] = true
+				labels = append(labels, pair)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// labelKeys returns the label keys used by services.
+func labelKeys(services []*service.Service) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, svc := range services {
+		for key := range svc.Labels {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// serviceNames returns the names of services.
+func serviceNames(services []*service.Service) []string {
+	names := make([]string, len(services))
+	for i, svc := range services {
+		names[i] = svc.Name
+	}
+	return names
+}
+
+// pathCandidates returns the files and directories whose path starts with cur,
+// directories with a trailing separator so completion can continue into them.
+func pathCandidates(cur string, dirsOnly bool) []string {
+	dir, base := filepath.Split(cur)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		switch {
+		case isDir:
+			candidates = append(candidates, dir+name+string(filepath.Separator))
+		case !dirsOnly:
+			candidates = append(candidates, dir+name)
+		}
+	}
+	return candidates
+}
+
+// positionals counts the arguments among words that aren't flags or their values.
+func positionals(words []string, fs *flag.FlagSet) int {
+	n := 0
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if !strings.HasPrefix(word, "-") {
+			n++
+			continue
+		}
+		if strings.Contains(word, "=") {
+			continue
+		}
+		if f := fs.Lookup(flagName(strings.TrimLeft(word, "-"))); f != nil && !isBoolFlag(f) {
+			i++ // Skip the value
+		}
+	}
+	return n
+}
+
+// flagName returns the long name of a flag given by either name.
+func flagName(name string) string {
+	if long, ok := shortFlags[name]; ok {
+		return long
+	}
+	return name
+}
+
+// longFlags returns names as --flags.
+func longFlags(names []string) []string {
+	flags := make([]string, len(names))
+	for i, name := range names {
+		flags[i] = "--" + name
+	}
+	return flags
+}
+
+// isBoolFlag reports whether f takes no value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// matching returns the candidates starting with prefix.
+func matching(prefix string, candidates []string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
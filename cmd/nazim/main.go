@@ -10,20 +10,59 @@
 // Commands:
 //
 //	add       add a new service
+//	pipeline add add a service that runs other services one after another
 //	list      list all services
 //	remove    remove a service
 //	enable    enable a service
 //	disable   disable a service
 //	run       run a service immediately
+//	start     same as run
+//	stop      stop a running service
+//	reset-failures clear the failures that paused a service
+//	logs      show captured output of a service
+//	events    show or follow the log of service changes and runs
+//	analyze   show when services start together over the next 24 hours
+//	stats     summarize runs, failures, slow services, and stale services
+//	doctor    check services' files for changes made outside nazim
+//	check     report a service's health as a Nagios plugin would
+//	version   show the version, or look up the latest release with --check
+//	test      run a service definition once without installing it
+//	backup    archive config, scripts, and run history
+//	restore   restore a backup and reinstall its services
+//	apply     install the services of a signed bundle
+//	generate  add a service for every value of a matrix from a template
+//	export    write services to a file another machine can import
+//	import    add the services of an export, translated for this OS
+//	sync-config sync the config and scripts with a Git repository
+//	import-cron convert crontab entries into services
+//	import-tasks convert Windows scheduled tasks into services
+//	adopt     add installed nazim services missing from the config back to it
+//	purge     uninstall every service and delete everything nazim keeps
+//	history prune remove old run history
+//	history export export run history as CSV or JSON
+//	config encrypt encrypt the config file at rest
+//	config decrypt write the config file back in plain text
+//	completion print a shell completion script
 //
 // Flags:
 //
 //	-h, --help    show help
 //	-v, --verbose enable verbose output
+//	-q, --quiet   suppress informational output
+//	--no-color    disable colored output
+//	-y, --yes     answer prompts without asking
+//	--strict      treat warnings as errors
+//	--lang        language of messages, en or pt-BR
+//	--no-update-check never look up the latest release
 //
 // Environment:
 //
+//	LANG           language of messages, as with --lang
 //	NAZIM_VERBOSE  set to "1" for verbose output
+//	NAZIM_TRACE    set to "1" to write external commands and their output to trace.log
+//	NAZIM_UPLOAD_TOKEN bearer token for uploading run output to an HTTP endpoint
+//	NAZIM_NO_UPDATE_CHECK set to "1" to never look up the latest release
+//	NO_COLOR       set to disable colored output
 //	XDG_CONFIG_HOME config directory base (default: ~/.config on Linux/macOS, %APPDATA% on Windows)
 //
 // Examples:
@@ -41,11 +80,15 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 
 	"github.com/calilkhalil/nazim/internal/cli"
 	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/i18n"
+	"github.com/calilkhalil/nazim/internal/trace"
 )
 
 // Version information (set by build flags)
@@ -63,21 +106,170 @@ const (
 // Flags holds parsed command-line flags.
 type Flags struct {
 	Verbose   bool
+	Quiet     bool
+	NoColor   bool
+	Yes       bool
+	Strict    bool
 	Help      bool
 	Name      string
 	Command   string
 	Args      string
+	Arg       stringList // Arguments given one at a time, see argList
+	RawArgs   []string   // Arguments after --, see parseFlags
 	WorkDir   string
 	OnStartup bool
 	OnLogon   bool
 	Interval  string
+	Offset    string
+	Schedule  string
+	Timezone  string
+	IfChanged stringList
+	Blackout  stringList
+
+	StartupDelay string
+	Priority     string
+	CatchUp      bool
+	NoVerify     bool
+	Portable     bool
+	Elevated     bool
+	Umask        string
+	Sandbox      string
+	Network      string
+	Target       string
+
+	WatchPaths stringList
+	OnEvent    string
+
+	LongRunning bool
+	System      bool
+
+	FromUnit stringList
+
+	Steps         string
+	OnStepFailure string
+	ParallelGroup string
+	MaxParallel   string
+
+	NotifyDesktop bool
+	DebugEnv      bool
+	SingletonLock string
+	SlowThreshold string
+	DailyBudget   string
+	Timeout       string
+	MaxLogLines   string
+	LogTimestamps string
+	LogLevel      string
+	NoLog         bool
+	OnFailure     string
+	Shell         string
+
+	FailPattern    string
+	SuccessPattern string
+
+	MaxConsecutiveFailures string
+
+	Description string
+	Owner       string
+	Labels      stringList
+
+	ClearArgs       bool
+	ClearWorkDir    bool
+	ClearInterval   bool
+	NoStartup       bool
+	NoDebugEnv      bool
+	NoSystem        bool
+	NoElevated      bool
+	NoNotifyDesktop bool
+	NoCatchUp       bool
+	ForcePlatform   bool
+
+	ConfigDir string
+	DataDir   string
+	StateDir  string
+	Lang      string
+	Stream    string
+	Tail      int
+	Since     string
+	All       bool
+	Wide      bool
+	Tree      bool
+	GroupBy   string
+	Output    string
+	Follow    bool
+
+	MinCluster int
+	Accept     bool
+	Grace      string
+	Notify     bool
+	Check      bool
+
+	EnableLinger bool
+
+	To         string
+	Force      bool
+	KeepConfig bool
+	Pin        bool
+	List       bool
+
+	Repo          string
+	MachineBranch bool
+	Prefer        string
+
+	File     string
+	User     bool
+	Select   string
+	Folder   string
+	TakeOver bool
+	Stagger  string
+
+	Template string
+	Matrix   stringList
+
+	Service   string
+	OlderThan string
+	Format    string
+	Until     string
+
+	Keychain       bool
+	PassphraseFrom string
+
+	Repeat bool
+	Poll   bool
+
+	NoUpdateCheck bool
+
+	given []string // Names of the flags given, see parseFlags
+}
+
+// stringList collects the values of a flag given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// argList returns the arguments given with --arg followed by those after --,
+// nil if there are none.
+func (f *Flags) argList() []string {
+	if len(f.Arg) == 0 && len(f.RawArgs) == 0 {
+		return nil
+	}
+	return append(append([]string{}, f.Arg...), f.RawArgs...)
 }
 
 func main() {
-	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
-func run(args []string, stdout, stderr io.Writer) int {
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	// Detect only returns supported languages
+	_ = i18n.SetLanguage(i18n.Detect())
+
 	if len(args) == 0 {
 		printUsage(stderr)
 		return exitError
@@ -86,10 +278,30 @@ func run(args []string, stdout, stderr io.Writer) int {
 	command := args[0]
 	remainingArgs := args[1:]
 
-	// Handle version flag
+	// Handle version flag; version --check looks up the latest release once
+	// the config is loaded
 	if command == "--version" || command == "version" {
 		fmt.Fprintf(stdout, "nazim version %s (commit: %s, built: %s)\n", version, commit, buildDate)
-		return exitOK
+		if command == "--version" || len(remainingArgs) == 0 {
+			return exitOK
+		}
+	}
+
+	// Completion prints before anything else is set up; __complete is what the
+	// completion scripts call
+	switch command {
+	case "completion":
+		return handleCompletion(remainingArgs, stdout, stderr)
+	case "__complete":
+		return handleComplete(remainingArgs, stdout)
+	}
+
+	// --lang applies to the usage too, which is shown before flags are parsed
+	if lang := flagValue(remainingArgs, "lang"); lang != "" {
+		if err := i18n.SetLanguage(lang); err != nil {
+			fmt.Fprintf(stderr, "nazim: %v\n", err)
+			return exitError
+		}
 	}
 
 	// Handle help flag
@@ -102,23 +314,76 @@ func run(args []string, stdout, stderr io.Writer) int {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	remainingArgs = preprocessArgs(remainingArgs, command)
-	flags, cmdArgs, err := parseFlags(remainingArgs)
+	if !slices.Contains(commands, command) {
+		fmt.Fprintf(stderr, "nazim: unknown command: %s\n", command)
+		printUsage(stderr)
+		return exitError
+	}
+
+	flags, cmdArgs, err := parseFlags(command, remainingArgs)
 	if err != nil {
 		fmt.Fprintf(stderr, "nazim: %v\n", err)
 		return exitError
 	}
+	if flags.Help {
+		printUsage(stdout)
+		return exitOK
+	}
+	if len(cmdArgs) > 0 && !takesArguments(command) {
+		fmt.Fprintf(stderr, "nazim: %s takes no arguments, got %q; quote values that contain spaces\n",
+			command, strings.Join(cmdArgs, " "))
+		return exitError
+	}
 
 	// Handle verbose from env if not set via flag
 	verbose := flags.Verbose || os.Getenv("NAZIM_VERBOSE") == "1"
 
-	cfg, err := config.New()
+	dirs := config.DefaultDirs()
+	if flags.ConfigDir != "" {
+		dirs = config.DirsAt(flags.ConfigDir)
+	}
+	if flags.DataDir != "" {
+		dirs.Data = flags.DataDir
+	}
+	if flags.StateDir != "" {
+		dirs.State = flags.StateDir
+	}
+	cfg, err := config.NewIn(dirs)
 	if err != nil {
 		fmt.Fprintf(stderr, "nazim: %v\n", err)
 		return exitError
 	}
 
-	cliHandler := cli.New(cfg)
+	// Scheduler commands are listed in verbose mode, and traced with their output on request
+	if verbose {
+		trace.SetOutput(stderr)
+	}
+	if os.Getenv("NAZIM_TRACE") == "1" {
+		closeTrace, err := trace.OpenFile(filepath.Join(cfg.GetLogsDir(), "trace.log"))
+		if err != nil {
+			fmt.Fprintf(stderr, "nazim: %v\n", err)
+			return exitError
+		}
+		defer closeTrace()
+	}
+
+	cliHandler := cli.New(cfg,
+		cli.WithInput(stdin),
+		cli.WithOutput(stdout, stderr),
+		cli.WithQuiet(flags.Quiet),
+		cli.WithNoColor(flags.NoColor),
+		cli.WithYes(flags.Yes),
+		cli.WithStrict(flags.Strict),
+		cli.WithVersion(version),
+		cli.WithUpdateCheck(!flags.NoUpdateCheck && os.Getenv("NAZIM_NO_UPDATE_CHECK") != "1"))
+
+	// Scheduled runs have no one to read the warnings
+	if command != "exec" {
+		if err := cliHandler.WarnDeprecated(flags.given); err != nil {
+			fmt.Fprintf(stderr, "nazim: %v\n", err)
+			return exitError
+		}
+	}
 
 	return handleCommand(ctx, command, flags, cmdArgs, cliHandler, verbose, stderr)
 }
@@ -128,19 +393,75 @@ func handleCommand(ctx context.Context, command string, flags *Flags, cmdArgs []
 	case "add":
 		return handleAdd(ctx, flags, cliHandler, verbose, stderr)
 	case "list":
-		return handleList(ctx, cliHandler, verbose, stderr)
+		return handleList(ctx, flags, cliHandler, verbose, stderr)
 	case "remove":
 		return handleRemove(ctx, cmdArgs, cliHandler, verbose, stderr)
 	case "enable":
 		return handleEnable(ctx, cmdArgs, cliHandler, verbose, stderr)
 	case "disable":
 		return handleDisable(ctx, cmdArgs, cliHandler, verbose, stderr)
-	case "run":
-		return handleRun(ctx, cmdArgs, cliHandler, verbose, stderr)
+	case "run", "start":
+		return handleRun(ctx, command, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "stop":
+		return handleStop(ctx, cmdArgs, cliHandler, verbose, stderr)
+	case "reset-failures":
+		return handleResetFailures(ctx, cmdArgs, cliHandler, verbose, stderr)
 	case "status", "info":
-		return handleStatus(ctx, command, cmdArgs, cliHandler, verbose, stderr)
+		return handleStatus(ctx, command, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "which":
+		return handleWhich(ctx, cmdArgs, cliHandler, verbose, stderr)
+	case "analyze":
+		return handleAnalyze(ctx, flags, cliHandler, verbose, stderr)
+	case "stats":
+		return handleStats(ctx, flags, cliHandler, verbose, stderr)
+	case "doctor":
+		return handleDoctor(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "check":
+		return handleCheck(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
 	case "edit":
 		return handleEdit(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "logs":
+		return handleLogs(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "events":
+		return handleEvents(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "test":
+		return handleTest(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "exec":
+		return handleExec(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "backup":
+		return handleBackup(ctx, flags, cliHandler, verbose, stderr)
+	case "restore":
+		return handleRestore(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "apply":
+		return handleApply(ctx, flags, cmdArgs, cliHandler, verbose, stderr)
+	case "generate":
+		return handleGenerate(ctx, flags, cliHandler, verbose, stderr)
+	case "export":
+		return handleExport(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "rollback":
+		return handleRollback(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "export-unit":
+		return handleExportUnit(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "import":
+		return handleImport(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "import-cron":
+		return handleImportCron(ctx, flags, cliHandler, verbose, stderr)
+	case "import-tasks":
+		return handleImportTasks(ctx, flags, cliHandler, verbose, stderr)
+	case "adopt":
+		return handleAdopt(ctx, flags, cliHandler, verbose, stderr)
+	case "sync-config":
+		return handleSyncConfig(ctx, flags, cliHandler, verbose, stderr)
+	case "purge":
+		return handlePurge(ctx, flags, cliHandler, verbose, stderr)
+	case "history":
+		return handleHistory(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "config":
+		return handleConfig(cmdArgs, flags, cliHandler, verbose, stderr)
+	case "pipeline":
+		return handlePipeline(ctx, cmdArgs, flags, cliHandler, verbose, stderr)
+	case "version":
+		return handleVersion(ctx, flags, cliHandler, stderr)
 	default:
 		fmt.Fprintf(stderr, "nazim: unknown command: %s\n", command)
 		printUsage(stderr)
@@ -148,16 +469,74 @@ func handleCommand(ctx context.Context, command string, flags *Flags, cmdArgs []
 	}
 }
 
-func handleAdd(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
-	addFlags := &cli.Flags{
+// definitionFlags converts the service flags taken by add, edit, and test.
+func definitionFlags(flags *Flags) *cli.Flags {
+	return &cli.Flags{
 		Name:      flags.Name,
 		Command:   flags.Command,
 		Args:      flags.Args,
+		ArgList:   flags.argList(),
 		WorkDir:   flags.WorkDir,
 		OnStartup: flags.OnStartup,
 		OnLogon:   flags.OnLogon,
 		Interval:  flags.Interval,
+		Offset:    flags.Offset,
+		Schedule:  flags.Schedule,
+		Timezone:  flags.Timezone,
+		IfChanged: flags.IfChanged,
+		Blackout:  flags.Blackout,
+
+		StartupDelay: flags.StartupDelay,
+		Priority:     flags.Priority,
+		CatchUp:      flags.CatchUp,
+		NoVerify:     flags.NoVerify,
+		Portable:     flags.Portable,
+		Elevated:     flags.Elevated,
+		Umask:        flags.Umask,
+		Sandbox:      flags.Sandbox,
+		Network:      flags.Network,
+		Target:       flags.Target,
+
+		WatchPaths: flags.WatchPaths,
+		OnEvent:    flags.OnEvent,
+
+		LongRunning: flags.LongRunning,
+		System:      flags.System,
+
+		FromUnit: flags.FromUnit,
+
+		Steps:         flags.Steps,
+		OnStepFailure: flags.OnStepFailure,
+		ParallelGroup: flags.ParallelGroup,
+		MaxParallel:   flags.MaxParallel,
+
+		NotifyDesktop: flags.NotifyDesktop,
+		DebugEnv:      flags.DebugEnv,
+		SingletonLock: flags.SingletonLock,
+		SlowThreshold: flags.SlowThreshold,
+		DailyBudget:   flags.DailyBudget,
+		Timeout:       flags.Timeout,
+		Shell:         flags.Shell,
+		MaxLogLines:   flags.MaxLogLines,
+		LogTimestamps: flags.LogTimestamps,
+		LogLevel:      flags.LogLevel,
+		NoLog:         flags.NoLog,
+		OnFailure:     flags.OnFailure,
+
+		FailPattern:    flags.FailPattern,
+		SuccessPattern: flags.SuccessPattern,
+
+		MaxConsecutiveFailures: flags.MaxConsecutiveFailures,
+
+		Description: flags.Description,
+		Owner:       flags.Owner,
+		Labels:      flags.Labels,
 	}
+
+}
+
+func handleAdd(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	addFlags := definitionFlags(flags)
 	if err := cliHandler.Add(ctx, addFlags, verbose); err != nil {
 		fmt.Fprintf(stderr, "nazim: %v\n", err)
 		return exitError
@@ -165,8 +544,16 @@ func handleAdd(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose b
 	return exitOK
 }
 
-func handleList(ctx context.Context, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
-	if err := cliHandler.List(ctx, verbose); err != nil {
+func handleList(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if flags.Check {
+		code, err := cliHandler.CheckList(ctx, flags.Grace, verbose)
+		if err != nil {
+			fmt.Fprintf(stderr, "nazim: %v\n", err)
+		}
+		return code
+	}
+	opts := cli.ListOptions{Wide: flags.Wide, Tree: flags.Tree || flags.GroupBy != "", GroupBy: flags.GroupBy, Grace: flags.Grace}
+	if err := cliHandler.List(ctx, opts, verbose); err != nil {
 		fmt.Fprintf(stderr, "nazim: %v\n", err)
 		return exitError
 	}
@@ -186,26 +573,111 @@ func handleRemove(ctx context.Context, cmdArgs []string, cliHandler *cli.CLI, ve
 	return exitOK
 }
 
-func handleRun(ctx context.Context, cmdArgs []string, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+func handleRun(ctx context.Context, command string, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
 	if len(cmdArgs) == 0 {
-		fmt.Fprintf(stderr, "nazim: run requires a service name\n")
+		fmt.Fprintf(stderr, "nazim: %s requires a service name\n", command)
 		return exitError
 	}
 	serviceName := strings.Join(cmdArgs, " ")
-	if err := cliHandler.Run(ctx, serviceName, verbose); err != nil {
+	if err := cliHandler.Run(ctx, serviceName, flags.Force, verbose); err != nil {
 		fmt.Fprintf(stderr, "nazim: %v\n", err)
 		return exitError
 	}
 	return exitOK
 }
 
-func handleStatus(ctx context.Context, command string, cmdArgs []string, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+func handleStop(ctx context.Context, cmdArgs []string, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: stop requires a service name\n")
+		return exitError
+	}
+	serviceName := strings.Join(cmdArgs, " ")
+	if err := cliHandler.Stop(ctx, serviceName, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleResetFailures(ctx context.Context, cmdArgs []string, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: reset-failures requires a service name\n")
+		return exitError
+	}
+	serviceName := strings.Join(cmdArgs, " ")
+	if err := cliHandler.ResetFailures(ctx, serviceName, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleStatus(ctx context.Context, command string, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
 	if len(cmdArgs) == 0 {
 		fmt.Fprintf(stderr, "nazim: %s requires a service name\n", command)
 		return exitError
 	}
 	serviceName := strings.Join(cmdArgs, " ")
-	if err := cliHandler.Status(ctx, serviceName, verbose); err != nil {
+
+	var err error
+	switch flags.Output {
+	case "", "text":
+		err = cliHandler.Status(ctx, serviceName, verbose)
+	case "json":
+		err = cliHandler.StatusJSON(ctx, serviceName, verbose)
+	default:
+		err = fmt.Errorf("invalid output format %q, use text or json", flags.Output)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleAnalyze(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.Analyze(ctx, flags.MinCluster, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleStats(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.Stats(ctx, flags.Output, flags.Grace, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleDoctor(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	opts := cli.DoctorOptions{Accept: flags.Accept, Grace: flags.Grace, Notify: flags.Notify, EnableLinger: flags.EnableLinger}
+	if err := cliHandler.Doctor(ctx, cmdArgs, opts, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleVersion(ctx context.Context, flags *Flags, cliHandler *cli.CLI, stderr io.Writer) int {
+	if !flags.Check {
+		return exitOK
+	}
+	if err := cliHandler.CheckForUpdate(ctx); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleWhich(ctx context.Context, cmdArgs []string, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: which requires a service name\n")
+		return exitError
+	}
+	serviceName := strings.Join(cmdArgs, " ")
+	if err := cliHandler.Which(ctx, serviceName, verbose); err != nil {
 		fmt.Fprintf(stderr, "nazim: %v\n", err)
 		return exitError
 	}
@@ -225,15 +697,17 @@ func handleEdit(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler
 		return exitError
 	}
 
-	editFlags := &cli.Flags{
-		Name:      flags.Name,
-		Command:   flags.Command,
-		Args:      flags.Args,
-		WorkDir:   flags.WorkDir,
-		OnStartup: flags.OnStartup,
-		OnLogon:   flags.OnLogon,
-		Interval:  flags.Interval,
-	}
+	editFlags := definitionFlags(flags)
+	editFlags.ClearArgs = flags.ClearArgs
+	editFlags.ClearWorkDir = flags.ClearWorkDir
+	editFlags.ClearInterval = flags.ClearInterval
+	editFlags.NoStartup = flags.NoStartup
+	editFlags.NoDebugEnv = flags.NoDebugEnv
+	editFlags.NoSystem = flags.NoSystem
+	editFlags.NoElevated = flags.NoElevated
+	editFlags.NoNotifyDesktop = flags.NoNotifyDesktop
+	editFlags.NoCatchUp = flags.NoCatchUp
+	editFlags.ForcePlatform = flags.ForcePlatform
 	if err := cliHandler.Edit(ctx, serviceName, editFlags, verbose); err != nil {
 		fmt.Fprintf(stderr, "nazim: %v\n", err)
 		return exitError
@@ -241,6 +715,273 @@ func handleEdit(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler
 	return exitOK
 }
 
+func handleLogs(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if flags.All && len(cmdArgs) > 0 {
+		fmt.Fprintf(stderr, "nazim: logs takes either a service name or --all\n")
+		return exitError
+	}
+	if !flags.All && len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: logs requires a service name or --all\n")
+		return exitError
+	}
+	serviceName := strings.Join(cmdArgs, " ")
+	opts := cli.LogsOptions{
+		Stream: flags.Stream,
+		Tail:   flags.Tail,
+		Since:  flags.Since,
+		All:    flags.All,
+	}
+	if err := cliHandler.Logs(ctx, serviceName, opts, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleEvents(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	serviceName := strings.Join(cmdArgs, " ")
+	opts := cli.EventsOptions{
+		Follow: flags.Follow,
+		Output: flags.Output,
+		Tail:   flags.Tail,
+	}
+	if err := cliHandler.Events(ctx, serviceName, opts, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleExec(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: exec requires a service name\n")
+		return exitError
+	}
+	serviceName := strings.Join(cmdArgs, " ")
+	code, err := cliHandler.Exec(ctx, serviceName, flags.CatchUp, flags.Repeat, flags.Poll, verbose)
+	if err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+	}
+	return code
+}
+
+func handleCheck(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: check requires a service name\n")
+		return 3 // UNKNOWN, as monitoring plugins exit
+	}
+	serviceName := strings.Join(cmdArgs, " ")
+	code, err := cliHandler.Check(ctx, serviceName, cli.CheckOptions{Format: flags.Format, Grace: flags.Grace}, verbose)
+	if err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+	}
+	return code
+}
+
+func handleTest(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 && flags.Command == "" && flags.Steps == "" && flags.ParallelGroup == "" {
+		fmt.Fprintf(stderr, "nazim: test requires a service name or --command\n")
+		return exitError
+	}
+	serviceName := strings.Join(cmdArgs, " ")
+	testFlags := definitionFlags(flags)
+	code, err := cliHandler.Test(ctx, serviceName, testFlags, verbose)
+	if err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+	}
+	return code
+}
+
+func handleBackup(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.Backup(ctx, flags.To, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleRestore(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: restore requires a backup file\n")
+		return exitError
+	}
+	if err := cliHandler.Restore(ctx, cmdArgs[0], flags.Force, flags.ForcePlatform, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleApply(ctx context.Context, flags *Flags, cmdArgs []string, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: apply requires a bundle file\n")
+		return exitError
+	}
+	if err := cliHandler.Apply(ctx, cmdArgs[0], flags.Stagger, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleGenerate(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.Generate(ctx, flags.Template, flags.Matrix, flags.NoVerify, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleExport(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.Export(ctx, cmdArgs, flags.To, flags.Portable, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleRollback(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: rollback requires a service name\n")
+		return exitError
+	}
+	opts := cli.RollbackOptions{To: flags.To, Pin: flags.Pin, List: flags.List}
+	if err := cliHandler.Rollback(ctx, strings.Join(cmdArgs, " "), opts, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleExportUnit(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: export-unit requires a service name\n")
+		return exitError
+	}
+	if err := cliHandler.ExportUnit(ctx, strings.Join(cmdArgs, " "), flags.To, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleImport(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 {
+		fmt.Fprintf(stderr, "nazim: import requires an export file\n")
+		return exitError
+	}
+	if err := cliHandler.Import(ctx, cmdArgs[0], flags.Select, flags.Stagger, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleImportCron(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if flags.User && flags.File != "" {
+		fmt.Fprintf(stderr, "nazim: --user and --file cannot be combined\n")
+		return exitError
+	}
+	if err := cliHandler.ImportCron(ctx, flags.File, flags.Select, flags.Stagger, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleImportTasks(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.ImportTasks(ctx, flags.File, flags.Folder, flags.Select, flags.Stagger, flags.TakeOver, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleAdopt(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.Adopt(ctx, flags.Select, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleSyncConfig(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	opts := cli.SyncOptions{Repo: flags.Repo, MachineBranch: flags.MachineBranch, Prefer: flags.Prefer}
+	if err := cliHandler.SyncConfig(ctx, opts, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handlePurge(ctx context.Context, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if err := cliHandler.Purge(ctx, flags.KeepConfig, verbose); err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleHistory(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) != 1 || (cmdArgs[0] != "prune" && cmdArgs[0] != "export") {
+		fmt.Fprintf(stderr, "nazim: usage: nazim history prune [--service <name>] [--older-than <dur>]\n"+
+			"       nazim history export [--service <name>] [--format csv|json] [--since <when>] [--until <when>] [--to <file>]\n")
+		return exitError
+	}
+
+	var err error
+	if cmdArgs[0] == "export" {
+		err = cliHandler.ExportHistory(ctx, cli.ExportOptions{
+			Service: flags.Service,
+			Format:  flags.Format,
+			Since:   flags.Since,
+			Until:   flags.Until,
+			To:      flags.To,
+		}, verbose)
+	} else {
+		err = cliHandler.PruneHistory(ctx, flags.Service, flags.OlderThan, verbose)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handleConfig(cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) != 1 || (cmdArgs[0] != "encrypt" && cmdArgs[0] != "decrypt") {
+		fmt.Fprintf(stderr, "nazim: usage: nazim config encrypt [--passphrase-from env:NAME|file:PATH | --keychain]\n"+
+			"       nazim config decrypt\n")
+		return exitError
+	}
+
+	var err error
+	if cmdArgs[0] == "encrypt" {
+		err = cliHandler.EncryptConfig(cli.EncryptOptions{
+			Keychain:       flags.Keychain,
+			PassphraseFrom: flags.PassphraseFrom,
+		}, verbose)
+	} else {
+		err = cliHandler.DecryptConfig(verbose)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "nazim: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func handlePipeline(ctx context.Context, cmdArgs []string, flags *Flags, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
+	if len(cmdArgs) == 0 || cmdArgs[0] != "add" || flags.Steps == "" {
+		fmt.Fprintf(stderr, "nazim: usage: nazim pipeline add <name> --steps <service,...> [--on-step-failure abort|continue] [trigger options]\n")
+		return exitError
+	}
+	if len(cmdArgs) > 1 {
+		flags.Name = strings.Join(cmdArgs[1:], " ")
+	}
+	return handleAdd(ctx, flags, cliHandler, verbose, stderr)
+}
+
 func handleEnable(ctx context.Context, cmdArgs []string, cliHandler *cli.CLI, verbose bool, stderr io.Writer) int {
 	if len(cmdArgs) == 0 {
 		fmt.Fprintf(stderr, "nazim: enable requires a service name\n")
@@ -267,11 +1008,77 @@ func handleDisable(ctx context.Context, cmdArgs []string, cliHandler *cli.CLI, v
 	return exitOK
 }
 
-func parseFlags(args []string) (*Flags, []string, error) {
-	fs := flag.NewFlagSet("nazim", flag.ContinueOnError)
+// parseFlags parses the flags command takes, which may come before, between,
+// or after its arguments. Everything after "--" is an argument, so arguments
+// starting with "-" can still be given.
+func parseFlags(command string, args []string) (*Flags, []string, error) {
+	flags := &Flags{}
+	fs := commandFlagSet(command, flags)
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, fmt.Errorf("%w (see nazim --help for the options of %s)", err, command)
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if endsWithTerminator(fs, args[:len(args)-len(rest)]) {
+			// The arguments after -- are the service command's own, kept as given
+			if slices.Contains(flagsOf(command), "arg") {
+				flags.RawArgs = rest
+			} else {
+				positional = append(positional, rest...)
+			}
+			break
+		}
+		// Parse stopped at an argument; the flags after it are parsed next
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	fs.Visit(func(f *flag.Flag) {
+		flags.given = append(flags.given, f.Name)
+	})
+	return flags, positional, nil
+}
+
+// endsWithTerminator reports whether the parsed arguments end with a "--" that
+// ended the flags, rather than a "--" given as the value of a flag.
+func endsWithTerminator(fs *flag.FlagSet, parsed []string) bool {
+	n := len(parsed)
+	if n == 0 || parsed[n-1] != "--" {
+		return false
+	}
+	if n >= 2 && !strings.Contains(parsed[n-2], "=") {
+		if f := fs.Lookup(strings.TrimLeft(parsed[n-2], "-")); f != nil && !isBoolFlag(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// commandFlagSet returns the flag set of the flags command takes, parsing into flags.
+func commandFlagSet(command string, flags *Flags) *flag.FlagSet {
+	allowed := make(map[string]bool)
+	for _, name := range flagsOf(command) {
+		allowed[name] = true
+	}
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
 	fs.SetOutput(io.Discard) // Handle errors manually
+	newFlagSet(flags).VisitAll(func(f *flag.Flag) {
+		if allowed[flagName(f.Name)] {
+			fs.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	return fs
+}
 
-	flags := &Flags{}
+// newFlagSet returns the flag set of every nazim flag, parsing into flags.
+func newFlagSet(flags *Flags) *flag.FlagSet {
+	fs := flag.NewFlagSet("nazim", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Handle errors manually
 
 	// Add command flags
 	fs.StringVar(&flags.Name, "name", "", "")
@@ -279,6 +1086,7 @@ func parseFlags(args []string) (*Flags, []string, error) {
 	fs.StringVar(&flags.Command, "command", "", "")
 	fs.StringVar(&flags.Command, "c", "", "")
 	fs.StringVar(&flags.Args, "args", "", "")
+	fs.Var(&flags.Arg, "arg", "")
 	fs.StringVar(&flags.Args, "a", "", "")
 	fs.StringVar(&flags.WorkDir, "workdir", "", "")
 	fs.StringVar(&flags.WorkDir, "w", "", "")
@@ -286,133 +1094,536 @@ func parseFlags(args []string) (*Flags, []string, error) {
 	fs.BoolVar(&flags.OnLogon, "on-logon", false, "")
 	fs.StringVar(&flags.Interval, "interval", "", "")
 	fs.StringVar(&flags.Interval, "i", "", "")
+	fs.StringVar(&flags.Offset, "offset", "", "")
+	fs.StringVar(&flags.Schedule, "schedule", "", "")
+	fs.StringVar(&flags.Timezone, "tz", "", "")
+	fs.Var(&flags.IfChanged, "if-changed", "")
+	fs.Var(&flags.Blackout, "blackout", "")
+	fs.Var(&flags.WatchPaths, "watch-path", "")
+	fs.StringVar(&flags.OnEvent, "on-event", "", "")
+	fs.BoolVar(&flags.LongRunning, "long-running", false, "")
+	fs.BoolVar(&flags.System, "system", false, "")
+	fs.Var(&flags.FromUnit, "from-unit", "")
+	fs.StringVar(&flags.Steps, "steps", "", "")
+	fs.StringVar(&flags.OnStepFailure, "on-step-failure", "", "")
+	fs.StringVar(&flags.ParallelGroup, "parallel-group", "", "")
+	fs.StringVar(&flags.MaxParallel, "max-parallel", "", "")
+	fs.StringVar(&flags.StartupDelay, "startup-delay", "", "")
+	fs.StringVar(&flags.Priority, "priority", "", "")
+	fs.BoolVar(&flags.CatchUp, "catch-up", false, "")
+	fs.BoolVar(&flags.NoVerify, "no-verify", false, "")
+	fs.BoolVar(&flags.Portable, "portable", false, "")
+	fs.BoolVar(&flags.Elevated, "elevated", false, "")
+	fs.StringVar(&flags.Umask, "umask", "", "")
+	fs.StringVar(&flags.Sandbox, "sandbox", "", "")
+	fs.StringVar(&flags.Network, "network", "", "")
+	fs.StringVar(&flags.Target, "target", "", "")
+	fs.BoolVar(&flags.NotifyDesktop, "notify-desktop", false, "")
+	fs.BoolVar(&flags.DebugEnv, "debug-env", false, "")
+	fs.StringVar(&flags.SingletonLock, "singleton-lock", "", "")
+	fs.StringVar(&flags.SlowThreshold, "slow-threshold", "", "")
+	fs.StringVar(&flags.DailyBudget, "daily-budget", "", "")
+	fs.StringVar(&flags.Timeout, "timeout", "", "")
+	fs.StringVar(&flags.Shell, "shell", "", "")
+	fs.StringVar(&flags.MaxLogLines, "max-log-lines-per-run", "", "")
+	fs.StringVar(&flags.LogTimestamps, "log-timestamps", "", "")
+	fs.StringVar(&flags.LogLevel, "log-level", "", "")
+	fs.BoolVar(&flags.NoLog, "no-log", false, "")
+	fs.StringVar(&flags.OnFailure, "on-failure", "", "")
+	fs.StringVar(&flags.FailPattern, "fail-pattern", "", "")
+	fs.StringVar(&flags.SuccessPattern, "success-pattern", "", "")
+	fs.StringVar(&flags.MaxConsecutiveFailures, "max-consecutive-failures", "", "")
+	fs.StringVar(&flags.Description, "description", "", "")
+	fs.StringVar(&flags.Owner, "owner", "", "")
+	fs.Var(&flags.Labels, "label", "")
+
+	// Edit flags
+	fs.BoolVar(&flags.ClearArgs, "clear-args", false, "")
+	fs.BoolVar(&flags.ClearWorkDir, "clear-workdir", false, "")
+	fs.BoolVar(&flags.ClearInterval, "clear-interval", false, "")
+	fs.BoolVar(&flags.NoStartup, "no-startup", false, "")
+	fs.BoolVar(&flags.NoDebugEnv, "no-debug-env", false, "")
+	fs.BoolVar(&flags.NoSystem, "no-system", false, "")
+	fs.BoolVar(&flags.NoElevated, "no-elevated", false, "")
+	fs.BoolVar(&flags.NoNotifyDesktop, "no-notify-desktop", false, "")
+	fs.BoolVar(&flags.NoCatchUp, "no-catch-up", false, "")
+	fs.BoolVar(&flags.ForcePlatform, "force-platform", false, "")
+
+	// Logs flags
+	fs.StringVar(&flags.Stream, "stream", "", "")
+	fs.IntVar(&flags.Tail, "tail", 0, "")
+	fs.StringVar(&flags.Since, "since", "", "")
+	fs.BoolVar(&flags.All, "all", false, "")
+
+	// Events flags
+	fs.BoolVar(&flags.Follow, "follow", false, "")
+
+	// List flags
+	fs.BoolVar(&flags.Wide, "wide", false, "")
+	fs.BoolVar(&flags.Tree, "tree", false, "")
+	fs.StringVar(&flags.GroupBy, "group-by", "", "")
+
+	// Analyze flags
+	fs.IntVar(&flags.MinCluster, "min-cluster", cli.DefaultMinCluster, "")
+
+	// Doctor flags
+	fs.BoolVar(&flags.Accept, "accept", false, "")
+	fs.StringVar(&flags.Grace, "grace", "", "")
+	fs.BoolVar(&flags.Notify, "notify", false, "")
+	fs.BoolVar(&flags.EnableLinger, "enable-linger", false, "")
+
+	// Version flags
+	fs.BoolVar(&flags.Check, "check", false, "")
+
+	// Status flags
+	fs.StringVar(&flags.Output, "output", "", "")
+
+	// Backup/restore flags
+	fs.StringVar(&flags.To, "to", "", "")
+	fs.BoolVar(&flags.Force, "force", false, "")
+
+	// Rollback flags
+	fs.BoolVar(&flags.Pin, "pin", false, "")
+	fs.BoolVar(&flags.List, "list", false, "")
+
+	// Purge flags
+	fs.BoolVar(&flags.KeepConfig, "keep-config", false, "")
+	fs.StringVar(&flags.Repo, "repo", "", "")
+	fs.BoolVar(&flags.MachineBranch, "machine-branch", false, "")
+	fs.StringVar(&flags.Prefer, "prefer", "", "")
+
+	// Import flags
+	fs.StringVar(&flags.File, "file", "", "")
+	fs.BoolVar(&flags.User, "user", false, "")
+	fs.StringVar(&flags.Select, "select", "", "")
+	fs.StringVar(&flags.Folder, "folder", `\`, "")
+	fs.BoolVar(&flags.TakeOver, "take-over", false, "")
+	fs.StringVar(&flags.Stagger, "stagger", "", "")
+
+	// Generate flags
+	fs.StringVar(&flags.Template, "template", "", "")
+	fs.Var(&flags.Matrix, "matrix", "")
+
+	// History flags
+	fs.StringVar(&flags.Service, "service", "", "")
+	fs.StringVar(&flags.OlderThan, "older-than", "", "")
+	fs.StringVar(&flags.Format, "format", "", "")
+	fs.StringVar(&flags.Until, "until", "", "")
+
+	// Config flags
+	fs.BoolVar(&flags.Keychain, "keychain", false, "")
+	fs.StringVar(&flags.PassphraseFrom, "passphrase-from", "", "")
+
+	// Exec flags, set by the definitions nazim installs
+	fs.BoolVar(&flags.Repeat, "repeat", false, "")
+	fs.BoolVar(&flags.Poll, "poll", false, "")
 
 	// Global flags
+	fs.StringVar(&flags.ConfigDir, "config-dir", "", "")
+	fs.StringVar(&flags.DataDir, "data-dir", "", "")
+	fs.StringVar(&flags.StateDir, "state-dir", "", "")
+	fs.StringVar(&flags.Lang, "lang", "", "")
 	fs.BoolVar(&flags.Verbose, "v", false, "")
 	fs.BoolVar(&flags.Verbose, "verbose", false, "")
+	fs.BoolVar(&flags.Quiet, "q", false, "")
+	fs.BoolVar(&flags.Quiet, "quiet", false, "")
+	fs.BoolVar(&flags.NoColor, "no-color", false, "")
+	fs.BoolVar(&flags.Yes, "yes", false, "")
+	fs.BoolVar(&flags.Yes, "y", false, "")
+	fs.BoolVar(&flags.Strict, "strict", false, "")
+	fs.BoolVar(&flags.NoUpdateCheck, "no-update-check", false, "")
 	fs.BoolVar(&flags.Help, "h", false, "")
 	fs.BoolVar(&flags.Help, "help", false, "")
 
-	if err := fs.Parse(args); err != nil {
-		return nil, nil, err
-	}
-
-	return flags, fs.Args(), nil
+	return fs
 }
 
-func preprocessArgs(args []string, command string) []string {
-	// For edit command, reorder args to put flags before positional arguments
-	if command == "edit" {
-		return reorderArgsForEdit(args)
-	}
-
-	// For add command, handle multi-word values for specific flags
-	if command != "add" {
-		return args
-	}
-
-	// Known nazim flags that we should recognize
-	knownFlags := map[string]bool{
-		"--name": true, "-n": true,
-		"--command": true, "-c": true,
-		"--args": true, "-a": true,
-		"--workdir": true, "-w": true,
-		"--interval": true, "-i": true,
-		"--on-startup": true,
-		"--on-logon":   true,
-		"--verbose":    true, "-v": true,
-		"--help": true, "-h": true,
-	}
-
-	result := make([]string, 0, len(args))
-	i := 0
-	for i < len(args) {
-		currentFlag := args[i]
-
-		// Flags that may contain complex values (with spaces or internal flags)
-		if currentFlag == "--name" || currentFlag == "-n" ||
-			currentFlag == "--command" || currentFlag == "-c" ||
-			currentFlag == "--args" || currentFlag == "-a" {
-
-			result = append(result, currentFlag)
-			i++
-
-			// Accumulate value parts until we hit a known nazim flag
-			if i < len(args) && !strings.HasPrefix(args[i], "-") {
-				var valueParts []string
-				valueParts = append(valueParts, args[i])
-				i++
-
-				// Continue accumulating until we find a known nazim flag
-				for i < len(args) {
-					if knownFlags[args[i]] {
-						break
-					}
-					valueParts = append(valueParts, args[i])
-					i++
-				}
-
-				result = append(result, strings.Join(valueParts, " "))
-			}
-		} else {
-			result = append(result, args[i])
-			i++
-		}
-	}
-	return result
-}
-
-// reorderArgsForEdit moves positional arguments (service name) to the end
-// so flags can be parsed correctly by Go's flag package
-func reorderArgsForEdit(args []string) []string {
-	if len(args) == 0 {
-		return args
-	}
-
-	// If first arg is not a flag, move it to the end
-	if !strings.HasPrefix(args[0], "-") {
-		serviceName := args[0]
-		remaining := args[1:]
-		return append(remaining, serviceName)
-	}
-
-	return args
+// usages holds the usage in the languages other than English.
+var usages = map[string]string{
+	i18n.BrazilianPortuguese: usagePtBR,
 }
 
 func printUsage(w io.Writer) {
+	if usage, ok := usages[i18n.Language()]; ok {
+		fmt.Fprint(w, usage)
+		return
+	}
 	fmt.Fprint(w, `nazim - Multi-OS Service Manager
 
 Usage: nazim [command] [options]
 
+Options may come before or after a command's arguments; each command takes
+only its own options and the global ones. Quote values that contain spaces,
+and put arguments that start with "-" after "--".
+
 Commands:
   add <options>     add a new service
+  pipeline add <name> add a service that runs other services one after
+                    another, stopping at the first that fails
   list              list all services
   status <name>     show detailed service information
+  which <name>      show where a service's config, script, scheduler
+                    definitions, logs, and history are
   edit <name>       update an existing service
   remove <name>     remove a service
   enable <name>     enable a service (allows scheduled execution)
   disable <name>    disable a service (prevents scheduled execution)
   run <name>        execute a service immediately
-  version           show version information
+  start <name>      same as run; starts a --long-running service
+  stop <name>       stop a running service: a --long-running service until it
+                    is started again, or the current run of any other
+  reset-failures <name> clear the failure streak of a service paused by
+                    --max-consecutive-failures, so it runs on schedule again
+  logs <name>|--all show captured output of a service, or of all services
+  events [<name>]   show the log of services added, changed, and removed, and
+                    of runs starting and finishing; --follow keeps printing them
+  analyze           show when services start together over the next 24 hours,
+                    and how to spread them out
+  stats             summarize the runs of the last 24 hours and 7 days: failure
+                    rates, the slowest services, and services that stopped running
+  doctor [<name>...] check that the scripts and scheduler files of services
+                    haven't changed since nazim installed them, that they can
+                    run while you're logged out, and whether a newer nazim is out
+  check <name>      report a service's health for Nagios, Icinga, or Zabbix:
+                    a status line with performance data, exiting with 0 (OK),
+                    1 (WARNING), 2 (CRITICAL), or 3 (UNKNOWN)
+  test <name>|<options> run a service once without installing it, showing
+                    its output and the definitions install would generate
+  exec <name>       run a service in the foreground (used by the scheduler)
+  backup            archive config, scripts, and run history
+  restore <file>    restore a backup and reinstall its services
+  rollback <name>   put a service back as it was before its last install, or
+                    at an earlier version (--to v2); --list shows the kept ones
+  apply <bundle>    install the services of a bundle signed by an administrator,
+                    verified with <bundle>.sig (see managed_mode in the policy)
+  generate          add a service for every value of a matrix from a template
+                    of the config, or update the ones it added
+  export [names]    write services to a file another machine can import
+  export-unit <name> print the systemd units, launchd plist, or Task Scheduler
+                    XML of a service, to edit by hand and install with --from-unit
+  import <file>     add the services of an export, checking and translating
+                    each for this OS
+  import-cron       convert crontab entries into services
+  import-tasks      convert Windows scheduled tasks into services
+  adopt             add services nazim installed but the config lost back to it
+  sync-config       commit and push the config and scripts to a Git repository,
+                    and pull and install what other machines pushed
+  purge             uninstall every service and delete the wrappers, scripts,
+                    logs, history, and config nazim created, after asking
+  history prune     remove old run history (see history.retention in the config)
+  history export    export run history as CSV or JSON for reporting
+  config encrypt    encrypt the config file, for secrets in args and env;
+                    nazim decrypts it whenever it reads it
+  config decrypt    write the config file back in plain text
+  completion <shell> print a completion script for bash, zsh, or fish
+  version           show version information; --check also looks up the
+                    latest release
 
 Add Options:
   -n, --name <name>        service name (required)
   -c, --command <cmd>      command or script to execute (required)
                            use "write" or "edit" to open editor interactively
-  -a, --args <args>        arguments for the command
+  -a, --args <args>        arguments for the command, split like a shell would:
+                           quote one with spaces, as in "job.py --name 'John Doe'"
+      --arg <arg>          one argument, kept as-is (repeatable); arguments after
+                           -- are taken as-is too
   -w, --workdir <dir>      working directory
       --on-startup         run at system boot (as SYSTEM, no user context)
       --on-logon           run at user logon (as current user, has HKCU access)
-  -i, --interval <dur>      execution interval (e.g., 5m, 1h, 30s)
+  -i, --interval <dur>     execution interval (e.g., 5m, 1h, 30s; under a minute
+                           it must divide one evenly)
+      --offset <dur>       align the interval to the clock, starting runs dur past
+                           midnight (e.g., 15m with 1h runs at 00:15, 01:15, ...);
+                           none with edit removes it
+      --schedule <when>    calendar schedule (e.g., "daily 03:30", "every monday 09:00")
+      --tz <zone>          time zone of the schedule (e.g., Europe/Lisbon, UTC);
+                           local with edit goes back to the local time zone
+      --if-changed <path>  run only if these files changed since the last successful
+                           run; a path or quoted glob, may be repeated; none with
+                           edit removes them
+      --blackout <window>  skip runs during these days, dates, or hours (e.g.,
+                           "sat,sun", "2024-12-24..2024-12-26", "22:00..06:00");
+                           may be repeated; none with edit removes them
+      --watch-path <path>  run when this file or directory changes, such as a drop
+                           folder; may be repeated; polled every minute on Windows,
+                           where it can't be combined with other triggers; none
+                           with edit removes them
+      --on-event <event>   run when a Windows event log event is written, as
+                           "Log=System;Source=<source>;ID=<id>" (Windows only;
+                           can't be combined with other triggers); none with
+                           edit removes it
+      --long-running       run continuously as a Windows service, started at
+                           boot and restarted when it fails (Windows only;
+                           replaces other triggers, which in turn replace it
+                           with edit)
+      --startup-delay <dur> delay on-startup/on-logon runs (e.g., 30s, 2m)
+      --priority <class>   high, normal, or low; lower classes start later at boot
+      --catch-up           run missed interval/schedule runs after the machine was off
+      --no-verify          skip checking that the command exists and is executable
+      --portable           keep paths relative to the config dir (for moving configs)
+      --elevated           run with highest privileges (Windows only)
+      --system             install as a launch daemon in /Library/LaunchDaemons,
+                           running as root from boot (macOS only; needs sudo)
+      --from-unit <file>   install this hand-edited unit, plist, or task XML, as
+                           written by export-unit, instead of generating it; may
+                           be repeated; none with edit goes back to generating
+      --parallel-group <names> run these services at the same time instead of
+                           a command, separated by commas; the run fails if
+                           any of them fails
+      --max-parallel <n>   run at most n services of a --parallel-group at a
+                           time (default: all); 0 with edit removes the limit
+      --umask <mask>       file mode creation mask for files the command creates,
+                           e.g. 027 (Linux and macOS only); none with edit removes it
+      --sandbox <level>    run under systemd hardening, basic or strict, for
+                           semi-trusted scripts (Linux only); none with edit
+                           removes it
+      --network none       cut the command off from the network: systemd's
+                           PrivateNetwork on Linux, Windows Firewall rules for
+                           its program on Windows (not macOS); default with
+                           edit gives it back
+      --target <where>     inside WSL, windows-host schedules the service in the
+                           Windows Task Scheduler, which runs it through wsl.exe
+                           even while WSL is shut down; none with edit removes it
+      --notify-desktop     show a desktop notification when a run finishes
+      --debug-env          log the user, working dir, and key environment
+                           variables at the start of each run, and keep them in
+                           its history, to see how the scheduler runs it
+      --singleton-lock <url> run on only one host of a group at a time, e.g.
+                           file:///mnt/shared/locks or redis://host:6379; none
+                           with edit removes it
+      --slow-threshold <dur> mark runs taking longer than dur as slow (e.g., 10m);
+                           none with edit removes it
+      --daily-budget <dur> total runtime allowed per day (e.g., 30m); a run going
+                           past it is stopped, and later runs that day skipped;
+                           none with edit removes it
+      --timeout <dur>      stop a run taking longer than dur (e.g., 10m) and
+                           record it as failed; none with edit removes it
+      --shell <shell>      shell for one-liners, e.g. bash or pwsh (default: sh, cmd
+                           on Windows); .ps1 scripts always run through PowerShell
+      --max-log-lines-per-run <n> log at most n lines of each stream per run,
+                           the first and last n/2; 0 with edit removes the cap
+      --log-timestamps <fmt> stamp log lines in local time (default), utc, both,
+                           or rfc3339 (UTC, for log pipelines); none with edit
+                           goes back to the default
+      --log-level <level>  log the output of all runs (default), errors-only
+                           (only failed runs), or off
+      --no-log             don't log the output of runs; same as --log-level off
+      --on-failure <policy> after a failed run, run:<service> starts another
+                           service, disable-self-after:<n> disables this one after
+                           n failed runs in a row; none with edit removes it
+      --fail-pattern <re>  fail runs with an output line matching this regular
+                           expression, even if they exit with 0; none with edit
+                           removes it
+      --success-pattern <re> fail runs with no output line matching this regular
+                           expression, even if they exit with 0; none with edit
+                           removes it
+      --max-consecutive-failures <n> after n failed runs in a row, pause runs
+                           for the gap between them, doubling with each further
+                           failure up to a day; 0 with edit removes the limit
+      --description <text> what the service does, shown by status and list --wide
+      --owner <who>        who to ask about the service
+      --label <key=value>  free-form metadata, may be repeated; key= with edit
+                           removes a label
+
+Edit Options:
+  Takes the add options to change a setting, and these to remove one:
+      --clear-args         remove the arguments
+      --clear-workdir      remove the working directory
+      --clear-interval     stop running at an interval
+      --no-startup         stop running at startup
+      --no-debug-env       stop recording the environment of each run
+      --no-system          install a --system service as your launch agent again
+      --no-elevated        run with the usual privileges again
+      --no-notify-desktop  stop showing a notification when a run finishes
+      --no-catch-up        stop making up missed runs
+      --force-platform     reinstall a service set up on another OS that has
+                           no platforms override for this one
+  The service must keep a trigger (startup, logon, interval, or schedule).
+
+Pipeline Options:
+  Takes the add options, except those of the command, and these:
+      --steps <names>      the services to run, in order, separated by commas
+                           (required); with edit, replaces the steps, or the
+                           command of a service that isn't a pipeline
+      --on-step-failure <what> abort (default) skips the remaining steps once
+                           one fails; continue runs them anyway; none with
+                           edit goes back to abort
+  Each step runs as it would on its own, keeping its logs and history; the
+  pipeline fails with the exit code of the first step that failed.
+
+Test Options:
+  Takes the add options to describe a service instead of naming a configured
+  one. No trigger is needed; output is shown instead of logged.
+
+Run/Start Options:
+      --force              run a disabled service anyway, in the foreground
+                           (without it, run asks first on a terminal)
+
+Status Options:
+      --output <format>    text (default) or json; json adds everything the
+                           scheduler reports for the service as platform_raw
+
+List Options:
+      --wide               show full commands instead of fitting the terminal width,
+                           and each service's owner, labels, and description
+      --tree               show services in groups, with only the program each one runs
+      --group-by <what>    group the tree by type (default), namespace (the name up
+                           to its first '.', '-', or '_'), label, or label:<key>
+      --grace <n>          mark enabled services Stale when they haven't run in
+                           over n times their interval (default: 2, or stale.grace
+                           in the config)
+      --check              print a one-line summary instead, and exit with 1
+                           unless every enabled service is installed, healthy
+                           (not paused or failing), and not stale
+
+Logs Options:
+      --stream <name>      show only stdout or stderr (default: both, merged)
+      --tail <n>           show only the last n lines
+      --since <dur>        show only lines from the last dur (e.g., 30m, 1h, 2d)
+      --all                interleave the logs of all services, prefixed with their names
+
+Events Options:
+      --follow             keep printing events as they happen, starting with new ones
+      --output <format>    text (default) or json, one event per line
+      --tail <n>           show only the last n past events (with --follow, show
+                           them before the new ones)
+
+Analyze Options:
+      --min-cluster <n>    report minutes in which at least n services start
+                           (default: 3)
+
+Stats Options:
+      --output <format>    text (default) or json
+      --grace <n>          count services as stale after n times their interval
+                           without running (default: 2)
+
+Doctor Options:
+      --accept             record the files as they are now, after a change
+                           made on purpose
+      --grace <n>          report services that haven't run in over n times
+                           their interval as stale (default: 2)
+      --notify             also report stale services with a desktop
+                           notification and to the Windows event log
+      --enable-linger      have systemd start your services at boot and keep
+                           them running after you log out, with sudo
+                           loginctl enable-linger (Linux only)
+
+Check Options:
+      --format <format>    nagios (default), a plugin's status line, or json
+      --grace <n>          report the service as stale after n times its
+                           interval (default: 2)
+
+Version Options:
+      --check              ask GitHub whether a newer release is out, and how
+                           to upgrade to it
+
+Backup Options:
+      --to <file>          backup file (default: nazim-backup-<timestamp>.tar.gz)
+      --force              restore over existing services, uninstalling them first
+      --force-platform     also install services set up on another OS that have
+                           no platforms override for this one
+
+Rollback Options:
+      --to <version>       version to go back to, such as v2 (default: the one
+                           before the latest)
+      --pin                install the version's unit files or plist as they
+                           were, instead of generating them again (not Windows)
+      --list               list the kept versions instead
+
+Export Options:
+      --to <file>          export file (default: the output)
+      --portable           tag each service with the OSes it runs on and the
+                           kind of its command, so import can translate it
+
+Export-unit Options:
+      --to <dir>           write each file to this directory instead of the output
+
+Generate Options:
+      --template <name>    template of the config's templates block (required)
+      --matrix <key=values> values for a {{key}} of the template, separated by
+                           commas; repeat for more keys, to get every
+                           combination (default: those of the services the
+                           template added, to update them after changing it)
+      --no-verify          skip checking that each command exists
+  Services added from a template are replaced by the next generate, edits
+  and all; ones left out of the matrix are kept until removed.
+
+Sync-config Options:
+      --repo <url>         repository to sync with; needed only the first time
+      --machine-branch     push to a branch of this machine's own,
+                           machines/<hostname>, and only pull the shared one
+      --prefer <side>      settle changes to the same lines made here and in the
+                           repository: local or remote (default: stop and ask)
+
+Purge Options:
+      --keep-config        keep the config and the scripts its services run
+
+Import Options:
+      --user               read the current user's crontab (default)
+      --file <path>        read a crontab file such as /etc/crontab, or an
+                           exported task XML file for import-tasks
+      --folder <path>      Task Scheduler folder to import from (default: \)
+      --take-over          delete each imported task once nazim manages it
+      --stagger <step>     give imported services sharing an interval offsets
+                           step apart, or auto to spread them evenly across it;
+                           also taken by import and apply
+      --select <which>     entries to import: all, or numbers such as 1,3-4
+                           (default: ask after showing the preview); also
+                           picks the services import and adopt add
+
+History Options:
+      --service <name>     prune or export only this service's history
+      --older-than <dur>   remove runs older than dur (e.g., 30d) instead of
+                           applying the configured retention
+      --format <format>    export as csv (default) or json
+      --since <when>       export runs that started on or after a date
+                           (2024-01-01), a time (RFC 3339), or dur ago (30d)
+      --until <when>       export runs that started up to the end of a date,
+                           before a time, or before dur ago
+      --to <file>          write the export to a file instead of the output
+
+Config Options:
+      --passphrase-from <src>
+                           read the passphrase from env:NAME or file:PATH
+                           instead of asking for it, whenever the config is
+                           read; needed for scheduled runs to read it
+      --keychain           encrypt with a random key kept in the OS keychain
+                           (Secret Service, macOS keychain, or DPAPI) instead
+                           of a passphrase
 
 Global Options:
-  -v, --verbose        enable verbose output
+      --config-dir <dir>   use an explicit config directory, which also holds
+                           scripts, logs, and history unless it is the default
+      --data-dir <dir>     keep scripts in dir
+      --state-dir <dir>    keep logs and run history in dir
+      --lang <lang>        language of messages: en or pt-BR (default: from LANG)
+  -v, --verbose        enable verbose output, listing every scheduler command
+                       run with how long it took and its exit status
+  -q, --quiet          suppress informational output (errors are still shown)
+      --no-color       disable colored output (also: NO_COLOR=1)
+  -y, --yes            answer prompts without asking (import and adopt take
+                       every entry unless --select is given, and purge
+                       removes everything without confirming)
+      --strict         treat warnings as errors, such as a command that may
+                       not exist or a failed uninstall during edit or remove,
+                       or a deprecated flag or config field
+      --no-update-check  never contact GitHub for the latest release (also:
+                       NAZIM_NO_UPDATE_CHECK=1)
   -h, --help           show this help
       --version        show version information
 
 Environment:
+  LANG, LC_ALL      language of messages, e.g. pt_BR.UTF-8 (LC_MESSAGES works too)
   NAZIM_VERBOSE     set to "1" for verbose output
+  NAZIM_TRACE       set to "1" to also write scheduler commands and their output
+                    to trace.log in the logs directory
+  NAZIM_UPLOAD_TOKEN bearer token for uploading output to an HTTP endpoint
+                    (see the upload block of the config)
+  NAZIM_NO_UPDATE_CHECK set to "1" to never look up the latest release
+  NO_COLOR          set to disable colored output
   XDG_CONFIG_HOME   config directory base (default: ~/.config on Linux/macOS, %APPDATA% on Windows)
+  XDG_DATA_HOME     scripts directory base (default: ~/.local/share, %LOCALAPPDATA% on Windows)
+  XDG_STATE_HOME    logs and history directory base (default: ~/.local/state, %LOCALAPPDATA% on Windows)
 
 Examples:
   # Simple one-liner command
@@ -424,24 +1635,67 @@ Examples:
   # Service that runs on startup
   nazim add --name init --command init.sh --on-startup
 
+  # Service that runs every Monday at 09:00
+  nazim add --name report --command report.sh --schedule "every monday 09:00"
+
   # Interactive mode: open editor to write script
   nazim add --name myscript --command write --interval 30m
 
+  # Rebuild the docs every 10 minutes, but only if their sources changed
+  nazim add --name docs --command "make html" --workdir ~/docs --interval 10m --if-changed src
+
+  # Process files as they are dropped into a folder
+  nazim add --name ingest --command ingest.sh --watch-path /srv/drop/incoming
+
+  # Windows: react each time a service stops or starts
+  nazim add --name svc-watch --command svc-watch.ps1 --on-event "Log=System;Source=Service Control Manager;ID=7036"
+
+  # Windows: keep a daemon running as a Windows service
+  nazim add --name relay --command C:\relay\relay.exe --long-running
+
+  # macOS: run a maintenance script as root at boot, before anyone logs in
+  sudo nazim add --name cleanup --command /usr/local/bin/cleanup.sh --on-startup --system
+
+  # PowerShell 7 script, on any platform
+  nazim add --name inventory --command inventory.ps1 --shell pwsh --interval 1h
+
+  # Service with metadata for whoever finds it later
+  nazim add --name backup --command backup.sh --schedule "daily 02:00" \
+    --description "Nightly backup of /srv" --owner ops@example.com --label team=infra
+
   # Command with arguments
   nazim add --name processor --command python --args "script.py --verbose" --interval 30m
 
+  # Arguments passed as-is, whatever they contain
+  nazim add --name greet --command python --interval 1h -- greet.py --name "John Doe"
+
   # List all services
   nazim list
 
+  # List services grouped by team
+  nazim list --tree --group-by label:team
+
+  # Exit with 1 when an enabled service is missing, failing, or stale
+  nazim list --check
+
   # Remove a service
   nazim remove backup
 
   # Show service status
   nazim status backup
 
+  # Try a command before adding it
+  nazim test --command python --args "job.py --dry" --workdir /srv
+
   # Edit a service
   nazim edit backup --interval 2h
 
+  # Run a service every hour at a quarter past
+  nazim edit backup --interval 1h --offset 15m
+
+  # Switch a service from startup to an interval, dropping its arguments
+  nazim edit backup --no-startup --interval 1h --clear-args
+
   # Enable/disable a service
   nazim enable backup
   nazim disable backup
@@ -449,10 +1703,101 @@ Examples:
   # Run a service immediately
   nazim run backup
 
+  # Tweak the systemd timer of a service by hand, keeping it in nazim
+  nazim export-unit backup --to ./units
+  nazim edit backup --from-unit ./units/nazim-backup.service --from-unit ./units/nazim-backup.timer
+
+  # Undo a bad edit
+  nazim rollback backup --list
+  nazim rollback backup
+
+  # Back up everything and restore it on another machine
+  nazim backup --to nazim-backup.tar.gz
+  nazim restore nazim-backup.tar.gz
+
+  # Preview the user's crontab and import the first two entries
+  nazim import-cron --select 1-2
+
+  # Import hourly cron jobs spread across the hour instead of all at :00
+  nazim import-cron --select all --stagger auto
+
+  # Import the tasks in a Task Scheduler folder and remove the originals
+  nazim import-tasks --folder \MyTasks --take-over
+
+  # Rebuild a lost config from the installed units, agents, or tasks
+  nazim adopt --select all
+
+  # Share services between machines through a Git repository, then keep
+  # them in sync every 15 minutes
+  nazim sync-config --repo git@github.com:me/nazim-config.git
+  nazim add --name config-sync --command "nazim sync-config" --interval 15m
+
+  # Uninstall nazim completely, keeping the config for later
+  nazim purge --keep-config
+
+  # Drop run history older than 30 days
+  nazim history prune --older-than 30d
+
+  # Export last month's runs of every service for a report
+  nazim history export --format csv --since 2024-01-01 --until 2024-01-31 --to runs.csv
+
+  # Encrypt the config, reading the passphrase from a file for scheduled runs
+  nazim config encrypt --passphrase-from file:/etc/nazim/passphrase
+
+  # Show the last 50 lines of a service's stderr
+  nazim logs backup --stream stderr --tail 50
+
+  # Show what every service printed in the last 8 hours
+  nazim logs --all --since 8h
+
+  # Back up, compress, and upload every night, one after another
+  nazim pipeline add nightly --steps "db-backup,compress,upload" --interval 24h
+
+  # Sync three repositories at once, two at a time
+  nazim add --name repos --parallel-group "sync-api,sync-web,sync-docs" --max-parallel 2 --interval 1h
+
+  # Add backup-a, backup-b, and backup-c from the backup-tpl template, then
+  # update them after changing it
+  nazim generate --template backup-tpl --matrix dir=/srv/a,/srv/b,/srv/c
+  nazim generate --template backup-tpl
+
+  # Stream every event as JSON, for a desktop widget or log shipper
+  nazim events --follow --output json
+
+  # Find the minutes in which two or more services start together
+  nazim analyze --min-cluster 2
+
+  # Summarize the runs of the last week as JSON
+  nazim stats --output json
+
+  # Check services for scripts changed outside nazim, then accept an edit
+  nazim doctor
+  nazim doctor backup --accept
+
+  # Check every hour that services still run, and notify when one stopped
+  nazim add --name stale-check --command "nazim doctor --notify" --interval 1h
+
+  # Keep services running on a Linux server after you log out
+  nazim doctor --enable-linger
+
+  # Let Nagios or Icinga track a job, as a plugin
+  nazim check backup --format nagios
+
+  # See whether a newer release is out
+  nazim version --check
+
+  # Enable tab completion in bash
+  source <(nazim completion bash)
+
 Interval Format:
   Use s (seconds), m (minutes), h (hours), or d (days)
   Examples: 30s, 5m, 1h, 24h
 
+Schedule Format:
+  <days> <HH:MM>, where days is daily, weekdays, weekends, or a
+  comma-separated list of weekdays (optionally prefixed with "every")
+  Examples: "daily 03:30", "weekdays 18:00", "every monday 09:00", "every mon,thu at 12:15"
+
 Config: ~/.config/nazim/services.yaml (or equivalent on Windows/macOS)
 `)
 }
@@ -0,0 +1,580 @@
+package main
+
+// usagePtBR is the usage in Brazilian Portuguese. Keep it in step with printUsage.
+const usagePtBR = `nazim - Gerenciador de Serviços Multiplataforma
+
+Uso: nazim [comando] [opções]
+
+As opções podem vir antes ou depois dos argumentos de um comando; cada comando
+aceita apenas as próprias opções e as globais. Use aspas em valores com espaços
+e coloque argumentos que começam com "-" depois de "--".
+
+Comandos:
+  add <opções>      adiciona um serviço
+  pipeline add <nome> adiciona um serviço que executa outros serviços um após
+                    o outro, parando no primeiro que falhar
+  list              lista todos os serviços
+  status <nome>     mostra informações detalhadas de um serviço
+  which <nome>      mostra onde ficam a configuração, o script, as definições
+                    do agendador, os logs e o histórico de um serviço
+  edit <nome>       altera um serviço existente
+  remove <nome>     remove um serviço
+  enable <nome>     ativa um serviço (permite a execução agendada)
+  disable <nome>    desativa um serviço (impede a execução agendada)
+  run <nome>        executa um serviço imediatamente
+  start <nome>      o mesmo que run; inicia um serviço --long-running
+  stop <nome>       para um serviço em execução: um serviço --long-running até
+                    ser iniciado de novo, ou a execução atual de qualquer outro
+  reset-failures <nome> zera as falhas seguidas de um serviço pausado por
+                    --max-consecutive-failures, para que volte a rodar no horário
+  logs <nome>|--all mostra a saída capturada de um serviço, ou de todos
+  events [<nome>]   mostra o registro de serviços adicionados, alterados e
+                    removidos, e de execuções iniciadas e terminadas; --follow
+                    continua mostrando-os
+  analyze           mostra quando serviços começam juntos nas próximas 24 horas
+                    e como espalhá-los
+  stats             resume as execuções das últimas 24 horas e 7 dias: taxas
+                    de falha, os serviços mais lentos e os que pararam de rodar
+  doctor [<nome>...] verifica se os scripts e arquivos do agendador dos serviços
+                    mudaram desde que o nazim os instalou, se podem rodar com
+                    você desconectado, e se há um nazim mais novo
+  check <nome>      informa a saúde de um serviço para Nagios, Icinga ou
+                    Zabbix: uma linha de status com dados de desempenho,
+                    saindo com 0 (OK), 1 (WARNING), 2 (CRITICAL) ou 3 (UNKNOWN)
+  test <nome>|<opções> executa um serviço uma vez sem instalá-lo, mostrando
+                    a saída e as definições que a instalação geraria
+  exec <nome>       executa um serviço em primeiro plano (usado pelo agendador)
+  backup            arquiva a configuração, os scripts e o histórico
+  restore <arquivo> restaura um backup e reinstala seus serviços
+  rollback <nome>   volta um serviço a como estava antes da última instalação,
+                    ou a uma versão anterior (--to v2); --list mostra as guardadas
+  apply <pacote>    instala os serviços de um pacote assinado por um administrador,
+                    verificado com <pacote>.sig (veja managed_mode na política)
+  generate          adiciona um serviço para cada valor de uma matriz a partir
+                    de um modelo da configuração, ou atualiza os que adicionou
+  export [nomes]    grava serviços num arquivo que outra máquina pode importar
+  export-unit <nome> mostra as units do systemd, o plist do launchd ou o XML do
+                    Agendador de Tarefas de um serviço, para editar à mão e
+                    instalar com --from-unit
+  import <arquivo>  adiciona os serviços de uma exportação, verificando e
+                    traduzindo cada um para este sistema
+  import-cron       converte entradas do crontab em serviços
+  import-tasks      converte tarefas agendadas do Windows em serviços
+  adopt             devolve à configuração serviços que o nazim instalou mas
+                    que ela perdeu
+  sync-config       faz commit e push da configuração e dos scripts para um
+                    repositório Git, e baixa e instala o que outras máquinas
+                    enviaram
+  purge             desinstala todos os serviços e apaga os wrappers, scripts,
+                    logs, histórico e configuração criados pelo nazim, após
+                    perguntar
+  history prune     remove histórico antigo (veja history.retention na configuração)
+  history export    exporta o histórico em CSV ou JSON para relatórios
+  config encrypt    criptografa o arquivo de configuração, para segredos em
+                    args e env; o nazim o descriptografa sempre que o lê
+  config decrypt    grava o arquivo de configuração de volta em texto puro
+  completion <shell> imprime um script de autocompletar para bash, zsh ou fish
+  version           mostra a versão; com --check, procura também a última
+                    versão publicada
+
+Opções de add:
+  -n, --name <nome>        nome do serviço (obrigatório)
+  -c, --command <cmd>      comando ou script a executar (obrigatório)
+                           use "write" ou "edit" para abrir o editor
+  -a, --args <args>        argumentos do comando, separados como num shell: use
+                           aspas para um com espaços, como "job.py --name 'João Silva'"
+      --arg <arg>          um argumento, mantido como está (repetível); argumentos
+                           depois de -- também são mantidos como estão
+  -w, --workdir <dir>      diretório de trabalho
+      --on-startup         executa na inicialização do sistema (como SYSTEM, sem
+                           contexto de usuário)
+      --on-logon           executa no logon do usuário (como o usuário atual, com
+                           acesso ao HKCU)
+  -i, --interval <dur>     intervalo de execução (ex.: 5m, 1h, 30s; abaixo de um
+                           minuto, deve dividi-lo exatamente)
+      --offset <dur>       alinha o intervalo ao relógio, começando as execuções
+                           dur após a meia-noite (ex.: 15m com 1h executa às
+                           00:15, 01:15, ...); none com edit o remove
+      --schedule <quando>  agenda de calendário (ex.: "daily 03:30", "every monday 09:00")
+      --tz <fuso>          fuso horário da agenda (ex.: Europe/Lisbon, UTC); local
+                           com edit volta ao fuso horário local
+      --if-changed <caminho> executa só se esses arquivos mudaram desde a última
+                           execução bem-sucedida; um caminho ou glob entre aspas,
+                           pode ser repetido; none com edit os remove
+      --blackout <janela>  pula as execuções nesses dias, datas ou horários (ex.:
+                           "sat,sun", "2024-12-24..2024-12-26", "22:00..06:00");
+                           pode ser repetido; none com edit as remove
+      --watch-path <caminho> executa quando esse arquivo ou diretório muda, como
+                           uma pasta de entrada; pode ser repetido; no Windows é
+                           verificado a cada minuto e não pode ser combinado com
+                           outros gatilhos; none com edit os remove
+      --on-event <evento>  executa quando um evento é gravado no log de eventos do
+                           Windows, como "Log=System;Source=<origem>;ID=<id>"
+                           (só no Windows; não pode ser combinado com outros
+                           gatilhos); none com edit o remove
+      --long-running       executa continuamente como serviço do Windows,
+                           iniciado no boot e reiniciado quando falha (só no
+                           Windows; substitui os outros gatilhos, que por sua
+                           vez o substituem com edit)
+      --startup-delay <dur> atrasa execuções de on-startup/on-logon (ex.: 30s, 2m)
+      --priority <classe>  high, normal ou low; classes mais baixas começam mais
+                           tarde na inicialização
+      --catch-up           executa depois as execuções perdidas com a máquina desligada
+      --no-verify          não verifica se o comando existe e é executável
+      --portable           mantém caminhos relativos ao diretório de configuração
+                           (para mover configurações)
+      --elevated           executa com os privilégios mais altos (só no Windows)
+      --system             instala como launch daemon em /Library/LaunchDaemons,
+                           executado como root desde o boot (só no macOS;
+                           requer sudo)
+      --from-unit <arquivo> instala esta unit, plist ou XML de tarefa editado à
+                           mão, como gravado por export-unit, em vez de gerá-lo;
+                           pode ser repetido; none com edit volta a gerá-lo
+      --parallel-group <nomes> executa estes serviços ao mesmo tempo em vez de
+                           um comando, separados por vírgulas; a execução
+                           falha se algum deles falhar
+      --max-parallel <n>   executa no máximo n serviços de um --parallel-group
+                           por vez (padrão: todos); 0 com edit remove o limite
+      --umask <máscara>    máscara de criação dos arquivos que o comando cria,
+                           ex.: 027 (só Linux e macOS); none com edit a remove
+      --sandbox <nível>    executa com o endurecimento do systemd, basic ou
+                           strict, para scripts pouco confiáveis (só Linux);
+                           none com edit o remove
+      --network none       isola o comando da rede: PrivateNetwork do systemd
+                           no Linux, regras do Firewall do Windows para o
+                           programa no Windows (não no macOS); default com
+                           edit devolve a rede
+      --target <onde>      dentro do WSL, windows-host agenda o serviço no Agendador
+                           de Tarefas do Windows, que o executa pelo wsl.exe mesmo
+                           com o WSL desligado; none com edit o remove
+      --notify-desktop     mostra uma notificação quando uma execução termina
+      --debug-env          registra o usuário, o diretório de trabalho e as
+                           principais variáveis de ambiente no início de cada
+                           execução, e os guarda no histórico, para ver como o
+                           agendador a executa
+      --singleton-lock <url> executa em apenas uma máquina do grupo por vez, ex.:
+                           file:///mnt/shared/locks ou redis://host:6379; none
+                           com edit o remove
+      --slow-threshold <dur> marca como lentas execuções mais longas que dur
+                           (ex.: 10m); none com edit o remove
+      --daily-budget <dur> tempo total de execução permitido por dia (ex.: 30m);
+                           uma execução que passa dele é parada, e as seguintes
+                           do dia são puladas; none com edit o remove
+      --timeout <dur>      para uma execução mais longa que dur (ex.: 10m) e a
+                           registra como falha; none com edit o remove
+      --shell <shell>      shell dos comandos de uma linha, ex.: bash ou pwsh
+                           (padrão: sh, ou cmd no Windows); scripts .ps1 sempre
+                           rodam no PowerShell
+      --max-log-lines-per-run <n> registra no máximo n linhas de cada saída por
+                           execução, as n/2 primeiras e últimas; 0 com edit remove
+                           o limite
+      --log-timestamps <fmt> data e hora das linhas do log em horário local
+                           (padrão), utc, both (os dois) ou rfc3339 (UTC, para
+                           pipelines de logs); none com edit volta ao padrão
+      --log-level <nível>  registra a saída de todas as execuções (all, padrão),
+                           só das que falharam (errors-only) ou de nenhuma (off)
+      --no-log             não registra a saída das execuções; o mesmo que
+                           --log-level off
+      --on-failure <política> após uma falha, run:<serviço> inicia outro serviço
+                           e disable-self-after:<n> desativa este após n falhas
+                           seguidas; none com edit a remove
+      --fail-pattern <re>  faz falhar as execuções com uma linha de saída que
+                           case com essa expressão regular, mesmo saindo com 0;
+                           none com edit a remove
+      --success-pattern <re> faz falhar as execuções sem nenhuma linha de saída
+                           que case com essa expressão regular, mesmo saindo com
+                           0; none com edit a remove
+      --max-consecutive-failures <n> após n falhas seguidas, pausa as execuções
+                           pelo intervalo entre elas, dobrando a cada nova falha
+                           até um dia; 0 com edit remove o limite
+      --description <texto> o que o serviço faz, mostrado por status e list --wide
+      --owner <quem>       a quem perguntar sobre o serviço
+      --label <chave=valor> metadados livres, pode ser repetido; chave= com edit
+                           remove um rótulo
+
+Opções de edit:
+  Aceita as opções de add para mudar uma configuração, e estas para removê-la:
+      --clear-args         remove os argumentos
+      --clear-workdir      remove o diretório de trabalho
+      --clear-interval     deixa de executar em intervalos
+      --no-startup         deixa de executar na inicialização
+      --no-debug-env       deixa de registrar o ambiente de cada execução
+      --no-system          instala um serviço --system de novo como seu launch agent
+      --no-elevated        executa de novo com os privilégios de costume
+      --no-notify-desktop  deixa de mostrar uma notificação quando uma execução termina
+      --no-catch-up        deixa de compensar execuções perdidas
+      --force-platform     reinstala um serviço configurado em outro sistema que
+                           não tem um override em platforms para este
+  O serviço precisa manter um gatilho (inicialização, logon, intervalo ou agenda).
+
+Opções de pipeline:
+  Aceita as opções de add, exceto as do comando, e estas:
+      --steps <nomes>      os serviços a executar, em ordem, separados por
+                           vírgulas (obrigatória); com edit, substitui as etapas,
+                           ou o comando de um serviço que não é um pipeline
+      --on-step-failure <o quê> abort (padrão) pula as etapas restantes quando
+                           uma falha; continue as executa mesmo assim; none com
+                           edit volta para abort
+  Cada etapa roda como rodaria sozinha, mantendo seus logs e histórico; o
+  pipeline falha com o código de saída da primeira etapa que falhou.
+
+Opções de test:
+  Aceita as opções de add para descrever um serviço em vez de nomear um
+  configurado. Nenhum gatilho é necessário; a saída é mostrada em vez de registrada.
+
+Opções de run e start:
+      --force              executa um serviço desativado mesmo assim, em primeiro
+                           plano (sem ela, run pergunta antes em um terminal)
+
+Opções de status:
+      --output <formato>   text (padrão) ou json; json inclui tudo o que o agendador
+                           informa sobre o serviço em platform_raw
+
+Opções de list:
+      --wide               mostra os comandos completos em vez de caber na largura
+                           do terminal, com dono, rótulos e descrição de cada serviço
+      --tree               mostra os serviços em grupos, apenas com o programa que cada um executa
+      --group-by <o quê>   agrupa a árvore por type (padrão), namespace (o nome até o
+                           primeiro '.', '-' ou '_'), label ou label:<chave>
+      --grace <n>          marca como Stale os serviços ativados que não rodam há
+                           mais de n vezes o seu intervalo (padrão: 2, ou
+                           stale.grace na configuração)
+      --check              imprime um resumo de uma linha em vez disso, e sai
+                           com 1 a menos que todo serviço ativado esteja
+                           instalado, saudável (nem pausado nem falhando) e
+                           não parado
+
+Opções de logs:
+      --stream <nome>      mostra apenas stdout ou stderr (padrão: ambos, mesclados)
+      --tail <n>           mostra apenas as últimas n linhas
+      --since <dur>        mostra apenas linhas do último dur (ex.: 30m, 1h, 2d)
+      --all                intercala os logs de todos os serviços, prefixados
+                           com seus nomes
+
+Opções de events:
+      --follow             continua mostrando os eventos conforme acontecem,
+                           começando pelos novos
+      --output <formato>   text (padrão) ou json, um evento por linha
+      --tail <n>           mostra apenas os últimos n eventos passados (com
+                           --follow, mostra-os antes dos novos)
+
+Opções de analyze:
+      --min-cluster <n>    aponta os minutos em que pelo menos n serviços começam
+                           (padrão: 3)
+
+Opções de stats:
+      --output <formato>   text (padrão) ou json
+      --grace <n>          considera parados os serviços que não rodam há mais
+                           de n vezes o seu intervalo (padrão: 2)
+
+Opções de doctor:
+      --accept             registra os arquivos como estão agora, após uma
+                           mudança intencional
+      --grace <n>          aponta como parados os serviços que não rodam há mais
+                           de n vezes o seu intervalo (padrão: 2)
+      --notify             também avisa dos serviços parados com uma notificação
+                           na área de trabalho e no log de eventos do Windows
+      --enable-linger      faz o systemd iniciar seus serviços no boot e mantê-los
+                           rodando após você sair, com sudo loginctl
+                           enable-linger (só no Linux)
+
+Opções de check:
+      --format <formato>   nagios (padrão), a linha de status de um plugin, ou json
+      --grace <n>          considera o serviço parado após n vezes o seu
+                           intervalo (padrão: 2)
+
+Opções de version:
+      --check              pergunta ao GitHub se há uma versão mais nova, e como
+                           atualizar para ela
+
+Opções de backup:
+      --to <arquivo>       arquivo de backup (padrão: nazim-backup-<data>.tar.gz)
+      --force              restaura sobre serviços existentes, desinstalando-os antes
+      --force-platform     instala também serviços configurados em outro sistema
+                           que não têm um override em platforms para este
+
+Opções de rollback:
+      --to <versão>        versão para a qual voltar, como v2 (padrão: a anterior
+                           à mais recente)
+      --pin                instala os arquivos de unit ou o plist da versão como
+                           eram, em vez de gerá-los de novo (exceto no Windows)
+      --list               lista as versões guardadas
+
+Opções de export:
+      --to <arquivo>       arquivo da exportação (padrão: a saída)
+      --portable           marca cada serviço com os sistemas em que roda e o
+                           tipo do seu comando, para que import possa traduzi-lo
+
+Opções de export-unit:
+      --to <diretório>     grava cada arquivo neste diretório em vez da saída
+
+Opções de generate:
+      --template <nome>    modelo do bloco templates da configuração (obrigatório)
+      --matrix <chave=valores> valores para uma {{chave}} do modelo, separados
+                           por vírgulas; repita para mais chaves, para ter
+                           todas as combinações (padrão: os dos serviços que o
+                           modelo adicionou, para atualizá-los após mudá-lo)
+      --no-verify          não verifica se cada comando existe
+  Serviços adicionados de um modelo são substituídos pelo próximo generate,
+  com edições e tudo; os que saem da matriz são mantidos até serem removidos.
+
+Opções de sync-config:
+      --repo <url>         repositório com que sincronizar; só é preciso na
+                           primeira vez
+      --machine-branch     envia para um branch só desta máquina,
+                           machines/<hostname>, e só baixa o compartilhado
+      --prefer <lado>      resolve mudanças nas mesmas linhas feitas aqui e no
+                           repositório: local ou remote (padrão: parar e perguntar)
+
+Opções de purge:
+      --keep-config        mantém a configuração e os scripts que seus serviços
+                           executam
+
+Opções de importação:
+      --user               lê o crontab do usuário atual (padrão)
+      --file <caminho>     lê um arquivo de crontab como /etc/crontab, ou um XML
+                           de tarefa exportado para import-tasks
+      --folder <caminho>   pasta do Agendador de Tarefas a importar (padrão: \)
+      --take-over          apaga cada tarefa importada quando o nazim passa a
+                           gerenciá-la
+      --stagger <passo>    dá aos serviços importados com o mesmo intervalo
+                           deslocamentos separados por passo, ou auto para
+                           espalhá-los por igual; também aceito por import e
+                           apply
+      --select <quais>     entradas a importar: all, ou números como 1,3-4
+                           (padrão: perguntar após mostrar a prévia); também
+                           escolhe os serviços que import e adopt adicionam
+
+Opções de history:
+      --service <nome>     limpa ou exporta apenas o histórico deste serviço
+      --older-than <dur>   remove execuções mais antigas que dur (ex.: 30d) em vez
+                           de aplicar a retenção configurada
+      --format <formato>   exporta como csv (padrão) ou json
+      --since <quando>     exporta execuções iniciadas a partir de uma data
+                           (2024-01-01), um horário (RFC 3339) ou dur atrás (30d)
+      --until <quando>     exporta execuções iniciadas até o fim de uma data,
+                           antes de um horário ou antes de dur atrás
+      --to <arquivo>       grava a exportação em um arquivo em vez da saída
+
+Opções de config:
+      --passphrase-from <origem>
+                           lê a senha de env:NOME ou file:CAMINHO em vez de
+                           perguntá-la, sempre que a configuração é lida;
+                           necessário para execuções agendadas a lerem
+      --keychain           criptografa com uma chave aleatória guardada no
+                           chaveiro do sistema (Secret Service, chaveiro do
+                           macOS ou DPAPI) em vez de uma senha
+
+Opções globais:
+      --config-dir <dir>   usa um diretório de configuração explícito, que também
+                           guarda scripts, logs e histórico, a menos que seja o padrão
+      --data-dir <dir>     guarda os scripts em dir
+      --state-dir <dir>    guarda os logs e o histórico em dir
+      --lang <idioma>      idioma das mensagens: en ou pt-BR (padrão: do LANG)
+  -v, --verbose        saída detalhada, listando cada comando do agendador
+                       executado com sua duração e código de saída
+  -q, --quiet          suprime mensagens informativas (erros ainda são mostrados)
+      --no-color       desativa as cores (também: NO_COLOR=1)
+  -y, --yes            responde às perguntas sem perguntar (import e adopt pegam
+                       todas as entradas, a menos que --select seja usado, e
+                       purge remove tudo sem confirmar)
+      --strict         trata avisos como erros, como um comando que pode não
+                       existir, uma desinstalação que falhou em edit ou remove,
+                       ou uma opção ou campo de configuração obsoleto
+      --no-update-check  nunca consulta o GitHub pela última versão (também:
+                       NAZIM_NO_UPDATE_CHECK=1)
+  -h, --help           mostra esta ajuda
+      --version        mostra a versão
+
+Ambiente:
+  LANG, LC_ALL      idioma das mensagens, ex.: pt_BR.UTF-8 (LC_MESSAGES também vale)
+  NAZIM_VERBOSE     "1" para saída detalhada
+  NAZIM_TRACE       "1" para gravar também os comandos do agendador e sua saída
+                    em trace.log, no diretório de logs
+  NAZIM_UPLOAD_TOKEN token bearer para enviar a saída a um endpoint HTTP
+                    (veja o bloco upload da configuração)
+  NAZIM_NO_UPDATE_CHECK "1" para nunca procurar a última versão
+  NO_COLOR          definida, desativa as cores
+  XDG_CONFIG_HOME   base do diretório de configuração (padrão: ~/.config no
+                    Linux/macOS, %APPDATA% no Windows)
+  XDG_DATA_HOME     base do diretório de scripts (padrão: ~/.local/share,
+                    %LOCALAPPDATA% no Windows)
+  XDG_STATE_HOME    base do diretório de logs e histórico (padrão: ~/.local/state,
+                    %LOCALAPPDATA% no Windows)
+
+Exemplos:
+  # Comando simples de uma linha
+  nazim add --name cleanup --command "rm -rf /tmp/old_files" --interval 1h
+
+  # Serviço com um arquivo de script
+  nazim add --name backup --command backup.sh --interval 1h
+
+  # Serviço executado na inicialização
+  nazim add --name init --command init.sh --on-startup
+
+  # Serviço executado toda segunda-feira às 09:00
+  nazim add --name report --command report.sh --schedule "every monday 09:00"
+
+  # Modo interativo: abre o editor para escrever o script
+  nazim add --name myscript --command write --interval 30m
+
+  # Gera a documentação a cada 10 minutos, mas só se os fontes mudaram
+  nazim add --name docs --command "make html" --workdir ~/docs --interval 10m --if-changed src
+
+  # Processa arquivos assim que são colocados numa pasta
+  nazim add --name ingest --command ingest.sh --watch-path /srv/drop/incoming
+
+  # Windows: reage sempre que um serviço para ou inicia
+  nazim add --name svc-watch --command svc-watch.ps1 --on-event "Log=System;Source=Service Control Manager;ID=7036"
+
+  # Windows: mantém um daemon rodando como serviço do Windows
+  nazim add --name relay --command C:\relay\relay.exe --long-running
+
+  # macOS: executa um script de manutenção como root no boot, antes de qualquer login
+  sudo nazim add --name cleanup --command /usr/local/bin/cleanup.sh --on-startup --system
+
+  # Script do PowerShell 7, em qualquer plataforma
+  nazim add --name inventory --command inventory.ps1 --shell pwsh --interval 1h
+
+  # Serviço com metadados para quem o encontrar depois
+  nazim add --name backup --command backup.sh --schedule "daily 02:00" \
+    --description "Backup noturno de /srv" --owner ops@example.com --label team=infra
+
+  # Comando com argumentos
+  nazim add --name processor --command python --args "script.py --verbose" --interval 30m
+
+  # Argumentos passados como estão, seja o que for que contenham
+  nazim add --name greet --command python --interval 1h -- greet.py --name "João Silva"
+
+  # Lista todos os serviços
+  nazim list
+
+  # Lista os serviços agrupados por equipe
+  nazim list --tree --group-by label:team
+
+  # Sai com 1 quando um serviço ativado está ausente, falhando ou parado
+  nazim list --check
+
+  # Remove um serviço
+  nazim remove backup
+
+  # Mostra o status de um serviço
+  nazim status backup
+
+  # Experimenta um comando antes de adicioná-lo
+  nazim test --command python --args "job.py --dry" --workdir /srv
+
+  # Altera um serviço
+  nazim edit backup --interval 2h
+
+  # Executa um serviço a cada hora, às e quinze
+  nazim edit backup --interval 1h --offset 15m
+
+  # Troca a inicialização por um intervalo, removendo os argumentos
+  nazim edit backup --no-startup --interval 1h --clear-args
+
+  # Ativa/desativa um serviço
+  nazim enable backup
+  nazim disable backup
+
+  # Executa um serviço imediatamente
+  nazim run backup
+
+  # Ajusta à mão o timer do systemd de um serviço, mantendo-o no nazim
+  nazim export-unit backup --to ./units
+  nazim edit backup --from-unit ./units/nazim-backup.service --from-unit ./units/nazim-backup.timer
+
+  # Desfaz uma edição ruim
+  nazim rollback backup --list
+  nazim rollback backup
+
+  # Salva tudo e restaura em outra máquina
+  nazim backup --to nazim-backup.tar.gz
+  nazim restore nazim-backup.tar.gz
+
+  # Mostra o crontab do usuário e importa as duas primeiras entradas
+  nazim import-cron --select 1-2
+
+  # Importa tarefas de hora em hora do cron espalhadas pela hora, em vez de
+  # todas em :00
+  nazim import-cron --select all --stagger auto
+
+  # Importa as tarefas de uma pasta do Agendador de Tarefas e remove as originais
+  nazim import-tasks --folder \MyTasks --take-over
+
+  # Reconstrói uma configuração perdida a partir das units, agentes ou tarefas
+  nazim adopt --select all
+
+  # Compartilha serviços entre máquinas por um repositório Git e os mantém
+  # sincronizados a cada 15 minutos
+  nazim sync-config --repo git@github.com:me/nazim-config.git
+  nazim add --name config-sync --command "nazim sync-config" --interval 15m
+
+  # Desinstala o nazim por completo, mantendo a configuração para depois
+  nazim purge --keep-config
+
+  # Descarta o histórico com mais de 30 dias
+  nazim history prune --older-than 30d
+
+  # Exporta as execuções do mês passado de todos os serviços para um relatório
+  nazim history export --format csv --since 2024-01-01 --until 2024-01-31 --to runs.csv
+
+  # Criptografa a configuração, lendo a senha de um arquivo para execuções agendadas
+  nazim config encrypt --passphrase-from file:/etc/nazim/passphrase
+
+  # Mostra as últimas 50 linhas do stderr de um serviço
+  nazim logs backup --stream stderr --tail 50
+
+  # Mostra o que cada serviço imprimiu nas últimas 8 horas
+  nazim logs --all --since 8h
+
+  # Faz backup, compacta e envia toda noite, um após o outro
+  nazim pipeline add nightly --steps "db-backup,compress,upload" --interval 24h
+
+  # Sincroniza três repositórios de uma vez, dois por vez
+  nazim add --name repos --parallel-group "sync-api,sync-web,sync-docs" --max-parallel 2 --interval 1h
+
+  # Adiciona backup-a, backup-b e backup-c a partir do modelo backup-tpl, e
+  # depois os atualiza após mudá-lo
+  nazim generate --template backup-tpl --matrix dir=/srv/a,/srv/b,/srv/c
+  nazim generate --template backup-tpl
+
+  # Transmite cada evento em JSON, para um widget de desktop ou coletor de logs
+  nazim events --follow --output json
+
+  # Encontra os minutos em que dois ou mais serviços começam juntos
+  nazim analyze --min-cluster 2
+
+  # Resume as execuções da última semana em JSON
+  nazim stats --output json
+
+  # Verifica scripts alterados fora do nazim e aceita uma edição
+  nazim doctor
+  nazim doctor backup --accept
+
+  # Verifica a cada hora se os serviços continuam rodando, avisando se um parou
+  nazim add --name stale-check --command "nazim doctor --notify" --interval 1h
+
+  # Mantém os serviços rodando num servidor Linux após você sair
+  nazim doctor --enable-linger
+
+  # Deixa o Nagios ou o Icinga acompanhar uma tarefa, como um plugin
+  nazim check backup --format nagios
+
+  # Verifica se há uma versão mais nova
+  nazim version --check
+
+  # Ativa o autocompletar no bash
+  source <(nazim completion bash)
+
+Formato de intervalo:
+  Use s (segundos), m (minutos), h (horas) ou d (dias)
+  Exemplos: 30s, 5m, 1h, 24h
+
+Formato de agenda:
+  <dias> <HH:MM>, em que dias é daily, weekdays, weekends ou uma lista de dias
+  da semana em inglês separados por vírgula (opcionalmente precedida de "every")
+  Exemplos: "daily 03:30", "weekdays 18:00", "every monday 09:00", "every mon,thu at 12:15"
+
+Configuração: ~/.config/nazim/services.yaml (ou equivalente no Windows/macOS)
+`
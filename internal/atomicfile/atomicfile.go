@@ -0,0 +1,35 @@
+// Package atomicfile replaces files in one step, so a concurrent reader sees
+// either the old content or the new, never half of it.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write writes data to path with the given permissions through a temporary
+// file of its own in the same directory, then renames it over path. Writers
+// racing on the same path each rename a complete file; the last one wins.
+func Write(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	// A temporary file is created readable only by its owner
+	if err == nil && perm != 0600 {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checksums.json")
+	if err := Write(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if err := Write(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("Write() over a file error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("content = %q, want new", data)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files in the directory, want no temporary ones left", len(entries))
+	}
+}
+
+func TestWriteMissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "state.json")
+	if err := Write(path, []byte("{}"), 0644); err == nil {
+		t.Errorf("Write() into a missing directory succeeded")
+	}
+}
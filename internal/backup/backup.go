@@ -0,0 +1,349 @@
+// Package backup archives and restores the nazim state directory.
+//
+// An archive is a gzip-compressed tar of the services file, scripts, wrappers,
+// run history, service state, kept versions, checksums, and the events log,
+// plus a manifest recording where and on which OS it was made so restores can
+// remap paths. Each directory of the config is archived under a prefix of its
+// own (see Roots).
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/config"
+)
+
+// ManifestName is the archive entry holding the Manifest.
+const ManifestName = "manifest.json"
+
+// Entries lists the entries included in a backup, each kept in one of the
+// directories of a config (see entryRoot).
+// Logs are left out: they can be large and are not needed to restore services.
+var Entries = []string{
+	"services.yaml", "services.db", "scripts", "wrappers", "history", "services",
+	"snapshots", "versions", "checksums.json", "events.jsonl", "events.jsonl.1",
+}
+
+// Roots are the prefixes the entries of each directory of a config are
+// archived under, so that entries of the same name, such as the scripts of the
+// config and data directories, stay apart. Backups made by older versions
+// have the entries at the top of the archive.
+const (
+	RootConfig = "config"
+	RootData   = "data"
+	RootState  = "state"
+)
+
+// entryRoot returns which directory of a config an entry is kept in.
+func entryRoot(entry string) string {
+	switch entry {
+	case "scripts":
+		return RootData
+	case "services.yaml", "services.db":
+		return RootConfig
+	}
+	return RootState
+}
+
+// rootDir returns the directory of dirs a root stands for.
+func rootDir(dirs config.Dirs, root string) string {
+	switch root {
+	case RootData:
+		return dirs.Data
+	case RootState:
+		return dirs.State
+	}
+	return dirs.Config
+}
+
+// entryDir returns which of dirs an entry is kept in.
+func entryDir(dirs config.Dirs, entry string) string {
+	return rootDir(dirs, entryRoot(entry))
+}
+
+// Manifest describes where a backup was made.
+type Manifest struct {
+	Created   time.Time `json:"created"`
+	Platform  string    `json:"platform"`            // GOOS of the machine that made the backup
+	ConfigDir string    `json:"config_dir"`          // Config directory on that machine
+	DataDir   string    `json:"data_dir,omitempty"`  // Data directory on that machine
+	StateDir  string    `json:"state_dir,omitempty"` // State directory on that machine
+}
+
+// dirs returns the directories of the machine the backup was made on.
+// Backups made by older versions kept everything in the config directory.
+func (m *Manifest) dirs() config.Dirs {
+	dirs := config.Dirs{Config: m.ConfigDir, Data: m.DataDir, State: m.StateDir}
+	if dirs.Data == "" {
+		dirs.Data = m.ConfigDir
+	}
+	if dirs.State == "" {
+		dirs.State = m.ConfigDir
+	}
+	return dirs
+}
+
+// Create writes a backup of the config in dirs to w.
+func Create(w io.Writer, dirs config.Dirs) (*Manifest, error) {
+	manifest := &Manifest{
+		Created:   time.Now(),
+		Platform:  runtime.GOOS,
+		ConfigDir: dirs.Config,
+		DataDir:   dirs.Data,
+		StateDir:  dirs.State,
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling manifest: %w", err)
+	}
+	if err := writeEntry(tw, ManifestName, data, 0644, manifest.Created); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range Entries {
+		if err := addPath(tw, entryRoot(entry), entryDir(dirs, entry), entry); err != nil {
+			return nil, err
+		}
+	}
+	if dirs.Data != dirs.Config {
+		// Scripts of portable services stay in the config directory
+		if err := addPath(tw, RootConfig, dirs.Config, "scripts"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("finishing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("finishing compression: %w", err)
+	}
+	return manifest, nil
+}
+
+// addPath adds a file or directory tree below dir to the archive, under
+// prefix. Missing entries are skipped; not every install has scripts or history.
+func addPath(tw *tar.Writer, prefix, dir, entry string) error {
+	return filepath.Walk(filepath.Join(dir, entry), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", p, err)
+		}
+		return writeEntry(tw, prefix+"/"+filepath.ToSlash(rel), data, int64(info.Mode().Perm()), info.ModTime())
+	})
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, mode int64, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// Check reads a backup through without writing anything and returns its
+// manifest. It fails where Extract would, so a restore can tell the archive
+// is good before it changes anything.
+func Check(r io.Reader) (*Manifest, error) {
+	return read(r, func(header *tar.Header, content io.Reader) error {
+		if _, err := entryPath(config.Dirs{}, header.Name); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, content); err != nil {
+			return fmt.Errorf("reading %s: %w", header.Name, err)
+		}
+		return nil
+	})
+}
+
+// Extract unpacks a backup into dirs and returns its manifest. Each entry goes
+// back to the directory it was archived from; the scripts of older backups
+// all go to the data directory, and Config.Migrate moves those of portable
+// services.
+// Only the entries a backup can contain are written; anything else, including
+// paths escaping dirs, is rejected. An archive not starting with a manifest
+// is rejected before anything is written.
+func Extract(r io.Reader, dirs config.Dirs) (*Manifest, error) {
+	return read(r, func(header *tar.Header, content io.Reader) error {
+		target, err := entryPath(dirs, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("creating directory: %w", err)
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode).Perm())
+		if err != nil {
+			return fmt.Errorf("restoring %s: %w", header.Name, err)
+		}
+		_, copyErr := io.Copy(f, content)
+		closeErr := f.Close()
+		if copyErr != nil {
+			return fmt.Errorf("restoring %s: %w", header.Name, copyErr)
+		}
+		if closeErr != nil {
+			return fmt.Errorf("restoring %s: %w", header.Name, closeErr)
+		}
+		return nil
+	})
+}
+
+// read reads a backup, whose first entry must be the manifest, as Create
+// writes it, and calls fn with each file entry after it.
+func read(r io.Reader, fn func(header *tar.Header, content io.Reader) error) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if manifest == nil {
+			if header.Name != ManifestName {
+				return nil, fmt.Errorf("not a nazim backup: %s missing", ManifestName)
+			}
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("reading manifest: %w", err)
+			}
+			continue
+		}
+		if err := fn(header, tr); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("not a nazim backup: %s missing", ManifestName)
+	}
+	return manifest, nil
+}
+
+// entryPath validates an archive entry name and maps it into dirs.
+func entryPath(dirs config.Dirs, name string) (string, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "\\") {
+		return "", fmt.Errorf("invalid archive entry %q", name)
+	}
+
+	root, rest, _ := strings.Cut(clean, "/")
+	switch root {
+	case RootConfig, RootData, RootState:
+	default:
+		// An older backup, without roots
+		root, rest = "", clean
+	}
+	top, _, _ := strings.Cut(rest, "/")
+	for _, entry := range Entries {
+		if top != entry {
+			continue
+		}
+		switch {
+		case root == "":
+			return filepath.Join(entryDir(dirs, entry), filepath.FromSlash(rest)), nil
+		case root == entryRoot(entry), root == RootConfig && entry == "scripts":
+			return filepath.Join(rootDir(dirs, root), filepath.FromSlash(rest)), nil
+		}
+	}
+	return "", fmt.Errorf("unexpected archive entry %q", name)
+}
+
+// RemapPath rewrites a path under one of the backup's directories to the same
+// location under dirs. Paths elsewhere are returned unchanged, and ok reports
+// whether the path was remapped. Entries are remapped to where they are kept
+// now, so scripts in the config directory of an older backup end up in the
+// data directory, where Extract put them.
+func (m *Manifest) RemapPath(p string, dirs config.Dirs) (remapped string, ok bool) {
+	if p == "" || m.ConfigDir == "" {
+		return p, false
+	}
+
+	old := m.dirs()
+	for _, entry := range Entries {
+		if remapped, ok := m.remapUnder(p, entryDir(old, entry)+"/"+entry, filepath.Join(entryDir(dirs, entry), entry)); ok {
+			return remapped, true
+		}
+	}
+	return m.remapUnder(p, m.ConfigDir, dirs.Config)
+}
+
+// remapUnder rewrites a path under oldRoot, on the machine the backup was made
+// on, to the same location under newRoot.
+// Windows paths are compared case-insensitively and with either separator,
+// so a backup made on one OS can be restored on another.
+func (m *Manifest) remapUnder(p, oldRoot, newRoot string) (remapped string, ok bool) {
+	oldRoot = strings.TrimRight(strings.ReplaceAll(oldRoot, "\\", "/"), "/")
+	candidate := strings.ReplaceAll(p, "\\", "/")
+	if m.Platform != "windows" {
+		// Backslashes are ordinary filename characters outside Windows
+		candidate = p
+	}
+
+	prefix := candidate
+	if len(prefix) > len(oldRoot) {
+		prefix = prefix[:len(oldRoot)]
+	}
+	matches := prefix == oldRoot
+	if m.Platform == "windows" {
+		matches = strings.EqualFold(prefix, oldRoot)
+	}
+	if !matches {
+		return p, false
+	}
+
+	rest := candidate[len(prefix):]
+	if rest != "" && rest[0] != '/' {
+		// Shares a prefix but is a sibling directory, e.g. "nazim-old"
+		return p, false
+	}
+	return filepath.Join(newRoot, filepath.FromSlash(strings.TrimPrefix(rest, "/"))), true
+}
@@ -0,0 +1,234 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/config"
+)
+
+func tempDirs(t *testing.T) config.Dirs {
+	root := t.TempDir()
+	return config.Dirs{
+		Config: filepath.Join(root, "config"),
+		Data:   filepath.Join(root, "data"),
+		State:  filepath.Join(root, "state"),
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := tempDirs(t)
+	files := map[string]string{
+		filepath.Join(src.Config, "services.yaml"):                "services: []\n",
+		filepath.Join(src.Config, "scripts", "run.sh"):            "portable\n",
+		filepath.Join(src.Data, "scripts", "run.sh"):              "data\n",
+		filepath.Join(src.State, "history", "job.jsonl"):          "{}\n",
+		filepath.Join(src.State, "services", "job.json"):          "{}\n",
+		filepath.Join(src.State, "versions", "job.json"):          "[]\n",
+		filepath.Join(src.State, "snapshots", "polls", "job.txt"): "poll\n",
+		filepath.Join(src.State, "checksums.json"):                "{}\n",
+		filepath.Join(src.State, "events.jsonl"):                  "{}\n",
+	}
+	for path, content := range files {
+		writeFile(t, path, content)
+	}
+	writeFile(t, filepath.Join(src.State, "logs", "job.stdout.log"), "not backed up\n")
+
+	var archive bytes.Buffer
+	if _, err := Create(&archive, src); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	dst := tempDirs(t)
+	manifest, err := Extract(&archive, dst)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if manifest.ConfigDir != src.Config || manifest.StateDir != src.State {
+		t.Errorf("manifest dirs = %q, %q, want %q, %q", manifest.ConfigDir, manifest.StateDir, src.Config, src.State)
+	}
+	for path, want := range files {
+		restored := strings.NewReplacer(src.Config, dst.Config, src.Data, dst.Data, src.State, dst.State).Replace(path)
+		got, err := os.ReadFile(restored)
+		if err != nil {
+			t.Errorf("%s not restored: %v", restored, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", restored, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst.State, "logs")); !os.IsNotExist(err) {
+		t.Errorf("logs were restored, want them left out")
+	}
+}
+
+// archiveOf returns a backup holding the manifest and the given entries.
+func archiveOf(t *testing.T, entries map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	manifest := `{"platform": "linux", "config_dir": "/old"}`
+	if err := writeEntry(tw, ManifestName, []byte(manifest), 0644, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range entries {
+		if err := writeEntry(tw, name, []byte(content), 0644, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractOlderBackup(t *testing.T) {
+	dst := tempDirs(t)
+	archive := archiveOf(t, map[string]string{
+		"services.yaml":     "services: []\n",
+		"scripts/run.sh":    "script\n",
+		"history/job.jsonl": "{}\n",
+	})
+	if _, err := Extract(archive, dst); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	for _, path := range []string{
+		filepath.Join(dst.Config, "services.yaml"),
+		filepath.Join(dst.Data, "scripts", "run.sh"),
+		filepath.Join(dst.State, "history", "job.jsonl"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s not restored: %v", path, err)
+		}
+	}
+}
+
+func TestEntryPath(t *testing.T) {
+	dirs := config.Dirs{Config: "/c", Data: "/d", State: "/s"}
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"config/services.yaml", filepath.Join("/c", "services.yaml"), false},
+		{"config/scripts/a.sh", filepath.Join("/c", "scripts", "a.sh"), false},
+		{"data/scripts/a.sh", filepath.Join("/d", "scripts", "a.sh"), false},
+		{"state/versions/job.json", filepath.Join("/s", "versions", "job.json"), false},
+		{"state/checksums.json", filepath.Join("/s", "checksums.json"), false},
+		{"scripts/a.sh", filepath.Join("/d", "scripts", "a.sh"), false},
+		{"versions/job.json", filepath.Join("/s", "versions", "job.json"), false},
+		{"state/services.yaml", "", true},
+		{"data/history/job.jsonl", "", true},
+		{"config/../../etc/passwd", "", true},
+		{"../etc/passwd", "", true},
+		{"/etc/passwd", "", true},
+		{"logs/job.stdout.log", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := entryPath(dirs, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("entryPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("entryPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// rawArchive returns a tar.gz holding entries in order, given as name, content.
+func rawArchive(t *testing.T, entries ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i+1 < len(entries); i += 2 {
+		if err := writeEntry(tw, entries[i], []byte(entries[i+1]), 0644, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractRejectsBeforeWriting(t *testing.T) {
+	manifest := `{"platform": "linux", "config_dir": "/old"}`
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{"garbage", []byte("garbage"), "reading archive"},
+		{"no manifest", rawArchive(t, "services.yaml", "services: []\n"), "not a nazim backup"},
+		{"manifest last", rawArchive(t, "services.yaml", "services: []\n", ManifestName, manifest), "not a nazim backup"},
+		{"empty", rawArchive(t), "not a nazim backup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := tempDirs(t)
+			if _, err := Extract(bytes.NewReader(tt.archive), dst); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Extract() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(dst.Config, "services.yaml")); !os.IsNotExist(err) {
+				t.Errorf("services.yaml was written: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	manifest := `{"platform": "linux", "config_dir": "/old"}`
+	good := rawArchive(t, ManifestName, manifest, "config/services.yaml", strings.Repeat("services: []\n", 1000))
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{"good", good, ""},
+		{"garbage", []byte("garbage"), "reading archive"},
+		{"truncated", good[:len(good)/2], "unexpected EOF"},
+		{"no manifest", rawArchive(t, "services.yaml", "services: []\n"), "not a nazim backup"},
+		{"unexpected entry", rawArchive(t, ManifestName, manifest, "../etc/passwd", "root"), "invalid archive entry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Check(bytes.NewReader(tt.archive))
+			if tt.wantErr == "" {
+				if err != nil || m.ConfigDir != "/old" {
+					t.Errorf("Check() = %+v, %v, want the manifest", m, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
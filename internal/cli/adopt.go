@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/importer"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/state"
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// Adopt finds the unit files, agents, or tasks nazim installed for services
+// that are missing from the config, such as after the config was lost or the
+// user was renamed, and adds them back. selection works as for ImportCron.
+//
+// Definitions that run `nazim exec` only name their service, so the command is
+// recovered from the config directory they point to when it still exists, or
+// from the script nazim created for the service.
+func (c *CLI) Adopt(ctx context.Context, selection string, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	defs, err := discoverDefinitions()
+	if err != nil {
+		return err
+	}
+	if platform.RequiresElevation() {
+		return fmt.Errorf("adopt installs several services; run it from an elevated (administrator) prompt")
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	var entries []*importer.Entry
+	configured := 0
+	for _, def := range defs {
+		if _, err := c.cfg.GetService(def.Service.Name); err == nil {
+			configured++
+			continue
+		}
+		entries = append(entries, c.adoptEntry(platformMgr, def))
+	}
+	if configured > 0 && verbose {
+		c.infof("Skipped %d definition(s) of services already in %s.\n", configured, c.cfg.GetConfigPath())
+	}
+	if len(entries) == 0 {
+		c.infoln("No definitions of unknown services found.")
+		return nil
+	}
+	return c.importEntries(entries, selection, 0, false, verbose, func(entry *importer.Entry) error {
+		_, err := state.Update(c.cfg.GetServiceStateDir(), entry.Service.Name, func(s *state.State) {
+			s.AdoptedAt = time.Now()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record its adoption: %w", err)
+		}
+		return nil
+	})
+}
+
+// adoptEntry converts a discovered definition to an import entry, recovering
+// the command of `nazim exec` definitions.
+func (c *CLI) adoptEntry(platformMgr platform.Manager, def *importer.Definition) *importer.Entry {
+	entry := &importer.Entry{Source: filepath.Base(def.Source), Problem: def.Problem}
+	if def.Problem != "" {
+		return entry
+	}
+	svc := def.Service
+	entry.When = describeTrigger(svc)
+
+	if state, err := platformMgr.GetTaskState(svc.Name); err == nil && !state.Enabled {
+		svc.Enabled = false
+	}
+
+	if def.Exec {
+		if recovered := recoverService(def.ConfigDir, svc.Name); recovered != nil {
+			// The config it came from is authoritative; only the state is the scheduler's
+			recovered.Enabled = svc.Enabled
+			svc = recovered
+			entry.Problem = "recovered from " + filepath.Join(def.ConfigDir, "services.yaml")
+		} else if script := c.findScript([]string{def.DataDir, def.ConfigDir}, svc.Name); script != "" {
+			svc.Command = script
+			entry.Problem = "recovered the service's script only"
+		} else {
+			entry.Command = "nazim exec " + svc.Name
+			entry.Problem = "its config and script are gone, so the command can't be recovered"
+			return entry
+		}
+	}
+	entry.Command = commandLine(svc)
+
+	if err := c.cfg.ValidateService(svc); err != nil {
+		entry.Problem = err.Error()
+		return entry
+	}
+	entry.Service = svc
+	return entry
+}
+
+// recoverService looks a service up in the config at dir, returning nil if the
+// config or the service is gone.
+func recoverService(dir, name string) *service.Service {
+	if dir == "" {
+		return nil
+	}
+	// NewAt creates its directory, so check first that the config still exists
+	if _, err := os.Stat(filepath.Join(dir, "services.yaml")); err != nil {
+		return nil
+	}
+	cfg, err := config.NewAt(dir)
+	if err != nil {
+		return nil
+	}
+	svc, err := cfg.GetService(name)
+	if err != nil {
+		return nil
+	}
+	recovered := *svc.ResolvePaths(dir)
+	recovered.Portable = false
+	return &recovered
+}
+
+// findScript returns the script nazim created for a service, in the data or
+// config directory a definition points to, or in ours.
+func (c *CLI) findScript(defDirs []string, name string) string {
+	var dirs []string
+	for _, dir := range defDirs {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "scripts"))
+		}
+	}
+	dirs = append(dirs, c.cfg.GetScriptsDir(), c.cfg.GetPortableScriptsDir())
+	for _, scriptsDir := range dirs {
+		for _, ext := range []string{".sh", ".bat", ".ps1"} {
+			path := filepath.Join(scriptsDir, name+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// discoverDefinitions reads back every definition nazim installed for the
+// current user.
+func discoverDefinitions() ([]*importer.Definition, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		units, err := filepath.Glob(filepath.Join(home, ".config", "systemd", "user", "nazim-*.service"))
+		if err != nil {
+			return nil, err
+		}
+		var defs []*importer.Definition
+		for _, unit := range units {
+			data, err := os.ReadFile(unit)
+			if err != nil {
+				return nil, fmt.Errorf("reading unit: %w", err)
+			}
+			timer, err := os.ReadFile(strings.TrimSuffix(unit, ".service") + ".timer")
+			if err != nil {
+				timer = nil
+			}
+			defs = append(defs, importer.ParseSystemdUnits(unit, data, timer))
+		}
+		return defs, nil
+
+	case "darwin":
+		plists, err := filepath.Glob(filepath.Join(home, "Library", "LaunchAgents", "com.nazim.*.plist"))
+		if err != nil {
+			return nil, err
+		}
+		var defs []*importer.Definition
+		for _, plist := range plists {
+			data, err := os.ReadFile(plist)
+			if err != nil {
+				return nil, fmt.Errorf("reading agent: %w", err)
+			}
+			defs = append(defs, importer.ParseLaunchdPlist(plist, data))
+		}
+		return defs, nil
+
+	case "windows":
+		data, err := trace.Output(exec.Command("schtasks", "/query", "/xml", "ONE"))
+		if err != nil {
+			return nil, fmt.Errorf("querying scheduled tasks: %w", err)
+		}
+		defs, err := importer.ParseNazimTasks(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		for _, def := range defs {
+			if def.Problem != "" {
+				continue
+			}
+			wrapper, err := os.ReadFile(def.Wrapper)
+			if err != nil {
+				def.Problem = fmt.Sprintf("wrapper %s can't be read", def.Wrapper)
+				continue
+			}
+			def.ParseWrapper(wrapper)
+		}
+		return defs, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+}
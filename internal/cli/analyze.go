@@ -0,0 +1,346 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/schedule"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// analyzeWindow is how far ahead Analyze looks.
+const analyzeWindow = 24 * time.Hour
+
+// staggerStep is how far apart Analyze suggests moving services that start together.
+const staggerStep = 5 * time.Minute
+
+// DefaultMinCluster is how many services starting in the same minute Analyze
+// reports by default.
+const DefaultMinCluster = 3
+
+// startKind is what starts a service at a given time.
+type startKind int
+
+const (
+	startSchedule startKind = iota
+	startInterval
+	startBoot
+	startLogon
+)
+
+// cluster is a set of services that start in the same minute, at one or more times.
+type cluster struct {
+	services []string
+	kinds    map[string]startKind
+	times    []time.Time
+	offset   time.Duration // For boot and logon clusters, the delay they start after
+	trigger  startKind     // startBoot or startLogon for those, otherwise startSchedule
+}
+
+// Analyze computes when the enabled services start over the next 24 hours and
+// reports the minutes in which at least minCluster of them start together,
+// including at boot and logon, with staggered settings that would spread them.
+// Services running every minute or more often are left out, since they start
+// with everything.
+func (c *CLI) Analyze(ctx context.Context, minCluster int, verbose bool) error {
+	if minCluster < 2 {
+		return fmt.Errorf("--min-cluster must be at least 2")
+	}
+
+	var services []*service.Service
+	for _, svc := range c.cfg.ListServices() {
+		if svc.Enabled && svc.CheckPlatform(runtime.GOOS) == nil {
+			services = append(services, svc)
+		}
+	}
+	if len(services) == 0 {
+		c.infoln("No enabled services found.")
+		return nil
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	now := time.Now()
+	end := now.Add(analyzeWindow)
+	minutes := make(map[time.Time]map[string]startKind)
+	add := func(t time.Time, name string, kind startKind) {
+		minute := t.Truncate(time.Minute)
+		if minutes[minute] == nil {
+			minutes[minute] = make(map[string]startKind)
+		}
+		if _, ok := minutes[minute][name]; !ok {
+			minutes[minute][name] = kind
+		}
+	}
+
+	var frequent, estimated []string
+	starts := 0
+	bootOffsets := make(map[startKind]map[time.Duration][]string)
+	for _, svc := range services {
+		if svc.OnStartup || svc.OnLogon {
+			kind := startBoot
+			if svc.OnLogon {
+				kind = startLogon
+			}
+			if bootOffsets[kind] == nil {
+				bootOffsets[kind] = make(map[time.Duration][]string)
+			}
+			offset := svc.EffectiveStartupDelay().Truncate(time.Minute)
+			bootOffsets[kind][offset] = append(bootOffsets[kind][offset], svc.Name)
+		}
+
+		sched := svc.GetSchedule()
+		if sched != nil {
+			for t := sched.Next(now); !t.IsZero() && t.Before(end); t = sched.Next(t) {
+				add(t, svc.Name, startSchedule)
+				starts++
+			}
+		}
+
+		interval := svc.GetInterval()
+		if interval <= 0 {
+			continue
+		}
+		if interval <= time.Minute {
+			frequent = append(frequent, svc.Name)
+			continue
+		}
+		if _, ok := svc.GetOffset(); ok {
+			// Aligned to the clock, so the runs are known whatever the scheduler says
+			for t := svc.NextAlignedRun(now); !t.IsZero() && t.Before(end); t = svc.NextAlignedRun(t) {
+				add(t, svc.Name, startInterval)
+				starts++
+			}
+			continue
+		}
+		first, err := platformMgr.NextRun(svc)
+		if err != nil && verbose {
+			c.warning("failed to query next run of '%s': %v", svc.Name, err)
+		}
+		if first.IsZero() || first.Before(now) || onSchedule(sched, first) {
+			// The scheduler doesn't say where the interval is at, so assume it starts now
+			first = now.Add(interval)
+			estimated = append(estimated, svc.Name)
+		}
+		for t := first; t.Before(end); t = t.Add(interval) {
+			add(t, svc.Name, startInterval)
+			starts++
+		}
+	}
+
+	clusters := findClusters(minutes, minCluster)
+	for _, kind := range []startKind{startBoot, startLogon} {
+		for offset, names := range bootOffsets[kind] {
+			if len(names) < minCluster {
+				continue
+			}
+			sort.Strings(names)
+			kinds := make(map[string]startKind, len(names))
+			for _, name := range names {
+				kinds[name] = kind
+			}
+			clusters = append(clusters, &cluster{services: names, kinds: kinds, offset: offset, trigger: kind})
+		}
+	}
+	sortClusters(clusters)
+
+	busiest, busiestCount := time.Time{}, 0
+	for minute, names := range minutes {
+		if len(names) > busiestCount || (len(names) == busiestCount && minute.Before(busiest)) {
+			busiest, busiestCount = minute, len(names)
+		}
+	}
+	fmt.Fprintf(c.out, "Next 24 hours: %d start(s) of %d service(s)", starts, len(services))
+	if busiestCount > 1 {
+		fmt.Fprintf(c.out, "; busiest minute %s with %d", formatRunTime(busiest, now), busiestCount)
+	}
+	fmt.Fprintln(c.out)
+
+	if len(clusters) == 0 {
+		fmt.Fprintf(c.out, "No %d or more services start in the same minute.\n", minCluster)
+	} else {
+		fmt.Fprintf(c.out, "\n%d or more services start in the same minute:\n\n", minCluster)
+		t := &table{columns: []column{{header: "WHEN"}, {header: "TIMES"}, {header: "SERVICES", flex: true}}}
+		for _, cl := range clusters {
+			times := "-"
+			if len(cl.times) > 0 {
+				times = fmt.Sprint(len(cl.times))
+			}
+			t.addRow(cl.when(now), times, strings.Join(cl.services, ", "))
+		}
+		width := term.Width(c.out)
+		if width == 0 {
+			width = defaultWidth
+		}
+		c.renderTable(t, width)
+	}
+
+	if len(frequent) > 0 {
+		sort.Strings(frequent)
+		fmt.Fprintf(c.out, "\nLeft out, as they run every minute or more often: %s\n", strings.Join(frequent, ", "))
+	}
+	if len(estimated) > 0 {
+		sort.Strings(estimated)
+		fmt.Fprintf(c.out, "\nThe scheduler doesn't report when these run next, so their intervals are assumed to start now: %s\n",
+			strings.Join(estimated, ", "))
+	}
+
+	if suggestions, pinned := c.suggestStagger(clusters); len(suggestions) > 0 || len(pinned) > 0 {
+		fmt.Fprintln(c.out, "\nTo spread them out:")
+		for _, suggestion := range suggestions {
+			fmt.Fprintf(c.out, "  %s\n", suggestion)
+		}
+		if len(pinned) > 0 {
+			fmt.Fprintf(c.out, "  %s start on intervals that can't take an offset; give them schedules if they must not overlap.\n",
+				strings.Join(pinned, ", "))
+		}
+	}
+	return nil
+}
+
+// onSchedule reports whether t is a fire time of sched.
+func onSchedule(sched *schedule.Schedule, t time.Time) bool {
+	if sched == nil {
+		return false
+	}
+	t = t.Local()
+	if sched.Location != nil {
+		t = t.In(sched.Location)
+	}
+	return sched.Matches(t.Weekday()) && t.Hour() == sched.Hour && t.Minute() == sched.Minute
+}
+
+// findClusters groups the minutes in which at least minCluster services start
+// by the set of services starting in them.
+func findClusters(minutes map[time.Time]map[string]startKind, minCluster int) []*cluster {
+	byServices := make(map[string]*cluster)
+	var clusters []*cluster
+	for minute, kinds := range minutes {
+		if len(kinds) < minCluster {
+			continue
+		}
+		names := make([]string, 0, len(kinds))
+		for name := range kinds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		key := strings.Join(names, "\x00")
+		cl := byServices[key]
+		if cl == nil {
+			cl = &cluster{services: names, kinds: kinds}
+			byServices[key] = cl
+			clusters = append(clusters, cl)
+		}
+		cl.times = append(cl.times, minute)
+	}
+	for _, cl := range clusters {
+		sort.Slice(cl.times, func(i, j int) bool { return cl.times[i].Before(cl.times[j]) })
+	}
+	return clusters
+}
+
+// sortClusters orders clusters by size, largest first, then boot and logon
+// clusters by delay and the others by their first time.
+func sortClusters(clusters []*cluster) {
+	sort.Slice(clusters, func(i, j int) bool {
+		a, b := clusters[i], clusters[j]
+		if len(a.services) != len(b.services) {
+			return len(a.services) > len(b.services)
+		}
+		if a.trigger != b.trigger {
+			return a.trigger < b.trigger
+		}
+		if len(a.times) == 0 || len(b.times) == 0 {
+			return a.offset < b.offset
+		}
+		return a.times[0].Before(b.times[0])
+	})
+}
+
+// when describes when a cluster's services start together.
+func (cl *cluster) when(now time.Time) string {
+	switch cl.trigger {
+	case startBoot, startLogon:
+		label := "boot"
+		if cl.trigger == startLogon {
+			label = "logon"
+		}
+		if cl.offset > 0 {
+			label += " +" + formatDuration(cl.offset)
+		}
+		return label
+	}
+	when := formatRunTime(cl.times[0], now)
+	if len(cl.times) > 1 {
+		when += " and later"
+	}
+	return when
+}
+
+// suggestStagger returns the edits that would move all but the first service of
+// each cluster a few minutes apart: schedules are moved later, or earlier where
+// that would cross midnight, startup delays are lengthened, and intervals are
+// given offsets. Services whose intervals can't take an offset can't be moved
+// and are returned separately. Each service is moved at most once.
+func (c *CLI) suggestStagger(clusters []*cluster) (suggestions, pinned []string) {
+	moved := make(map[string]bool)
+	for _, cl := range clusters {
+		shift := time.Duration(0)
+		kept := false
+		for _, name := range cl.services {
+			if moved[name] {
+				continue
+			}
+			svc, err := c.cfg.GetService(name)
+			if err != nil {
+				continue
+			}
+			if !kept {
+				// The first one stays where it is
+				kept, moved[name] = true, true
+				continue
+			}
+			shift += staggerStep
+
+			switch cl.kinds[name] {
+			case startSchedule:
+				sched := *svc.GetSchedule()
+				minutes := sched.Hour*60 + sched.Minute + int(shift/time.Minute)
+				if minutes >= 24*60 {
+					minutes = sched.Hour*60 + sched.Minute - int(shift/time.Minute)
+				}
+				sched.Hour, sched.Minute = minutes/60, minutes%60
+				suggestions = append(suggestions, fmt.Sprintf("nazim edit %s --schedule %q", name, sched.String()))
+			case startBoot, startLogon:
+				delay := svc.StartupDelay.Duration + shift
+				value := formatDuration(delay)
+				if delay%time.Minute != 0 {
+					// Durations take a single unit
+					value = fmt.Sprintf("%ds", int(delay.Seconds()))
+				}
+				suggestions = append(suggestions, fmt.Sprintf("nazim edit %s --startup-delay %s", name, value))
+			default:
+				interval := svc.GetInterval()
+				if !service.Alignable(interval) || svc.OnStartup || svc.OnLogon || len(cl.times) == 0 {
+					pinned = append(pinned, name)
+					break
+				}
+				// Align the interval to where it starts now, then move it along
+				start := cl.times[0].Local()
+				pastMidnight := time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+				offset := (pastMidnight + shift) % interval
+				suggestions = append(suggestions, fmt.Sprintf("nazim edit %s --offset %s", name, service.FormatOffset(offset)))
+			}
+			moved[name] = true
+		}
+	}
+	return suggestions, pinned
+}
@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/events"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/progress"
+	"github.com/calilkhalil/nazim/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// Apply installs the services of a bundle distributed by an administrator,
+// after verifying its signature in <bundle>.sig against the policy's trusted
+// keys. Services the bundle defines are added or updated, and services the
+// previously applied bundle defined but this one doesn't are removed. In
+// managed mode, unsigned bundles are refused; otherwise they are applied with
+// a warning. With staggerValue, services of the bundle sharing an interval are
+// given offsets that spread them out (see stagger).
+func (c *CLI) Apply(ctx context.Context, path, staggerValue string, verbose bool) error {
+	if platform.RequiresElevation() {
+		return fmt.Errorf("apply installs several services; run it from an elevated (administrator) prompt")
+	}
+	step, auto, err := parseStagger(staggerValue)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading bundle: %w", err)
+	}
+	sig, err := os.ReadFile(path + ".sig")
+	switch {
+	case err == nil:
+		if err := c.cfg.VerifySignature(data, sig); err != nil {
+			if c.cfg.Managed() {
+				return fmt.Errorf("bundle %s: %w", path, err)
+			}
+			if err := c.warn("bundle %s can't be verified: %v", path, err); err != nil {
+				return err
+			}
+			sig = nil
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("reading bundle signature: %w", err)
+	case c.cfg.Managed():
+		return fmt.Errorf("bundle %s is not signed (%s.sig is missing), and %s only allows signed bundles",
+			path, path, config.PolicyPath())
+	default:
+		if err := c.warn("bundle %s is not signed", path); err != nil {
+			return err
+		}
+	}
+
+	services, err := config.ParseBundle(data)
+	if err != nil {
+		return err
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	var local []*service.Service
+	for _, svc := range services {
+		if svc.Platform == "" {
+			svc.Platform = runtime.GOOS
+		}
+		if svc.CheckPlatform(runtime.GOOS) == nil {
+			local = append(local, svc)
+		}
+	}
+	c.reportStagger(stagger(local, step, auto), verbose)
+
+	// Services of the last bundle are the ones it may remove
+	var previous []*service.Service
+	if old, err := os.ReadFile(c.cfg.BundlePath()); err == nil {
+		previous, _ = config.ParseBundle(old)
+	}
+
+	var sp *progress.Spinner
+	platformMgr, err := c.newManager(platform.WithProgress(func(step string) {
+		if sp != nil {
+			sp.Step(step)
+		}
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	var added, updated, unchanged, removed int
+	var failed []string
+	inBundle := make(map[string]bool)
+	// Save the changes to the config in one go rather than after each service
+	err = c.cfg.Tx(func() error {
+		for _, svc := range services {
+			inBundle[svc.Name] = true
+			if err := svc.CheckPlatform(runtime.GOOS); err != nil {
+				if verbose {
+					c.infof("Skipping '%s': %v\n", svc.Name, err)
+				}
+				continue
+			}
+			if err := c.cfg.ValidateService(svc); err != nil {
+				c.warning("service '%s' is invalid: %v", svc.Name, err)
+				failed = append(failed, svc.Name)
+				continue
+			}
+
+			existing, err := c.cfg.GetService(svc.Name)
+			if err == nil && sameDefinition(existing, svc) {
+				unchanged++
+				continue
+			}
+
+			sp = c.startProgress(fmt.Sprintf("Installing '%s'", svc.Name), verbose)
+			if existing != nil {
+				err = c.replaceService(platformMgr, svc)
+			} else {
+				err = c.installBundled(platformMgr, svc)
+			}
+			sp.Stop()
+			if err != nil {
+				c.warning("failed to install '%s': %v", svc.Name, err)
+				failed = append(failed, svc.Name)
+				continue
+			}
+			if !svc.Enabled {
+				if err := platformMgr.Disable(svc.Name); err != nil {
+					c.warning("failed to disable '%s': %v", svc.Name, err)
+				}
+			}
+			if existing != nil {
+				updated++
+				c.serviceEvent(events.ServiceUpdated, svc.Name)
+			} else {
+				added++
+				c.serviceEvent(events.ServiceAdded, svc.Name)
+			}
+			if verbose {
+				c.infof("Applied '%s'\n", svc.Name)
+			}
+		}
+
+		for _, svc := range previous {
+			if inBundle[svc.Name] {
+				continue
+			}
+			if _, err := c.cfg.GetService(svc.Name); err != nil {
+				continue
+			}
+			if err := platformMgr.Uninstall(svc.Name); err != nil {
+				c.warning("failed to uninstall '%s': %v", svc.Name, err)
+			}
+			if err := c.cfg.RemoveService(svc.Name); err != nil {
+				c.warning("failed to remove '%s' from the config: %v", svc.Name, err)
+				failed = append(failed, svc.Name)
+				continue
+			}
+			removed++
+			c.serviceEvent(events.ServiceRemoved, svc.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving applied services: %w", err)
+	}
+
+	// Keep the bundle for the next apply, and for exec to check services against
+	if err := os.WriteFile(c.cfg.BundlePath(), data, 0644); err != nil {
+		return fmt.Errorf("saving applied bundle: %w", err)
+	}
+	sigPath := c.cfg.BundlePath() + ".sig"
+	if sig != nil {
+		err = os.WriteFile(sigPath, sig, 0644)
+	} else if err = os.Remove(sigPath); os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("saving applied bundle signature: %w", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to apply: %s", strings.Join(failed, ", "))
+	}
+	c.infof("Applied %s: %d added, %d updated, %d removed, %d unchanged.\n", path, added, updated, removed, unchanged)
+	return nil
+}
+
+// installBundled installs a service of a bundle and adds it to the config.
+// Unlike installNew, the config's defaults block isn't applied: a bundle
+// service runs as signed.
+func (c *CLI) installBundled(platformMgr platform.Manager, svc *service.Service) error {
+	if err := platformMgr.Install(svc); err != nil {
+		return fmt.Errorf("failed to install service: %w", err)
+	}
+	if err := c.cfg.AddService(svc); err != nil {
+		if uninstallErr := platformMgr.Uninstall(svc.Name); uninstallErr != nil {
+			return fmt.Errorf("failed to add service to config: %w (also failed to uninstall: %v)", err, uninstallErr)
+		}
+		return fmt.Errorf("failed to add service to config: %w", err)
+	}
+	return nil
+}
+
+// replaceService reinstalls a configured service with a bundle's definition of it.
+func (c *CLI) replaceService(platformMgr platform.Manager, svc *service.Service) error {
+	if err := platformMgr.Uninstall(svc.Name); err != nil {
+		if err := c.warn("failed to uninstall old service: %v", err); err != nil {
+			return err
+		}
+	}
+	if err := platformMgr.Install(svc); err != nil {
+		return fmt.Errorf("failed to install service: %w", err)
+	}
+	return c.cfg.UpdateService(svc)
+}
+
+// sameDefinition reports whether two service definitions are the same.
+func sameDefinition(a, b *service.Service) bool {
+	x, errX := yaml.Marshal(a)
+	y, errY := yaml.Marshal(b)
+	return errX == nil && errY == nil && string(x) == string(y)
+}
+
+// checkUnmanaged returns an error if the policy only lets services change
+// through signed bundles.
+func (c *CLI) checkUnmanaged() error {
+	if c.cfg.Managed() {
+		return fmt.Errorf("services on this machine are managed by %s and can only be changed by applying a signed bundle (nazim apply)",
+			config.PolicyPath())
+	}
+	return nil
+}
+
+// checkSigned returns an error if svc isn't defined as it is in the applied
+// signed bundle, so services added or changed by hand don't run in managed mode.
+func (c *CLI) checkSigned(svc *service.Service) error {
+	bundled, err := c.cfg.AppliedBundle()
+	if errors.Is(err, config.ErrUnsigned) {
+		return fmt.Errorf("%w, and %s only allows services from one", err, config.PolicyPath())
+	}
+	if err != nil {
+		return err
+	}
+	for _, b := range bundled {
+		if b.Name != svc.Name {
+			continue
+		}
+		if b.Platform == "" {
+			b.Platform = runtime.GOOS
+		}
+		if b.Offset == "" {
+			// apply --stagger may have given it one, which only moves its runs
+			b.Offset = svc.Offset
+		}
+		if !sameDefinition(b, svc) {
+			return fmt.Errorf("it differs from its definition in the signed bundle; apply the bundle again")
+		}
+		return nil
+	}
+	return fmt.Errorf("it isn't in the signed bundle")
+}
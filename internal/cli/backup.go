@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/backup"
+	"github.com/calilkhalil/nazim/internal/events"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/progress"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Backup archives the config, scripts, wrappers, run history, and service state to a .tar.gz file.
+// An empty path writes nazim-backup-<timestamp>.tar.gz in the current directory.
+func (c *CLI) Backup(ctx context.Context, to string, verbose bool) error {
+	if to == "" {
+		to = fmt.Sprintf("nazim-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	f, err := os.Create(to)
+	if err != nil {
+		return fmt.Errorf("creating backup file: %w", err)
+	}
+
+	if _, err := backup.Create(f, c.cfg.Dirs()); err != nil {
+		f.Close()
+		os.Remove(to)
+		return fmt.Errorf("creating backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing backup file: %w", err)
+	}
+
+	c.infof("Backed up %d service(s) to %s\n", len(c.cfg.UserServices()), to)
+	return nil
+}
+
+// Restore unpacks a backup into the config, data, and state directories and
+// reinstalls every service on the current platform. Paths inside the backup's
+// directories are remapped to this machine's; a backup from another OS is restored with warnings for what
+// is unlikely to work unchanged. Services set up on another OS that have no
+// override for this one are left uninstalled, unless forcePlatform is set.
+// Restoring over existing services requires force, which uninstalls them
+// once the backup has been read through and found good.
+func (c *CLI) Restore(ctx context.Context, from string, force, forcePlatform, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	if existing := c.cfg.UserServices(); len(existing) > 0 && !force {
+		return fmt.Errorf("config already has %d service(s); use --force to replace them", len(existing))
+	}
+	if platform.RequiresElevation() {
+		return fmt.Errorf("restore installs several services; run it from an elevated (administrator) prompt")
+	}
+
+	f, err := os.Open(from)
+	if err != nil {
+		return fmt.Errorf("opening backup: %w", err)
+	}
+	defer f.Close()
+
+	// Nothing is uninstalled until the whole archive is known to be good
+	if _, err := backup.Check(f); err != nil {
+		return fmt.Errorf("restoring backup: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("reading backup: %w", err)
+	}
+
+	// Each service gets its own spinner so warnings print between them cleanly
+	var sp *progress.Spinner
+	platformMgr, err := c.newManager(platform.WithProgress(func(step string) {
+		if sp != nil {
+			sp.Step(step)
+		}
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	for _, svc := range c.cfg.UserServices() {
+		if err := platformMgr.Uninstall(svc.Name); err != nil {
+			if c.strict {
+				return fmt.Errorf("failed to uninstall '%s': %w", svc.Name, err)
+			}
+			if verbose {
+				c.warning("failed to uninstall '%s': %v", svc.Name, err)
+			}
+		}
+	}
+
+	manifest, err := backup.Extract(f, c.cfg.Dirs())
+	if err != nil {
+		return fmt.Errorf("restoring backup: %w", err)
+	}
+	if err := c.cfg.Load(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("loading restored config: %w", err)
+	}
+	if err := c.cfg.Migrate(); err != nil {
+		return fmt.Errorf("placing restored scripts: %w", err)
+	}
+
+	crossOS := manifest.Platform != runtime.GOOS
+	if crossOS {
+		c.warning("backup was made on %s; services are reinstalled for %s and may need adjusting",
+			manifest.Platform, runtime.GOOS)
+	}
+
+	services := c.cfg.UserServices()
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	var failed []string
+	// Save the restored services in one go rather than after each of them
+	err = c.cfg.Tx(func() error {
+		for _, svc := range services {
+			c.remapService(svc, manifest, verbose)
+			if err := svc.CheckPlatform(runtime.GOOS); err != nil && !forcePlatform {
+				c.warning("%v; skipping it (use --force-platform to install it anyway)", err)
+				// Keep the remapped paths for a later `nazim edit --force-platform`
+				if err := c.cfg.UpdateService(svc); err != nil {
+					c.warning("service '%s' is invalid: %v", svc.Name, err)
+				}
+				failed = append(failed, svc.Name)
+				continue
+			}
+			if crossOS {
+				for _, warning := range crossOSWarnings(svc) {
+					c.warning("service '%s': %s", svc.Name, warning)
+				}
+			}
+			svc.Platform = runtime.GOOS
+
+			if err := c.cfg.UpdateService(svc); err != nil {
+				c.warning("service '%s' is invalid: %v", svc.Name, err)
+				failed = append(failed, svc.Name)
+				continue
+			}
+			sp = c.startProgress(fmt.Sprintf("Installing '%s'", svc.Name), verbose)
+			err := platformMgr.Install(svc)
+			sp.Stop()
+			if err != nil {
+				c.warning("failed to install '%s': %v", svc.Name, err)
+				failed = append(failed, svc.Name)
+				continue
+			}
+			if !svc.Enabled {
+				if err := platformMgr.Disable(svc.Name); err != nil {
+					c.warning("failed to disable '%s': %v", svc.Name, err)
+					if c.strict {
+						failed = append(failed, svc.Name)
+						continue
+					}
+				}
+			}
+			c.serviceEvent(events.ServiceAdded, svc.Name)
+			if verbose {
+				c.infof("Restored service '%s'\n", svc.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving restored services: %w", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("restored %d of %d service(s); failed: %s",
+			len(services)-len(failed), len(services), strings.Join(failed, ", "))
+	}
+	c.infof("Restored %d service(s) from %s\n", len(services), from)
+	return nil
+}
+
+// remapService rewrites paths that pointed into the backup's directories, such
+// as scripts created with `--command write`, to this machine's.
+func (c *CLI) remapService(svc *service.Service, manifest *backup.Manifest, verbose bool) {
+	remap := func(p string) string {
+		remapped, ok := manifest.RemapPath(p, c.cfg.Dirs())
+		if ok && verbose {
+			c.infof("Remapped %s -> %s\n", p, remapped)
+		}
+		return remapped
+	}
+
+	svc.Command = remap(svc.Command)
+	svc.WorkDir = remap(svc.WorkDir)
+	for i, arg := range svc.Args {
+		svc.Args[i] = remap(arg)
+	}
+	for _, override := range svc.Platforms {
+		override.Command = remap(override.Command)
+		override.WorkDir = remap(override.WorkDir)
+		for i, arg := range override.Args {
+			override.Args[i] = remap(arg)
+		}
+	}
+}
+
+// crossOSWarnings describes parts of a service restored from another OS that
+// are unlikely to work on this one.
+func crossOSWarnings(svc *service.Service) []string {
+	var warnings []string
+	svc = svc.ForPlatform(runtime.GOOS)
+
+	ext := strings.ToLower(filepath.Ext(svc.Command))
+	switch {
+	case runtime.GOOS == "windows" && ext == ".sh":
+		warnings = append(warnings, fmt.Sprintf("command %s is a shell script", svc.Command))
+	case runtime.GOOS != "windows" && ext == ".ps1":
+		// PowerShell 7 runs .ps1 scripts on Linux and macOS too
+		if _, err := exec.LookPath(service.ShellPwsh); err != nil {
+			warnings = append(warnings, fmt.Sprintf("command %s is a PowerShell script and pwsh is not installed", svc.Command))
+		}
+	case runtime.GOOS != "windows" && (ext == ".bat" || ext == ".cmd" || ext == ".exe"):
+		warnings = append(warnings, fmt.Sprintf("command %s is Windows-only", svc.Command))
+	}
+
+	if filepath.IsAbs(svc.Command) {
+		if _, err := os.Stat(svc.Command); err != nil {
+			warnings = append(warnings, fmt.Sprintf("command %s does not exist on this machine", svc.Command))
+		}
+	} else if runtime.GOOS != "windows" && strings.Contains(svc.Command, `\`) {
+		warnings = append(warnings, fmt.Sprintf("command %s uses Windows path separators", svc.Command))
+	}
+
+	if svc.WorkDir != "" {
+		if _, err := os.Stat(svc.WorkDir); err != nil {
+			warnings = append(warnings, fmt.Sprintf("working directory %s does not exist on this machine", svc.WorkDir))
+		}
+	}
+
+	if svc.OnStartup && runtime.GOOS != "windows" {
+		warnings = append(warnings, "on_startup runs as the current user here, not as SYSTEM")
+	}
+
+	return warnings
+}
@@ -0,0 +1,18 @@
+package cli
+
+import (
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// normalizeBlackouts returns blackout windows in the form they are stored in.
+func normalizeBlackouts(windows []string) ([]string, error) {
+	blackouts, err := service.ParseBlackouts(windows)
+	if err != nil {
+		return nil, err
+	}
+	var normalized []string
+	for _, b := range blackouts {
+		normalized = append(normalized, b.String())
+	}
+	return normalized, nil
+}
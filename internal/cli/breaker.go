@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/eventlog"
+	"github.com/calilkhalil/nazim/internal/notify"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/state"
+)
+
+// pausedStatus is the status list shows for an enabled service whose runs are
+// paused after failing too many times in a row.
+const pausedStatus = "Paused"
+
+// Bounds of the pauses of a service backing off, see backoff.
+const (
+	minBackoff = 15 * time.Minute // The first pause of services without a gap between runs
+	maxBackoff = 24 * time.Hour
+)
+
+// backoff returns how long runs of a service that failed streak runs in a row
+// are paused: nothing until it reaches max_consecutive_failures, then its gap
+// between runs, doubling with each further failure, up to a day. Services
+// without a gap between runs, such as startup or event services, start at
+// 15 minutes.
+func backoff(svc *service.Service, streak int, now time.Time) time.Duration {
+	limit := svc.MaxConsecutiveFailures
+	if limit == 0 || streak < limit {
+		return 0
+	}
+	pause := runGap(svc, now)
+	if pause <= 0 {
+		pause = minBackoff
+	}
+	for i := limit; i < streak && pause < maxBackoff; i++ {
+		pause *= 2
+	}
+	return min(pause, maxBackoff)
+}
+
+// pauseFailing pauses a service after a failed run, the last of a streak of failed
+// runs, once the streak reaches its max_consecutive_failures, so a service
+// that keeps failing stops running every time its schedule comes up. The
+// first pause also shows a desktop notification and, on Windows, writes an
+// event to the event log; the pause ends with a successful run, or with
+// `nazim reset-failures`.
+func (c *CLI) pauseFailing(svc *service.Service, st *state.State) {
+	pause := backoff(svc, st.FailureStreak, st.LastRun)
+	if pause == 0 {
+		return
+	}
+	until := st.LastRun.Add(pause)
+	if _, err := state.Update(c.cfg.GetServiceStateDir(), svc.Name, func(s *state.State) {
+		s.PausedUntil = until
+	}); err != nil {
+		c.warning("failed to save state of '%s': %v", svc.Name, err)
+		return
+	}
+
+	message := fmt.Sprintf("Service '%s' failed %d runs in a row; its runs are paused until %s.",
+		svc.Name, st.FailureStreak, until.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(c.errOut, message)
+	if st.FailureStreak > svc.MaxConsecutiveFailures {
+		return // Only the first pause is reported
+	}
+	if err := notify.Desktop(fmt.Sprintf("nazim: %s", svc.Name), message, true); err != nil {
+		c.warning("failed to show desktop notification: %v", err)
+	}
+	c.writeEvent(eventlog.Event{
+		ID:      eventlog.IDPaused,
+		Message: message,
+		Fields: [][2]string{
+			{"Service", svc.Name},
+			{"Failures", fmt.Sprint(st.FailureStreak)},
+			{"Paused Until", until.Format(time.RFC3339)},
+		},
+	})
+}
+
+// pausedState returns the state of a service whose runs are paused, or nil
+// when they aren't.
+func (c *CLI) pausedState(name string) *state.State {
+	st, err := state.Load(c.cfg.GetServiceStateDir(), name)
+	if err != nil || !st.Paused(time.Now()) {
+		return nil
+	}
+	return st
+}
+
+// ResetFailures clears the failure streak of a service and ends the pause it
+// backed off with, so it runs on schedule again.
+func (c *CLI) ResetFailures(ctx context.Context, name string, verbose bool) error {
+	if _, err := c.cfg.GetService(name); err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	}
+	if _, err := state.Update(c.cfg.GetServiceStateDir(), name, func(s *state.State) {
+		s.FailureStreak = 0
+		s.PausedUntil = time.Time{}
+	}); err != nil {
+		return fmt.Errorf("failed to save state of '%s': %w", name, err)
+	}
+	c.infof("Cleared the failures of '%s'; it runs on schedule again.\n", name)
+	return nil
+}
@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/state"
+)
+
+// Problems of a service other than failing runs, as problem returns them.
+const (
+	problemNotInstalled = "not installed"
+	problemDisabled     = "disabled in the scheduler"
+)
+
+// problem returns what keeps an enabled service from being healthy, or "" if
+// nothing does: it must be installed and enabled in the scheduler, and neither
+// paused, failing, nor stale (see checkStale).
+func (c *CLI) problem(platformMgr platform.Manager, svc *service.Service, st *state.State, now time.Time, grace float64) string {
+	taskState, err := platformMgr.GetTaskState(svc.Name)
+	switch {
+	case err != nil:
+		return problemNotInstalled
+	case !taskState.Enabled:
+		return problemDisabled
+	case st.Paused(now):
+		return "paused"
+	case st.FailureStreak > 0:
+		return "failing"
+	}
+	if s := c.checkStale(platformMgr, svc, now, grace); s != nil && s.Stale {
+		return "stale"
+	}
+	return ""
+}
+
+// CheckList checks that every enabled service is healthy (see problem), for
+// monitoring scripts: it prints a one-line summary, naming the services that
+// aren't, and returns 0 if they all are and 1 otherwise. Services of the
+// system config the user hasn't installed aren't counted.
+func (c *CLI) CheckList(ctx context.Context, grace string, verbose bool) (int, error) {
+	graceValue, err := c.parseGrace(grace)
+	if err != nil {
+		return 1, err
+	}
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return 1, fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	now := time.Now()
+	checked := 0
+	var problems []string
+	for _, svc := range c.cfg.ListServices() {
+		if !svc.Enabled || svc.CheckPlatform(runtime.GOOS) != nil {
+			continue
+		}
+		if c.cfg.Scope(svc.Name) == config.ScopeSystem {
+			if installed, _ := platformMgr.IsInstalled(svc.Name); !installed {
+				continue
+			}
+		}
+		checked++
+		st, err := state.Load(c.cfg.GetServiceStateDir(), svc.Name)
+		if err != nil && verbose {
+			c.warning("failed to read state of '%s': %v", svc.Name, err)
+		}
+		if problem := c.problem(platformMgr, svc, st, now, graceValue); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s (%s)", svc.Name, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(c.out, "FAIL: %d of %d enabled service(s) unhealthy: %s\n", len(problems), checked, strings.Join(problems, ", "))
+		return 1, nil
+	}
+	fmt.Fprintf(c.out, "OK: %d enabled service(s) installed and healthy\n", checked)
+	return 0, nil
+}
+
+// Exit codes of Check, those of Nagios plugins.
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+// checkStatuses names the exit codes of Check.
+var checkStatuses = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// CheckOptions selects how Check reports a service.
+type CheckOptions struct {
+	Format string // "nagios" (default) or "json"
+	Grace  string // Stale grace; empty uses the config's
+}
+
+// checkResult is a service's health as Check reports it in JSON.
+type checkResult struct {
+	Service  string     `json:"service"`
+	Status   string     `json:"status"` // One of checkStatuses
+	Code     int        `json:"code"`
+	Message  string     `json:"message"`
+	LastRun  *time.Time `json:"last_run,omitempty"`
+	Age      *float64   `json:"last_run_age_seconds,omitempty"`
+	Duration *float64   `json:"duration_seconds,omitempty"`
+	ExitCode *int       `json:"exit_code,omitempty"`
+}
+
+// Check reports the health of one service for monitoring systems, returning
+// the exit code of a Nagios plugin: OK when it is healthy (see problem),
+// WARNING when it is disabled or its last run was slow, CRITICAL when it isn't
+// installed, is paused or failing, or is stale, and UNKNOWN when it can't be
+// checked. The nagios format prints the plugin's status line with the age of
+// the last run, its duration, and its exit code as performance data; the json
+// format prints the same as an object, for Zabbix and other systems that
+// parse JSON.
+func (c *CLI) Check(ctx context.Context, name string, opts CheckOptions, verbose bool) (int, error) {
+	format := opts.Format
+	if format == "" {
+		format = "nagios"
+	}
+	if format != "nagios" && format != "json" {
+		return checkUnknown, fmt.Errorf("invalid --format %q: use nagios or json", opts.Format)
+	}
+	grace, err := c.parseGrace(opts.Grace)
+	if err != nil {
+		return checkUnknown, err
+	}
+
+	result := checkResult{Service: name}
+	var thresholds [2]time.Duration // Warning and critical ages of the last run
+	var slowThreshold time.Duration
+	svc, err := c.cfg.GetService(name)
+	if err != nil {
+		result.Code, result.Message = checkUnknown, fmt.Sprintf("service '%s' does not exist", name)
+	} else {
+		result.Code, result.Message, thresholds = c.checkService(svc, grace, &result)
+		slowThreshold = svc.SlowThreshold.Duration
+	}
+	result.Status = checkStatuses[result.Code]
+
+	if format == "json" {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return result.Code, encoder.Encode(result)
+	}
+
+	var perfdata []string
+	if result.Age != nil {
+		data := fmt.Sprintf("last_run_age=%.0fs", *result.Age)
+		if thresholds[0] > 0 {
+			data += fmt.Sprintf(";%.0f;%.0f;0;", thresholds[0].Seconds(), thresholds[1].Seconds())
+		}
+		perfdata = append(perfdata, data)
+	}
+	if result.Duration != nil {
+		data := fmt.Sprintf("duration=%.3fs", *result.Duration)
+		if slowThreshold > 0 {
+			data += fmt.Sprintf(";%.0f;;0;", slowThreshold.Seconds())
+		}
+		perfdata = append(perfdata, data)
+	}
+	if result.ExitCode != nil {
+		perfdata = append(perfdata, fmt.Sprintf("exit_code=%d;;1;;", *result.ExitCode))
+	}
+	line := fmt.Sprintf("NAZIM %s - %s", result.Status, result.Message)
+	if len(perfdata) > 0 {
+		line += " | " + strings.Join(perfdata, " ")
+	}
+	fmt.Fprintln(c.out, line)
+	return result.Code, nil
+}
+
+// checkService works out the health of an existing service for Check, filling
+// in its last run, and returns the ages of the last run that are worth a
+// warning and critical: its gap between runs, and grace times that.
+func (c *CLI) checkService(svc *service.Service, grace float64, result *checkResult) (int, string, [2]time.Duration) {
+	now := time.Now()
+	var thresholds [2]time.Duration
+	if every := runGap(svc, now); every > 0 {
+		thresholds = [2]time.Duration{every, time.Duration(grace * float64(every))}
+	}
+
+	st, err := state.Load(c.cfg.GetServiceStateDir(), svc.Name)
+	if err != nil {
+		return checkUnknown, fmt.Sprintf("%s: %v", svc.Name, err), thresholds
+	}
+	last, err := history.Last(c.cfg.GetHistoryDir(), svc.Name)
+	if err != nil {
+		return checkUnknown, fmt.Sprintf("%s: %v", svc.Name, err), thresholds
+	}
+	lastRun := st.LastRun
+	if last != nil {
+		lastRun = latest(lastRun, last.End)
+		duration := last.Duration().Seconds()
+		exitCode := last.ExitCode
+		result.Duration, result.ExitCode = &duration, &exitCode
+	} else if !st.LastRun.IsZero() {
+		result.ExitCode = &st.LastExitCode
+	}
+	ran := "never ran"
+	if !lastRun.IsZero() {
+		age := now.Sub(lastRun).Seconds()
+		result.LastRun, result.Age = &lastRun, &age
+		ran = fmt.Sprintf("last ran %s ago", formatDuration(now.Sub(lastRun).Round(time.Second)))
+		if result.ExitCode != nil {
+			ran += fmt.Sprintf(", exit %d", *result.ExitCode)
+		}
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return checkUnknown, fmt.Sprintf("%s: %v", svc.Name, err), thresholds
+	}
+	if !svc.Enabled {
+		return checkWarning, fmt.Sprintf("%s is disabled, %s", svc.Name, ran), thresholds
+	}
+	switch problem := c.problem(platformMgr, svc, st, now, grace); {
+	case problem == problemDisabled:
+		return checkWarning, fmt.Sprintf("%s is %s, %s", svc.Name, problem, ran), thresholds
+	case problem != "":
+		return checkCritical, fmt.Sprintf("%s is %s, %s", svc.Name, problem, ran), thresholds
+	case last != nil && last.Slow:
+		return checkWarning, fmt.Sprintf("%s was slow, %s", svc.Name, ran), thresholds
+	}
+	return checkOK, fmt.Sprintf("%s is healthy, %s", svc.Name, ran), thresholds
+}
@@ -2,91 +2,352 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/eventlog"
+	"github.com/calilkhalil/nazim/internal/events"
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/i18n"
+	"github.com/calilkhalil/nazim/internal/lock"
+	"github.com/calilkhalil/nazim/internal/notify"
 	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/progress"
+	"github.com/calilkhalil/nazim/internal/runner"
+	"github.com/calilkhalil/nazim/internal/schedule"
 	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/state"
+	"github.com/calilkhalil/nazim/internal/term"
+	"github.com/calilkhalil/nazim/internal/upload"
+	"github.com/calilkhalil/nazim/internal/versions"
+	"github.com/calilkhalil/nazim/internal/watch"
 )
 
 // CLI handles command-line operations.
+//
+// Requested data (lists, status, logs) is written to the output writer, and
+// confirmations such as "service added" go there too unless the CLI is quiet.
+// Warnings always go to the error writer; errors are returned to the caller.
 type CLI struct {
-	cfg *config.Config
+	cfg    *config.Config
+	in     *bufio.Reader // Answers to prompts
+	inFile *os.File      // The file in reads, if any, to tell whether it's a terminal
+	out    io.Writer
+	errOut io.Writer
+	quiet  bool
+	color  bool // Whether out is a terminal that shows colors
+	yes    bool // Answer prompts with their default instead of asking
+	strict bool // Fail on problems that are otherwise only warnings
+
+	version     string // Of this nazim, as set at build time
+	updateCheck bool   // Whether nazim may look for a newer release, see CheckForUpdate
+
+	lingerChecked bool // Whether an install checked lingering already, see warnLinger
+
+	// newPlatform creates the scheduler's manager, platform.NewManager unless
+	// tests stand in for the scheduler
+	newPlatform func(opts ...platform.Option) (platform.Manager, error)
 }
 
-// New creates a new CLI instance.
-func New(cfg *config.Config) *CLI {
-	return &CLI{cfg: cfg}
+// Option configures a CLI.
+type Option func(*CLI)
+
+// WithOutput sets where the CLI writes output and warnings.
+// Embedding programs can pass io.Discard to silence it.
+func WithOutput(out, errOut io.Writer) Option {
+	return func(c *CLI) {
+		c.out = out
+		c.errOut = errOut
+	}
+}
+
+// WithInput sets where the CLI reads answers to prompts. Prompts are only shown
+// when in is a terminal, or a reader other than a file, such as the answers an
+// embedding program or test supplies; otherwise they get their default answer.
+func WithInput(in io.Reader) Option {
+	return func(c *CLI) {
+		c.in = bufio.NewReader(in)
+		c.inFile, _ = in.(*os.File)
+	}
+}
+
+// WithNoColor turns off colored output, which is otherwise used on terminals
+// unless NO_COLOR is set.
+func WithNoColor(noColor bool) Option {
+	return func(c *CLI) {
+		c.color = !noColor
+	}
+}
+
+// WithQuiet suppresses informational messages.
+func WithQuiet(quiet bool) Option {
+	return func(c *CLI) {
+		c.quiet = quiet
+	}
+}
+
+// WithYes answers prompts without asking: import and adopt take every entry.
+// It makes commands usable from scripts and configuration-management tools.
+func WithYes(yes bool) Option {
+	return func(c *CLI) {
+		c.yes = yes
+	}
+}
+
+// WithStrict turns warnings into errors, such as a command that may not exist
+// or a failure to uninstall a service being replaced, so automation stops
+// instead of carrying on with a half-applied change.
+func WithStrict(strict bool) Option {
+	return func(c *CLI) {
+		c.strict = strict
+	}
+}
+
+// WithVersion sets the version of nazim that update checks compare releases with.
+func WithVersion(version string) Option {
+	return func(c *CLI) {
+		c.version = version
+	}
+}
+
+// WithUpdateCheck sets whether the CLI may ask GitHub for the latest release.
+// Without it, version --check fails and doctor doesn't look.
+func WithUpdateCheck(enabled bool) Option {
+	return func(c *CLI) {
+		c.updateCheck = enabled
+	}
+}
+
+// New creates a new CLI instance reading from os.Stdin and writing to os.Stdout
+// and os.Stderr by default.
+func New(cfg *config.Config, opts ...Option) *CLI {
+	c := &CLI{cfg: cfg, out: os.Stdout, errOut: os.Stderr, color: true, version: "dev", updateCheck: true, newPlatform: platform.NewManager}
+	WithInput(os.Stdin)(c)
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.color = c.color && term.ColorEnabled(c.out)
+	return c
+}
+
+// paint colors s when the output shows colors.
+func (c *CLI) paint(color term.Color, s string) string {
+	if !c.color {
+		return s
+	}
+	return term.Paint(color, s)
+}
+
+// infof prints an informational message, translated, unless the CLI is quiet.
+func (c *CLI) infof(format string, args ...any) {
+	if !c.quiet {
+		fmt.Fprint(c.out, i18n.Sprintf(format, args...))
+	}
+}
+
+// infoln prints an informational line, translated, unless the CLI is quiet.
+func (c *CLI) infoln(msg string) {
+	if !c.quiet {
+		fmt.Fprintln(c.out, i18n.T(msg))
+	}
+}
+
+// warn prints a warning, or returns it as an error in strict mode.
+func (c *CLI) warn(format string, args ...any) error {
+	if c.strict {
+		return fmt.Errorf(format, args...)
+	}
+	c.warning(format, args...)
+	return nil
+}
+
+// warning prints a translated warning, even in strict mode.
+func (c *CLI) warning(format string, args ...any) {
+	fmt.Fprintln(c.errOut, i18n.T("Warning: ")+i18n.Sprintf(format, args...))
+}
+
+// newManager creates the platform manager, pointing scheduled runs at this config.
+func (c *CLI) newManager(opts ...platform.Option) (platform.Manager, error) {
+	tracking := &trackingManager{c: c}
+	platformMgr, err := c.newPlatform(append([]platform.Option{
+		platform.WithConfigDir(c.cfg.ConfigDir),
+		platform.WithDataDir(c.cfg.DataDir),
+		platform.WithStateDir(c.cfg.StateDir),
+		platform.WithOutput(c.errOut),
+		platform.WithTargets(c.targetOf),
+		platform.WithFallback(func(name, mode string) { tracking.fallback = mode }),
+	}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	tracking.Manager = platformMgr
+	return tracking, nil
+}
+
+// targetOf returns the target of a service in the config, or "" for one that isn't.
+func (c *CLI) targetOf(name string) string {
+	if svc, err := c.cfg.GetService(name); err == nil {
+		return svc.Target
+	}
+	return ""
+}
+
+// startProgress starts reporting a slow operation on the error writer.
+// Pass its Step method to platform.WithProgress, and Stop it before printing.
+func (c *CLI) startProgress(message string, verbose bool) *progress.Spinner {
+	return progress.Start(c.errOut, progress.ModeFor(c.errOut, verbose, c.quiet), message)
 }
 
 // Flags holds command-line flags for add command.
 type Flags struct {
 	Name      string
 	Command   string
-	Args      string
+	Args      string   // Split shell-style, see commandArgs
+	ArgList   []string // Given one at a time with --arg or after --, kept as they are
 	WorkDir   string
 	OnStartup bool
 	OnLogon   bool
 	Interval  string
+	Offset    string // Aligns the interval to the clock, e.g. 15m past midnight; "none" removes it on edit
+	Schedule  string
+	Timezone  string   // IANA time zone of the schedule, e.g. Europe/Lisbon; "local" removes it on edit
+	IfChanged []string // Paths or globs; runs are skipped unless they changed; "none" removes them on edit
+	Blackout  []string // Windows runs are skipped in, e.g. "sat,sun"; "none" removes them on edit
+
+	StartupDelay string
+	Priority     string
+	CatchUp      bool
+	NoVerify     bool   // Skip checking that the command exists and is executable
+	Portable     bool   // Store paths inside the config dir relative to it
+	Elevated     bool   // Run with highest privileges (Windows)
+	Umask        string // File mode creation mask in octal; "none" removes it on edit
+	Sandbox      string // systemd hardening: basic or strict; "none" removes it on edit
+	Network      string // none cuts the command off from the network; "default" gives it back
+	Target       string // Where to schedule it instead of with this system's scheduler, e.g. windows-host; "none" removes it on edit
+
+	WatchPaths []string // Files or directories whose changes start a run; "none" removes them on edit
+	OnEvent    string   // Windows event log event that starts a run, Log=...;Source=...;ID=...; "none" removes it on edit
+
+	LongRunning bool // Run continuously as a Windows service; other triggers turn it off on edit
+	System      bool // Install as a launch daemon, running as root from boot (macOS)
+
+	FromUnit []string // Hand-edited scheduler definitions installed instead of generated ones; "none" removes them on edit
+
+	Steps         string // Services a pipeline runs in order, comma-separated, instead of a command
+	OnStepFailure string // What a pipeline does when a step fails: abort or continue; "none" goes back to abort on edit
+	ParallelGroup string // Services a parallel group runs at the same time, comma-separated, instead of a command
+	MaxParallel   string // How many members of a parallel group run at a time; "0" runs them all at once on edit
+
+	NotifyDesktop bool   // Show a desktop notification when a run finishes
+	DebugEnv      bool   // Record the environment of each run
+	SingletonLock string // Lock backend URL shared by the hosts that run the service; "none" removes it on edit
+	SlowThreshold string // Runs taking longer are marked slow; "none" removes it on edit
+	DailyBudget   string // Total runtime allowed per day, e.g. 30m; "none" removes it on edit
+	Timeout       string // Runs taking longer are stopped, e.g. 10m; "none" removes it on edit
+	Shell         string // Shell for one-liners; a PowerShell shell also runs .ps1 scripts
+	MaxLogLines   string // Lines logged per run and stream; "0" removes the cap on edit
+	LogTimestamps string // local, utc, both, or rfc3339; "none" goes back to the default on edit
+	LogLevel      string // all, errors-only, or off
+	NoLog         bool   // Same as LogLevel off
+	OnFailure     string // run:<service> or disable-self-after:<n>; "none" removes the policy on edit
+
+	MaxConsecutiveFailures string // Failed runs in a row after which runs are paused; "0" removes the limit on edit
+
+	FailPattern    string // Regular expression failing runs whose output matches it; "none" removes it on edit
+	SuccessPattern string // Regular expression failing runs whose output never matches it; "none" removes it on edit
+
+	Description string
+	Owner       string
+	Labels      []string // key=value pairs; on edit, key= removes a label
+
+	// Edit only: remove a setting, since empty flags keep the existing value
+	ClearArgs       bool
+	ClearWorkDir    bool
+	ClearInterval   bool
+	NoStartup       bool
+	NoDebugEnv      bool
+	NoSystem        bool
+	NoElevated      bool
+	NoNotifyDesktop bool
+	NoCatchUp       bool
+
+	ForcePlatform bool // Install a service set up on another platform anyway
 }
 
 // Add adds a new service.
 func (c *CLI) Add(ctx context.Context, flags *Flags, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
 	if flags.Name == "" {
 		return fmt.Errorf("service name is required (use --name or -n)")
 	}
-	if flags.Command == "" {
+	if flags.Command == "" && flags.Steps == "" && flags.ParallelGroup == "" {
 		return fmt.Errorf("service command is required (use --command or -c, or 'write'/'edit' for interactive mode)")
 	}
+	if c.cfg.Scope(flags.Name) == config.ScopeSystem {
+		// Adding over it would rewrite the config every user shares
+		return fmt.Errorf("service '%s' is defined for every user in %s; change it with nazim edit %s",
+			flags.Name, c.cfg.ServiceConfigPath(flags.Name), flags.Name)
+	}
 
 	command := flags.Command
 	if command == "write" || command == "edit" {
-		scriptPath, err := c.createScriptInteractive(flags.Name, verbose)
+		scriptPath, err := c.createScriptInteractive(flags.Name, flags.Shell, flags.Portable, verbose)
 		if err != nil {
 			return fmt.Errorf("failed to create script: %w", err)
 		}
 		command = scriptPath
 	}
 
-	var intervalDuration time.Duration
-	if flags.Interval != "" {
-		var err error
-		intervalDuration, err = parseDuration(flags.Interval)
-		if err != nil {
-			return fmt.Errorf("invalid interval format: %w", err)
-		}
+	svc, err := serviceFromFlags(flags, command)
+	if err != nil {
+		return err
 	}
+	c.cfg.ApplyDefaults(svc)
 
-	var args []string
-	if flags.Args != "" {
-		args = strings.Fields(flags.Args)
+	if err := c.resolvePaths(svc); err != nil {
+		return err
 	}
 
-	svc := &service.Service{
-		Name:      flags.Name,
-		Command:   command,
-		Args:      args,
-		WorkDir:   flags.WorkDir,
-		OnStartup: flags.OnStartup,
-		OnLogon:   flags.OnLogon,
-		Interval:  service.Duration{Duration: intervalDuration},
-		Enabled:   true,
-		Platform:  runtime.GOOS,
+	if err := c.cfg.ValidateService(svc); err != nil {
+		return err
 	}
-
-	if err := svc.Validate(); err != nil {
+	if err := checkTarget(svc); err != nil {
+		return err
+	}
+	if err := c.checkUnits(svc); err != nil {
+		return err
+	}
+	if err := c.checkFailurePolicy(svc); err != nil {
 		return err
 	}
 
-	platformMgr, err := platform.NewManager()
+	if !flags.NoVerify {
+		if err := c.verifyCommand(svc); err != nil {
+			return err
+		}
+	}
+
+	sp := c.startProgress(fmt.Sprintf("Installing '%s'", svc.Name), verbose)
+	defer sp.Stop()
+
+	platformMgr, err := c.newManager(platform.WithProgress(sp.Step))
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
@@ -94,6 +355,7 @@ func (c *CLI) Add(ctx context.Context, flags *Flags, verbose bool) error {
 	if err := platformMgr.Install(svc); err != nil {
 		return fmt.Errorf("failed to install service: %w", err)
 	}
+	sp.Stop()
 
 	if err := c.cfg.AddService(svc); err != nil {
 		if strings.Contains(err.Error(), "already exists") {
@@ -112,22 +374,49 @@ func (c *CLI) Add(ctx context.Context, flags *Flags, verbose bool) error {
 	}
 
 	if verbose {
-		fmt.Printf("Service '%s' added to configuration.\n", flags.Name)
+		c.infof("Service '%s' added to configuration.\n", flags.Name)
 	}
 
-	fmt.Printf("Service '%s' installed successfully!\n", flags.Name)
+	c.serviceEvent(events.ServiceAdded, flags.Name)
+	c.infof("Service '%s' installed successfully!\n", flags.Name)
+	if platform.IsWSL() && !svc.OnWindowsHost() && !svc.OnStartup {
+		// systemd, if it runs at all, stops with WSL once no program uses it
+		c.infof("WSL stops systemd and its timers when it shuts down; to run '%s' whether WSL is up or not, schedule it on Windows with: nazim edit %s --target windows-host\n", svc.Name, svc.Name)
+	}
 	return nil
 }
 
+// ListOptions selects how List shows services.
+type ListOptions struct {
+	Wide    bool   // Show full commands and metadata instead of fitting the terminal width
+	Tree    bool   // Show services in groups, with collapsed commands
+	GroupBy string // What Tree groups by: "type" (default), "namespace", "label", or "label:<key>"
+	Grace   string // Stale grace; empty uses the config's
+}
+
 // List lists all services.
-func (c *CLI) List(ctx context.Context, verbose bool) error {
+func (c *CLI) List(ctx context.Context, opts ListOptions, verbose bool) error {
+	grace, err := c.parseGrace(opts.Grace)
+	if err != nil {
+		return err
+	}
+
+	var groupsOf func(svc *service.Service) []string
+	var ungrouped string
+	if opts.Tree {
+		var err error
+		if groupsOf, ungrouped, err = grouping(opts.GroupBy); err != nil {
+			return err
+		}
+	}
+
 	services := c.cfg.ListServices()
 	if len(services) == 0 {
-		fmt.Println("No services found.")
+		c.infoln("No services found.")
 		return nil
 	}
 
-	platformMgr, err := platform.NewManager()
+	platformMgr, err := c.newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
@@ -137,44 +426,51 @@ func (c *CLI) List(ctx context.Context, verbose bool) error {
 		command string
 		svcType string
 		status  string
+		nextRun string
+		svc     *service.Service
 	}
 
 	rows := make([]rowData, 0, len(services))
 	for _, svc := range services {
 		// Get task state (Enabled/Disabled)
 		state, err := platformMgr.GetTaskState(svc.Name)
-		status := state
-		if err != nil {
+		var status string
+		if err == nil {
+			status = state.String()
+		} else if c.cfg.Scope(svc.Name) == config.ScopeSystem {
+			// Services of the system config are installed by each user, with enable
+			status = notInstalled
+		} else {
 			// If task not found, skip this service (it shouldn't be in the list)
 			if verbose {
-				fmt.Fprintf(os.Stderr, "Warning: service '%s' not found in system, skipping...\n", svc.Name)
+				c.warning("service '%s' not found in system, skipping...", svc.Name)
 			}
 			continue
 		}
 
-		svcType := ""
-		if svc.OnStartup {
-			svcType = "Startup"
+		svcType := describeTrigger(svc)
+
+		effective := svc.ForPlatform(runtime.GOOS)
+		cmdStr := commandLine(effective)
+		if opts.Tree && !opts.Wide {
+			cmdStr = collapseCommand(effective)
 		}
-		if svc.OnLogon {
-			if svcType != "" {
-				svcType += " + "
+
+		nextRun := "-"
+		if state != nil && state.Enabled {
+			next := state.NextRun
+			if next.IsZero() {
+				// Not every scheduler plans runs ahead; nazim works those out
+				if next, err = platformMgr.NextRun(svc); err != nil && verbose {
+					c.warning("failed to query next run of '%s': %v", svc.Name, err)
+				}
 			}
-			svcType += "Logon"
-		}
-		if svc.GetInterval() > 0 {
-			if svcType != "" {
-				svcType += " + "
+			nextRun = formatRunTime(next, time.Now())
+			if c.pausedState(svc.Name) != nil {
+				status = pausedStatus
+			} else if s := c.checkStale(platformMgr, svc, time.Now(), grace); s != nil && s.Stale {
+				status = staleStatus
 			}
-			svcType += fmt.Sprintf("Every %s", formatDuration(svc.GetInterval()))
-		}
-		if svcType == "" {
-			svcType = "-"
-		}
-
-		cmdStr := svc.Command
-		if len(svc.Args) > 0 {
-			cmdStr += " " + strings.Join(svc.Args, " ")
 		}
 
 		rows = append(rows, rowData{
@@ -182,95 +478,236 @@ func (c *CLI) List(ctx context.Context, verbose bool) error {
 			command: cmdStr,
 			svcType: svcType,
 			status:  status,
+			nextRun: nextRun,
+			svc:     svc,
 		})
 	}
 
 	// If no valid services found, show message
 	if len(rows) == 0 {
-		fmt.Println("No services found.")
+		c.infoln("No services found.")
 		return nil
 	}
 
-	const maxCmdDisplay = 50
-	for i := range rows {
-		if len(rows[i].command) > maxCmdDisplay {
-			rows[i].command = rows[i].command[:maxCmdDisplay] + "..."
+	t := &table{columns: []column{
+		{header: "NAME"},
+		{header: "COMMAND", flex: true},
+		{header: "TYPE"},
+		{header: "STATUS", color: statusColor},
+		{header: "NEXT RUN"},
+	}}
+	// Only machines with a system config have services of more than one scope
+	scoped := c.cfg.HasSystemServices()
+	if scoped {
+		t.columns = append(t.columns, column{header: "SCOPE"})
+	}
+	if opts.Wide {
+		t.columns = append(t.columns, column{header: "OWNER"}, column{header: "LABELS"}, column{header: "DESCRIPTION"})
+	}
+	cells := func(row rowData, name string) []string {
+		cells := []string{name, row.command, row.svcType, row.status, row.nextRun}
+		if scoped {
+			cells = append(cells, c.cfg.Scope(row.name))
+		}
+		if opts.Wide {
+			cells = append(cells, row.svc.Owner, strings.Join(row.svc.LabelPairs(), ","), row.svc.Description)
 		}
+		return cells
 	}
 
-	maxNameLen := 4
-	maxCmdLen := 7
-	maxTypeLen := 4
-	maxStatusLen := 6
-
-	for _, row := range rows {
-		if len(row.name) > maxNameLen {
-			maxNameLen = len(row.name)
+	if opts.Tree {
+		// A service is listed under each of its groups, e.g. each of its labels
+		members := make(map[string][]rowData)
+		var outside []rowData
+		for _, row := range rows {
+			groups := groupsOf(row.svc)
+			if len(groups) == 0 {
+				outside = append(outside, row)
+			}
+			for _, group := range groups {
+				members[group] = append(members[group], row)
+			}
+		}
+		groups := make([]string, 0, len(members))
+		for group := range members {
+			groups = append(groups, group)
 		}
-		if len(row.command) > maxCmdLen {
-			maxCmdLen = len(row.command)
+		sort.Strings(groups)
+		if len(outside) > 0 {
+			// Services outside every group come last
+			groups = append(groups, ungrouped)
+			members[ungrouped] = outside
 		}
-		if len(row.svcType) > maxTypeLen {
-			maxTypeLen = len(row.svcType)
+		for _, group := range groups {
+			t.addHeading(fmt.Sprintf("%s (%d)", group, len(members[group])))
+			for i, row := range members[group] {
+				branch := "├─ "
+				if i == len(members[group])-1 {
+					branch = "└─ "
+				}
+				t.addRow(cells(row, branch+row.name)...)
+			}
+		}
+	} else {
+		for _, row := range rows {
+			t.addRow(cells(row, row.name)...)
 		}
-		if len(row.status) > maxStatusLen {
-			maxStatusLen = len(row.status)
+	}
+
+	width := 0
+	if !opts.Wide {
+		// Redirected output is fitted to a common terminal width
+		if width = term.Width(c.out); width == 0 {
+			width = defaultWidth
 		}
 	}
+	c.renderTable(t, width)
+	fmt.Fprintf(c.out, "\nTotal: %d service(s)\n", len(rows))
+
+	return nil
+}
 
-	headerSeparator := strings.Repeat("-", maxNameLen+maxCmdLen+maxTypeLen+maxStatusLen+13)
-	fmt.Println(headerSeparator)
-	fmt.Printf("| %-*s | %-*s | %-*s | %-*s |\n",
-		maxNameLen, "NAME",
-		maxCmdLen, "COMMAND",
-		maxTypeLen, "TYPE",
-		maxStatusLen, "STATUS")
-	fmt.Println(headerSeparator)
+// notInstalled is the status list shows for a service of the system config
+// the user hasn't installed.
+const notInstalled = "Not Installed"
+
+// defaultWidth is the width tables are fitted to when it can't be detected.
+const defaultWidth = 80
+
+// statusColor colors a service state: green when enabled, red when disabled,
+// and yellow when unknown.
+func statusColor(status string) term.Color {
+	switch status {
+	case "Enabled":
+		return term.Green
+	case "Disabled":
+		return term.Red
+	default:
+		return term.Yellow
+	}
+}
 
-	for _, row := range rows {
-		fmt.Printf("| %-*s | %-*s | %-*s | %-*s |\n",
-			maxNameLen, row.name,
-			maxCmdLen, row.command,
-			maxTypeLen, row.svcType,
-			maxStatusLen, row.status)
+// formatRunTime formats a run time for a table: the time of day when it's
+// today, with the date otherwise, and "-" for the zero time.
+func formatRunTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
 	}
+	t = t.Local()
+	if y, m, d := t.Date(); now.Year() == y && now.Month() == m && now.Day() == d {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan 2 15:04")
+}
 
-	fmt.Println(headerSeparator)
-	fmt.Printf("\nTotal: %d service(s)\n", len(rows))
+// describeInterval describes the interval of a service, e.g. "Every 1h", or
+// "Every 1h from 00:15" when it has an offset.
+func describeInterval(svc *service.Service) string {
+	desc := fmt.Sprintf("Every %s", formatDuration(svc.GetInterval()))
+	if offset, ok := svc.GetOffset(); ok {
+		desc += fmt.Sprintf(" from %02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+	}
+	return desc
+}
 
-	return nil
+// describeFallback explains how a service was installed in the Fallback modes
+// of mode, as state.State records them.
+func describeFallback(mode string) string {
+	var parts []string
+	for _, m := range strings.Split(mode, ",") {
+		switch m {
+		case platform.FallbackUserTask:
+			parts = append(parts, "a task of your own, which runs only while you are logged on")
+		case platform.FallbackStartupFolder:
+			parts = append(parts, "a Startup folder shortcut, which runs at your logon")
+		default:
+			parts = append(parts, m)
+		}
+	}
+	return "user-level fallback, without administrator privileges: " + strings.Join(parts, " and ")
+}
+
+// describeTrigger summarizes when a service runs, e.g. "Startup + Every 1h".
+func describeTrigger(svc *service.Service) string {
+	var parts []string
+	if svc.OnStartup {
+		parts = append(parts, "Startup")
+	}
+	if svc.OnLogon {
+		parts = append(parts, "Logon")
+	}
+	if svc.GetInterval() > 0 {
+		parts = append(parts, describeInterval(svc))
+	}
+	if svc.Schedule != "" {
+		parts = append(parts, strings.TrimSpace(svc.Schedule+" "+svc.Timezone))
+	}
+	if len(svc.WatchPaths) > 0 {
+		parts = append(parts, "On changes to "+strings.Join(svc.WatchPaths, ", "))
+	}
+	if svc.OnEvent != "" {
+		parts = append(parts, "On event "+svc.OnEvent)
+	}
+	if svc.LongRunning {
+		parts = append(parts, "Long-running")
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " + ")
 }
 
 // Remove removes a service completely (from system, config, scripts, and logs).
 func (c *CLI) Remove(ctx context.Context, name string, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
 	if _, err := c.cfg.GetService(name); err != nil {
 		return fmt.Errorf("service '%s' does not exist", name)
 	}
+	if err := c.cfg.CheckModifiable(name); err != nil {
+		return err
+	}
 
 	var removalErrors []string
 
 	// 1. Remove from platform (Task Scheduler, systemd, launchd)
-	platformMgr, err := platform.NewManager()
+	sp := c.startProgress(fmt.Sprintf("Uninstalling '%s'", name), verbose)
+	defer sp.Stop()
+
+	platformMgr, err := c.newManager(platform.WithProgress(sp.Step))
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
 
-	if err := platformMgr.Uninstall(name); err != nil {
+	err = platformMgr.Uninstall(name)
+	sp.Stop()
+	if err != nil {
 		// Check if elevation was triggered
 		if strings.Contains(err.Error(), "Elevated process will handle") {
 			// Elevation was triggered - stop here, elevated process will handle everything
 			// Don't show error, just exit silently (elevated process will show success)
 			return nil
 		}
-		// Other errors are warnings
+		// Other errors are warnings, unless the service must be gone from the system
+		if c.strict {
+			return fmt.Errorf("failed to uninstall from system: %w", err)
+		}
 		if verbose {
-			fmt.Fprintf(os.Stderr, "Warning: failed to uninstall from system: %v\n", err)
+			c.warning("failed to uninstall from system: %v", err)
+		}
+		// What the system kept is listed artifact by artifact
+		var leftovers *platform.MultiError
+		if errors.As(err, &leftovers) {
+			for _, artifactErr := range leftovers.Errors {
+				removalErrors = append(removalErrors, fmt.Sprintf("system uninstall: %v", artifactErr))
+			}
+		} else {
+			removalErrors = append(removalErrors, fmt.Sprintf("system uninstall: %v", err))
 		}
-		removalErrors = append(removalErrors, fmt.Sprintf("system uninstall: %v", err))
 	}
 
 	// 2. Remove script file (always attempt, regardless of svc.Command)
-	scriptsDir := c.cfg.GetScriptsDir()
 	var ext string
 	switch runtime.GOOS {
 	case "windows":
@@ -280,35 +717,80 @@ func (c *CLI) Remove(ctx context.Context, name string, verbose bool) error {
 	default:
 		ext = ".sh"
 	}
-	scriptPath := filepath.Join(scriptsDir, fmt.Sprintf("%s%s", name, ext))
+	// Scripts written for a PowerShell shell are .ps1 on every platform, and
+	// those of portable services are kept in the config directory
+	var scriptPaths []string
+	for _, scriptsDir := range []string{c.cfg.GetScriptsDir(), c.cfg.GetPortableScriptsDir()} {
+		for _, scriptExt := range []string{ext, ".ps1"} {
+			scriptPaths = append(scriptPaths, filepath.Join(scriptsDir, fmt.Sprintf("%s%s", name, scriptExt)))
+		}
+	}
+	for _, scriptPath := range scriptPaths {
+		if err := os.Remove(scriptPath); err != nil && !os.IsNotExist(err) {
+			if verbose {
+				c.warning("failed to remove script file: %v", err)
+			}
+			removalErrors = append(removalErrors, fmt.Sprintf("script file: %v", err))
+		} else if err == nil {
+			if verbose {
+				c.infof("Removed script file: %s\n", scriptPath)
+			}
+		}
+	}
 
-	if err := os.Remove(scriptPath); err != nil && !os.IsNotExist(err) {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "Warning: failed to remove script file: %v\n", err)
+	// 3. Remove log files (per-stream logs and the legacy merged log)
+	logsDir := c.cfg.GetLogsDir()
+	logPaths := []string{
+		runner.LogPath(logsDir, name, runner.StreamStdout),
+		runner.LogPath(logsDir, name, runner.StreamStderr),
+		filepath.Join(logsDir, fmt.Sprintf("%s.log", name)),
+	}
+
+	for _, logPath := range logPaths {
+		if err := os.Remove(logPath); err != nil && !os.IsNotExist(err) {
+			if verbose {
+				c.warning("failed to remove log file: %v", err)
+			}
+			removalErrors = append(removalErrors, fmt.Sprintf("log file: %v", err))
+		} else if err == nil {
+			if verbose {
+				c.infof("Removed log file: %s\n", logPath)
+			}
 		}
-		removalErrors = append(removalErrors, fmt.Sprintf("script file: %v", err))
-	} else if err == nil {
+	}
+
+	// 4. Remove run history
+	if err := history.Remove(c.cfg.GetHistoryDir(), name); err != nil {
 		if verbose {
-			fmt.Printf("Removed script file: %s\n", scriptPath)
+			c.warning("%v", err)
 		}
+		removalErrors = append(removalErrors, fmt.Sprintf("history: %v", err))
 	}
 
-	// 3. Remove log file
-	logsDir := c.cfg.GetLogsDir()
-	logPath := filepath.Join(logsDir, fmt.Sprintf("%s.log", name))
+	for _, dir := range []string{c.cfg.GetSnapshotsDir(), c.cfg.GetPollsDir()} {
+		if err := watch.Remove(dir, name); err != nil {
+			if verbose {
+				c.warning("%v", err)
+			}
+			removalErrors = append(removalErrors, fmt.Sprintf("snapshot: %v", err))
+		}
+	}
 
-	if err := os.Remove(logPath); err != nil && !os.IsNotExist(err) {
+	if err := state.Remove(c.cfg.GetServiceStateDir(), name); err != nil {
 		if verbose {
-			fmt.Fprintf(os.Stderr, "Warning: failed to remove log file: %v\n", err)
+			c.warning("%v", err)
 		}
-		removalErrors = append(removalErrors, fmt.Sprintf("log file: %v", err))
-	} else if err == nil {
+		removalErrors = append(removalErrors, fmt.Sprintf("state: %v", err))
+	}
+
+	if err := versions.Remove(c.cfg.GetVersionsDir(), name); err != nil {
 		if verbose {
-			fmt.Printf("Removed log file: %s\n", logPath)
+			c.warning("%v", err)
 		}
+		removalErrors = append(removalErrors, fmt.Sprintf("versions: %v", err))
 	}
 
-	// 4. Log removal with timestamp
+	// 5. Log removal with timestamp
 	removalLogPath := filepath.Join(logsDir, "removals.log")
 	logEntry := fmt.Sprintf("%s - Service '%s' removed\n",
 		time.Now().Format("2006-01-02 15:04:05"), name)
@@ -316,47 +798,76 @@ func (c *CLI) Remove(ctx context.Context, name string, verbose bool) error {
 	f, err := os.OpenFile(removalLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		if verbose {
-			fmt.Fprintf(os.Stderr, "Warning: failed to log removal: %v\n", err)
+			c.warning("failed to log removal: %v", err)
 		}
 	} else {
 		defer f.Close()
 		if _, err := f.WriteString(logEntry); err != nil {
 			if verbose {
-				fmt.Fprintf(os.Stderr, "Warning: failed to write removal log: %v\n", err)
+				c.warning("failed to write removal log: %v", err)
 			}
 		}
 	}
 
-	// 5. Remove from config
+	// 6. Remove from config
 	if err := c.cfg.RemoveService(name); err != nil {
 		return fmt.Errorf("failed to remove service from config: %w", err)
 	}
 
+	c.serviceEvent(events.ServiceRemoved, name)
+
 	// Report results
+	if len(removalErrors) > 0 && c.strict {
+		return fmt.Errorf("service '%s' removed from config, but cleanup failed: %s", name, strings.Join(removalErrors, "; "))
+	}
 	if len(removalErrors) > 0 {
-		fmt.Printf("Service '%s' removed from config with warnings:\n", name)
+		fmt.Fprintf(c.errOut, "Service '%s' removed from config with warnings:\n", name)
 		for _, e := range removalErrors {
-			fmt.Printf("  - %s\n", e)
+			fmt.Fprintf(c.errOut, "  - %s\n", e)
 		}
 	} else {
-		fmt.Printf("Service '%s' removed successfully!\n", name)
+		c.infof("Service '%s' removed successfully!\n", name)
 	}
 
 	return nil
 }
 
-// Enable enables a service (allows it to run on schedule).
+// Enable enables a service (allows it to run on schedule). A service of the
+// system config that isn't installed for this user yet is installed.
 func (c *CLI) Enable(ctx context.Context, name string, verbose bool) error {
-	if _, err := c.cfg.GetService(name); err != nil {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	svc, err := c.cfg.GetService(name)
+	if err != nil {
 		return fmt.Errorf("service '%s' does not exist", name)
 	}
 
-	platformMgr, err := platform.NewManager()
+	sp := c.startProgress(fmt.Sprintf("Enabling '%s'", name), verbose)
+	defer sp.Stop()
+
+	platformMgr, err := c.newManager(platform.WithProgress(sp.Step))
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
 
-	if err := platformMgr.Enable(name); err != nil {
+	if installed, _ := platformMgr.IsInstalled(name); !installed && c.cfg.Scope(name) == config.ScopeSystem {
+		if err := c.cfg.ValidateService(svc); err != nil {
+			return err
+		}
+		err = platformMgr.Install(svc)
+		sp.Stop()
+		if err != nil {
+			return fmt.Errorf("failed to install service: %w", err)
+		}
+		c.serviceEvent(events.ServiceAdded, name)
+		c.infof("Service '%s' installed successfully!\n", name)
+		return nil
+	}
+
+	err = platformMgr.Enable(name)
+	sp.Stop()
+	if err != nil {
 		// Check if elevated process is handling it
 		if strings.Contains(err.Error(), "Elevated process will handle") {
 			// UAC was triggered, elevated process will show success message
@@ -365,22 +876,31 @@ func (c *CLI) Enable(ctx context.Context, name string, verbose bool) error {
 		return fmt.Errorf("failed to enable service: %w", err)
 	}
 
-	fmt.Printf("Service '%s' enabled successfully!\n", name)
+	c.serviceEvent(events.ServiceEnabled, name)
+	c.infof("Service '%s' enabled successfully!\n", name)
 	return nil
 }
 
 // Disable disables a service (prevents it from running on schedule).
 func (c *CLI) Disable(ctx context.Context, name string, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
 	if _, err := c.cfg.GetService(name); err != nil {
 		return fmt.Errorf("service '%s' does not exist", name)
 	}
 
-	platformMgr, err := platform.NewManager()
+	sp := c.startProgress(fmt.Sprintf("Disabling '%s'", name), verbose)
+	defer sp.Stop()
+
+	platformMgr, err := c.newManager(platform.WithProgress(sp.Step))
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
 
-	if err := platformMgr.Disable(name); err != nil {
+	err = platformMgr.Disable(name)
+	sp.Stop()
+	if err != nil {
 		// Check if elevated process is handling it
 		if strings.Contains(err.Error(), "Elevated process will handle") {
 			// UAC was triggered, elevated process will show success message
@@ -389,26 +909,76 @@ func (c *CLI) Disable(ctx context.Context, name string, verbose bool) error {
 		return fmt.Errorf("failed to disable service: %w", err)
 	}
 
-	fmt.Printf("Service '%s' disabled successfully!\n", name)
+	c.serviceEvent(events.ServiceDisabled, name)
+	c.infof("Service '%s' disabled successfully!\n", name)
 	return nil
 }
 
 // Run executes a service immediately (independent of schedule).
-func (c *CLI) Run(ctx context.Context, name string, verbose bool) error {
-	if _, err := c.cfg.GetService(name); err != nil {
+func (c *CLI) Run(ctx context.Context, name string, force, verbose bool) error {
+	svc, err := c.cfg.GetService(name)
+	if err != nil {
 		return fmt.Errorf("service '%s' does not exist", name)
 	}
+	// exec would skip the run
+	if st := c.pausedState(name); st != nil {
+		return fmt.Errorf("service '%s' is paused until %s after failing %d runs in a row; run nazim reset-failures %s first",
+			name, st.PausedUntil.Local().Format("2006-01-02 15:04:05"), st.FailureStreak, name)
+	}
+	if window := svc.InBlackout(time.Now(), c.cfg.Blackout()); window != nil {
+		return fmt.Errorf("service '%s' is in blackout window %s, so its runs are skipped until it ends", name, window)
+	}
 
-	platformMgr, err := platform.NewManager()
+	platformMgr, err := c.newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
 
+	// Schedulers treat disabled definitions differently: Task Scheduler refuses
+	// to start them, systemd starts the unit anyway, and launchd has unloaded
+	// the agent. So a disabled service is only run when asked to, and then here,
+	// the same way on every platform.
+	if state, err := platformMgr.GetTaskState(name); err == nil && !state.Enabled {
+		if !force && !c.confirm(i18n.Sprintf("Service '%s' is disabled. Run it anyway? [y/N]: ", name)) {
+			return fmt.Errorf("service '%s' is disabled; use --force to run it anyway, or enable it first", name)
+		}
+		c.infof("Running disabled service '%s' in the foreground...\n", name)
+		code, err := c.Exec(ctx, name, false, false, false, verbose)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("service '%s' exited with code %d", name, code)
+		}
+		c.infof("Service '%s' finished.\n", name)
+		return nil
+	}
+
 	if err := platformMgr.Run(name); err != nil {
 		return fmt.Errorf("failed to run service: %w", err)
 	}
 
-	fmt.Printf("Service '%s' is now running!\n", name)
+	c.infof("Service '%s' is now running!\n", name)
+	return nil
+}
+
+// Stop stops a running service: a long-running service until it is started
+// again or the machine restarts, or the current run of any other.
+func (c *CLI) Stop(ctx context.Context, name string, verbose bool) error {
+	if _, err := c.cfg.GetService(name); err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+	if err := platformMgr.Stop(name); err != nil {
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+
+	c.serviceEvent(events.ServiceStopped, name)
+	c.infof("Service '%s' stopped.\n", name)
 	return nil
 }
 
@@ -419,7 +989,7 @@ func (c *CLI) Status(ctx context.Context, name string, verbose bool) error {
 		return fmt.Errorf("service '%s' does not exist", name)
 	}
 
-	platformMgr, err := platform.NewManager()
+	platformMgr, err := c.newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
@@ -432,18 +1002,67 @@ func (c *CLI) Status(ctx context.Context, name string, verbose bool) error {
 		status = "Unknown"
 	}
 
-	fmt.Printf("Service: %s\n", svc.Name)
-	fmt.Printf("Status: %s\n", status)
-	fmt.Printf("Enabled: %v\n", svc.Enabled)
-	fmt.Printf("Command: %s", svc.Command)
-	if len(svc.Args) > 0 {
-		fmt.Printf(" %s", strings.Join(svc.Args, " "))
+	fmt.Fprintf(c.out, "Service: %s\n", svc.Name)
+	if svc.Description != "" {
+		fmt.Fprintf(c.out, "Description: %s\n", svc.Description)
+	}
+	if svc.Owner != "" {
+		fmt.Fprintf(c.out, "Owner: %s\n", svc.Owner)
+	}
+	if len(svc.Labels) > 0 {
+		fmt.Fprintf(c.out, "Labels: %s\n", strings.Join(svc.LabelPairs(), ", "))
+	}
+	if c.cfg.Scope(name) == config.ScopeSystem {
+		fmt.Fprintf(c.out, "Scope: system (defined in %s)\n", c.cfg.ServiceConfigPath(name))
+	}
+	if svc.Template != "" {
+		fmt.Fprintf(c.out, "Template: %s (%s)\n", svc.Template, describeMatrix(svc.Matrix))
+	}
+	fmt.Fprintf(c.out, "Status: %s\n", status)
+	if installed {
+		if state, err := platformMgr.GetTaskState(name); err == nil {
+			fmt.Fprintf(c.out, "Scheduler State: %s\n", describeState(state))
+		} else if verbose {
+			c.warning("failed to query scheduler: %v", err)
+		}
+	}
+	fmt.Fprintf(c.out, "Enabled: %v\n", svc.Enabled)
+	// Show what runs here, with this platform's overrides
+	effective := svc.ForPlatform(runtime.GOOS)
+	if svc.RunsServices() {
+		c.printMembers(svc)
+	} else {
+		fmt.Fprintf(c.out, "Command: %s\n", commandLine(effective))
+	}
+	if effective.WorkDir != "" {
+		fmt.Fprintf(c.out, "Working Directory: %s\n", effective.WorkDir)
+	}
+	if svc.Portable {
+		fmt.Fprintf(c.out, "Paths: relative to %s\n", c.cfg.ConfigDir)
+	}
+	if effective.Shell != "" {
+		fmt.Fprintf(c.out, "Shell: %s\n", effective.Shell)
+	}
+	if len(svc.Env) > 0 {
+		// Values may hold secrets, so only the names are shown
+		names := make([]string, 0, len(svc.Env))
+		for name := range svc.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(c.out, "Environment: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(c.out, "Platform: %s", svc.Platform)
+	if svc.CheckPlatform(runtime.GOOS) != nil {
+		fmt.Fprintf(c.out, " %s", c.paint(term.Yellow, "(set up on another OS)"))
 	}
-	fmt.Println()
-	if svc.WorkDir != "" {
-		fmt.Printf("Working Directory: %s\n", svc.WorkDir)
+	fmt.Fprintln(c.out)
+	if svc.OnWindowsHost() {
+		fmt.Fprintln(c.out, "Target: windows-host (Task Scheduler of the Windows host, through wsl.exe)")
+	}
+	if len(svc.Platforms) > 0 {
+		fmt.Fprintf(c.out, "Overrides: %s\n", strings.Join(svc.OverriddenPlatforms(), ", "))
 	}
-	fmt.Printf("Platform: %s\n", svc.Platform)
 
 	svcType := ""
 	if svc.OnStartup {
@@ -453,103 +1072,1931 @@ func (c *CLI) Status(ctx context.Context, name string, verbose bool) error {
 		if svcType != "" {
 			svcType += " + "
 		}
-		svcType += fmt.Sprintf("Every %s", formatDuration(svc.GetInterval()))
+		svcType += describeInterval(svc)
+	}
+	if svc.Schedule != "" {
+		if svcType != "" {
+			svcType += " + "
+		}
+		svcType += svc.Schedule
 	}
 	if svcType == "" {
 		svcType = "None"
 	}
-	fmt.Printf("Schedule: %s\n", svcType)
-
-	return nil
-}
-
-// Edit updates an existing service.
-func (c *CLI) Edit(ctx context.Context, name string, flags *Flags, verbose bool) error {
-	existingSvc, err := c.cfg.GetService(name)
-	if err != nil {
-		return fmt.Errorf("service '%s' does not exist", name)
+	fmt.Fprintf(c.out, "Schedule: %s\n", svcType)
+	if sched := svc.GetSchedule(); sched != nil && sched.Location != nil {
+		fmt.Fprintf(c.out, "Timezone: %s%s\n", sched.Location, describeLocalSchedule(svc, sched))
 	}
-
-	var intervalDuration time.Duration
-	if flags.Interval != "" {
-		var err error
-		intervalDuration, err = parseDuration(flags.Interval)
-		if err != nil {
-			return fmt.Errorf("invalid interval format: %w", err)
-		}
+	if trigger := svc.GetEventTrigger(); trigger != nil {
+		fmt.Fprintf(c.out, "On Event: %s (query %s)\n", trigger, trigger.Query())
+	}
+	if svc.Priority != "" {
+		fmt.Fprintf(c.out, "Priority: %s\n", svc.Priority)
+	}
+	if svc.CatchUp {
+		fmt.Fprintln(c.out, "Catch-up: missed runs are made up after boot")
+	}
+	if svc.Elevated {
+		note := ""
+		if runtime.GOOS != "windows" {
+			note = " (ignored: Windows only)"
+		}
+		fmt.Fprintf(c.out, "Run Level: highest privileges%s\n", note)
+	}
+	if svc.System {
+		fmt.Fprintln(c.out, "Runs As: root, as a launch daemon from boot")
+	}
+	if svc.Umask != "" {
+		note := ""
+		if runtime.GOOS == "windows" {
+			note = " (ignored: Linux and macOS only)"
+		}
+		fmt.Fprintf(c.out, "Umask: %s%s\n", svc.Umask, note)
+	}
+	if svc.Sandbox != "" {
+		note := ""
+		if runtime.GOOS != "linux" {
+			note = " (ignored: Linux only)"
+		}
+		fmt.Fprintf(c.out, "Sandbox: %s%s\n", svc.Sandbox, note)
+	}
+	if svc.Network != "" {
+		isolation := platform.NetworkIsolation(svc)
+		if isolation == "" {
+			isolation = "not enforced on " + runtime.GOOS
+		}
+		fmt.Fprintf(c.out, "Network: %s (%s)\n", svc.Network, isolation)
+	}
+	if len(svc.Units) > 0 {
+		fmt.Fprintf(c.out, "Units: %s (hand-edited, installed instead of generated ones)\n", strings.Join(svc.Units, ", "))
+	}
+	if svc.NotifyDesktop {
+		fmt.Fprintln(c.out, "Notifications: desktop, when a run finishes")
+	}
+	if svc.DebugEnv {
+		fmt.Fprintln(c.out, "Debug Env: each run records its user, working dir, and key environment variables")
+	}
+	if svc.SingletonLock != "" {
+		fmt.Fprintf(c.out, "Singleton Lock: %s\n", redactURL(svc.SingletonLock))
+	}
+	if len(svc.IfChanged) > 0 {
+		fmt.Fprintf(c.out, "If Changed: %s\n", strings.Join(svc.IfChanged, ", "))
+	}
+	if len(svc.Blackout) > 0 {
+		fmt.Fprintf(c.out, "Blackout: %s\n", strings.Join(svc.Blackout, ", "))
+	}
+	if len(svc.WatchPaths) > 0 {
+		note := ""
+		if svc.PollsWatchPaths(runtime.GOOS) {
+			note = " (polled every minute)"
+		}
+		fmt.Fprintf(c.out, "Watch Paths: %s%s\n", strings.Join(svc.WatchPaths, ", "), note)
+	}
+	if svc.FailPattern != "" {
+		fmt.Fprintf(c.out, "Fail Pattern: %s (runs whose output matches it fail)\n", svc.FailPattern)
+	}
+	if svc.SuccessPattern != "" {
+		fmt.Fprintf(c.out, "Success Pattern: %s (runs whose output never matches it fail)\n", svc.SuccessPattern)
+	}
+	if policy := svc.GetFailurePolicy(); policy != nil {
+		fmt.Fprintf(c.out, "On Failure: %s\n", describeFailurePolicy(policy))
+	}
+	if svc.MaxConsecutiveFailures > 0 {
+		fmt.Fprintf(c.out, "Max Consecutive Failures: %d (then runs are paused, for longer after each further failure)\n",
+			svc.MaxConsecutiveFailures)
+	}
+	if head, tail := svc.LogLineLimits(); head > 0 {
+		fmt.Fprintf(c.out, "Log Lines Per Run: %d (the first %d and last %d of each stream)\n", svc.MaxLogLines, head, tail)
+	}
+	if svc.LogTimestamps != "" {
+		fmt.Fprintf(c.out, "Log Timestamps: %s\n", svc.LogTimestamps)
+	}
+	switch svc.LogLevel {
+	case service.LogLevelErrorsOnly:
+		fmt.Fprintf(c.out, "Log Level: %s (only the output of failed runs is logged)\n", svc.LogLevel)
+	case service.LogLevelOff:
+		fmt.Fprintf(c.out, "Log Level: %s (the output of runs is not logged)\n", svc.LogLevel)
+	}
+	if delay := svc.EffectiveStartupDelay(); delay > 0 {
+		fmt.Fprintf(c.out, "Startup Delay: %s\n", delay)
+	}
+	if installed {
+		c.printIntegrity(name)
+	}
+
+	if sched := svc.GetSchedule(); sched != nil {
+		fmt.Fprintln(c.out, "Next Runs:")
+		for _, t := range sched.NextN(time.Now(), 3) {
+			if sched.Location != nil {
+				fmt.Fprintf(c.out, "  %s (%s local)\n", t.In(sched.Location).Format("Mon 2006-01-02 15:04 MST"), t.Format("Mon 15:04 MST"))
+				continue
+			}
+			fmt.Fprintf(c.out, "  %s\n", t.Format("Mon 2006-01-02 15:04 MST"))
+		}
+	} else if installed {
+		// Intervals count from boot and the last run, which only the scheduler knows
+		next, err := platformMgr.NextRun(svc)
+		if err != nil && verbose {
+			c.warning("failed to query next run: %v", err)
+		}
+		if !next.IsZero() {
+			fmt.Fprintf(c.out, "Next Run: %s\n", next.Local().Format("Mon 2006-01-02 15:04 MST"))
+		}
+	}
+
+	st, err := state.Load(c.cfg.GetServiceStateDir(), name)
+	if err != nil && verbose {
+		c.warning("%v", err)
+	}
+	if st.Paused(time.Now()) {
+		fmt.Fprintf(c.out, "Paused: %s\n", c.paint(term.Red, fmt.Sprintf("until %s (nazim reset-failures %s resumes it)",
+			st.PausedUntil.Local().Format("2006-01-02 15:04:05"), name)))
+	}
+	if budget := svc.DailyBudget.Duration; budget > 0 {
+		used := st.Runtime(time.Now())
+		line := fmt.Sprintf("%s, %s used today", budget, roundDuration(used))
+		if used >= budget {
+			line = c.paint(term.Yellow, line+", runs are skipped until tomorrow")
+		}
+		fmt.Fprintf(c.out, "Daily Budget: %s\n", line)
+	}
+	if window := svc.InBlackout(time.Now(), c.cfg.Blackout()); window != nil {
+		fmt.Fprintf(c.out, "In Blackout: %s\n", c.paint(term.Yellow, fmt.Sprintf("%s, runs are skipped", window)))
+	}
+	if s := c.checkStale(platformMgr, svc, time.Now(), c.cfg.StaleGrace()); s != nil && s.Stale {
+		fmt.Fprintf(c.out, "Stale: %s\n", c.paint(term.Red, s.describe(time.Now())))
+	}
+	if !st.AdoptedAt.IsZero() {
+		fmt.Fprintf(c.out, "Adopted: %s\n", st.AdoptedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if kept, _ := versions.Load(c.cfg.GetVersionsDir(), name, c.cfg.EncryptionKey()); len(kept) > 0 {
+		latest := kept[len(kept)-1]
+		line := fmt.Sprintf("%s, installed %s", latest, latest.Installed.Local().Format("2006-01-02 15:04:05"))
+		if len(kept) > 1 {
+			line += fmt.Sprintf(" (nazim rollback %s goes back to %s)", name, kept[len(kept)-2])
+		}
+		fmt.Fprintf(c.out, "Version: %s\n", line)
+	}
+	if st.Fallback != "" && installed {
+		fmt.Fprintf(c.out, "Install Mode: %s\n", c.paint(term.Yellow, describeFallback(st.Fallback)))
+		fmt.Fprintf(c.out, "  Reinstall it from an elevated prompt (nazim edit %s) to install it the usual way\n", name)
+	}
+	if st.FailureStreak > 0 {
+		since := "never succeeded"
+		if !st.LastSuccess.IsZero() {
+			since = "last success " + st.LastSuccess.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(c.out, "Failure Streak: %s (%s)\n",
+			c.paint(term.Red, fmt.Sprintf("%d run(s) in a row", st.FailureStreak)), since)
+	}
+
+	records, err := history.Load(c.cfg.GetHistoryDir(), name)
+	if err != nil && verbose {
+		c.warning("failed to read run history: %v", err)
+	}
+	if len(records) == 0 {
+		if !st.LastRun.IsZero() {
+			fmt.Fprintf(c.out, "Last Run: %s (exit code %d, no history kept)\n",
+				st.LastRun.Local().Format("2006-01-02 15:04:05"), st.LastExitCode)
+			return nil
+		}
+		// The run may not have gone through nazim exec
+		var started time.Time
+		if installed {
+			started, _ = platformMgr.LastRun(name)
+		}
+		if started.IsZero() {
+			fmt.Fprintln(c.out, "Last Run: never")
+		} else {
+			fmt.Fprintf(c.out, "Last Run: %s (started by the scheduler, no history recorded)\n",
+				started.Local().Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	}
+	last := records[len(records)-1]
+	slow := ""
+	if last.Slow {
+		slow = ", " + c.paint(term.Yellow, "slow")
+	}
+	fmt.Fprintf(c.out, "Last Run: %s (exit code %d, took %s%s)\n",
+		last.Start.Local().Format("2006-01-02 15:04:05"), last.ExitCode, last.Duration().Round(time.Millisecond), slow)
+	c.printDurationStats(svc, records)
+
+	if last.Failed() {
+		var details map[string]string
+		if installed {
+			details, err = platformMgr.Details(name)
+			if err != nil && verbose {
+				c.warning("failed to query scheduler: %v", err)
+			}
+		}
+		printLastFailure(c.out, last, details)
+	}
+
+	return nil
+}
+
+// describeState describes the scheduler's state of a service, such as
+// "Enabled, running" or "Disabled (not loaded)", with the scheduler's own word
+// for it when that says more.
+func describeState(state *platform.ServiceState) string {
+	desc := state.String()
+	if state.Running {
+		desc += ", running"
+	}
+	switch detail := strings.ToLower(state.BackendDetail); detail {
+	case "", "enabled", "disabled", "running":
+	default:
+		desc += fmt.Sprintf(" (%s)", state.BackendDetail)
+	}
+	return desc
+}
+
+// Number of recent runs that duration statistics and the sparkline cover.
+const (
+	statsRuns     = 50
+	sparklineRuns = 20
+)
+
+// sparkBars are the bar heights of a duration sparkline, lowest first.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// printDurationStats prints statistics and a sparkline of the recent run durations.
+func (c *CLI) printDurationStats(svc *service.Service, records []*history.Record) {
+	recent := records[max(0, len(records)-statsRuns):]
+	stats := history.DurationStats(recent)
+	if stats.Count < 2 {
+		return
+	}
+	fmt.Fprintf(c.out, "Durations (last %d runs): min %s, avg %s, max %s, p95 %s\n", stats.Count,
+		roundDuration(stats.Min), roundDuration(stats.Avg), roundDuration(stats.Max), roundDuration(stats.P95))
+
+	spark := recent[max(0, len(recent)-sparklineRuns):]
+	var line strings.Builder
+	for _, rec := range spark {
+		bar := sparkBars[0]
+		if span := stats.Max - stats.Min; span > 0 && rec.Error == "" {
+			level := int(float64(rec.Duration()-stats.Min) / float64(span) * float64(len(sparkBars)-1))
+			bar = sparkBars[min(max(level, 0), len(sparkBars)-1)]
+		}
+		switch {
+		case rec.Failed():
+			line.WriteString(c.paint(term.Red, string(bar)))
+		case rec.Slow:
+			line.WriteString(c.paint(term.Yellow, string(bar)))
+		default:
+			line.WriteRune(bar)
+		}
+	}
+	fmt.Fprintf(c.out, "Recent Durations: %s (oldest to newest)\n", line.String())
+
+	if threshold := svc.SlowThreshold.Duration; threshold > 0 {
+		slow := 0
+		for _, rec := range recent {
+			if rec.Slow {
+				slow++
+			}
+		}
+		fmt.Fprintf(c.out, "Slow Threshold: %s (%d of the last %d runs exceeded it)\n", threshold, slow, len(recent))
+	}
+}
+
+// roundDuration rounds d for display: to milliseconds below a second, to tenths
+// of a second below a minute, and to seconds above.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond)
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond)
+	default:
+		return d.Round(time.Second)
+	}
+}
+
+// printLastFailure prints the diagnostics of a failed run: nazim's own record of
+// the run followed by what the platform scheduler reports.
+func printLastFailure(w io.Writer, rec *history.Record, details map[string]string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Last Failure:")
+	fmt.Fprintf(w, "  Exit Code: %d\n", rec.ExitCode)
+	fmt.Fprintf(w, "  Failed At: %s\n", rec.End.Local().Format("2006-01-02 15:04:05"))
+	if rec.Error != "" {
+		fmt.Fprintf(w, "  Error: %s\n", rec.Error)
+	}
+	if rec.OutputError != "" {
+		fmt.Fprintf(w, "  Output Error: %s\n", rec.OutputError)
+	}
+	if len(rec.ErrorLines) > 0 {
+		fmt.Fprintln(w, "  Error Output:")
+		for _, line := range rec.ErrorLines {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	if rec.Env != nil {
+		fmt.Fprintln(w, "  Environment:")
+		fmt.Fprintf(w, "    User: %s\n", rec.Env.User)
+		fmt.Fprintf(w, "    Working Dir: %s\n", rec.Env.Dir)
+		names := make([]string, 0, len(rec.Env.Vars))
+		for name := range rec.Env.Vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "    %s=%s\n", name, rec.Env.Vars[name])
+		}
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(w, "  Scheduler (%s):\n", runtime.GOOS)
+		keys := make([]string, 0, len(details))
+		for key := range details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "    %s: %s\n", key, details[key])
+		}
+	}
+}
+
+// Edit updates an existing service.
+func (c *CLI) Edit(ctx context.Context, name string, flags *Flags, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	existingSvc, err := c.cfg.GetService(name)
+	if err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	}
+	if err := c.cfg.CheckModifiable(name); err != nil {
+		return err
+	}
+
+	for _, conflict := range []struct {
+		set, clear   bool
+		flag, option string
+	}{
+		{flags.Args != "", flags.ClearArgs, "--args", "--clear-args"},
+		{flags.ArgList != nil, flags.ClearArgs, "--arg", "--clear-args"},
+		{flags.WorkDir != "", flags.ClearWorkDir, "--workdir", "--clear-workdir"},
+		{flags.Interval != "", flags.ClearInterval, "--interval", "--clear-interval"},
+		{flags.OnStartup, flags.NoStartup, "--on-startup", "--no-startup"},
+		{flags.DebugEnv, flags.NoDebugEnv, "--debug-env", "--no-debug-env"},
+		{flags.System, flags.NoSystem, "--system", "--no-system"},
+		{flags.Elevated, flags.NoElevated, "--elevated", "--no-elevated"},
+		{flags.NotifyDesktop, flags.NoNotifyDesktop, "--notify-desktop", "--no-notify-desktop"},
+		{flags.CatchUp, flags.NoCatchUp, "--catch-up", "--no-catch-up"},
+	} {
+		if conflict.set && conflict.clear {
+			return fmt.Errorf("%s and %s cannot be combined", conflict.flag, conflict.option)
+		}
+	}
+
+	if err := existingSvc.CheckPlatform(runtime.GOOS); err != nil && !flags.ForcePlatform {
+		return fmt.Errorf("%w; use --force-platform to reinstall it here anyway", err)
+	}
+
+	// Flags change the service's own settings, which this platform's override
+	// would keep hiding, so those are edited in the config instead
+	if override := existingSvc.Platforms[runtime.GOOS]; override != nil {
+		for _, overridden := range []struct {
+			set   bool
+			field string
+		}{
+			{flags.Command != "" && override.Command != "", "command"},
+			{(flags.Args != "" || flags.ArgList != nil || flags.ClearArgs) && (override.Command != "" || len(override.Args) > 0), "args"},
+			{(flags.WorkDir != "" || flags.ClearWorkDir) && override.WorkDir != "", "workdir"},
+			{flags.Shell != "" && override.Shell != "", "shell"},
+		} {
+			if overridden.set {
+				return fmt.Errorf("'%s' sets its %s under platforms.%s; change it there in %s",
+					name, overridden.field, runtime.GOOS, c.cfg.GetConfigPath())
+			}
+		}
+	}
+
+	var intervalDuration time.Duration
+	if flags.Interval != "" {
+		var err error
+		intervalDuration, err = parseDuration(flags.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid interval format: %w", err)
+		}
 	} else {
 		intervalDuration = existingSvc.GetInterval()
 	}
 
-	var args []string
-	if flags.Args != "" {
-		args = strings.Fields(flags.Args)
-	} else {
-		args = existingSvc.Args
+	args, argsGiven, err := flags.commandArgs()
+	if err != nil {
+		return err
+	}
+
+	// Whatever the flags don't change is kept as it is
+	updated := *existingSvc
+	updated.Platform = runtime.GOOS // It is reinstalled for this platform
+	updated.System = (existingSvc.System || flags.System) && !flags.NoSystem
+	updated.Portable = existingSvc.Portable || flags.Portable
+	updated.Elevated = (existingSvc.Elevated || flags.Elevated) && !flags.NoElevated
+	updated.NotifyDesktop = (existingSvc.NotifyDesktop || flags.NotifyDesktop) && !flags.NoNotifyDesktop
+	updated.DebugEnv = (existingSvc.DebugEnv || flags.DebugEnv) && !flags.NoDebugEnv
+	// resolvePaths rewrites these in place
+	updated.WatchPaths = append([]string(nil), existingSvc.WatchPaths...)
+	updated.Units = append([]string(nil), existingSvc.Units...)
+	updated.IfChanged = append([]string(nil), existingSvc.IfChanged...)
+
+	if flags.Command != "" {
+		// A command turns a pipeline or group back into a service of its own
+		updated.Command = flags.Command
+		updated.Steps, updated.OnStepFailure = nil, ""
+		updated.ParallelGroup, updated.MaxParallel = nil, 0
+	}
+	if flags.Steps != "" {
+		steps, err := service.ParseSteps(flags.Steps)
+		if err != nil {
+			return err
+		}
+		updated.Steps = steps
+		updated.Command, updated.Args, updated.Shell = "", nil, ""
+		updated.ParallelGroup, updated.MaxParallel = nil, 0
+	}
+	if flags.ParallelGroup != "" {
+		group, err := service.ParseSteps(flags.ParallelGroup)
+		if err != nil {
+			return err
+		}
+		updated.ParallelGroup = group
+		updated.Command, updated.Args, updated.Shell = "", nil, ""
+		updated.Steps, updated.OnStepFailure = nil, ""
+	}
+	if flags.MaxParallel != "" {
+		maxParallel, err := parseMaxParallel(flags.MaxParallel)
+		if err != nil {
+			return err
+		}
+		updated.MaxParallel = maxParallel
+	}
+	if flags.OnStepFailure == "none" {
+		updated.OnStepFailure = ""
+	} else if flags.OnStepFailure != "" {
+		updated.OnStepFailure = strings.ToLower(flags.OnStepFailure)
+	}
+	if argsGiven {
+		updated.Args = args
+	}
+	if flags.WorkDir != "" {
+		updated.WorkDir = flags.WorkDir
+	}
+	if flags.MaxLogLines != "" {
+		maxLogLines, err := parseLineCount(flags.MaxLogLines)
+		if err != nil {
+			return err
+		}
+		updated.MaxLogLines = maxLogLines
+	}
+	if flags.MaxConsecutiveFailures != "" {
+		maxFailures, err := parseFailureCount(flags.MaxConsecutiveFailures)
+		if err != nil {
+			return err
+		}
+		updated.MaxConsecutiveFailures = maxFailures
+	}
+	if flags.LogTimestamps == "none" {
+		updated.LogTimestamps = ""
+	} else if flags.LogTimestamps != "" {
+		updated.LogTimestamps = strings.ToLower(flags.LogTimestamps)
+	}
+	if flags.LogLevel != "" || flags.NoLog {
+		logLevel, err := parseLogLevel(flags.LogLevel, flags.NoLog)
+		if err != nil {
+			return err
+		}
+		updated.LogLevel = logLevel
+	}
+	if flags.Shell != "" {
+		updated.Shell = flags.Shell
+	}
+	if flags.Umask == "none" {
+		updated.Umask = ""
+	} else if flags.Umask != "" {
+		mask, err := service.ParseUmask(flags.Umask)
+		if err != nil {
+			return err
+		}
+		updated.Umask = service.FormatUmask(mask)
+	}
+	if flags.Sandbox == "none" {
+		updated.Sandbox = ""
+	} else if flags.Sandbox != "" {
+		updated.Sandbox = strings.ToLower(flags.Sandbox)
+	}
+	if network := strings.ToLower(flags.Network); network == "default" {
+		updated.Network = ""
+	} else if network != "" {
+		updated.Network = network
+	}
+	if flags.Target == "none" {
+		updated.Target = ""
+	} else if flags.Target != "" {
+		updated.Target = strings.ToLower(flags.Target)
+	}
+	if len(flags.WatchPaths) == 1 && flags.WatchPaths[0] == "none" {
+		updated.WatchPaths = nil
+	} else if len(flags.WatchPaths) > 0 {
+		updated.WatchPaths = flags.WatchPaths
+	}
+	if flags.OnEvent == "none" {
+		updated.OnEvent = ""
+	} else if flags.OnEvent != "" {
+		trigger, err := service.ParseEventTrigger(flags.OnEvent)
+		if err != nil {
+			return err
+		}
+		updated.OnEvent = trigger.String()
+	}
+	if len(flags.IfChanged) == 1 && flags.IfChanged[0] == "none" {
+		updated.IfChanged = nil
+	} else if len(flags.IfChanged) > 0 {
+		updated.IfChanged = flags.IfChanged
+	}
+	if len(flags.FromUnit) == 1 && flags.FromUnit[0] == "none" {
+		updated.Units = nil
+	} else if len(flags.FromUnit) > 0 {
+		updated.Units = flags.FromUnit
+	}
+	if len(flags.Blackout) == 1 && flags.Blackout[0] == "none" {
+		updated.Blackout = nil
+	} else if len(flags.Blackout) > 0 {
+		if updated.Blackout, err = normalizeBlackouts(flags.Blackout); err != nil {
+			return err
+		}
+	}
+	if flags.OnFailure == "none" {
+		updated.OnFailure = ""
+	} else if flags.OnFailure != "" {
+		policy, err := service.ParseFailurePolicy(flags.OnFailure)
+		if err != nil {
+			return err
+		}
+		updated.OnFailure = policy.String()
+	}
+	if flags.FailPattern == "none" {
+		updated.FailPattern = ""
+	} else if flags.FailPattern != "" {
+		updated.FailPattern = flags.FailPattern
+	}
+	if flags.SuccessPattern == "none" {
+		updated.SuccessPattern = ""
+	} else if flags.SuccessPattern != "" {
+		updated.SuccessPattern = flags.SuccessPattern
+	}
+	if flags.Description != "" {
+		updated.Description = flags.Description
+	}
+	if flags.Owner != "" {
+		updated.Owner = flags.Owner
+	}
+	if len(flags.Labels) > 0 {
+		changes, err := parseLabels(flags.Labels)
+		if err != nil {
+			return err
+		}
+		labels := make(map[string]string, len(existingSvc.Labels)+len(changes))
+		for key, value := range existingSvc.Labels {
+			labels[key] = value
+		}
+		for key, value := range changes {
+			if value == "" {
+				delete(labels, key)
+			} else {
+				labels[key] = value
+			}
+		}
+		if len(labels) == 0 {
+			labels = nil
+		}
+		updated.Labels = labels
+	}
+	if flags.Interval != "" {
+		updated.Interval = service.Duration{Duration: intervalDuration}
+	}
+	if flags.ClearArgs {
+		updated.Args = nil
+	}
+	if flags.ClearWorkDir {
+		updated.WorkDir = ""
+	}
+	if flags.ClearInterval {
+		updated.Interval = service.Duration{}
+	}
+	if flags.NoStartup {
+		updated.OnStartup = false
+	}
+
+	if flags.OnStartup {
+		updated.OnStartup = true
+		updated.Schedule = ""
+		if flags.Interval == "" {
+			// Given --interval too, it runs at boot and then every interval
+			updated.Interval = service.Duration{Duration: 0}
+		}
+	} else if flags.Interval != "" {
+		updated.OnStartup = false
+		updated.Schedule = ""
+	}
+
+	if flags.Schedule != "" {
+		sched, err := schedule.Parse(flags.Schedule)
+		if err != nil {
+			return err
+		}
+		updated.Schedule = sched.String()
+		updated.Interval = service.Duration{Duration: 0}
+		updated.OnStartup = false
+		updated.OnLogon = false
+	}
+
+	if flags.LongRunning {
+		updated.LongRunning = true
+		updated.OnStartup = false
+		updated.OnLogon = false
+		updated.Interval = service.Duration{}
+		updated.Schedule = ""
+	} else if flags.OnStartup || flags.Interval != "" || flags.Schedule != "" {
+		updated.LongRunning = false
+	}
+
+	if flags.Timezone == "local" {
+		updated.Timezone = ""
+	} else if flags.Timezone != "" {
+		updated.Timezone = flags.Timezone
+	}
+	// A time zone is meaningless once the schedule is gone
+	if updated.Schedule == "" && flags.Timezone == "" {
+		updated.Timezone = ""
+	}
+
+	// A startup delay is meaningless once the service no longer runs at boot/logon
+	if !updated.OnStartup && !updated.OnLogon {
+		updated.StartupDelay = service.Duration{}
+	}
+	// Likewise an offset once the interval is gone or counts from boot/logon
+	if updated.Interval.Duration == 0 || updated.OnStartup || updated.OnLogon {
+		updated.Offset = ""
+	}
+
+	if flags.Offset == "none" {
+		updated.Offset = ""
+	} else if flags.Offset != "" {
+		offset, err := service.ParseOffset(flags.Offset)
+		if err != nil {
+			return fmt.Errorf("invalid offset format: %w", err)
+		}
+		updated.Offset = service.FormatOffset(offset)
+	}
+
+	if flags.StartupDelay != "" {
+		startupDelay, err := parseDuration(flags.StartupDelay)
+		if err != nil {
+			return fmt.Errorf("invalid startup delay format: %w", err)
+		}
+		updated.StartupDelay = service.Duration{Duration: startupDelay}
+	}
+	if flags.Priority != "" {
+		updated.Priority = strings.ToLower(flags.Priority)
+	}
+	if flags.SingletonLock == "none" {
+		updated.SingletonLock = ""
+	} else if flags.SingletonLock != "" {
+		updated.SingletonLock = flags.SingletonLock
+	}
+	if flags.SlowThreshold == "none" {
+		updated.SlowThreshold = service.Duration{}
+	} else if flags.SlowThreshold != "" {
+		slowThreshold, err := parseDuration(flags.SlowThreshold)
+		if err != nil {
+			return fmt.Errorf("invalid slow threshold format: %w", err)
+		}
+		updated.SlowThreshold = service.Duration{Duration: slowThreshold}
+	}
+	if flags.DailyBudget == "none" {
+		updated.DailyBudget = service.Duration{}
+	} else if flags.DailyBudget != "" {
+		budget, err := parseDuration(flags.DailyBudget)
+		if err != nil {
+			return fmt.Errorf("invalid daily budget format: %w", err)
+		}
+		updated.DailyBudget = service.Duration{Duration: budget}
+	}
+	if flags.Timeout == "none" {
+		updated.Timeout = service.Duration{}
+	} else if flags.Timeout != "" {
+		timeout, err := parseDuration(flags.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout format: %w", err)
+		}
+		updated.Timeout = service.Duration{Duration: timeout}
+	}
+
+	if flags.CatchUp {
+		updated.CatchUp = true
+	} else if flags.NoCatchUp || !updated.RequiresScheduling() {
+		// Only interval and calendar runs can be missed
+		updated.CatchUp = false
+	}
+
+	if err := c.resolvePaths(&updated); err != nil {
+		return err
+	}
+
+	if !updated.HasOtherTrigger() && len(updated.WatchPaths) == 0 {
+		return fmt.Errorf("service '%s' would be left without a trigger; add --on-startup, --interval, --schedule, --watch-path, --on-event, or --long-running", name)
+	}
+	if err := c.cfg.ValidateService(&updated); err != nil {
+		return err
+	}
+	if err := checkTarget(&updated); err != nil {
+		return err
+	}
+	if err := c.checkUnits(&updated); err != nil {
+		return err
+	}
+
+	if flags.OnFailure != "" {
+		if err := c.checkFailurePolicy(&updated); err != nil {
+			return err
+		}
+	}
+
+	commandChanged := flags.Command != "" || flags.Steps != "" || flags.ParallelGroup != "" || argsGiven || flags.WorkDir != "" || flags.Shell != "" || flags.ClearArgs || flags.ClearWorkDir
+	if commandChanged && !flags.NoVerify {
+		if err := c.verifyCommand(&updated); err != nil {
+			return err
+		}
+	}
+
+	if err := c.cfg.UpdateService(&updated); err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+
+	if verbose {
+		c.infof("Service '%s' updated in configuration.\n", name)
+	}
+
+	sp := c.startProgress(fmt.Sprintf("Reinstalling '%s'", name), verbose)
+	defer sp.Stop()
+
+	platformMgr, err := c.newManager(platform.WithProgress(sp.Step))
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	if err := platformMgr.Uninstall(name); err != nil {
+		if c.strict {
+			return fmt.Errorf("failed to uninstall old service: %w", err)
+		}
+		if verbose {
+			c.warning("failed to uninstall old service: %v", err)
+		}
+	}
+
+	if err := platformMgr.Install(&updated); err != nil {
+		return fmt.Errorf("failed to reinstall service: %w", err)
+	}
+	sp.Stop()
+
+	c.serviceEvent(events.ServiceUpdated, name)
+	c.infof("Service '%s' updated successfully!\n", name)
+	return nil
+}
+
+// serviceFromFlags builds a service from add flags, running command.
+func serviceFromFlags(flags *Flags, command string) (*service.Service, error) {
+	var intervalDuration time.Duration
+	if flags.Interval != "" {
+		var err error
+		intervalDuration, err = parseDuration(flags.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval format: %w", err)
+		}
+	}
+
+	var offset string
+	if flags.Offset != "" {
+		d, err := service.ParseOffset(flags.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset format: %w", err)
+		}
+		offset = service.FormatOffset(d)
+	}
+
+	var scheduleStr string
+	if flags.Schedule != "" {
+		sched, err := schedule.Parse(flags.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		scheduleStr = sched.String()
+	}
+
+	var startupDelay time.Duration
+	if flags.StartupDelay != "" {
+		var err error
+		startupDelay, err = parseDuration(flags.StartupDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startup delay format: %w", err)
+		}
+	}
+
+	var slowThreshold time.Duration
+	if flags.SlowThreshold != "" {
+		var err error
+		slowThreshold, err = parseDuration(flags.SlowThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slow threshold format: %w", err)
+		}
+	}
+
+	var dailyBudget time.Duration
+	if flags.DailyBudget != "" {
+		var err error
+		dailyBudget, err = parseDuration(flags.DailyBudget)
+		if err != nil {
+			return nil, fmt.Errorf("invalid daily budget format: %w", err)
+		}
+	}
+
+	var timeout time.Duration
+	if flags.Timeout != "" {
+		var err error
+		timeout, err = parseDuration(flags.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout format: %w", err)
+		}
+	}
+
+	var maxLogLines int
+	if flags.MaxLogLines != "" {
+		var err error
+		maxLogLines, err = parseLineCount(flags.MaxLogLines)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var maxFailures int
+	if flags.MaxConsecutiveFailures != "" {
+		var err error
+		maxFailures, err = parseFailureCount(flags.MaxConsecutiveFailures)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logLevel, err := parseLogLevel(flags.LogLevel, flags.NoLog)
+	if err != nil {
+		return nil, err
+	}
+
+	network := strings.ToLower(flags.Network)
+	if network == "default" {
+		network = ""
+	}
+
+	var umask string
+	if flags.Umask != "" {
+		mask, err := service.ParseUmask(flags.Umask)
+		if err != nil {
+			return nil, err
+		}
+		umask = service.FormatUmask(mask)
+	}
+
+	var onEvent string
+	if flags.OnEvent != "" {
+		trigger, err := service.ParseEventTrigger(flags.OnEvent)
+		if err != nil {
+			return nil, err
+		}
+		onEvent = trigger.String()
+	}
+
+	var onFailure string
+	if flags.OnFailure != "" {
+		policy, err := service.ParseFailurePolicy(flags.OnFailure)
+		if err != nil {
+			return nil, err
+		}
+		onFailure = policy.String()
+	}
+
+	blackout, err := normalizeBlackouts(flags.Blackout)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := parseLabels(flags.Labels)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range labels {
+		if value == "" {
+			delete(labels, key)
+		}
+	}
+
+	args, _, err := flags.commandArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps, group []string
+	if flags.Steps != "" {
+		if steps, err = service.ParseSteps(flags.Steps); err != nil {
+			return nil, err
+		}
+	}
+	if flags.ParallelGroup != "" {
+		if group, err = service.ParseSteps(flags.ParallelGroup); err != nil {
+			return nil, err
+		}
+	}
+	var maxParallel int
+	if flags.MaxParallel != "" {
+		if maxParallel, err = parseMaxParallel(flags.MaxParallel); err != nil {
+			return nil, err
+		}
+	}
+
+	svc := &service.Service{
+		Name:      flags.Name,
+		Command:   command,
+		Args:      args,
+		WorkDir:   flags.WorkDir,
+		Shell:     flags.Shell,
+		OnStartup: flags.OnStartup,
+		OnLogon:   flags.OnLogon,
+		Interval:  service.Duration{Duration: intervalDuration},
+		Offset:    offset,
+		Schedule:  scheduleStr,
+		Timezone:  flags.Timezone,
+		Enabled:   true,
+		Platform:  runtime.GOOS,
+		Target:    strings.ToLower(flags.Target),
+
+		WatchPaths: flags.WatchPaths,
+		OnEvent:    onEvent,
+
+		LongRunning: flags.LongRunning,
+		System:      flags.System,
+
+		Units: flags.FromUnit,
+
+		Steps:         steps,
+		OnStepFailure: strings.ToLower(flags.OnStepFailure),
+		ParallelGroup: group,
+		MaxParallel:   maxParallel,
+
+		StartupDelay: service.Duration{Duration: startupDelay},
+		Priority:     strings.ToLower(flags.Priority),
+		CatchUp:      flags.CatchUp,
+		Portable:     flags.Portable,
+		Elevated:     flags.Elevated,
+		Umask:        umask,
+		Sandbox:      strings.ToLower(flags.Sandbox),
+		Network:      network,
+
+		NotifyDesktop: flags.NotifyDesktop,
+		SingletonLock: flags.SingletonLock,
+		IfChanged:     flags.IfChanged,
+		Blackout:      blackout,
+		SlowThreshold: service.Duration{Duration: slowThreshold},
+		DailyBudget:   service.Duration{Duration: dailyBudget},
+		Timeout:       service.Duration{Duration: timeout},
+		MaxLogLines:   maxLogLines,
+		LogTimestamps: strings.ToLower(flags.LogTimestamps),
+		LogLevel:      logLevel,
+		OnFailure:     onFailure,
+		DebugEnv:      flags.DebugEnv,
+
+		FailPattern:    flags.FailPattern,
+		SuccessPattern: flags.SuccessPattern,
+
+		MaxConsecutiveFailures: maxFailures,
+
+		Description: flags.Description,
+		Owner:       flags.Owner,
+		Labels:      labels,
+	}
+
+	return svc, nil
+}
+
+// parseLabels parses --label key=value pairs. A later pair for the same key wins.
+func parseLabels(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid label %q: expected key=value", pair)
+		}
+		labels[strings.TrimSpace(key)] = value
+	}
+	return labels, nil
+}
+
+// commandArgs returns the command's arguments the flags give and whether they
+// give any: --args split shell-style (see service.SplitArgs), or the arguments
+// given one at a time with --arg or after --, as they are. NAZIM_ARGS_SPLIT=fields
+// splits --args on whitespace alone instead, as nazim did before it read quotes.
+func (f *Flags) commandArgs() ([]string, bool, error) {
+	switch {
+	case f.Args != "" && f.ArgList != nil:
+		return nil, false, fmt.Errorf("--args can't be combined with --arg or arguments after --")
+	case f.ArgList != nil:
+		return f.ArgList, true, nil
+	case f.Args == "":
+		return nil, false, nil
+	case os.Getenv("NAZIM_ARGS_SPLIT") == "fields":
+		return strings.Fields(f.Args), true, nil
+	}
+	args, err := service.SplitArgs(f.Args)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid --args: %w", err)
+	}
+	return args, true, nil
+}
+
+// parseLogLevel parses the --log-level value, with --no-log standing for off.
+// The default level, all, is left unset.
+func parseLogLevel(level string, noLog bool) (string, error) {
+	level = strings.ToLower(level)
+	if noLog {
+		if level != "" && level != service.LogLevelOff {
+			return "", fmt.Errorf("--no-log can't be combined with --log-level %s", level)
+		}
+		return service.LogLevelOff, nil
+	}
+	if level == service.LogLevelAll {
+		return "", nil
+	}
+	return level, nil
+}
+
+// parseLineCount parses the --max-log-lines-per-run value.
+func parseLineCount(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid line count %q: expected a number of lines such as 1000", s)
+	}
+	return n, nil
+}
+
+// parseMaxParallel parses the --max-parallel value.
+func parseMaxParallel(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid --max-parallel %q: expected how many services run at a time, such as 4", s)
+	}
+	return n, nil
+}
+
+// parseFailureCount parses the --max-consecutive-failures value.
+func parseFailureCount(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid failure count %q: expected a number of failed runs such as 3", s)
+	}
+	return n, nil
+}
+
+// resolvePaths records relative command, working directory, and watched paths
+// explicitly. Normally they are made absolute: the working directory against
+// the current directory, and the command and watched paths against the working
+// directory (where it will start) or the current directory. Portable services instead keep paths inside the
+// config directory relative to it, and relative paths are taken to be relative
+// to the config directory, so the config can move between machines.
+func (c *CLI) resolvePaths(svc *service.Service) error {
+	// Unit files stay where they are, such as a checkout of an infra repo
+	for i, path := range svc.Units {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("resolving unit file: %w", err)
+		}
+		svc.Units[i] = abs
+	}
+
+	if svc.Portable {
+		var err error
+		if svc.WorkDir, err = c.portablePath(svc.WorkDir); err != nil {
+			return err
+		}
+		if svc.CommandIsPath() {
+			if svc.Command, err = c.portablePath(svc.Command); err != nil {
+				return err
+			}
+		}
+		for _, paths := range [][]string{svc.IfChanged, svc.WatchPaths} {
+			for i, path := range paths {
+				if paths[i], err = c.portablePath(path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if svc.WorkDir != "" && !filepath.IsAbs(svc.WorkDir) {
+		abs, err := filepath.Abs(svc.WorkDir)
+		if err != nil {
+			return fmt.Errorf("resolving working directory: %w", err)
+		}
+		svc.WorkDir = abs
+	}
+
+	if svc.CommandIsPath() && !filepath.IsAbs(svc.Command) {
+		if svc.WorkDir != "" {
+			svc.Command = filepath.Join(svc.WorkDir, svc.Command)
+		} else {
+			abs, err := filepath.Abs(svc.Command)
+			if err != nil {
+				return fmt.Errorf("resolving command path: %w", err)
+			}
+			svc.Command = abs
+		}
+	}
+
+	for _, paths := range [][]string{svc.IfChanged, svc.WatchPaths} {
+		for i, path := range paths {
+			if filepath.IsAbs(path) {
+				continue
+			}
+			if svc.WorkDir != "" {
+				paths[i] = filepath.Join(svc.WorkDir, path)
+				continue
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("resolving watched path: %w", err)
+			}
+			paths[i] = abs
+		}
+	}
+	return nil
+}
+
+// portablePath makes an absolute path inside the config directory relative to it.
+// Relative paths are already relative to the config directory and are kept;
+// absolute paths elsewhere are kept with a warning, as they won't move with the config.
+func (c *CLI) portablePath(p string) (string, error) {
+	if p == "" || !filepath.IsAbs(p) {
+		return p, nil
 	}
+	rel, err := filepath.Rel(c.cfg.ConfigDir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return p, c.warn("%s is outside the config directory and is not portable", p)
+	}
+	return rel, nil
+}
 
-	updatedSvc := &service.Service{
-		Name:      name,
-		Command:   existingSvc.Command,
-		Args:      existingSvc.Args,
-		WorkDir:   existingSvc.WorkDir,
-		OnStartup: existingSvc.OnStartup,
-		Interval:  existingSvc.Interval,
-		Enabled:   existingSvc.Enabled,
-		Platform:  existingSvc.Platform,
+// verifyCommand checks that the service command can be started, printing warnings
+// for problems that may be harmless.
+func (c *CLI) verifyCommand(svc *service.Service) error {
+	if svc.RunsServices() {
+		return c.verifyMembers(svc)
+	}
+	warnings, err := runner.Verify(svc.ResolvePaths(c.cfg.ConfigDir))
+	if err != nil {
+		return fmt.Errorf("%w; use --no-verify to skip this check", err)
 	}
+	for _, warning := range warnings {
+		if err := c.warn("%s", warning); err != nil {
+			return fmt.Errorf("%w; use --no-verify to skip this check", err)
+		}
+	}
+	return nil
+}
 
-	if flags.Command != "" {
-		updatedSvc.Command = flags.Command
+// Exec runs a service in the foreground and records its output.
+// This is what scheduled tasks, units, and agents invoke; it returns the command's exit code.
+// With catchUp set, the run only happens if a scheduled run was missed since the last one,
+// which lets boot-time catch-up triggers fire unconditionally.
+// With repeat set, a service with an interval under a minute runs at that interval
+// until the minute is over, for schedulers that can't trigger it more often; the
+// exit code is that of the last run.
+// With poll set, the run only happens if the service's watch_paths changed since
+// the last poll, for schedulers without a file trigger that poll them instead.
+// Started by the Windows service control manager, as long-running services
+// are, it reports to it while the service runs, and stops the run when asked.
+func (c *CLI) Exec(ctx context.Context, name string, catchUp, repeat, poll, verbose bool) (int, error) {
+	run := func(ctx context.Context) (int, error) {
+		code, err := c.exec(ctx, name, catchUp, repeat, poll, verbose)
+		if errors.Is(err, errStillRunning) {
+			// Recorded as skipped; the scheduler has nothing to retry
+			return 0, nil
+		}
+		return code, err
 	}
-	if flags.Args != "" {
-		updatedSvc.Args = args
+	if platform.IsWindowsService() {
+		return platform.RunAsService(ctx, run)
 	}
-	if flags.WorkDir != "" {
-		updatedSvc.WorkDir = flags.WorkDir
+	return run(ctx)
+}
+
+// errStillRunning is returned by exec when it skipped a run because the
+// previous one is still going, which a pipeline must not take for a step
+// that succeeded.
+var errStillRunning = errors.New("the previous run is still going")
+
+// exec is Exec, once it answered the service control manager if need be.
+func (c *CLI) exec(ctx context.Context, name string, catchUp, repeat, poll, verbose bool) (int, error) {
+	svc, err := c.cfg.GetService(name)
+	if err != nil {
+		return 1, fmt.Errorf("service '%s' does not exist", name)
 	}
-	if flags.Interval != "" {
-		updatedSvc.Interval = service.Duration{Duration: intervalDuration}
+
+	st, err := state.Load(c.cfg.GetServiceStateDir(), name)
+	if err != nil {
+		c.warning("%v", err)
+	}
+	if st.Paused(time.Now()) {
+		if verbose {
+			fmt.Fprintf(c.errOut, "Service '%s' is paused until %s, skipping\n", name, st.PausedUntil.Local().Format("2006-01-02 15:04:05"))
+		}
+		return 0, nil
 	}
 
-	if flags.OnStartup {
-		updatedSvc.OnStartup = true
-		updatedSvc.Interval = service.Duration{Duration: 0}
-	} else if flags.Interval != "" {
-		updatedSvc.OnStartup = false
+	if window := svc.InBlackout(time.Now(), c.cfg.Blackout()); window != nil {
+		c.skipRun(svc, fmt.Sprintf("in blackout window %s", window))
+		return 0, nil
 	}
 
-	if err := updatedSvc.Validate(); err != nil {
-		return err
+	if poll {
+		changed, err := c.pollWatchPaths(svc)
+		if err != nil {
+			return 1, err
+		}
+		if !changed {
+			if verbose {
+				fmt.Fprintf(c.errOut, "Watched paths of '%s' have not changed, skipping\n", name)
+			}
+			return 0, nil
+		}
 	}
 
-	if err := c.cfg.UpdateService(updatedSvc); err != nil {
-		return fmt.Errorf("failed to update service: %w", err)
+	if catchUp {
+		last, err := history.Last(c.cfg.GetHistoryDir(), name)
+		if err != nil {
+			return 1, fmt.Errorf("failed to read run history: %w", err)
+		}
+		var lastRun time.Time
+		if last != nil {
+			lastRun = last.Start
+		}
+		if !svc.MissedRun(lastRun, time.Now()) {
+			if verbose {
+				fmt.Fprintf(c.errOut, "Service '%s' has not missed a run, skipping\n", name)
+			}
+			return 0, nil
+		}
+	}
+
+	// The policy may have changed, or the config been edited by hand, since install
+	if err := c.cfg.CheckPolicy(svc); err != nil {
+		return 1, fmt.Errorf("service '%s' is not allowed to run: %w", name, err)
+	}
+	// The system config is as much the administrator's as the signed bundle
+	if c.cfg.Managed() && c.cfg.Scope(name) == config.ScopeUser {
+		if err := c.checkSigned(svc); err != nil {
+			return 1, fmt.Errorf("service '%s' is not allowed to run: %w", name, err)
+		}
+	}
+
+	runs := 1
+	if repeat {
+		runs = svc.RunsPerMinute()
+	}
+	start := time.Now()
+	code := 0
+	for i := 0; i < runs; i++ {
+		if i > 0 {
+			// Slots a long run overlapped are skipped rather than run back to back
+			next := start.Add(time.Duration(i) * svc.GetInterval())
+			if time.Now().After(next) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return code, nil
+			case <-time.After(time.Until(next)):
+			}
+		}
+		var err error
+		code, err = c.execOnce(ctx, svc, verbose)
+		if errors.Is(err, errStillRunning) && i+1 < runs {
+			continue
+		}
+		if err != nil {
+			return code, err
+		}
+	}
+	return code, nil
+}
+
+// timeoutError is the cause a run stopped by the service's timeout reports.
+func timeoutError(timeout time.Duration) error {
+	return fmt.Errorf("timeout of %s reached", timeout)
+}
+
+// execOnce runs a service once for Exec, recording and reporting the run.
+func (c *CLI) execOnce(ctx context.Context, svc *service.Service, verbose bool) (int, error) {
+	name := svc.Name
+	runCtx := ctx
+	if budget := svc.DailyBudget.Duration; budget > 0 {
+		st, err := state.Load(c.cfg.GetServiceStateDir(), name)
+		if err != nil {
+			c.warning("%v", err)
+		}
+		used := fmt.Errorf("daily budget of %s used up", budget)
+		remaining := budget - st.Runtime(time.Now())
+		if remaining <= 0 {
+			c.skipRun(svc, used.Error())
+			return 0, nil
+		}
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeoutCause(ctx, remaining, used)
+		defer cancel()
+	}
+	if timeout := svc.Timeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeoutCause(runCtx, timeout, timeoutError(timeout))
+		defer cancel()
+	}
+	if svc.SingletonLock == "" && !svc.LongRunning {
+		// A run started by hand, to catch up, or by a failure policy must not
+		// overlap one the scheduler started
+		held, err := lock.AcquireFile(ctx, c.cfg.GetRunLocksDir(), name, 0)
+		var heldErr *lock.HeldError
+		if errors.As(err, &heldErr) {
+			c.skipRun(svc, errStillRunning.Error())
+			return 0, errStillRunning
+		}
+		if err != nil {
+			c.warning("overlap guard: %v", err)
+		} else {
+			defer func() {
+				if err := held.Release(context.Background()); err != nil {
+					c.warning("overlap guard: %v", err)
+				}
+			}()
+		}
+	}
+	if svc.SingletonLock != "" {
+		held, err := lock.Acquire(ctx, svc.SingletonLock, name)
+		var heldErr *lock.HeldError
+		if errors.As(err, &heldErr) {
+			// Another host is running it; that's the point of the lock, not a failure
+			fmt.Fprintf(c.errOut, "Skipping '%s': %v\n", name, heldErr)
+			return 0, nil
+		}
+		if err != nil {
+			return 1, fmt.Errorf("failed to acquire singleton lock: %w", err)
+		}
+		defer func() {
+			if err := held.Release(context.Background()); err != nil {
+				c.warning("singleton lock: %v", err)
+			}
+		}()
+	}
+
+	var snapshot watch.Snapshot
+	if len(svc.IfChanged) > 0 {
+		var changed bool
+		if snapshot, changed = c.watchedChanged(svc); !changed {
+			if verbose {
+				fmt.Fprintf(c.errOut, "Watched files of '%s' have not changed, skipping\n", name)
+			}
+			return 0, nil
+		}
+	}
+
+	opts := runner.Options{LogsDir: c.cfg.GetLogsDir()}
+	var transcript bytes.Buffer
+	if c.cfg.Upload() != nil {
+		opts.Transcript = &transcript
+	}
+	c.writeEvent(eventlog.Event{
+		ID:      eventlog.IDStarted,
+		Message: fmt.Sprintf("Service '%s' started.", name),
+		Fields:  [][2]string{{"Service", name}, {"Command", commandLine(svc)}},
+	})
+	var result *runner.Result
+	var err error
+	switch {
+	case svc.IsPipeline():
+		result, err = c.runPipeline(runCtx, svc, opts, verbose)
+	case svc.IsGroup():
+		result, err = c.runGroup(runCtx, svc, opts, verbose)
+	default:
+		result, err = runner.Run(runCtx, svc.ResolvePaths(c.cfg.ConfigDir), opts)
+	}
+	if err != nil {
+		c.writeEvent(eventlog.Event{
+			ID:      eventlog.IDStartFailed,
+			Message: fmt.Sprintf("Service '%s' could not be run.", name),
+			Fields:  [][2]string{{"Service", name}, {"Error", err.Error()}},
+		})
+		return 1, fmt.Errorf("failed to run service: %w", err)
+	}
+
+	rec := &history.Record{
+		ID:       result.ID,
+		Service:  name,
+		Start:    result.Start,
+		End:      result.End,
+		ExitCode: result.ExitCode,
+		Error:    result.Error,
+		Env:      (*history.Environment)(result.Env),
+
+		OutputError: result.OutputError,
+	}
+	// Successful runs don't need their stderr kept around
+	if rec.Failed() {
+		rec.ErrorLines = result.ErrorLines
+	}
+	if threshold := svc.SlowThreshold.Duration; threshold > 0 && rec.Duration() > threshold {
+		rec.Slow = true
+		c.warning("service '%s' took %s, over its slow threshold of %s",
+			name, roundDuration(rec.Duration()), threshold)
+	}
+	c.writeEvent(runEvent(rec))
+	if rec.Slow {
+		event := runEvent(rec)
+		event.ID = eventlog.IDSlow
+		event.Message = fmt.Sprintf("Service '%s' took %s, over its slow threshold of %s.",
+			name, roundDuration(rec.Duration()), svc.SlowThreshold.Duration)
+		c.writeEvent(event)
+	}
+	if err := history.Append(c.cfg.GetHistoryDir(), rec); err != nil {
+		// The run itself happened; losing its record shouldn't change the exit code
+		c.warning("failed to record run: %v", err)
+	} else if _, err := history.Prune(c.cfg.GetHistoryDir(), name, c.cfg.Retention(), time.Now()); err != nil {
+		c.warning("failed to prune run history: %v", err)
+	}
+	st := c.recordState(rec)
+	if rec.Failed() {
+		c.escalateFailure(svc, st.FailureStreak)
+		c.pauseFailing(svc, st)
+	}
+
+	// Until a run succeeds, the changes it was for are still pending
+	if snapshot != nil && !rec.Failed() {
+		if err := snapshot.Save(c.cfg.GetSnapshotsDir(), name); err != nil {
+			c.warning("failed to save snapshot of watched files: %v", err)
+		}
 	}
 
 	if verbose {
-		fmt.Printf("Service '%s' updated in configuration.\n", name)
+		fmt.Fprintf(c.errOut, "Service '%s' finished with exit code %d in %s\n",
+			name, result.ExitCode, result.Duration().Round(time.Millisecond))
+		if rec.OutputError != "" {
+			fmt.Fprintf(c.errOut, "Run failed: %s\n", rec.OutputError)
+		}
+	}
+
+	if settings := c.cfg.Upload(); settings != nil {
+		// Like a lost history record, a failed upload doesn't change the exit code
+		if key, err := c.uploadRun(ctx, settings, rec, transcript.Bytes()); err != nil {
+			c.warning("failed to upload output of '%s': %v", name, err)
+		} else if verbose {
+			fmt.Fprintf(c.errOut, "Uploaded output to %s\n", key)
+		}
+	}
+
+	if settings := c.cfg.Telemetry(); settings != nil {
+		if err := c.reportRun(ctx, settings, svc, rec); err != nil {
+			c.warning("failed to report run of '%s' to the telemetry collector: %v", name, err)
+		}
+	}
+
+	if svc.NotifyDesktop {
+		// Slow runs are as urgent as failures: something is off either way
+		if err := notify.Desktop(fmt.Sprintf("nazim: %s", name), describeRun(rec), rec.Failed() || rec.Slow); err != nil {
+			c.warning("failed to show desktop notification: %v", err)
+		}
+	}
+	if rec.OutputError != "" {
+		// The command exited with 0, but the scheduler should see the failure too
+		return 1, nil
+	}
+	return result.ExitCode, nil
+}
+
+// recordState records a finished run in the state of its service, returning
+// the updated state. Services that ran before nazim kept state take their
+// failure streak from the run history.
+func (c *CLI) recordState(rec *history.Record) *state.State {
+	st, err := state.Update(c.cfg.GetServiceStateDir(), rec.Service, func(s *state.State) {
+		first := s.LastRunID == ""
+		s.RecordRun(rec.ID, rec.End, rec.ExitCode, rec.Failed())
+		s.AddRuntime(rec.End, rec.Duration())
+		if first && rec.Failed() {
+			if records, err := history.Load(c.cfg.GetHistoryDir(), rec.Service); err == nil {
+				s.FailureStreak = max(s.FailureStreak, history.ConsecutiveFailures(records))
+			}
+		}
+	})
+	if err != nil {
+		c.warning("failed to save state of '%s': %v", rec.Service, err)
+	}
+	return st
+}
+
+// skipRun records, for Exec, that a run of svc was skipped and why, such as
+// for a blackout window: in its logs, in the events log, and in its state, so
+// that it isn't taken for stale over a weekend it was meant to sit out.
+func (c *CLI) skipRun(svc *service.Service, reason string) {
+	fmt.Fprintf(c.errOut, "Skipping '%s': %s\n", svc.Name, reason)
+	runner.Skip(svc, runner.Options{LogsDir: c.cfg.GetLogsDir()}, reason)
+	c.writeEvent(eventlog.Event{
+		ID:      eventlog.IDSkipped,
+		Message: fmt.Sprintf("Service '%s' skipped a run: %s.", svc.Name, reason),
+		Fields:  [][2]string{{"Service", svc.Name}, {"Reason", reason}},
+	})
+	_, err := state.Update(c.cfg.GetServiceStateDir(), svc.Name, func(s *state.State) {
+		s.LastSkipped = time.Now()
+	})
+	if err != nil {
+		c.warning("failed to save state of '%s': %v", svc.Name, err)
+	}
+}
+
+// uploadTimeout bounds how long a run's output upload may take.
+const uploadTimeout = time.Minute
+
+// uploadRun copies the transcript of a run to the upload destination, as
+// <host>/<service>/<start>-<run ID>.log below its prefix, returning the key.
+func (c *CLI) uploadRun(ctx context.Context, settings *config.UploadSettings, rec *history.Record, transcript []byte) (string, error) {
+	envFile := settings.EnvFile
+	if envFile != "" && !filepath.IsAbs(envFile) {
+		envFile = filepath.Join(c.cfg.ConfigDir, envFile)
+	}
+	getenv, err := upload.Getenv(envFile)
+	if err != nil {
+		return "", err
+	}
+	target, err := upload.New(settings.URL, getenv)
+	if err != nil {
+		return "", err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	key := fmt.Sprintf("%s/%s/%s-%s.log", host, rec.Service, rec.Start.UTC().Format("20060102T150405Z"), rec.ID)
+
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+	return key, target.Put(ctx, key, transcript)
+}
+
+// escalateFailure applies a service's on_failure policy after a failed run, the
+// last of failures in a row: it starts the remediation service, or disables the
+// service once it failed the given number of runs in a row, notifying the desktop.
+func (c *CLI) escalateFailure(svc *service.Service, failures int) {
+	policy := svc.GetFailurePolicy()
+	if policy == nil {
+		return
 	}
+	platformMgr, err := c.newManager()
+	if err != nil {
+		c.warning("on_failure: failed to create platform manager: %v", err)
+		return
+	}
+
+	if policy.Run != "" {
+		if _, err := c.cfg.GetService(policy.Run); err != nil {
+			c.warning("on_failure: service '%s' does not exist", policy.Run)
+		} else if err := platformMgr.Run(policy.Run); err != nil {
+			c.warning("on_failure: failed to start '%s': %v", policy.Run, err)
+		} else {
+			fmt.Fprintf(c.errOut, "Service '%s' failed; started '%s'\n", svc.Name, policy.Run)
+		}
+		return
+	}
+
+	if failures < policy.DisableAfter {
+		return
+	}
+	if platform.RequiresElevation() {
+		// Disabling would re-run this command elevated, running the service again
+		c.warning("on_failure: cannot disable '%s' without administrator privileges; install it with --elevated", svc.Name)
+		return
+	}
+	if err := platformMgr.Disable(svc.Name); err != nil {
+		c.warning("on_failure: failed to disable '%s': %v", svc.Name, err)
+		return
+	}
+
+	fmt.Fprintf(c.errOut, "Service '%s' disabled after %d failed runs in a row\n", svc.Name, failures)
+	message := fmt.Sprintf("Disabled after %d failed runs in a row. Run 'nazim enable %s' once it is fixed.", failures, svc.Name)
+	if err := notify.Desktop(fmt.Sprintf("nazim: %s", svc.Name), message, true); err != nil {
+		c.warning("failed to show desktop notification: %v", err)
+	}
+}
+
+// checkTarget checks that the scheduler a service targets can be reached from
+// here, before it is saved to the config.
+func checkTarget(svc *service.Service) error {
+	if svc.OnWindowsHost() && !platform.IsWSL() {
+		return fmt.Errorf("target %s needs nazim running inside WSL", service.TargetWindowsHost)
+	}
+	return nil
+}
 
-	platformMgr, err := platform.NewManager()
+// checkUnits checks that the hand-edited units of a service fit it, before it
+// is saved to the config, which reads them as Install would.
+func (c *CLI) checkUnits(svc *service.Service) error {
+	if len(svc.Units) == 0 {
+		return nil
+	}
+	platformMgr, err := c.newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create platform manager: %w", err)
 	}
+	_, err = platformMgr.Preview(svc)
+	return err
+}
 
-	if err := platformMgr.Uninstall(name); err != nil {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "Warning: failed to uninstall old service: %v\n", err)
+// checkFailurePolicy checks the service an on_failure policy starts: it should
+// exist, and must not start the failing service back, which would loop.
+func (c *CLI) checkFailurePolicy(svc *service.Service) error {
+	policy := svc.GetFailurePolicy()
+	if policy == nil || policy.Run == "" {
+		return nil
+	}
+	target, err := c.cfg.GetService(policy.Run)
+	if err != nil {
+		return c.warn("on_failure service '%s' does not exist yet", policy.Run)
+	}
+	if back := target.GetFailurePolicy(); back != nil && back.Run == svc.Name {
+		return fmt.Errorf("on_failure of '%s' already runs '%s', so failures would start each other in a loop", policy.Run, svc.Name)
+	}
+	return nil
+}
+
+// describeFailurePolicy describes an on_failure policy for status.
+func describeFailurePolicy(policy *service.FailurePolicy) string {
+	if policy.Run != "" {
+		return fmt.Sprintf("run '%s'", policy.Run)
+	}
+	return fmt.Sprintf("disable after %d failed runs in a row", policy.DisableAfter)
+}
+
+// redactURL hides the password in a URL so it can be displayed.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// describeRun summarizes how a run ended, e.g. "Failed (exit code 2) after 4s".
+func describeRun(rec *history.Record) string {
+	duration := rec.Duration().Round(time.Millisecond)
+	switch {
+	case rec.Error != "":
+		return fmt.Sprintf("Failed after %s: %s", duration, rec.Error)
+	case rec.OutputError != "":
+		return fmt.Sprintf("Failed after %s: %s", duration, rec.OutputError)
+	case rec.Failed():
+		return fmt.Sprintf("Failed (exit code %d) after %s", rec.ExitCode, duration)
+	case rec.Slow:
+		return fmt.Sprintf("Succeeded, but slowly, in %s", duration)
+	default:
+		return fmt.Sprintf("Succeeded in %s", duration)
+	}
+}
+
+// watchedChanged reports whether the files a service watches with if_changed
+// changed since its last successful run, returning their snapshot to save once
+// this run succeeds. A snapshot that can't be read or taken doesn't hold the
+// run back, since running needlessly beats silently not running.
+func (c *CLI) watchedChanged(svc *service.Service) (watch.Snapshot, bool) {
+	dir := c.cfg.GetSnapshotsDir()
+	previous, err := watch.Load(dir, svc.Name)
+	if err != nil {
+		c.warning("%v", err)
+	}
+	current, err := watch.Take(svc.ResolvePaths(c.cfg.ConfigDir).IfChanged, previous)
+	if err != nil {
+		c.warning("%v", err)
+		return nil, true
+	}
+	if current.Changed(previous) {
+		return current, true
+	}
+	// Touched files keep their checksums, saved so they aren't read again
+	if current.Refreshed(previous) {
+		if err := current.Save(dir, svc.Name); err != nil {
+			c.warning("failed to save snapshot of watched files: %v", err)
 		}
 	}
+	return nil, false
+}
 
-	if err := platformMgr.Install(updatedSvc); err != nil {
-		return fmt.Errorf("failed to reinstall service: %w", err)
+// pollWatchPaths reports whether the watch_paths of a service changed since the
+// last poll, recording them as they are now. The first poll only records them,
+// since a file watcher only sees the changes made after it starts.
+func (c *CLI) pollWatchPaths(svc *service.Service) (bool, error) {
+	dir := c.cfg.GetPollsDir()
+	previous, err := watch.Load(dir, svc.Name)
+	if err != nil {
+		c.warning("%v", err)
+	}
+	current, err := watch.Take(svc.ResolvePaths(c.cfg.ConfigDir).WatchPaths, previous)
+	if err != nil {
+		return false, err
+	}
+	changed := current.Changed(previous)
+	if changed || current.Refreshed(previous) {
+		if err := current.Save(dir, svc.Name); err != nil {
+			return false, err
+		}
+	}
+	return changed && previous != nil, nil
+}
+
+// runEvent returns the event reporting how a run ended.
+func runEvent(rec *history.Record) eventlog.Event {
+	event := eventlog.Event{
+		ID:      eventlog.IDSucceeded,
+		Message: fmt.Sprintf("Service '%s' succeeded.", rec.Service),
+	}
+	switch {
+	case rec.Error != "":
+		event.ID = eventlog.IDStartFailed
+		event.Message = fmt.Sprintf("Service '%s' could not be started.", rec.Service)
+	case rec.OutputError != "":
+		event.ID = eventlog.IDFailed
+		event.Message = fmt.Sprintf("Service '%s' failed: %s.", rec.Service, rec.OutputError)
+	case rec.Failed():
+		event.ID = eventlog.IDFailed
+		event.Message = fmt.Sprintf("Service '%s' failed with exit code %d.", rec.Service, rec.ExitCode)
+	}
+	event.Fields = [][2]string{
+		{"Service", rec.Service},
+		{"Run ID", rec.ID},
+		{"Start", rec.Start.Format(time.RFC3339)},
+		{"Duration", rec.Duration().Round(time.Millisecond).String()},
+		{"Exit Code", strconv.Itoa(rec.ExitCode)},
+	}
+	if rec.Error != "" {
+		event.Fields = append(event.Fields, [2]string{"Error", rec.Error})
+	}
+	if rec.OutputError != "" {
+		event.Fields = append(event.Fields, [2]string{"Output Error", rec.OutputError})
+	}
+	for _, line := range rec.ErrorLines {
+		event.Fields = append(event.Fields, [2]string{"Stderr", line})
+	}
+	return event
+}
+
+// writeEvent writes a run event to the Windows Event Log, and records it in the
+// events log. Like a lost history record, a failed write doesn't change the
+// exit code.
+func (c *CLI) writeEvent(event eventlog.Event) {
+	if err := eventlog.Write(event); err != nil {
+		c.warning("failed to write to the event log: %v", err)
+	}
+	c.recordEvent(fromEventLog(event))
+}
+
+// LogsOptions selects the log lines Logs shows.
+type LogsOptions struct {
+	Stream string // "stdout" or "stderr"; empty shows both, merged by time
+	Tail   int    // Show only the last n lines; 0 shows all
+	Since  string // Show only lines newer than this duration, e.g. "1h"
+	All    bool   // Interleave the logs of every service instead of one
+}
+
+// Logs prints the captured output of a service, or of all services with
+// opts.All, oldest first. The logs of a pipeline or a parallel group include
+// those its members wrote while it ran them.
+func (c *CLI) Logs(ctx context.Context, name string, opts LogsOptions, verbose bool) error {
+	names := []string{name}
+	var runsServices *service.Service
+	if opts.All {
+		names = nil
+		for _, svc := range c.cfg.ListServices() {
+			names = append(names, svc.Name)
+		}
+		sort.Strings(names)
+	} else if svc, err := c.cfg.GetService(name); err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	} else if svc.RunsServices() {
+		runsServices = svc
+	}
+
+	streams := []string{runner.StreamStdout, runner.StreamStderr}
+	switch opts.Stream {
+	case "":
+	case runner.StreamStdout, runner.StreamStderr:
+		streams = []string{opts.Stream}
+	default:
+		return fmt.Errorf("invalid stream %q, use stdout or stderr", opts.Stream)
+	}
+
+	var since time.Time
+	if opts.Since != "" {
+		d, err := parseDuration(opts.Since)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		since = time.Now().Add(-d)
+	}
+
+	var lines []runner.LogLine
+	for _, n := range names {
+		serviceLines, err := runner.ReadLogs(c.cfg.GetLogsDir(), n, streams...)
+		if err != nil {
+			return err
+		}
+		for _, line := range serviceLines {
+			if !line.Time.Before(since) {
+				lines = append(lines, line)
+			}
+		}
+	}
+	if runsServices != nil {
+		memberLines, err := c.memberLines(runsServices, streams)
+		if err != nil {
+			return err
+		}
+		for _, line := range memberLines {
+			if !line.Time.Before(since) {
+				lines = append(lines, line)
+			}
+		}
+		names = append(names, runsServices.Members()...)
+	}
+	// Each service's lines are already in order; stable keeps them so on equal times
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Time.Before(lines[j].Time)
+	})
+
+	if len(lines) == 0 {
+		if opts.All {
+			c.infoln("No logs found.")
+		} else {
+			c.infof("No logs found for service '%s'.\n", name)
+		}
+		return nil
+	}
+
+	if opts.Tail > 0 && len(lines) > opts.Tail {
+		lines = lines[len(lines)-opts.Tail:]
+	}
+
+	width := 0
+	for _, n := range names {
+		width = max(width, len(n))
+	}
+
+	for _, line := range lines {
+		prefix := ""
+		if len(names) > 1 {
+			label := fmt.Sprintf("%-*s", width, line.Service)
+			prefix = c.paint(serviceColor(line.Service), label) + " | "
+		}
+		marker := ""
+		// Only the merged view needs to tell streams apart
+		if opts.Stream == "" && line.Stream == runner.StreamStderr {
+			marker = c.paint(term.Red, "[stderr]") + " "
+		}
+		fmt.Fprintf(c.out, "%s %s%s%s\n", line.Time.Local().Format("2006-01-02 15:04:05"), prefix, marker, line.Text)
 	}
 
-	fmt.Printf("Service '%s' updated successfully!\n", name)
 	return nil
 }
 
+// serviceColors are the colors service names are shown in; red is left for errors.
+var serviceColors = []term.Color{term.Cyan, term.Green, term.Yellow, term.Blue, term.Magenta}
+
+// serviceColor picks a color for a service name, the same one on every run.
+func serviceColor(name string) term.Color {
+	sum := 0
+	for _, b := range []byte(name) {
+		sum += int(b)
+	}
+	return serviceColors[sum%len(serviceColors)]
+}
+
 func parseDuration(s string) (time.Duration, error) {
 	s = strings.TrimSpace(s)
 
@@ -600,18 +3047,22 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dd", int(d.Hours()/24))
 }
 
-func (c *CLI) createScriptInteractive(serviceName string, verbose bool) (string, error) {
+func (c *CLI) createScriptInteractive(serviceName, shell string, portable, verbose bool) (string, error) {
 	scriptsDir := c.cfg.GetScriptsDir()
+	if portable {
+		// Portable paths are relative to the config directory
+		scriptsDir = c.cfg.GetPortableScriptsDir()
+	}
 	if err := os.MkdirAll(scriptsDir, 0755); err != nil {
 		return "", fmt.Errorf("creating scripts directory: %w", err)
 	}
 
 	var ext string
-	switch runtime.GOOS {
-	case "windows":
+	switch {
+	case service.IsPowerShell(shell):
+		ext = ".ps1"
+	case runtime.GOOS == "windows":
 		ext = ".bat"
-	case "darwin", "linux":
-		ext = ".sh"
 	default:
 		ext = ".sh"
 	}
@@ -622,7 +3073,7 @@ func (c *CLI) createScriptInteractive(serviceName string, verbose bool) (string,
 	if _, err := os.Stat(scriptPath); err == nil {
 		// File exists, use it without opening editor again
 		if verbose {
-			fmt.Printf("Script already exists at %s, using it\n", scriptPath)
+			c.infof("Script already exists at %s, using it\n", scriptPath)
 		}
 		return scriptPath, nil
 	}
@@ -630,16 +3081,16 @@ func (c *CLI) createScriptInteractive(serviceName string, verbose bool) (string,
 	editor := getEditor()
 
 	if verbose {
-		fmt.Printf("Opening editor: %s\n", editor)
-		fmt.Printf("Script will be saved to: %s\n", scriptPath)
+		c.infof("Opening editor: %s\n", editor)
+		c.infof("Script will be saved to: %s\n", scriptPath)
 	}
 
 	var initialContent string
-	switch runtime.GOOS {
-	case "windows":
+	switch {
+	case service.IsPowerShell(shell):
+		initialContent = createPowerShellTemplate(serviceName)
+	case runtime.GOOS == "windows":
 		initialContent = createWindowsTemplate(serviceName)
-	case "darwin", "linux":
-		initialContent = createUnixTemplate(serviceName)
 	default:
 		initialContent = createUnixTemplate(serviceName)
 	}
@@ -684,7 +3135,7 @@ func (c *CLI) createScriptInteractive(serviceName string, verbose bool) (string,
 	}
 
 	if verbose {
-		fmt.Printf("Script created successfully: %s\n", scriptPath)
+		c.infof("Script created successfully: %s\n", scriptPath)
 	}
 
 	return scriptPath, nil
@@ -752,6 +3203,21 @@ func createUnixTemplate(serviceName string) string {
 `, serviceName, time.Now().Format("2006-01-02 15:04:05"))
 }
 
+// createPowerShellTemplate returns a .ps1 script template. The shebang line lets
+// the script also run directly on Linux and macOS.
+func createPowerShellTemplate(serviceName string) string {
+	return fmt.Sprintf(`#!/usr/bin/env pwsh
+# Service: %s
+# Created: %s
+
+$ErrorActionPreference = 'Stop'
+
+# Your code here:
+
+exit 0
+`, serviceName, time.Now().Format("2006-01-02 15:04:05"))
+}
+
 func createWindowsTemplate(serviceName string) string {
 	username := os.Getenv("USERNAME")
 	if username == "" {
@@ -855,7 +3321,7 @@ func (c *CLI) openNotepadWithMonitoring(scriptPath string, verbose bool) (string
 	}
 
 	if verbose {
-		fmt.Printf("Script created successfully: %s\n", scriptPath)
+		c.infof("Script created successfully: %s\n", scriptPath)
 	}
 
 	return scriptPath, nil
@@ -0,0 +1,279 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/lock"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/runner"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/state"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// fakeManager stands in for the scheduler, reporting the services of states
+// as installed. Calls the tests don't expect panic on the nil Manager.
+type fakeManager struct {
+	platform.Manager
+	states      map[string]*platform.ServiceState
+	installed   []*service.Service
+	uninstalled []string
+}
+
+func (m *fakeManager) Uninstall(name string) error {
+	m.uninstalled = append(m.uninstalled, name)
+	delete(m.states, name)
+	return nil
+}
+
+func (m *fakeManager) Install(svc *service.Service) error {
+	m.installed = append(m.installed, svc)
+	m.states[svc.Name] = &platform.ServiceState{Installed: true, Enabled: svc.Enabled}
+	return nil
+}
+
+func (m *fakeManager) Artifacts(svc *service.Service) ([]platform.Artifact, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) Preview(svc *service.Service) ([]platform.Definition, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) GetTaskState(name string) (*platform.ServiceState, error) {
+	if st, ok := m.states[name]; ok {
+		return st, nil
+	}
+	return nil, errors.New("not installed")
+}
+
+func (m *fakeManager) IsInstalled(name string) (bool, error) {
+	_, ok := m.states[name]
+	return ok, nil
+}
+
+func (m *fakeManager) NextRun(svc *service.Service) (time.Time, error) {
+	if st, ok := m.states[svc.Name]; ok {
+		return st.NextRun, nil
+	}
+	return time.Time{}, nil
+}
+
+func (m *fakeManager) LastRun(name string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+const testConfig = `services:
+  - name: backup
+    command: /opt/backup.sh
+    args: ["--full", "/var/lib/app data"]
+    interval: 1h
+    offset: 15m
+    enabled: true
+    description: Nightly copy of the app data
+    owner: ops
+    labels:
+      team: infra
+    env:
+      TOKEN: s3cret
+  - name: cleanup
+    command: rm -rf /tmp/cache
+    on_startup: true
+    enabled: false
+  - name: report
+    command: report
+    schedule: "Mon,Fri 09:00"
+    enabled: true
+  - name: uninstalled
+    command: "true"
+    interval: 5m
+    enabled: true
+`
+
+// newTestCLI returns a CLI on a config in a temporary directory holding
+// testConfig, with every service but "uninstalled" installed, and the buffer
+// its output is written to.
+func newTestCLI(t *testing.T) (*CLI, *bytes.Buffer) {
+	t.Helper()
+	// Times are shown in local time
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "services.yaml"), []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.NewAt(dir)
+	if err != nil {
+		t.Fatalf("config.NewAt() error = %v", err)
+	}
+
+	next := time.Date(2099, 3, 4, 5, 15, 0, 0, time.UTC)
+	mgr := &fakeManager{states: map[string]*platform.ServiceState{
+		"backup":  {Installed: true, Enabled: true, NextRun: next, BackendDetail: "active"},
+		"cleanup": {Installed: true, BackendDetail: "inactive"},
+		"report":  {Installed: true, Enabled: true, NextRun: next.Add(4 * time.Hour), BackendDetail: "active"},
+	}}
+
+	var out bytes.Buffer
+	c := New(cfg, WithInput(strings.NewReader("")), WithOutput(&out, &out), WithUpdateCheck(false))
+	c.newPlatform = func(...platform.Option) (platform.Manager, error) { return mgr, nil }
+	return c, &out
+}
+
+// checkGolden compares got with testdata/<name>.golden, or rewrites the file
+// with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run go test -update to write it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+func TestListGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ListOptions
+	}{
+		{"list", ListOptions{}},
+		{"list_wide", ListOptions{Wide: true}},
+		{"list_tree", ListOptions{Tree: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, out := newTestCLI(t)
+			if err := c.List(context.Background(), tt.opts, false); err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			checkGolden(t, tt.name, out.Bytes())
+		})
+	}
+}
+
+func TestStatusGolden(t *testing.T) {
+	for _, name := range []string{"backup", "cleanup", "uninstalled"} {
+		t.Run(name, func(t *testing.T) {
+			c, out := newTestCLI(t)
+			if err := c.Status(context.Background(), name, false); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			checkGolden(t, "status_"+name, out.Bytes())
+		})
+	}
+}
+
+func TestTestTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	c, out := newTestCLI(t)
+	start := time.Now()
+	code, err := c.Test(context.Background(), "", &Flags{Command: "sleep 30", Timeout: "1s", NoVerify: true}, false)
+	if err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	if code == 0 {
+		t.Errorf("Test() = 0, want the code of the stopped run")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Test() took %s, want it stopped after the timeout", elapsed)
+	}
+	if !strings.Contains(out.String(), "stopped: timeout of 1s reached") {
+		t.Errorf("Test() output = %q, want the timeout", out.String())
+	}
+}
+
+func TestRestoreBadArchive(t *testing.T) {
+	c, _ := newTestCLI(t)
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := os.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restore(context.Background(), path, true, false, false); err == nil {
+		t.Fatal("Restore() of a bad archive succeeded")
+	}
+	mgr, _ := c.newPlatform()
+	if got := mgr.(*fakeManager).uninstalled; len(got) != 0 {
+		t.Errorf("Restore() of a bad archive uninstalled %q, want nothing", got)
+	}
+}
+
+func TestExecSkipsOverlappingRun(t *testing.T) {
+	c, _ := newTestCLI(t)
+	held, err := lock.AcquireFile(context.Background(), c.cfg.GetRunLocksDir(), "backup", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release(context.Background())
+
+	code, err := c.Exec(context.Background(), "backup", false, false, false, false)
+	if code != 0 || err != nil {
+		t.Errorf("Exec() = %d, %v, want 0 for a skipped run", code, err)
+	}
+	st, err := state.Load(c.cfg.GetServiceStateDir(), "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.LastSkipped.IsZero() {
+		t.Errorf("LastSkipped not recorded")
+	}
+
+	// A pipeline doesn't take the skipped step for one that succeeded
+	journal, err := runner.Begin(&service.Service{Name: "pipeline"}, runner.Options{LogsDir: c.cfg.GetLogsDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := c.runMember(context.Background(), journal, "step", "backup", false); code == 0 {
+		t.Errorf("runMember() = 0, want the skipped step to fail")
+	}
+	journal.Finish(0)
+}
+
+func TestEditKeepsSettings(t *testing.T) {
+	c, _ := newTestCLI(t)
+	if err := c.Edit(context.Background(), "backup", &Flags{Interval: "2h", NoVerify: true}, false); err != nil {
+		t.Fatalf("Edit() error = %v", err)
+	}
+	svc, err := c.cfg.GetService("backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.GetInterval() != 2*time.Hour {
+		t.Errorf("Interval = %v, want 2h", svc.GetInterval())
+	}
+	if svc.Command != "/opt/backup.sh" || len(svc.Args) != 2 || svc.Offset != "15m" || svc.Env["TOKEN"] != "s3cret" ||
+		svc.Description != "Nightly copy of the app data" || svc.Owner != "ops" || svc.Labels["team"] != "infra" {
+		t.Errorf("Edit() changed settings no flag touched: %+v", svc)
+	}
+	if svc.Platform != runtime.GOOS {
+		t.Errorf("Platform = %q, want %q", svc.Platform, runtime.GOOS)
+	}
+	mgr, _ := c.newPlatform()
+	if got := mgr.(*fakeManager).installed; len(got) != 1 || got[0].Name != "backup" {
+		t.Errorf("Edit() installed %v, want backup reinstalled", got)
+	}
+}
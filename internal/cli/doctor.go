@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/eventlog"
+	"github.com/calilkhalil/nazim/internal/integrity"
+	"github.com/calilkhalil/nazim/internal/notify"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/state"
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// trackingManager records the checksums of a service's files whenever it is
+// installed, and forgets them when it is uninstalled, so every command that
+// installs services keeps the baseline Doctor checks against, and records the
+// service as a version Rollback can go back to. It also records
+// when the service was installed, which stale checks count from until it runs,
+// and whether it had to fall back to a degraded install.
+type trackingManager struct {
+	platform.Manager
+	c        *CLI
+	fallback string // How the last Install fell back, reported through platform.WithFallback
+}
+
+func (m *trackingManager) Install(svc *service.Service) error {
+	m.fallback = ""
+	if err := m.Manager.Install(svc); err != nil {
+		return err
+	}
+	if _, err := state.Update(m.c.cfg.GetServiceStateDir(), svc.Name, func(s *state.State) {
+		s.InstalledAt = time.Now()
+		s.Fallback = m.fallback
+	}); err != nil {
+		m.c.warning("failed to save state of '%s': %v", svc.Name, err)
+	}
+	// Without privileges, the elevated process installs the service and records
+	// it, unless it fell back to installing without them
+	if !platform.RequiresElevation() || m.fallback != "" {
+		m.c.recordChecksums(m.Manager, svc)
+		m.c.recordVersion(m.Manager, svc)
+	}
+	m.c.warnLinger(svc)
+	m.c.warnTimezone(svc)
+	return nil
+}
+
+func (m *trackingManager) Uninstall(name string) error {
+	if err := m.Manager.Uninstall(name); err != nil {
+		return err
+	}
+	if err := integrity.Update(m.c.cfg.GetChecksumsPath(), func(baseline integrity.Baseline) error {
+		baseline.Forget(name)
+		return nil
+	}); err != nil {
+		m.c.warning("failed to record checksums: %v", err)
+	}
+	return nil
+}
+
+// trackedFiles returns the files a service runs through: its script or
+// program when the command is a path, and what the scheduler keeps on disk
+// for it, such as unit files, a plist, or a wrapper, and the hand-edited units
+// those were installed from.
+func trackedFiles(platformMgr platform.Manager, svc *service.Service, configDir string) []string {
+	var paths []string
+	if resolved := svc.ResolvePaths(configDir); resolved.CommandIsPath() {
+		if info, err := os.Stat(resolved.Command); err == nil && info.Mode().IsRegular() {
+			paths = append(paths, resolved.Command)
+		}
+	}
+	artifacts, _ := platformMgr.Artifacts(svc)
+	for _, artifact := range artifacts {
+		if artifact.Path != "" {
+			paths = append(paths, artifact.Path)
+		}
+	}
+	return append(paths, svc.Units...)
+}
+
+// recordChecksums records the files of a service as they are now. Failing to
+// doesn't undo the install, so it's only a warning.
+func (c *CLI) recordChecksums(platformMgr platform.Manager, svc *service.Service) {
+	if err := integrity.Update(c.cfg.GetChecksumsPath(), func(baseline integrity.Baseline) error {
+		return baseline.Record(svc.Name, trackedFiles(platformMgr, svc, c.cfg.ConfigDir))
+	}); err != nil {
+		c.warning("failed to record checksums: %v", err)
+	}
+}
+
+// DoctorOptions selects what Doctor does besides checking.
+type DoctorOptions struct {
+	Accept bool   // Record the files as they are now instead of checking them
+	Grace  string // Stale grace; empty uses the config's
+	Notify bool   // Report stale services with a desktop notification and to the Windows event log
+
+	EnableLinger bool // Have the user's systemd user manager linger before checking, see platform.EnableLinger
+}
+
+// Doctor checks the installed services for files changed or deleted since
+// nazim installed them, and for stale services (see checkStale), returning an
+// error if it finds any, and mentions a newer release of nazim if there is
+// one. It also says when the services can't run while the user is logged
+// out, since their systemd user manager doesn't linger (see checkLinger).
+// With opts.Accept, the files of the named services, or of all of them, are
+// recorded as they are now instead.
+func (c *CLI) Doctor(ctx context.Context, names []string, opts DoctorOptions, verbose bool) error {
+	grace, err := c.parseGrace(opts.Grace)
+	if err != nil {
+		return err
+	}
+	services := c.cfg.ListServices()
+	if len(names) > 0 {
+		services = nil
+		for _, name := range names {
+			svc, err := c.cfg.GetService(name)
+			if err != nil {
+				return fmt.Errorf("service '%s' does not exist", name)
+			}
+			services = append(services, svc)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	if opts.EnableLinger {
+		if err := platform.EnableLinger(); err != nil {
+			return err
+		}
+		c.infoln("Lingering enabled: services now run from boot, and while you are logged out.")
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+	baseline, err := integrity.Load(c.cfg.GetChecksumsPath())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	checked, problems, userUnits := 0, 0, 0
+	var untracked, accepted []string
+	var stale []*staleness
+	for _, svc := range services {
+		if svc.CheckPlatform(runtime.GOOS) != nil {
+			continue
+		}
+		if installed, _ := platformMgr.IsInstalled(svc.Name); !installed {
+			continue
+		}
+		checked++
+		if !svc.OnWindowsHost() {
+			userUnits++
+		}
+
+		if opts.Accept {
+			if err := baseline.Record(svc.Name, trackedFiles(platformMgr, svc, c.cfg.ConfigDir)); err != nil {
+				return err
+			}
+			accepted = append(accepted, svc.Name)
+			if verbose {
+				c.infof("Recorded the files of '%s'\n", svc.Name)
+			}
+			continue
+		}
+
+		if s := c.checkStale(platformMgr, svc, now, grace); s != nil && s.Stale {
+			stale = append(stale, s)
+		}
+
+		changes, tracked := baseline.Check(svc.Name)
+		if !tracked {
+			untracked = append(untracked, svc.Name)
+			continue
+		}
+		for _, change := range changes {
+			fmt.Fprintf(c.out, "%s: %s %s outside nazim\n", svc.Name, change.Path, c.paint(term.Red, change.Kind))
+			problems++
+		}
+	}
+
+	if opts.Accept {
+		// Merged into the baseline as it is now, in case a service was installed meanwhile
+		if err := integrity.Update(c.cfg.GetChecksumsPath(), func(current integrity.Baseline) error {
+			for _, name := range accepted {
+				current[name] = baseline[name]
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		c.infof("Recorded the files of %d service(s) as they are now.\n", checked)
+		return nil
+	}
+	defer c.doctorUpdate(ctx, verbose)
+
+	for _, s := range stale {
+		fmt.Fprintf(c.out, "%s: %s, %s\n", s.Service, c.paint(term.Red, "stale"), s.describe(now))
+		if opts.Notify {
+			c.notifyStale(s, now)
+		}
+	}
+
+	if len(untracked) > 0 {
+		if problems > 0 {
+			fmt.Fprintln(c.out)
+		}
+		fmt.Fprintf(c.out, "No checksums recorded yet for: %s\n", strings.Join(untracked, ", "))
+		fmt.Fprintln(c.out, "Run 'nazim doctor --accept' to record their files as they are now.")
+	}
+	if userUnits > 0 {
+		c.checkLinger(problems > 0 || len(untracked) > 0, verbose)
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d file(s) changed outside nazim; if that was expected, run 'nazim doctor --accept' to record them as they are now", problems)
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("%d service(s) stale, not run in over %g times their interval; check that the scheduler still starts them", len(stale), grace)
+	}
+	if checked == 0 {
+		c.infoln("No installed services found.")
+		return nil
+	}
+	fmt.Fprintf(c.out, "Checked %d service(s): no problems found.\n", checked)
+	return nil
+}
+
+// notifyStale reports a stale service with a desktop notification and to the
+// Windows event log, for doctor runs that no one watches.
+func (c *CLI) notifyStale(s *staleness, now time.Time) {
+	message := fmt.Sprintf("Service '%s' is stale: %s.", s.Service, s.describe(now))
+	if err := notify.Desktop(fmt.Sprintf("nazim: %s", s.Service), message, true); err != nil {
+		c.warning("failed to show desktop notification: %v", err)
+	}
+	fields := [][2]string{{"Service", s.Service}, {"Expected Every", s.Every.String()}}
+	if !s.LastRun.IsZero() {
+		fields = append(fields, [2]string{"Last Run", s.LastRun.Format(time.RFC3339)})
+	} else {
+		fields = append(fields, [2]string{"Installed", s.InstalledAt.Format(time.RFC3339)})
+	}
+	c.writeEvent(eventlog.Event{ID: eventlog.IDStale, Message: message, Fields: fields})
+}
+
+// printIntegrity prints whether the files of a service changed since they
+// were recorded, for status.
+func (c *CLI) printIntegrity(name string) {
+	baseline, err := integrity.Load(c.cfg.GetChecksumsPath())
+	if err != nil {
+		c.warning("failed to read checksums: %v", err)
+		return
+	}
+	changes, tracked := baseline.Check(name)
+	switch {
+	case !tracked:
+		fmt.Fprintf(c.out, "Integrity: not tracked (run 'nazim doctor --accept %s' to record its files)\n", name)
+	case len(changes) == 0:
+		fmt.Fprintf(c.out, "Integrity: %d file(s) unchanged since recorded\n", len(baseline[name]))
+	default:
+		fmt.Fprintf(c.out, "Integrity: %s\n", c.paint(term.Red, fmt.Sprintf("%d file(s) changed outside nazim", len(changes))))
+		for _, change := range changes {
+			fmt.Fprintf(c.out, "  %s: %s\n", change.Kind, change.Path)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/seal"
+	"github.com/calilkhalil/nazim/internal/versions"
+)
+
+// EncryptOptions selects the key EncryptConfig encrypts the config file with.
+type EncryptOptions struct {
+	Keychain bool // Keep a random key in the OS keychain rather than using a passphrase
+	// PassphraseFrom reads the passphrase from env:NAME or file:PATH rather
+	// than asking for it, then and whenever the config is loaded.
+	PassphraseFrom string
+}
+
+// EncryptConfig encrypts the config file, which nazim then decrypts whenever
+// it loads it and encrypts again whenever it changes. An encrypted file is
+// encrypted again with the new key.
+func (c *CLI) EncryptConfig(opts EncryptOptions, verbose bool) error {
+	if opts.Keychain && opts.PassphraseFrom != "" {
+		return fmt.Errorf("--keychain and --passphrase-from cannot be used together")
+	}
+	if opts.PassphraseFrom != "" {
+		if err := seal.CheckSource(opts.PassphraseFrom); err != nil {
+			return err
+		}
+		// Scheduled runs don't start in the current directory
+		if path, ok := strings.CutPrefix(opts.PassphraseFrom, "file:"); ok {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			opts.PassphraseFrom = "file:" + abs
+		}
+	}
+
+	var key *seal.Key
+	var err error
+	if opts.Keychain {
+		key, err = seal.NewKeychainKey()
+	} else {
+		key, err = seal.NewPassphraseKey(opts.PassphraseFrom)
+	}
+	if err != nil {
+		return err
+	}
+	previous := c.cfg.EncryptionKey()
+	if err := c.cfg.Encrypt(key); err != nil {
+		return err
+	}
+	c.resealVersions(previous, key)
+
+	switch {
+	case opts.Keychain:
+		c.infof("Encrypted %s with a key kept in the OS keychain.\n", c.cfg.GetConfigPath())
+	case opts.PassphraseFrom != "":
+		c.infof("Encrypted %s with the passphrase from %s.\n", c.cfg.GetConfigPath(), opts.PassphraseFrom)
+	default:
+		c.infof("Encrypted %s with a passphrase.\n", c.cfg.GetConfigPath())
+		if len(c.cfg.UserServices()) > 0 {
+			c.warning("scheduled runs cannot ask for the passphrase and will fail; " +
+				"encrypt the config with --passphrase-from or --keychain instead")
+		}
+	}
+	return nil
+}
+
+// DecryptConfig writes the config file back in plain text.
+func (c *CLI) DecryptConfig(verbose bool) error {
+	previous := c.cfg.EncryptionKey()
+	if previous == nil {
+		return fmt.Errorf("%s is not encrypted", c.cfg.GetConfigPath())
+	}
+	if err := c.cfg.Encrypt(nil); err != nil {
+		return err
+	}
+	c.resealVersions(previous, nil)
+	c.infof("Decrypted %s.\n", c.cfg.GetConfigPath())
+	return nil
+}
+
+// resealVersions encrypts the kept versions of services like the config file,
+// which holds the same secrets, after its key changed from previous to key.
+// The config file is already written by then, so failing to is only a warning.
+func (c *CLI) resealVersions(previous, key *seal.Key) {
+	if err := versions.Reseal(c.cfg.GetVersionsDir(), previous, key); err != nil {
+		c.warning("failed to rewrite the kept versions of services: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/eventlog"
+	"github.com/calilkhalil/nazim/internal/events"
+)
+
+// EventsOptions selects the events Events shows.
+type EventsOptions struct {
+	Follow bool   // Keep printing events as they happen
+	Output string // "text" or "json"; empty is text
+	Tail   int    // Show only the last n past events; 0 shows all, or none with Follow
+}
+
+// eventTypes maps the Windows Event Log IDs of run events to their type in
+// the events log.
+var eventTypes = map[uint32]string{
+	eventlog.IDStarted:     events.RunStarted,
+	eventlog.IDSucceeded:   events.RunSucceeded,
+	eventlog.IDFailed:      events.RunFailed,
+	eventlog.IDStartFailed: events.RunStartFailed,
+	eventlog.IDSlow:        events.RunSlow,
+	eventlog.IDStale:       events.ServiceStale,
+	eventlog.IDPaused:      events.ServicePaused,
+	eventlog.IDSkipped:     events.RunSkipped,
+}
+
+// Events prints the events of services, or only those of name when it isn't
+// empty, oldest first: changes nazim commands made to them and their runs.
+// The events of removed services are kept, so name needn't exist anymore.
+// With opts.Follow, it keeps printing new events until ctx is done.
+func (c *CLI) Events(ctx context.Context, name string, opts EventsOptions, verbose bool) error {
+	if opts.Output != "" && opts.Output != "text" && opts.Output != "json" {
+		return fmt.Errorf("invalid output format %q, use text or json", opts.Output)
+	}
+	if opts.Tail < 0 {
+		return fmt.Errorf("invalid tail %d, must be positive", opts.Tail)
+	}
+	path := c.cfg.GetEventsPath()
+	past, offset, err := events.Read(path)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		past = filterEvents(past, name)
+	}
+	switch {
+	case opts.Tail > 0 && len(past) > opts.Tail:
+		past = past[len(past)-opts.Tail:]
+	case opts.Tail == 0 && opts.Follow:
+		past = nil
+	}
+
+	show := func(e events.Event) {
+		if name == "" || e.Service == name {
+			c.printEvent(e, opts.Output == "json")
+		}
+	}
+	for _, e := range past {
+		show(e)
+	}
+	if !opts.Follow {
+		if len(past) == 0 && opts.Output != "json" {
+			c.infoln("No events recorded.")
+		}
+		return nil
+	}
+	return events.Follow(ctx, path, offset, show)
+}
+
+// filterEvents returns the events of the service name.
+func filterEvents(all []events.Event, name string) []events.Event {
+	var matching []events.Event
+	for _, e := range all {
+		if e.Service == name {
+			matching = append(matching, e)
+		}
+	}
+	return matching
+}
+
+// printEvent prints an event as a line of JSON, or as its time, type, and
+// message followed by its fields, indented.
+func (c *CLI) printEvent(e events.Event, asJSON bool) {
+	if asJSON {
+		enc := json.NewEncoder(c.out)
+		enc.SetEscapeHTML(false)
+		enc.Encode(e)
+		return
+	}
+	fmt.Fprintf(c.out, "%s  %-18s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Type, e.Message)
+	if len(e.Fields) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, line := range strings.Split(e.Fields[key], "\n") {
+			fmt.Fprintf(c.out, "    %s: %s\n", key, line)
+		}
+	}
+}
+
+// recordEvent adds an event to the events log. Like a lost history record, a
+// failed write doesn't fail the command that made it.
+func (c *CLI) recordEvent(e events.Event) {
+	if err := events.Append(c.cfg.GetEventsPath(), e); err != nil {
+		c.warning("failed to record event: %v", err)
+	}
+}
+
+// serviceEvent records a change a command made to the service name, with a
+// message like "Service 'backup' added.".
+func (c *CLI) serviceEvent(kind, name string) {
+	c.recordEvent(events.Event{
+		Type:    kind,
+		Service: name,
+		Message: fmt.Sprintf("Service '%s' %s.", name, strings.TrimPrefix(kind, "service.")),
+	})
+}
+
+// fromEventLog converts an event written to the Windows Event Log to one of
+// the events log. Its fields are keyed in snake case, and the lines of
+// repeated fields, such as the Stderr lines of a failed run, are joined.
+func fromEventLog(event eventlog.Event) events.Event {
+	e := events.Event{Type: eventTypes[event.ID], Message: event.Message}
+	for _, field := range event.Fields {
+		if field[0] == "Service" {
+			e.Service = field[1]
+			continue
+		}
+		if e.Fields == nil {
+			e.Fields = make(map[string]string)
+		}
+		key := strings.ToLower(strings.ReplaceAll(field[0], " ", "_"))
+		if previous, ok := e.Fields[key]; ok {
+			e.Fields[key] = previous + "\n" + field[1]
+		} else {
+			e.Fields[key] = field[1]
+		}
+	}
+	return e
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/export"
+	"github.com/calilkhalil/nazim/internal/importer"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// Export writes the named services, or every service of the user's config, to
+// a file another machine can import with Import; an empty path writes them to
+// standard output. With portable, each service is tagged with the operating
+// systems it runs on and the kind of its command, which lets Import on
+// another OS translate it.
+func (c *CLI) Export(ctx context.Context, names []string, to string, portable, verbose bool) error {
+	var services []*service.Service
+	if len(names) == 0 {
+		services = c.cfg.UserServices()
+	}
+	for _, name := range names {
+		svc, err := c.cfg.GetService(name)
+		if err != nil {
+			return fmt.Errorf("service '%s' does not exist", name)
+		}
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	if to == "" {
+		return export.Write(c.out, services, runtime.GOOS, portable)
+	}
+	f, err := os.Create(to)
+	if err != nil {
+		return fmt.Errorf("creating export file: %w", err)
+	}
+	if err := export.Write(f, services, runtime.GOOS, portable); err != nil {
+		f.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing export file: %w", err)
+	}
+	c.infof("Exported %d service(s) to %s\n", len(services), to)
+	return nil
+}
+
+// ExportUnit prints the scheduler definitions of a service, the systemd units,
+// launchd plist, or Task Scheduler XML nazim installs for it, or writes each
+// to a file of its name in the directory to. Edited by hand, they can be
+// installed in place of the generated ones with --from-unit.
+func (c *CLI) ExportUnit(ctx context.Context, name, to string, verbose bool) error {
+	svc, err := c.cfg.GetService(name)
+	if err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	}
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+	defs, err := platformMgr.Export(svc)
+	if err != nil {
+		return err
+	}
+
+	if to == "" {
+		for _, def := range defs {
+			fmt.Fprintln(c.out, c.paint(term.Cyan, "--- "+filepath.Base(def.Name)+" ---"))
+			fmt.Fprint(c.out, def.Content)
+			if !strings.HasSuffix(def.Content, "\n") {
+				fmt.Fprintln(c.out)
+			}
+		}
+		return nil
+	}
+	if err := os.MkdirAll(to, 0755); err != nil {
+		return fmt.Errorf("creating export directory: %w", err)
+	}
+	var files []string
+	for _, def := range defs {
+		path := filepath.Join(to, filepath.Base(def.Name))
+		if err := os.WriteFile(path, []byte(def.Content), 0644); err != nil {
+			return fmt.Errorf("writing unit file: %w", err)
+		}
+		files = append(files, path)
+	}
+	c.infof("Exported %d file(s) to %s\n", len(files), to)
+	c.infof("Install them once edited with: nazim edit %s --from-unit %s\n", name, strings.Join(files, " --from-unit "))
+	return nil
+}
+
+// Import adds the services of a file written by Export. Each is checked
+// against this OS first: services of a portable export made on another OS
+// are translated where they can be, such as a shell script moving between
+// Linux and macOS or a Windows PowerShell script run with pwsh, and skipped
+// where they can't, such as a batch file outside Windows. The preview shows
+// what happens to each, and selection and staggerValue work as for the
+// other imports.
+func (c *CLI) Import(ctx context.Context, path, selection, staggerValue string, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	step, auto, err := parseStagger(staggerValue)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening export: %w", err)
+	}
+	defer f.Close()
+	file, err := export.Read(f)
+	if err != nil {
+		return err
+	}
+	if platform.RequiresElevation() {
+		return fmt.Errorf("import installs several services; run it from an elevated (administrator) prompt")
+	}
+
+	from := file.ExportedFrom
+	if from == "" {
+		from = runtime.GOOS
+	}
+	if from != runtime.GOOS {
+		c.infof("Services exported from %s; checking them against %s.\n\n", from, runtime.GOOS)
+	}
+
+	entries := make([]*importer.Entry, 0, len(file.Services))
+	for _, exported := range file.Services {
+		entry := &importer.Entry{
+			Source:  exported.Name,
+			Command: strings.Join(append([]string{exported.Command}, exported.Args...), " "),
+			When:    describeTrigger(exported.Service),
+		}
+		svc, notes, err := export.Adapt(exported, from, runtime.GOOS)
+		if err != nil {
+			entry.Problem = err.Error()
+		} else {
+			entry.Service = svc
+			entry.Problem = strings.Join(notes, "; ")
+		}
+		entries = append(entries, entry)
+	}
+	return c.importEntries(entries, selection, step, auto, verbose, nil)
+}
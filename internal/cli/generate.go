@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/events"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/progress"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Generate installs a service for every combination of values of a matrix,
+// each expanded from a template of the config's templates block, such as
+// backup-a and backup-b from backup-tpl with dir=/a,/b. Each instance records
+// the template and values it came from, so without a matrix Generate expands
+// the template again for the instances it has, updating them after the
+// template changed. Instances are otherwise left alone: one no longer in the
+// matrix is kept until removed.
+func (c *CLI) Generate(ctx context.Context, name string, matrix []string, noVerify, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	if platform.RequiresElevation() {
+		return fmt.Errorf("generate installs several services; run it from an elevated (administrator) prompt")
+	}
+	if name == "" {
+		return fmt.Errorf("--template is required")
+	}
+	tpl, err := c.cfg.Template(name)
+	if err != nil {
+		return err
+	}
+
+	combinations, err := config.ParseMatrix(matrix)
+	if err != nil {
+		return err
+	}
+	if len(matrix) == 0 {
+		combinations = c.instanceValues(name)
+		if len(combinations) == 0 {
+			return fmt.Errorf("no services were generated from template '%s' yet; give the values with --matrix", name)
+		}
+	}
+
+	var services []*service.Service
+	seen := make(map[string]bool)
+	for _, values := range combinations {
+		svc, err := c.expandTemplate(tpl, values, noVerify)
+		if err != nil {
+			return err
+		}
+		if seen[svc.Name] {
+			return fmt.Errorf("the matrix gives two services the name '%s'; set instance_name in template '%s' to tell them apart", svc.Name, name)
+		}
+		seen[svc.Name] = true
+		services = append(services, svc)
+	}
+
+	var sp *progress.Spinner
+	platformMgr, err := c.newManager(platform.WithProgress(func(step string) {
+		if sp != nil {
+			sp.Step(step)
+		}
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	var added, updated, unchanged int
+	var failed []string
+	// Save the changes to the config in one go rather than after each service
+	err = c.cfg.Tx(func() error {
+		for _, svc := range services {
+			existing, err := c.cfg.GetService(svc.Name)
+			if err == nil && sameDefinition(existing, svc) {
+				unchanged++
+				continue
+			}
+
+			// Disabling an instance outlasts the template being generated again
+			disabled := !svc.Enabled
+			if state, err := platformMgr.GetTaskState(svc.Name); existing != nil && err == nil && !state.Enabled {
+				disabled = true
+			}
+
+			sp = c.startProgress(fmt.Sprintf("Installing '%s'", svc.Name), verbose)
+			if existing != nil {
+				err = c.replaceService(platformMgr, svc)
+			} else {
+				err = c.installBundled(platformMgr, svc)
+			}
+			sp.Stop()
+			if err != nil {
+				c.warning("failed to install '%s': %v", svc.Name, err)
+				failed = append(failed, svc.Name)
+				continue
+			}
+			if disabled {
+				if err := platformMgr.Disable(svc.Name); err != nil {
+					c.warning("failed to disable '%s': %v", svc.Name, err)
+				}
+			}
+			if existing != nil {
+				updated++
+				c.serviceEvent(events.ServiceUpdated, svc.Name)
+			} else {
+				added++
+				c.serviceEvent(events.ServiceAdded, svc.Name)
+			}
+			if verbose {
+				c.infof("Generated '%s'\n", svc.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving generated services: %w", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to generate: %s", strings.Join(failed, ", "))
+	}
+	c.infof("Generated %s: %d added, %d updated, %d unchanged.\n", name, added, updated, unchanged)
+	return nil
+}
+
+// expandTemplate returns the service tpl describes for values, ready to
+// install. A configured service of the same name must have been generated
+// from tpl: Generate doesn't overwrite services it didn't make.
+func (c *CLI) expandTemplate(tpl *config.Template, values map[string]string, noVerify bool) (*service.Service, error) {
+	svc, err := tpl.Expand(values)
+	if err != nil {
+		return nil, err
+	}
+	if svc.Platform == "" {
+		svc.Platform = runtime.GOOS
+	}
+
+	svc.Enabled = true
+	if existing, err := c.cfg.GetService(svc.Name); err == nil {
+		if existing.Template != tpl.Name {
+			return nil, fmt.Errorf("service '%s' already exists and wasn't generated from template '%s'", svc.Name, tpl.Name)
+		}
+		svc.Enabled = existing.Enabled
+	}
+
+	c.cfg.ApplyDefaults(svc)
+	if err := c.resolvePaths(svc); err != nil {
+		return nil, fmt.Errorf("service '%s': %w", svc.Name, err)
+	}
+	if err := c.cfg.ValidateService(svc); err != nil {
+		return nil, fmt.Errorf("service '%s' is invalid: %w", svc.Name, err)
+	}
+	if !noVerify {
+		if err := c.verifyCommand(svc); err != nil {
+			return nil, fmt.Errorf("service '%s': %w", svc.Name, err)
+		}
+	}
+	return svc, nil
+}
+
+// instanceValues returns the values of the services generated from the
+// template name, in the order of their names.
+func (c *CLI) instanceValues(name string) []map[string]string {
+	var instances []*service.Service
+	for _, svc := range c.cfg.ListServices() {
+		if svc.Template == name {
+			instances = append(instances, svc)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+
+	var values []map[string]string
+	for _, svc := range instances {
+		values = append(values, svc.Matrix)
+	}
+	return values
+}
+
+// describeMatrix formats the values a service was generated with, such as
+// dir=/a, env=prod.
+func describeMatrix(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + values[key]
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/history"
+)
+
+// PruneHistory removes old run history. With olderThan set (such as "30d"),
+// runs that started longer ago are removed; otherwise the config's history
+// retention is applied, as it is after every run. With name set only that
+// service's history is pruned, else every service's, including the leftover
+// history of services that no longer exist.
+func (c *CLI) PruneHistory(ctx context.Context, name, olderThan string, verbose bool) error {
+	keep := c.cfg.Retention()
+	if olderThan != "" {
+		age, err := parseDuration(olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		if age <= 0 {
+			return fmt.Errorf("invalid --older-than: must be positive")
+		}
+		keep = history.Retention{MaxAge: age}
+	}
+	if keep.IsZero() {
+		return fmt.Errorf("nothing to prune: pass --older-than or set history.retention in %s", c.cfg.GetConfigPath())
+	}
+
+	names := []string{name}
+	if name == "" {
+		var err error
+		if names, err = history.Services(c.cfg.GetHistoryDir()); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	total, pruned := 0, 0
+	for _, n := range names {
+		removed, err := history.Prune(c.cfg.GetHistoryDir(), n, keep, now)
+		if err != nil {
+			return fmt.Errorf("pruning history of '%s': %w", n, err)
+		}
+		if removed > 0 {
+			total += removed
+			pruned++
+			if verbose {
+				c.infof("Removed %d run(s) of '%s'\n", removed, n)
+			}
+		}
+	}
+
+	c.infof("Removed %d run(s) from the history of %d service(s).\n", total, pruned)
+	return nil
+}
+
+// ExportOptions selects the runs ExportHistory writes and how.
+type ExportOptions struct {
+	Service string // Export only this service's runs; empty exports every service's
+	Format  string // "csv" or "json"
+	Since   string // Only runs that started at or after this date or duration ago
+	Until   string // Only runs that started before the end of this date or duration ago
+	To      string // File to write; empty writes to the output
+}
+
+// exportRecord is a run as ExportHistory writes it to JSON.
+type exportRecord struct {
+	*history.Record
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// ExportHistory writes run history as CSV or JSON, oldest run first, for
+// reporting on scheduled jobs outside nazim. Like PruneHistory, it includes the
+// history of services that no longer exist.
+func (c *CLI) ExportHistory(ctx context.Context, opts ExportOptions, verbose bool) error {
+	switch opts.Format {
+	case "":
+		opts.Format = "csv"
+	case "csv", "json":
+	default:
+		return fmt.Errorf("invalid format %q, use csv or json", opts.Format)
+	}
+
+	now := time.Now()
+	var since, until time.Time
+	if opts.Since != "" {
+		var err error
+		if since, err = parseTimeBound(opts.Since, now, false); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if opts.Until != "" {
+		var err error
+		if until, err = parseTimeBound(opts.Until, now, true); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	names := []string{opts.Service}
+	if opts.Service == "" {
+		var err error
+		if names, err = history.Services(c.cfg.GetHistoryDir()); err != nil {
+			return err
+		}
+	}
+
+	var records []*history.Record
+	for _, name := range names {
+		serviceRecords, err := history.Load(c.cfg.GetHistoryDir(), name)
+		if err != nil {
+			return fmt.Errorf("reading history of '%s': %w", name, err)
+		}
+		for _, rec := range serviceRecords {
+			if rec.Start.Before(since) || (!until.IsZero() && !rec.Start.Before(until)) {
+				continue
+			}
+			records = append(records, rec)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Start.Before(records[j].Start) })
+
+	out := c.out
+	if opts.To != "" {
+		f, err := os.Create(opts.To)
+		if err != nil {
+			return fmt.Errorf("creating export file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	if opts.Format == "json" {
+		err = writeHistoryJSON(out, records)
+	} else {
+		err = writeHistoryCSV(out, records)
+	}
+	if err != nil {
+		return fmt.Errorf("writing export: %w", err)
+	}
+
+	if opts.To != "" {
+		c.infof("Exported %d run(s) to %s\n", len(records), opts.To)
+	} else if verbose {
+		fmt.Fprintf(c.errOut, "Exported %d run(s)\n", len(records))
+	}
+	return nil
+}
+
+// writeHistoryCSV writes records as CSV with a header row. Times are RFC 3339
+// in UTC, so spreadsheets and scripts on any machine read them the same way.
+func writeHistoryCSV(w io.Writer, records []*history.Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"service", "id", "start", "end", "duration_seconds", "exit_code", "result", "slow", "error"}); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		result := "success"
+		if rec.Failed() {
+			result = "failure"
+		}
+		row := []string{
+			rec.Service,
+			rec.ID,
+			rec.Start.UTC().Format(time.RFC3339),
+			rec.End.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(rec.Duration().Seconds(), 'f', 3, 64),
+			strconv.Itoa(rec.ExitCode),
+			result,
+			strconv.FormatBool(rec.Slow),
+			cmp.Or(rec.Error, rec.OutputError),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeHistoryJSON writes records as a JSON array, as they are stored plus their duration.
+func writeHistoryJSON(w io.Writer, records []*history.Record) error {
+	exported := make([]exportRecord, len(records))
+	for i, rec := range records {
+		exported[i] = exportRecord{Record: rec, DurationSeconds: rec.Duration().Seconds()}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exported)
+}
+
+// parseTimeBound parses a date such as 2024-01-31 (in local time), an RFC 3339
+// time, or a duration such as 30d meaning that long before now. A date given as
+// an end bound means the end of that day, so the day itself is included.
+func parseTimeBound(s string, now time.Time, end bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := parseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("use a date (2024-01-31), a time (2024-01-31T08:00:00Z), or a duration (30d)")
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,321 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/events"
+	"github.com/calilkhalil/nazim/internal/i18n"
+	"github.com/calilkhalil/nazim/internal/importer"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/term"
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// ImportCron converts crontab entries to services. With an empty file the
+// current user's crontab (`crontab -l`) is read. selection picks the entries to
+// install ("all" or a list such as "1,3-4"); when empty, the user is asked.
+// staggerValue, if set, spreads out the selected services sharing an interval.
+func (c *CLI) ImportCron(ctx context.Context, file, selection, staggerValue string, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	step, auto, err := parseStagger(staggerValue)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	system := false
+	if file == "" {
+		data, err = trace.Output(exec.Command("crontab", "-l"))
+		if err != nil {
+			return fmt.Errorf("reading user crontab: %w", err)
+		}
+	} else {
+		data, err = os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("reading crontab: %w", err)
+		}
+		// System crontabs carry a user column
+		clean := filepath.ToSlash(filepath.Clean(file))
+		system = clean == "/etc/crontab" || strings.HasPrefix(clean, "/etc/cron.d/")
+	}
+
+	entries, err := importer.ParseCrontab(bytes.NewReader(data), system)
+	if err != nil {
+		return err
+	}
+	return c.importEntries(entries, selection, step, auto, verbose, nil)
+}
+
+// ImportTasks converts Task Scheduler tasks in folder (such as `\` or `\MyTasks`)
+// to services. With an empty file the tasks are read through schtasks, otherwise
+// from an exported task XML file. With takeOver, each imported task is deleted
+// once its nazim replacement is installed, so it doesn't run twice.
+func (c *CLI) ImportTasks(ctx context.Context, file, folder, selection, staggerValue string, takeOver, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	step, auto, err := parseStagger(staggerValue)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if file == "" {
+		if runtime.GOOS != "windows" {
+			return fmt.Errorf("reading scheduled tasks requires Windows; use --file with an exported task XML")
+		}
+		data, err = trace.Output(exec.Command("schtasks", "/query", "/xml", "ONE"))
+		if err != nil {
+			return fmt.Errorf("querying scheduled tasks: %w", err)
+		}
+	} else {
+		if takeOver {
+			return fmt.Errorf("--take-over cannot be combined with --file")
+		}
+		data, err = os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("reading task file: %w", err)
+		}
+	}
+	if platform.RequiresElevation() {
+		return fmt.Errorf("import installs several services; run it from an elevated (administrator) prompt")
+	}
+
+	entries, err := importer.ParseTasks(bytes.NewReader(data), folder)
+	if err != nil {
+		return err
+	}
+
+	leftInPlace := false
+	err = c.importEntries(entries, selection, step, auto, verbose, func(e *importer.Entry) error {
+		if !takeOver {
+			leftInPlace = true
+			return nil
+		}
+		output, err := trace.CombinedOutput(exec.Command("schtasks", "/delete", "/tn", e.Source, "/f"))
+		if err != nil {
+			return fmt.Errorf("deleting original task %s failed: %s: %w", e.Source, strings.TrimSpace(string(output)), err)
+		}
+		return nil
+	})
+	if leftInPlace {
+		c.infoln("The original tasks were left in place; disable them or import with --take-over to avoid duplicate runs.")
+	}
+	return err
+}
+
+// importEntries previews converted jobs, lets the user select some, and installs them,
+// staggering the selected ones by step or auto (see stagger).
+// onImported, if set, is called for every entry whose service was installed.
+func (c *CLI) importEntries(entries []*importer.Entry, selection string, step time.Duration, auto, verbose bool, onImported func(*importer.Entry) error) error {
+	if len(entries) == 0 {
+		c.infoln("No jobs found.")
+		return nil
+	}
+
+	importer.AssignNames(entries, func(name string) bool {
+		_, err := c.cfg.GetService(name)
+		return err == nil
+	})
+
+	c.printImportPreview(entries)
+
+	if selection == "" && c.yes {
+		selection = "all"
+	}
+	if selection == "" {
+		if !c.isInteractive() {
+			c.infoln("\nUse --select all or --select <numbers> to import entries.")
+			return nil
+		}
+		fmt.Fprint(c.out, "\n"+i18n.T("Import which entries? [all, none, or numbers such as 1,3-4]: "))
+		answer, _ := c.in.ReadString('\n')
+		selection = strings.TrimSpace(answer)
+	}
+
+	selected, err := parseSelection(selection, len(entries))
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		c.infoln("Nothing imported.")
+		return nil
+	}
+
+	var batch []*service.Service
+	for _, i := range selected {
+		if entries[i].Importable() {
+			batch = append(batch, entries[i].Service)
+		}
+	}
+	c.reportStagger(stagger(batch, step, auto), verbose)
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	var failed []string
+	for _, i := range selected {
+		entry := entries[i]
+		if !entry.Importable() {
+			c.warning("skipping #%d: %s", i+1, entry.Problem)
+			failed = append(failed, fmt.Sprintf("#%d", i+1))
+			continue
+		}
+
+		svc := entry.Service
+		svc.Platform = runtime.GOOS
+		sp := c.startProgress(fmt.Sprintf("Installing '%s'", svc.Name), verbose)
+		err := c.installNew(platformMgr, svc)
+		sp.Stop()
+		if err != nil {
+			c.warning("failed to import #%d '%s': %v", i+1, svc.Name, err)
+			failed = append(failed, fmt.Sprintf("#%d", i+1))
+			continue
+		}
+		if !svc.Enabled {
+			if err := platformMgr.Disable(svc.Name); err != nil {
+				c.warning("failed to disable '%s': %v", svc.Name, err)
+				if c.strict {
+					failed = append(failed, fmt.Sprintf("#%d", i+1))
+					continue
+				}
+			}
+		}
+		if onImported != nil {
+			if err := onImported(entry); err != nil {
+				c.warning("'%s' was imported, but %v", svc.Name, err)
+			}
+		}
+		if verbose {
+			c.infof("Imported '%s'\n", svc.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("imported %d of %d selected job(s); failed: %s",
+			len(selected)-len(failed), len(selected), strings.Join(failed, ", "))
+	}
+	c.infof("Imported %d job(s).\n", len(selected))
+	return nil
+}
+
+// installNew installs a service and adds it to the config, uninstalling it again
+// if the config can't be saved.
+func (c *CLI) installNew(platformMgr platform.Manager, svc *service.Service) error {
+	c.cfg.ApplyDefaults(svc)
+	if err := c.cfg.ValidateService(svc); err != nil {
+		return err
+	}
+	if err := platformMgr.Install(svc); err != nil {
+		return fmt.Errorf("failed to install service: %w", err)
+	}
+	if err := c.cfg.AddService(svc); err != nil {
+		if uninstallErr := platformMgr.Uninstall(svc.Name); uninstallErr != nil {
+			return fmt.Errorf("failed to add service to config: %w (also failed to uninstall: %v)", err, uninstallErr)
+		}
+		return fmt.Errorf("failed to add service to config: %w", err)
+	}
+	c.serviceEvent(events.ServiceAdded, svc.Name)
+	return nil
+}
+
+func (c *CLI) printImportPreview(entries []*importer.Entry) {
+	t := &table{columns: []column{
+		{header: "#"},
+		{header: "SOURCE"},
+		{header: "NAME"},
+		{header: "RUNS"},
+		{header: "COMMAND", flex: true},
+		{header: "NOTE", flex: true},
+	}}
+	for i, e := range entries {
+		name, runs, note := "-", e.When, e.Problem
+		if e.Importable() {
+			name = e.Service.Name
+			runs = describeTrigger(e.Service)
+		} else {
+			note = "skipped: " + note
+		}
+		t.addRow(strconv.Itoa(i+1), e.Source, name, runs, e.Command, note)
+	}
+
+	width := term.Width(c.out)
+	if width == 0 {
+		width = defaultWidth
+	}
+	c.renderTable(t, width)
+}
+
+// parseSelection parses "all", "none", or a comma-separated list of 1-based
+// numbers and ranges into 0-based indexes.
+func parseSelection(s string, n int) ([]int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "none":
+		return nil, nil
+	case "all":
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	var selected []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		start, end, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(end); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", part)
+			}
+		}
+		if first < 1 || last > n || last < first {
+			return nil, fmt.Errorf("selection %q out of range 1-%d", part, n)
+		}
+		for i := first; i <= last; i++ {
+			if !seen[i] {
+				seen[i] = true
+				selected = append(selected, i-1)
+			}
+		}
+	}
+	return selected, nil
+}
+
+// isInteractive reports whether prompts can be answered: the input is a
+// terminal, or answers supplied by whoever embeds the CLI.
+func (c *CLI) isInteractive() bool {
+	return c.inFile == nil || term.IsTerminal(c.inFile)
+}
+
+// confirm asks a yes/no question, defaulting to no. With --yes it answers yes
+// without asking, and without a terminal to ask on it answers no.
+func (c *CLI) confirm(question string) bool {
+	if c.yes {
+		return true
+	}
+	if !c.isInteractive() {
+		return false
+	}
+	fmt.Fprint(c.out, question)
+	answer, _ := c.in.ReadString('\n')
+	return i18n.IsYes(answer)
+}
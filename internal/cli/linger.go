@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// warnLinger warns, the first time a command installs a service, when the
+// user's systemd user manager doesn't linger: the service then only runs
+// while the user is logged in, which is rarely what a scheduled job wants.
+// When logind can't say, nothing is said; doctor reports that.
+func (c *CLI) warnLinger(svc *service.Service) {
+	if c.lingerChecked || svc.OnWindowsHost() {
+		return
+	}
+	c.lingerChecked = true
+	username, lingering, err := platform.Linger()
+	if err != nil || lingering {
+		return
+	}
+	c.warning("lingering is off for %s, so services only run while %s is logged in; run 'nazim doctor --enable-linger' (or 'sudo loginctl enable-linger %s') to run them from boot and after logout",
+		username, username, username)
+}
+
+// checkLinger tells, for doctor, when the user's systemd user manager doesn't
+// linger. That isn't counted as a problem: services that only need to run
+// while the user is logged in, as on a desktop, are fine without it. With
+// separate, a blank line sets it apart from what doctor printed before.
+func (c *CLI) checkLinger(separate, verbose bool) {
+	username, lingering, err := platform.Linger()
+	if err != nil {
+		if verbose {
+			c.infof("Couldn't check lingering: %v\n", err)
+		}
+		return
+	}
+	if lingering {
+		return
+	}
+	if separate {
+		fmt.Fprintln(c.out)
+	}
+	fmt.Fprintf(c.out, "Lingering is off for %s: services don't start at boot, and stop when %s logs out.\n", username, username)
+	fmt.Fprintln(c.out, "Run 'nazim doctor --enable-linger' to keep them running.")
+}
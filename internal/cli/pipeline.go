@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/runner"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// memberNoun returns what the services a pipeline or a parallel group runs
+// are called in messages.
+func memberNoun(svc *service.Service) string {
+	if svc.IsGroup() {
+		return "member"
+	}
+	return "step"
+}
+
+// verifyMembers checks that the steps of a pipeline, or the members of a
+// parallel group, are services it can run: ones that exist and don't run
+// other services themselves.
+func (c *CLI) verifyMembers(svc *service.Service) error {
+	noun := memberNoun(svc)
+	for _, name := range svc.Members() {
+		member, err := c.cfg.GetService(name)
+		if err != nil {
+			return fmt.Errorf("%s '%s' does not exist; add it first, or use --no-verify to skip this check", noun, name)
+		}
+		if member.RunsServices() {
+			return fmt.Errorf("%s '%s' is a pipeline or parallel group itself, and those can't be nested", noun, name)
+		}
+	}
+	return nil
+}
+
+// runPipeline runs the steps of a pipeline one after another, each as
+// `nazim exec` would, so they keep their own logs, history, and failure
+// handling. The pipeline's own log records when each step started and how it
+// finished, and the run fails with the exit code of the first step that
+// failed. Unless the pipeline continues on failure, the steps after it are
+// skipped.
+func (c *CLI) runPipeline(ctx context.Context, svc *service.Service, opts runner.Options, verbose bool) (*runner.Result, error) {
+	journal, err := runner.Begin(svc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	exitCode := 0
+	for i, step := range svc.Steps {
+		if ctx.Err() != nil {
+			journal.Errorf("pipeline stopped before step '%s'", step)
+			exitCode = max(exitCode, 1)
+			break
+		}
+
+		code := c.runMember(ctx, journal, "step", step, verbose)
+		if code == 0 {
+			continue
+		}
+		if exitCode == 0 {
+			exitCode = code
+		}
+		if rest := svc.Steps[i+1:]; len(rest) > 0 && !svc.ContinuesOnFailure() {
+			journal.Printf("skipping the remaining steps: %s", strings.Join(rest, ", "))
+			break
+		}
+	}
+	return journal.Finish(exitCode), nil
+}
+
+// runGroup runs the members of a parallel group at the same time, at most
+// max_parallel at once, each as `nazim exec` would. The group's own log
+// records when each member started and how it finished, followed by how
+// many failed; the run fails with the exit code of the first member, in the
+// group's order, that failed.
+func (c *CLI) runGroup(ctx context.Context, svc *service.Service, opts runner.Options, verbose bool) (*runner.Result, error) {
+	journal, err := runner.Begin(svc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]int, len(svc.ParallelGroup))
+	slots := make(chan struct{}, svc.Concurrency())
+	var wg sync.WaitGroup
+	for i, member := range svc.ParallelGroup {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			journal.Errorf("group stopped before member '%s'", member)
+			codes[i] = 1
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			codes[i] = c.runMember(ctx, journal, "member", member, verbose)
+		}()
+	}
+	wg.Wait()
+
+	exitCode := 0
+	var failed []string
+	for i, code := range codes {
+		if code == 0 {
+			continue
+		}
+		if exitCode == 0 {
+			exitCode = code
+		}
+		failed = append(failed, svc.ParallelGroup[i])
+	}
+	if len(failed) > 0 {
+		journal.Errorf("%d of %d members failed: %s", len(failed), len(codes), strings.Join(failed, ", "))
+	} else {
+		journal.Printf("all %d members succeeded", len(codes))
+	}
+	return journal.Finish(exitCode), nil
+}
+
+// runMember runs a step of a pipeline or a member of a parallel group,
+// called noun in the journal's lines, and returns its exit code. A paused
+// member, or one skipped because its previous run is still going, counts as
+// failed: the pipeline or group can't be said to have done its job without it.
+func (c *CLI) runMember(ctx context.Context, journal *runner.Journal, noun, name string, verbose bool) int {
+	member, err := c.cfg.GetService(name)
+	if err != nil {
+		journal.Errorf("%s '%s' does not exist", noun, name)
+		return 1
+	}
+	if member.RunsServices() {
+		journal.Errorf("%s '%s' is a pipeline or parallel group itself, and those can't be nested", noun, name)
+		return 1
+	}
+	if st := c.pausedState(name); st != nil {
+		journal.Errorf("%s '%s' is paused until %s", noun, name, st.PausedUntil.Local().Format("2006-01-02 15:04:05"))
+		return 1
+	}
+
+	journal.Printf("%s '%s' started", noun, name)
+	start := time.Now()
+	code, err := c.exec(ctx, name, false, false, false, verbose)
+	duration := time.Since(start).Round(time.Millisecond)
+	if errors.Is(err, errStillRunning) {
+		journal.Errorf("%s '%s' was skipped: its previous run is still going", noun, name)
+		return 1
+	}
+	if err != nil {
+		journal.Errorf("%s '%s' failed after %s: %v", noun, name, duration, err)
+		return max(code, 1)
+	}
+	if code == 0 {
+		journal.Printf("%s '%s' succeeded in %s", noun, name, duration)
+		return 0
+	}
+
+	// The member's last stderr lines tell why at a glance, as they do for a command
+	if rec, err := history.Last(c.cfg.GetHistoryDir(), name); err == nil && rec != nil && !rec.Start.Before(start) {
+		for _, line := range rec.ErrorLines {
+			journal.Errorf("%s: %s", name, line)
+		}
+	}
+	journal.Errorf("%s '%s' failed with exit code %d after %s", noun, name, code, duration)
+	return code
+}
+
+// printMembers prints the steps of a pipeline, or the members of a parallel
+// group, for Status, each with how its last run went.
+func (c *CLI) printMembers(svc *service.Service) {
+	if svc.IsGroup() {
+		fmt.Fprintf(c.out, "Parallel Group (%d at a time):\n", svc.Concurrency())
+	} else {
+		onFailure := service.StepFailureAbort
+		if svc.ContinuesOnFailure() {
+			onFailure = service.StepFailureContinue
+		}
+		fmt.Fprintf(c.out, "Steps (on failure: %s):\n", onFailure)
+	}
+	for i, name := range svc.Members() {
+		last := "never run"
+		if _, err := c.cfg.GetService(name); err != nil {
+			last = c.paint(term.Red, "does not exist")
+		} else if rec, err := history.Last(c.cfg.GetHistoryDir(), name); err == nil && rec != nil {
+			last = fmt.Sprintf("%s, %s", rec.Start.Local().Format("2006-01-02 15:04:05"), describeRun(rec))
+			if rec.Failed() {
+				last = c.paint(term.Red, last)
+			}
+		}
+		fmt.Fprintf(c.out, "  %d. %s: %s\n", i+1, name, last)
+	}
+}
+
+// memberLines returns the log lines the members of a pipeline or parallel
+// group wrote during its runs, to show with its own. Runs of the members on
+// their own schedule, or by hand, are left out.
+func (c *CLI) memberLines(svc *service.Service, streams []string) ([]runner.LogLine, error) {
+	records, err := history.Load(c.cfg.GetHistoryDir(), svc.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run history: %w", err)
+	}
+	during := func(t time.Time) bool {
+		for _, rec := range records {
+			if !t.Before(rec.Start) && !t.After(rec.End) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var lines []runner.LogLine
+	for _, name := range svc.Members() {
+		memberLines, err := runner.ReadLogs(c.cfg.GetLogsDir(), name, streams...)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range memberLines {
+			if during(line.Time) {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines, nil
+}
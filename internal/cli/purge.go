@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/eventlog"
+	"github.com/calilkhalil/nazim/internal/i18n"
+	"github.com/calilkhalil/nazim/internal/platform"
+)
+
+// Entries of the config directories that Purge removes. Anything else in them
+// was put there by someone else, so it stays, and so does its directory.
+var (
+	purgedConfigEntries = []string{"services.yaml", "services.yaml.tmp", "services.db", "bundle.yaml", "scripts"}
+	purgedDataEntries   = []string{"scripts"}
+	purgedStateEntries  = []string{"logs", "history", "services", "snapshots", "wrappers", "sync", "runs", "versions", "checksums.json", "checksums.lock", "events.jsonl", "events.jsonl.1"}
+)
+
+// Purge uninstalls nazim from the machine: every service is removed from the
+// scheduler, including services nazim installed that the config no longer
+// lists, and so are wrappers, logs, including those left in ~/.nazim by the
+// first versions, run history, the Windows event source, and, unless
+// keepConfig, the config and the scripts its services run. It asks
+// first, listing what it will remove; with --yes it doesn't ask.
+func (c *CLI) Purge(ctx context.Context, keepConfig, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	if platform.RequiresElevation() {
+		return fmt.Errorf("purge uninstalls every service; run it from an elevated (administrator) prompt")
+	}
+
+	var names []string
+	for _, svc := range c.cfg.ListServices() {
+		names = append(names, svc.Name)
+	}
+	defs, err := discoverDefinitions()
+	if err != nil {
+		if err := c.warn("%v", err); err != nil {
+			return err
+		}
+	}
+	for _, def := range defs {
+		if !slices.Contains(names, def.Service.Name) {
+			names = append(names, def.Service.Name)
+		}
+	}
+	slices.Sort(names)
+
+	dirs := c.cfg.Dirs()
+	paths := existingEntries(dirs.State, purgedStateEntries)
+	var purgedDirs []string
+	if !keepConfig {
+		paths = append(paths, existingEntries(dirs.Data, purgedDataEntries)...)
+		paths = append(paths, existingEntries(dirs.Config, purgedConfigEntries)...)
+		purgedDirs = append(purgedDirs, dirs.State, dirs.Data, dirs.Config)
+	} else if dirs.State != dirs.Config && dirs.State != dirs.Data {
+		// The config keeps its scripts, which may share the state directory
+		purgedDirs = append(purgedDirs, dirs.State)
+	}
+	if legacy := config.LegacyDir(); legacy != "" {
+		// Logs of the first versions of nazim that weren't moved, see Config.Migrate
+		paths = append(paths, existingEntries(legacy, []string{"logs"})...)
+		purgedDirs = append(purgedDirs, legacy)
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	if len(names) == 0 && len(paths) == 0 {
+		c.infoln("Nothing to purge.")
+		return nil
+	}
+	if len(names) > 0 {
+		c.infof("Services to uninstall: %s\n", strings.Join(names, ", "))
+	}
+	if len(paths) > 0 {
+		c.infoln("Files to delete:")
+		for _, path := range paths {
+			fmt.Fprintf(c.out, "  %s\n", path)
+		}
+	}
+	if keepConfig {
+		c.infof("The config in %s is kept.\n", dirs.Config)
+	}
+	if !c.confirm(i18n.T("Remove all of this? [y/N]: ")) {
+		if !c.yes && !c.isInteractive() {
+			return fmt.Errorf("purge needs confirmation; run it from a terminal or pass --yes")
+		}
+		return fmt.Errorf("purge cancelled, nothing was removed")
+	}
+
+	sp := c.startProgress("Purging nazim", verbose)
+	defer sp.Stop()
+
+	var failures int
+	if len(names) > 0 {
+		platformMgr, err := c.newManager(platform.WithProgress(sp.Step))
+		if err != nil {
+			return fmt.Errorf("failed to create platform manager: %w", err)
+		}
+		for _, name := range names {
+			sp.Step(fmt.Sprintf("Uninstalling '%s'", name))
+			if err := platformMgr.Uninstall(name); err != nil {
+				sp.Stop()
+				c.warning("failed to uninstall '%s': %v", name, err)
+				failures++
+			}
+		}
+	}
+
+	sp.Step("Removing event source")
+	if err := eventlog.Unregister(); err != nil {
+		sp.Stop()
+		c.warning("%v", err)
+		failures++
+	}
+
+	sp.Step("Deleting files")
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			sp.Stop()
+			c.warning("%v", err)
+			failures++
+		}
+	}
+	sp.Stop()
+
+	// Directories left with files nazim didn't create are kept
+	slices.Sort(purgedDirs)
+	for _, dir := range slices.Compact(purgedDirs) {
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			c.infof("Kept %s, which holds files nazim didn't create.\n", dir)
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d item(s) could not be removed", failures)
+	}
+	c.infof("Removed %d service(s) and %d file(s) or folder(s).\n", len(names), len(paths))
+	if exe, err := os.Executable(); err == nil {
+		c.infof("nazim itself is still at %s; delete it to finish uninstalling.\n", exe)
+	}
+	return nil
+}
+
+// existingEntries returns the paths of the entries of dir that exist.
+func existingEntries(dir string, entries []string) []string {
+	var paths []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry)
+		if _, err := os.Lstat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/events"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/term"
+	"github.com/calilkhalil/nazim/internal/versions"
+)
+
+// recordVersion records a service and the scheduler definitions generated for
+// it as its latest version, see package versions. Failing to doesn't undo the
+// install, so it's only a warning.
+func (c *CLI) recordVersion(platformMgr platform.Manager, svc *service.Service) {
+	var files []versions.File
+	if defs, err := platformMgr.Preview(svc); err == nil {
+		for _, def := range defs {
+			files = append(files, versions.File{Name: def.Name, Content: def.Content})
+		}
+	}
+	if _, err := versions.Record(c.cfg.GetVersionsDir(), svc, files, time.Now(), c.cfg.EncryptionKey()); err != nil {
+		c.warning("failed to record version of '%s': %v", svc.Name, err)
+	}
+}
+
+// RollbackOptions selects the version Rollback goes back to.
+type RollbackOptions struct {
+	To   string // The version, such as v3; empty is the one before the latest
+	Pin  bool   // Install the version's definitions as they were, instead of generating them again
+	List bool   // List the kept versions instead
+}
+
+// Rollback puts a service back as it was at an earlier version, see package
+// versions: its settings are restored, keeping whether it is enabled, and it
+// is reinstalled, which records them as its latest version in turn. The
+// definitions generated for it may differ from those of the version, after
+// upgrading nazim; with opts.Pin, those are installed as they were instead,
+// as hand-edited units (see service.Service.Units).
+func (c *CLI) Rollback(ctx context.Context, name string, opts RollbackOptions, verbose bool) error {
+	current, err := c.cfg.GetService(name)
+	if err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	}
+	kept, err := versions.Load(c.cfg.GetVersionsDir(), name, c.cfg.EncryptionKey())
+	if err != nil {
+		return err
+	}
+	if opts.List {
+		c.listVersions(current, kept)
+		return nil
+	}
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	if err := c.cfg.CheckModifiable(name); err != nil {
+		return err
+	}
+
+	var target *versions.Version
+	if opts.To == "" {
+		if len(kept) < 2 {
+			return fmt.Errorf("service '%s' has no earlier version to roll back to", name)
+		}
+		target = kept[len(kept)-2]
+	} else {
+		number, err := versions.ParseNumber(opts.To)
+		if err != nil {
+			return err
+		}
+		if target = versions.Find(kept, number); target == nil {
+			return fmt.Errorf("service '%s' has no version v%d; nazim rollback %s --list shows the kept ones", name, number, name)
+		}
+	}
+	svc, err := target.Service()
+	if err != nil {
+		return err
+	}
+	if svc.Name != name {
+		return fmt.Errorf("%s of '%s' is of service '%s'", target, name, svc.Name)
+	}
+	svc.Enabled = current.Enabled
+
+	if opts.Pin {
+		if err := c.pinVersion(svc, target); err != nil {
+			return err
+		}
+	}
+	if err := c.cfg.ValidateService(svc); err != nil {
+		return fmt.Errorf("%s can't be restored: %w", target, err)
+	}
+	if err := c.checkUnits(svc); err != nil {
+		return err
+	}
+
+	if err := c.cfg.UpdateService(svc); err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+
+	sp := c.startProgress(fmt.Sprintf("Reinstalling '%s'", name), verbose)
+	defer sp.Stop()
+	platformMgr, err := c.newManager(platform.WithProgress(sp.Step))
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+	if err := platformMgr.Uninstall(name); err != nil {
+		if c.strict {
+			return fmt.Errorf("failed to uninstall current version: %w", err)
+		}
+		if verbose {
+			c.warning("failed to uninstall current version: %v", err)
+		}
+	}
+	if err := platformMgr.Install(svc); err != nil {
+		return fmt.Errorf("failed to reinstall service: %w", err)
+	}
+	sp.Stop()
+
+	c.serviceEvent(events.ServiceUpdated, name)
+	c.infof("Service '%s' rolled back to %s.\n", name, target)
+	if !opts.Pin && !c.sameDefinitions(platformMgr, svc, target) {
+		c.infof("The scheduler definitions generated now differ from those of %s; --pin installs them as they were.\n", target)
+	}
+	return nil
+}
+
+// pinVersion writes the definitions of a version to the pin directory of the
+// service, and has the service install them as they are. Only unit files and
+// plists can be installed that way; Windows versions keep the command that
+// created the task rather than its XML.
+func (c *CLI) pinVersion(svc *service.Service, v *versions.Version) error {
+	if runtime.GOOS == "windows" || svc.OnWindowsHost() || svc.LongRunning {
+		return fmt.Errorf("--pin needs the unit files or plist of %s, which Task Scheduler tasks don't have; roll back without it", v)
+	}
+	dir := filepath.Join(versions.PinDir(c.cfg.GetVersionsDir(), svc.Name), v.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating pin directory: %w", err)
+	}
+	svc.Units = nil
+	for _, file := range v.Files {
+		path := filepath.Join(dir, filepath.Base(file.Name))
+		if err := os.WriteFile(path, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("writing pinned definition: %w", err)
+		}
+		svc.Units = append(svc.Units, path)
+	}
+	if len(svc.Units) == 0 {
+		return fmt.Errorf("%s of '%s' has no definitions to pin", v, svc.Name)
+	}
+	return nil
+}
+
+// sameDefinitions reports whether the definitions generated for svc now are
+// those of version v. It reports true when they can't be generated, since
+// there is nothing to point out then.
+func (c *CLI) sameDefinitions(platformMgr platform.Manager, svc *service.Service, v *versions.Version) bool {
+	defs, err := platformMgr.Preview(svc)
+	if err != nil {
+		return true
+	}
+	if len(defs) != len(v.Files) {
+		return false
+	}
+	for i, def := range defs {
+		if def.Name != v.Files[i].Name || def.Content != v.Files[i].Content {
+			return false
+		}
+	}
+	return true
+}
+
+// listVersions prints the kept versions of a service, newest first.
+func (c *CLI) listVersions(svc *service.Service, kept []*versions.Version) {
+	if len(kept) == 0 {
+		c.infof("No versions of '%s' are kept yet; one is recorded whenever it is installed.\n", svc.Name)
+		return
+	}
+	t := &table{columns: []column{{header: "VERSION"}, {header: "INSTALLED"}, {header: "TRIGGER"}, {header: "COMMAND", flex: true}}}
+	for i := len(kept) - 1; i >= 0; i-- {
+		v := kept[i]
+		name := v.String()
+		if i == len(kept)-1 {
+			name += " (current)"
+		}
+		trigger, command := "?", "?"
+		if old, err := v.Service(); err == nil {
+			trigger, command = describeTrigger(old), commandLine(old)
+			if len(old.Units) > 0 {
+				trigger += " (hand-edited)"
+			}
+		}
+		t.addRow(name, v.Installed.Local().Format("2006-01-02 15:04:05"), trigger, command)
+	}
+	c.renderTable(t, term.Width(c.out))
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// parseStagger parses a --stagger value: "auto", which spreads services evenly
+// across their interval, or the step between them, such as 5m. A zero step
+// and no error means no staggering.
+func parseStagger(value string) (step time.Duration, auto bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return 0, false, nil
+	case "auto":
+		return 0, true, nil
+	}
+	step, err = parseDuration(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid --stagger '%s' (use auto or a duration such as 5m): %w", value, err)
+	}
+	if step%time.Minute != 0 {
+		return 0, false, fmt.Errorf("invalid --stagger '%s': use whole minutes", value)
+	}
+	return step, false, nil
+}
+
+// stagger gives the services that share an interval offsets that spread their
+// runs across it, so a batch of hourly jobs doesn't start in the same minute:
+// with auto, 12 hourly services start 5 minutes apart, and with a step, each
+// starts step after the one before, wrapping around the interval. Services are
+// taken in name order, so staggering the same batch again gives the same
+// offsets. Services that already have an offset, start at boot or logon, or
+// whose interval can't take an offset are left alone. It returns the services
+// it changed.
+func stagger(services []*service.Service, step time.Duration, auto bool) []*service.Service {
+	if step == 0 && !auto {
+		return nil
+	}
+
+	byInterval := make(map[time.Duration][]*service.Service)
+	for _, svc := range services {
+		interval := svc.GetInterval()
+		if svc.Offset != "" || svc.OnStartup || svc.OnLogon || !service.Alignable(interval) {
+			continue
+		}
+		byInterval[interval] = append(byInterval[interval], svc)
+	}
+
+	var staggered []*service.Service
+	for interval, group := range byInterval {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Name < group[j].Name })
+		spacing := step
+		if auto {
+			spacing = max(time.Minute, (interval / time.Duration(len(group))).Truncate(time.Minute))
+		}
+		for i, svc := range group {
+			svc.Offset = service.FormatOffset(time.Duration(i) * spacing % interval)
+			staggered = append(staggered, svc)
+		}
+	}
+	sort.Slice(staggered, func(i, j int) bool { return staggered[i].Name < staggered[j].Name })
+	return staggered
+}
+
+// reportStagger tells which services stagger moved: each of them with verbose,
+// otherwise how many.
+func (c *CLI) reportStagger(staggered []*service.Service, verbose bool) {
+	if len(staggered) == 0 {
+		return
+	}
+	if !verbose {
+		c.infof("Staggered %d service(s) across their intervals.\n", len(staggered))
+		return
+	}
+	for _, svc := range staggered {
+		c.infof("Staggered '%s': %s\n", svc.Name, describeInterval(svc))
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/state"
+)
+
+// staleStatus is the status list shows for an enabled service that is stale.
+const staleStatus = "Stale"
+
+// staleness is how long an enabled service has gone without running, against
+// how long it should have.
+type staleness struct {
+	Service     string
+	Every       time.Duration // The longest gap expected between its runs, see runGap
+	LastRun     time.Time     // When it last ran; zero if it never did
+	InstalledAt time.Time     // When nazim installed it, counted from while it never ran
+	Stale       bool          // It went over grace times Every without running
+}
+
+// checkStale works out whether an enabled service was expected to run but
+// didn't, such as when the machine slept through its runs or the scheduler
+// stopped starting it. Its last run is the latest of the run history, its
+// state, and what the scheduler reports, which also counts runs nazim couldn't
+// record; a service that never ran is stale once grace times its gap between
+// runs passed since it was installed. It returns nil for services that aren't
+// expected to run at any particular time: disabled ones, paused ones (see
+// pauseFailing), ones started only by boot, logon, events, or file changes,
+// and ones installed before nazim kept their install time that never ran.
+func (c *CLI) checkStale(platformMgr platform.Manager, svc *service.Service, now time.Time, grace float64) *staleness {
+	if !svc.Enabled {
+		return nil
+	}
+	every := runGap(svc, now)
+	if every <= 0 {
+		return nil
+	}
+
+	s := &staleness{Service: svc.Name, Every: every}
+	if last, err := history.Last(c.cfg.GetHistoryDir(), svc.Name); err == nil && last != nil {
+		s.LastRun = last.End
+	}
+	if st, err := state.Load(c.cfg.GetServiceStateDir(), svc.Name); err == nil {
+		if st.Paused(now) {
+			return nil
+		}
+		// A run skipped for a blackout was as good as one for staleness
+		s.LastRun = latest(s.LastRun, latest(st.LastRun, st.LastSkipped))
+		s.InstalledAt = st.InstalledAt
+	}
+	if started, err := platformMgr.LastRun(svc.Name); err == nil {
+		s.LastRun = latest(s.LastRun, started)
+	}
+
+	since := s.LastRun
+	if since.IsZero() {
+		if since = s.InstalledAt; since.IsZero() {
+			return nil
+		}
+	}
+	s.Stale = now.Sub(since) > time.Duration(grace*float64(every))
+	return s
+}
+
+// describe says how long the service went without running, e.g. "last ran Oct 14 09:00, expected every 1h".
+func (s *staleness) describe(now time.Time) string {
+	if s.LastRun.IsZero() {
+		return fmt.Sprintf("never ran since it was installed %s, expected every %s",
+			formatRunTime(s.InstalledAt, now), formatDuration(s.Every))
+	}
+	return fmt.Sprintf("last ran %s, expected every %s", formatRunTime(s.LastRun, now), formatDuration(s.Every))
+}
+
+// latest returns the later of two times.
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// parseGrace parses the --grace value, falling back to the config's stale grace.
+func (c *CLI) parseGrace(value string) (float64, error) {
+	if value == "" {
+		return c.cfg.StaleGrace(), nil
+	}
+	grace, err := strconv.ParseFloat(value, 64)
+	if err != nil || grace < 1 {
+		return 0, fmt.Errorf("invalid --grace %q: must be a number of at least 1", value)
+	}
+	return grace, nil
+}
+
+// runGap returns the longest a service is expected to go between runs: its
+// interval, or the longest gap between the next runs of its schedule, which
+// for weekday schedules spans the weekend. Services started only by boot,
+// logon, events, or file changes have none.
+func runGap(svc *service.Service, now time.Time) time.Duration {
+	if interval := svc.GetInterval(); interval > 0 {
+		return interval
+	}
+	sched := svc.GetSchedule()
+	if sched == nil {
+		return 0
+	}
+	var gap time.Duration
+	runs := sched.NextN(now, 8)
+	for i := 1; i < len(runs); i++ {
+		gap = max(gap, runs[i].Sub(runs[i-1]))
+	}
+	return gap
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// statsSlowest is how many of the slowest services Stats lists.
+const statsSlowest = 5
+
+// statsReport is what Stats shows, and its JSON form.
+type statsReport struct {
+	Generated time.Time     `json:"generated"`
+	Services  int           `json:"services"`
+	LastDay   runTotals     `json:"last_24h"`
+	LastWeek  runTotals     `json:"last_7d"`
+	Grace     float64       `json:"grace"` // Stale services went over this many of their gaps between runs
+	Slowest   []slowService `json:"slowest"`
+	Stale     []staleRun    `json:"stale"`
+}
+
+// runTotals counts the runs of every service over a period.
+type runTotals struct {
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"` // Failures over runs, 0 without runs
+}
+
+func (t *runTotals) add(rec *history.Record) {
+	t.Runs++
+	if rec.Failed() {
+		t.Failures++
+	}
+	t.FailureRate = float64(t.Failures) / float64(t.Runs)
+}
+
+// describe formats the totals, e.g. "312 run(s), 9 failed (2.9%)".
+func (t *runTotals) describe() string {
+	if t.Runs == 0 {
+		return "no runs"
+	}
+	return fmt.Sprintf("%d run(s), %d failed (%.1f%%)", t.Runs, t.Failures, 100*t.FailureRate)
+}
+
+// slowService is a service by how long its runs of the last 7 days took.
+type slowService struct {
+	Service          string  `json:"service"`
+	Runs             int     `json:"runs"`
+	AverageSeconds   float64 `json:"average_seconds"`
+	LongestSeconds   float64 `json:"longest_seconds"`
+	average, longest time.Duration
+}
+
+// staleRun is a stale service, see checkStale.
+type staleRun struct {
+	Service      string    `json:"service"`
+	EverySeconds float64   `json:"every_seconds"`     // The longest gap expected between its runs
+	LastRun      time.Time `json:"last_run,omitzero"` // Zero if it never ran
+	InstalledAt  time.Time `json:"installed_at,omitzero"`
+	staleness    *staleness
+}
+
+// Stats summarizes the runs of every configured service: how many ran and
+// failed over the last 24 hours and 7 days, the services whose runs took
+// longest on average, and the stale services, which haven't run in over grace
+// times their interval or the longest gap of their schedule (see checkStale).
+func (c *CLI) Stats(ctx context.Context, output, grace string, verbose bool) error {
+	if output != "" && output != "text" && output != "json" {
+		return fmt.Errorf("invalid output format %q, use text or json", output)
+	}
+	graceFactor, err := c.parseGrace(grace)
+	if err != nil {
+		return err
+	}
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	now := time.Now()
+	report := &statsReport{Generated: now, Grace: graceFactor, Slowest: []slowService{}, Stale: []staleRun{}}
+	for _, svc := range c.cfg.ListServices() {
+		if svc.CheckPlatform(runtime.GOOS) != nil {
+			continue
+		}
+		report.Services++
+
+		records, err := history.Load(c.cfg.GetHistoryDir(), svc.Name)
+		if err != nil {
+			return fmt.Errorf("reading history of '%s': %w", svc.Name, err)
+		}
+		slow := slowService{Service: svc.Name}
+		var total time.Duration
+		for _, rec := range records {
+			if now.Sub(rec.Start) > 7*24*time.Hour {
+				continue
+			}
+			report.LastWeek.add(rec)
+			if now.Sub(rec.Start) <= 24*time.Hour {
+				report.LastDay.add(rec)
+			}
+			slow.Runs++
+			total += rec.Duration()
+			slow.longest = max(slow.longest, rec.Duration())
+		}
+		if slow.Runs > 0 {
+			slow.average = total / time.Duration(slow.Runs)
+			slow.AverageSeconds = slow.average.Seconds()
+			slow.LongestSeconds = slow.longest.Seconds()
+			report.Slowest = append(report.Slowest, slow)
+		}
+
+		if s := c.checkStale(platformMgr, svc, now, graceFactor); s != nil && s.Stale {
+			report.Stale = append(report.Stale, staleRun{
+				Service: svc.Name, EverySeconds: s.Every.Seconds(), LastRun: s.LastRun, InstalledAt: s.InstalledAt, staleness: s,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Slowest, func(i, j int) bool {
+		return report.Slowest[i].average > report.Slowest[j].average
+	})
+	if len(report.Slowest) > statsSlowest {
+		report.Slowest = report.Slowest[:statsSlowest]
+	}
+	sort.SliceStable(report.Stale, func(i, j int) bool {
+		return report.Stale[i].LastRun.Before(report.Stale[j].LastRun)
+	})
+
+	if output == "json" {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	if report.Services == 0 {
+		c.infoln("No services found.")
+		return nil
+	}
+	fmt.Fprintf(c.out, "Services:      %d\n", report.Services)
+	fmt.Fprintf(c.out, "Last 24 hours: %s\n", report.LastDay.describe())
+	fmt.Fprintf(c.out, "Last 7 days:   %s\n", report.LastWeek.describe())
+
+	width := term.Width(c.out)
+	if width == 0 {
+		width = defaultWidth
+	}
+	if len(report.Slowest) > 0 {
+		fmt.Fprint(c.out, "\nSlowest services over the last 7 days:\n\n")
+		t := &table{columns: []column{{header: "SERVICE", flex: true}, {header: "RUNS"}, {header: "AVERAGE"}, {header: "LONGEST"}}}
+		for _, slow := range report.Slowest {
+			t.addRow(slow.Service, fmt.Sprint(slow.Runs), roundDuration(slow.average).String(), roundDuration(slow.longest).String())
+		}
+		c.renderTable(t, width)
+	}
+
+	if len(report.Stale) > 0 {
+		fmt.Fprintf(c.out, "\nStale services, which haven't run in over %g times their interval:\n\n", graceFactor)
+		t := &table{columns: []column{
+			{header: "SERVICE", flex: true, color: func(string) term.Color { return term.Red }},
+			{header: "EVERY"}, {header: "LAST RUN"},
+		}}
+		for _, stale := range report.Stale {
+			lastRun := formatRunTime(stale.LastRun, now)
+			if stale.LastRun.IsZero() {
+				lastRun = "never, installed " + formatRunTime(stale.InstalledAt, now)
+			}
+			t.addRow(stale.Service, formatDuration(stale.staleness.Every), lastRun)
+		}
+		c.renderTable(t, width)
+	} else {
+		fmt.Fprintln(c.out, "\nNo stale services.")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/state"
+)
+
+// statusReport is the machine-readable form of Status.
+type statusReport struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Command     string            `json:"command"`
+	Args        []string          `json:"args,omitempty"`
+	Steps       []string          `json:"steps,omitempty"`          // The services a pipeline runs, in order
+	Group       []string          `json:"parallel_group,omitempty"` // The services a parallel group runs at once
+	WorkDir     string            `json:"workdir,omitempty"`
+	Trigger     string            `json:"trigger"`
+	Timezone    string            `json:"timezone,omitempty"` // Of the schedule, whose next runs are given in it
+	NextRuns    []time.Time       `json:"next_runs,omitempty"`
+	NextRun     *time.Time        `json:"next_run,omitempty"` // When the scheduler will next start the service
+	Blackout    []string          `json:"blackout,omitempty"`
+	Units       []string          `json:"units,omitempty"`       // Hand-edited scheduler definitions installed instead of generated ones
+	InBlackout  string            `json:"in_blackout,omitempty"` // The window runs are being skipped in, the service's or a global one
+	OnFailure   string            `json:"on_failure,omitempty"`
+	Scope       string            `json:"scope"` // Where the service is defined: user or system
+
+	Enabled   bool   `json:"enabled"`         // What the config says
+	Installed bool   `json:"installed"`       // Whether the scheduler has the service
+	State     string `json:"state,omitempty"` // What the scheduler says: Enabled or Disabled
+	Running   bool   `json:"running"`         // Whether the scheduler has a run going on
+
+	LastRun     *history.Record `json:"last_run,omitempty"`
+	LastStarted *time.Time      `json:"last_started,omitempty"` // When the scheduler last started the service
+	LastResult  string          `json:"last_result,omitempty"`  // How the scheduler says that run ended, in its own terms
+	RunState    *state.State    `json:"run_state"`              // What nazim keeps of the service's runs, see package state
+
+	Platform      string            `json:"platform"`
+	Scheduler     map[string]string `json:"scheduler,omitempty"`      // The fields status shows for the last run
+	PlatformRaw   map[string]string `json:"platform_raw"`             // Everything the scheduler reports
+	PlatformError string            `json:"platform_error,omitempty"` // Why the scheduler couldn't be queried
+}
+
+// StatusJSON prints the status of a service as JSON. Besides what Status shows,
+// it includes every property the scheduler reports for the service, such as the
+// verbose schtasks fields, `systemctl show` properties, or `launchctl print`
+// values, for tracking down differences between the config and the scheduler.
+func (c *CLI) StatusJSON(ctx context.Context, name string, verbose bool) error {
+	svc, err := c.cfg.GetService(name)
+	if err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	effective := svc.ForPlatform(runtime.GOOS)
+	report := &statusReport{
+		Name:        svc.Name,
+		Description: svc.Description,
+		Owner:       svc.Owner,
+		Labels:      svc.Labels,
+		Command:     effective.Command,
+		Args:        effective.Args,
+		Steps:       svc.Steps,
+		Group:       svc.ParallelGroup,
+		WorkDir:     effective.WorkDir,
+		Trigger:     describeTrigger(svc),
+		Blackout:    svc.Blackout,
+		Units:       svc.Units,
+		OnFailure:   svc.OnFailure,
+		Scope:       c.cfg.Scope(name),
+		Enabled:     svc.Enabled,
+		Platform:    runtime.GOOS,
+		PlatformRaw: map[string]string{},
+	}
+	if sched := svc.GetSchedule(); sched != nil {
+		report.NextRuns = sched.NextN(time.Now(), 3)
+		if sched.Location != nil {
+			report.Timezone = sched.Location.String()
+			for i, t := range report.NextRuns {
+				report.NextRuns[i] = t.In(sched.Location)
+			}
+		}
+	}
+
+	if window := svc.InBlackout(time.Now(), c.cfg.Blackout()); window != nil {
+		report.InBlackout = window.String()
+	}
+
+	report.Installed, _ = platformMgr.IsInstalled(name)
+	if report.Installed {
+		if state, err := platformMgr.GetTaskState(name); err == nil {
+			report.State = state.String()
+			report.Running = state.Running
+			report.LastResult = state.LastResult
+		}
+		if next, err := platformMgr.NextRun(svc); err == nil && !next.IsZero() {
+			report.NextRun = &next
+		}
+		if started, err := platformMgr.LastRun(name); err == nil && !started.IsZero() {
+			report.LastStarted = &started
+		}
+		if details, err := platformMgr.Details(name); err == nil {
+			report.Scheduler = details
+		}
+		raw, err := platformMgr.Raw(name)
+		if err != nil {
+			report.PlatformError = err.Error()
+		} else {
+			report.PlatformRaw = raw
+		}
+	}
+
+	report.LastRun, err = history.Last(c.cfg.GetHistoryDir(), name)
+	if err != nil && verbose {
+		c.warning("failed to read run history: %v", err)
+	}
+	report.RunState, err = state.Load(c.cfg.GetServiceStateDir(), name)
+	if err != nil && verbose {
+		c.warning("%v", err)
+	}
+
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/events"
+	"github.com/calilkhalil/nazim/internal/gitsync"
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/progress"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// syncedPaths are the entries of the config directory SyncConfig syncs: the
+// config file and the scripts of portable services.
+var syncedPaths = []string{"services.yaml", "scripts"}
+
+// SyncOptions holds the options of SyncConfig.
+type SyncOptions struct {
+	Repo          string // The repository; empty to use the one of the last sync
+	MachineBranch bool   // Push to machines/<hostname> instead of the shared branch
+	Prefer        string // gitsync.PreferLocal or PreferRemote settles conflicts
+}
+
+// SyncConfig syncs the config file and the scripts of portable services with
+// a Git repository (see package gitsync): changes made here are committed and
+// pushed, and changes pushed from other machines are pulled, after which the
+// services they added or changed are installed and the ones they removed are
+// uninstalled. With MachineBranch, this machine pushes to a branch of its own
+// and only pulls from the shared one.
+func (c *CLI) SyncConfig(ctx context.Context, opts SyncOptions, verbose bool) error {
+	if err := c.checkUnmanaged(); err != nil {
+		return err
+	}
+	if !c.cfg.InFile() {
+		return fmt.Errorf("sync-config syncs the services listed in %s, but this config keeps them in another store",
+			c.cfg.GetConfigPath())
+	}
+	if platform.RequiresElevation() {
+		return fmt.Errorf("sync-config installs the services it pulls; run it from an elevated (administrator) prompt")
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("getting host name: %w", err)
+	}
+	var branch string
+	if opts.MachineBranch {
+		branch = "machines/" + strings.ToLower(host)
+	}
+
+	before := make(map[string]*service.Service)
+	for _, svc := range c.cfg.UserServices() {
+		before[svc.Name] = svc
+	}
+
+	sp := c.startProgress("Syncing config", verbose)
+	res, err := gitsync.Sync(gitsync.Options{
+		Dir:        c.cfg.GetSyncDir(),
+		URL:        opts.Repo,
+		ConfigDir:  c.cfg.ConfigDir,
+		Paths:      syncedPaths,
+		Branch:     branch,
+		Prefer:     opts.Prefer,
+		Message:    "Sync services from " + host,
+		LocalEmpty: len(before) == 0,
+		Check: func(dir string) error {
+			return c.cfg.CheckFile(filepath.Join(dir, syncedPaths[0]), runtime.GOOS)
+		},
+	})
+	sp.Stop()
+	var conflict *gitsync.ConflictError
+	if errors.As(err, &conflict) {
+		return fmt.Errorf("%w; nothing was changed. Sync again with --prefer local to keep this machine's version, or --prefer remote to take the repository's", err)
+	}
+	if err != nil && res == nil {
+		return fmt.Errorf("syncing config: %w", err)
+	}
+	pushErr := err
+
+	if len(res.Pulled) > 0 {
+		if err := c.installPulled(before, verbose); err != nil {
+			return err
+		}
+	}
+	if pushErr != nil {
+		return fmt.Errorf("syncing config: %w", pushErr)
+	}
+	if res.Pushed {
+		c.infof("Pushed this machine's changes to %s.\n", res.Branch)
+	}
+	if len(res.Pulled) == 0 && !res.Pushed {
+		c.infoln("Config is already in sync.")
+	}
+	return nil
+}
+
+// installPulled reloads the config after a sync pulled changes into it, and
+// installs, reinstalls, or uninstalls services to match, comparing them with
+// the services before the sync.
+func (c *CLI) installPulled(before map[string]*service.Service, verbose bool) error {
+	if err := c.cfg.Load(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("loading synced config: %w", err)
+	}
+
+	var sp *progress.Spinner
+	platformMgr, err := c.newManager(platform.WithProgress(func(step string) {
+		if sp != nil {
+			sp.Step(step)
+		}
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+
+	services := c.cfg.UserServices()
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	var added, updated, removed int
+	var failed []string
+	for _, svc := range services {
+		old := before[svc.Name]
+		delete(before, svc.Name)
+		if err := svc.CheckPlatform(runtime.GOOS); err != nil {
+			if verbose {
+				c.infof("Skipping '%s': %v\n", svc.Name, err)
+			}
+			continue
+		}
+		if old != nil && sameDefinition(old, svc) {
+			continue
+		}
+
+		sp = c.startProgress(fmt.Sprintf("Installing '%s'", svc.Name), verbose)
+		if old != nil {
+			if err := platformMgr.Uninstall(svc.Name); err != nil && verbose {
+				c.warning("failed to uninstall '%s': %v", svc.Name, err)
+			}
+		}
+		err := platformMgr.Install(svc)
+		sp.Stop()
+		if err != nil {
+			c.warning("failed to install '%s': %v", svc.Name, err)
+			failed = append(failed, svc.Name)
+			continue
+		}
+		if !svc.Enabled {
+			if err := platformMgr.Disable(svc.Name); err != nil {
+				c.warning("failed to disable '%s': %v", svc.Name, err)
+			}
+		}
+		if old != nil {
+			updated++
+			c.serviceEvent(events.ServiceUpdated, svc.Name)
+		} else {
+			added++
+			c.serviceEvent(events.ServiceAdded, svc.Name)
+		}
+	}
+
+	// What remains was removed on another machine
+	for name := range before {
+		if err := platformMgr.Uninstall(name); err != nil {
+			c.warning("failed to uninstall '%s': %v", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		removed++
+		c.serviceEvent(events.ServiceRemoved, name)
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("the config was synced, but these services failed to install or uninstall: %s",
+			strings.Join(failed, ", "))
+	}
+	c.infof("Pulled changes: %d service(s) added, %d updated, %d removed.\n", added, updated, removed)
+	return nil
+}
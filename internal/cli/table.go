@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// minFlexWidth is the narrowest a flexible column is shrunk to.
+const minFlexWidth = 16
+
+// column describes one table column.
+type column struct {
+	header string
+	flex   bool                         // Shrinks to fit the width, cut in the middle
+	color  func(cell string) term.Color // Picks a cell's color; nil or "" for none
+}
+
+// table lays out rows in aligned columns separated by two spaces.
+type table struct {
+	columns  []column
+	rows     [][]string
+	headings map[int]string // Lines shown above the row with that index, across all columns
+}
+
+func (t *table) addRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// addHeading adds a line shown in bold above the next row, such as the name
+// of a group of rows. It doesn't affect the width of the columns.
+func (t *table) addHeading(heading string) {
+	if t.headings == nil {
+		t.headings = make(map[int]string)
+	}
+	t.headings[len(t.rows)] = heading
+}
+
+// renderTable writes t to the output. When width is positive and the table is
+// wider, flexible columns are shrunk to fit, but not below minFlexWidth.
+func (c *CLI) renderTable(t *table, width int) {
+	widths := make([]int, len(t.columns))
+	for i, col := range t.columns {
+		widths[i] = utf8.RuneCountInString(col.header)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	if width > 0 {
+		total := 2 * (len(widths) - 1)
+		for _, w := range widths {
+			total += w
+		}
+		for i, col := range t.columns {
+			if total <= width {
+				break
+			}
+			if !col.flex || widths[i] <= minFlexWidth {
+				continue
+			}
+			shrink := min(total-width, widths[i]-minFlexWidth)
+			widths[i] -= shrink
+			total -= shrink
+		}
+	}
+
+	var line strings.Builder
+	writeRow := func(cells []string, header bool) {
+		line.Reset()
+		for i, cell := range cells {
+			cell = truncateMiddle(cell, widths[i])
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i == len(cells)-1 {
+				padding = "" // No trailing spaces
+			}
+			switch {
+			case header:
+				cell = c.paint(term.Bold, cell)
+			case t.columns[i].color != nil:
+				if color := t.columns[i].color(cell); color != "" {
+					cell = c.paint(color, cell)
+				}
+			}
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(cell)
+			line.WriteString(padding)
+		}
+		fmt.Fprintln(c.out, line.String())
+	}
+
+	headers := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		headers[i] = col.header
+	}
+	writeRow(headers, true)
+	for i, row := range t.rows {
+		if heading, ok := t.headings[i]; ok {
+			fmt.Fprintln(c.out, c.paint(term.Bold, heading))
+		}
+		writeRow(row, false)
+	}
+}
+
+// truncateMiddle shortens s to n characters by replacing its middle with "...",
+// keeping both the start of a command and its last arguments visible.
+func truncateMiddle(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	head := (n - 3 + 1) / 2
+	tail := n - 3 - head
+	return string(runes[:head]) + "..." + string(runes[len(runes)-tail:])
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/config"
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/telemetry"
+	"github.com/calilkhalil/nazim/internal/upload"
+)
+
+// telemetryTimeout bounds how long reporting a run to the collector may take,
+// which a scheduled run waits for before it exits.
+const telemetryTimeout = 10 * time.Second
+
+// reportRun sends a finished run of svc to the collector of the config's
+// telemetry block, as a span and metrics.
+func (c *CLI) reportRun(ctx context.Context, settings *config.TelemetrySettings, svc *service.Service, rec *history.Record) error {
+	envFile := settings.EnvFile
+	if envFile != "" && !filepath.IsAbs(envFile) {
+		envFile = filepath.Join(c.cfg.ConfigDir, envFile)
+	}
+	getenv, err := upload.Getenv(envFile)
+	if err != nil {
+		return err
+	}
+	headers := make(map[string]string, len(settings.Headers))
+	for key, value := range settings.Headers {
+		headers[key] = os.Expand(value, getenv)
+	}
+	exporter, err := telemetry.New(settings.Endpoint, headers, c.version)
+	if err != nil {
+		return err
+	}
+
+	run := &telemetry.Run{
+		Service:    rec.Service,
+		ID:         rec.ID,
+		Command:    commandLine(svc),
+		Trigger:    describeTrigger(svc),
+		Start:      rec.Start,
+		End:        rec.End,
+		ExitCode:   rec.ExitCode,
+		Outcome:    telemetry.OutcomeSuccess,
+		Slow:       rec.Slow,
+		Attributes: make(map[string]string),
+	}
+	switch {
+	case rec.Error != "":
+		run.Outcome = telemetry.OutcomeStartFailure
+		run.Error = rec.Error
+	case rec.OutputError != "":
+		run.Outcome = telemetry.OutcomeFailure
+		run.Error = rec.OutputError
+	case rec.Failed():
+		run.Outcome = telemetry.OutcomeFailure
+		run.Error = fmt.Sprintf("exit code %d", rec.ExitCode)
+	}
+	if svc.Owner != "" {
+		run.Attributes["nazim.service.owner"] = svc.Owner
+	}
+	for key, value := range svc.Labels {
+		run.Attributes["nazim.service.label."+key] = value
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, telemetryTimeout)
+	defer cancel()
+	return exporter.Export(ctx, run)
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/runner"
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// testServiceName names a service given only by flags when no name is set.
+const testServiceName = "test"
+
+// Test runs a service once in the foreground without installing it, and returns
+// the command's exit code. The service is either a configured one, looked up by
+// name, or described by add flags when a command is given.
+//
+// It prints the scheduler definitions Install would generate, then runs the
+// command through the same runner scheduled runs use. Output is shown as it
+// arrives rather than logged, and no run history is recorded.
+func (c *CLI) Test(ctx context.Context, name string, flags *Flags, verbose bool) (int, error) {
+	svc, err := c.testService(name, flags)
+	if err != nil {
+		return 1, err
+	}
+
+	c.printDefinitions(svc)
+
+	logsDir, err := os.MkdirTemp("", "nazim-test-")
+	if err != nil {
+		return 1, fmt.Errorf("failed to create temporary logs directory: %w", err)
+	}
+	defer os.RemoveAll(logsDir)
+
+	resolved := svc.ResolvePaths(c.cfg.ConfigDir)
+	fmt.Fprintf(c.out, "%s %s\n", c.paint(term.Bold, "Running:"), commandLine(resolved))
+	if resolved.WorkDir != "" {
+		fmt.Fprintf(c.out, "%s %s\n", c.paint(term.Bold, "Working dir:"), resolved.WorkDir)
+	}
+	fmt.Fprintln(c.out)
+
+	if timeout := svc.Timeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, timeoutError(timeout))
+		defer cancel()
+	}
+	result, err := runner.Run(ctx, resolved, runner.Options{LogsDir: logsDir, Stdout: c.out, Stderr: c.errOut})
+	if err != nil {
+		return 1, err
+	}
+
+	fmt.Fprintln(c.out)
+	if result.Error != "" {
+		fmt.Fprintf(c.errOut, "%s\n", c.paint(term.Red, result.Error))
+		return result.ExitCode, nil
+	}
+	status := c.paint(term.Green, fmt.Sprintf("Exit code %d", result.ExitCode))
+	if result.ExitCode != 0 {
+		status = c.paint(term.Red, fmt.Sprintf("Exit code %d", result.ExitCode))
+	}
+	fmt.Fprintf(c.out, "%s after %s\n", status, roundDuration(result.Duration()))
+	if result.OutputError != "" {
+		fmt.Fprintf(c.errOut, "%s\n", c.paint(term.Red, "Run failed: "+result.OutputError))
+	}
+	if threshold := svc.SlowThreshold.Duration; threshold > 0 && result.Duration() > threshold {
+		c.warning("run exceeded the slow threshold of %s", threshold)
+	}
+
+	if result.OutputError != "" {
+		return 1, nil
+	}
+	return result.ExitCode, nil
+}
+
+// testService returns the service Test runs.
+func (c *CLI) testService(name string, flags *Flags) (*service.Service, error) {
+	if flags.Command == "" && (flags.Steps != "" || flags.ParallelGroup != "") {
+		return nil, fmt.Errorf("a pipeline or parallel group runs other services; test them one by one")
+	}
+	if flags.Command == "" {
+		if name == "" {
+			return nil, fmt.Errorf("service name or --command is required")
+		}
+		svc, err := c.cfg.GetService(name)
+		if err != nil {
+			return nil, fmt.Errorf("service '%s' does not exist", name)
+		}
+		if svc.RunsServices() {
+			// Its members would run for real, recording history and all
+			return nil, fmt.Errorf("'%s' runs other services; test them one by one, or run it with nazim run %s", name, name)
+		}
+		return svc, nil
+	}
+
+	if flags.Command == "write" || flags.Command == "edit" {
+		return nil, fmt.Errorf("interactive script creation is not supported by test; pass a command")
+	}
+	if flags.Name == "" {
+		flags.Name = name
+	}
+	if flags.Name == "" {
+		flags.Name = testServiceName
+	}
+
+	svc, err := serviceFromFlags(flags, flags.Command)
+	if err != nil {
+		return nil, err
+	}
+	c.cfg.ApplyDefaults(svc)
+	if err := c.resolvePaths(svc); err != nil {
+		return nil, err
+	}
+
+	// A trigger only matters once installed, so validate as if one were given
+	check := *svc
+	if !hasTrigger(svc) {
+		check.OnStartup = true
+	}
+	if err := check.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !flags.NoVerify {
+		if err := c.verifyCommand(svc); err != nil {
+			return nil, err
+		}
+	}
+	return svc, nil
+}
+
+// printDefinitions prints the scheduler configuration Install would generate.
+// Problems are reported as notes: they shouldn't keep the command from running.
+func (c *CLI) printDefinitions(svc *service.Service) {
+	if !hasTrigger(svc) {
+		fmt.Fprintf(c.out, "No trigger given, so there are no scheduler definitions to show.\n\n")
+		return
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Note: cannot show scheduler definitions: %v\n\n", err)
+		return
+	}
+	defs, err := platformMgr.Preview(svc)
+	if err != nil {
+		fmt.Fprintf(c.errOut, "Note: cannot show scheduler definitions: %v\n\n", err)
+		return
+	}
+
+	for _, def := range defs {
+		fmt.Fprintln(c.out, c.paint(term.Cyan, "--- "+def.Name+" ---"))
+		fmt.Fprint(c.out, def.Content)
+		if !strings.HasSuffix(def.Content, "\n") {
+			fmt.Fprintln(c.out)
+		}
+		fmt.Fprintln(c.out)
+	}
+}
+
+// hasTrigger reports whether a service has any trigger set.
+func hasTrigger(svc *service.Service) bool {
+	return svc.HasOtherTrigger() || len(svc.WatchPaths) > 0
+}
+
+// commandLine formats a service command for display.
+func commandLine(svc *service.Service) string {
+	if svc.IsPipeline() {
+		return "pipeline: " + svc.DescribeSteps()
+	}
+	if svc.IsGroup() {
+		return "parallel: " + strings.Join(svc.ParallelGroup, ", ")
+	}
+	if len(svc.Args) == 0 {
+		return svc.Command
+	}
+	return svc.Command + " " + service.JoinArgs(svc.Args)
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/platform"
+	"github.com/calilkhalil/nazim/internal/schedule"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// describeLocalSchedule says, for status, at what local times the scheduler
+// runs a schedule with a time zone when it has no time zones of its own (see
+// platform.ZonedSchedules), and "" when it has.
+func describeLocalSchedule(svc *service.Service, sched *schedule.Schedule) string {
+	if platform.ZonedSchedules(svc) {
+		return ""
+	}
+	local, stable := sched.In(time.Local, time.Now())
+	if stable {
+		return fmt.Sprintf(" (scheduled as %s local time)", local)
+	}
+	return fmt.Sprintf(" (scheduled as %s local time until daylight saving time changes, see 'nazim edit %s')", local, svc.Name)
+}
+
+// warnTimezone warns, once a service is installed, when its scheduler has no
+// time zones and the local times its schedule was converted to will drift
+// from the schedule's time zone when daylight saving time starts or ends.
+func (c *CLI) warnTimezone(svc *service.Service) {
+	sched := svc.GetSchedule()
+	if sched == nil || sched.Location == nil || platform.ZonedSchedules(svc) {
+		return
+	}
+	if local, stable := sched.In(time.Local, time.Now()); !stable {
+		c.warning("the scheduler has no time zones, so '%s' is scheduled as %s local time; "+
+			"once daylight saving time changes the difference from %s, run 'nazim edit %s' to schedule it again",
+			svc.Name, local, sched.Location, svc.Name)
+	}
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// grouping returns the function giving the groups list --tree shows a service
+// under, for a --group-by value, and the name of the group of services that
+// have none:
+//
+//   - type: what starts it, e.g. "Interval" or "Startup + Schedule"
+//   - namespace: its name up to the first '.', '-', or '_', e.g. "backup" for "backup.db"
+//   - label: each of its labels, as key=value
+//   - label:<key>: its value of that label, as key=value
+func grouping(groupBy string) (groupsOf func(svc *service.Service) []string, none string, err error) {
+	switch groupBy {
+	case "", "type":
+		return func(svc *service.Service) []string {
+			if kind := triggerKind(svc); kind != "" {
+				return []string{kind}
+			}
+			return nil
+		}, "no trigger", nil
+	case "namespace":
+		return func(svc *service.Service) []string {
+			if i := strings.IndexAny(svc.Name, ".-_"); i > 0 {
+				return []string{svc.Name[:i]}
+			}
+			return nil
+		}, "no namespace", nil
+	case "label":
+		return func(svc *service.Service) []string {
+			return svc.LabelPairs()
+		}, "no labels", nil
+	}
+
+	key, ok := strings.CutPrefix(groupBy, "label:")
+	if !ok || key == "" {
+		return nil, "", fmt.Errorf("invalid --group-by '%s' (use type, namespace, label, or label:<key>)", groupBy)
+	}
+	return func(svc *service.Service) []string {
+		if value, ok := svc.Labels[key]; ok {
+			return []string{key + "=" + value}
+		}
+		return nil
+	}, "no " + key, nil
+}
+
+// triggerKind names what starts a service, without the details
+// describeTrigger gives, e.g. "Startup + Interval". It's empty for none.
+func triggerKind(svc *service.Service) string {
+	var kinds []string
+	if svc.OnStartup {
+		kinds = append(kinds, "Startup")
+	}
+	if svc.OnLogon {
+		kinds = append(kinds, "Logon")
+	}
+	if svc.GetInterval() > 0 {
+		kinds = append(kinds, "Interval")
+	}
+	if svc.Schedule != "" {
+		kinds = append(kinds, "Schedule")
+	}
+	if len(svc.WatchPaths) > 0 {
+		kinds = append(kinds, "Watch")
+	}
+	if svc.OnEvent != "" {
+		kinds = append(kinds, "Event")
+	}
+	if svc.LongRunning {
+		kinds = append(kinds, "Long-running")
+	}
+	return strings.Join(kinds, " + ")
+}
+
+// collapseCommand shortens a command for list --tree to the name of the
+// program or script it runs, followed by "..." if it takes arguments.
+func collapseCommand(svc *service.Service) string {
+	if svc.RunsServices() {
+		return commandLine(svc)
+	}
+	fields := strings.Fields(svc.Command)
+	if len(fields) == 0 {
+		return "-"
+	}
+	name := fields[0]
+	if svc.CommandIsPath() || len(fields) == 1 {
+		// A path may contain spaces; only its last element matters here
+		name, fields = svc.Command, fields[:1]
+	}
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if len(fields) > 1 || len(svc.Args) > 0 {
+		name += " ..."
+	}
+	return name
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/deprecation"
+	"github.com/calilkhalil/nazim/internal/term"
+	"github.com/calilkhalil/nazim/internal/update"
+)
+
+// updateTimeout bounds how long asking for the latest release may take, so a
+// machine without internet access doesn't hang.
+const updateTimeout = 5 * time.Second
+
+// upgradeCommand installs the latest release from source.
+const upgradeCommand = "go install github.com/calilkhalil/nazim/cmd/nazim@latest"
+
+// CheckForUpdate asks GitHub for the latest release of nazim and says whether
+// it is newer than this one, and how to upgrade if so.
+func (c *CLI) CheckForUpdate(ctx context.Context) error {
+	if !c.updateCheck {
+		return fmt.Errorf("update checks are turned off by --no-update-check or NAZIM_NO_UPDATE_CHECK")
+	}
+	release, err := c.latestRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	if release == nil {
+		fmt.Fprintln(c.out, "No release of nazim has been published yet.")
+		return nil
+	}
+	newer, ok := update.Newer(release.Version, c.version)
+	switch {
+	case !ok:
+		fmt.Fprintf(c.out, "This is a development build (%s); the latest release is %s: %s\n", c.version, release.Version, release.URL)
+	case newer:
+		c.printUpgrade(release)
+	default:
+		fmt.Fprintf(c.out, "nazim is up to date (latest release: %s).\n", release.Version)
+	}
+	return nil
+}
+
+// latestRelease returns the latest release, giving up after updateTimeout.
+func (c *CLI) latestRelease(ctx context.Context) (*update.Release, error) {
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+	return update.Latest(ctx)
+}
+
+// printUpgrade says a newer release is out and how to get it.
+func (c *CLI) printUpgrade(release *update.Release) {
+	fmt.Fprintf(c.out, "nazim %s is available (you have %s): %s\n", c.paint(term.Green, release.Version), c.version, release.URL)
+	fmt.Fprintf(c.out, "Upgrade by downloading it from there, or with: %s\n", upgradeCommand)
+}
+
+// doctorUpdate tells, for doctor, whether a newer release is out. Failing to
+// find out isn't a problem with the services, so it is only reported with
+// verbose, and development builds aren't compared at all.
+func (c *CLI) doctorUpdate(ctx context.Context, verbose bool) {
+	if !c.updateCheck || !update.IsRelease(c.version) {
+		return
+	}
+	release, err := c.latestRelease(ctx)
+	if err != nil {
+		if verbose {
+			c.infof("Couldn't check for updates: %v\n", err)
+		}
+		return
+	}
+	if release == nil {
+		return
+	}
+	if newer, _ := update.Newer(release.Version, c.version); newer {
+		fmt.Fprintln(c.out)
+		c.printUpgrade(release)
+	}
+}
+
+// WarnDeprecated warns about each deprecated flag among the flags given and
+// each deprecated field the config's services set, saying what replaces it.
+// In strict mode, the first one is returned as an error instead.
+func (c *CLI) WarnDeprecated(flags []string) error {
+	for _, name := range deprecation.UsedFlags(flags) {
+		if err := c.warn("--%s is deprecated and will be removed in a future release; use %s instead",
+			name, deprecation.Flags[name]); err != nil {
+			return err
+		}
+	}
+
+	usedBy := make(map[string][]string)
+	for _, svc := range c.cfg.ListServices() {
+		for _, field := range deprecation.UsedFields(svc) {
+			usedBy[field] = append(usedBy[field], svc.Name)
+		}
+	}
+	fields := make([]string, 0, len(usedBy))
+	for field := range usedBy {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		services := usedBy[field]
+		sort.Strings(services)
+		if err := c.warn("%s (used by %s) is deprecated and will be removed in a future release; use %s instead",
+			field, strings.Join(services, ", "), deprecation.Fields[field]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
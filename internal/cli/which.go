@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/runner"
+	"github.com/calilkhalil/nazim/internal/state"
+	"github.com/calilkhalil/nazim/internal/term"
+	"github.com/calilkhalil/nazim/internal/watch"
+)
+
+// Which prints where everything belonging to a service lives: its config entry,
+// script, scheduler definitions, logs, and run history. Files that don't exist
+// (yet) are marked, since a service that never ran has no logs.
+func (c *CLI) Which(ctx context.Context, name string, verbose bool) error {
+	svc, err := c.cfg.GetService(name)
+	if err != nil {
+		return fmt.Errorf("service '%s' does not exist", name)
+	}
+
+	platformMgr, err := c.newManager()
+	if err != nil {
+		return fmt.Errorf("failed to create platform manager: %w", err)
+	}
+	artifacts, err := platformMgr.Artifacts(svc)
+	if err != nil {
+		return err
+	}
+
+	type entry struct{ label, value string }
+	entries := []entry{{"Config", c.cfg.ServiceConfigPath(name)}}
+	if resolved := svc.ResolvePaths(c.cfg.ConfigDir); !resolved.IsOneLiner() {
+		if info, err := os.Stat(resolved.Command); err == nil && !info.IsDir() {
+			entries = append(entries, entry{"Script", c.describeFile(resolved.Command)})
+		}
+	}
+	for _, path := range svc.Units {
+		entries = append(entries, entry{"Hand-edited unit", c.describeFile(path)})
+	}
+	for _, artifact := range artifacts {
+		value := artifact.Name
+		switch {
+		case artifact.Path != "" && value != "":
+			value += " at " + c.describeFile(artifact.Path)
+		case artifact.Path != "":
+			value = c.describeFile(artifact.Path)
+		}
+		entries = append(entries, entry{artifact.Kind, value})
+	}
+	logsDir := c.cfg.GetLogsDir()
+	entries = append(entries,
+		entry{"Stdout log", c.describeFile(runner.LogPath(logsDir, name, runner.StreamStdout))},
+		entry{"Stderr log", c.describeFile(runner.LogPath(logsDir, name, runner.StreamStderr))},
+		entry{"History", c.describeFile(history.Path(c.cfg.GetHistoryDir(), name))},
+		entry{"State", c.describeFile(state.Path(c.cfg.GetServiceStateDir(), name))},
+	)
+	if len(svc.IfChanged) > 0 {
+		entries = append(entries, entry{"Snapshot", c.describeFile(watch.Path(c.cfg.GetSnapshotsDir(), name))})
+	}
+
+	width := 0
+	for _, e := range entries {
+		width = max(width, len(e.label))
+	}
+	for _, e := range entries {
+		fmt.Fprintf(c.out, "%-*s  %s\n", width+1, e.label+":", e.value)
+	}
+	return nil
+}
+
+// describeFile returns path, marked when the file doesn't exist.
+func (c *CLI) describeFile(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return path + " " + c.paint(term.Yellow, "(not found)")
+	}
+	return path
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// A bundle is a file of services in the config file format, distributed by an
+// administrator with a detached Ed25519 signature next to it, in <bundle>.sig.
+// `nazim apply` installs it and keeps a copy in the config directory, which
+// nazim exec checks services against in managed mode.
+
+// ErrUnsigned is returned by AppliedBundle when no signed bundle was applied.
+var ErrUnsigned = errors.New("no signed bundle has been applied")
+
+// ParseBundle returns the services of a bundle, with its defaults block applied.
+func ParseBundle(data []byte) ([]*service.Service, error) {
+	f, _, err := decodeFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing bundle: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, svc := range f.Services {
+		if seen[svc.Name] {
+			return nil, fmt.Errorf("parsing bundle: service %s is defined twice", svc.Name)
+		}
+		seen[svc.Name] = true
+		f.Defaults.Apply(svc)
+	}
+	return f.Services, nil
+}
+
+// Managed reports whether the policy only lets services change through signed bundles.
+func (c *Config) Managed() bool {
+	return c.policy != nil && c.policy.ManagedMode
+}
+
+// VerifySignature checks sig, raw or base64, against data and the policy's trusted keys.
+func (c *Config) VerifySignature(data, sig []byte) error {
+	if c.policy == nil || len(c.policy.keys) == 0 {
+		return fmt.Errorf("no trusted_keys in %s to verify the signature with", PolicyPath())
+	}
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return fmt.Errorf("signature is not an Ed25519 signature, raw or in base64")
+		}
+		sig = decoded
+	}
+	for _, key := range c.policy.keys {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature doesn't match any of the trusted_keys in %s", PolicyPath())
+}
+
+// BundlePath returns where the last applied bundle is kept; its signature is
+// kept next to it with a .sig extension.
+func (c *Config) BundlePath() string {
+	return filepath.Join(c.ConfigDir, "bundle.yaml")
+}
+
+// AppliedBundle returns the services of the last applied bundle after
+// verifying its signature again, since the copy could have been changed since.
+func (c *Config) AppliedBundle() ([]*service.Service, error) {
+	data, err := os.ReadFile(c.BundlePath())
+	if os.IsNotExist(err) {
+		return nil, ErrUnsigned
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading applied bundle: %w", err)
+	}
+	sig, err := os.ReadFile(c.BundlePath() + ".sig")
+	if os.IsNotExist(err) {
+		return nil, ErrUnsigned
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading applied bundle signature: %w", err)
+	}
+	if err := c.VerifySignature(data, sig); err != nil {
+		return nil, fmt.Errorf("applied bundle %s: %w", c.BundlePath(), err)
+	}
+	return ParseBundle(data)
+}
+
+// parsePublicKey parses an Ed25519 public key given as a PEM block, as
+// `openssl pkey -pubout` prints it, as the base64 of that block's contents, or
+// as the base64 of the 32-byte key.
+func parsePublicKey(s string) (ed25519.PublicKey, error) {
+	der := []byte(s)
+	if block, _ := pem.Decode([]byte(strings.TrimSpace(s))); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+		if err != nil {
+			return nil, fmt.Errorf("key %q is neither PEM nor base64", abbreviate(s))
+		}
+		if len(decoded) == ed25519.PublicKeySize {
+			return ed25519.PublicKey(decoded), nil
+		}
+		der = decoded
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", abbreviate(s), err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key %q is not an Ed25519 key", abbreviate(s))
+	}
+	return edKey, nil
+}
+
+// abbreviate shortens a key for error messages.
+func abbreviate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 24 {
+		return s[:24] + "..."
+	}
+	return s
+}
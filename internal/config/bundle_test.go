@@ -0,0 +1,133 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testBundle = `defaults:
+  workdir: /srv/jobs
+services:
+  - name: report
+    command: /opt/report.sh
+    schedule: daily 02:00
+  - name: sync
+    command: /opt/sync.sh
+    interval: 1h
+    workdir: /srv/sync
+`
+
+func TestParseBundle(t *testing.T) {
+	services, err := ParseBundle([]byte(testBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("ParseBundle() returned %d services, want 2", len(services))
+	}
+	if services[0].WorkDir != "/srv/jobs" || services[1].WorkDir != "/srv/sync" {
+		t.Errorf("workdirs = %q, %q, want the default only where none is set", services[0].WorkDir, services[1].WorkDir)
+	}
+
+	twice := "services:\n  - name: a\n    command: /bin/true\n  - name: a\n    command: /bin/false\n"
+	if _, err := ParseBundle([]byte(twice)); err == nil || !strings.Contains(err.Error(), "defined twice") {
+		t.Errorf("ParseBundle() of a service defined twice error = %v", err)
+	}
+}
+
+func TestAppliedBundle(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(testBundle)
+	sig := ed25519.Sign(priv, data)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		sig     []byte
+		wantErr string
+	}{
+		{"raw signature", data, sig, ""},
+		{"base64 signature", data, []byte(base64.StdEncoding.EncodeToString(sig) + "\n"), ""},
+		{"altered bundle", []byte(strings.Replace(testBundle, "report.sh", "evil.sh", 1)), sig, "doesn't match"},
+		{"other key", data, ed25519.Sign(otherPriv, data), "doesn't match"},
+		{"not a signature", data, []byte("signed"), "not an Ed25519 signature"},
+		{"unsigned", data, nil, ErrUnsigned.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ConfigDir: t.TempDir(), policy: &Policy{keys: []ed25519.PublicKey{pub}}}
+			if err := os.WriteFile(cfg.BundlePath(), tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.sig != nil {
+				if err := os.WriteFile(cfg.BundlePath()+".sig", tt.sig, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			services, err := cfg.AppliedBundle()
+			if tt.wantErr == "" {
+				if err != nil || len(services) != 2 {
+					t.Fatalf("AppliedBundle() = %d services, %v, want 2", len(services), err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("AppliedBundle() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := &Config{ConfigDir: t.TempDir()}
+	if _, err := cfg.AppliedBundle(); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("AppliedBundle() without a bundle error = %v, want ErrUnsigned", err)
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"PEM", pemKey, false},
+		{"base64 of the PEM contents", base64.StdEncoding.EncodeToString(der), false},
+		{"base64 of the key", base64.StdEncoding.EncodeToString(pub), false},
+		{"not base64", "not a key!", true},
+		{"too short", base64.StdEncoding.EncodeToString(pub[:16]), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePublicKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePublicKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !got.Equal(pub) {
+				t.Errorf("parsePublicKey() = %x, want %x", got, pub)
+			}
+		})
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 
-	"gopkg.in/yaml.v3"
+	"github.com/calilkhalil/nazim/internal/history"
+	"github.com/calilkhalil/nazim/internal/seal"
 	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/telemetry"
+	"github.com/calilkhalil/nazim/internal/upload"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -19,16 +24,121 @@ const (
 // Config holds application configuration.
 type Config struct {
 	ConfigDir  string
+	DataDir    string // Scripts, see Dirs
+	StateDir   string // Logs and run history, see Dirs
 	ConfigFile string
 	services   map[string]*service.Service
+	store      Store // Where services are saved, see Store
+	defaults   *service.Defaults
+	history    *HistorySettings
+	upload     *UploadSettings
+	telemetry  *TelemetrySettings
+	stale      *StaleSettings
+	blackout   []string
+	templates  []*Template
+	policy     *Policy // System-wide limits, see PolicyPath
+
+	// system holds the services defined for every user, which are merged into
+	// services and take precedence over user services of the same name; those
+	// stay in the store untouched. See SystemConfigPath.
+	system *Config
+}
+
+// file is the layout of a config file with a defaults, history, upload,
+// telemetry, stale, blackout, templates, or store block:
+//
+//	store: sqlite
+//	defaults:
+//	  workdir: /srv/jobs
+//	history:
+//	  retention:
+//	    max_runs: 500
+//	upload:
+//	  url: s3://audit-logs/nazim
+//	telemetry:
+//	  endpoint: http://localhost:4318
+//	stale:
+//	  grace: 3
+//	blackout:
+//	  - 2024-12-24..2024-12-26
+//	templates:
+//	  - name: backup-tpl
+//	    service:
+//	      ...
+//	services:
+//	  - name: backup
+//	    ...
+//
+// A file holding just the list of services is also accepted.
+type file struct {
+	Defaults  *service.Defaults  `yaml:"defaults,omitempty"`
+	History   *HistorySettings   `yaml:"history,omitempty"`
+	Upload    *UploadSettings    `yaml:"upload,omitempty"`
+	Telemetry *TelemetrySettings `yaml:"telemetry,omitempty"`
+	Stale     *StaleSettings     `yaml:"stale,omitempty"`
+	Blackout  []string           `yaml:"blackout,omitempty"`  // Windows the runs of every service are skipped in
+	Templates []*Template        `yaml:"templates,omitempty"` // See Template
+	Store     string             `yaml:"store,omitempty"`     // StoreYAML or StoreSQLite
+	Services  []*service.Service `yaml:"services"`
+}
+
+// HistorySettings is the history block of a config file.
+type HistorySettings struct {
+	Retention struct {
+		MaxRuns int              `yaml:"max_runs,omitempty"` // Runs kept per service
+		MaxAge  service.Duration `yaml:"max_age,omitempty"`  // Runs older than this are dropped
+	} `yaml:"retention"`
+}
+
+// UploadSettings is the upload block of a config file.
+type UploadSettings struct {
+	URL string `yaml:"url"` // Where the output of every run is copied, see package upload
+	// EnvFile holds KEY=VALUE lines with the credentials for URL, for
+	// scheduled runs that don't see the shell's environment. A relative
+	// path is taken from the config directory.
+	EnvFile string `yaml:"env_file,omitempty"`
+}
+
+// TelemetrySettings is the telemetry block of a config file.
+type TelemetrySettings struct {
+	Endpoint string `yaml:"endpoint"` // OTLP/HTTP collector runs are reported to, see package telemetry
+	// Headers are sent with every request, such as the API key of a hosted
+	// backend. $VAR and ${VAR} in their values are replaced with variables
+	// of the environment or of EnvFile.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// EnvFile holds KEY=VALUE lines for the variables of Headers, as the
+	// upload block's does.
+	EnvFile string `yaml:"env_file,omitempty"`
 }
 
+// StaleSettings is the stale block of a config file.
+type StaleSettings struct {
+	Grace float64 `yaml:"grace"` // How many of its gaps between runs a service can miss before it's stale
+}
+
+// DefaultStaleGrace is the stale grace without a stale block.
+const DefaultStaleGrace = 2
+
 // New creates a Config with XDG-compliant paths.
 func New() (*Config, error) {
-	configDir := xdgPath("XDG_CONFIG_HOME", getDefaultConfigDir())
+	return NewIn(DefaultDirs())
+}
 
+// NewAt creates a Config rooted at an explicit directory, which holds its
+// scripts, logs, and history too unless it is the default one (see DirsAt).
+func NewAt(dir string) (*Config, error) {
+	return NewIn(DirsAt(dir))
+}
+
+// NewIn creates a Config keeping its files in dirs, and moves files older
+// versions kept in the config directory (see Migrate).
+// Scheduled runs are given every directory, as they may not share the installing
+// user's environment.
+func NewIn(dirs Dirs) (*Config, error) {
 	cfg := &Config{
-		ConfigDir: filepath.Join(configDir, AppName),
+		ConfigDir: dirs.Config,
+		DataDir:   dirs.Data,
+		StateDir:  dirs.State,
 		services:  make(map[string]*service.Service),
 	}
 
@@ -39,11 +149,27 @@ func New() (*Config, error) {
 		return nil, fmt.Errorf("creating config dir: %w", err)
 	}
 
+	system, err := loadSystem(SystemConfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("loading system config: %w", err)
+	}
+	cfg.system = system
+
 	// Load existing services
 	if err := cfg.Load(); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("loading config: %w", err)
 	}
 
+	policy, err := loadPolicy(PolicyPath())
+	if err != nil {
+		return nil, fmt.Errorf("loading policy: %w", err)
+	}
+	cfg.policy = policy
+
+	if err := cfg.Migrate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -105,48 +231,289 @@ func getDefaultConfigDir() string {
 	}
 }
 
-// Load loads services from the configuration file.
+// Load loads services from the configuration file, or the store it selects,
+// along with those of the system config.
 func (c *Config) Load() error {
+	c.services = make(map[string]*service.Service)
+	c.store = &yamlStore{path: c.ConfigFile}
+	if c.system != nil {
+		for name, svc := range c.system.services {
+			c.services[name] = svc
+		}
+	}
+
 	data, err := os.ReadFile(c.ConfigFile)
 	if err != nil {
 		return err
 	}
+	var key *seal.Key
+	if seal.IsSealed(data) {
+		if data, key, err = seal.Open(data); err != nil {
+			return fmt.Errorf("decrypting config: %w", err)
+		}
+	}
 
-	var services []*service.Service
-	if err := yaml.Unmarshal(data, &services); err != nil {
+	f, structured, err := decodeFile(data)
+	if err != nil {
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
-	c.services = make(map[string]*service.Service)
+	if err := checkBlocks(f); err != nil {
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	store, err := openStore(c.ConfigFile, f, structured, key)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	services, err := store.List()
+	if err != nil {
+		return fmt.Errorf("reading services: %w", err)
+	}
+
+	c.store = store
+	c.defaults = f.Defaults
+	c.history = f.History
+	c.upload = f.Upload
+	c.telemetry = f.Telemetry
+	c.stale = f.Stale
+	c.blackout = f.Blackout
+	c.templates = f.Templates
 	for _, svc := range services {
+		if c.Scope(svc.Name) == ScopeSystem {
+			continue
+		}
+		c.defaults.Apply(svc)
 		c.services[svc.Name] = svc
 	}
 
 	return nil
 }
 
-// Save saves services to the configuration file.
-func (c *Config) Save() error {
-	services := make([]*service.Service, 0, len(c.services))
-	for _, svc := range c.services {
-		services = append(services, svc)
+// checkBlocks checks the blocks of a config file other than its services.
+func checkBlocks(f file) error {
+	if f.History != nil && (f.History.Retention.MaxRuns < 0 || f.History.Retention.MaxAge.Duration < 0) {
+		return fmt.Errorf("history retention cannot be negative")
 	}
 
-	data, err := yaml.Marshal(services)
+	if f.Upload != nil {
+		if f.Upload.URL == "" {
+			return fmt.Errorf("upload block has no url")
+		}
+		if err := upload.CheckURL(f.Upload.URL); err != nil {
+			return err
+		}
+	}
+
+	if f.Telemetry != nil {
+		if f.Telemetry.Endpoint == "" {
+			return fmt.Errorf("telemetry block has no endpoint")
+		}
+		if err := telemetry.CheckEndpoint(f.Telemetry.Endpoint); err != nil {
+			return err
+		}
+	}
+
+	if f.Stale != nil && f.Stale.Grace < 1 {
+		return fmt.Errorf("stale grace must be at least 1")
+	}
+
+	if _, err := service.ParseBlackouts(f.Blackout); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for _, t := range f.Templates {
+		if err := t.check(); err != nil {
+			return err
+		}
+		if names[t.Name] {
+			return fmt.Errorf("template '%s' is defined more than once", t.Name)
+		}
+		names[t.Name] = true
+	}
+	return nil
+}
+
+// CheckFile checks a config file before it replaces the config's own, as
+// Load would read it: its blocks, and that the services meant for goos are
+// valid and allowed by the policy. A missing file has no services to check.
+func (c *Config) CheckFile(path, goos string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if seal.IsSealed(data) {
+		if data, _, err = seal.Open(data); err != nil {
+			return fmt.Errorf("decrypting %s: %w", path, err)
+		}
+	}
+	f, _, err := decodeFile(data)
 	if err != nil {
-		return fmt.Errorf("marshaling config: %w", err)
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := checkBlocks(f); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for _, svc := range f.Services {
+		if seen[svc.Name] {
+			return fmt.Errorf("service %s is defined twice", svc.Name)
+		}
+		seen[svc.Name] = true
+		f.Defaults.Apply(svc)
+		if svc.CheckPlatform(goos) != nil {
+			continue
+		}
+		if err := c.ValidateService(svc); err != nil {
+			return fmt.Errorf("service '%s' is invalid: %w", svc.Name, err)
+		}
 	}
+	return nil
+}
 
-	if err := os.WriteFile(c.ConfigFile, data, 0644); err != nil {
-		return fmt.Errorf("writing config: %w", err)
+// decodeFile decodes a config file, reporting whether it holds a mapping of
+// blocks rather than a bare list of services.
+func decodeFile(data []byte) (f file, structured bool, err error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return f, false, err
+	}
+
+	structured = len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode
+	switch {
+	case doc.Kind == 0:
+		// Empty file
+	case structured:
+		err = doc.Decode(&f)
+	default:
+		err = doc.Decode(&f.Services)
 	}
+	return f, structured, err
+}
 
+// Save saves all of the user's services to the store.
+func (c *Config) Save() error {
+	return c.store.Tx(func(tx Store) error {
+		for _, svc := range c.UserServices() {
+			if err := tx.Put(c.defaults.Strip(svc)); err != nil {
+				return fmt.Errorf("saving service %s: %w", svc.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Tx runs fn, which may change any number of services, saving those changes
+// together once it returns: the YAML file is rewritten once rather than for
+// every change, and the SQLite store commits them in one transaction. If fn
+// returns an error, none of its changes are saved and the config is loaded
+// again.
+func (c *Config) Tx(fn func() error) error {
+	store := c.store
+	err := store.Tx(func(tx Store) error {
+		c.store = tx
+		defer func() { c.store = store }()
+		return fn()
+	})
+	if err != nil {
+		if loadErr := c.Load(); loadErr != nil && !os.IsNotExist(loadErr) {
+			return fmt.Errorf("%w (also failed to reload config: %v)", err, loadErr)
+		}
+	}
+	return err
+}
+
+// put saves a service to the store of the user's config.
+func (c *Config) put(svc *service.Service) error {
+	if err := c.store.Put(c.defaults.Strip(svc)); err != nil {
+		return fmt.Errorf("saving service %s: %w", svc.Name, err)
+	}
 	return nil
 }
 
+// ApplyDefaults fills the settings svc leaves unset from the config's defaults block,
+// as Load does for the services in the file.
+func (c *Config) ApplyDefaults(svc *service.Service) {
+	c.defaults.Apply(svc)
+}
+
+// Defaults returns the config's defaults block, or nil if it has none.
+func (c *Config) Defaults() *service.Defaults {
+	return c.defaults
+}
+
+// Retention returns how much run history to keep, from the history block.
+// Without one, all history is kept.
+func (c *Config) Retention() history.Retention {
+	if c.history == nil {
+		return history.Retention{}
+	}
+	return history.Retention{
+		MaxRuns: c.history.Retention.MaxRuns,
+		MaxAge:  c.history.Retention.MaxAge.Duration,
+	}
+}
+
+// StaleGrace returns how many of its gaps between runs a service can go
+// without running before it's stale, from the stale block.
+func (c *Config) StaleGrace() float64 {
+	if c.stale == nil {
+		return DefaultStaleGrace
+	}
+	return c.stale.Grace
+}
+
+// Blackout returns the windows the runs of every service are skipped in, from
+// the blackout blocks of the system config and of the config.
+func (c *Config) Blackout() []string {
+	var windows []string
+	if c.system != nil {
+		windows = append(windows, c.system.blackout...)
+	}
+	return append(windows, c.blackout...)
+}
+
+// Upload returns the config's upload block, or nil if run output isn't uploaded.
+func (c *Config) Upload() *UploadSettings {
+	return c.upload
+}
+
+// Telemetry returns the config's telemetry block, or nil if runs aren't
+// reported to a collector.
+func (c *Config) Telemetry() *TelemetrySettings {
+	return c.telemetry
+}
+
+// Template returns the template name from the templates block.
+func (c *Config) Template(name string) (*Template, error) {
+	for _, t := range c.templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("template '%s' does not exist; define it in the templates block of %s", name, c.ConfigFile)
+}
+
+// Templates returns the templates of the templates block.
+func (c *Config) Templates() []*Template {
+	return c.templates
+}
+
+// ValidateService validates svc and checks it against the system-wide policy.
+func (c *Config) ValidateService(svc *service.Service) error {
+	if err := svc.Validate(); err != nil {
+		return err
+	}
+	return c.CheckPolicy(svc)
+}
+
 // AddService adds a service.
 func (c *Config) AddService(svc *service.Service) error {
-	if err := svc.Validate(); err != nil {
+	if err := c.ValidateService(svc); err != nil {
 		return err
 	}
 
@@ -155,22 +522,31 @@ func (c *Config) AddService(svc *service.Service) error {
 	}
 
 	c.services[svc.Name] = svc
-	return c.Save()
+	return c.put(svc)
 }
 
-// RemoveService removes a service.
+// RemoveService removes a service, from the system config if it is defined there.
 func (c *Config) RemoveService(name string) error {
 	if _, exists := c.services[name]; !exists {
 		return fmt.Errorf("service %s does not exist", name)
 	}
 
+	if c.Scope(name) == ScopeSystem {
+		delete(c.system.services, name)
+		delete(c.services, name)
+		return c.system.store.Delete(name)
+	}
 	delete(c.services, name)
-	return c.Save()
+	if err := c.store.Delete(name); err != nil {
+		return fmt.Errorf("removing service %s: %w", name, err)
+	}
+	return nil
 }
 
-// UpdateService updates an existing service.
+// UpdateService updates an existing service, in the system config if it is
+// defined there.
 func (c *Config) UpdateService(svc *service.Service) error {
-	if err := svc.Validate(); err != nil {
+	if err := c.ValidateService(svc); err != nil {
 		return err
 	}
 
@@ -179,7 +555,11 @@ func (c *Config) UpdateService(svc *service.Service) error {
 	}
 
 	c.services[svc.Name] = svc
-	return c.Save()
+	if c.Scope(svc.Name) == ScopeSystem {
+		c.system.services[svc.Name] = svc
+		return c.system.put(svc)
+	}
+	return c.put(svc)
 }
 
 // GetService returns a service by name.
@@ -191,12 +571,13 @@ func (c *Config) GetService(name string) (*service.Service, error) {
 	return svc, nil
 }
 
-// ListServices returns all services.
+// ListServices returns all services, sorted by name.
 func (c *Config) ListServices() []*service.Service {
 	services := make([]*service.Service, 0, len(c.services))
 	for _, svc := range c.services {
 		services = append(services, svc)
 	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
 	return services
 }
 
@@ -205,12 +586,77 @@ func (c *Config) GetConfigPath() string {
 	return c.ConfigFile
 }
 
+// Dirs returns the directories the config keeps its files in.
+func (c *Config) Dirs() Dirs {
+	return Dirs{Config: c.ConfigDir, Data: c.DataDir, State: c.StateDir}
+}
+
 // GetScriptsDir returns the directory where scripts are stored.
 func (c *Config) GetScriptsDir() string {
+	return filepath.Join(c.DataDir, "scripts")
+}
+
+// GetPortableScriptsDir returns the directory where scripts of portable services
+// are stored, inside the config directory so they move with it.
+func (c *Config) GetPortableScriptsDir() string {
 	return filepath.Join(c.ConfigDir, "scripts")
 }
 
 // GetLogsDir returns the directory where service logs are stored.
 func (c *Config) GetLogsDir() string {
-	return filepath.Join(c.ConfigDir, "logs")
+	return filepath.Join(c.StateDir, "logs")
+}
+
+// GetHistoryDir returns the directory where run history is stored.
+func (c *Config) GetHistoryDir() string {
+	return filepath.Join(c.StateDir, "history")
+}
+
+// GetSnapshotsDir returns the directory where snapshots of the files services
+// watch with if_changed are stored, see package watch.
+func (c *Config) GetSnapshotsDir() string {
+	return filepath.Join(c.StateDir, "snapshots")
+}
+
+// GetPollsDir returns the directory where snapshots of the watch_paths of
+// services that poll them are stored, see service.PollsWatchPaths.
+func (c *Config) GetPollsDir() string {
+	return filepath.Join(c.GetSnapshotsDir(), "polls")
+}
+
+// GetServiceStateDir returns the directory where the runtime state of services
+// is stored, see package state.
+func (c *Config) GetServiceStateDir() string {
+	return filepath.Join(c.StateDir, "services")
+}
+
+// GetRunLocksDir returns the directory holding the lock of each service's
+// running run, apart from the locks of its state, which take the service's
+// name as is.
+func (c *Config) GetRunLocksDir() string {
+	return filepath.Join(c.StateDir, "runs")
+}
+
+// GetVersionsDir returns the directory where the versions of services are
+// kept, see package versions.
+func (c *Config) GetVersionsDir() string {
+	return filepath.Join(c.StateDir, "versions")
+}
+
+// GetSyncDir returns the clone of the repository the config is synced with,
+// see package gitsync.
+func (c *Config) GetSyncDir() string {
+	return filepath.Join(c.StateDir, "sync")
+}
+
+// GetChecksumsPath returns the file where the checksums of the files services
+// run through are recorded, see package integrity.
+func (c *Config) GetChecksumsPath() string {
+	return filepath.Join(c.StateDir, "checksums.json")
+}
+
+// GetEventsPath returns the file where events of services are logged, see
+// package events.
+func (c *Config) GetEventsPath() string {
+	return filepath.Join(c.StateDir, "events.jsonl")
 }
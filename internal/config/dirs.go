@@ -0,0 +1,278 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Dirs are the directories a Config keeps its files in.
+type Dirs struct {
+	Config string // services.yaml, and the scripts of portable services
+	Data   string // Scripts
+	State  string // Logs, run history, and Windows task wrappers
+}
+
+// DefaultDirs returns nazim's directories under the XDG base directories:
+// XDG_CONFIG_HOME, XDG_DATA_HOME, and XDG_STATE_HOME, which default to
+// ~/.config, ~/.local/share, and ~/.local/state. On Windows, the config is kept
+// in %APPDATA% and the rest, which shouldn't roam, in %LOCALAPPDATA%.
+func DefaultDirs() Dirs {
+	return Dirs{
+		Config: filepath.Join(xdgPath("XDG_CONFIG_HOME", getDefaultConfigDir()), AppName),
+		Data:   filepath.Join(xdgPath("XDG_DATA_HOME", getDefaultLocalDir(".local", "share")), AppName),
+		State:  filepath.Join(xdgPath("XDG_STATE_HOME", getDefaultLocalDir(".local", "state")), AppName),
+	}
+}
+
+// DirsAt returns the directories for a config directory given explicitly.
+// Everything is kept in it, so a config made for a project or a test stays in
+// one place, unless it is the default config directory.
+func DirsAt(dir string) Dirs {
+	defaults := DefaultDirs()
+	if sameDir(dir, defaults.Config) {
+		return defaults
+	}
+	return Dirs{Config: dir, Data: dir, State: dir}
+}
+
+// getDefaultLocalDir returns the default base directory for files that stay on
+// this machine: %LOCALAPPDATA% on Windows, otherwise home joined with elem.
+func getDefaultLocalDir(elem ...string) string {
+	if runtime.GOOS == "windows" {
+		if localAppData := os.Getenv("LOCALAPPDATA"); len(localAppData) >= 3 && filepath.IsAbs(localAppData) {
+			return localAppData
+		}
+		if profile := os.Getenv("USERPROFILE"); profile != "" && filepath.IsAbs(profile) {
+			return filepath.Join(profile, "AppData", "Local")
+		}
+		return getDefaultConfigDir()
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		// Don't fall back to "/", like the config directory
+		if cwd, err := os.Getwd(); err == nil {
+			home = cwd
+		} else {
+			home = "."
+		}
+	}
+	return filepath.Join(append([]string{home}, elem...)...)
+}
+
+// sameDir reports whether a and b name the same directory.
+func sameDir(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// LegacyDir returns the directory the first versions of nazim kept logs in on
+// Linux and macOS, ~/.nazim, or "" where there is none.
+func LegacyDir() string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".nazim")
+}
+
+// Migrate moves files older versions kept in the config directory to where
+// they belong now: logs and run history to the state directory, and scripts
+// to the data directory, updating the services that refer to them. Scripts of
+// portable services are kept in, or moved back to, the config directory, since
+// their paths are relative to it. Windows task wrappers are not moved, as the
+// installed tasks run them; services are given new ones when next installed.
+// The default config also takes over the logs in LegacyDir, which is removed
+// once empty.
+func (c *Config) Migrate() error {
+	var errs []string
+	for _, entry := range []string{"logs", "history"} {
+		if err := moveEntries(filepath.Join(c.ConfigDir, entry), filepath.Join(c.StateDir, entry), nil); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := c.migrateScripts(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("moving files out of %s: %s", c.ConfigDir, strings.Join(errs, "; "))
+	}
+
+	if legacy := LegacyDir(); legacy != "" && sameDir(c.ConfigDir, DefaultDirs().Config) {
+		if err := moveEntries(filepath.Join(legacy, "logs"), c.GetLogsDir(), nil); err != nil {
+			return fmt.Errorf("moving files out of %s: %w", legacy, err)
+		}
+		// Only succeeds once nothing is left
+		_ = os.Remove(legacy)
+	}
+	return nil
+}
+
+// migrateScripts moves scripts between the config and data directories so that
+// portable services find theirs in the config directory and all others are in
+// the data directory.
+func (c *Config) migrateScripts() error {
+	legacyDir := c.GetPortableScriptsDir()
+	scriptsDir := c.GetScriptsDir()
+	if sameDir(legacyDir, scriptsDir) {
+		return nil
+	}
+
+	portable := make(map[string]bool)
+	for _, svc := range c.services {
+		if !svc.Portable {
+			continue
+		}
+		commands := []string{svc.Command}
+		for _, override := range svc.Platforms {
+			commands = append(commands, override.Command)
+		}
+		for _, p := range commands {
+			if p != "" && !filepath.IsAbs(p) {
+				portable[filepath.Join(c.ConfigDir, p)] = true
+			}
+		}
+	}
+
+	// Restoring a backup puts every script in the data directory
+	if err := moveEntries(scriptsDir, legacyDir, func(name string) bool {
+		return portable[filepath.Join(legacyDir, name)]
+	}); err != nil {
+		return err
+	}
+	if err := moveEntries(legacyDir, scriptsDir, func(name string) bool {
+		return !portable[filepath.Join(legacyDir, name)]
+	}); err != nil {
+		return err
+	}
+
+	changed := false
+	for _, svc := range c.services {
+		if svc.Portable {
+			continue
+		}
+		if movePaths(svc, legacyDir, scriptsDir) {
+			changed = true
+		}
+	}
+	if changed {
+		return c.Save()
+	}
+	return nil
+}
+
+// movePaths points the absolute paths of svc that were inside from, and whose
+// file has moved, at the same place inside to. It reports whether any changed.
+func movePaths(svc *service.Service, from, to string) bool {
+	changed := false
+	move := func(p *string) {
+		if *p == "" || !filepath.IsAbs(*p) {
+			return
+		}
+		rel, err := filepath.Rel(from, *p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return
+		}
+		if _, err := os.Stat(*p); err == nil {
+			return
+		}
+		moved := filepath.Join(to, rel)
+		if _, err := os.Stat(moved); err == nil {
+			*p = moved
+			changed = true
+		}
+	}
+
+	move(&svc.Command)
+	move(&svc.WorkDir)
+	for i := range svc.Args {
+		move(&svc.Args[i])
+	}
+	for _, override := range svc.Platforms {
+		move(&override.Command)
+		move(&override.WorkDir)
+		for i := range override.Args {
+			move(&override.Args[i])
+		}
+	}
+	return changed
+}
+
+// moveEntries moves the files in from to to, for those keep is nil or true of,
+// and removes from once it is empty. Files that already exist in to are left
+// where they are.
+func moveEntries(from, to string, keep func(name string) bool) error {
+	if sameDir(from, to) {
+		return nil
+	}
+	entries, err := os.ReadDir(from)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || (keep != nil && !keep(entry.Name())) {
+			continue
+		}
+		target := filepath.Join(to, entry.Name())
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(to, 0755); err != nil {
+			return fmt.Errorf("creating %s: %w", to, err)
+		}
+		if err := moveFile(filepath.Join(from, entry.Name()), target); err != nil {
+			return err
+		}
+	}
+	// Only succeeds once nothing is left
+	_ = os.Remove(from)
+	return nil
+}
+
+// moveFile moves a file, copying it when it can't be renamed, as across filesystems.
+func moveFile(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return fmt.Errorf("copying %s: %w", from, err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return fmt.Errorf("copying %s: %w", from, err)
+	}
+	src.Close()
+	return os.Remove(from)
+}
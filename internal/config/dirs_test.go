@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMigrateLegacyLogs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("~/.nazim was only used on Linux and macOS")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+
+	legacyLogs := filepath.Join(home, ".nazim", "logs")
+	if err := os.MkdirAll(legacyLogs, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"job.out", "job.err"} {
+		if err := os.WriteFile(filepath.Join(legacyLogs, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := NewIn(DefaultDirs())
+	if err != nil {
+		t.Fatalf("NewIn() error = %v", err)
+	}
+	for _, name := range []string{"job.out", "job.err"} {
+		if data, err := os.ReadFile(filepath.Join(cfg.GetLogsDir(), name)); err != nil || string(data) != name {
+			t.Errorf("moved %s = %q, %v", name, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(home, ".nazim")); !os.IsNotExist(err) {
+		t.Errorf("~/.nazim is left after moving its logs: %v", err)
+	}
+}
+
+func TestMigrateLegacyLogsOtherConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("~/.nazim was only used on Linux and macOS")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	legacyLog := filepath.Join(home, ".nazim", "logs", "job.out")
+	if err := os.MkdirAll(filepath.Dir(legacyLog), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacyLog, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A config of its own, such as a project's, doesn't take them over
+	if _, err := NewAt(t.TempDir()); err != nil {
+		t.Fatalf("NewAt() error = %v", err)
+	}
+	if _, err := os.Stat(legacyLog); err != nil {
+		t.Errorf("legacy log moved into a config given explicitly: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/calilkhalil/nazim/internal/seal"
+)
+
+// EncryptionKey returns the key the config file is encrypted with, or nil
+// when it is in plain text. See package seal.
+func (c *Config) EncryptionKey() *seal.Key {
+	if s, ok := c.store.(*yamlStore); ok {
+		return s.key
+	}
+	return nil
+}
+
+// Encrypt rewrites the config file encrypted with key, or in plain text when
+// key is nil. Only the YAML store keeps services in the file: with another
+// store, they would stay in plain text, so the file isn't encrypted.
+func (c *Config) Encrypt(key *seal.Key) error {
+	s, ok := c.store.(*yamlStore)
+	if !ok {
+		return fmt.Errorf("services are kept in %s, which cannot be encrypted; only the %s store can be",
+			SQLitePath(c.ConfigDir), StoreYAML)
+	}
+	previous := s.key
+	s.key = key
+	if err := s.write(); err != nil {
+		s.key = previous
+		return err
+	}
+	return nil
+}
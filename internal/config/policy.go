@@ -0,0 +1,167 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy holds limits an administrator sets for every user of a machine, in a
+// system-wide file users can't edit:
+//
+//	min_interval: 5m
+//	allowed_dirs:
+//	  - /opt/jobs
+//	  - /usr/bin
+//	managed_mode: true
+//	trusted_keys:
+//	  - MCowBQYDK2VwAyEA...
+type Policy struct {
+	MinInterval service.Duration `yaml:"min_interval,omitempty"` // Shortest interval a service may run at
+	AllowedDirs []string         `yaml:"allowed_dirs,omitempty"` // Directories commands must reside in
+	// ManagedMode only lets services be changed by applying a bundle signed
+	// with one of TrustedKeys, and only lets those services run
+	ManagedMode bool     `yaml:"managed_mode,omitempty"`
+	TrustedKeys []string `yaml:"trusted_keys,omitempty"` // Ed25519 public keys bundles may be signed with
+
+	keys []ed25519.PublicKey
+}
+
+// PolicyPath returns the path of the system-wide policy file:
+// /etc/nazim/policy.yaml, or %ProgramData%\nazim\policy.yaml on Windows.
+func PolicyPath() string {
+	return filepath.Join(systemDir(), "policy.yaml")
+}
+
+// loadPolicy reads the policy file at path. A missing file means no policy.
+func loadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if policy.MinInterval.Duration < 0 {
+		return nil, fmt.Errorf("parsing %s: min_interval cannot be negative", path)
+	}
+	for i, dir := range policy.AllowedDirs {
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("parsing %s: allowed_dirs entry %q is not an absolute path", path, dir)
+		}
+		policy.AllowedDirs[i] = filepath.Clean(dir)
+	}
+	for _, key := range policy.TrustedKeys {
+		parsed, err := parsePublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: trusted_keys: %w", path, err)
+		}
+		policy.keys = append(policy.keys, parsed)
+	}
+	if policy.ManagedMode && len(policy.keys) == 0 {
+		return nil, fmt.Errorf("parsing %s: managed_mode needs trusted_keys to verify bundles with", path)
+	}
+	return &policy, nil
+}
+
+// CheckPolicy returns an error if svc breaks the system-wide policy: it runs
+// more often than min_interval allows, or its command is outside allowed_dirs.
+// Pipelines and parallel groups run no command of their own, so it's the
+// commands of their members that must be inside allowed_dirs.
+func (c *Config) CheckPolicy(svc *service.Service) error {
+	policy := c.policy
+	if policy == nil {
+		return nil
+	}
+
+	if interval := svc.GetInterval(); interval > 0 && interval < policy.MinInterval.Duration {
+		return fmt.Errorf("interval %s is shorter than the %s minimum set in %s",
+			interval, policy.MinInterval.Duration, PolicyPath())
+	}
+
+	if len(policy.AllowedDirs) == 0 {
+		return nil
+	}
+	if svc.RunsServices() {
+		noun := "step"
+		if svc.IsGroup() {
+			noun = "member"
+		}
+		for _, name := range svc.Members() {
+			member, exists := c.services[name]
+			if !exists || member.RunsServices() {
+				// Not something it can run; each member is checked again when it runs
+				continue
+			}
+			if err := c.checkCommand(member); err != nil {
+				return fmt.Errorf("%s '%s': %w", noun, name, err)
+			}
+		}
+		return nil
+	}
+	return c.checkCommand(svc)
+}
+
+// checkCommand returns an error if the command of svc is outside the
+// allowed_dirs of the policy.
+func (c *Config) checkCommand(svc *service.Service) error {
+	policy := c.policy
+	resolved := svc.ResolvePaths(c.ConfigDir)
+	if resolved.IsOneLiner() {
+		// The shell could run anything, so only scripts and programs can be checked
+		return fmt.Errorf("one-liner commands are not allowed by %s; use a script in one of: %s",
+			PolicyPath(), strings.Join(policy.AllowedDirs, ", "))
+	}
+	path, err := commandPath(resolved)
+	if err != nil {
+		return err
+	}
+	for _, dir := range policy.AllowedDirs {
+		if withinDir(dir, path) {
+			return nil
+		}
+	}
+	return fmt.Errorf("command %s is outside the directories allowed by %s: %s",
+		path, PolicyPath(), strings.Join(policy.AllowedDirs, ", "))
+}
+
+// commandPath returns the file a service's command runs, following symlinks so
+// that a link inside an allowed directory can't point outside of it.
+func commandPath(svc *service.Service) (string, error) {
+	path := svc.Command
+	if !svc.CommandIsPath() {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("command %s not found in PATH, so it can't be checked against %s", path, PolicyPath())
+		}
+		path = found
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	return path, nil
+}
+
+// withinDir reports whether path is dir or inside it.
+func withinDir(dir, path string) bool {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"empty", "", ""},
+		{"limits", "min_interval: 5m\nallowed_dirs:\n  - /opt/jobs\n", ""},
+		{"negative interval", "min_interval: -5m\n", "must be positive"},
+		{"relative dir", "allowed_dirs:\n  - jobs\n", "not an absolute path"},
+		{"managed without keys", "managed_mode: true\n", "needs trusted_keys"},
+		{"bad key", "trusted_keys:\n  - nope\n", "trusted_keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && strings.Contains(tt.content, "/opt") {
+				t.Skip("Unix paths")
+			}
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadPolicy(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("loadPolicy() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("loadPolicy() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if policy, err := loadPolicy(filepath.Join(t.TempDir(), "missing.yaml")); policy != nil || err != nil {
+		t.Errorf("loadPolicy() of a missing file = %v, %v, want no policy", policy, err)
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix scripts")
+	}
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	script := filepath.Join(allowed, "job.sh")
+	other := filepath.Join(outside, "job.sh")
+	for _, path := range []string{script, other} {
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(allowed, "link.sh")
+	if err := os.Symlink(other, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		ConfigDir: t.TempDir(),
+		policy: &Policy{
+			MinInterval: service.Duration{Duration: 5 * time.Minute},
+			AllowedDirs: []string{allowed},
+		},
+		services: map[string]*service.Service{
+			"inside":  {Name: "inside", Command: script},
+			"outside": {Name: "outside", Command: other},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		svc     *service.Service
+		wantErr string
+	}{
+		{"script inside", &service.Service{Name: "a", Command: script}, ""},
+		{"script outside", &service.Service{Name: "a", Command: other}, "outside the directories"},
+		{"symlink out", &service.Service{Name: "a", Command: link}, "outside the directories"},
+		{"one-liner", &service.Service{Name: "a", Command: "echo hi | tee /tmp/x"}, "one-liner"},
+		{"too often", &service.Service{Name: "a", Command: script, Interval: service.Duration{Duration: time.Minute}}, "shorter than"},
+		{"often enough", &service.Service{Name: "a", Command: script, Interval: service.Duration{Duration: time.Hour}}, ""},
+		{"pipeline", &service.Service{Name: "p", Steps: []string{"inside"}}, ""},
+		{"pipeline with a step outside", &service.Service{Name: "p", Steps: []string{"inside", "outside"}}, "step 'outside'"},
+		{"pipeline with a missing step", &service.Service{Name: "p", Steps: []string{"inside", "missing"}}, ""},
+		{"group", &service.Service{Name: "g", ParallelGroup: []string{"inside"}}, ""},
+		{"group with a member outside", &service.Service{Name: "g", ParallelGroup: []string{"outside"}}, "member 'outside'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cfg.CheckPolicy(tt.svc)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("CheckPolicy() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("CheckPolicy() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPolicyWithoutPolicy(t *testing.T) {
+	cfg := &Config{}
+	if err := cfg.CheckPolicy(&service.Service{Name: "a", Command: "echo hi | tee /tmp/x"}); err != nil {
+		t.Errorf("CheckPolicy() without a policy = %v, want nil", err)
+	}
+}
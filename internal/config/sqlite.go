@@ -0,0 +1,120 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/calilkhalil/nazim/internal/service"
+	"gopkg.in/yaml.v3"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteStore keeps services in a SQLite database, one row each, so a change
+// writes only its own row, and a crash midway leaves the last committed state.
+// Each row holds the service's YAML, the same as in the config file, so new
+// service settings need no change to the schema.
+type sqliteStore struct {
+	db *sql.DB
+	q  interface {
+		Exec(query string, args ...any) (sql.Result, error)
+		Query(query string, args ...any) (*sql.Rows, error)
+		QueryRow(query string, args ...any) *sql.Row
+	}
+	tx *sql.Tx // Set while a Tx is running
+}
+
+// openSQLite opens the database at path, creating it if needed.
+func openSQLite(path string) (Store, error) {
+	// Take the write lock when a transaction starts, so concurrent runs
+	// wait for each other instead of failing to upgrade a read lock
+	db, err := sql.Open("sqlite", path+"?_txlock=immediate&_pragma=busy_timeout(10000)")
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS services (
+		name TEXT PRIMARY KEY,
+		definition TEXT NOT NULL
+	)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return &sqliteStore{db: db, q: db}, nil
+}
+
+func (s *sqliteStore) Get(name string) (*service.Service, error) {
+	var definition string
+	err := s.q.QueryRow(`SELECT definition FROM services WHERE name = ?`, name).Scan(&definition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoService
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeService(name, definition)
+}
+
+func (s *sqliteStore) List() ([]*service.Service, error) {
+	rows, err := s.q.Query(`SELECT name, definition FROM services ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []*service.Service
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, err
+		}
+		svc, err := decodeService(name, definition)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, svc)
+	}
+	return services, rows.Err()
+}
+
+func (s *sqliteStore) Put(svc *service.Service) error {
+	definition, err := yaml.Marshal(svc)
+	if err != nil {
+		return fmt.Errorf("marshaling service: %w", err)
+	}
+	// Updating in place keeps the rowid, and so the service's place in List
+	_, err = s.q.Exec(`INSERT INTO services (name, definition) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET definition = excluded.definition`, svc.Name, string(definition))
+	return err
+}
+
+func (s *sqliteStore) Delete(name string) error {
+	_, err := s.q.Exec(`DELETE FROM services WHERE name = ?`, name)
+	return err
+}
+
+func (s *sqliteStore) Tx(fn func(Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	if err := fn(&sqliteStore{db: s.db, q: tx, tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// decodeService decodes the definition of a service stored under name.
+func decodeService(name, definition string) (*service.Service, error) {
+	var svc service.Service
+	if err := yaml.Unmarshal([]byte(definition), &svc); err != nil {
+		return nil, fmt.Errorf("parsing service %s: %w", name, err)
+	}
+	return &svc, nil
+}
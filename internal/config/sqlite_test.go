@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.db")
+	store, err := openSQLite(path)
+	if err != nil {
+		t.Fatalf("openSQLite() error = %v", err)
+	}
+
+	for _, name := range []string{"b", "a"} {
+		if err := store.Put(&service.Service{Name: name, Command: "echo " + name}); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	if err := store.Put(&service.Service{Name: "b", Command: "echo changed"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	svc, err := store.Get("b")
+	if err != nil || svc.Command != "echo changed" {
+		t.Fatalf("Get() = %+v, %v, want the replaced service", svc, err)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNoService) {
+		t.Errorf("Get() of a missing service error = %v, want ErrNoService", err)
+	}
+
+	rollback := errors.New("rolled back")
+	err = store.Tx(func(tx Store) error {
+		if err := tx.Delete("a"); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Tx() error = %v, want %v", err, rollback)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Errorf("Delete() of a missing service error = %v", err)
+	}
+
+	// Reopening sees what was committed, in the order it was first stored
+	reopened, err := openSQLite(path)
+	if err != nil {
+		t.Fatalf("openSQLite() error = %v", err)
+	}
+	services, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var names []string
+	for _, svc := range services {
+		names = append(names, svc.Name)
+	}
+	if len(names) != 2 || names[0] != "b" || names[1] != "a" {
+		t.Errorf("List() = %q, want [b a]", names)
+	}
+}
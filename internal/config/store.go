@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/calilkhalil/nazim/internal/seal"
+	"github.com/calilkhalil/nazim/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of store, set with the store key of the config file.
+const (
+	StoreYAML   = "yaml"   // Services are listed in the config file itself (the default)
+	StoreSQLite = "sqlite" // Services are kept in services.db, see SQLitePath
+)
+
+// ErrNoService is returned by Store.Get for a service that isn't stored.
+var ErrNoService = errors.New("no such service")
+
+// Store keeps the services of a config. Config reads them through its store
+// when it loads and writes each change back through it, so they can live
+// somewhere other than the YAML file. Services are stored as written, without
+// the config's defaults applied, and the services a store returns are copies:
+// changing them doesn't change the store.
+type Store interface {
+	// Get returns the service with the given name, or ErrNoService.
+	Get(name string) (*service.Service, error)
+	// List returns every service, in the order they were first stored.
+	List() ([]*service.Service, error)
+	// Put adds a service, or replaces the one with the same name.
+	Put(svc *service.Service) error
+	// Delete removes a service. Deleting one that isn't stored is not an error.
+	Delete(name string) error
+	// Tx runs fn with a store whose changes are saved together once fn
+	// returns, or discarded if it returns an error.
+	Tx(fn func(Store) error) error
+}
+
+// openStore returns the store the config file f selects, moving the services
+// the file still lists into it when it isn't the file itself.
+func openStore(path string, f file, structured bool, key *seal.Key) (Store, error) {
+	yamlStore := &yamlStore{path: path, doc: f, structured: structured, key: key}
+	switch f.Store {
+	case "", StoreYAML:
+		return yamlStore, nil
+	case StoreSQLite:
+	default:
+		return nil, fmt.Errorf("unknown store '%s' (use %s or %s)", f.Store, StoreYAML, StoreSQLite)
+	}
+
+	store, err := openSQLite(SQLitePath(filepath.Dir(path)))
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Services) == 0 {
+		return store, nil
+	}
+	// Switching to SQLite, or services added to the file by hand since
+	err = store.Tx(func(tx Store) error {
+		for _, svc := range f.Services {
+			if err := tx.Put(svc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("moving services into %s: %w", SQLitePath(filepath.Dir(path)), err)
+	}
+	yamlStore.doc.Services = nil
+	if err := yamlStore.write(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// InFile reports whether the services are kept in the config file itself
+// rather than in another store.
+func (c *Config) InFile() bool {
+	_, ok := c.store.(*yamlStore)
+	return ok
+}
+
+// SQLitePath returns where a config in dir keeps its services with the SQLite store.
+func SQLitePath(dir string) string {
+	return filepath.Join(dir, "services.db")
+}
+
+// yamlStore keeps services in the services list of the config file, next to
+// its other blocks. Every change rewrites the whole file, which becomes slow
+// with hundreds of services; Tx rewrites it once for a batch of changes.
+type yamlStore struct {
+	path       string
+	doc        file
+	structured bool      // The file holds a mapping of blocks rather than a bare list
+	key        *seal.Key // The file is encrypted with it, see package seal
+	inTx       bool
+}
+
+func (s *yamlStore) Get(name string) (*service.Service, error) {
+	for _, svc := range s.doc.Services {
+		if svc.Name == name {
+			copied := *svc
+			return &copied, nil
+		}
+	}
+	return nil, ErrNoService
+}
+
+func (s *yamlStore) List() ([]*service.Service, error) {
+	services := make([]*service.Service, 0, len(s.doc.Services))
+	for _, svc := range s.doc.Services {
+		copied := *svc
+		services = append(services, &copied)
+	}
+	return services, nil
+}
+
+func (s *yamlStore) Put(svc *service.Service) error {
+	copied := *svc
+	i := slices.IndexFunc(s.doc.Services, func(stored *service.Service) bool { return stored.Name == svc.Name })
+	if i >= 0 {
+		s.doc.Services[i] = &copied
+	} else {
+		s.doc.Services = append(s.doc.Services, &copied)
+	}
+	return s.write()
+}
+
+func (s *yamlStore) Delete(name string) error {
+	s.doc.Services = slices.DeleteFunc(s.doc.Services, func(svc *service.Service) bool { return svc.Name == name })
+	return s.write()
+}
+
+func (s *yamlStore) Tx(fn func(Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	saved := slices.Clone(s.doc.Services)
+	s.inTx = true
+	err := fn(s)
+	s.inTx = false
+	if err != nil {
+		s.doc.Services = saved
+		return err
+	}
+	return s.write()
+}
+
+// write rewrites the config file, unless a Tx is running. The file is
+// replaced in one step, so a crash never leaves half of it, and is encrypted
+// again if it was.
+func (s *yamlStore) write() error {
+	if s.inTx {
+		return nil
+	}
+
+	services := s.doc.Services
+	if services == nil {
+		services = []*service.Service{}
+	}
+	var doc any = services
+	if s.structured || !s.doc.Defaults.IsZero() || s.doc.History != nil || s.doc.Upload != nil ||
+		s.doc.Telemetry != nil || s.doc.Stale != nil || s.doc.Blackout != nil || s.doc.Templates != nil || s.doc.Store != "" {
+		doc = file{
+			Defaults: s.doc.Defaults, History: s.doc.History, Upload: s.doc.Upload, Telemetry: s.doc.Telemetry,
+			Stale: s.doc.Stale, Blackout: s.doc.Blackout, Templates: s.doc.Templates, Store: s.doc.Store, Services: services,
+		}
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+	if s.key != nil {
+		if data, err = seal.Seal(data, s.key); err != nil {
+			return fmt.Errorf("encrypting config: %w", err)
+		}
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(s.path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Scopes of a service: where it is defined.
+const (
+	ScopeUser   = "user"   // The user's config
+	ScopeSystem = "system" // The system config, see SystemConfigPath
+)
+
+// systemDir returns the directory of the system-wide files: /etc/nazim, or
+// %ProgramData%\nazim on Windows.
+func systemDir() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, AppName)
+	}
+	return filepath.Join("/etc", AppName)
+}
+
+// SystemConfigPath returns the path of the system config, which defines
+// services for every user of the machine: /etc/nazim/services.yaml, or
+// %ProgramData%\nazim\services.yaml on Windows. It has the layout of a user
+// config, but only its defaults and services blocks are used.
+func SystemConfigPath() string {
+	return filepath.Join(systemDir(), "services.yaml")
+}
+
+// loadSystem reads the system config at path. A missing file defines no services.
+func loadSystem(path string) (*Config, error) {
+	system := &Config{
+		ConfigDir:  filepath.Dir(path),
+		ConfigFile: path,
+		services:   make(map[string]*service.Service),
+	}
+	if err := system.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return system, nil
+}
+
+// Scope returns where a service is defined: ScopeSystem or ScopeUser.
+func (c *Config) Scope(name string) string {
+	if c.system != nil && c.system.services[name] != nil {
+		return ScopeSystem
+	}
+	return ScopeUser
+}
+
+// HasSystemServices reports whether the system config defines any service.
+func (c *Config) HasSystemServices() bool {
+	return c.system != nil && len(c.system.services) > 0
+}
+
+// ServiceConfigPath returns the path of the config file defining a service.
+func (c *Config) ServiceConfigPath(name string) string {
+	if c.Scope(name) == ScopeSystem {
+		return c.system.ConfigFile
+	}
+	return c.ConfigFile
+}
+
+// UserServices returns the services of the user's config, leaving out those
+// of the system config.
+func (c *Config) UserServices() []*service.Service {
+	var services []*service.Service
+	for name, svc := range c.services {
+		if c.Scope(name) == ScopeUser {
+			services = append(services, svc)
+		}
+	}
+	return services
+}
+
+// CheckModifiable returns an error if a service is defined in the system config
+// and the current user can't write it, which usually takes root or
+// administrator privileges.
+func (c *Config) CheckModifiable(name string) error {
+	if c.Scope(name) != ScopeSystem {
+		return nil
+	}
+	f, err := os.OpenFile(c.system.ConfigFile, os.O_WRONLY, 0)
+	if os.IsPermission(err) {
+		return fmt.Errorf("service %s is defined for every user in %s; changing it needs administrator (root) privileges",
+			name, c.system.ConfigFile)
+	}
+	if err != nil {
+		return fmt.Errorf("opening system config: %w", err)
+	}
+	return f.Close()
+}
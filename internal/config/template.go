@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// Template is an entry of the templates block of a config file: a service
+// definition with placeholders such as {{dir}} in its text fields, which
+// `nazim generate` expands into one service per combination of values of a
+// matrix.
+//
+//	templates:
+//	  - name: backup-tpl
+//	    instance_name: backup-{{dir}}
+//	    service:
+//	      command: backup.sh
+//	      args: ["{{dir}}"]
+//	      schedule: daily 02:00
+type Template struct {
+	Name string `yaml:"name"`
+	// InstanceName is the name of each service generated from the template,
+	// with placeholders. Values stand in for them as name slugs: /srv/a
+	// becomes a. Without it, services are named after the template, less a
+	// -tpl or -template suffix, followed by the slugs of the values.
+	InstanceName string `yaml:"instance_name,omitempty"`
+	// Service is kept as written, placeholders and all, so saving the config
+	// doesn't fill in the fields it leaves out.
+	Service yaml.Node `yaml:"service"`
+}
+
+// placeholder matches {{key}} in a template, spaces around key allowed.
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// matrixKey matches the keys a matrix may set.
+var matrixKey = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// unsafeNameChars matches what a service name can't hold.
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// check checks a template as far as it can be without values: it needs a
+// name and a service.
+func (t *Template) check() error {
+	if t.Name == "" {
+		return fmt.Errorf("template has no name")
+	}
+	if t.Service.Kind != yaml.MappingNode {
+		return fmt.Errorf("template '%s' has no service", t.Name)
+	}
+	return nil
+}
+
+// Keys returns the placeholder keys the template uses, sorted.
+func (t *Template) Keys() []string {
+	data, _ := yaml.Marshal(&t.Service)
+	seen := make(map[string]bool)
+	var keys []string
+	for _, match := range placeholder.FindAllStringSubmatch(string(data)+t.InstanceName, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			keys = append(keys, match[1])
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Expand returns the service the template describes for values, named
+// after them. Every placeholder must have a value.
+func (t *Template) Expand(values map[string]string) (*service.Service, error) {
+	var missing []string
+	for _, key := range t.Keys() {
+		if _, ok := values[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("template '%s' uses {{%s}}, which the matrix doesn't set", t.Name, strings.Join(missing, "}}, {{"))
+	}
+
+	var node yaml.Node
+	data, _ := yaml.Marshal(&t.Service)
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("expanding template '%s': %w", t.Name, err)
+	}
+	substitute(&node, func(key string) string { return values[key] })
+	svc := &service.Service{}
+	if err := node.Decode(svc); err != nil {
+		return nil, fmt.Errorf("expanding template '%s': %w", t.Name, err)
+	}
+
+	svc.Name = t.instanceName(values)
+	svc.Template = t.Name
+	svc.Matrix = values
+	return svc, nil
+}
+
+// instanceName returns the name of the service generated for values.
+func (t *Template) instanceName(values map[string]string) string {
+	if t.InstanceName != "" {
+		return placeholder.ReplaceAllStringFunc(t.InstanceName, func(match string) string {
+			return slug(values[placeholder.FindStringSubmatch(match)[1]])
+		})
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := []string{strings.TrimSuffix(strings.TrimSuffix(t.Name, "-template"), "-tpl")}
+	for _, key := range keys {
+		parts = append(parts, slug(values[key]))
+	}
+	return strings.Join(parts, "-")
+}
+
+// substitute replaces the placeholders in the string scalars of node.
+func substitute(node *yaml.Node, value func(key string) string) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Value = placeholder.ReplaceAllStringFunc(node.Value, func(match string) string {
+			return value(placeholder.FindStringSubmatch(match)[1])
+		})
+	}
+	for _, child := range node.Content {
+		substitute(child, value)
+	}
+}
+
+// slug turns a value into something a service name can hold: the last
+// element of a path, with other characters replaced by dashes.
+func slug(value string) string {
+	value = strings.TrimRight(strings.ReplaceAll(value, `\`, "/"), "/")
+	if strings.Contains(value, "/") {
+		value = filepath.Base(value)
+	}
+	return strings.Trim(unsafeNameChars.ReplaceAllString(value, "-"), "-")
+}
+
+// ParseMatrix parses the values of a matrix, each as key=value1,value2,...,
+// and returns every combination of them, in the order given: dir=/a,/b with
+// env=dev,prod gives four.
+func ParseMatrix(entries []string) ([]map[string]string, error) {
+	combinations := []map[string]string{{}}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		key, list, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !matrixKey.MatchString(key) {
+			return nil, fmt.Errorf("invalid matrix %q: expected key=value1,value2,...", entry)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("matrix key '%s' is given more than once", key)
+		}
+		seen[key] = true
+
+		var values []string
+		for _, value := range strings.Split(list, ",") {
+			if value = strings.TrimSpace(value); value == "" {
+				return nil, fmt.Errorf("invalid matrix %q: empty value", entry)
+			}
+			values = append(values, value)
+		}
+
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range values {
+				expanded := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					expanded[k] = v
+				}
+				expanded[key] = value
+				next = append(next, expanded)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
@@ -0,0 +1,52 @@
+// Package deprecation lists the flags and config fields scheduled for removal.
+// They keep working until then, but using one prints a warning naming what
+// replaces it, so scripts and configs can be updated before a release drops it.
+package deprecation
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Flags maps each deprecated flag, without its dashes, to what to use instead,
+// such as "--interval none". Short forms are listed separately.
+var Flags = map[string]string{}
+
+// Fields maps each deprecated field of a service, as written in the config,
+// to what to use instead, such as "triggers".
+var Fields = map[string]string{}
+
+// UsedFlags returns the deprecated flags among names, in order.
+func UsedFlags(names []string) []string {
+	var used []string
+	for _, name := range names {
+		if _, ok := Flags[name]; ok {
+			used = append(used, name)
+		}
+	}
+	return used
+}
+
+// UsedFields returns the deprecated fields set in v, a struct or a pointer to
+// one whose fields are named by their yaml tags, such as a service.Service.
+// Fields holding their zero value aren't written out, so they don't count.
+func UsedFields(v any) []string {
+	if len(Fields) == 0 {
+		return nil
+	}
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var used []string
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		if _, ok := Fields[name]; ok && !value.Field(i).IsZero() {
+			used = append(used, name)
+		}
+	}
+	sort.Strings(used)
+	return used
+}
@@ -0,0 +1,83 @@
+// Package eventlog reports runs to the Windows Event Log, under the "Nazim"
+// source in the Application log, so monitoring that watches it (SCOM, Wazuh,
+// and the like) picks up failed jobs without reading nazim's text logs.
+//
+// Each kind of event has its own ID; the details of the run follow the
+// message as "Key: value" lines. On other platforms, nothing is written.
+package eventlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source is the event source nazim registers and writes as.
+const Source = "Nazim"
+
+// Event IDs.
+const (
+	IDStarted     = 100 // A run started
+	IDSucceeded   = 101 // A run finished with exit code 0
+	IDFailed      = 102 // A run finished with another exit code
+	IDStartFailed = 103 // The command couldn't be started
+	IDSlow        = 104 // A run took longer than the service's slow threshold
+	IDStale       = 105 // A service hasn't run for longer than expected, reported by `nazim doctor --notify`
+	IDPaused      = 106 // A service failed max_consecutive_failures runs in a row, and its runs are paused
+	IDSkipped     = 107 // A run was skipped for a blackout window
+)
+
+// Event levels.
+const (
+	Info = iota
+	Warning
+	Error
+)
+
+// Event is a run event, a stale service, or a paused one.
+type Event struct {
+	ID      uint32
+	Message string
+	Fields  [][2]string // Key and value pairs, in order
+}
+
+// Level returns the level the event is written at.
+func (e *Event) Level() int {
+	switch e.ID {
+	case IDFailed, IDStartFailed:
+		return Error
+	case IDSlow, IDStale, IDPaused:
+		return Warning
+	}
+	return Info
+}
+
+// Text returns the event's message followed by its fields.
+func (e *Event) Text() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	if len(e.Fields) > 0 {
+		b.WriteString("\r\n")
+	}
+	for _, field := range e.Fields {
+		fmt.Fprintf(&b, "\r\n%s: %s", field[0], field[1])
+	}
+	return b.String()
+}
+
+// Register registers the event source, which needs administrator privileges.
+// Registering it again does nothing.
+func Register() error {
+	return register()
+}
+
+// Unregister removes the event source, which needs administrator privileges.
+// Unregistering a source that isn't registered does nothing.
+func Unregister() error {
+	return unregister()
+}
+
+// Write writes an event. Events of an unregistered source are still written,
+// but Event Viewer shows them with a note that their description is missing.
+func Write(e Event) error {
+	return write(e)
+}
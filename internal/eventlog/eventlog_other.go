@@ -0,0 +1,15 @@
+//go:build !windows
+
+package eventlog
+
+func register() error {
+	return nil
+}
+
+func unregister() error {
+	return nil
+}
+
+func write(e Event) error {
+	return nil
+}
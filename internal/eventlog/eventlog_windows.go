@@ -0,0 +1,52 @@
+//go:build windows
+
+package eventlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// register registers Source with EventCreate.exe as its message file, which
+// shows the text of events with IDs up to 1000 as written.
+func register() error {
+	err := eventlog.InstallAsEventCreate(Source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil && !strings.Contains(err.Error(), "already exists") {
+		return fmt.Errorf("registering event source: %w", err)
+	}
+	return nil
+}
+
+// unregister removes the registry key of Source.
+func unregister() error {
+	err := eventlog.Remove(Source)
+	if err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return fmt.Errorf("removing event source: %w", err)
+	}
+	return nil
+}
+
+func write(e Event) error {
+	log, err := eventlog.Open(Source)
+	if err != nil {
+		return fmt.Errorf("opening event log: %w", err)
+	}
+	defer log.Close()
+
+	switch e.Level() {
+	case Error:
+		err = log.Error(e.ID, e.Text())
+	case Warning:
+		err = log.Warning(e.ID, e.Text())
+	default:
+		err = log.Info(e.ID, e.Text())
+	}
+	if err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package events keeps a log of what happens to services, for tools that
+// follow it, such as desktop widgets and log shippers: services added,
+// changed, or removed by nazim commands, and runs starting and finishing as
+// `nazim exec` reports them. Each event is a line of JSON in events.jsonl in
+// the state directory; once the file grows past MaxSize, it is moved to
+// events.jsonl.1 and a new one is started.
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Event types.
+const (
+	ServiceAdded    = "service.added"
+	ServiceUpdated  = "service.updated"
+	ServiceRemoved  = "service.removed"
+	ServiceEnabled  = "service.enabled"
+	ServiceDisabled = "service.disabled"
+	ServiceStopped  = "service.stopped"
+	ServicePaused   = "service.paused" // Runs are paused after too many failures in a row
+	ServiceStale    = "service.stale"  // Reported by `nazim doctor --notify`
+
+	RunStarted     = "run.started"
+	RunSucceeded   = "run.succeeded"
+	RunFailed      = "run.failed"
+	RunStartFailed = "run.start_failed" // The command couldn't be started
+	RunSlow        = "run.slow"
+	RunSkipped     = "run.skipped" // The run fell in a blackout window
+)
+
+// MaxSize is the size past which the log is rotated.
+const MaxSize = 10 << 20
+
+// pollInterval is how often Follow looks for new events.
+const pollInterval = 500 * time.Millisecond
+
+// Event is something that happened to a service.
+type Event struct {
+	Time    time.Time         `json:"time"`
+	Type    string            `json:"type"`
+	Service string            `json:"service,omitempty"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Append adds an event to the log at path, rotating it first if it has grown
+// past MaxSize. Each event is a single write, so events of runs finishing at
+// the same time don't interleave.
+func Append(path string, e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false) // Keep commands readable, such as "2>&1"
+	if err := enc.Encode(e); err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() >= MaxSize {
+		if err := os.Rename(path, path+".1"); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotating event log: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening event log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(line.Bytes()); err != nil {
+		return fmt.Errorf("writing event log: %w", err)
+	}
+	return nil
+}
+
+// Read returns the events in the log at path, oldest first, and the offset
+// following the last one, to Follow from. A missing log has no events.
+// Malformed lines, for example from a write cut short, are skipped.
+func Read(path string) ([]Event, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("opening event log: %w", err)
+	}
+	defer f.Close()
+	return readFrom(f)
+}
+
+// readFrom reads the complete lines of r as events, returning how many bytes
+// they took. A last line without its newline is still being written.
+func readFrom(r io.Reader) ([]Event, int64, error) {
+	var events []Event
+	var read int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return events, read, nil
+		}
+		if err != nil {
+			return events, read, fmt.Errorf("reading event log: %w", err)
+		}
+		read += int64(len(line))
+		var e Event
+		if json.Unmarshal(line, &e) == nil {
+			events = append(events, e)
+		}
+	}
+}
+
+// Follow calls fn with each event added to the log at path after offset, as
+// it is added, until ctx is done. When the log is rotated or truncated, it
+// starts over at the beginning of the new one.
+func Follow(ctx context.Context, path string, offset int64, fn func(Event)) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				offset = 0
+				continue
+			}
+			return fmt.Errorf("reading event log: %w", err)
+		}
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("opening event log: %w", err)
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return fmt.Errorf("reading event log: %w", err)
+		}
+		events, read, err := readFrom(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		offset += read
+		for _, e := range events {
+			fn(e)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package export writes services to a file another machine can import, and
+// reads them back, checking each against the operating system importing it.
+// A portable export tags every service with what it needs to run: the
+// operating systems it works on and the kind of shell or program its command
+// is. Importing translates what it can to the importing OS and reports what
+// it can't.
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of command, as Requirements.Shell records them.
+const (
+	ShellSh         = "sh"         // A shell script or a one-liner of a POSIX shell
+	ShellBat        = "bat"        // A batch file or a cmd one-liner
+	ShellPowerShell = "powershell" // Windows PowerShell 5.1
+	ShellPwsh       = "pwsh"       // PowerShell 7 and later, on every OS
+	ShellExe        = "exe"        // A Windows program
+	ShellProgram    = "program"    // Any other program, found by path or in PATH
+)
+
+// File is an export: the services.yaml layout, which `nazim apply` also
+// reads, with the OS it was exported from.
+type File struct {
+	ExportedFrom string     `yaml:"exported_from,omitempty"` // GOOS of the exporting machine
+	Services     []*Service `yaml:"services"`
+}
+
+// Service is an exported service, with its requirements in a portable export.
+type Service struct {
+	*service.Service `yaml:",inline"`
+	Requires         *Requirements `yaml:"requires,omitempty"`
+}
+
+// Requirements is what a service needs to run.
+type Requirements struct {
+	OS    []string `yaml:"os"`              // Operating systems it runs on unchanged
+	Shell string   `yaml:"shell,omitempty"` // What runs its command, see the Shell constants
+}
+
+// Write writes services exported from goos to w. With portable, each is
+// tagged with its requirements.
+func Write(w io.Writer, services []*service.Service, goos string, portable bool) error {
+	f := File{ExportedFrom: goos}
+	for _, svc := range services {
+		exported := &Service{Service: svc}
+		if portable {
+			exported.Requires = Require(svc, goos)
+		}
+		f.Services = append(f.Services, exported)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&f); err != nil {
+		return fmt.Errorf("writing export: %w", err)
+	}
+	return enc.Close()
+}
+
+// Read reads an export. Config files and bundles read as exports without
+// requirements.
+func Read(r io.Reader) (*File, error) {
+	var f File
+	if err := yaml.NewDecoder(r).Decode(&f); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("parsing export: %w", err)
+	}
+	for i, svc := range f.Services {
+		if svc == nil || svc.Service == nil || svc.Name == "" {
+			return nil, fmt.Errorf("parsing export: service #%d has no name", i+1)
+		}
+	}
+	return &f, nil
+}
+
+// Require returns what a service set up on goos needs to run: the operating
+// systems its command and triggers work on, including those it has platform
+// overrides for, and the kind of its command there.
+func Require(svc *service.Service, goos string) *Requirements {
+	effective := svc.ForPlatform(goos)
+	shell := ShellOf(effective, goos)
+
+	var systems []string
+	switch {
+	case svc.RunsServices():
+		// Its members are exported with requirements of their own
+		shell = ""
+		systems = []string{"darwin", "linux", "windows"}
+	case shell == ShellSh:
+		systems = []string{"darwin", "linux"}
+	case shell == ShellBat, shell == ShellExe, shell == ShellPowerShell:
+		systems = []string{"windows"}
+	case shell == ShellPwsh:
+		systems = []string{"darwin", "linux", "windows"}
+	default:
+		systems = []string{goos}
+	}
+	for other := range svc.Platforms {
+		if !slices.Contains(systems, other) {
+			systems = append(systems, other)
+		}
+	}
+
+	// Triggers and settings only one scheduler has narrow them down
+	switch {
+	case svc.OnEvent != "" || svc.LongRunning:
+		systems = keep(systems, "windows")
+	case svc.System:
+		systems = keep(systems, "darwin")
+	case svc.OnWindowsHost():
+		systems = keep(systems, "linux")
+	}
+	if svc.Network == service.NetworkNone {
+		// macOS can't isolate it
+		systems = slices.DeleteFunc(systems, func(goos string) bool { return goos == "darwin" })
+	}
+	sort.Strings(systems)
+	return &Requirements{OS: systems, Shell: shell}
+}
+
+// keep returns goos if systems has it, and no systems otherwise.
+func keep(systems []string, goos string) []string {
+	if slices.Contains(systems, goos) {
+		return []string{goos}
+	}
+	return []string{}
+}
+
+// ShellOf returns the kind of command a service runs on goos.
+func ShellOf(svc *service.Service, goos string) string {
+	if svc.IsOneLiner() {
+		var shell string
+		if svc.Shell != "" {
+			shell = strings.ToLower(strings.TrimSuffix(filepath.Base(strings.ReplaceAll(svc.Shell, `\`, "/")), ".exe"))
+		}
+		switch {
+		case shell == ShellPwsh:
+			return ShellPwsh
+		case shell == ShellPowerShell:
+			return ShellPowerShell
+		case shell == service.ShellCmd, shell == "" && goos == "windows":
+			return ShellBat // One-liners run in cmd on Windows by default
+		}
+		return ShellSh
+	}
+
+	switch strings.ToLower(filepath.Ext(svc.Command)) {
+	case ".sh", ".bash", ".zsh":
+		return ShellSh
+	case ".bat", ".cmd":
+		return ShellBat
+	case ".exe":
+		return ShellExe
+	case ".ps1":
+		// Only PowerShell 7 runs scripts on Linux and macOS
+		if goos != "windows" || strings.EqualFold(strings.TrimSuffix(filepath.Base(svc.Shell), ".exe"), ShellPwsh) {
+			return ShellPwsh
+		}
+		return ShellPowerShell
+	}
+	return ShellProgram
+}
+
+// Adapt checks an exported service against goos, the OS importing it, and
+// returns it as it should be installed there, along with notes on what was
+// changed or may need checking. It returns an error when the service can't
+// run on goos, saying why.
+func Adapt(exported *Service, from, goos string) (*service.Service, []string, error) {
+	svc := exported.Service
+	req := exported.Requires
+	if req == nil {
+		// Without requirements, only the platform the service was set up on is known
+		if err := svc.CheckPlatform(goos); err != nil {
+			return nil, nil, fmt.Errorf("%w; export it with --portable to have it translated", err)
+		}
+		svc.Platform = goos
+		return svc, nil, nil
+	}
+
+	if slices.Contains(req.OS, goos) || svc.Platforms[goos] != nil {
+		svc.Platform = goos
+		return svc, nil, nil
+	}
+
+	var notes []string
+	switch {
+	case svc.OnEvent != "":
+		return nil, nil, fmt.Errorf("on_event triggers on Windows event log events, which %s doesn't have", goos)
+	case svc.LongRunning:
+		return nil, nil, fmt.Errorf("long_running services are Windows services, which %s doesn't have", goos)
+	case svc.Network == service.NetworkNone && goos == "darwin":
+		return nil, nil, fmt.Errorf("network %s can't be enforced on macOS, where launchd has no network isolation", service.NetworkNone)
+	case svc.System && goos != "darwin":
+		svc.System = false
+		notes = append(notes, "installed as a user service; system daemons are macOS only")
+	case svc.OnWindowsHost() && goos != "linux":
+		svc.Target = ""
+		notes = append(notes, "scheduled here instead of on a Windows host")
+	}
+
+	switch req.Shell {
+	case ShellSh:
+		if goos == "windows" {
+			return nil, nil, fmt.Errorf("%s is a shell script, which Windows doesn't run; add a platforms.windows override with a Windows command", svc.Command)
+		}
+	case ShellBat:
+		if goos != "windows" {
+			return nil, nil, fmt.Errorf("%s is a Windows batch command, which %s doesn't run; add a platforms.%s override with a shell command", svc.Command, goos, goos)
+		}
+	case ShellExe:
+		if goos != "windows" {
+			return nil, nil, fmt.Errorf("%s is a Windows program, which %s doesn't run", svc.Command, goos)
+		}
+	case ShellPowerShell:
+		if _, err := exec.LookPath(ShellPwsh); err != nil {
+			return nil, nil, fmt.Errorf("%s needs Windows PowerShell, and PowerShell 7 (pwsh), which runs it on %s, is not installed", svc.Command, goos)
+		}
+		svc.Shell = ShellPwsh
+		notes = append(notes, "runs with PowerShell 7 (pwsh) instead of Windows PowerShell")
+	case ShellProgram:
+		if (from == "windows") != (goos == "windows") {
+			return nil, nil, fmt.Errorf("%s is a program set up on %s, which is unlikely to run on %s", svc.Command, from, goos)
+		}
+		if !svc.CommandIsPath() {
+			notes = append(notes, fmt.Sprintf("%s was set up on %s; check that it is installed here", svc.Command, from))
+		}
+	}
+
+	if svc.CommandIsPath() {
+		if _, err := os.Stat(svc.Command); err != nil {
+			notes = append(notes, fmt.Sprintf("%s doesn't exist on this machine", svc.Command))
+		}
+	}
+	if svc.Elevated && goos != "windows" {
+		notes = append(notes, "elevated is ignored outside Windows")
+	}
+	if svc.OnStartup && from == "windows" {
+		notes = append(notes, "on_startup runs as the current user here, not as SYSTEM")
+	}
+	svc.Platform = goos
+	return svc, notes, nil
+}
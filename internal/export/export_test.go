@@ -0,0 +1,198 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestShellOf(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  service.Service
+		goos string
+		want string
+	}{
+		{"sh one-liner", service.Service{Command: "rm -rf /tmp/cache"}, "linux", ShellSh},
+		{"bash one-liner", service.Service{Command: "echo hi", Shell: "/bin/bash"}, "darwin", ShellSh},
+		{"cmd one-liner on windows", service.Service{Command: `del /Q C:\temp\*.tmp`}, "windows", ShellBat},
+		{"cmd shell", service.Service{Command: "dir /b", Shell: "cmd"}, "windows", ShellBat},
+		{"pwsh one-liner", service.Service{Command: "Get-Date -Format o", Shell: "pwsh"}, "linux", ShellPwsh},
+		{"powershell one-liner", service.Service{Command: "Get-Date -Format o", Shell: `C:\Windows\System32\powershell.exe`}, "windows", ShellPowerShell},
+		{"shell script", service.Service{Command: "/opt/backup.sh"}, "linux", ShellSh},
+		{"batch file", service.Service{Command: `C:\jobs\backup.BAT`}, "windows", ShellBat},
+		{"windows program", service.Service{Command: `C:\tools\sync.exe`}, "windows", ShellExe},
+		{"ps1 on windows", service.Service{Command: `C:\jobs\report.ps1`}, "windows", ShellPowerShell},
+		{"ps1 with pwsh on windows", service.Service{Command: `C:\jobs\report.ps1`, Shell: "pwsh.exe"}, "windows", ShellPwsh},
+		{"ps1 elsewhere", service.Service{Command: "/opt/report.ps1"}, "linux", ShellPwsh},
+		{"program", service.Service{Command: "restic"}, "linux", ShellProgram},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShellOf(&tt.svc, tt.goos); got != tt.want {
+				t.Errorf("ShellOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  service.Service
+		goos string
+		want Requirements
+	}{
+		{"shell script", service.Service{Command: "/opt/backup.sh"}, "linux",
+			Requirements{OS: []string{"darwin", "linux"}, Shell: ShellSh}},
+		{"batch file", service.Service{Command: `C:\jobs\backup.bat`}, "windows",
+			Requirements{OS: []string{"windows"}, Shell: ShellBat}},
+		{"pwsh script", service.Service{Command: "/opt/report.ps1"}, "darwin",
+			Requirements{OS: []string{"darwin", "linux", "windows"}, Shell: ShellPwsh}},
+		{"program", service.Service{Command: "restic"}, "linux",
+			Requirements{OS: []string{"linux"}, Shell: ShellProgram}},
+		{"windows override", service.Service{Command: "/opt/backup.sh",
+			Platforms: map[string]*service.PlatformOverride{"windows": {Command: `C:\jobs\backup.bat`}}}, "linux",
+			Requirements{OS: []string{"darwin", "linux", "windows"}, Shell: ShellSh}},
+		{"pipeline", service.Service{Steps: []string{"fetch", "report"}}, "linux",
+			Requirements{OS: []string{"darwin", "linux", "windows"}}},
+		{"event trigger", service.Service{Command: "pwsh -File x.ps1", Shell: "pwsh", OnEvent: "Log=System;ID=1"}, "windows",
+			Requirements{OS: []string{"windows"}, Shell: ShellPwsh}},
+		{"long running sh", service.Service{Command: "/opt/serve.sh", LongRunning: true}, "linux",
+			Requirements{OS: []string{}, Shell: ShellSh}},
+		{"macos system daemon", service.Service{Command: "/opt/backup.sh", System: true}, "darwin",
+			Requirements{OS: []string{"darwin"}, Shell: ShellSh}},
+		{"wsl windows host", service.Service{Command: "/opt/backup.sh", Target: service.TargetWindowsHost}, "linux",
+			Requirements{OS: []string{"linux"}, Shell: ShellSh}},
+		{"no network", service.Service{Command: "/opt/backup.sh", Network: service.NetworkNone}, "linux",
+			Requirements{OS: []string{"linux"}, Shell: ShellSh}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Require(&tt.svc, tt.goos); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Require() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	tests := []struct {
+		name      string
+		svc       service.Service
+		from      string
+		goos      string
+		wantErr   string
+		wantNotes []string
+		check     func(t *testing.T, svc *service.Service)
+	}{
+		{name: "runs unchanged", svc: service.Service{Command: "/opt/backup.sh"}, from: "linux", goos: "darwin",
+			wantNotes: nil},
+		{name: "shell script on windows", svc: service.Service{Command: "/opt/backup.sh"}, from: "linux", goos: "windows",
+			wantErr: "shell script, which Windows doesn't run"},
+		{name: "batch file on linux", svc: service.Service{Command: `C:\jobs\backup.bat`}, from: "windows", goos: "linux",
+			wantErr: "platforms.linux override"},
+		{name: "windows program on darwin", svc: service.Service{Command: `C:\tools\sync.exe`}, from: "windows", goos: "darwin",
+			wantErr: "Windows program"},
+		{name: "event trigger", svc: service.Service{Command: `C:\tools\sync.exe`, OnEvent: "Log=System;ID=1"}, from: "windows", goos: "linux",
+			wantErr: "on_event"},
+		{name: "long running", svc: service.Service{Command: `C:\tools\sync.exe`, LongRunning: true}, from: "windows", goos: "linux",
+			wantErr: "long_running"},
+		{name: "no network on darwin", svc: service.Service{Command: "/opt/backup.sh", Network: service.NetworkNone}, from: "linux", goos: "darwin",
+			wantErr: "network isolation"},
+		{name: "program across windows", svc: service.Service{Command: "restic"}, from: "linux", goos: "windows",
+			wantErr: "unlikely to run on windows"},
+		{name: "program across unixes", svc: service.Service{Command: "restic"}, from: "linux", goos: "darwin",
+			wantNotes: []string{"restic was set up on linux; check that it is installed here"}},
+		{name: "system daemon on linux", svc: service.Service{Command: "/opt/missing/backup.sh", System: true}, from: "darwin", goos: "linux",
+			wantNotes: []string{
+				"installed as a user service; system daemons are macOS only",
+				"/opt/missing/backup.sh doesn't exist on this machine",
+			},
+			check: func(t *testing.T, svc *service.Service) {
+				if svc.System {
+					t.Errorf("System = true, want it dropped")
+				}
+			}},
+		{name: "windows host target on darwin", svc: service.Service{Command: "/opt/missing/backup.sh", Target: service.TargetWindowsHost}, from: "linux", goos: "darwin",
+			wantNotes: []string{
+				"scheduled here instead of on a Windows host",
+				"/opt/missing/backup.sh doesn't exist on this machine",
+			},
+			check: func(t *testing.T, svc *service.Service) {
+				if svc.Target != "" {
+					t.Errorf("Target = %q, want it dropped", svc.Target)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := tt.svc
+			exported := &Service{Service: &svc, Requires: Require(&svc, tt.from)}
+			got, notes, err := Adapt(exported, tt.from, tt.goos)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Adapt() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Adapt() error = %v", err)
+			}
+			if got.Platform != tt.goos {
+				t.Errorf("Platform = %q, want %q", got.Platform, tt.goos)
+			}
+			if !reflect.DeepEqual(notes, tt.wantNotes) {
+				t.Errorf("Adapt() notes = %q, want %q", notes, tt.wantNotes)
+			}
+			if tt.check != nil {
+				tt.check(t, got)
+			}
+		})
+	}
+}
+
+func TestAdaptWithoutRequirements(t *testing.T) {
+	svc := &service.Service{Name: "backup", Command: "/opt/backup.sh", Platform: "linux"}
+	if _, _, err := Adapt(&Service{Service: svc}, "linux", "windows"); err == nil || !strings.Contains(err.Error(), "--portable") {
+		t.Errorf("Adapt() error = %v, want a hint at --portable", err)
+	}
+	if got, _, err := Adapt(&Service{Service: svc}, "linux", "linux"); err != nil || got.Platform != "linux" {
+		t.Errorf("Adapt() on its own platform = %v, %v", got, err)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	services := []*service.Service{
+		{Name: "backup", Command: "/opt/backup.sh", Interval: service.Duration{Duration: 3600e9}},
+		{Name: "cleanup", Command: `C:\jobs\cleanup.bat`, OnStartup: true},
+	}
+	for _, portable := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := Write(&buf, services, "linux", portable); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		f, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if f.ExportedFrom != "linux" || len(f.Services) != 2 {
+			t.Fatalf("Read() = %+v", f)
+		}
+		if f.Services[0].Name != "backup" || f.Services[0].Interval.Duration != 3600e9 || !f.Services[1].OnStartup {
+			t.Errorf("Read() services = %+v, %+v", f.Services[0].Service, f.Services[1].Service)
+		}
+		if got := f.Services[0].Requires; (got != nil) != portable {
+			t.Errorf("portable %v: Requires = %+v", portable, got)
+		} else if portable && !reflect.DeepEqual(got.OS, []string{"darwin", "linux"}) {
+			t.Errorf("Requires.OS = %q, want darwin and linux", got.OS)
+		}
+	}
+
+	if _, err := Read(strings.NewReader("services:\n  - command: x\n")); err == nil {
+		t.Errorf("Read() of a service without a name succeeded")
+	}
+}
@@ -0,0 +1,496 @@
+// Package gitsync keeps a config directory in step with a Git repository, so
+// several machines can share their services without a server.
+//
+// It works in a clone of the repository kept apart from the config (see
+// Options.Dir). Each sync copies the synced files of the config into the
+// clone and commits them if they changed, merges what other machines pushed
+// since the last sync, copies the result back into the config, and pushes it.
+// The clone's branch therefore always holds the config as it was after the
+// last sync, which is what changes on either side are measured against.
+//
+// Changes made to the same lines on both sides since the last sync can't be
+// merged; Sync then changes nothing and returns a *ConflictError, unless told
+// which side to prefer. A machine given its own branch (Options.Branch) still
+// merges the shared branch, but pushes only to its own, so its changes reach
+// the others once someone merges them.
+//
+// Git itself does the work, run as the git command, so the repository's
+// credentials come from the user's SSH agent or Git credential helper.
+package gitsync
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// Which side's changes win where both changed the same lines, see Options.Prefer.
+const (
+	PreferLocal  = "local"
+	PreferRemote = "remote"
+)
+
+// Options describe a sync.
+type Options struct {
+	Dir       string   // The clone of the repository, created on the first sync
+	URL       string   // The repository; empty to use the one the clone was made from
+	ConfigDir string   // The config directory synced with the repository
+	Paths     []string // The files and directories of ConfigDir that are synced
+	Branch    string   // The branch to push to; empty for the repository's default branch
+	Prefer    string   // PreferLocal or PreferRemote settles conflicts; empty fails on them
+	Message   string   // The message of the commit holding the local changes
+
+	// LocalEmpty means ConfigDir has nothing worth keeping yet, as on a new
+	// machine, so a first sync takes the repository's files instead of
+	// conflicting with them.
+	LocalEmpty bool
+
+	// Check is given the clone with the merged files before they are copied
+	// into ConfigDir; if it returns an error, the merge is undone.
+	Check func(dir string) error
+}
+
+// Result is what a sync did.
+type Result struct {
+	Branch   string   // The branch pushed to
+	Upstream string   // The branch merged from
+	Pulled   []string // Synced paths that changed in ConfigDir
+	Pushed   bool     // Whether there was anything to push
+}
+
+// ConflictError is returned when the same files changed on both sides.
+type ConflictError struct {
+	Files []string
+	First bool // The first sync found different files on both sides
+}
+
+func (e *ConflictError) Error() string {
+	if e.First {
+		return fmt.Sprintf("the repository and this machine have different versions of %s", strings.Join(e.Files, ", "))
+	}
+	return fmt.Sprintf("%s changed on this machine and in the repository in ways that can't be merged", strings.Join(e.Files, ", "))
+}
+
+// Sync syncs a config directory with a repository, see the package documentation.
+func Sync(opts Options) (*Result, error) {
+	if opts.Prefer != "" && opts.Prefer != PreferLocal && opts.Prefer != PreferRemote {
+		return nil, fmt.Errorf("invalid preference %q, use %s or %s", opts.Prefer, PreferLocal, PreferRemote)
+	}
+	r, cloned, err := open(opts.Dir, opts.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Upstream: r.defaultBranch()}
+	res.Branch = opts.Branch
+	if res.Branch == "" {
+		res.Branch = res.Upstream
+	}
+	created, err := r.checkout(res.Branch, res.Upstream)
+	if err != nil {
+		return nil, err
+	}
+	first := cloned || created
+	upstream := "origin/" + res.Upstream
+	hasUpstream := r.exists(upstream)
+
+	for _, path := range opts.Paths {
+		if err := replace(opts.ConfigDir, r.dir, path); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.git("add", "--all", "--", "."); err != nil {
+		return nil, err
+	}
+	changed, err := r.staged()
+	if err != nil {
+		return nil, err
+	}
+
+	before, _ := r.git("rev-parse", "--verify", "--quiet", "HEAD")
+	switch {
+	case first && hasUpstream && len(changed) > 0 && (opts.LocalEmpty || opts.Prefer == PreferRemote):
+		// Take the repository's files as they are
+		if _, err := r.git("reset", "--quiet", "--hard", upstream); err != nil {
+			return nil, err
+		}
+	case first && hasUpstream && len(changed) > 0 && opts.Prefer != PreferLocal && r.tracksAny(upstream, opts.Paths):
+		r.abandon(res.Branch)
+		return nil, &ConflictError{Files: changed, First: true}
+	default:
+		if len(changed) > 0 {
+			if err := r.commit(opts.Message); err != nil {
+				return nil, err
+			}
+		}
+		if hasUpstream {
+			if err := r.merge(upstream, opts.Prefer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.Check != nil {
+		if err := opts.Check(r.dir); err != nil {
+			if first {
+				r.abandon(res.Branch)
+			} else if before != "" {
+				// Keep the local commit, so the next sync tries again
+				r.git("reset", "--quiet", "--hard", before)
+			}
+			return nil, fmt.Errorf("the merged config is invalid, nothing was changed: %w", err)
+		}
+	}
+
+	for _, path := range opts.Paths {
+		same, err := sameTree(filepath.Join(r.dir, path), filepath.Join(opts.ConfigDir, path))
+		if err != nil {
+			return nil, err
+		}
+		if same {
+			continue
+		}
+		if err := replace(r.dir, opts.ConfigDir, path); err != nil {
+			return nil, err
+		}
+		res.Pulled = append(res.Pulled, path)
+	}
+
+	if !r.exists("HEAD") || r.sameCommit("HEAD", "origin/"+res.Branch) {
+		return res, nil
+	}
+	if _, err := r.git("push", "--quiet", "origin", "HEAD:refs/heads/"+res.Branch); err != nil {
+		return res, fmt.Errorf("pushing to %s: %w; sync again to merge what was pushed meanwhile", res.Branch, err)
+	}
+	res.Pushed = true
+	return res, nil
+}
+
+// repo is a clone of the synced repository.
+type repo struct {
+	dir string
+}
+
+// open clones url into dir, or opens the clone there, pointing it at url if
+// given, and fetches what other machines pushed. It reports whether it cloned.
+func open(dir, url string) (*repo, bool, error) {
+	r := &repo{dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if url == "" {
+			return nil, false, fmt.Errorf("no repository to sync with yet; give one with --repo")
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return nil, false, fmt.Errorf("creating sync directory: %w", err)
+		}
+		if _, err := r.run(filepath.Dir(dir), "clone", "--quiet", url, dir); err != nil {
+			os.RemoveAll(dir)
+			return nil, false, err
+		}
+		return r, true, nil
+	}
+
+	if url != "" {
+		if _, err := r.git("remote", "set-url", "origin", url); err != nil {
+			return nil, false, err
+		}
+	}
+	if _, err := r.git("fetch", "--quiet", "--prune", "origin"); err != nil {
+		return nil, false, err
+	}
+	return r, false, nil
+}
+
+// git runs a git command in the clone, returning its output.
+func (r *repo) git(args ...string) (string, error) {
+	return r.run(r.dir, args...)
+}
+
+func (r *repo) run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// Scheduled syncs have no terminal to ask for credentials on
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := trace.Output(cmd)
+	if err != nil {
+		name := args[0]
+		for i := 0; i+1 < len(args) && args[i] == "-c"; i += 2 {
+			name = args[i+2]
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", name, strings.TrimSpace(trace.Redact(string(exitErr.Stderr))))
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("syncing needs git, which isn't installed or isn't in PATH")
+		}
+		return "", fmt.Errorf("git %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// exists reports whether a revision, such as a branch, exists.
+func (r *repo) exists(rev string) bool {
+	_, err := r.git("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	return err == nil
+}
+
+// sameCommit reports whether two revisions exist and are the same commit.
+func (r *repo) sameCommit(a, b string) bool {
+	x, errX := r.git("rev-parse", "--verify", "--quiet", a)
+	y, errY := r.git("rev-parse", "--verify", "--quiet", b)
+	return errX == nil && errY == nil && x == y
+}
+
+// defaultBranch returns the branch the repository's HEAD points at, main for
+// an empty repository.
+func (r *repo) defaultBranch() string {
+	if ref, err := r.git("symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	// Cloned empty, or origin/HEAD was never set
+	if r.exists("origin/main") || !r.exists("origin/master") {
+		return "main"
+	}
+	return "master"
+}
+
+// checkout switches the clone to branch, creating it from the remote branch
+// of that name, or else from upstream, on the first sync to it. It reports
+// whether it is the first.
+func (r *repo) checkout(branch, upstream string) (bool, error) {
+	if current, err := r.git("symbolic-ref", "--short", "HEAD"); err == nil && current == branch && r.exists("HEAD") {
+		return false, nil
+	}
+	if r.exists("refs/heads/" + branch) {
+		_, err := r.git("checkout", "--quiet", branch)
+		return false, err
+	}
+	for _, start := range []string{"origin/" + branch, "origin/" + upstream} {
+		if r.exists(start) {
+			_, err := r.git("checkout", "--quiet", "--no-track", "-B", branch, start)
+			return true, err
+		}
+	}
+	// An empty repository: the first commit starts the branch
+	_, err := r.git("symbolic-ref", "HEAD", "refs/heads/"+branch)
+	return true, err
+}
+
+// abandon leaves the branch started by the first sync to it, so the next sync
+// is a first one again.
+func (r *repo) abandon(branch string) {
+	r.git("checkout", "--quiet", "--force", "--detach")
+	r.git("branch", "--quiet", "-D", branch)
+}
+
+// staged returns the paths whose changes are staged.
+func (r *repo) staged() ([]string, error) {
+	out, err := r.git("diff", "--cached", "--name-only", "--no-renames")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// tracksAny reports whether rev has any of paths.
+func (r *repo) tracksAny(rev string, paths []string) bool {
+	out, err := r.git(append([]string{"ls-tree", "--name-only", rev, "--"}, paths...)...)
+	return err == nil && out != ""
+}
+
+// commit commits the staged changes, as nazim on this machine if git has no
+// identity configured.
+func (r *repo) commit(message string) error {
+	_, err := r.git(append(r.identity(), "commit", "--quiet", "--no-verify", "-m", message)...)
+	return err
+}
+
+// identity returns the options making nazim on this machine the author of
+// commits when git has no identity configured.
+func (r *repo) identity() []string {
+	if email, _ := r.git("config", "user.email"); email != "" {
+		return nil
+	}
+	host, _ := os.Hostname()
+	return []string{"-c", "user.name=nazim", "-c", "user.email=nazim@" + host}
+}
+
+// merge merges upstream into the branch, settling conflicts with prefer. On a
+// conflict it leaves the branch as it was and returns a *ConflictError.
+func (r *repo) merge(upstream, prefer string) error {
+	if !r.exists("HEAD") {
+		_, err := r.git("reset", "--quiet", "--hard", upstream)
+		return err
+	}
+	args := append(r.identity(), "merge", "--quiet", "--no-edit", "--allow-unrelated-histories")
+	switch prefer {
+	case PreferLocal:
+		args = append(args, "-X", "ours")
+	case PreferRemote:
+		args = append(args, "-X", "theirs")
+	}
+	_, mergeErr := r.git(append(args, upstream)...)
+	if mergeErr == nil {
+		return nil
+	}
+	out, err := r.git("diff", "--name-only", "--diff-filter=U")
+	r.git("merge", "--abort")
+	if err != nil || out == "" {
+		return mergeErr
+	}
+	return &ConflictError{Files: strings.Split(out, "\n")}
+}
+
+// replace replaces path under dst with its copy under src, removing it from
+// dst if src has none. Files are replaced one by one, so a reader of dst never
+// finds one missing or half written.
+func replace(src, dst, path string) error {
+	from, to := filepath.Join(src, path), filepath.Join(dst, path)
+	if _, err := os.Lstat(from); os.IsNotExist(err) {
+		if err := os.RemoveAll(to); err != nil {
+			return fmt.Errorf("replacing %s: %w", to, err)
+		}
+		return nil
+	}
+
+	copied := make(map[string]bool)
+	err := filepath.Walk(from, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		switch {
+		case info.IsDir():
+			copied[target] = true
+			if existing, err := os.Lstat(target); err == nil && !existing.IsDir() {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			return os.MkdirAll(target, 0755)
+		case info.Mode().IsRegular():
+			copied[target] = true
+			return copyFile(p, target, info.Mode().Perm())
+		}
+		return nil // Links and such aren't synced
+	})
+	if err != nil {
+		return fmt.Errorf("replacing %s: %w", to, err)
+	}
+
+	// What dst has and src doesn't goes
+	err = filepath.Walk(to, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if copied[p] {
+			return nil
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("replacing %s: %w", to, err)
+	}
+	return nil
+}
+
+// copyFile copies from to to through a temporary file renamed over it. A file
+// already at to keeps its mode, as a config written by nazim does; only
+// whether it is executable, all Git records of a mode, comes from perm.
+func copyFile(from, to string, perm os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if info, err := os.Lstat(to); err == nil {
+		if info.IsDir() {
+			if err := os.RemoveAll(to); err != nil {
+				return err
+			}
+		} else {
+			mode := info.Mode().Perm()
+			if perm&0111 != 0 {
+				mode |= (mode & 0444) >> 2
+			} else {
+				mode &^= 0111
+			}
+			perm = mode
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(filepath.Dir(to), "."+filepath.Base(to)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Chmod(perm); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), to)
+}
+
+// sameTree reports whether two files, or directories of regular files, have
+// the same contents; two missing paths are the same.
+func sameTree(a, b string) (bool, error) {
+	files := func(root string) (map[string]string, error) {
+		m := make(map[string]string)
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) && p == root {
+				return nil
+			}
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, p)
+			m[rel] = string(data)
+			return nil
+		})
+		return m, err
+	}
+	x, err := files(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := files(b)
+	if err != nil || len(x) != len(y) {
+		return false, err
+	}
+	for name, data := range x {
+		if other, ok := y[name]; !ok || other != data {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,303 @@
+package gitsync
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// machine is a config directory synced with a repository, as on one machine.
+type machine struct {
+	t    *testing.T
+	opts Options
+}
+
+// newRepo creates an empty bare repository to sync with and returns its path.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("needs git")
+	}
+	// Commits are made as nazim on this machine, whatever the user's git config
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := filepath.Join(t.TempDir(), "config.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", "--initial-branch=main", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	return dir
+}
+
+func newMachine(t *testing.T, url string) *machine {
+	t.Helper()
+	base := t.TempDir()
+	return &machine{t: t, opts: Options{
+		Dir:       filepath.Join(base, "sync"),
+		URL:       url,
+		ConfigDir: filepath.Join(base, "config"),
+		Paths:     []string{"services.yaml", "scripts"},
+		Message:   "Sync services",
+	}}
+}
+
+func (m *machine) write(path, content string) {
+	m.t.Helper()
+	path = filepath.Join(m.opts.ConfigDir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		m.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		m.t.Fatal(err)
+	}
+}
+
+func (m *machine) read(path string) string {
+	m.t.Helper()
+	data, err := os.ReadFile(filepath.Join(m.opts.ConfigDir, path))
+	if err != nil && !os.IsNotExist(err) {
+		m.t.Fatal(err)
+	}
+	return string(data)
+}
+
+// sync syncs the machine, failing the test on an error.
+func (m *machine) sync() *Result {
+	m.t.Helper()
+	res, err := Sync(m.opts)
+	if err != nil {
+		m.t.Fatalf("Sync() error = %v", err)
+	}
+	return res
+}
+
+const (
+	base   = "services:\n  - name: backup\n    interval: 1h\n\n  - name: report\n    interval: 1d\n"
+	ours   = "services:\n  - name: backup\n    interval: 2h\n\n  - name: report\n    interval: 1d\n"
+	theirs = "services:\n  - name: backup\n    interval: 3h\n\n  - name: report\n    interval: 1d\n"
+)
+
+func TestSyncBetweenMachines(t *testing.T) {
+	url := newRepo(t)
+	a, b := newMachine(t, url), newMachine(t, url)
+
+	a.write("services.yaml", base)
+	a.write("scripts/backup.sh", "#!/bin/sh\n")
+	if res := a.sync(); !res.Pushed || res.Branch != "main" {
+		t.Errorf("first Sync() = %+v, want a push to main", res)
+	}
+
+	b.opts.LocalEmpty = true
+	res := b.sync()
+	if want := []string{"services.yaml", "scripts"}; !reflect.DeepEqual(res.Pulled, want) {
+		t.Errorf("Sync() on a new machine pulled %q, want %q", res.Pulled, want)
+	}
+	if got := b.read("services.yaml"); got != base {
+		t.Errorf("pulled services.yaml = %q, want %q", got, base)
+	}
+	if got := b.read("scripts/backup.sh"); got != "#!/bin/sh\n" {
+		t.Errorf("pulled script = %q", got)
+	}
+
+	b.opts.LocalEmpty = false
+	b.write("services.yaml", theirs)
+	if err := os.RemoveAll(filepath.Join(b.opts.ConfigDir, "scripts")); err != nil {
+		t.Fatal(err)
+	}
+	if res := b.sync(); !res.Pushed || len(res.Pulled) != 0 {
+		t.Errorf("Sync() of a change = %+v, want a push only", res)
+	}
+
+	res = a.sync()
+	if want := []string{"services.yaml", "scripts"}; !reflect.DeepEqual(res.Pulled, want) || res.Pushed {
+		t.Errorf("Sync() after the other machine's change = %+v, want %q pulled", res, want)
+	}
+	if got := a.read("services.yaml"); got != theirs {
+		t.Errorf("services.yaml = %q, want %q", got, theirs)
+	}
+	if _, err := os.Stat(filepath.Join(a.opts.ConfigDir, "scripts")); !os.IsNotExist(err) {
+		t.Errorf("scripts removed on the other machine still exist: %v", err)
+	}
+
+	if res := a.sync(); len(res.Pulled) != 0 || res.Pushed {
+		t.Errorf("Sync() with nothing changed = %+v", res)
+	}
+}
+
+func TestSyncMerge(t *testing.T) {
+	url := newRepo(t)
+	a, b := newMachine(t, url), newMachine(t, url)
+	a.write("services.yaml", base)
+	a.sync()
+	b.opts.LocalEmpty = true
+	b.sync()
+	b.opts.LocalEmpty = false
+
+	// Different lines changed on each side merge
+	b.write("services.yaml", strings.Replace(base, "1d", "7d", 1))
+	b.sync()
+	a.write("services.yaml", ours)
+	res := a.sync()
+	want := strings.Replace(ours, "1d", "7d", 1)
+	if !res.Pushed || !reflect.DeepEqual(res.Pulled, []string{"services.yaml"}) {
+		t.Errorf("Sync() = %+v, want the merge pulled and pushed", res)
+	}
+	if got := a.read("services.yaml"); got != want {
+		t.Errorf("merged services.yaml = %q, want %q", got, want)
+	}
+
+	b.sync()
+	if got := b.read("services.yaml"); got != want {
+		t.Errorf("services.yaml on the other machine = %q, want %q", got, want)
+	}
+}
+
+func TestSyncConflict(t *testing.T) {
+	tests := []struct {
+		prefer  string
+		want    string
+		wantErr bool
+	}{
+		{"", ours, true},
+		{PreferLocal, ours, false},
+		{PreferRemote, theirs, false},
+	}
+	for _, tt := range tests {
+		t.Run("prefer "+tt.prefer, func(t *testing.T) {
+			url := newRepo(t)
+			a, b := newMachine(t, url), newMachine(t, url)
+			a.write("services.yaml", base)
+			a.sync()
+			b.opts.LocalEmpty = true
+			b.sync()
+
+			// The same line changed on both sides
+			b.write("services.yaml", theirs)
+			b.sync()
+			a.write("services.yaml", ours)
+			a.opts.Prefer = tt.prefer
+			_, err := Sync(a.opts)
+
+			var conflict *ConflictError
+			if tt.wantErr {
+				if !errors.As(err, &conflict) || conflict.First || !reflect.DeepEqual(conflict.Files, []string{"services.yaml"}) {
+					t.Fatalf("Sync() error = %v, want a conflict on services.yaml", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+			if got := a.read("services.yaml"); got != tt.want {
+				t.Errorf("services.yaml = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncFirstConflict(t *testing.T) {
+	url := newRepo(t)
+	a, b := newMachine(t, url), newMachine(t, url)
+	a.write("services.yaml", ours)
+	a.sync()
+
+	// A machine that already has services of its own
+	b.write("services.yaml", theirs)
+	_, err := Sync(b.opts)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !conflict.First {
+		t.Fatalf("Sync() error = %v, want a first-sync conflict", err)
+	}
+	if got := b.read("services.yaml"); got != theirs {
+		t.Errorf("services.yaml after the conflict = %q, want it unchanged", got)
+	}
+
+	// Syncing again is still a first sync, settled by a preference
+	b.opts.Prefer = PreferRemote
+	res := b.sync()
+	if !reflect.DeepEqual(res.Pulled, []string{"services.yaml"}) {
+		t.Errorf("Sync() preferring the repository pulled %q", res.Pulled)
+	}
+	if got := b.read("services.yaml"); got != ours {
+		t.Errorf("services.yaml = %q, want %q", got, ours)
+	}
+}
+
+func TestSyncCheck(t *testing.T) {
+	url := newRepo(t)
+	a, b := newMachine(t, url), newMachine(t, url)
+	a.write("services.yaml", base)
+	a.sync()
+	b.opts.LocalEmpty = true
+	b.sync()
+	b.opts.LocalEmpty = false
+
+	a.write("services.yaml", theirs)
+	a.sync()
+	b.write("services.yaml", strings.Replace(base, "1d", "7d", 1))
+	b.opts.Check = func(dir string) error { return errors.New("invalid interval") }
+	if _, err := Sync(b.opts); err == nil || !strings.Contains(err.Error(), "invalid interval") {
+		t.Fatalf("Sync() error = %v, want the check's", err)
+	}
+	if got, want := b.read("services.yaml"), strings.Replace(base, "1d", "7d", 1); got != want {
+		t.Errorf("services.yaml after a failed check = %q, want %q", got, want)
+	}
+
+	// The local change is kept for the next sync
+	b.opts.Check = nil
+	b.sync()
+	if got, want := b.read("services.yaml"), strings.Replace(theirs, "1d", "7d", 1); got != want {
+		t.Errorf("services.yaml = %q, want %q", got, want)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for path, content := range map[string]string{"services.yaml": "new", "scripts/a.sh": "a"} {
+		if err := os.MkdirAll(filepath.Join(src, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dst, "scripts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{"services.yaml": "old", "scripts/gone.sh": "gone"} {
+		if err := os.WriteFile(filepath.Join(dst, path), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, path := range []string{"services.yaml", "scripts", "missing"} {
+		if err := replace(src, dst, path); err != nil {
+			t.Fatalf("replace(%s) error = %v", path, err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "services.yaml")); string(data) != "new" {
+		t.Errorf("services.yaml = %q, want new", data)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "services.yaml")); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("services.yaml mode = %v, want the 0600 it had", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "scripts", "a.sh")); string(data) != "a" {
+		t.Errorf("scripts/a.sh = %q, want a", data)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "scripts", "gone.sh")); !os.IsNotExist(err) {
+		t.Errorf("scripts/gone.sh, missing from src, still exists: %v", err)
+	}
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("dst holds %d entries, want services.yaml and scripts only", len(entries))
+	}
+}
@@ -0,0 +1,121 @@
+// Package history records the outcome of each service run.
+//
+// Records are appended as JSON lines to one file per service, so concurrent runs
+// of different services never contend for the same file.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record describes a single finished run.
+type Record struct {
+	ID         string    `json:"id"`
+	Service    string    `json:"service"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	ExitCode   int       `json:"exit_code"`
+	Error      string    `json:"error,omitempty"`       // Failure to start the command
+	ErrorLines []string  `json:"error_lines,omitempty"` // Last stderr lines of a failed run
+	Slow       bool      `json:"slow,omitempty"`        // The run took longer than the service's slow threshold
+
+	OutputError string `json:"output_error,omitempty"` // Why the output failed a run that exited with 0, under fail_pattern or success_pattern
+
+	Env *Environment `json:"env,omitempty"` // What the run saw of its environment, for services with debug_env
+}
+
+// Environment describes the surroundings a run's command ran in.
+type Environment struct {
+	User string            `json:"user"`
+	Dir  string            `json:"dir"`            // Working directory
+	Vars map[string]string `json:"vars,omitempty"` // Key environment variables that were set, by name
+}
+
+// Failed reports whether the run did not succeed.
+func (r *Record) Failed() bool {
+	return r.ExitCode != 0 || r.Error != "" || r.OutputError != ""
+}
+
+// Duration returns how long the run took.
+func (r *Record) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Path returns the history file for a service.
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+".jsonl")
+}
+
+// Append adds a record to the service's history.
+func Append(dir string, rec *Record) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshaling history record: %w", err)
+	}
+
+	f, err := os.OpenFile(Path(dir, rec.Service), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return nil
+}
+
+// Load returns all records of a service, oldest first.
+// A missing history file yields no records. Malformed lines, for example from
+// a run interrupted mid-write, are skipped.
+func Load(dir, name string) ([]*Record, error) {
+	f, err := os.Open(Path(dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening history: %w", err)
+	}
+	defer f.Close()
+
+	var records []*Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		records = append(records, &rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	return records, nil
+}
+
+// Last returns the most recent record of a service, or nil if it never ran.
+func Last(dir, name string) (*Record, error) {
+	records, err := Load(dir, name)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[len(records)-1], nil
+}
+
+// Remove deletes a service's history.
+func Remove(dir, name string) error {
+	if err := os.Remove(Path(dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Retention limits how much history is kept for each service.
+// A zero field sets no limit.
+type Retention struct {
+	MaxRuns int           // Keep at most this many of the most recent runs
+	MaxAge  time.Duration // Drop runs that started longer ago than this
+}
+
+// IsZero reports whether the retention keeps everything.
+func (r Retention) IsZero() bool {
+	return r.MaxRuns <= 0 && r.MaxAge <= 0
+}
+
+// Prune removes the records of a service that keep doesn't retain, and returns
+// how many were removed. The file is only rewritten when something is removed,
+// and it is replaced atomically so a crash never leaves it half-written.
+func Prune(dir, name string, keep Retention, now time.Time) (int, error) {
+	if keep.IsZero() {
+		return 0, nil
+	}
+	records, err := Load(dir, name)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := records
+	if keep.MaxAge > 0 {
+		cutoff := now.Add(-keep.MaxAge)
+		kept = kept[:0:0]
+		for _, rec := range records {
+			if !rec.Start.Before(cutoff) {
+				kept = append(kept, rec)
+			}
+		}
+	}
+	if keep.MaxRuns > 0 && len(kept) > keep.MaxRuns {
+		kept = kept[len(kept)-keep.MaxRuns:]
+	}
+
+	removed := len(records) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	if len(kept) == 0 {
+		return removed, Remove(dir, name)
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range kept {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return 0, fmt.Errorf("marshaling history record: %w", err)
+		}
+		buf.Write(append(data, '\n'))
+	}
+
+	path := Path(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return 0, fmt.Errorf("writing history: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("replacing history: %w", err)
+	}
+	return removed, nil
+}
+
+// Services returns the names of the services that have a history, sorted.
+// A missing history directory yields no names.
+func Services(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading history directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == ".jsonl" {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".jsonl"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
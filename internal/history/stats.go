@@ -0,0 +1,55 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats summarizes the durations of a set of runs.
+type Stats struct {
+	Count int
+	Min   time.Duration
+	Avg   time.Duration
+	Max   time.Duration
+	P95   time.Duration // 95% of runs took at most this long
+}
+
+// DurationStats computes duration statistics over records. Records of runs
+// that never started are left out, since their duration says nothing about the job.
+func DurationStats(records []*Record) Stats {
+	var durations []time.Duration
+	for _, rec := range records {
+		if rec.Error == "" {
+			durations = append(durations, rec.Duration())
+		}
+	}
+	if len(durations) == 0 {
+		return Stats{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	// Nearest-rank percentile
+	rank := int(math.Ceil(0.95*float64(len(durations)))) - 1
+	return Stats{
+		Count: len(durations),
+		Min:   durations[0],
+		Avg:   total / time.Duration(len(durations)),
+		Max:   durations[len(durations)-1],
+		P95:   durations[rank],
+	}
+}
+
+// ConsecutiveFailures returns how many of the most recent records, oldest
+// first as Load returns them, are failed runs in a row.
+func ConsecutiveFailures(records []*Record) int {
+	count := 0
+	for i := len(records) - 1; i >= 0 && records[i].Failed(); i-- {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,124 @@
+// Package i18n translates nazim's user-facing messages.
+//
+// Messages are written in English in the code and looked up by that text in
+// the catalog of the selected language; messages a catalog lacks are shown in
+// English. Errors are not translated, so they can be searched for and quoted
+// in bug reports as-is.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Supported languages.
+const (
+	English             = "en"
+	BrazilianPortuguese = "pt-BR"
+)
+
+// Languages lists the supported languages.
+var Languages = []string{English, BrazilianPortuguese}
+
+// catalogs maps each language other than English to its translations.
+var catalogs = map[string]map[string]string{
+	BrazilianPortuguese: ptBR,
+}
+
+var (
+	mu      sync.RWMutex
+	current = English
+)
+
+// SetLanguage selects the language messages are shown in. It accepts locale
+// names as found in LANG, such as pt_BR.UTF-8, as well as language tags.
+func SetLanguage(name string) error {
+	lang, ok := Parse(name)
+	if !ok {
+		return fmt.Errorf("unsupported language %q, use %s", name, strings.Join(Languages, " or "))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current = lang
+	return nil
+}
+
+// Language returns the selected language.
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Parse returns the supported language a locale name or language tag refers to.
+// Portuguese is shown in Brazilian Portuguese, the only variant with a catalog.
+func Parse(name string) (string, bool) {
+	// pt_BR.UTF-8@euro -> pt-br
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	name = strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+	base, _, _ := strings.Cut(name, "-")
+
+	switch {
+	case base == "en" || name == "c" || name == "posix":
+		return English, true
+	case base == "pt":
+		return BrazilianPortuguese, true
+	}
+	return "", false
+}
+
+// Detect returns the language of the user's locale: the first of LC_ALL,
+// LC_MESSAGES, and LANG that is set, or on Windows the display language. An
+// unsupported language is reported as English.
+func Detect() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			if lang, ok := Parse(value); ok {
+				return lang
+			}
+			return English
+		}
+	}
+	for _, name := range systemLanguages() {
+		if lang, ok := Parse(name); ok {
+			return lang
+		}
+	}
+	return English
+}
+
+// T returns the translation of msg in the selected language, or msg itself.
+func T(msg string) string {
+	if translated, ok := catalogs[Language()][msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Sprintf formats the translation of format.
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// yesAnswers are the answers taken as yes to a [y/N] question, besides y and yes.
+var yesAnswers = map[string][]string{
+	BrazilianPortuguese: {"s", "sim"},
+}
+
+// IsYes reports whether an answer to a [y/N] question means yes. English
+// answers are accepted in every language.
+func IsYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "y" || answer == "yes" {
+		return true
+	}
+	for _, yes := range yesAnswers[Language()] {
+		if answer == yes {
+			return true
+		}
+	}
+	return false
+}
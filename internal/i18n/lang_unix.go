@@ -0,0 +1,9 @@
+//go:build !windows
+
+package i18n
+
+// systemLanguages returns nothing: the locale environment variables are the
+// system's language.
+func systemLanguages() []string {
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package i18n
+
+import "golang.org/x/sys/windows"
+
+// systemLanguages returns the user's preferred display languages, as tags such as pt-BR.
+func systemLanguages() []string {
+	languages, err := windows.GetUserPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	if err != nil {
+		return nil
+	}
+	return languages
+}
@@ -0,0 +1,162 @@
+package i18n
+
+// ptBR translates messages into Brazilian Portuguese. Verbs such as %s must
+// stay in the same order as in the English message.
+var ptBR = map[string]string{
+	"Warning: ": "Aviso: ",
+
+	// Adding, changing, and removing services
+	"Service '%s' added to configuration.\n":   "Serviço '%s' adicionado à configuração.\n",
+	"Service '%s' installed successfully!\n":   "Serviço '%s' instalado com sucesso!\n",
+	"Service '%s' updated in configuration.\n": "Serviço '%s' atualizado na configuração.\n",
+	"Service '%s' updated successfully!\n":     "Serviço '%s' atualizado com sucesso!\n",
+	"Service '%s' removed successfully!\n":     "Serviço '%s' removido com sucesso!\n",
+	"Service '%s' enabled successfully!\n":     "Serviço '%s' ativado com sucesso!\n",
+	"Service '%s' disabled successfully!\n":    "Serviço '%s' desativado com sucesso!\n",
+	"Removed script file: %s\n":                "Script removido: %s\n",
+	"Removed log file: %s\n":                   "Log removido: %s\n",
+	"No services found.":                       "Nenhum serviço encontrado.",
+	"No enabled services found.":               "Nenhum serviço ativado encontrado.",
+	"WSL stops systemd and its timers when it shuts down; to run '%s' whether WSL is up or not, schedule it on Windows with: nazim edit %s --target windows-host\n": "O WSL para o systemd e seus timers quando é desligado; para executar '%s' com o WSL ligado ou não, agende-o no Windows com: nazim edit %s --target windows-host\n",
+
+	"failed to uninstall from system: %v":                    "falha ao desinstalar do sistema: %v",
+	"failed to uninstall old service: %v":                    "falha ao desinstalar o serviço antigo: %v",
+	"failed to remove script file: %v":                       "falha ao remover o script: %v",
+	"failed to remove log file: %v":                          "falha ao remover o log: %v",
+	"failed to log removal: %v":                              "falha ao registrar a remoção: %v",
+	"failed to write removal log: %v":                        "falha ao gravar o registro da remoção: %v",
+	"service '%s' not found in system, skipping...":          "serviço '%s' não encontrado no sistema, ignorando...",
+	"%s is outside the config directory and is not portable": "%s está fora do diretório de configuração e não é portátil",
+	"on_failure service '%s' does not exist yet":             "o serviço '%s' de on_failure ainda não existe",
+	"failed to query next run of '%s': %v":                   "falha ao consultar a próxima execução de '%s': %v",
+	"failed to query next run: %v":                           "falha ao consultar a próxima execução: %v",
+	"failed to query scheduler: %v":                          "falha ao consultar o agendador: %v",
+	"failed to read run history: %v":                         "falha ao ler o histórico de execuções: %v",
+	"Script already exists at %s, using it\n":                "O script já existe em %s, usando-o\n",
+	"Opening editor: %s\n":                                   "Abrindo o editor: %s\n",
+	"Script will be saved to: %s\n":                          "O script será salvo em: %s\n",
+	"Script created successfully: %s\n":                      "Script criado com sucesso: %s\n",
+	"No logs found.":                                         "Nenhum log encontrado.",
+	"No logs found for service '%s'.\n":                      "Nenhum log encontrado para o serviço '%s'.\n",
+	"Service '%s' is disabled. Run it anyway? [y/N]: ":       "O serviço '%s' está desativado. Executar mesmo assim? [s/N]: ",
+	"Running disabled service '%s' in the foreground...\n":   "Executando o serviço desativado '%s' em primeiro plano...\n",
+	"Service '%s' finished.\n":                               "Serviço '%s' concluído.\n",
+	"Service '%s' is now running!\n":                         "Serviço '%s' em execução!\n",
+	"run exceeded the slow threshold of %s":                  "a execução passou do limite de lentidão de %s",
+	"service '%s' took %s, over its slow threshold of %s":    "o serviço '%s' levou %s, acima do limite de lentidão de %s",
+	"singleton lock: %v":                                     "trava singleton: %v",
+	"overlap guard: %v":                                      "trava contra sobreposição: %v",
+	"failed to record run: %v":                               "falha ao registrar a execução: %v",
+	"failed to prune run history: %v":                        "falha ao limpar o histórico de execuções: %v",
+	"failed to save snapshot of watched files: %v":           "falha ao salvar o retrato dos arquivos observados: %v",
+	"failed to save state of '%s': %v":                       "falha ao salvar o estado de '%s': %v",
+	"failed to show desktop notification: %v":                "falha ao mostrar a notificação na área de trabalho: %v",
+	"failed to upload output of '%s': %v":                    "falha ao enviar a saída de '%s': %v",
+	"failed to write to the event log: %v":                   "falha ao gravar no log de eventos: %v",
+	"failed to record event: %v":                             "falha ao registrar o evento: %v",
+	"No events recorded.":                                    "Nenhum evento registrado.",
+	"on_failure: failed to create platform manager: %v":      "on_failure: falha ao criar o gerenciador da plataforma: %v",
+	"on_failure: service '%s' does not exist":                "on_failure: o serviço '%s' não existe",
+	"on_failure: failed to start '%s': %v":                   "on_failure: falha ao iniciar '%s': %v",
+	"on_failure: failed to disable '%s': %v":                 "on_failure: falha ao desativar '%s': %v",
+	"on_failure: cannot disable '%s' without administrator privileges; install it with --elevated": "on_failure: não é possível desativar '%s' sem privilégios de administrador; instale-o com --elevated",
+	"failed to report run of '%s' to the telemetry collector: %v":                                  "falha ao reportar a execução de '%s' ao coletor de telemetria: %v",
+
+	// Backup and restore
+	"Backed up %d service(s) to %s\n":                             "%d serviço(s) salvo(s) em %s\n",
+	"Restored service '%s'\n":                                     "Serviço '%s' restaurado\n",
+	"Restored %d service(s) from %s\n":                            "%d serviço(s) restaurado(s) de %s\n",
+	"Remapped %s -> %s\n":                                         "Caminho remapeado: %s -> %s\n",
+	"failed to uninstall '%s': %v":                                "falha ao desinstalar '%s': %v",
+	"failed to install '%s': %v":                                  "falha ao instalar '%s': %v",
+	"failed to disable '%s': %v":                                  "falha ao desativar '%s': %v",
+	"service '%s' is invalid: %v":                                 "o serviço '%s' é inválido: %v",
+	"service '%s': %s":                                            "serviço '%s': %s",
+	"%v; skipping it (use --force-platform to install it anyway)": "%v; ignorando-o (use --force-platform para instalá-lo mesmo assim)",
+	"backup was made on %s; services are reinstalled for %s and may need adjusting": "o backup foi feito em %s; os serviços são reinstalados para %s e podem precisar de ajustes",
+
+	// Signed bundles
+	"bundle %s is not signed":                                       "o pacote %s não está assinado",
+	"bundle %s can't be verified: %v":                               "o pacote %s não pode ser verificado: %v",
+	"failed to remove '%s' from the config: %v":                     "falha ao remover '%s' da configuração: %v",
+	"Skipping '%s': %v\n":                                           "Ignorando '%s': %v\n",
+	"Applied '%s'\n":                                                "'%s' aplicado\n",
+	"Applied %s: %d added, %d updated, %d removed, %d unchanged.\n": "%s aplicado: %d adicionado(s), %d atualizado(s), %d removido(s), %d sem mudanças.\n",
+
+	// Templates
+	"Generated '%s'\n": "'%s' gerado\n",
+	"Generated %s: %d added, %d updated, %d unchanged.\n": "%s gerado: %d adicionado(s), %d atualizado(s), %d sem mudanças.\n",
+
+	// Config sync
+	"Pushed this machine's changes to %s.\n":                         "Mudanças desta máquina enviadas para %s.\n",
+	"Config is already in sync.":                                     "A configuração já está sincronizada.",
+	"Pulled changes: %d service(s) added, %d updated, %d removed.\n": "Mudanças baixadas: %d serviço(s) adicionado(s), %d atualizado(s), %d removido(s).\n",
+	"Cleared the failures of '%s'; it runs on schedule again.\n":     "Falhas de '%s' zeradas; ele volta a rodar no horário.\n",
+	"Service '%s' stopped.\n":                                        "Serviço '%s' parado.\n",
+
+	// Imports and adopt
+	"No jobs found.":    "Nenhuma tarefa encontrada.",
+	"Nothing imported.": "Nada foi importado.",
+	"\nUse --select all or --select <numbers> to import entries.":   "\nUse --select all ou --select <números> para importar entradas.",
+	"Import which entries? [all, none, or numbers such as 1,3-4]: ": "Importar quais entradas? [all, none ou números como 1,3-4]: ",
+	"Imported '%s'\n":               "'%s' importado\n",
+	"Imported %d job(s).\n":         "%d tarefa(s) importada(s).\n",
+	"skipping #%d: %s":              "ignorando #%d: %s",
+	"failed to import #%d '%s': %v": "falha ao importar #%d '%s': %v",
+	"'%s' was imported, but %v":     "'%s' foi importado, mas %v",
+	"The original tasks were left in place; disable them or import with --take-over to avoid duplicate runs.": "As tarefas originais foram mantidas; desative-as ou importe com --take-over para evitar execuções duplicadas.",
+	"Skipped %d definition(s) of services already in %s.\n":                                                   "%d definição(ões) de serviços que já estão em %s ignorada(s).\n",
+	"Exported %d service(s) to %s\n":                                                                          "%d serviço(s) exportado(s) para %s\n",
+	"Exported %d file(s) to %s\n":                                                                             "%d arquivo(s) exportado(s) para %s\n",
+	"Install them once edited with: nazim edit %s --from-unit %s\n":                                           "Depois de editá-los, instale-os com: nazim edit %s --from-unit %s\n",
+	"Services exported from %s; checking them against %s.\n\n":                                                "Serviços exportados de %s; verificando-os para %s.\n\n",
+	"No definitions of unknown services found.":                                                               "Nenhuma definição de serviço desconhecido encontrada.",
+	"Staggered %d service(s) across their intervals.\n":                                                       "%d serviço(s) escalonado(s) ao longo de seus intervalos.\n",
+	"Staggered '%s': %s\n":                                                                                    "'%s' escalonado: %s\n",
+
+	// Integrity checks
+	"failed to record checksums: %v":          "falha ao registrar as somas de verificação: %v",
+	"failed to record version of '%s': %v":    "falha ao registrar a versão de '%s': %v",
+	"failed to uninstall current version: %v": "falha ao desinstalar a versão atual: %v",
+	"Service '%s' rolled back to %s.\n":       "Serviço '%s' voltou para %s.\n",
+	"The scheduler definitions generated now differ from those of %s; --pin installs them as they were.\n": "As definições do agendador geradas agora diferem das de %s; --pin as instala como eram.\n",
+	"No versions of '%s' are kept yet; one is recorded whenever it is installed.\n":                        "Nenhuma versão de '%s' foi guardada ainda; uma é registrada sempre que ele é instalado.\n",
+	"failed to read checksums: %v":                           "falha ao ler as somas de verificação: %v",
+	"Recorded the files of '%s'\n":                           "Arquivos de '%s' registrados\n",
+	"Recorded the files of %d service(s) as they are now.\n": "Arquivos de %d serviço(s) registrados como estão agora.\n",
+	"No installed services found.":                           "Nenhum serviço instalado encontrado.",
+
+	// Lingering
+	"the scheduler has no time zones, so '%s' is scheduled as %s local time; once daylight saving time changes the difference from %s, run 'nazim edit %s' to schedule it again": "o agendador não tem fusos horários, então '%s' foi agendado como %s no horário local; quando o horário de verão mudar a diferença para %s, execute 'nazim edit %s' para agendá-lo de novo",
+	"Couldn't check lingering: %v\n":                                               "Não foi possível verificar o lingering: %v\n",
+	"Lingering enabled: services now run from boot, and while you are logged out.": "Lingering ativado: os serviços agora rodam desde o boot e enquanto você está desconectado.",
+
+	"lingering is off for %s, so services only run while %s is logged in; run 'nazim doctor --enable-linger' (or 'sudo loginctl enable-linger %s') to run them from boot and after logout": "o lingering está desligado para %s, então os serviços só rodam enquanto %s está conectado; execute 'nazim doctor --enable-linger' (ou 'sudo loginctl enable-linger %s') para rodá-los desde o boot e após a saída",
+
+	// Versions and deprecations
+	"Couldn't check for updates: %v\n":                                                      "Não foi possível verificar se há atualizações: %v\n",
+	"--%s is deprecated and will be removed in a future release; use %s instead":            "--%s está obsoleto e será removido em uma versão futura; use %s no lugar",
+	"%s (used by %s) is deprecated and will be removed in a future release; use %s instead": "%s (usado por %s) está obsoleto e será removido em uma versão futura; use %s no lugar",
+
+	// Purge
+	"Nothing to purge.":                                                "Nada a remover.",
+	"Services to uninstall: %s\n":                                      "Serviços a desinstalar: %s\n",
+	"Files to delete:":                                                 "Arquivos a apagar:",
+	"The config in %s is kept.\n":                                      "A configuração em %s é mantida.\n",
+	"Remove all of this? [y/N]: ":                                      "Remover tudo isso? [s/N]: ",
+	"Kept %s, which holds files nazim didn't create.\n":                "%s foi mantido, pois contém arquivos que o nazim não criou.\n",
+	"Removed %d service(s) and %d file(s) or folder(s).\n":             "%d serviço(s) e %d arquivo(s) ou pasta(s) removido(s).\n",
+	"nazim itself is still at %s; delete it to finish uninstalling.\n": "O próprio nazim continua em %s; apague-o para concluir a desinstalação.\n",
+
+	// Run history
+	"Removed %d run(s) of '%s'\n":                            "%d execução(ões) de '%s' removida(s)\n",
+	"Removed %d run(s) from the history of %d service(s).\n": "%d execução(ões) removida(s) do histórico de %d serviço(s).\n",
+	"Exported %d run(s) to %s\n":                             "%d execução(ões) exportada(s) para %s\n",
+
+	// Config encryption
+	"Encrypted %s with a key kept in the OS keychain.\n": "%s criptografado com uma chave guardada no chaveiro do sistema.\n",
+	"Encrypted %s with the passphrase from %s.\n":        "%s criptografado com a senha de %s.\n",
+	"Encrypted %s with a passphrase.\n":                  "%s criptografado com uma senha.\n",
+	"Decrypted %s.\n":                                    "%s descriptografado.\n",
+	"scheduled runs cannot ask for the passphrase and will fail; encrypt the config with --passphrase-from or --keychain instead": "execuções agendadas não podem pedir a senha e vão falhar; criptografe a configuração com --passphrase-from ou --keychain",
+}
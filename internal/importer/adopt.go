@@ -0,0 +1,688 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/schedule"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Definition is a scheduler definition installed by nazim, read back into a
+// service so it can be adopted into a config.
+type Definition struct {
+	Source  string           // The unit file, plist, or task the definition was read from
+	Service *service.Service // Name, triggers, and settings; Command is empty when Exec is set
+
+	Exec      bool   // The definition runs `nazim exec`, which loads the command from a config
+	ConfigDir string // The config directory given to `nazim exec`, if any
+	DataDir   string // The data directory given to `nazim exec`, if any
+	Wrapper   string // The PowerShell wrapper a task runs; its content holds the command line
+
+	Problem string // Why the definition can't be read back
+}
+
+// applyCommandLine fills in a definition from the command line it runs: either
+// `nazim exec [--config-dir DIR] [--data-dir DIR] [--state-dir DIR] [--catch-up]
+// [--repeat] NAME`, or the service's own command for definitions written by
+// versions before `nazim exec`.
+func (d *Definition) applyCommandLine(argv []string) {
+	if len(argv) == 0 {
+		d.Problem = "definition runs no command"
+		return
+	}
+	if len(argv) >= 3 && argv[1] == "exec" {
+		var configDir, dataDir string
+		var catchUp bool
+		rest := argv[2:]
+	flags:
+		for len(rest) > 1 {
+			switch rest[0] {
+			case "--config-dir":
+				configDir = rest[1]
+				rest = rest[2:]
+			case "--data-dir":
+				dataDir = rest[1]
+				rest = rest[2:]
+			case "--state-dir":
+				rest = rest[2:]
+			case "--catch-up":
+				catchUp = true
+				rest = rest[1:]
+			case "--repeat":
+				// The sub-minute interval itself is only in the config
+				rest = rest[1:]
+			default:
+				break flags
+			}
+		}
+		if len(rest) == 1 && !strings.HasPrefix(rest[0], "-") {
+			d.Exec = true
+			d.ConfigDir = configDir
+			d.DataDir = dataDir
+			d.Service.Name = rest[0]
+			if catchUp {
+				d.Service.CatchUp = true
+			}
+			return
+		}
+	}
+	d.Service.Command = argv[0]
+	d.Service.Args = argv[1:]
+}
+
+// ParseSystemdUnits reads back the service unit nazim installed, and its timer
+// unit if the service is scheduled (nil otherwise).
+func ParseSystemdUnits(source string, unit, timer []byte) *Definition {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(source), "nazim-"), ".service")
+	d := &Definition{Source: source, Service: &service.Service{Name: name, Enabled: true}}
+	svc := d.Service
+
+	keys := parseUnitFile(unit)
+	if desc, ok := strings.CutPrefix(keys["Description"], "Nazim Service: "); ok {
+		// Service names can't contain a colon, so one ends the name
+		svc.Name, svc.Description, _ = strings.Cut(desc, ": ")
+	}
+	argv, err := splitSystemdArgs(keys["ExecStart"])
+	if err != nil {
+		d.Problem = err.Error()
+		return d
+	}
+	d.applyCommandLine(argv)
+	svc.WorkDir = keys["WorkingDirectory"]
+	if keys["Nice"] == "10" {
+		svc.Priority = service.PriorityLow
+	}
+	if mask, err := service.ParseUmask(keys["UMask"]); keys["UMask"] != "" && err == nil {
+		svc.Umask = service.FormatUmask(mask)
+	}
+	if keys["PrivateNetwork"] == "yes" {
+		svc.Network = service.NetworkNone
+	}
+	switch keys["ProtectSystem"] {
+	case "full":
+		svc.Sandbox = service.SandboxBasic
+	case "strict":
+		svc.Sandbox = service.SandboxStrict
+	}
+
+	if timer == nil {
+		if keys["WantedBy"] == "" {
+			d.Problem = "unit has no trigger"
+			return d
+		}
+		// Startup and logon services both start with the user manager
+		svc.OnStartup = true
+		if pre, err := splitSystemdArgs(keys["ExecStartPre"]); err == nil && len(pre) == 2 && filepath.Base(pre[0]) == "sleep" {
+			if seconds, err := strconv.Atoi(pre[1]); err == nil {
+				setStartupDelay(svc, time.Duration(seconds)*time.Second)
+			}
+		}
+		return d
+	}
+
+	timerKeys := parseUnitFile(timer)
+	switch {
+	case timerKeys["OnCalendar"] != "" && strings.Contains(timerKeys["OnCalendar"], "/"):
+		interval, offset, err := parseAlignedCalendar(timerKeys["OnCalendar"])
+		if err != nil {
+			d.Problem = err.Error()
+			return d
+		}
+		svc.Interval = service.Duration{Duration: interval}
+		svc.Offset = service.FormatOffset(offset)
+		if timerKeys["Persistent"] == "true" {
+			svc.CatchUp = true
+		}
+	case timerKeys["OnCalendar"] != "":
+		sched, err := parseOnCalendar(timerKeys["OnCalendar"])
+		if err != nil {
+			d.Problem = err.Error()
+			return d
+		}
+		svc.Schedule = sched.String()
+		if sched.Location != nil {
+			svc.Timezone = sched.Location.String()
+		}
+		if timerKeys["Persistent"] == "true" {
+			svc.CatchUp = true
+		}
+	case timerKeys["OnUnitActiveSec"] != "":
+		interval, err := time.ParseDuration(timerKeys["OnUnitActiveSec"])
+		if err != nil {
+			d.Problem = fmt.Sprintf("invalid interval %q", timerKeys["OnUnitActiveSec"])
+			return d
+		}
+		svc.Interval = service.Duration{Duration: interval}
+		if value := timerKeys["OnStartupSec"]; value != "" {
+			// Startup and logon services that also run at an interval
+			svc.OnStartup = true
+			if delay, err := time.ParseDuration(value); err == nil {
+				setStartupDelay(svc, delay)
+			}
+		}
+	default:
+		d.Problem = "timer has no supported trigger"
+	}
+	return d
+}
+
+// setStartupDelay sets the startup delay that, with the stagger offset of the
+// service's priority, adds up to the total delay a definition waits.
+func setStartupDelay(svc *service.Service, total time.Duration) {
+	svc.StartupDelay = service.Duration{}
+	offset := svc.EffectiveStartupDelay()
+	if total < offset {
+		// The delay doesn't fit the priority class; keep the delay rather than the class
+		svc.Priority = ""
+		offset = svc.EffectiveStartupDelay()
+	}
+	svc.StartupDelay = service.Duration{Duration: total - offset}
+}
+
+// parseUnitFile returns the Key=Value settings of a unit file; section names
+// are dropped, as the keys nazim writes are unique across sections.
+func parseUnitFile(data []byte) map[string]string {
+	keys := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '[' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			keys[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return keys
+}
+
+// splitSystemdArgs splits a command line as systemd does for ExecStart=, undoing
+// the quoting and C-style escapes nazim writes.
+func splitSystemdArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			switch r {
+			case 'n':
+				current.WriteByte('\n')
+			case 'r':
+				current.WriteByte('\r')
+			case 't':
+				current.WriteByte('\t')
+			default:
+				current.WriteRune(r)
+			}
+			escaped = false
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// parseOnCalendar parses the OnCalendar expressions nazim writes, such as
+// "*-*-* 03:30:00" or "Mon,Fri *-*-* 09:00:00 Europe/Lisbon".
+func parseOnCalendar(s string) (*schedule.Schedule, error) {
+	fields := strings.Fields(s)
+	var loc *time.Location
+	if n := len(fields); n > 2 && !strings.Contains(fields[n-1], ":") {
+		var err error
+		if loc, err = time.LoadLocation(fields[n-1]); err != nil {
+			return nil, fmt.Errorf("unsupported calendar expression %q", s)
+		}
+		fields = fields[:n-1]
+	}
+	if len(fields) < 2 || len(fields) > 3 || fields[len(fields)-2] != "*-*-*" {
+		return nil, fmt.Errorf("unsupported calendar expression %q", s)
+	}
+	clock := strings.Split(fields[len(fields)-1], ":")
+	h, errH := strconv.Atoi(clock[0])
+	var m int
+	var errM error
+	if len(clock) > 1 {
+		m, errM = strconv.Atoi(clock[1])
+	}
+	if errH != nil || errM != nil {
+		return nil, fmt.Errorf("unsupported calendar expression %q", s)
+	}
+
+	sched := &schedule.Schedule{Hour: h, Minute: m, Location: loc}
+	if len(fields) == 3 {
+		for _, name := range strings.Split(fields[0], ",") {
+			day, ok := systemdDayNames[name]
+			if !ok {
+				return nil, fmt.Errorf("unsupported calendar expression %q", s)
+			}
+			sched.Days = append(sched.Days, day)
+		}
+	}
+	return sched, nil
+}
+
+// parseAlignedCalendar parses the OnCalendar expressions nazim writes for an
+// interval with an offset, "*-*-* *:MM/I:00" or "*-*-* HH/H:MM:00", into the
+// interval and offset.
+func parseAlignedCalendar(s string) (interval, offset time.Duration, err error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 || fields[0] != "*-*-*" {
+		return 0, 0, fmt.Errorf("unsupported calendar expression %q", s)
+	}
+	clock := strings.Split(fields[1], ":")
+	if len(clock) != 3 {
+		return 0, 0, fmt.Errorf("unsupported calendar expression %q", s)
+	}
+	var start, step, minute int
+	var errs [3]error
+	if clock[0] == "*" {
+		// Every I minutes from minute MM of each hour
+		first, repeat, _ := strings.Cut(clock[1], "/")
+		start, errs[0] = strconv.Atoi(first)
+		step, errs[1] = strconv.Atoi(repeat)
+		interval, offset = time.Duration(step)*time.Minute, time.Duration(start)*time.Minute
+	} else {
+		first, repeat, _ := strings.Cut(clock[0], "/")
+		start, errs[0] = strconv.Atoi(first)
+		step, errs[1] = strconv.Atoi(repeat)
+		minute, errs[2] = strconv.Atoi(clock[1])
+		interval = time.Duration(step) * time.Hour
+		offset = time.Duration(start)*time.Hour + time.Duration(minute)*time.Minute
+	}
+	for _, err := range errs {
+		if err != nil {
+			return 0, 0, fmt.Errorf("unsupported calendar expression %q", s)
+		}
+	}
+	if !service.Alignable(interval) || offset >= interval {
+		return 0, 0, fmt.Errorf("unsupported calendar expression %q", s)
+	}
+	return interval, offset, nil
+}
+
+var systemdDayNames = map[string]time.Weekday{
+	"Sun": time.Sunday, "Mon": time.Monday, "Tue": time.Tuesday, "Wed": time.Wednesday,
+	"Thu": time.Thursday, "Fri": time.Friday, "Sat": time.Saturday,
+}
+
+// plistValue is a value of a property list: a string, integer, or boolean
+// (held as text), an array, or a dict.
+type plistValue struct {
+	kind  string
+	text  string
+	items []plistValue
+	dict  map[string]plistValue
+}
+
+// ParseLaunchdPlist reads back the launchd agent plist nazim installed.
+func ParseLaunchdPlist(source string, data []byte) *Definition {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(source), "com.nazim."), ".plist")
+	d := &Definition{Source: source, Service: &service.Service{Name: name, Enabled: true}}
+	svc := d.Service
+
+	root, err := decodePlist(data)
+	if err != nil {
+		d.Problem = err.Error()
+		return d
+	}
+	keys := root.dict
+
+	var argv []string
+	for _, item := range keys["ProgramArguments"].items {
+		argv = append(argv, item.text)
+	}
+	// Startup agents with a delay sleep in a shell first: /bin/sh -c 'sleep N; exec "$0" "$@"' ...
+	var delay time.Duration
+	if len(argv) > 3 && argv[1] == "-c" && strings.HasPrefix(argv[2], "sleep ") {
+		seconds, _ := strconv.Atoi(strings.TrimSuffix(strings.Fields(argv[2])[1], ";"))
+		delay = time.Duration(seconds) * time.Second
+		argv = argv[3:]
+	}
+	d.applyCommandLine(argv)
+	svc.WorkDir = keys["WorkingDirectory"].text
+
+	switch keys["ProcessType"].text {
+	case "Interactive":
+		svc.Priority = service.PriorityHigh
+	case "Background":
+		svc.Priority = service.PriorityLow
+	}
+	if value, ok := keys["Umask"]; ok {
+		if mask, err := strconv.Atoi(value.text); err == nil && mask >= 0 && mask <= 0777 {
+			svc.Umask = service.FormatUmask(os.FileMode(mask))
+		}
+	}
+
+	if value, ok := keys["StartInterval"]; ok {
+		seconds, err := strconv.Atoi(value.text)
+		if err != nil {
+			d.Problem = fmt.Sprintf("invalid StartInterval %q", value.text)
+			return d
+		}
+		svc.Interval = service.Duration{Duration: time.Duration(seconds) * time.Second}
+	}
+	if value, ok := keys["StartCalendarInterval"]; ok {
+		if interval, offset, err := parseAlignedCalendarInterval(value); err == nil {
+			svc.Interval = service.Duration{Duration: interval}
+			svc.Offset = service.FormatOffset(offset)
+		} else {
+			sched, err := parseCalendarInterval(value)
+			if err != nil {
+				d.Problem = err.Error()
+				return d
+			}
+			svc.Schedule = sched.String()
+		}
+	}
+
+	// Catch-up agents also run at load; for others running at load is a trigger
+	if keys["RunAtLoad"].kind == "true" && !svc.CatchUp {
+		svc.OnStartup = true
+		if !svc.RequiresScheduling() {
+			setStartupDelay(svc, delay)
+		}
+	}
+	if !svc.RequiresScheduling() && !svc.OnStartup {
+		d.Problem = "agent has no trigger"
+	}
+	return d
+}
+
+// parseCalendarInterval converts a StartCalendarInterval value, a dict or an
+// array of dicts with Hour, Minute, and optionally Weekday, to a schedule.
+func parseCalendarInterval(value plistValue) (*schedule.Schedule, error) {
+	entries := value.items
+	if value.kind == "dict" {
+		entries = []plistValue{value}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("empty StartCalendarInterval")
+	}
+
+	sched := &schedule.Schedule{Hour: -1}
+	for _, entry := range entries {
+		h, errH := strconv.Atoi(entry.dict["Hour"].text)
+		m, errM := strconv.Atoi(entry.dict["Minute"].text)
+		if errH != nil || errM != nil || (sched.Hour >= 0 && (h != sched.Hour || m != sched.Minute)) {
+			return nil, fmt.Errorf("unsupported StartCalendarInterval")
+		}
+		sched.Hour, sched.Minute = h, m
+		if weekday, ok := entry.dict["Weekday"]; ok {
+			day, err := strconv.Atoi(weekday.text)
+			if err != nil || day < 0 || day > 7 {
+				return nil, fmt.Errorf("unsupported StartCalendarInterval")
+			}
+			sched.Days = append(sched.Days, time.Weekday(day%7))
+		}
+	}
+	return sched, nil
+}
+
+// parseAlignedCalendarInterval converts the StartCalendarInterval array nazim
+// writes for an interval with an offset, one dict per run with a Minute and,
+// for intervals over an hour, an Hour, to the interval and offset. A single
+// run a day is left to parseCalendarInterval, as a daily schedule.
+func parseAlignedCalendarInterval(value plistValue) (interval, offset time.Duration, err error) {
+	unsupported := fmt.Errorf("unsupported StartCalendarInterval")
+	if value.kind != "array" || len(value.items) == 0 {
+		return 0, 0, unsupported
+	}
+	_, hasHour := value.items[0].dict["Hour"]
+	if hasHour && len(value.items) < 2 {
+		return 0, 0, unsupported
+	}
+
+	times := make([]time.Duration, len(value.items))
+	for i, entry := range value.items {
+		if _, ok := entry.dict["Weekday"]; ok {
+			return 0, 0, unsupported
+		}
+		m, err := strconv.Atoi(entry.dict["Minute"].text)
+		if err != nil {
+			return 0, 0, unsupported
+		}
+		times[i] = time.Duration(m) * time.Minute
+		hour, ok := entry.dict["Hour"]
+		if ok != hasHour {
+			return 0, 0, unsupported
+		}
+		if ok {
+			h, err := strconv.Atoi(hour.text)
+			if err != nil {
+				return 0, 0, unsupported
+			}
+			times[i] += time.Duration(h) * time.Hour
+		}
+	}
+
+	period := time.Hour
+	if hasHour {
+		period = 24 * time.Hour
+	}
+	interval = period / time.Duration(len(times))
+	for i, t := range times {
+		if t != times[0]+time.Duration(i)*interval {
+			return 0, 0, unsupported
+		}
+	}
+	if !service.Alignable(interval) || times[0] >= interval {
+		return 0, 0, unsupported
+	}
+	return interval, times[0], nil
+}
+
+// decodePlist decodes an XML property list into its top-level value.
+func decodePlist(data []byte) (plistValue, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return plistValue{}, fmt.Errorf("parsing plist: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			value, err := decodePlistValue(decoder, start)
+			if err != nil {
+				return plistValue{}, fmt.Errorf("parsing plist: %w", err)
+			}
+			return value, nil
+		}
+	}
+}
+
+// decodePlistValue decodes the value that start opens.
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (plistValue, error) {
+	value := plistValue{kind: start.Name.Local}
+	switch value.kind {
+	case "array", "dict":
+		if value.kind == "dict" {
+			value.dict = make(map[string]plistValue)
+		}
+		var key string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return value, err
+			}
+			switch t := token.(type) {
+			case xml.EndElement:
+				return value, nil
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &t); err != nil {
+						return value, err
+					}
+					continue
+				}
+				item, err := decodePlistValue(decoder, t)
+				if err != nil {
+					return value, err
+				}
+				if value.kind == "dict" {
+					value.dict[key] = item
+				} else {
+					value.items = append(value.items, item)
+				}
+			}
+		}
+	default:
+		if err := decoder.DecodeElement(&value.text, &start); err != nil {
+			return value, err
+		}
+		return value, nil
+	}
+}
+
+// ParseNazimTasks reads back the tasks nazim installed from the <Tasks> document
+// printed by `schtasks /query /xml ONE`. The command line of each task is in its
+// wrapper script, which ParseWrapper reads.
+func ParseNazimTasks(r io.Reader) ([]*Definition, error) {
+	var defs []*Definition
+	err := decodeTasks(r, func(path string, task *taskXML) {
+		if name, ok := nazimTaskService(splitTaskPath(path)); ok {
+			defs = append(defs, convertNazimTask(path, name, task))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// nazimTaskService returns the service a task nazim installed runs. Tasks live in
+// the \Nazim folder; older versions prefixed their names with Nazim_ in the root.
+func nazimTaskService(dir, name string) (string, bool) {
+	if strings.EqualFold(dir, `\Nazim`) {
+		return name, true
+	}
+	if dir == `\` && strings.HasPrefix(name, "Nazim_") {
+		return strings.TrimPrefix(name, "Nazim_"), true
+	}
+	return "", false
+}
+
+// convertNazimTask reads back the triggers and settings of a task nazim installed.
+func convertNazimTask(path, name string, task *taskXML) *Definition {
+	svc := &service.Service{Name: name, Enabled: !strings.EqualFold(task.Settings.Enabled, "false")}
+	d := &Definition{Source: path, Service: svc}
+
+	if len(task.Actions.Exec) != 1 {
+		d.Problem = "task doesn't run a nazim wrapper"
+		return d
+	}
+	args := splitTaskArguments(task.Actions.Exec[0].Arguments)
+	for i := 0; i+1 < len(args); i++ {
+		if strings.EqualFold(args[i], "-File") {
+			d.Wrapper = args[i+1]
+		}
+	}
+	if d.Wrapper == "" {
+		d.Problem = "task doesn't run a nazim wrapper"
+		return d
+	}
+
+	if err := convertTaskTriggers(svc, task); err != nil {
+		d.Problem = err.Error()
+		return d
+	}
+	if strings.EqualFold(task.Settings.StartWhenAvailable, "true") && svc.RequiresScheduling() {
+		svc.CatchUp = true
+	}
+	for _, p := range task.Principals.Principal {
+		if p.RunLevel == "HighestAvailable" {
+			svc.Elevated = true
+		}
+	}
+	return d
+}
+
+// ParseWrapper completes a task's definition from its wrapper script, which runs
+// either `nazim exec` or, in wrappers written by older versions, the command
+// itself through cmd.
+func (d *Definition) ParseWrapper(data []byte) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "& "); ok {
+			d.applyCommandLine(splitPowerShellQuoted(rest))
+			return
+		}
+		if rest, ok := strings.CutPrefix(line, "$output = & cmd /c "); ok {
+			quoted := splitPowerShellQuoted(strings.TrimSuffix(rest, " 2>&1"))
+			if len(quoted) != 1 {
+				break
+			}
+			command := quoted[0]
+			if strings.HasPrefix(command, "cd /d ") {
+				if dir, rest, ok := strings.Cut(strings.TrimPrefix(command, "cd /d "), " && "); ok {
+					d.Service.WorkDir = strings.ReplaceAll(strings.Trim(dir, `"`), `""`, `"`)
+					command = rest
+				}
+			}
+			d.applyCommandLine(splitTaskArguments(command))
+			return
+		}
+	}
+	d.Problem = "wrapper doesn't run a command nazim recognizes"
+}
+
+// splitPowerShellQuoted splits a list of PowerShell single-quoted strings, such
+// as 'a' 'b', into their values. A doubled quote inside a string is a quote.
+func splitPowerShellQuoted(s string) []string {
+	var values []string
+	var current strings.Builder
+	inQuote := false
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case !inQuote && r == '\'':
+			inQuote = true
+		case inQuote && r == '\'' && i+1 < len(runes) && runes[i+1] == '\'':
+			current.WriteRune('\'')
+			i++
+		case inQuote && r == '\'':
+			values = append(values, current.String())
+			current.Reset()
+			inQuote = false
+		case inQuote:
+			current.WriteRune(r)
+		}
+	}
+	return values
+}
@@ -0,0 +1,261 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/schedule"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// cronMacros maps crontab @-shortcuts to their five-field equivalents.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronDayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseCrontab reads crontab entries and converts each to a service.
+// System crontabs (/etc/crontab, /etc/cron.d) have a user column after the
+// schedule, which is skipped when system is set. Comments, blank lines, and
+// environment assignments are ignored.
+func ParseCrontab(r io.Reader, system bool) ([]*Entry, error) {
+	var entries []*Entry
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || isCronAssignment(line) {
+			continue
+		}
+
+		entry := &Entry{Source: fmt.Sprintf("line %d", lineNum)}
+		entries = append(entries, entry)
+
+		fields := strings.Fields(line)
+		var when []string
+		if strings.HasPrefix(fields[0], "@") {
+			when, fields = fields[:1], fields[1:]
+		} else if len(fields) >= 5 {
+			when, fields = fields[:5], fields[5:]
+		} else {
+			entry.Command = line
+			entry.Problem = "malformed entry"
+			continue
+		}
+		if system && len(fields) > 0 {
+			fields = fields[1:] // user column
+		}
+
+		entry.When = strings.Join(when, " ")
+		entry.Command = strings.Join(fields, " ")
+		if entry.Command == "" {
+			entry.Problem = "malformed entry: no command"
+			continue
+		}
+
+		convertCronEntry(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading crontab: %w", err)
+	}
+	return entries, nil
+}
+
+// isCronAssignment reports whether a line sets an environment variable, e.g. MAILTO=root.
+func isCronAssignment(line string) bool {
+	name, _, ok := strings.Cut(line, "=")
+	return ok && !strings.ContainsAny(strings.TrimSpace(name), " \t")
+}
+
+// convertCronEntry fills in the entry's service, or its problem if the schedule
+// has no nazim equivalent.
+func convertCronEntry(entry *Entry) {
+	// In crontab an unescaped % ends the command and starts its stdin
+	command, err := unescapeCronCommand(entry.Command)
+	if err != nil {
+		entry.Problem = err.Error()
+		return
+	}
+
+	svc := &service.Service{
+		Name:    deriveName(command),
+		Command: command,
+		Enabled: true,
+	}
+
+	if entry.When == "@reboot" {
+		svc.OnStartup = true
+		entry.Problem = "runs at login rather than boot on Linux and macOS"
+	} else {
+		spec := entry.When
+		if macro, ok := cronMacros[spec]; ok {
+			spec = macro
+		} else if strings.HasPrefix(spec, "@") {
+			entry.Problem = fmt.Sprintf("unknown shortcut %s", spec)
+			return
+		}
+
+		interval, sched, caveat, err := convertCronSpec(strings.Fields(spec))
+		if err != nil {
+			entry.Problem = err.Error()
+			return
+		}
+		svc.Interval = service.Duration{Duration: interval}
+		if sched != nil {
+			svc.Schedule = sched.String()
+		}
+		entry.Problem = caveat
+	}
+
+	if err := svc.Validate(); err != nil {
+		entry.Problem = err.Error()
+		return
+	}
+	entry.Service = svc
+}
+
+func unescapeCronCommand(command string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(command); i++ {
+		switch {
+		case command[i] == '\\' && i+1 < len(command) && command[i+1] == '%':
+			out.WriteByte('%')
+			i++
+		case command[i] == '%':
+			return "", fmt.Errorf("uses %% to pass stdin, which nazim does not support")
+		default:
+			out.WriteByte(command[i])
+		}
+	}
+	return out.String(), nil
+}
+
+// convertCronSpec converts five crontab fields to an interval or a calendar schedule.
+// Supported are fixed times on any set of weekdays ("30 3 * * 1-5"), and regular
+// minute or hour steps ("*/15 * * * *", "0 */2 * * *"). Day-of-month and month
+// restrictions have no nazim equivalent.
+func convertCronSpec(fields []string) (time.Duration, *schedule.Schedule, string, error) {
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if dom != "*" || month != "*" {
+		return 0, nil, "", fmt.Errorf("day-of-month and month restrictions are not supported")
+	}
+
+	// Interval forms; weekday restrictions can't be expressed on an interval
+	if step, ok := cronStep(minute); ok && hour == "*" {
+		if dow != "*" {
+			return 0, nil, "", fmt.Errorf("intervals restricted to weekdays are not supported")
+		}
+		return time.Duration(step) * time.Minute, nil, "", nil
+	}
+	if _, err := strconv.Atoi(minute); err == nil {
+		if step, ok := cronStep(hour); ok {
+			if dow != "*" {
+				return 0, nil, "", fmt.Errorf("intervals restricted to weekdays are not supported")
+			}
+			caveat := ""
+			if minute != "0" {
+				caveat = fmt.Sprintf("runs every %dh from install, not at minute %s", step, minute)
+			}
+			return time.Duration(step) * time.Hour, nil, caveat, nil
+		}
+	}
+
+	m, errM := strconv.Atoi(minute)
+	h, errH := strconv.Atoi(hour)
+	if errM != nil || errH != nil {
+		return 0, nil, "", fmt.Errorf("only single times of day or regular steps are supported")
+	}
+	if m < 0 || m > 59 || h < 0 || h > 23 {
+		return 0, nil, "", fmt.Errorf("time out of range")
+	}
+
+	days, err := parseCronDays(dow)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	return 0, &schedule.Schedule{Days: days, Hour: h, Minute: m}, "", nil
+}
+
+// cronStep parses "*" (every unit) and "*/N" (every N units).
+func cronStep(field string) (int, bool) {
+	if field == "*" {
+		return 1, true
+	}
+	if rest, ok := strings.CutPrefix(field, "*/"); ok {
+		if n, err := strconv.Atoi(rest); err == nil && n > 0 {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// parseCronDays parses a day-of-week field: "*", numbers 0-7 (0 and 7 are Sunday),
+// three-letter names, and comma-separated lists and ranges of those.
+func parseCronDays(field string) ([]time.Weekday, error) {
+	if field == "*" {
+		return nil, nil
+	}
+
+	seen := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(field, ",") {
+		start, end, isRange := strings.Cut(part, "-")
+		first, err := parseCronDay(start)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseCronDay(end); err != nil {
+				return nil, err
+			}
+			// 7 is Sunday at the end of a range such as 5-7
+			if end == "7" {
+				last = 7
+			}
+		}
+		if last < first {
+			return nil, fmt.Errorf("invalid day range %q", part)
+		}
+		for d := first; d <= last; d++ {
+			seen[d%7] = true
+		}
+	}
+
+	var days []time.Weekday
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if seen[d] {
+			days = append(days, d)
+		}
+	}
+	if len(days) == 7 {
+		return nil, nil
+	}
+	return days, nil
+}
+
+func parseCronDay(s string) (time.Weekday, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 7 {
+			return 0, fmt.Errorf("day of week %d out of range", n)
+		}
+		return time.Weekday(n % 7), nil
+	}
+	if d, ok := cronDayNames[strings.ToLower(s)]; ok {
+		return d, nil
+	}
+	return 0, fmt.Errorf("unsupported day of week %q", s)
+}
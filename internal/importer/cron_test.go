@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCrontab(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		system   bool
+		command  string
+		interval time.Duration
+		schedule string
+		startup  bool
+		problem  string // Substring of the problem; "" for none
+		skipped  bool   // Entry expected to have no service
+	}{
+		{name: "every 15 minutes", line: "*/15 * * * * /opt/backup.sh", command: "/opt/backup.sh", interval: 15 * time.Minute},
+		{name: "every minute", line: "* * * * * /bin/true", command: "/bin/true", interval: time.Minute},
+		{name: "every 2 hours", line: "0 */2 * * * /opt/sync.sh --all", command: "/opt/sync.sh --all", interval: 2 * time.Hour},
+		{name: "hourly step off the hour", line: "30 */2 * * * /opt/sync.sh", command: "/opt/sync.sh", interval: 2 * time.Hour, problem: "not at minute 30"},
+		{name: "daily time", line: "30 3 * * * /opt/report.sh", command: "/opt/report.sh", schedule: "daily 03:30"},
+		{name: "weekdays", line: "0 18 * * 1-5 /opt/wrap.sh", command: "/opt/wrap.sh", schedule: "weekdays 18:00"},
+		{name: "day names", line: "0 9 * * mon,wed,fri /opt/a.sh", command: "/opt/a.sh", schedule: "every monday,wednesday,friday 09:00"},
+		{name: "sunday as 7", line: "0 10 * * 6-7 /opt/a.sh", command: "/opt/a.sh", schedule: "weekends 10:00"},
+		{name: "macro", line: "@daily /opt/a.sh", command: "/opt/a.sh", schedule: "daily 00:00"},
+		{name: "hourly macro", line: "@hourly /opt/a.sh", command: "/opt/a.sh", interval: time.Hour},
+		{name: "reboot", line: "@reboot /opt/start.sh", command: "/opt/start.sh", startup: true, problem: "at login"},
+		{name: "system crontab", line: "0 4 * * * root /opt/clean.sh", system: true, command: "/opt/clean.sh", schedule: "daily 04:00"},
+		{name: "escaped percent", line: `0 1 * * * date +\%F`, command: "date +%F", schedule: "daily 01:00"},
+		{name: "stdin percent", line: "0 1 * * * mail -s hi root%body", problem: "stdin", skipped: true},
+		{name: "day of month", line: "0 1 1 * * /opt/a.sh", problem: "day-of-month", skipped: true},
+		{name: "interval on weekdays", line: "*/5 * * * 1-5 /opt/a.sh", problem: "weekdays", skipped: true},
+		{name: "time list", line: "0 1,13 * * * /opt/a.sh", problem: "single times", skipped: true},
+		{name: "unknown macro", line: "@fortnightly /opt/a.sh", problem: "unknown shortcut", skipped: true},
+		{name: "too few fields", line: "0 1 * *", problem: "malformed", skipped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ParseCrontab(strings.NewReader(tt.line+"\n"), tt.system)
+			if err != nil {
+				t.Fatalf("ParseCrontab() error = %v", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("ParseCrontab() returned %d entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			if tt.problem == "" && entry.Problem != "" || !strings.Contains(entry.Problem, tt.problem) {
+				t.Errorf("Problem = %q, want one containing %q", entry.Problem, tt.problem)
+			}
+			if tt.skipped {
+				if entry.Importable() {
+					t.Errorf("entry converted to %+v, want it left out", entry.Service)
+				}
+				return
+			}
+			svc := entry.Service
+			if svc == nil {
+				t.Fatalf("entry not converted: %s", entry.Problem)
+			}
+			if svc.Command != tt.command || svc.GetInterval() != tt.interval || svc.Schedule != tt.schedule || svc.OnStartup != tt.startup {
+				t.Errorf("service = command %q, interval %s, schedule %q, startup %v; want %q, %s, %q, %v",
+					svc.Command, svc.GetInterval(), svc.Schedule, svc.OnStartup, tt.command, tt.interval, tt.schedule, tt.startup)
+			}
+		})
+	}
+}
+
+func TestParseCrontabSkipsNonEntries(t *testing.T) {
+	crontab := "# nightly jobs\n\nMAILTO=root\nSHELL=/bin/sh\n0 2 * * * /opt/nightly.sh\n"
+	entries, err := ParseCrontab(strings.NewReader(crontab), false)
+	if err != nil {
+		t.Fatalf("ParseCrontab() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Source != "line 5" {
+		t.Fatalf("ParseCrontab() = %d entries, want only the one on line 5", len(entries))
+	}
+	if name := entries[0].Service.Name; name != "nightly" {
+		t.Errorf("Name = %q, want nightly", name)
+	}
+}
+
+func TestAssignNames(t *testing.T) {
+	entries, err := ParseCrontab(strings.NewReader("@daily /opt/job.sh\n@hourly /opt/job.sh\n@weekly /srv/job.py\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssignNames(entries, func(name string) bool { return name == "job" })
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Service.Name)
+	}
+	if got, want := strings.Join(names, " "), "job-2 job-3 job-4"; got != want {
+		t.Errorf("names = %s, want %s", got, want)
+	}
+}
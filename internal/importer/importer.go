@@ -0,0 +1,75 @@
+// Package importer converts jobs from other schedulers into nazim services.
+package importer
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Entry is a job found in another scheduler.
+type Entry struct {
+	Source  string // Where the job came from, e.g. "line 12" or a task path
+	Command string // The job's command line as written
+	When    string // The job's schedule as written
+
+	Service *service.Service // The converted service, nil if the job can't be imported
+	Problem string           // Why the job can't be imported, or a caveat of the conversion
+}
+
+// Importable reports whether the entry converted to a service.
+func (e *Entry) Importable() bool {
+	return e.Service != nil
+}
+
+// deriveName turns a command into a service name: the base name of its
+// executable, without extension, as a slug.
+func deriveName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "job"
+	}
+	base := filepath.Base(strings.Trim(strings.ReplaceAll(fields[0], `\`, "/"), `"'`))
+	return slugify(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// slugify lowercases s and replaces runs of other characters than letters and
+// digits with a dash, so it can be used as a service name.
+func slugify(s string) string {
+	var name strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			name.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			name.WriteByte('-')
+			lastDash = true
+		}
+	}
+	if result := strings.Trim(name.String(), "-"); result != "" {
+		return result
+	}
+	return "job"
+}
+
+// AssignNames gives every importable entry a unique name that doesn't clash with
+// the taken names, by appending -2, -3, and so on.
+func AssignNames(entries []*Entry, taken func(name string) bool) {
+	used := make(map[string]bool)
+	for _, e := range entries {
+		if e.Service == nil {
+			continue
+		}
+		base := e.Service.Name
+		name := base
+		for i := 2; used[name] || taken(name); i++ {
+			name = base + "-" + strconv.Itoa(i)
+		}
+		used[name] = true
+		e.Service.Name = name
+	}
+}
@@ -0,0 +1,505 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/calilkhalil/nazim/internal/schedule"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// taskXML is the part of a Task Scheduler task definition nazim can translate.
+// Element names are matched without their namespace.
+type taskXML struct {
+	RegistrationInfo struct {
+		URI string `xml:"URI"`
+	} `xml:"RegistrationInfo"`
+	Triggers struct {
+		Boot     []taskTrigger `xml:"BootTrigger"`
+		Logon    []taskTrigger `xml:"LogonTrigger"`
+		Time     []taskTrigger `xml:"TimeTrigger"`
+		Calendar []taskTrigger `xml:"CalendarTrigger"`
+		Any      []xmlElement  `xml:",any"`
+	} `xml:"Triggers"`
+	Settings struct {
+		Enabled            string `xml:"Enabled"`
+		StartWhenAvailable string `xml:"StartWhenAvailable"`
+	} `xml:"Settings"`
+	Principals struct {
+		Principal []struct {
+			UserID   string `xml:"UserId"`
+			RunLevel string `xml:"RunLevel"`
+		} `xml:"Principal"`
+	} `xml:"Principals"`
+	Actions struct {
+		Exec []struct {
+			Command          string `xml:"Command"`
+			Arguments        string `xml:"Arguments"`
+			WorkingDirectory string `xml:"WorkingDirectory"`
+		} `xml:"Exec"`
+		Any []xmlElement `xml:",any"`
+	} `xml:"Actions"`
+}
+
+type taskTrigger struct {
+	Enabled       string `xml:"Enabled"`
+	StartBoundary string `xml:"StartBoundary"`
+	Delay         string `xml:"Delay"`
+	Repetition    struct {
+		Interval string `xml:"Interval"`
+		Duration string `xml:"Duration"`
+	} `xml:"Repetition"`
+	ScheduleByDay *struct {
+		DaysInterval string `xml:"DaysInterval"`
+	} `xml:"ScheduleByDay"`
+	ScheduleByWeek *struct {
+		WeeksInterval string     `xml:"WeeksInterval"`
+		DaysOfWeek    xmlElement `xml:"DaysOfWeek"`
+	} `xml:"ScheduleByWeek"`
+	ScheduleByMonth          *xmlElement `xml:"ScheduleByMonth"`
+	ScheduleByMonthDayOfWeek *xmlElement `xml:"ScheduleByMonthDayOfWeek"`
+}
+
+// xmlElement captures an element's name and children's names.
+type xmlElement struct {
+	XMLName  xml.Name
+	Children []xmlElement `xml:",any"`
+}
+
+// enabled reports whether a trigger is enabled; triggers are enabled unless they say otherwise.
+func (t *taskTrigger) enabled() bool {
+	return !strings.EqualFold(t.Enabled, "false")
+}
+
+var taskDayNames = map[string]time.Weekday{
+	"Sunday": time.Sunday, "Monday": time.Monday, "Tuesday": time.Tuesday, "Wednesday": time.Wednesday,
+	"Thursday": time.Thursday, "Friday": time.Friday, "Saturday": time.Saturday,
+}
+
+// ParseTasks reads Task Scheduler task definitions and converts each to a service.
+// r holds either a single exported task or the <Tasks> document printed by
+// `schtasks /query /xml ONE`, in UTF-8 or UTF-16. Only tasks directly in folder
+// (such as `\` or `\MyTasks`) are returned, and tasks installed by nazim itself
+// are left out.
+func ParseTasks(r io.Reader, folder string) ([]*Entry, error) {
+	folder = normalizeTaskFolder(folder)
+	var entries []*Entry
+	err := decodeTasks(r, func(path string, task *taskXML) {
+		dir, name := splitTaskPath(path)
+		if !strings.EqualFold(dir, folder) {
+			return
+		}
+		if _, ok := nazimTaskService(dir, name); ok {
+			return
+		}
+		entries = append(entries, convertTask(path, name, task))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// decodeTasks calls fn with the path and definition of every task in r, which is
+// read as described for ParseTasks.
+func decodeTasks(r io.Reader, fn func(path string, task *taskXML)) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading tasks: %w", err)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(decodeUTF16(data)))
+	// The declared encoding is often UTF-16 even after conversion, or a console code page
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var lastComment string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("parsing tasks: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.Comment:
+			// schtasks precedes every task with a comment holding its path
+			lastComment = strings.TrimSpace(string(t))
+		case xml.StartElement:
+			if t.Name.Local != "Task" {
+				continue
+			}
+			var task taskXML
+			if err := decoder.DecodeElement(&task, &t); err != nil {
+				return fmt.Errorf("parsing tasks: %w", err)
+			}
+			path := task.RegistrationInfo.URI
+			if path == "" {
+				path = lastComment
+			}
+			lastComment = ""
+			fn(path, &task)
+		}
+	}
+}
+
+// decodeUTF16 converts UTF-16 data with a byte order mark to UTF-8, as written by
+// the Task Scheduler's export. Other data is returned unchanged.
+func decodeUTF16(data []byte) []byte {
+	var bigEndian bool
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		bigEndian = true
+	default:
+		return data
+	}
+
+	units := make([]uint16, 0, len(data)/2)
+	for i := 2; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// normalizeTaskFolder turns a folder such as "MyTasks" or "\MyTasks\" into "\MyTasks".
+func normalizeTaskFolder(folder string) string {
+	folder = strings.Trim(strings.ReplaceAll(folder, "/", `\`), `\`)
+	return `\` + folder
+}
+
+// splitTaskPath splits a task path such as \MyTasks\Backup into its folder and name.
+func splitTaskPath(path string) (string, string) {
+	i := strings.LastIndex(path, `\`)
+	if i < 0 {
+		return `\`, path
+	}
+	return normalizeTaskFolder(path[:i]), path[i+1:]
+}
+
+// convertTask fills in an entry for a task, with its service or the reason it
+// can't be imported.
+func convertTask(path, name string, task *taskXML) *Entry {
+	entry := &Entry{Source: path, When: describeTaskTriggers(task)}
+
+	if len(task.Actions.Exec) != 1 || len(task.Actions.Any) > 0 {
+		entry.Problem = "only tasks with a single program action are supported"
+		if len(task.Actions.Exec) > 0 {
+			entry.Command = task.Actions.Exec[0].Command
+		}
+		return entry
+	}
+	action := task.Actions.Exec[0]
+	entry.Command = strings.TrimSpace(action.Command + " " + action.Arguments)
+
+	svc := &service.Service{
+		Name:    slugify(name),
+		Command: strings.Trim(action.Command, `"`),
+		Args:    splitTaskArguments(action.Arguments),
+		WorkDir: strings.Trim(action.WorkingDirectory, `"`),
+		Enabled: !strings.EqualFold(task.Settings.Enabled, "false"),
+	}
+
+	var caveats []string
+	if err := convertTaskTriggers(svc, task); err != nil {
+		entry.Problem = err.Error()
+		return entry
+	}
+
+	if strings.EqualFold(task.Settings.StartWhenAvailable, "true") && svc.RequiresScheduling() {
+		svc.CatchUp = true
+	}
+	for _, p := range task.Principals.Principal {
+		if p.RunLevel == "HighestAvailable" {
+			svc.Elevated = true
+		}
+		if isSystemAccount(p.UserID) && !svc.OnStartup {
+			caveats = append(caveats, "ran as SYSTEM; will run as the current user")
+		}
+	}
+	if strings.Contains(svc.Command, "%") || strings.Contains(svc.WorkDir, "%") {
+		caveats = append(caveats, "uses environment variables in paths")
+	}
+
+	if err := svc.Validate(); err != nil {
+		entry.Problem = err.Error()
+		return entry
+	}
+	entry.Service = svc
+	entry.Problem = strings.Join(caveats, "; ")
+	return entry
+}
+
+// convertTaskTriggers sets the service's startup, logon, interval, and schedule
+// from the task's enabled triggers.
+func convertTaskTriggers(svc *service.Service, task *taskXML) error {
+	if len(task.Triggers.Any) > 0 {
+		return fmt.Errorf("%s triggers are not supported", strings.TrimSuffix(task.Triggers.Any[0].XMLName.Local, "Trigger"))
+	}
+
+	setInterval := func(t *taskTrigger) error {
+		if t.Repetition.Interval == "" {
+			return nil
+		}
+		if d, err := parseTaskDuration(t.Repetition.Duration); err != nil || (d > 0 && d < 24*time.Hour) {
+			return fmt.Errorf("repetition limited to a duration is not supported")
+		}
+		interval, err := parseTaskDuration(t.Repetition.Interval)
+		if err != nil {
+			return err
+		}
+		if svc.Interval.Duration > 0 && svc.Interval.Duration != interval {
+			return fmt.Errorf("triggers repeat at different intervals")
+		}
+		svc.Interval = service.Duration{Duration: interval}
+		return nil
+	}
+	setDelay := func(t *taskTrigger) error {
+		delay, err := parseTaskDuration(t.Delay)
+		if err != nil {
+			return err
+		}
+		if delay > svc.StartupDelay.Duration {
+			svc.StartupDelay = service.Duration{Duration: delay}
+		}
+		return nil
+	}
+
+	for i := range task.Triggers.Boot {
+		t := &task.Triggers.Boot[i]
+		if !t.enabled() {
+			continue
+		}
+		svc.OnStartup = true
+		if err := setDelay(t); err != nil {
+			return err
+		}
+		if err := setInterval(t); err != nil {
+			return err
+		}
+	}
+	for i := range task.Triggers.Logon {
+		t := &task.Triggers.Logon[i]
+		if !t.enabled() {
+			continue
+		}
+		svc.OnLogon = true
+		if err := setDelay(t); err != nil {
+			return err
+		}
+		if err := setInterval(t); err != nil {
+			return err
+		}
+	}
+	for i := range task.Triggers.Time {
+		t := &task.Triggers.Time[i]
+		if !t.enabled() {
+			continue
+		}
+		if t.Repetition.Interval == "" {
+			return fmt.Errorf("one-time triggers are not supported")
+		}
+		if err := setInterval(t); err != nil {
+			return err
+		}
+	}
+	for i := range task.Triggers.Calendar {
+		t := &task.Triggers.Calendar[i]
+		if !t.enabled() {
+			continue
+		}
+		if t.Repetition.Interval != "" {
+			// A daily trigger repeating all day is an interval
+			if t.ScheduleByDay == nil || !isOne(t.ScheduleByDay.DaysInterval) {
+				return fmt.Errorf("repeating weekly or monthly triggers are not supported")
+			}
+			if err := setInterval(t); err != nil {
+				return err
+			}
+			continue
+		}
+		if svc.Schedule != "" {
+			return fmt.Errorf("multiple calendar triggers are not supported")
+		}
+		sched, err := convertCalendarTrigger(t)
+		if err != nil {
+			return err
+		}
+		svc.Schedule = sched.String()
+	}
+
+	if svc.StartupDelay.Duration > 0 && !svc.OnStartup && !svc.OnLogon {
+		svc.StartupDelay = service.Duration{}
+	}
+	return nil
+}
+
+// convertCalendarTrigger converts a daily or weekly trigger to a calendar schedule.
+func convertCalendarTrigger(t *taskTrigger) (*schedule.Schedule, error) {
+	// StartBoundary is YYYY-MM-DDTHH:MM:SS with an optional zone; the clock time is what matters
+	_, clock, ok := strings.Cut(t.StartBoundary, "T")
+	if !ok || len(clock) < 5 {
+		return nil, fmt.Errorf("calendar trigger without a start time")
+	}
+	h, errH := strconv.Atoi(clock[0:2])
+	m, errM := strconv.Atoi(clock[3:5])
+	if errH != nil || errM != nil {
+		return nil, fmt.Errorf("invalid start time %q", t.StartBoundary)
+	}
+
+	switch {
+	case t.ScheduleByDay != nil:
+		if !isOne(t.ScheduleByDay.DaysInterval) {
+			return nil, fmt.Errorf("triggers every %s days are not supported", t.ScheduleByDay.DaysInterval)
+		}
+		return &schedule.Schedule{Hour: h, Minute: m}, nil
+	case t.ScheduleByWeek != nil:
+		if !isOne(t.ScheduleByWeek.WeeksInterval) {
+			return nil, fmt.Errorf("triggers every %s weeks are not supported", t.ScheduleByWeek.WeeksInterval)
+		}
+		seen := make(map[time.Weekday]bool)
+		for _, child := range t.ScheduleByWeek.DaysOfWeek.Children {
+			if d, ok := taskDayNames[child.XMLName.Local]; ok {
+				seen[d] = true
+			}
+		}
+		var days []time.Weekday
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if seen[d] {
+				days = append(days, d)
+			}
+		}
+		if len(days) == 0 {
+			return nil, fmt.Errorf("weekly trigger without days")
+		}
+		if len(days) == 7 {
+			days = nil
+		}
+		return &schedule.Schedule{Days: days, Hour: h, Minute: m}, nil
+	default:
+		return nil, fmt.Errorf("monthly triggers are not supported")
+	}
+}
+
+// isOne reports whether an optional repeat count is 1; an empty count means 1.
+func isOne(s string) bool {
+	return s == "" || s == "1"
+}
+
+// isSystemAccount reports whether a principal is the LocalSystem account.
+func isSystemAccount(userID string) bool {
+	return userID == "S-1-5-18" || strings.EqualFold(userID, "SYSTEM") || strings.EqualFold(userID, `NT AUTHORITY\SYSTEM`)
+}
+
+// describeTaskTriggers names the task's enabled triggers, e.g. "Boot, Calendar".
+func describeTaskTriggers(task *taskXML) string {
+	var kinds []string
+	add := func(kind string, triggers []taskTrigger) {
+		for i := range triggers {
+			if triggers[i].enabled() {
+				kinds = append(kinds, kind)
+				return
+			}
+		}
+	}
+	add("Boot", task.Triggers.Boot)
+	add("Logon", task.Triggers.Logon)
+	add("Time", task.Triggers.Time)
+	add("Calendar", task.Triggers.Calendar)
+	for _, other := range task.Triggers.Any {
+		kinds = append(kinds, strings.TrimSuffix(other.XMLName.Local, "Trigger"))
+	}
+	if len(kinds) == 0 {
+		return "no trigger"
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// parseTaskDuration parses the ISO 8601 durations used by Task Scheduler, such
+// as PT15M, PT1H30M, or P1D. An empty string is zero.
+func parseTaskDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var total time.Duration
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, _ := strconv.Atoi(rest[:i])
+		unit := rest[i]
+		rest = rest[i+1:]
+
+		switch {
+		case !inTime && unit == 'D':
+			total += time.Duration(n) * 24 * time.Hour
+		case !inTime && unit == 'W':
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case inTime && unit == 'H':
+			total += time.Duration(n) * time.Hour
+		case inTime && unit == 'M':
+			total += time.Duration(n) * time.Minute
+		case inTime && unit == 'S':
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("unsupported duration %q", s)
+		}
+	}
+	return total, nil
+}
+
+// splitTaskArguments splits an action's argument string the way most Windows
+// programs do: on spaces, keeping double-quoted parts together.
+func splitTaskArguments(s string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// taskDocument returns a task definition at path with the given triggers and
+// actions, the way Task Scheduler exports it.
+func taskDocument(path, triggers, actions string) string {
+	return `<?xml version="1.0" encoding="UTF-16"?>
+<Task version="1.4" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <RegistrationInfo><URI>` + path + `</URI></RegistrationInfo>
+  <Triggers>` + triggers + `</Triggers>
+  <Settings><Enabled>true</Enabled></Settings>
+  <Actions Context="Author">` + actions + `</Actions>
+</Task>`
+}
+
+const backupAction = `<Exec><Command>"C:\Tools\backup.exe"</Command><Arguments>--dest "D:\My Backups" -v</Arguments><WorkingDirectory>C:\Tools</WorkingDirectory></Exec>`
+
+func TestParseTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		triggers string
+		actions  string
+		interval time.Duration
+		schedule string
+		startup  bool
+		delay    time.Duration
+		problem  string // Substring of the problem; "" for none
+	}{
+		{name: "daily", triggers: `<CalendarTrigger><StartBoundary>2024-01-01T03:30:00</StartBoundary><ScheduleByDay><DaysInterval>1</DaysInterval></ScheduleByDay></CalendarTrigger>`,
+			schedule: "daily 03:30"},
+		{name: "weekly", triggers: `<CalendarTrigger><StartBoundary>2024-01-01T18:00:00+01:00</StartBoundary><ScheduleByWeek><WeeksInterval>1</WeeksInterval><DaysOfWeek><Monday/><Friday/></DaysOfWeek></ScheduleByWeek></CalendarTrigger>`,
+			schedule: "every monday,friday 18:00"},
+		{name: "repeating daily", triggers: `<CalendarTrigger><StartBoundary>2024-01-01T00:00:00</StartBoundary><Repetition><Interval>PT15M</Interval></Repetition><ScheduleByDay><DaysInterval>1</DaysInterval></ScheduleByDay></CalendarTrigger>`,
+			interval: 15 * time.Minute},
+		{name: "boot with delay", triggers: `<BootTrigger><Delay>PT2M</Delay></BootTrigger>`,
+			startup: true, delay: 2 * time.Minute},
+		{name: "disabled trigger", triggers: `<BootTrigger><Enabled>false</Enabled></BootTrigger><TimeTrigger><StartBoundary>2024-01-01T00:00:00</StartBoundary><Repetition><Interval>PT1H</Interval></Repetition></TimeTrigger>`,
+			interval: time.Hour},
+		{name: "one-time", triggers: `<TimeTrigger><StartBoundary>2024-01-01T00:00:00</StartBoundary></TimeTrigger>`,
+			problem: "one-time"},
+		{name: "every other day", triggers: `<CalendarTrigger><StartBoundary>2024-01-01T03:30:00</StartBoundary><ScheduleByDay><DaysInterval>2</DaysInterval></ScheduleByDay></CalendarTrigger>`,
+			problem: "every 2 days"},
+		{name: "monthly", triggers: `<CalendarTrigger><StartBoundary>2024-01-01T03:30:00</StartBoundary><ScheduleByMonth/></CalendarTrigger>`,
+			problem: "monthly"},
+		{name: "event trigger", triggers: `<EventTrigger/>`, problem: "Event triggers"},
+		{name: "limited repetition", triggers: `<TimeTrigger><StartBoundary>2024-01-01T00:00:00</StartBoundary><Repetition><Interval>PT5M</Interval><Duration>PT1H</Duration></Repetition></TimeTrigger>`,
+			problem: "limited to a duration"},
+		{name: "two actions", triggers: `<BootTrigger/>`, actions: backupAction + backupAction, problem: "single program action"},
+		{name: "e-mail action", triggers: `<BootTrigger/>`, actions: backupAction + `<SendEmail/>`, problem: "single program action"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := tt.actions
+			if actions == "" {
+				actions = backupAction
+			}
+			entries, err := ParseTasks(strings.NewReader(taskDocument(`\Nightly Backup`, tt.triggers, actions)), `\`)
+			if err != nil {
+				t.Fatalf("ParseTasks() error = %v", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("ParseTasks() returned %d entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			if tt.problem != "" {
+				if entry.Importable() || !strings.Contains(entry.Problem, tt.problem) {
+					t.Errorf("entry = %+v, problem %q, want it left out for %q", entry.Service, entry.Problem, tt.problem)
+				}
+				return
+			}
+			svc := entry.Service
+			if svc == nil {
+				t.Fatalf("entry not converted: %s", entry.Problem)
+			}
+			if svc.Name != "nightly-backup" || svc.Command != `C:\Tools\backup.exe` || svc.WorkDir != `C:\Tools` {
+				t.Errorf("service = %q running %q in %q", svc.Name, svc.Command, svc.WorkDir)
+			}
+			if want := []string{"--dest", `D:\My Backups`, "-v"}; !reflect.DeepEqual(svc.Args, want) {
+				t.Errorf("Args = %q, want %q", svc.Args, want)
+			}
+			if svc.GetInterval() != tt.interval || svc.Schedule != tt.schedule || svc.OnStartup != tt.startup || svc.StartupDelay.Duration != tt.delay {
+				t.Errorf("service = interval %s, schedule %q, startup %v, delay %s; want %s, %q, %v, %s",
+					svc.GetInterval(), svc.Schedule, svc.OnStartup, svc.StartupDelay.Duration, tt.interval, tt.schedule, tt.startup, tt.delay)
+			}
+		})
+	}
+}
+
+func TestParseTasksFolders(t *testing.T) {
+	boot := `<BootTrigger/>`
+	doc := `<Tasks>
+<!-- \Backup -->` + taskDocument("", boot, backupAction) + `
+<!-- \MyTasks\Report -->` + taskDocument("", boot, backupAction) + `
+<!-- \Nazim_job -->` + taskDocument("", boot, backupAction) + `
+</Tasks>`
+	doc = strings.ReplaceAll(doc, `<?xml version="1.0" encoding="UTF-16"?>`, "")
+
+	tests := []struct {
+		folder string
+		want   []string
+	}{
+		{`\`, []string{`\Backup`}},
+		{"MyTasks", []string{`\MyTasks\Report`}},
+		{`\Other\`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			entries, err := ParseTasks(strings.NewReader(doc), tt.folder)
+			if err != nil {
+				t.Fatalf("ParseTasks() error = %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Source)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTasks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTasksUTF16(t *testing.T) {
+	doc := taskDocument(`\Backup`, `<BootTrigger/>`, backupAction)
+	data := []byte{0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune(doc)) {
+		data = append(data, byte(unit), byte(unit>>8))
+	}
+	entries, err := ParseTasks(strings.NewReader(string(data)), `\`)
+	if err != nil {
+		t.Fatalf("ParseTasks() error = %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Importable() {
+		t.Fatalf("ParseTasks() = %d entries, want the one task converted", len(entries))
+	}
+}
+
+func TestParseTaskDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"PT15M", 15 * time.Minute, false},
+		{"PT1H30M", 90 * time.Minute, false},
+		{"P1D", 24 * time.Hour, false},
+		{"P1DT12H", 36 * time.Hour, false},
+		{"P1W", 7 * 24 * time.Hour, false},
+		{"PT30S", 30 * time.Second, false},
+		{"15M", 0, true},
+		{"PT", 0, false},
+		{"PT5", 0, true},
+		{"P1M", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseTaskDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTaskDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTaskDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTaskArguments(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"-a -b", []string{"-a", "-b"}},
+		{`--dest "D:\My Backups"  -v`, []string{"--dest", `D:\My Backups`, "-v"}},
+		{`/path:"C:\Program Files\x"`, []string{`/path:C:\Program Files\x`}},
+		{`""`, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := splitTaskArguments(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitTaskArguments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
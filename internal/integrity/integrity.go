@@ -0,0 +1,157 @@
+// Package integrity records SHA-256 checksums of the files a service runs
+// through, such as its script and the unit files or wrapper nazim installs for
+// it, and reports which of them changed since. Since these files run
+// unattended, a change nazim didn't make is worth a look.
+package integrity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/atomicfile"
+	"github.com/calilkhalil/nazim/internal/lock"
+)
+
+// Baseline holds the recorded checksums: for each service, the hex SHA-256 of
+// each of its files by path.
+type Baseline map[string]map[string]string
+
+// Kinds of changes.
+const (
+	Modified = "modified"
+	Deleted  = "deleted"
+)
+
+// Change is a file whose content differs from its recorded checksum.
+type Change struct {
+	Path string
+	Kind string // Modified or Deleted
+}
+
+// Load reads the baseline at path. A missing file is an empty baseline.
+func Load(path string) (Baseline, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Baseline{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading checksums: %w", err)
+	}
+	b := Baseline{}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parsing checksums %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Save writes the baseline to path, replacing the file in one step.
+func (b Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling checksums: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating checksums dir: %w", err)
+	}
+	if err := atomicfile.Write(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing checksums: %w", err)
+	}
+	return nil
+}
+
+// lockWait is how long Update waits for another update of the baseline.
+const lockWait = 10 * time.Second
+
+// Update loads the baseline at path, applies change, and saves it, holding a
+// lock file next to it so concurrent updates, such as two installs, don't lose
+// each other's checksums. Nothing is saved if change fails.
+func Update(path string, change func(Baseline) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating checksums dir: %w", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	held, err := lock.AcquireFile(context.Background(), filepath.Dir(path), name, lockWait)
+	if err != nil {
+		return fmt.Errorf("locking checksums: %w", err)
+	}
+	defer held.Release(context.Background())
+
+	b, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := change(b); err != nil {
+		return err
+	}
+	return b.Save(path)
+}
+
+// Record replaces the checksums of a service with those of paths as they are
+// now. Paths that don't exist are left out.
+func (b Baseline) Record(service string, paths []string) error {
+	sums := make(map[string]string, len(paths))
+	for _, path := range paths {
+		sum, err := Sum(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		sums[path] = sum
+	}
+	b[service] = sums
+	return nil
+}
+
+// Forget drops the checksums of a service.
+func (b Baseline) Forget(service string) {
+	delete(b, service)
+}
+
+// Check compares the files recorded for a service with what is on disk,
+// reporting whether the service has a baseline at all.
+func (b Baseline) Check(service string) (changes []Change, tracked bool) {
+	sums, tracked := b[service]
+	paths := make([]string, 0, len(sums))
+	for path := range sums {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		sum, err := Sum(path)
+		switch {
+		case os.IsNotExist(err):
+			changes = append(changes, Change{Path: path, Kind: Deleted})
+		case err != nil || sum != sums[path]:
+			// An unreadable file can't be vouched for either
+			changes = append(changes, Change{Path: path, Kind: Modified})
+		}
+	}
+	return changes, tracked
+}
+
+// Sum returns the hex SHA-256 of the file at path.
+func Sum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,55 @@
+package integrity
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checksums.json")
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent installs each keep their service's checksums
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := Update(path, func(b Baseline) error {
+				return b.Record(fmt.Sprintf("job%d", i), []string{script})
+			}); err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	b, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 8 {
+		t.Errorf("baseline holds %d services, want 8", len(b))
+	}
+	if changes, tracked := b.Check("job3"); !tracked || len(changes) != 0 {
+		t.Errorf("Check() = %v, %v, want job3 tracked and unchanged", changes, tracked)
+	}
+
+	if err := Update(path, func(b Baseline) error { return fmt.Errorf("failed") }); err == nil {
+		t.Errorf("Update() with a failing change succeeded")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d files in the directory, want the script and the checksums only", len(entries))
+	}
+}
@@ -0,0 +1,135 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// consulBackend locks the KV key nazim/locks/<name> with a session whose TTL
+// is renewed while held; when the session expires, the key is deleted.
+// An ACL token is taken from the URL's token parameter or CONSUL_HTTP_TOKEN.
+type consulBackend struct {
+	base    string
+	token   string
+	key     string
+	owner   string
+	session string
+}
+
+func newConsulBackend(u *url.URL, name, owner string) (*consulBackend, error) {
+	base, err := httpBase(u, "8500")
+	if err != nil {
+		return nil, err
+	}
+	token := u.Query().Get("token")
+	if token == "" {
+		token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	return &consulBackend{base: base, token: token, key: "nazim/locks/" + url.PathEscape(name), owner: owner}, nil
+}
+
+func (b *consulBackend) acquire(ctx context.Context) error {
+	var session struct{ ID string }
+	body := map[string]string{
+		"Name":      "nazim " + b.key,
+		"TTL":       fmt.Sprintf("%ds", int(TTL.Seconds())),
+		"Behavior":  "delete",
+		"LockDelay": "0s",
+	}
+	if err := b.call(ctx, "/v1/session/create", body, &session); err != nil {
+		return fmt.Errorf("creating Consul session: %w", err)
+	}
+	b.session = session.ID
+
+	var acquired bool
+	if err := b.call(ctx, "/v1/kv/"+b.key+"?acquire="+b.session, b.owner, &acquired); err != nil {
+		b.destroySession(ctx)
+		return fmt.Errorf("acquiring Consul lock: %w", err)
+	}
+	if acquired {
+		return nil
+	}
+
+	b.destroySession(ctx)
+	holder, _ := b.get(ctx, "/v1/kv/"+b.key+"?raw")
+	return &HeldError{Holder: holder}
+}
+
+func (b *consulBackend) refresh(ctx context.Context) error {
+	return b.call(ctx, "/v1/session/renew/"+b.session, nil, nil)
+}
+
+func (b *consulBackend) release(ctx context.Context) error {
+	var released bool
+	err := b.call(ctx, "/v1/kv/"+b.key+"?release="+b.session, b.owner, &released)
+	b.destroySession(ctx)
+	return err
+}
+
+func (b *consulBackend) destroySession(ctx context.Context) {
+	b.call(ctx, "/v1/session/destroy/"+b.session, nil, nil)
+}
+
+// call sends a PUT request. A string body is sent raw, other bodies as JSON.
+func (b *consulBackend) call(ctx context.Context, path string, body, result any) error {
+	var data []byte
+	switch v := body.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.base+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	respBody, err := b.send(req)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+func (b *consulBackend) get(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.base+path, nil)
+	if err != nil {
+		return "", err
+	}
+	body, err := b.send(req)
+	return strings.TrimSpace(string(body)), err
+}
+
+func (b *consulBackend) send(req *http.Request) ([]byte, error) {
+	if b.token != "" {
+		req.Header.Set("X-Consul-Token", b.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,118 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// fakeConsul serves the session and KV endpoints used by consulBackend, with
+// the lock key already held by holder when it isn't empty.
+type fakeConsul struct {
+	holder string
+	token  string
+	calls  []string
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	f.token = r.Header.Get("X-Consul-Token")
+	body, _ := io.ReadAll(r.Body)
+	q := r.URL.Query()
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		io.WriteString(w, `{"ID":"session-1"}`)
+	case r.URL.Path == "/v1/kv/nazim/locks/job" && q.Has("acquire"):
+		if f.holder != "" {
+			io.WriteString(w, "false")
+			return
+		}
+		f.holder = string(body)
+		io.WriteString(w, "true")
+	case r.URL.Path == "/v1/kv/nazim/locks/job" && q.Has("release"):
+		f.holder = ""
+		io.WriteString(w, "true")
+	case r.URL.Path == "/v1/kv/nazim/locks/job" && r.Method == http.MethodGet:
+		io.WriteString(w, f.holder)
+	case r.URL.Path == "/v1/session/renew/session-1", r.URL.Path == "/v1/session/destroy/session-1":
+		io.WriteString(w, "[]")
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+	}
+}
+
+func newTestConsul(t *testing.T, f *fakeConsul, query string) *consulBackend {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	b, err := newConsulBackend(&url.URL{Scheme: "consul", Host: u.Host, RawQuery: query}, "job", "me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestConsulAcquireRelease(t *testing.T) {
+	f := &fakeConsul{}
+	b := newTestConsul(t, f, "token=secret")
+	ctx := context.Background()
+
+	if err := b.acquire(ctx); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if f.holder != "me" {
+		t.Errorf("lock holder = %q, want %q", f.holder, "me")
+	}
+	if f.token != "secret" {
+		t.Errorf("X-Consul-Token = %q, want %q", f.token, "secret")
+	}
+	if err := b.refresh(ctx); err != nil {
+		t.Errorf("refresh() error = %v", err)
+	}
+	if err := b.release(ctx); err != nil {
+		t.Errorf("release() error = %v", err)
+	}
+	if f.holder != "" {
+		t.Errorf("lock holder after release = %q, want none", f.holder)
+	}
+	if last := f.calls[len(f.calls)-1]; last != "PUT /v1/session/destroy/session-1" {
+		t.Errorf("last call = %q, want the session destroyed", last)
+	}
+}
+
+func TestConsulHeld(t *testing.T) {
+	f := &fakeConsul{holder: "other (pid 1)"}
+	b := newTestConsul(t, f, "")
+
+	err := b.acquire(context.Background())
+	var held *HeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("acquire() error = %v, want a *HeldError", err)
+	}
+	if held.Holder != "other (pid 1)" {
+		t.Errorf("Holder = %q, want %q", held.Holder, "other (pid 1)")
+	}
+}
+
+func TestConsulServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	b, err := newConsulBackend(&url.URL{Scheme: "consul", Host: u.Host}, "job", "me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.acquire(context.Background())
+	var held *HeldError
+	if err == nil || errors.As(err, &held) {
+		t.Errorf("acquire() error = %v, want a server error", err)
+	}
+}
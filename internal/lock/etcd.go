@@ -0,0 +1,147 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// etcdBackend locks the key nazim/locks/<name> through etcd's v3 JSON gateway:
+// the key is created only if it doesn't exist, attached to a lease that is kept
+// alive while held and deletes the key when it expires.
+// Credentials in the URL are exchanged for an auth token.
+type etcdBackend struct {
+	base     string
+	username string
+	password string
+	token    string
+	key      string
+	owner    string
+	lease    string
+}
+
+func newEtcdBackend(u *url.URL, name, owner string) (*etcdBackend, error) {
+	base, err := httpBase(u, "2379")
+	if err != nil {
+		return nil, err
+	}
+	b := &etcdBackend{base: base, key: "nazim/locks/" + name, owner: owner}
+	if u.User != nil {
+		b.username = u.User.Username()
+		b.password, _ = u.User.Password()
+	}
+	return b, nil
+}
+
+func (b *etcdBackend) acquire(ctx context.Context) error {
+	if b.username != "" {
+		var auth struct{ Token string }
+		if err := b.call(ctx, "/v3/auth/authenticate", map[string]string{"name": b.username, "password": b.password}, &auth); err != nil {
+			return fmt.Errorf("authenticating with etcd: %w", err)
+		}
+		b.token = auth.Token
+	}
+
+	var grant struct{ ID string }
+	if err := b.call(ctx, "/v3/lease/grant", map[string]any{"TTL": int(TTL.Seconds())}, &grant); err != nil {
+		return fmt.Errorf("creating etcd lease: %w", err)
+	}
+	b.lease = grant.ID
+
+	key := base64.StdEncoding.EncodeToString([]byte(b.key))
+	txn := map[string]any{
+		"compare": []map[string]any{{"key": key, "target": "CREATE", "result": "EQUAL", "create_revision": "0"}},
+		"success": []map[string]any{{"request_put": map[string]any{
+			"key":   key,
+			"value": base64.StdEncoding.EncodeToString([]byte(b.owner)),
+			"lease": b.lease,
+		}}},
+		"failure": []map[string]any{{"request_range": map[string]any{"key": key}}},
+	}
+	var result struct {
+		Succeeded bool
+		Responses []struct {
+			ResponseRange struct {
+				Kvs []struct{ Value string }
+			} `json:"response_range"`
+		}
+	}
+	if err := b.call(ctx, "/v3/kv/txn", txn, &result); err != nil {
+		b.revoke(ctx)
+		return fmt.Errorf("acquiring etcd lock: %w", err)
+	}
+	if result.Succeeded {
+		return nil
+	}
+
+	b.revoke(ctx)
+	held := &HeldError{}
+	if len(result.Responses) > 0 && len(result.Responses[0].ResponseRange.Kvs) > 0 {
+		value, _ := base64.StdEncoding.DecodeString(result.Responses[0].ResponseRange.Kvs[0].Value)
+		held.Holder = string(value)
+	}
+	return held
+}
+
+func (b *etcdBackend) refresh(ctx context.Context) error {
+	var result struct {
+		Result struct{ TTL string }
+	}
+	if err := b.call(ctx, "/v3/lease/keepalive", map[string]string{"ID": b.lease}, &result); err != nil {
+		return err
+	}
+	// An expired lease is reported with a TTL of zero or less
+	if ttl, err := strconv.Atoi(result.Result.TTL); err == nil && ttl <= 0 {
+		return &HeldError{}
+	}
+	return nil
+}
+
+func (b *etcdBackend) release(ctx context.Context) error {
+	return b.revoke(ctx)
+}
+
+// revoke ends the lease, deleting the key if we hold it.
+func (b *etcdBackend) revoke(ctx context.Context) error {
+	return b.call(ctx, "/v3/lease/revoke", map[string]string{"ID": b.lease}, nil)
+}
+
+func (b *etcdBackend) call(ctx context.Context, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.base+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.token != "" {
+		req.Header.Set("Authorization", b.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
+}
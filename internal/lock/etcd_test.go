@@ -0,0 +1,132 @@
+package lock
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// fakeEtcd serves the v3 JSON gateway endpoints used by etcdBackend, with the
+// lock key already held by holder when it isn't empty.
+type fakeEtcd struct {
+	holder   string
+	leaseTTL string
+	auth     string
+	revoked  bool
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	if r.URL.Path != "/v3/auth/authenticate" {
+		f.auth = r.Header.Get("Authorization")
+	}
+	switch r.URL.Path {
+	case "/v3/auth/authenticate":
+		if body["name"] != "root" || body["password"] != "pw" {
+			http.Error(w, `{"error":"authentication failed"}`, http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, `{"token":"tok"}`)
+	case "/v3/lease/grant":
+		io.WriteString(w, `{"ID":"7","TTL":"60"}`)
+	case "/v3/kv/txn":
+		if f.holder != "" {
+			value := base64.StdEncoding.EncodeToString([]byte(f.holder))
+			io.WriteString(w, `{"responses":[{"response_range":{"kvs":[{"value":"`+value+`"}]}}]}`)
+			return
+		}
+		put := body["success"].([]any)[0].(map[string]any)["request_put"].(map[string]any)
+		value, _ := base64.StdEncoding.DecodeString(put["value"].(string))
+		f.holder = string(value)
+		io.WriteString(w, `{"succeeded":true}`)
+	case "/v3/lease/keepalive":
+		io.WriteString(w, `{"result":{"ID":"7","TTL":"`+f.leaseTTL+`"}}`)
+	case "/v3/lease/revoke":
+		f.revoked = true
+		io.WriteString(w, `{}`)
+	default:
+		http.Error(w, "unexpected request", http.StatusNotFound)
+	}
+}
+
+func newTestEtcd(t *testing.T, f *fakeEtcd, user *url.Userinfo) *etcdBackend {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	b, err := newEtcdBackend(&url.URL{Scheme: "etcd", Host: u.Host, User: user}, "job", "me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestEtcdAcquireRelease(t *testing.T) {
+	f := &fakeEtcd{leaseTTL: "60"}
+	b := newTestEtcd(t, f, url.UserPassword("root", "pw"))
+	ctx := context.Background()
+
+	if err := b.acquire(ctx); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if f.holder != "me" {
+		t.Errorf("lock holder = %q, want %q", f.holder, "me")
+	}
+	if f.auth != "tok" {
+		t.Errorf("Authorization = %q, want the token from authenticate", f.auth)
+	}
+	if err := b.refresh(ctx); err != nil {
+		t.Errorf("refresh() error = %v", err)
+	}
+	if err := b.release(ctx); err != nil {
+		t.Errorf("release() error = %v", err)
+	}
+	if !f.revoked {
+		t.Error("release() did not revoke the lease")
+	}
+}
+
+func TestEtcdHeld(t *testing.T) {
+	f := &fakeEtcd{holder: "other (pid 1)"}
+	b := newTestEtcd(t, f, nil)
+
+	err := b.acquire(context.Background())
+	var held *HeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("acquire() error = %v, want a *HeldError", err)
+	}
+	if held.Holder != "other (pid 1)" {
+		t.Errorf("Holder = %q, want %q", held.Holder, "other (pid 1)")
+	}
+	if !f.revoked {
+		t.Error("acquire() kept the lease of a lock it didn't get")
+	}
+}
+
+func TestEtcdExpiredLease(t *testing.T) {
+	f := &fakeEtcd{leaseTTL: "60"}
+	b := newTestEtcd(t, f, nil)
+	if err := b.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	f.leaseTTL = "0"
+	var held *HeldError
+	if err := b.refresh(context.Background()); !errors.As(err, &held) {
+		t.Errorf("refresh() of an expired lease error = %v, want a *HeldError", err)
+	}
+}
+
+func TestEtcdAuthFailure(t *testing.T) {
+	b := newTestEtcd(t, &fakeEtcd{}, url.UserPassword("root", "wrong"))
+	if err := b.acquire(context.Background()); err == nil {
+		t.Error("acquire() with a wrong password succeeded")
+	}
+}
@@ -0,0 +1,144 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileBackend locks by creating <dir>/<name>.lock exclusively. The file holds
+// its owner, and its modification time is refreshed while held; a file older
+// than the TTL is stale and may be taken over.
+//
+// Exclusive creation is atomic on local filesystems and on NFSv3 and later and
+// SMB, which makes this suitable for hosts sharing a mount.
+type fileBackend struct {
+	path  string
+	owner string
+}
+
+func newFileBackend(u *url.URL, name, owner string) (*fileBackend, error) {
+	dir := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		// file://server/share/locks is a UNC path on Windows
+		dir = `\\` + u.Host + filepath.FromSlash(u.Path)
+	} else if len(dir) > 2 && dir[0] == '/' && dir[2] == ':' {
+		// file:///C:/locks
+		dir = dir[1:]
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("lock URL %q has no directory", u.String())
+	}
+	return &fileBackend{path: filepath.Join(filepath.FromSlash(dir), name+".lock"), owner: owner}, nil
+}
+
+func (b *fileBackend) acquire(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(b.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.WriteString(b.owner)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(b.path)
+				return fmt.Errorf("writing lock file: %w", err)
+			}
+			return nil
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("creating lock file: %w", err)
+		}
+
+		info, statErr := os.Stat(b.path)
+		if statErr != nil {
+			if os.IsNotExist(statErr) && attempt == 0 {
+				continue // Released in the meantime
+			}
+			return fmt.Errorf("checking lock file: %w", statErr)
+		}
+		if time.Since(info.ModTime()) <= TTL || attempt > 0 {
+			return &HeldError{Holder: b.holder()}
+		}
+
+		// The holder stopped refreshing; take the stale lock over once
+		if err := b.takeOver(info); err != nil {
+			return err
+		}
+	}
+}
+
+// takeOver removes the stale lock file described by stale. Several hosts may
+// find it stale at once, and one of them may already have created a new lock
+// in its place, so the file is moved aside under a name of our own first: the
+// rename is atomic, and the moved file is only removed if it is still the
+// stale one. A fresh lock moved by mistake is put back.
+func (b *fileBackend) takeOver(stale os.FileInfo) error {
+	holder := b.holder()
+	if current, err := os.Stat(b.path); err != nil || !current.ModTime().Equal(stale.ModTime()) {
+		return nil // Refreshed, released, or taken over since
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("removing stale lock file: %w", err)
+	}
+	aside := fmt.Sprintf("%s.%x.stale", b.path, suffix)
+	if err := os.Rename(b.path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil // Taken over or released by someone else
+		}
+		return fmt.Errorf("removing stale lock file: %w", err)
+	}
+
+	moved, err := os.Stat(aside)
+	if movedHolder := readHolder(aside); err == nil && (!moved.ModTime().Equal(stale.ModTime()) || movedHolder != holder) {
+		// Another host took the lock over first and this moved its new one
+		err = os.Link(aside, b.path)
+		os.Remove(aside)
+		if err != nil {
+			return fmt.Errorf("restoring lock file: %w", err)
+		}
+		return &HeldError{Holder: movedHolder}
+	}
+	if err := os.Remove(aside); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale lock file: %w", err)
+	}
+	return nil
+}
+
+func (b *fileBackend) refresh(ctx context.Context) error {
+	if holder := b.holder(); holder != b.owner {
+		return &HeldError{Holder: holder}
+	}
+	now := time.Now()
+	return os.Chtimes(b.path, now, now)
+}
+
+func (b *fileBackend) release(ctx context.Context) error {
+	if b.holder() != b.owner {
+		return nil // Expired and taken over; not ours to remove
+	}
+	if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// holder returns the owner written to the lock file, or "" if it can't be read.
+func (b *fileBackend) holder() string {
+	return readHolder(b.path)
+}
+
+// readHolder returns the owner written to a lock file, or "" if it can't be read.
+func readHolder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
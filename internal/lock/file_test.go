@@ -0,0 +1,85 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// staleLock writes a lock file held by holder that stopped refreshing it.
+func staleLock(t *testing.T, path, holder string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(holder), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * TTL)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+	b := &fileBackend{path: path, owner: "me"}
+
+	if err := os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var held *HeldError
+	if err := b.acquire(context.Background()); !errors.As(err, &held) || held.Holder != "other" {
+		t.Fatalf("acquire() of a held lock error = %v, want it held by other", err)
+	}
+
+	staleLock(t, path, "other")
+	if err := b.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() of a stale lock error = %v", err)
+	}
+	if got := b.holder(); got != "me" {
+		t.Errorf("holder() = %q, want me", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("%d files left in the lock directory, want the lock only", len(entries))
+	}
+}
+
+func TestFileTakeOverRace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.lock")
+	for round := 0; round < 20; round++ {
+		staleLock(t, path, "crashed")
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			owners []string
+		)
+		for i := 0; i < 8; i++ {
+			b := &fileBackend{path: path, owner: fmt.Sprintf("host%d", i)}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := b.acquire(context.Background())
+				var held *HeldError
+				if err != nil && !errors.As(err, &held) {
+					t.Errorf("acquire() error = %v", err)
+					return
+				}
+				if err == nil {
+					mu.Lock()
+					owners = append(owners, b.owner)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if len(owners) != 1 {
+			t.Fatalf("round %d: %q took the stale lock over, want one host", round, owners)
+		}
+		if got := readHolder(path); got != owners[0] {
+			t.Fatalf("round %d: lock file held by %q, want %q", round, got, owners[0])
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package lock
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Scripts that only touch the key while it still holds our owner string.
+const (
+	redisRefreshScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	redisReleaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+// redisBackend locks with SET NX on the key nazim:lock:<name>, holding the
+// owner string and expiring after the TTL.
+type redisBackend struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       string
+	key      string
+	owner    string
+}
+
+func newRedisBackend(u *url.URL, name, owner string) (*redisBackend, error) {
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("lock URL %q has no host", u.Redacted())
+	}
+	b := &redisBackend{
+		addr:   u.Host,
+		useTLS: u.Scheme == "rediss",
+		db:     strings.Trim(u.Path, "/"),
+		key:    "nazim:lock:" + name,
+		owner:  owner,
+	}
+	if u.Port() == "" {
+		b.addr += ":6379"
+	}
+	if b.db != "" {
+		if _, err := strconv.Atoi(b.db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", b.db)
+		}
+	}
+	if u.User != nil {
+		b.username = u.User.Username()
+		b.password, _ = u.User.Password()
+	}
+	return b, nil
+}
+
+func (b *redisBackend) acquire(ctx context.Context) error {
+	reply, err := b.do(ctx, "SET", b.key, b.owner, "NX", "PX", strconv.FormatInt(TTL.Milliseconds(), 10))
+	if err != nil {
+		return err
+	}
+	if reply != nil {
+		return nil
+	}
+
+	holder, _ := b.do(ctx, "GET", b.key)
+	h, _ := holder.(string)
+	return &HeldError{Holder: h}
+}
+
+func (b *redisBackend) refresh(ctx context.Context) error {
+	reply, err := b.do(ctx, "EVAL", redisRefreshScript, "1", b.key, b.owner, strconv.FormatInt(TTL.Milliseconds(), 10))
+	if err != nil {
+		return err
+	}
+	if n, _ := reply.(int64); n == 0 {
+		return &HeldError{}
+	}
+	return nil
+}
+
+func (b *redisBackend) release(ctx context.Context) error {
+	_, err := b.do(ctx, "EVAL", redisReleaseScript, "1", b.key, b.owner)
+	return err
+}
+
+// do runs a command on a fresh connection, after authenticating and selecting
+// the database. Replies are strings, int64s, or nil for a nil bulk reply.
+func (b *redisBackend) do(ctx context.Context, args ...string) (any, error) {
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if b.useTLS {
+		host, _, _ := net.SplitHostPort(b.addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", b.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", b.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to Redis: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Closing the connection unblocks a read from a server that never replies
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	var commands [][]string
+	if b.password != "" {
+		if b.username != "" {
+			commands = append(commands, []string{"AUTH", b.username, b.password})
+		} else {
+			commands = append(commands, []string{"AUTH", b.password})
+		}
+	}
+	if b.db != "" {
+		commands = append(commands, []string{"SELECT", b.db})
+	}
+	commands = append(commands, args)
+
+	var reply any
+	for _, command := range commands {
+		if _, err := conn.Write(encodeRESP(command)); err != nil {
+			return nil, fmt.Errorf("writing to Redis: %w", contextErr(ctx, err))
+		}
+		if reply, err = readRESP(r); err != nil {
+			return nil, fmt.Errorf("redis %s: %w", command[0], contextErr(ctx, err))
+		}
+	}
+	return reply, nil
+}
+
+// contextErr reports ctx's error in place of err once ctx is done, since the
+// connection error is then only a consequence of closing it.
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// encodeRESP encodes a command as a RESP array of bulk strings.
+func encodeRESP(args []string) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(sb.String())
+}
+
+// readRESP reads a single reply. Arrays are not needed by the lock commands.
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,110 @@
+package lock
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeRESP(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "single", args: []string{"PING"}, want: "*1\r\n$4\r\nPING\r\n"},
+		{name: "set", args: []string{"SET", "k", "v"}, want: "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"},
+		{name: "empty argument", args: []string{"GET", ""}, want: "*2\r\n$3\r\nGET\r\n$0\r\n\r\n"},
+		{name: "CRLF in argument", args: []string{"a\r\nb"}, want: "*1\r\n$4\r\na\r\nb\r\n"},
+		{name: "multibyte", args: []string{"ção"}, want: "*1\r\n$5\r\nção\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(encodeRESP(tt.args)); got != tt.want {
+				t.Errorf("encodeRESP(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadRESP(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    any
+		wantErr string
+	}{
+		{name: "simple string", input: "+OK\r\n", want: "OK"},
+		{name: "integer", input: ":42\r\n", want: int64(42)},
+		{name: "negative integer", input: ":-1\r\n", want: int64(-1)},
+		{name: "bulk string", input: "$5\r\nhello\r\n", want: "hello"},
+		{name: "bulk string with CRLF", input: "$4\r\na\r\nb\r\n", want: "a\r\nb"},
+		{name: "empty bulk string", input: "$0\r\n\r\n", want: ""},
+		{name: "nil bulk string", input: "$-1\r\n", want: nil},
+		{name: "error", input: "-WRONGPASS invalid password\r\n", wantErr: "WRONGPASS invalid password"},
+		{name: "empty line", input: "\r\n", wantErr: "empty reply"},
+		{name: "array", input: "*1\r\n", wantErr: "unexpected reply"},
+		{name: "bad bulk length", input: "$x\r\n", wantErr: "invalid bulk length"},
+		{name: "truncated bulk string", input: "$5\r\nhel", wantErr: "EOF"},
+		{name: "no reply", input: "", wantErr: "EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRESP(bufio.NewReader(strings.NewReader(tt.input)))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readRESP(%q) error = %v, want one containing %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readRESP(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("readRESP(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisUnresponsiveServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept and never reply
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	b, err := newRedisBackend(&url.URL{Scheme: "redis", Host: ln.Addr().String()}, "job", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No deadline, only cancellation, as with a run stopped by a signal
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- b.acquire(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("acquire() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire() kept waiting on an unresponsive server after cancellation")
+	}
+}
@@ -0,0 +1,32 @@
+// Package notify shows native desktop notifications.
+//
+// Notifications need a graphical user session: services that run before logon
+// or as another user (such as Windows startup tasks running as SYSTEM) have no
+// desktop to show them on, and Desktop returns an error.
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// Desktop shows a notification with the given title and message. Failures are
+// shown as urgent where the platform supports it.
+func Desktop(title, message string, failed bool) error {
+	return desktop(title, message, failed)
+}
+
+// run runs a notifier command, including its output in the error.
+func run(name string, args ...string) error {
+	output, err := trace.CombinedOutput(exec.Command(name, args...))
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%s: %s: %w", name, msg, err)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
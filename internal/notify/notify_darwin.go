@@ -0,0 +1,34 @@
+//go:build darwin
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// desktop uses terminal-notifier when installed, and AppleScript's
+// display notification otherwise.
+func desktop(title, message string, failed bool) error {
+	if path, err := exec.LookPath("terminal-notifier"); err == nil {
+		args := []string{"-title", title, "-message", message, "-group", "nazim"}
+		if failed {
+			args = append(args, "-sound", "Basso")
+		}
+		return run(path, args...)
+	}
+
+	script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+	if failed {
+		script += ` sound name "Basso"`
+	}
+	return run("osascript", "-e", script)
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
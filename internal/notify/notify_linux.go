@@ -0,0 +1,13 @@
+//go:build linux
+
+package notify
+
+// desktop uses notify-send (libnotify), which talks to the session's
+// notification daemon over D-Bus.
+func desktop(title, message string, failed bool) error {
+	urgency := "normal"
+	if failed {
+		urgency = "critical"
+	}
+	return run("notify-send", "--app-name=nazim", "--urgency="+urgency, title, message)
+}
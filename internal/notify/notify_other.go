@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package notify
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func desktop(title, message string, failed bool) error {
+	return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// powerShellAppID is the AppUserModelID toasts are shown under; Windows only
+// shows toasts for registered apps, and PowerShell always is.
+const powerShellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// desktop shows a toast through the WinRT notification API from PowerShell.
+func desktop(title, message string, failed bool) error {
+	script := fmt.Sprintf(`
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode('%s')) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode('%s')) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('%s').Show($toast)
+`, quote(title), quote(message), quote(powerShellAppID))
+
+	return run("powershell", "-NoProfile", "-NonInteractive", "-WindowStyle", "Hidden", "-Command", script)
+}
+
+// quote escapes s for a PowerShell single-quoted string.
+func quote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
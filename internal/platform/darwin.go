@@ -7,16 +7,21 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/calilkhalil/nazim/internal/schedule"
 	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/trace"
 )
 
 // DarwinManager manages services on macOS using launchd.
-type DarwinManager struct{}
+type DarwinManager struct {
+	opts options
+}
 
 // NewDarwinManager creates a new manager for macOS.
-func NewDarwinManager() *DarwinManager {
-	return &DarwinManager{}
+func NewDarwinManager(opts ...Option) *DarwinManager {
+	return &DarwinManager{opts: newOptions(opts)}
 }
 
 func escapeXML(s string) string {
@@ -28,20 +33,106 @@ func escapeXML(s string) string {
 	return s
 }
 
-// Install installs a service on macOS using launchd.
+// escapeXMLComment makes s safe inside an XML comment, which can't contain "--".
+func escapeXMLComment(s string) string {
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "- -")
+	}
+	return s
+}
+
+// formatCalendarInterval converts a calendar schedule in local time to a launchd StartCalendarInterval value.
+// Daily schedules use a single dict; weekday schedules use an array with one dict per day.
+func formatCalendarInterval(sched *schedule.Schedule) string {
+	entry := func(indent string, day *time.Weekday) string {
+		var b strings.Builder
+		b.WriteString(indent + "<dict>\n")
+		if day != nil {
+			b.WriteString(fmt.Sprintf("%s  <key>Weekday</key>\n%s  <integer>%d</integer>\n", indent, indent, int(*day)))
+		}
+		b.WriteString(fmt.Sprintf("%s  <key>Hour</key>\n%s  <integer>%d</integer>\n", indent, indent, sched.Hour))
+		b.WriteString(fmt.Sprintf("%s  <key>Minute</key>\n%s  <integer>%d</integer>\n", indent, indent, sched.Minute))
+		b.WriteString(indent + "</dict>\n")
+		return b.String()
+	}
+
+	if sched.Daily() {
+		return entry("  ", nil)
+	}
+
+	var b strings.Builder
+	b.WriteString("  <array>\n")
+	for i := range sched.Days {
+		b.WriteString(entry("    ", &sched.Days[i]))
+	}
+	b.WriteString("  </array>\n")
+	return b.String()
+}
+
+// formatAlignedCalendarInterval converts the times of day an interval with an
+// offset runs at to a launchd StartCalendarInterval array. Intervals of an hour
+// or less repeat every hour, so they only need the minutes of the first one.
+func formatAlignedCalendarInterval(interval time.Duration, times []time.Duration) string {
+	var b strings.Builder
+	b.WriteString("  <array>\n")
+	for _, at := range times {
+		if interval <= time.Hour && at >= time.Hour {
+			break
+		}
+		b.WriteString("    <dict>\n")
+		if interval > time.Hour {
+			b.WriteString(fmt.Sprintf("      <key>Hour</key>\n      <integer>%d</integer>\n", int(at.Hours())))
+		}
+		b.WriteString(fmt.Sprintf("      <key>Minute</key>\n      <integer>%d</integer>\n", int(at.Minutes())%60))
+		b.WriteString("    </dict>\n")
+	}
+	b.WriteString("  </array>\n")
+	return b.String()
+}
+
+// Install installs a service on macOS using launchd: as a launch agent of the
+// current user, or for a system service, as a launch daemon.
 func (m *DarwinManager) Install(svc *service.Service) error {
-	home, err := getHomeDir()
+	job, err := launchdJobOf(svc)
+	if err != nil {
+		return err
+	}
+	content, err := m.plistContent(svc, job)
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
 
-	launchAgentsDir := filepath.Join(home, "Library", "LaunchAgents")
-	if err := os.MkdirAll(launchAgentsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create LaunchAgents directory: %w", err)
+	m.opts.step("Writing launchd plist")
+	if job.daemon() {
+		if err := requireRoot(svc.Name); err != nil {
+			return err
+		}
+		if err := m.checkDaemonAccess(svc); err != nil {
+			return err
+		}
+		if err := writeDaemonPlist(job.plist, content); err != nil {
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(job.plist), 0755); err != nil {
+			return fmt.Errorf("failed to create LaunchAgents directory: %w", err)
+		}
+		if err := os.WriteFile(job.plist, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write plist file: %w", err)
+		}
 	}
 
+	m.opts.step("Loading " + job.kind())
+	// Replace any loaded instance and clear a persisted disabled override
+	_ = trace.Run(exec.Command("launchctl", "bootout", job.target()))
+	_ = trace.Run(exec.Command("launchctl", "enable", job.target()))
+
+	return job.bootstrap()
+}
+
+// buildPlistContent generates the launchd plist for a service.
+func (m *DarwinManager) buildPlistContent(svc *service.Service) string {
 	normalizedName := normalizeServiceName(svc.Name)
-	plistFile := filepath.Join(launchAgentsDir, fmt.Sprintf("com.nazim.%s.plist", normalizedName))
 
 	var content strings.Builder
 	content.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
@@ -49,177 +140,501 @@ func (m *DarwinManager) Install(svc *service.Service) error {
 	content.WriteString("<plist version=\"1.0\">\n")
 	content.WriteString("<dict>\n")
 	content.WriteString(fmt.Sprintf("  <key>Label</key>\n  <string>com.nazim.%s</string>\n", normalizedName))
+	comments := metadataComments(svc)
+	if svc.Description != "" {
+		comments = append([]string{"Description: " + svc.Description}, comments...)
+	}
+	for _, comment := range comments {
+		content.WriteString(fmt.Sprintf("  <!-- %s -->\n", escapeXMLComment(comment)))
+	}
 	content.WriteString("  <key>ProgramArguments</key>\n")
 	content.WriteString("  <array>\n")
 
-	escapedCmd := escapeXML(svc.Command)
-	content.WriteString(fmt.Sprintf("    <string>%s</string>\n", escapedCmd))
+	// launchd has no native start delay, so startup-only agents sleep in a shell first
+	if delay := svc.EffectiveStartupDelay(); delay > 0 && !svc.RequiresScheduling() {
+		content.WriteString("    <string>/bin/sh</string>\n")
+		content.WriteString("    <string>-c</string>\n")
+		content.WriteString(fmt.Sprintf("    <string>sleep %d; exec \"$0\" \"$@\"</string>\n", int(delay.Seconds())))
+	}
 
-	for _, arg := range svc.Args {
-		escapedArg := escapeXML(arg)
-		content.WriteString(fmt.Sprintf("    <string>%s</string>\n", escapedArg))
+	// The agent runs the service through `nazim exec`, which captures stdout and stderr
+	for _, arg := range m.opts.execArgs(svc) {
+		content.WriteString(fmt.Sprintf("    <string>%s</string>\n", escapeXML(arg)))
 	}
 	content.WriteString("  </array>\n")
 
-	if svc.WorkDir != "" {
-		escapedWorkDir := escapeXML(svc.WorkDir)
-		content.WriteString(fmt.Sprintf("  <key>WorkingDirectory</key>\n  <string>%s</string>\n", escapedWorkDir))
+	switch svc.Priority {
+	case service.PriorityHigh:
+		content.WriteString("  <key>ProcessType</key>\n  <string>Interactive</string>\n")
+	case service.PriorityLow:
+		content.WriteString("  <key>ProcessType</key>\n  <string>Background</string>\n")
+		content.WriteString("  <key>LowPriorityIO</key>\n  <true/>\n")
+	}
+	if mask, err := service.ParseUmask(svc.Umask); svc.Umask != "" && err == nil {
+		// launchd takes the mask as a decimal integer
+		content.WriteString(fmt.Sprintf("  <key>Umask</key>\n  <integer>%d</integer>\n", uint32(mask)))
 	}
 
-	// Support both OnStartup and OnLogon (macOS LaunchAgents run at user login)
-	if svc.OnStartup || svc.OnLogon {
+	// Support both OnStartup and OnLogon (macOS LaunchAgents run at user login).
+	// Catch-up agents also run at load; `nazim exec --catch-up` skips the run
+	// unless one was missed while the machine was off.
+	if svc.OnStartup || svc.OnLogon || svc.CatchUp {
 		content.WriteString("  <key>RunAtLoad</key>\n  <true/>\n")
 	}
 
-	if svc.GetInterval() > 0 {
+	if times := svc.AlignedTimes(); len(times) > 0 {
+		content.WriteString("  <key>StartCalendarInterval</key>\n")
+		content.WriteString(formatAlignedCalendarInterval(svc.GetInterval(), times))
+	} else if svc.GetInterval() > 0 {
 		content.WriteString("  <key>StartInterval</key>\n")
 		content.WriteString(fmt.Sprintf("  <integer>%d</integer>\n", int(svc.GetInterval().Seconds())))
 	}
 
-	normalizedNameForLog := normalizeServiceName(svc.Name)
-	logDir := filepath.Join(home, ".nazim", "logs")
-	content.WriteString("  <key>StandardOutPath</key>\n")
-	content.WriteString(fmt.Sprintf("  <string>%s</string>\n", filepath.Join(logDir, fmt.Sprintf("%s.out", normalizedNameForLog))))
-	content.WriteString("  <key>StandardErrorPath</key>\n")
-	content.WriteString(fmt.Sprintf("  <string>%s</string>\n", filepath.Join(logDir, fmt.Sprintf("%s.err", normalizedNameForLog))))
+	if sched := svc.GetSchedule(); sched != nil {
+		content.WriteString("  <key>StartCalendarInterval</key>\n")
+		content.WriteString(formatCalendarInterval(localSchedule(sched)))
+	}
+
+	// launchd starts the agent when any of these paths is modified
+	if paths := svc.ResolvePaths(m.opts.configDir).WatchPaths; len(paths) > 0 {
+		content.WriteString("  <key>WatchPaths</key>\n")
+		content.WriteString("  <array>\n")
+		for _, path := range paths {
+			content.WriteString(fmt.Sprintf("    <string>%s</string>\n", escapeXML(path)))
+		}
+		content.WriteString("  </array>\n")
+	}
 
 	content.WriteString("</dict>\n")
 	content.WriteString("</plist>\n")
+	return content.String()
+}
+
+// Preview returns the plist Install would write for a service.
+func (m *DarwinManager) Preview(svc *service.Service) ([]Definition, error) {
+	job, err := launchdJobOf(svc)
+	if err != nil {
+		return nil, err
+	}
+	content, err := m.plistContent(svc, job)
+	if err != nil {
+		return nil, err
+	}
+	return []Definition{{Name: job.plist, Content: content}}, nil
+}
+
+// Export returns the plist of a service, as Preview does.
+func (m *DarwinManager) Export(svc *service.Service) ([]Definition, error) {
+	return m.Preview(svc)
+}
+
+// plistContent returns the plist Install writes for a service: its
+// hand-edited plist if it has one, and a generated one otherwise.
+func (m *DarwinManager) plistContent(svc *service.Service, job launchdJob) (string, error) {
+	if len(svc.Units) == 0 {
+		return m.buildPlistContent(svc), nil
+	}
+	defs, err := readUnits(svc, func(base string) string {
+		if base == filepath.Base(job.plist) {
+			return job.plist
+		}
+		return ""
+	})
+	if err != nil {
+		return "", err
+	}
+	// launchctl finds the job by its label, so it can't be changed
+	if !strings.Contains(defs[0].Content, "<string>"+job.label+"</string>") {
+		return "", fmt.Errorf("the Label of %s must stay %s", filepath.Base(job.plist), job.label)
+	}
+	return defs[0].Content, nil
+}
+
+// Artifacts returns the launch agent or daemon of a service and its plist.
+func (m *DarwinManager) Artifacts(svc *service.Service) ([]Artifact, error) {
+	job, err := launchdJobOf(svc)
+	if err != nil {
+		return nil, err
+	}
+	kind := "Launch agent"
+	if job.daemon() {
+		kind = "Launch daemon"
+	}
+	return []Artifact{{Kind: kind, Name: job.label, Path: job.plist}}, nil
+}
+
+// launchdJob is a job of a service in launchd: a launch agent in the current
+// user's GUI session, or a launch daemon in the system domain.
+type launchdJob struct {
+	label  string // com.nazim.<name>
+	domain string // gui/<uid>, or system
+	plist  string
+}
 
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
+// target returns the launchctl service target of the job.
+func (j launchdJob) target() string {
+	return j.domain + "/" + j.label
+}
+
+// daemon reports whether the job is a launch daemon.
+func (j launchdJob) daemon() bool {
+	return j.domain == launchdSystemDomain
+}
+
+// kind returns what the job is called in progress steps.
+func (j launchdJob) kind() string {
+	if j.daemon() {
+		return "daemon"
+	}
+	return "agent"
+}
+
+// bootstrap loads the job's plist into its domain.
+func (j launchdJob) bootstrap() error {
+	output, err := trace.CombinedOutput(exec.Command("launchctl", "bootstrap", j.domain, j.plist))
+	if err == nil {
+		return nil
+	}
+	if j.daemon() {
+		return daemonBootstrapError(strings.TrimSpace(string(output)), err)
+	}
+	return fmt.Errorf("failed to bootstrap service: %s: %w", strings.TrimSpace(string(output)), err)
+}
+
+// launchdDomain returns the launchd domain target for the current user's GUI session.
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// launchdAgent returns the launch agent of a service.
+func launchdAgent(name string) (launchdJob, error) {
+	home, err := getHomeDir()
+	if err != nil {
+		return launchdJob{}, fmt.Errorf("failed to get home directory: %w", err)
 	}
+	label := "com.nazim." + normalizeServiceName(name)
+	return launchdJob{
+		label:  label,
+		domain: launchdDomain(),
+		plist:  filepath.Join(home, "Library", "LaunchAgents", label+".plist"),
+	}, nil
+}
 
-	if err := os.WriteFile(plistFile, []byte(content.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write plist file: %w", err)
+// launchdJobOf returns the job Install creates for a service.
+func launchdJobOf(svc *service.Service) (launchdJob, error) {
+	if svc.System {
+		return launchdDaemon(svc.Name), nil
 	}
+	return launchdAgent(svc.Name)
+}
+
+// installedJob returns the job of an installed service, found by name: its
+// launch daemon when one is installed, and its launch agent otherwise.
+func installedJob(name string) (launchdJob, error) {
+	if job := launchdDaemon(name); fileExists(job.plist) {
+		return job, nil
+	}
+	return launchdAgent(name)
+}
 
-	cmd := exec.Command("launchctl", "load", plistFile)
-	if err := cmd.Run(); err != nil {
-		_ = exec.Command("launchctl", "unload", plistFile).Run()
-		cmd = exec.Command("launchctl", "load", plistFile)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to load service: %w", err)
+// isLaunchdNotLoaded reports whether launchctl output indicates the service is not loaded.
+func isLaunchdNotLoaded(output string) bool {
+	output = strings.ToLower(output)
+	return strings.Contains(output, "could not find service") ||
+		strings.Contains(output, "no such process") ||
+		strings.Contains(output, "not find")
+}
+
+// launchdPrint runs `launchctl print` for a job and returns its top-level properties.
+// Returns an error if the job is not loaded.
+func launchdPrint(job launchdJob) (map[string]string, error) {
+	output, err := trace.CombinedOutput(exec.Command("launchctl", "print", job.target()))
+	if err != nil {
+		if isLaunchdNotLoaded(string(output)) {
+			return nil, fmt.Errorf("service not loaded")
 		}
+		return nil, fmt.Errorf("failed to query launchd: %s: %w", strings.TrimSpace(string(output)), err)
 	}
+	return parseLaunchctlPrint(string(output)), nil
+}
 
-	return nil
+// parseLaunchctlPrint parses the "key = value" properties of the top-level block
+// of `launchctl print` output. Nested blocks are skipped.
+func parseLaunchctlPrint(output string) map[string]string {
+	props := make(map[string]string)
+	depth := 0
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, "{") {
+			depth++
+			continue
+		}
+		if trimmed == "}" {
+			depth--
+			continue
+		}
+		// Depth 1 is the service block itself
+		if depth != 1 {
+			continue
+		}
+		if key, value, ok := strings.Cut(trimmed, " = "); ok {
+			props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return props
+}
+
+// launchdDisabled reports whether the job has a persistent disabled override.
+func launchdDisabled(job launchdJob) (bool, error) {
+	output, err := trace.CombinedOutput(exec.Command("launchctl", "print-disabled", job.domain))
+	if err != nil {
+		return false, fmt.Errorf("failed to query disabled services: %w", err)
+	}
+
+	label := fmt.Sprintf("%q", job.label)
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=>")
+		if !ok || strings.TrimSpace(key) != label {
+			continue
+		}
+		// Newer macOS prints "disabled"/"enabled", older releases print true/false
+		value = strings.TrimSpace(value)
+		return value == "disabled" || value == "true", nil
+	}
+	return false, nil
 }
 
 // Uninstall removes a service from macOS.
 func (m *DarwinManager) Uninstall(name string) error {
-	home, err := getHomeDir()
+	job, err := m.changeableJob(name)
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
 
-	normalizedName := normalizeServiceName(name)
-	plistFile := filepath.Join(home, "Library", "LaunchAgents", fmt.Sprintf("com.nazim.%s.plist", normalizedName))
-
-	_ = exec.Command("launchctl", "unload", plistFile).Run()
+	failed := &MultiError{Service: name}
+	m.opts.step("Unloading " + job.kind())
+	if output, err := trace.CombinedOutput(exec.Command("launchctl", "bootout", job.target())); err != nil && !isLaunchdNotLoaded(string(output)) {
+		failed.add(job.kind()+" "+job.label, fmt.Errorf("failed to boot out service: %s: %w", strings.TrimSpace(string(output)), err))
+	}
+	// Drop the disabled override so a future service with the same name
+	// starts clean; a leftover override only matters to that service
+	_ = trace.Run(exec.Command("launchctl", "enable", job.target()))
 
-	if err := os.Remove(plistFile); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove plist file: %w", err)
+	if err := os.Remove(job.plist); err != nil && !os.IsNotExist(err) {
+		failed.add("plist "+job.plist, err)
 	}
 
-	return nil
+	return failed.verify(m)
 }
 
-// Enable enables a service on macOS (loads the launchd agent).
+// Enable enables a service on macOS.
+// The enabled state is persisted by launchd across logins, and the job is bootstrapped if needed.
 func (m *DarwinManager) Enable(name string) error {
-	home, err := getHomeDir()
+	job, err := m.changeableJob(name)
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
 
-	normalizedName := normalizeServiceName(name)
-	plistPath := filepath.Join(home, "Library", "LaunchAgents", fmt.Sprintf("com.nazim.%s.plist", normalizedName))
+	if output, err := trace.CombinedOutput(exec.Command("launchctl", "enable", job.target())); err != nil {
+		return fmt.Errorf("failed to enable service: %s: %w", strings.TrimSpace(string(output)), err)
+	}
 
-	cmd := exec.Command("launchctl", "load", plistPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to enable service: %s: %w", string(output), err)
+	if _, err := launchdPrint(job); err == nil {
+		// Already loaded
+		return nil
 	}
-	return nil
+	return job.bootstrap()
 }
 
-// Disable disables a service on macOS (unloads the launchd agent).
+// Disable disables a service on macOS.
+// The disabled state is persisted by launchd, so the job stays off across logins and reboots.
 func (m *DarwinManager) Disable(name string) error {
-	home, err := getHomeDir()
+	job, err := m.changeableJob(name)
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return err
 	}
 
-	normalizedName := normalizeServiceName(name)
-	plistPath := filepath.Join(home, "Library", "LaunchAgents", fmt.Sprintf("com.nazim.%s.plist", normalizedName))
+	if output, err := trace.CombinedOutput(exec.Command("launchctl", "disable", job.target())); err != nil {
+		return fmt.Errorf("failed to disable service: %s: %w", strings.TrimSpace(string(output)), err)
+	}
 
-	cmd := exec.Command("launchctl", "unload", plistPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		outputStr := strings.ToLower(string(output))
-		if strings.Contains(outputStr, "could not find service") {
-			return nil
-		}
-		return fmt.Errorf("failed to disable service: %s: %w", string(output), err)
+	if output, err := trace.CombinedOutput(exec.Command("launchctl", "bootout", job.target())); err != nil && !isLaunchdNotLoaded(string(output)) {
+		return fmt.Errorf("failed to boot out service: %s: %w", strings.TrimSpace(string(output)), err)
 	}
 	return nil
 }
 
 // Run executes a service immediately on macOS.
 func (m *DarwinManager) Run(name string) error {
-	normalizedName := normalizeServiceName(name)
-	label := fmt.Sprintf("com.nazim.%s", normalizedName)
+	job, err := m.changeableJob(name)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("launchctl", "kickstart", job.target())
+	output, err := trace.CombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to run service: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
 
-	cmd := exec.Command("launchctl", "start", label)
-	output, err := cmd.CombinedOutput()
+// Stop sends SIGTERM to the running job of a service. launchctl fails when
+// the job has no process, which counts as stopped.
+func (m *DarwinManager) Stop(name string) error {
+	job, err := m.changeableJob(name)
 	if err != nil {
-		return fmt.Errorf("failed to run service: %s: %w", string(output), err)
+		return err
+	}
+
+	output, err := trace.CombinedOutput(exec.Command("launchctl", "kill", "SIGTERM", job.target()))
+	if err != nil && !strings.Contains(string(output), "No process") {
+		return fmt.Errorf("failed to stop service: %s: %w", strings.TrimSpace(string(output)), err)
 	}
 	return nil
 }
 
+// changeableJob returns the job of an installed service for a command that
+// changes it. Launch daemons can only be changed by root.
+func (m *DarwinManager) changeableJob(name string) (launchdJob, error) {
+	job, err := installedJob(name)
+	if err != nil {
+		return launchdJob{}, err
+	}
+	if job.daemon() {
+		if err := requireRoot(name); err != nil {
+			return launchdJob{}, err
+		}
+	}
+	return job, nil
+}
+
 // IsInstalled checks if a service is installed.
 func (m *DarwinManager) IsInstalled(name string) (bool, error) {
-	home, err := getHomeDir()
+	job, err := installedJob(name)
 	if err != nil {
-		return false, fmt.Errorf("failed to get home directory: %w", err)
+		return false, err
 	}
+	return fileExists(job.plist), nil
+}
 
-	normalizedName := normalizeServiceName(name)
-	plistFile := filepath.Join(home, "Library", "LaunchAgents", fmt.Sprintf("com.nazim.%s.plist", normalizedName))
+// GetTaskState returns the state of a launchd job. launchd doesn't record
+// when it last started a job nor plan its next start ahead, so LastRun and
+// NextRun are left zero; see NextRun.
+// Returns error if the job is not found.
+func (m *DarwinManager) GetTaskState(name string) (*ServiceState, error) {
+	job, err := installedJob(name)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err = os.Stat(plistFile)
-	return err == nil, nil
-}
+	if !fileExists(job.plist) {
+		return nil, fmt.Errorf("%s not found", job.kind())
+	}
 
-// GetTaskState returns the state of a launchd agent ("Enabled" or "Disabled").
-// Returns error if agent is not found.
-func (m *DarwinManager) GetTaskState(name string) (string, error) {
-	home, err := getHomeDir()
+	disabled, err := launchdDisabled(job)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		return &ServiceState{Installed: true, BackendDetail: "disabled"}, nil
+	}
+
+	// Installed but not bootstrapped into its domain will not run either
+	props, err := launchdPrint(job)
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return &ServiceState{Installed: true, BackendDetail: "not loaded"}, nil
 	}
 
-	normalizedName := normalizeServiceName(name)
-	label := fmt.Sprintf("com.nazim.%s", normalizedName)
+	state := &ServiceState{
+		Installed:     true,
+		Enabled:       true,
+		Running:       props["state"] == "running",
+		BackendDetail: props["state"],
+	}
+	if runs := props["runs"]; runs != "" && runs != "0" {
+		state.LastResult = props["last exit code"]
+	}
+	return state, nil
+}
 
-	// Check if agent is loaded (enabled)
-	cmd := exec.Command("launchctl", "list")
-	output, err := cmd.CombinedOutput()
+// Details returns launchd's record of the job's last run.
+func (m *DarwinManager) Details(name string) (map[string]string, error) {
+	job, err := installedJob(name)
+	if err != nil {
+		return nil, err
+	}
+	props, err := launchdPrint(job)
 	if err != nil {
-		return "", fmt.Errorf("failed to query launchd: %w", err)
+		return nil, err
 	}
+	return pickDetails(props, "state", "runs", "last exit code", "last terminating signal"), nil
+}
 
-	// If the label appears in the list, it's loaded (enabled)
-	if strings.Contains(string(output), label) {
-		return "Enabled", nil
+// Raw returns the top-level properties `launchctl print` reports for the job.
+func (m *DarwinManager) Raw(name string) (map[string]string, error) {
+	job, err := installedJob(name)
+	if err != nil {
+		return nil, err
 	}
+	return launchdPrint(job)
+}
 
-	// Check if plist file exists (installed but not loaded = disabled)
-	plistFile := filepath.Join(home, "Library", "LaunchAgents", fmt.Sprintf("com.nazim.%s.plist", normalizedName))
-	if _, err := os.Stat(plistFile); err == nil {
-		return "Disabled", nil
+// NextRun estimates when launchd will next start the agent, which it doesn't
+// report. Calendar schedules and intervals with an offset are computed the way
+// launchd does. StartInterval counts from when the agent was loaded, so the
+// later of the plist's last write and the boot stands in for that. Agents that aren't loaded have no next run.
+func (m *DarwinManager) NextRun(svc *service.Service) (time.Time, error) {
+	job, err := launchdJobOf(svc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(job.plist)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	if _, err := launchdPrint(job); err != nil {
+		return time.Time{}, nil
 	}
 
-	return "", fmt.Errorf("agent not found")
+	now := time.Now()
+	var next time.Time
+	if sched := svc.GetSchedule(); sched != nil {
+		if runs := sched.NextN(now, 1); len(runs) > 0 {
+			next = runs[0]
+		}
+	}
+	if _, ok := svc.GetOffset(); ok {
+		next = svc.NextAlignedRun(now)
+	} else if interval := svc.GetInterval(); interval > 0 {
+		loaded := info.ModTime()
+		if boot, err := launchdBootTime(); err == nil && boot.After(loaded) {
+			loaded = boot
+		}
+		elapsed := max(now.Sub(loaded), 0)
+		if t := loaded.Add((elapsed/interval + 1) * interval); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next, nil
+}
+
+// LastRun returns the zero time: launchd counts an agent's runs but doesn't
+// record when it last started one.
+func (m *DarwinManager) LastRun(name string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+// launchdBootTime returns when the machine booted, from the kern.boottime
+// sysctl, which prints as "{ sec = 1760000000, usec = 0 } Thu Oct  9 ...".
+func launchdBootTime() (time.Time, error) {
+	output, err := trace.Output(exec.Command("sysctl", "-n", "kern.boottime"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query boot time: %w", err)
+	}
+	var sec int64
+	if _, err := fmt.Sscanf(string(output), "{ sec = %d,", &sec); err != nil {
+		return time.Time{}, fmt.Errorf("unexpected boot time %q", strings.TrimSpace(string(output)))
+	}
+	return time.Unix(sec, 0), nil
 }
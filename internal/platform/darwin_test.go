@@ -0,0 +1,49 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestBuildPlistContent(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  *service.Service
+		want string // The keys after ProgramArguments
+	}{
+		{
+			name: "interval",
+			svc:  &service.Service{Name: "job", Interval: service.Duration{Duration: time.Hour}},
+			want: "  <key>StartInterval</key>\n  <integer>3600</integer>\n",
+		},
+		{
+			name: "startup then interval",
+			svc:  &service.Service{Name: "job", OnStartup: true, Interval: service.Duration{Duration: time.Hour}},
+			want: "  <key>RunAtLoad</key>\n  <true/>\n  <key>StartInterval</key>\n  <integer>3600</integer>\n",
+		},
+		{
+			name: "logon then interval",
+			svc:  &service.Service{Name: "job", OnLogon: true, Interval: service.Duration{Duration: 24 * time.Hour}},
+			want: "  <key>RunAtLoad</key>\n  <true/>\n  <key>StartInterval</key>\n  <integer>86400</integer>\n",
+		},
+		{
+			name: "startup only",
+			svc:  &service.Service{Name: "job", OnStartup: true},
+			want: "  <key>RunAtLoad</key>\n  <true/>\n",
+		},
+	}
+	m := NewDarwinManager()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.buildPlistContent(tt.svc)
+			start := strings.Index(got, "  </array>\n") + len("  </array>\n")
+			end := strings.Index(got, "</dict>\n")
+			if keys := got[start:end]; keys != tt.want {
+				t.Errorf("buildPlistContent() keys =\n%s\nwant\n%s", keys, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// Windows has no network namespaces, so network none is enforced by Windows
+// Firewall: a rule in each direction blocks the program the service runs,
+// while `nazim exec` around it keeps its network access for uploads and
+// locks. Firewall rules match programs rather than processes, so they block
+// that program wherever it runs, and a service run through a shell nazim or
+// other software needs is refused instead.
+
+// firewallGroup is the rule group nazim's firewall rules are created in.
+const firewallGroup = "Nazim"
+
+// sharedPrograms are the programs a firewall rule must not block: the
+// shells nazim's own wrappers and scripts run through.
+var sharedPrograms = []string{service.ShellCmd, service.ShellPowerShell, service.ShellPwsh}
+
+// isolatedProgram returns the program the firewall rules of a service with
+// network none block, or why it can't have any.
+func isolatedProgram(svc *service.Service) (string, error) {
+	effective := svc.ForPlatform("windows")
+	switch {
+	case svc.RunsServices():
+		return "", fmt.Errorf("network %s blocks a program, and a pipeline or parallel group runs services instead; set it on them", service.NetworkNone)
+	case effective.IsOneLiner():
+		return "", fmt.Errorf("network %s blocks a program, and one-liners run in a shell; make the command a program of its own", service.NetworkNone)
+	}
+	switch strings.ToLower(filepath.Ext(effective.Command)) {
+	case ".bat", ".cmd", ".ps1":
+		return "", fmt.Errorf("network %s blocks a program, and %s runs through a shell that blocking would cut off for everything else", service.NetworkNone, effective.Command)
+	}
+	program, err := exec.LookPath(effective.Command)
+	if err != nil {
+		return "", fmt.Errorf("network %s: %w", service.NetworkNone, err)
+	}
+	program, err = filepath.Abs(program)
+	if err != nil {
+		return "", fmt.Errorf("network %s: %w", service.NetworkNone, err)
+	}
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(program), filepath.Ext(program)))
+	for _, shared := range sharedPrograms {
+		if base == shared {
+			return "", fmt.Errorf("network %s would block %s for everything that runs it; make the command a program of its own", service.NetworkNone, filepath.Base(program))
+		}
+	}
+	return program, nil
+}
+
+// blockNetwork creates the firewall rules of a service, blocking its program
+// in both directions.
+func blockNetwork(normalizedName, program string) error {
+	script := fmt.Sprintf(
+		"foreach ($d in 'Outbound', 'Inbound') { New-NetFirewallRule -DisplayName '%s' -Group '%s' -Direction $d -Program '%s' -Action Block | Out-Null }",
+		escapePowerShellSingleQuoted(firewallRuleName(normalizedName)), firewallGroup, escapePowerShellSingleQuoted(program))
+	if output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)); err != nil {
+		return fmt.Errorf("failed to create firewall rules: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// unblockNetwork deletes the firewall rules of a service, if it has any.
+func unblockNetwork(normalizedName string) {
+	script := fmt.Sprintf("Remove-NetFirewallRule -DisplayName '%s' -ErrorAction SilentlyContinue",
+		escapePowerShellSingleQuoted(firewallRuleName(normalizedName)))
+	_ = trace.Run(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script))
+}
@@ -0,0 +1,169 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// launchdSystemDomain is the launchd domain of launch daemons, which run as
+// root from boot, outside any login session.
+const launchdSystemDomain = "system"
+
+// launchDaemonsDir holds the plists of launch daemons. System Integrity
+// Protection leaves it writable by root, unlike /System/Library/LaunchDaemons.
+const launchDaemonsDir = "/Library/LaunchDaemons"
+
+// tccFolders are the folders of a home directory macOS privacy protection
+// (TCC) keeps processes from reading without the user's consent. Launch
+// daemons can't ask for it, so they need Full Disk Access instead.
+var tccFolders = []string{
+	"Desktop", "Documents", "Downloads",
+	filepath.Join("Library", "Mobile Documents"), // iCloud Drive
+	filepath.Join("Library", "CloudStorage"),
+}
+
+// launchdDaemon returns the launch daemon of a service.
+func launchdDaemon(name string) launchdJob {
+	label := "com.nazim." + normalizeServiceName(name)
+	return launchdJob{
+		label:  label,
+		domain: launchdSystemDomain,
+		plist:  filepath.Join(launchDaemonsDir, label+".plist"),
+	}
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// requireRoot returns an error unless nazim runs as root, which installing
+// and changing launch daemons takes.
+func requireRoot(name string) error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	return fmt.Errorf("'%s' is a system service, installed as a launch daemon in %s, which only root can change; run nazim with sudo",
+		name, launchDaemonsDir)
+}
+
+// writeDaemonPlist writes the plist of a launch daemon. launchd refuses to
+// load daemons whose plist isn't owned by root:wheel or is writable by others,
+// so the file is written next to its final path with that owner and mode 644,
+// and only then renamed over it.
+func writeDaemonPlist(path, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".nazim-*.plist")
+	if err != nil {
+		return fmt.Errorf("failed to write plist file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write plist file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write plist file: %w", err)
+	}
+	if err := os.Chown(tmp.Name(), 0, 0); err != nil {
+		return fmt.Errorf("failed to set the owner of the plist file to root:wheel: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set the permissions of the plist file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write plist file: %w", err)
+	}
+	return nil
+}
+
+// checkDaemonAccess checks the files a launch daemon of svc runs or reads
+// before it is installed. A daemon runs as root, so the programs it runs must
+// not be writable by other users. It also runs outside any login session, so
+// files in folders macOS privacy protection guards, such as ~/Documents, or
+// on other volumes, can only be read with Full Disk Access; when nazim can't
+// read them itself, the daemon won't either.
+func (m *DarwinManager) checkDaemonAccess(svc *service.Service) error {
+	resolved := svc.ResolvePaths(m.opts.configDir)
+
+	programs := []string{m.opts.executable}
+	if filepath.IsAbs(resolved.Command) {
+		programs = append(programs, resolved.Command)
+	}
+	for _, program := range programs {
+		if info, err := os.Stat(program); err == nil && info.Mode().Perm()&0022 != 0 {
+			return fmt.Errorf("'%s' can be modified by other users, and a launch daemon runs it as root; remove their write permission with chmod go-w", program)
+		}
+	}
+
+	paths := append(programs, m.opts.configDir, m.opts.dataDir, m.opts.stateDir, resolved.WorkDir)
+	paths = append(paths, resolved.WatchPaths...)
+	paths = append(paths, resolved.IfChanged...)
+	for _, path := range paths {
+		folder := protectedFolder(path)
+		if folder == "" || readable(path) {
+			continue
+		}
+		return fmt.Errorf("'%s' is in %s, which macOS only lets launch daemons read with Full Disk Access; "+
+			"grant it to %s in System Settings > Privacy & Security > Full Disk Access, or move the file elsewhere",
+			path, folder, m.opts.executable)
+	}
+	return nil
+}
+
+// protectedFolder returns the folder guarded by macOS privacy protection that
+// path is in, or "" when it isn't in one.
+func protectedFolder(path string) string {
+	if path == "" || !filepath.IsAbs(path) {
+		return ""
+	}
+	path = filepath.Clean(path)
+	if rest, ok := strings.CutPrefix(path, "/Volumes/"); ok {
+		volume, _, _ := strings.Cut(rest, "/")
+		return filepath.Join("/Volumes", volume)
+	}
+
+	rest, ok := strings.CutPrefix(path, "/Users/")
+	if !ok {
+		return ""
+	}
+	user, rest, _ := strings.Cut(rest, "/")
+	for _, folder := range tccFolders {
+		if rest == folder || strings.HasPrefix(rest, folder+"/") {
+			return filepath.Join("/Users", user, folder)
+		}
+	}
+	return ""
+}
+
+// readable reports whether this process can read path, listing it when it is
+// a directory. Only a permission error counts: a path that doesn't exist yet
+// may be created later.
+func readable(path string) bool {
+	f, err := os.Open(path)
+	if err == nil {
+		if info, statErr := f.Stat(); statErr == nil && info.IsDir() {
+			_, err = f.Readdirnames(1)
+		}
+		f.Close()
+	}
+	return !errors.Is(err, fs.ErrPermission)
+}
+
+// daemonBootstrapError explains a failed `launchctl bootstrap system`. launchd
+// says little more than "Operation not permitted" when System Integrity
+// Protection or privacy protection keep it from loading a daemon.
+func daemonBootstrapError(output string, err error) error {
+	if strings.Contains(output, "Operation not permitted") {
+		return fmt.Errorf("failed to bootstrap daemon: %s: %w; macOS refused to load it, which System Integrity Protection or privacy protection do when a daemon runs files they guard: "+
+			"grant nazim Full Disk Access in System Settings > Privacy & Security, or move the files out of protected folders", output, err)
+	}
+	return fmt.Errorf("failed to bootstrap daemon: %s: %w", output, err)
+}
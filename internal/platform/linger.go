@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// lingerDir holds an empty file named after each user whose systemd user
+// manager lingers.
+const lingerDir = "/var/lib/systemd/linger"
+
+// Linger reports whether the systemd user manager of the current user, which
+// runs nazim's services on Linux, lingers: starts at boot and keeps running
+// after they log out. Otherwise it only runs while they are logged in, and so
+// do their services. Other platforms have no user manager, so they report
+// true with an empty user name.
+func Linger() (username string, lingering bool, err error) {
+	if runtime.GOOS != "linux" {
+		return "", true, nil
+	}
+	current, err := user.Current()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get the current user: %w", err)
+	}
+	if fileExists(filepath.Join(lingerDir, current.Username)) {
+		return current.Username, true, nil
+	}
+
+	// Distributions may keep the flag elsewhere; logind knows for sure
+	output, err := trace.Output(exec.Command("loginctl", "show-user", current.Username, "--property=Linger", "--value"))
+	if err != nil {
+		return current.Username, false, fmt.Errorf("failed to ask logind whether %s lingers: %w", current.Username, err)
+	}
+	return current.Username, strings.TrimSpace(string(output)) == "yes", nil
+}
+
+// EnableLinger has logind make the current user's systemd user manager
+// linger, through sudo unless nazim runs as root. sudo may ask for a
+// password on the terminal.
+func EnableLinger() error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("lingering is a systemd setting, and only applies on Linux")
+	}
+	current, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("failed to get the current user: %w", err)
+	}
+
+	args := []string{"loginctl", "enable-linger", current.Username}
+	if os.Geteuid() != 0 {
+		args = append([]string{"sudo"}, args...)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := trace.Run(cmd); err != nil {
+		return fmt.Errorf("%s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
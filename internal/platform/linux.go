@@ -6,29 +6,119 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/calilkhalil/nazim/internal/schedule"
 	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/trace"
+	"github.com/calilkhalil/nazim/internal/watch"
 )
 
 // LinuxManager manages services on Linux using systemd.
-type LinuxManager struct{}
+type LinuxManager struct {
+	opts options
+}
 
 // NewLinuxManager creates a new manager for Linux.
-func NewLinuxManager() (*LinuxManager, error) {
+func NewLinuxManager(opts ...Option) (*LinuxManager, error) {
 	// Verify systemd is available
-	if err := exec.Command("systemctl", "--version").Run(); err != nil {
+	if err := trace.Run(exec.Command("systemctl", "--version")); err != nil {
 		return nil, fmt.Errorf("systemd is not available: %w", err)
 	}
-	return &LinuxManager{}, nil
+	return &LinuxManager{opts: newOptions(opts)}, nil
 }
 
 // Install installs a service on Linux using systemd.
 func (m *LinuxManager) Install(svc *service.Service) error {
+	if svc.OnWindowsHost() {
+		return fmt.Errorf("target %s needs nazim running inside WSL", service.TargetWindowsHost)
+	}
+	if len(svc.Units) > 0 {
+		return m.installUnits(svc)
+	}
 	return m.installSystemd(svc)
 }
 
+// installUnits installs the hand-edited units of a service instead of
+// generated ones, and enables its timer and path unit, or its service unit
+// when it has neither.
+func (m *LinuxManager) installUnits(svc *service.Service) error {
+	defs, err := m.unitDefinitions(svc)
+	if err != nil {
+		return err
+	}
+	if len(defs) > 0 {
+		if err := os.MkdirAll(filepath.Dir(defs[0].Name), 0755); err != nil {
+			return fmt.Errorf("failed to create systemd directory: %w", err)
+		}
+	}
+
+	m.opts.step("Writing unit files")
+	var triggers []string
+	for _, def := range defs {
+		if err := os.WriteFile(def.Name, []byte(def.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write unit file: %w", err)
+		}
+		if unit := filepath.Base(def.Name); !strings.HasSuffix(unit, ".service") {
+			triggers = append(triggers, unit)
+		}
+	}
+
+	m.opts.step("Reloading systemd")
+	if err := trace.Run(exec.Command("systemctl", "--user", "daemon-reload")); err != nil {
+		return fmt.Errorf("failed to reload systemd daemon: %w", err)
+	}
+	if len(triggers) == 0 {
+		m.opts.step("Enabling service")
+		if err := trace.Run(exec.Command("systemctl", "--user", "enable", fmt.Sprintf("nazim-%s.service", normalizeServiceName(svc.Name)))); err != nil {
+			return fmt.Errorf("failed to enable service: %w", err)
+		}
+		return nil
+	}
+	m.opts.step("Enabling units")
+	for _, unit := range triggers {
+		if err := trace.Run(exec.Command("systemctl", "--user", "enable", unit)); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", unit, err)
+		}
+		if err := trace.Run(exec.Command("systemctl", "--user", "start", unit)); err != nil {
+			return fmt.Errorf("failed to start %s: %w", unit, err)
+		}
+	}
+	return nil
+}
+
+// unitDefinitions returns the hand-edited units of a service as the files
+// they are installed as. They are named as Preview names the generated ones,
+// and include the service unit.
+func (m *LinuxManager) unitDefinitions(svc *service.Service) ([]Definition, error) {
+	home, err := getHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	userSystemdDir := filepath.Join(home, ".config", "systemd", "user")
+	unitName := "nazim-" + normalizeServiceName(svc.Name)
+
+	defs, err := readUnits(svc, func(base string) string {
+		switch base {
+		case unitName + ".service", unitName + ".timer", unitName + ".path":
+			return filepath.Join(userSystemdDir, base)
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, def := range defs {
+		if filepath.Base(def.Name) == unitName+".service" {
+			return defs, nil
+		}
+	}
+	return nil, fmt.Errorf("the unit files of '%s' must include %s.service", svc.Name, unitName)
+}
+
 func (m *LinuxManager) installSystemd(svc *service.Service) error {
 	home, err := getHomeDir()
 	if err != nil {
@@ -43,89 +133,292 @@ func (m *LinuxManager) installSystemd(svc *service.Service) error {
 	normalizedName := normalizeServiceName(svc.Name)
 	serviceFile := filepath.Join(userSystemdDir, fmt.Sprintf("nazim-%s.service", normalizedName))
 
-	logDir := filepath.Join(home, ".nazim", "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
-	}
-
-	// Build ExecStart with proper escaping to prevent directive injection
-	execStartLine, err := escapeSystemdExec(svc.Command, svc.Args)
+	content, err := m.buildServiceContent(svc)
 	if err != nil {
-		return fmt.Errorf("failed to escape command: %w", err)
+		return err
 	}
 
-	var content strings.Builder
-	content.WriteString("[Unit]\n")
-	content.WriteString(fmt.Sprintf("Description=Nazim Service: %s\n", escapeSystemdValue(svc.Name)))
-	content.WriteString("After=network.target\n\n")
-	content.WriteString("[Service]\n")
-	content.WriteString("Type=oneshot\n")
-	content.WriteString(execStartLine)
-	content.WriteString("\n")
-	if svc.WorkDir != "" {
-		content.WriteString(fmt.Sprintf("WorkingDirectory=%s\n", escapeSystemdValue(svc.WorkDir)))
+	pathUnit := fmt.Sprintf("nazim-%s.path", normalizedName)
+	if watchesFiles(svc) {
+		m.opts.step("Writing path unit")
+		if err := os.WriteFile(filepath.Join(userSystemdDir, pathUnit), []byte(m.buildPathContent(svc)), 0644); err != nil {
+			return fmt.Errorf("failed to write path file: %w", err)
+		}
 	}
-	normalizedNameForLog := normalizeServiceName(svc.Name)
-	logPath := filepath.Join(logDir, fmt.Sprintf("%s.log", normalizedNameForLog))
-	content.WriteString(fmt.Sprintf("StandardOutput=append:%s\n", logPath))
-	content.WriteString(fmt.Sprintf("StandardError=append:%s\n", logPath))
-	content.WriteString("\n")
-
-	if svc.GetInterval() > 0 {
-		content.WriteString("[Install]\n")
-		content.WriteString("WantedBy=default.target\n")
 
-		if err := os.WriteFile(serviceFile, []byte(content.String()), 0644); err != nil {
+	if svc.RequiresScheduling() {
+		m.opts.step("Writing unit files")
+		if err := os.WriteFile(serviceFile, []byte(content), 0644); err != nil {
 			return fmt.Errorf("failed to write service file: %w", err)
 		}
 
 		timerFile := filepath.Join(userSystemdDir, fmt.Sprintf("nazim-%s.timer", normalizedName))
-		interval := svc.GetInterval()
-		timerContent := fmt.Sprintf(`[Unit]
-Description=Timer for Nazim Service: %s
-
-[Timer]
-OnBootSec=%s
-OnUnitActiveSec=%s
-
-[Install]
-WantedBy=timers.target
-`, escapeSystemdValue(svc.Name), formatSystemdDuration(interval), formatSystemdDuration(interval))
+		timerContent := buildTimerContent(svc)
 
 		if err := os.WriteFile(timerFile, []byte(timerContent), 0644); err != nil {
 			return fmt.Errorf("failed to write timer file: %w", err)
 		}
 
-		if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
+		m.opts.step("Reloading systemd")
+		if err := trace.Run(exec.Command("systemctl", "--user", "daemon-reload")); err != nil {
 			return fmt.Errorf("failed to reload systemd daemon: %w", err)
 		}
-		if err := exec.Command("systemctl", "--user", "enable", fmt.Sprintf("nazim-%s.timer", normalizedName)).Run(); err != nil {
+		m.opts.step("Enabling timer")
+		if err := trace.Run(exec.Command("systemctl", "--user", "enable", fmt.Sprintf("nazim-%s.timer", normalizedName))); err != nil {
 			return fmt.Errorf("failed to enable timer: %w", err)
 		}
-		if err := exec.Command("systemctl", "--user", "start", fmt.Sprintf("nazim-%s.timer", normalizedName)).Run(); err != nil {
+		if err := trace.Run(exec.Command("systemctl", "--user", "start", fmt.Sprintf("nazim-%s.timer", normalizedName))); err != nil {
 			return fmt.Errorf("failed to start timer: %w", err)
 		}
-	} else if (svc.OnStartup || svc.OnLogon) && svc.GetInterval() == 0 {
-		// Both OnStartup and OnLogon use default.target for user services
-		// User services are started when the user logs in (graphical or console session)
-		content.WriteString("[Install]\n")
-		content.WriteString("WantedBy=default.target\n")
-
-		if err := os.WriteFile(serviceFile, []byte(content.String()), 0644); err != nil {
+		if svc.RepeatsAfterStartup() && svc.Enabled {
+			// OnStartupSec= has passed for this session and OnUnitActiveSec= only
+			// counts from a run, so the timer would stay idle until the next boot
+			m.opts.step("Starting first run")
+			if err := trace.Run(exec.Command("systemctl", "--user", "start", "--no-block", fmt.Sprintf("nazim-%s.service", normalizedName))); err != nil {
+				return fmt.Errorf("failed to start service: %w", err)
+			}
+		}
+	} else if svc.OnStartup || svc.OnLogon {
+		m.opts.step("Writing unit file")
+		if err := os.WriteFile(serviceFile, []byte(content), 0644); err != nil {
 			return fmt.Errorf("failed to write service file: %w", err)
 		}
 
-		if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
+		m.opts.step("Reloading systemd")
+		if err := trace.Run(exec.Command("systemctl", "--user", "daemon-reload")); err != nil {
 			return fmt.Errorf("failed to reload systemd daemon: %w", err)
 		}
-		if err := exec.Command("systemctl", "--user", "enable", fmt.Sprintf("nazim-%s.service", normalizedName)).Run(); err != nil {
+		m.opts.step("Enabling service")
+		if err := trace.Run(exec.Command("systemctl", "--user", "enable", fmt.Sprintf("nazim-%s.service", normalizedName))); err != nil {
 			return fmt.Errorf("failed to enable service: %w", err)
 		}
+	} else if len(svc.WatchPaths) > 0 {
+		// Only the path unit starts it
+		m.opts.step("Writing unit file")
+		if err := os.WriteFile(serviceFile, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write service file: %w", err)
+		}
+
+		m.opts.step("Reloading systemd")
+		if err := trace.Run(exec.Command("systemctl", "--user", "daemon-reload")); err != nil {
+			return fmt.Errorf("failed to reload systemd daemon: %w", err)
+		}
+	}
+
+	if watchesFiles(svc) {
+		m.opts.step("Enabling path unit")
+		if err := trace.Run(exec.Command("systemctl", "--user", "enable", pathUnit)); err != nil {
+			return fmt.Errorf("failed to enable path unit: %w", err)
+		}
+		if err := trace.Run(exec.Command("systemctl", "--user", "start", pathUnit)); err != nil {
+			return fmt.Errorf("failed to start path unit: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// watchesFiles reports whether a service gets a path unit: for its watch_paths,
+// or to run as soon as the files it watches with if_changed change.
+func watchesFiles(svc *service.Service) bool {
+	return len(svc.WatchPaths) > 0 || len(svc.IfChanged) > 0
+}
+
+// buildPathContent generates the systemd path unit that starts a service when
+// its watch_paths, or the files it watches with if_changed, change. Path units
+// only notice changes directly in a watched directory, and can't watch globs,
+// so for if_changed they watch the roots of the patterns: a change they miss
+// is still seen by the next scheduled run, and one that isn't in the patterns
+// is dismissed by `nazim exec`.
+func (m *LinuxManager) buildPathContent(svc *service.Service) string {
+	resolved := svc.ResolvePaths(m.opts.configDir)
+	paths := append(append([]string(nil), resolved.WatchPaths...), watch.Roots(resolved.IfChanged)...)
+
+	var content strings.Builder
+	content.WriteString("[Unit]\n")
+	content.WriteString(fmt.Sprintf("Description=Watch for Nazim Service: %s\n\n", escapeSystemdValue(svc.Name)))
+	content.WriteString("[Path]\n")
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			content.WriteString(fmt.Sprintf("PathChanged=%s\n", escapeSystemdValue(path)))
+		}
+	}
+	content.WriteString(fmt.Sprintf("Unit=nazim-%s.service\n", normalizeServiceName(svc.Name)))
+	content.WriteString("\n[Install]\n")
+	content.WriteString("WantedBy=paths.target\n")
+	return content.String()
+}
+
+// buildServiceContent generates the systemd service unit for a service.
+func (m *LinuxManager) buildServiceContent(svc *service.Service) (string, error) {
+	// The unit runs the service through `nazim exec`, which captures stdout and stderr
+	// into nazim's own logs. Build ExecStart with proper escaping to prevent directive injection.
+	execArgs := m.opts.execArgs(svc)
+	execStartLine, err := escapeSystemdExec(execArgs[0], execArgs[1:])
+	if err != nil {
+		return "", fmt.Errorf("failed to escape command: %w", err)
+	}
+
+	var content strings.Builder
+	for _, line := range metadataComments(svc) {
+		content.WriteString("# " + escapeSystemdValue(line) + "\n")
+	}
+	content.WriteString("[Unit]\n")
+	description := "Nazim Service: " + svc.Name
+	if svc.Description != "" {
+		description += ": " + svc.Description
+	}
+	content.WriteString(fmt.Sprintf("Description=%s\n", escapeSystemdValue(description)))
+	content.WriteString("After=network.target\n\n")
+	content.WriteString("[Service]\n")
+	content.WriteString("Type=oneshot\n")
+	if delay := svc.EffectiveStartupDelay(); delay > 0 && !svc.RequiresScheduling() {
+		sleepPath, err := exec.LookPath("sleep")
+		if err != nil {
+			sleepPath = "/bin/sleep"
+		}
+		content.WriteString(fmt.Sprintf("ExecStartPre=%s %d\n", quoteSystemdArg(sleepPath), int(delay.Seconds())))
+	}
+	content.WriteString(execStartLine)
+	content.WriteString("\n")
+	if svc.Priority == service.PriorityLow {
+		// Unprivileged user managers can only lower priority, so only the low class maps to Nice=
+		content.WriteString("Nice=10\n")
+	}
+	if mask, err := service.ParseUmask(svc.Umask); svc.Umask != "" && err == nil {
+		// Also covers the logs nazim writes for the service
+		content.WriteString(fmt.Sprintf("UMask=%s\n", service.FormatUmask(mask)))
+	}
+	for _, line := range m.sandboxDirectives(svc) {
+		content.WriteString(line + "\n")
+	}
+	if svc.Network == service.NetworkNone {
+		// A network namespace of its own, with only a loopback device
+		content.WriteString("PrivateNetwork=yes\n")
+	}
+	content.WriteString("\n")
+
+	if svc.RequiresScheduling() || svc.OnStartup || svc.OnLogon {
+		// Both OnStartup and OnLogon use default.target for user services
+		// User services are started when the user logs in (graphical or console session)
+		content.WriteString("[Install]\n")
+		content.WriteString("WantedBy=default.target\n")
+	}
+
+	return content.String(), nil
+}
+
+// sandboxDirectives returns the hardening directives of a service's unit for
+// its sandbox level, see service.SandboxLevels. The user manager runs them in
+// a user namespace of their own, which needs unprivileged user namespaces.
+func (m *LinuxManager) sandboxDirectives(svc *service.Service) []string {
+	if svc.Sandbox == "" {
+		return nil
+	}
+	lines := []string{
+		"NoNewPrivileges=yes",
+		"PrivateTmp=yes",
+		"ProtectHome=read-only",
+	}
+	// `nazim exec` writes the logs, history, and state of each run, and
+	// records versions and checksums in nazim's own directories
+	var writable []string
+	for _, dir := range []string{m.opts.configDir, m.opts.dataDir, m.opts.stateDir} {
+		if dir != "" && !slices.Contains(writable, dir) {
+			writable = append(writable, dir)
+		}
+	}
+	// disable-self-after disables the service's units through systemctl,
+	// which removes their links from the user unit directory
+	if policy := svc.GetFailurePolicy(); policy != nil && policy.DisableAfter > 0 {
+		if home, err := getHomeDir(); err == nil {
+			writable = append(writable, filepath.Join(home, ".config", "systemd", "user"))
+		}
+	}
+	switch svc.Sandbox {
+	case service.SandboxBasic:
+		lines = append(lines, "ProtectSystem=full")
+		if workDir := svc.ForPlatform("linux").WorkDir; workDir != "" {
+			writable = append(writable, workDir)
+		}
+	case service.SandboxStrict:
+		lines = append(lines,
+			"ProtectSystem=strict",
+			"PrivateDevices=yes",
+			"ProtectKernelTunables=yes",
+			"ProtectKernelModules=yes",
+			"ProtectControlGroups=yes",
+			"RestrictSUIDSGID=yes",
+			"RestrictRealtime=yes",
+			"LockPersonality=yes",
+		)
+	}
+	if len(writable) > 0 {
+		// A leading - skips paths that don't exist, which would fail the run
+		quoted := make([]string, len(writable))
+		for i, path := range writable {
+			quoted[i] = quoteSystemdArg("-" + path)
+		}
+		lines = append(lines, "ReadWritePaths="+strings.Join(quoted, " "))
+	}
+	return lines
+}
+
+// Preview returns the unit files Install would write for a service.
+func (m *LinuxManager) Preview(svc *service.Service) ([]Definition, error) {
+	if len(svc.Units) > 0 {
+		return m.unitDefinitions(svc)
+	}
+	home, err := getHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	userSystemdDir := filepath.Join(home, ".config", "systemd", "user")
+	normalizedName := normalizeServiceName(svc.Name)
+
+	content, err := m.buildServiceContent(svc)
+	if err != nil {
+		return nil, err
+	}
+	defs := []Definition{{Name: filepath.Join(userSystemdDir, fmt.Sprintf("nazim-%s.service", normalizedName)), Content: content}}
+	if svc.RequiresScheduling() {
+		defs = append(defs, Definition{Name: filepath.Join(userSystemdDir, fmt.Sprintf("nazim-%s.timer", normalizedName)), Content: buildTimerContent(svc)})
+	}
+	if watchesFiles(svc) {
+		defs = append(defs, Definition{Name: filepath.Join(userSystemdDir, fmt.Sprintf("nazim-%s.path", normalizedName)), Content: m.buildPathContent(svc)})
+	}
+	return defs, nil
+}
+
+// Export returns the unit files of a service, as Preview does.
+func (m *LinuxManager) Export(svc *service.Service) ([]Definition, error) {
+	return m.Preview(svc)
+}
+
+// Artifacts returns the units of a service and their files.
+func (m *LinuxManager) Artifacts(svc *service.Service) ([]Artifact, error) {
+	home, err := getHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	userSystemdDir := filepath.Join(home, ".config", "systemd", "user")
+	normalizedName := normalizeServiceName(svc.Name)
+
+	unit := fmt.Sprintf("nazim-%s.service", normalizedName)
+	artifacts := []Artifact{{Kind: "Service unit", Name: unit, Path: filepath.Join(userSystemdDir, unit)}}
+	if svc.RequiresScheduling() {
+		timer := fmt.Sprintf("nazim-%s.timer", normalizedName)
+		artifacts = append(artifacts, Artifact{Kind: "Timer unit", Name: timer, Path: filepath.Join(userSystemdDir, timer)})
+	}
+	if watchesFiles(svc) {
+		path := fmt.Sprintf("nazim-%s.path", normalizedName)
+		artifacts = append(artifacts, Artifact{Kind: "Path unit", Name: path, Path: filepath.Join(userSystemdDir, path)})
+	}
+	return artifacts, nil
+}
+
 // Uninstall removes a service from Linux.
 func (m *LinuxManager) Uninstall(name string) error {
 	home, err := getHomeDir()
@@ -134,48 +427,94 @@ func (m *LinuxManager) Uninstall(name string) error {
 	}
 
 	normalizedName := normalizeServiceName(name)
-	serviceName := fmt.Sprintf("nazim-%s", normalizedName)
-	timerName := fmt.Sprintf("nazim-%s.timer", normalizedName)
-
-	_ = exec.Command("systemctl", "--user", "stop", timerName).Run()
-	_ = exec.Command("systemctl", "--user", "disable", timerName).Run()
-
-	_ = exec.Command("systemctl", "--user", "stop", fmt.Sprintf("%s.service", serviceName)).Run()
-	_ = exec.Command("systemctl", "--user", "disable", fmt.Sprintf("%s.service", serviceName)).Run()
+	units := []struct{ kind, name string }{
+		{"Timer unit", fmt.Sprintf("nazim-%s.timer", normalizedName)},
+		{"Path unit", fmt.Sprintf("nazim-%s.path", normalizedName)},
+		{"Service unit", fmt.Sprintf("nazim-%s.service", normalizedName)},
+	}
+	failed := &MultiError{Service: name}
+
+	// Units that aren't installed or running fail to stop or disable, which
+	// is what we want; whether they are gone is verified below
+	m.opts.step("Stopping units")
+	for _, unit := range units {
+		_ = trace.Run(exec.Command("systemctl", "--user", "stop", unit.name))
+		_ = trace.Run(exec.Command("systemctl", "--user", "disable", unit.name))
+	}
 
+	m.opts.step("Removing unit files")
 	userSystemdDir := filepath.Join(home, ".config", "systemd", "user")
-	_ = os.Remove(filepath.Join(userSystemdDir, fmt.Sprintf("%s.service", serviceName)))
-	_ = os.Remove(filepath.Join(userSystemdDir, timerName))
+	for _, unit := range units {
+		if err := os.Remove(filepath.Join(userSystemdDir, unit.name)); err != nil && !os.IsNotExist(err) {
+			failed.add(unit.kind+" "+unit.name, err)
+		}
+	}
 
-	if err := exec.Command("systemctl", "--user", "daemon-reload").Run(); err != nil {
-		return fmt.Errorf("failed to reload systemd daemon: %w", err)
+	m.opts.step("Reloading systemd")
+	if err := trace.Run(exec.Command("systemctl", "--user", "daemon-reload")); err != nil {
+		failed.add("systemd user manager", fmt.Errorf("failed to reload systemd daemon: %w", err))
 	}
 
-	return nil
+	m.opts.step("Verifying removal")
+	for _, unit := range units {
+		output, _ := trace.Output(exec.Command("systemctl", "--user", "is-active", unit.name))
+		switch state := strings.TrimSpace(string(output)); state {
+		case "active", "activating", "deactivating", "reloading":
+			failed.add(unit.kind+" "+unit.name, fmt.Errorf("still %s", state))
+		}
+	}
+	return failed.verify(m)
 }
 
 // Enable enables a service on Linux (allows it to start automatically).
 func (m *LinuxManager) Enable(name string) error {
-	normalizedName := normalizeServiceName(name)
-	timerName := fmt.Sprintf("nazim-%s.timer", normalizedName)
-
-	cmd := exec.Command("systemctl", "--user", "enable", timerName)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to enable service: %w", err)
+	for _, unit := range m.triggerUnits(name) {
+		cmd := exec.Command("systemctl", "--user", "enable", unit)
+		if err := trace.Run(cmd); err != nil {
+			return fmt.Errorf("failed to enable service: %w", err)
+		}
 	}
 	return nil
 }
 
 // Disable disables a service on Linux (prevents automatic start).
 func (m *LinuxManager) Disable(name string) error {
+	for _, unit := range m.triggerUnits(name) {
+		cmd := exec.Command("systemctl", "--user", "disable", unit)
+		if err := trace.Run(cmd); err != nil {
+			return fmt.Errorf("failed to disable service: %w", err)
+		}
+	}
+	return nil
+}
+
+// triggerUnits returns the units that start a service: its timer, its path
+// unit, or both. A service with neither, which runs at startup or logon, gets
+// its service unit, which is enabled itself; one with nothing installed gets
+// its timer, so that errors name what is missing.
+func (m *LinuxManager) triggerUnits(name string) []string {
 	normalizedName := normalizeServiceName(name)
-	timerName := fmt.Sprintf("nazim-%s.timer", normalizedName)
+	timer := fmt.Sprintf("nazim-%s.timer", normalizedName)
+	path := fmt.Sprintf("nazim-%s.path", normalizedName)
 
-	cmd := exec.Command("systemctl", "--user", "disable", timerName)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to disable service: %w", err)
+	home, err := getHomeDir()
+	if err != nil {
+		return []string{timer}
 	}
-	return nil
+	userSystemdDir := filepath.Join(home, ".config", "systemd", "user")
+	var units []string
+	for _, unit := range []string{timer, path} {
+		if fileExists(filepath.Join(userSystemdDir, unit)) {
+			units = append(units, unit)
+		}
+	}
+	if len(units) == 0 {
+		if serviceUnit := fmt.Sprintf("nazim-%s.service", normalizedName); fileExists(filepath.Join(userSystemdDir, serviceUnit)) {
+			return []string{serviceUnit}
+		}
+		return []string{timer}
+	}
+	return units
 }
 
 // Run executes a service immediately on Linux.
@@ -184,61 +523,170 @@ func (m *LinuxManager) Run(name string) error {
 	serviceName := fmt.Sprintf("nazim-%s.service", normalizedName)
 
 	cmd := exec.Command("systemctl", "--user", "start", serviceName)
-	if err := cmd.Run(); err != nil {
+	if err := trace.Run(cmd); err != nil {
 		return fmt.Errorf("failed to run service: %w", err)
 	}
 	return nil
 }
 
+// Stop stops the running service unit of a service.
+func (m *LinuxManager) Stop(name string) error {
+	serviceName := fmt.Sprintf("nazim-%s.service", normalizeServiceName(name))
+
+	output, err := trace.CombinedOutput(exec.Command("systemctl", "--user", "stop", serviceName))
+	if err != nil {
+		return fmt.Errorf("failed to stop service: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
 // IsInstalled checks if a service is installed.
 func (m *LinuxManager) IsInstalled(name string) (bool, error) {
 	normalizedName := normalizeServiceName(name)
 	serviceName := fmt.Sprintf("nazim-%s.service", normalizedName)
 
 	cmd := exec.Command("systemctl", "--user", "is-enabled", serviceName)
-	err := cmd.Run()
+	err := trace.Run(cmd)
 	return err == nil, nil
 }
 
-// GetTaskState returns the state of a systemd timer ("Enabled" or "Disabled").
-// Returns error if timer is not found.
-func (m *LinuxManager) GetTaskState(name string) (string, error) {
-	normalizedName := normalizeServiceName(name)
-	timerName := fmt.Sprintf("nazim-%s.timer", normalizedName)
+// GetTaskState returns the state of a service: whether the unit that starts
+// it (see triggerUnits) is enabled, and what systemd reports of the runs of
+// its service unit.
+// Returns error if the unit is not found.
+func (m *LinuxManager) GetTaskState(name string) (*ServiceState, error) {
+	unit := m.triggerUnits(name)[0]
 
-	cmd := exec.Command("systemctl", "--user", "is-enabled", timerName)
-	output, err := cmd.CombinedOutput()
+	cmd := exec.Command("systemctl", "--user", "is-enabled", unit)
+	output, err := trace.CombinedOutput(cmd)
 
 	outputStr := strings.TrimSpace(string(output))
 
 	if err != nil {
-		// If command failed, timer might not exist or be in a bad state
+		// If command failed, the unit might not exist or be in a bad state
 		if strings.Contains(outputStr, "not found") || strings.Contains(outputStr, "No such file") {
-			return "", fmt.Errorf("timer not found")
+			return nil, fmt.Errorf("%s not found", unit)
 		}
 		// If disabled, is-enabled returns exit code 1 with "disabled" output
-		if outputStr == "disabled" {
-			return "Disabled", nil
+		if outputStr != "disabled" {
+			return nil, fmt.Errorf("failed to query unit state: %w", err)
 		}
-		return "", fmt.Errorf("failed to query timer state: %w", err)
 	}
 
 	// is-enabled returns "enabled" if active
-	if outputStr == "enabled" || outputStr == "static" {
-		return "Enabled", nil
+	state := &ServiceState{
+		Installed:     true,
+		Enabled:       outputStr == "enabled" || outputStr == "static",
+		BackendDetail: outputStr,
+	}
+
+	// The rest is what systemd remembers, which a restart of the user's
+	// service manager forgets, so it's only filled in when it is there
+	props, err := systemctlShow(fmt.Sprintf("nazim-%s.service", normalizeServiceName(name)),
+		"--property=LoadState,ActiveState,Result,ExecMainStartTimestamp")
+	if err == nil {
+		// The service unit is a oneshot, which is activating while it runs
+		state.Running = props["ActiveState"] == "active" || props["ActiveState"] == "activating"
+		if state.LastRun, _ = parseSystemdTimestamp(props["ExecMainStartTimestamp"]); !state.LastRun.IsZero() {
+			state.LastResult = props["Result"]
+		}
 	}
+	if strings.HasSuffix(unit, ".timer") {
+		state.NextRun, _ = timerNextRun(unit)
+	}
+	return state, nil
+}
+
+// buildTimerContent generates the systemd timer unit for an interval or calendar schedule.
+func buildTimerContent(svc *service.Service) string {
+	var timer strings.Builder
+	timer.WriteString("[Unit]\n")
+	timer.WriteString(fmt.Sprintf("Description=Timer for Nazim Service: %s\n\n", escapeSystemdValue(svc.Name)))
+	timer.WriteString("[Timer]\n")
+
+	if sched := svc.GetSchedule(); sched != nil {
+		timer.WriteString(fmt.Sprintf("OnCalendar=%s\n", formatOnCalendar(sched)))
+		if svc.CatchUp {
+			// Fire once at boot if a calendar run elapsed while the machine was off
+			timer.WriteString("Persistent=true\n")
+		}
+	} else if offset, ok := svc.GetOffset(); ok {
+		timer.WriteString(fmt.Sprintf("OnCalendar=%s\n", formatAlignedCalendar(svc.GetInterval(), offset)))
+		if svc.CatchUp {
+			timer.WriteString("Persistent=true\n")
+		}
+	} else {
+		interval := svc.GetInterval()
+		switch {
+		case svc.OnStartup || svc.OnLogon:
+			// Run once the startup delay has passed, as the task does on Windows.
+			// The user manager starts at boot for lingering users, otherwise at logon
+			timer.WriteString(fmt.Sprintf("OnStartupSec=%s\n", formatSystemdDuration(svc.EffectiveStartupDelay())))
+		case svc.CatchUp:
+			// Persistent= only applies to OnCalendar; check soon after boot instead
+			// and let `nazim exec --catch-up` decide whether a run was missed
+			timer.WriteString(fmt.Sprintf("OnBootSec=%s\n", formatSystemdDuration(time.Minute)))
+		default:
+			timer.WriteString(fmt.Sprintf("OnBootSec=%s\n", formatSystemdDuration(interval)))
+		}
+		timer.WriteString(fmt.Sprintf("OnUnitActiveSec=%s\n", formatSystemdDuration(interval)))
+		if interval < time.Minute {
+			// Timers coalesce within a minute by default, which would swallow shorter intervals
+			timer.WriteString("AccuracySec=1s\n")
+		}
+	}
+
+	timer.WriteString("\n[Install]\n")
+	timer.WriteString("WantedBy=timers.target\n")
+	return timer.String()
+}
+
+// formatOnCalendar converts a calendar schedule to a systemd OnCalendar
+// expression, ending with the schedule's time zone if it has one, such as
+// "Mon,Fri *-*-* 09:00:00 Europe/Lisbon".
+func formatOnCalendar(sched *schedule.Schedule) string {
+	clock := fmt.Sprintf("%02d:%02d:00", sched.Hour, sched.Minute)
+	if sched.Location != nil {
+		clock += " " + sched.Location.String()
+	}
+	if sched.Daily() {
+		return "*-*-* " + clock
+	}
+
+	days := make([]string, len(sched.Days))
+	for i, d := range sched.Days {
+		days[i] = d.String()[:3]
+	}
+	return strings.Join(days, ",") + " *-*-* " + clock
+}
 
-	return "Disabled", nil
+// formatAlignedCalendar converts an interval with an offset to a systemd
+// OnCalendar expression, such as "*-*-* *:05/15:00" for every 15 minutes from
+// 00:05, or "*-*-* 01/6:30:00" for every 6 hours from 01:30.
+func formatAlignedCalendar(interval, offset time.Duration) string {
+	if interval <= time.Hour {
+		return fmt.Sprintf("*-*-* *:%02d/%d:00", int(offset.Minutes()), int(interval.Minutes()))
+	}
+	return fmt.Sprintf("*-*-* %02d/%d:%02d:00", int(offset.Hours()), int(interval.Hours()), int(offset.Minutes())%60)
 }
 
+// formatSystemdDuration formats a duration as a systemd time span.
 func formatSystemdDuration(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%ds", int(d.Seconds()))
 	}
-	if d < time.Hour {
-		return fmt.Sprintf("%dm", int(d.Minutes()))
+	// Whole units, such as 1h30m, which both systemd and time.ParseDuration read
+	var b strings.Builder
+	if hours := int(d.Hours()); hours > 0 {
+		fmt.Fprintf(&b, "%dh", hours)
+	}
+	if minutes := int(d.Minutes()) % 60; minutes > 0 {
+		fmt.Fprintf(&b, "%dm", minutes)
 	}
-	return fmt.Sprintf("%dh", int(d.Hours()))
+	if seconds := int(d.Seconds()) % 60; seconds > 0 {
+		fmt.Fprintf(&b, "%ds", seconds)
+	}
+	return b.String()
 }
 
 // escapeSystemdExec escapes a command and arguments for use in systemd ExecStart directives.
@@ -300,3 +748,112 @@ func escapeSystemdValue(s string) string {
 	s = strings.ReplaceAll(s, "\r", " ")
 	return s
 }
+
+// Details returns the systemd result of the service's last run.
+func (m *LinuxManager) Details(name string) (map[string]string, error) {
+	props, err := systemctlShow(fmt.Sprintf("nazim-%s.service", normalizeServiceName(name)),
+		"--property=LoadState,ActiveState,Result,ExecMainStatus,ExecMainExitTimestamp")
+	if err != nil {
+		return nil, err
+	}
+	return pickDetails(props, "ActiveState", "Result", "ExecMainStatus", "ExecMainExitTimestamp"), nil
+}
+
+// Raw returns every property `systemctl show` reports for the service unit,
+// and for its timer prefixed with "Timer.", when the service has one.
+func (m *LinuxManager) Raw(name string) (map[string]string, error) {
+	normalizedName := normalizeServiceName(name)
+	props, err := systemctlShow(fmt.Sprintf("nazim-%s.service", normalizedName))
+	if err != nil {
+		return nil, err
+	}
+	if timer, err := systemctlShow(fmt.Sprintf("nazim-%s.timer", normalizedName)); err == nil {
+		for key, value := range timer {
+			props["Timer."+key] = value
+		}
+	}
+	return props, nil
+}
+
+// systemctlShow returns the properties `systemctl --user show` reports for a unit.
+func systemctlShow(unit string, args ...string) (map[string]string, error) {
+	output, err := trace.CombinedOutput(exec.Command("systemctl", append([]string{"--user", "show", unit}, args...)...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unit: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	if props["LoadState"] == "not-found" {
+		return nil, fmt.Errorf("unit not found")
+	}
+	return props, nil
+}
+
+// NextRun returns when the service's timer elapses next, as `systemctl list-timers`
+// reports it. Interval timers count from boot and the last run, so systemd is the
+// only one that knows. Startup-only services have no timer and no next run.
+func (m *LinuxManager) NextRun(svc *service.Service) (time.Time, error) {
+	return timerNextRun(fmt.Sprintf("nazim-%s.timer", normalizeServiceName(svc.Name)))
+}
+
+// timerNextRun returns when systemd will next trigger a timer unit; zero if
+// it has nothing planned.
+func timerNextRun(timerName string) (time.Time, error) {
+	output, err := trace.CombinedOutput(exec.Command("systemctl", "--user", "list-timers", "--all", "--no-legend", timerName))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to list timers: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	// The columns are NEXT LEFT LAST PASSED UNIT ACTIVATES, with "-" or "n/a"
+	// for unset times, so only a timestamp starting the line is the next run
+	line := strings.TrimSpace(string(output))
+	if loc := systemdTimestamp.FindStringIndex(line); loc != nil && loc[0] == 0 {
+		return parseSystemdTimestamp(line[:loc[1]])
+	}
+	return time.Time{}, nil
+}
+
+// LastRun returns when systemd last started the service unit, whether its timer,
+// the boot, or `nazim run` started it. systemd forgets it when the user's
+// service manager restarts.
+func (m *LinuxManager) LastRun(name string) (time.Time, error) {
+	props, err := systemctlShow(fmt.Sprintf("nazim-%s.service", normalizeServiceName(name)),
+		"--property=LoadState,ExecMainStartTimestamp")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseSystemdTimestamp(props["ExecMainStartTimestamp"])
+}
+
+// systemdTimestamp matches a timestamp as systemctl prints it.
+var systemdTimestamp = regexp.MustCompile(`[A-Z][a-z]{2} \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} \S+`)
+
+// parseSystemdTimestamp parses a timestamp as systemctl prints it, such as
+// "Thu 2026-10-16 15:00:00 CEST". Empty and "n/a" values are the zero time.
+func parseSystemdTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "n/a" {
+		return time.Time{}, nil
+	}
+
+	// systemctl prints local time. Zones like "+03" don't parse as abbreviations,
+	// so the zone is dropped and the rest read in the local zone.
+	i := strings.LastIndex(s, " ")
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("unexpected timestamp %q", s)
+	}
+	loc := time.Local
+	if s[i+1:] == "UTC" {
+		loc = time.UTC
+	}
+	t, err := time.ParseInLocation("Mon 2006-01-02 15:04:05", s[:i], loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected timestamp %q", s)
+	}
+	return t, nil
+}
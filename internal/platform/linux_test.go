@@ -0,0 +1,116 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestTriggerUnits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the home directory comes from USERPROFILE")
+	}
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{"nothing installed", nil, []string{"nazim-job.timer"}},
+		{"timer", []string{"nazim-job.service", "nazim-job.timer"}, []string{"nazim-job.timer"}},
+		{"path unit", []string{"nazim-job.service", "nazim-job.path"}, []string{"nazim-job.path"}},
+		{"timer and path unit", []string{"nazim-job.service", "nazim-job.timer", "nazim-job.path"}, []string{"nazim-job.timer", "nazim-job.path"}},
+		{"startup only", []string{"nazim-job.service"}, []string{"nazim-job.service"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			unitDir := filepath.Join(home, ".config", "systemd", "user")
+			if err := os.MkdirAll(unitDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range tt.files {
+				if err := os.WriteFile(filepath.Join(unitDir, file), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := (&LinuxManager{}).triggerUnits("job"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("triggerUnits() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSandboxWritablePaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the home directory comes from USERPROFILE")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	m := &LinuxManager{opts: newOptions([]Option{WithConfigDir("/c"), WithDataDir("/d"), WithStateDir("/c")})}
+
+	tests := []struct {
+		name string
+		svc  *service.Service
+		want string
+	}{
+		{"strict", &service.Service{Sandbox: service.SandboxStrict}, `ReadWritePaths="-/c" "-/d"`},
+		{"basic with a working directory", &service.Service{Sandbox: service.SandboxBasic, WorkDir: "/w"}, `ReadWritePaths="-/c" "-/d" "-/w"`},
+		{"disables itself", &service.Service{Sandbox: service.SandboxStrict, OnFailure: "disable-self-after:3"},
+			`ReadWritePaths="-/c" "-/d" "-` + filepath.Join(home, ".config", "systemd", "user") + `"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := m.sandboxDirectives(tt.svc)
+			if got := lines[len(lines)-1]; got != tt.want {
+				t.Errorf("sandboxDirectives() ends with %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTimerContent(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  *service.Service
+		want string
+	}{
+		{
+			name: "interval",
+			svc:  &service.Service{Name: "job", Interval: service.Duration{Duration: time.Hour}},
+			want: "OnBootSec=1h\nOnUnitActiveSec=1h\n",
+		},
+		{
+			name: "startup then interval, high priority",
+			svc:  &service.Service{Name: "job", OnStartup: true, Priority: service.PriorityHigh, Interval: service.Duration{Duration: time.Hour}},
+			want: "OnStartupSec=0s\nOnUnitActiveSec=1h\n",
+		},
+		{
+			name: "logon then interval, delayed",
+			svc: &service.Service{Name: "job", OnLogon: true, Interval: service.Duration{Duration: 24 * time.Hour},
+				StartupDelay: service.Duration{Duration: 2 * time.Minute}},
+			want: "OnStartupSec=2m15s\nOnUnitActiveSec=24h\n",
+		},
+		{
+			name: "interval under a minute",
+			svc:  &service.Service{Name: "job", Interval: service.Duration{Duration: 30 * time.Second}},
+			want: "OnBootSec=30s\nOnUnitActiveSec=30s\nAccuracySec=1s\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildTimerContent(tt.svc)
+			start := strings.Index(got, "[Timer]\n") + len("[Timer]\n")
+			end := strings.Index(got, "\n[Install]")
+			if trigger := got[start:end]; trigger != tt.want {
+				t.Errorf("buildTimerContent() triggers =\n%s\nwant\n%s", trigger, tt.want)
+			}
+		})
+	}
+}
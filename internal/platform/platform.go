@@ -3,10 +3,14 @@ package platform
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
+	"github.com/calilkhalil/nazim/internal/schedule"
 	"github.com/calilkhalil/nazim/internal/service"
 )
 
@@ -65,6 +69,43 @@ func normalizeServiceName(name string) string {
 	return normalized
 }
 
+// NetworkIsolation describes how the scheduler of this platform cuts a
+// service with network none off from the network, or returns "" where it
+// can't.
+func NetworkIsolation(svc *service.Service) string {
+	if svc.Network != service.NetworkNone {
+		return ""
+	}
+	switch runtime.GOOS {
+	case "linux":
+		return "PrivateNetwork=yes in its systemd unit"
+	case "windows":
+		return fmt.Sprintf("Windows Firewall rules '%s' blocking its program", firewallRuleName(normalizeServiceName(svc.Name)))
+	}
+	return ""
+}
+
+// ZonedSchedules reports whether the scheduler of a service runs its schedule
+// in the schedule's own time zone, as systemd does. Task Scheduler and launchd
+// only know local time, so nazim converts the schedule to it when installing
+// the service (see localSchedule).
+func ZonedSchedules(svc *service.Service) bool {
+	return runtime.GOOS == "linux" && !svc.OnWindowsHost()
+}
+
+// localSchedule returns a calendar schedule as the local times it fires at
+// from now on, for schedulers without time zones.
+func localSchedule(sched *schedule.Schedule) *schedule.Schedule {
+	local, _ := sched.In(time.Local, time.Now())
+	return local
+}
+
+// firewallRuleName returns the display name of the Windows Firewall rules of
+// a service with network none.
+func firewallRuleName(normalizedName string) string {
+	return "Nazim " + normalizedName
+}
+
 // Manager is an interface for managing services on different platforms.
 type Manager interface {
 	Install(service *service.Service) error
@@ -72,19 +113,215 @@ type Manager interface {
 	Enable(name string) error
 	Disable(name string) error
 	Run(name string) error
+	Stop(name string) error // Stops a running service; a service that isn't running is left alone
 	IsInstalled(name string) (bool, error)
-	GetTaskState(name string) (string, error) // Returns "Enabled", "Disabled", or error if not found
+	GetTaskState(name string) (*ServiceState, error)        // The scheduler's state of the service, or an error if it isn't installed
+	Details(name string) (map[string]string, error)         // Scheduler-reported run information, keyed by the scheduler's field names
+	Raw(name string) (map[string]string, error)             // Every property the scheduler reports, for debugging
+	Preview(service *service.Service) ([]Definition, error) // What Install would write, without touching the scheduler
+	Export(service *service.Service) ([]Definition, error)  // The scheduler definitions of the service as files that Service.Units can install
+	Artifacts(service *service.Service) ([]Artifact, error) // Where Install puts the service, installed or not
+	NextRun(service *service.Service) (time.Time, error)    // When the scheduler will next start the service; zero if no run is planned
+	LastRun(name string) (time.Time, error)                 // When the scheduler last started the service; zero if it never did or doesn't record it
+}
+
+// Definition is a piece of scheduler configuration generated for a service.
+type Definition struct {
+	Name    string // File path, or a description when nothing is written to disk
+	Content string
+}
+
+// ServiceState is the state of an installed service as its scheduler
+// reports it, in the same terms on every platform. Fields the scheduler
+// doesn't report are left zero.
+type ServiceState struct {
+	Installed     bool
+	Enabled       bool      // Whether the scheduler starts the service when it triggers
+	Running       bool      // Whether a run is going on
+	LastRun       time.Time // When the scheduler last started the service
+	LastResult    string    // How that run ended, as the scheduler puts it, such as "exit-code" or "0x1"
+	NextRun       time.Time // When the scheduler will next start the service; see also Manager.NextRun
+	BackendDetail string    // The scheduler's own word for the state, such as "static" or "Ready"
+}
+
+// String returns "Enabled" or "Disabled", as list shows the state.
+func (s *ServiceState) String() string {
+	if s.Enabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
+
+// Artifact is something the scheduler keeps for a service.
+type Artifact struct {
+	Kind string // What it is, such as "Timer unit"
+	Name string // The scheduler's name for it, if any
+	Path string // Its file, if any
+}
+
+// metadataComments returns the owner and labels of a service as lines for the
+// comments of its scheduler definitions.
+func metadataComments(svc *service.Service) []string {
+	var lines []string
+	if svc.Owner != "" {
+		lines = append(lines, "Owner: "+svc.Owner)
+	}
+	if len(svc.Labels) > 0 {
+		lines = append(lines, "Labels: "+strings.Join(svc.LabelPairs(), ", "))
+	}
+	return lines
+}
+
+// pickDetails returns the non-empty values of keys from props.
+func pickDetails(props map[string]string, keys ...string) map[string]string {
+	details := make(map[string]string)
+	for _, key := range keys {
+		if value := props[key]; value != "" {
+			details[key] = value
+		}
+	}
+	return details
+}
+
+// Option configures a platform manager.
+type Option func(*options)
+
+// options holds settings shared by all platform managers.
+type options struct {
+	executable string    // nazim binary invoked by scheduled runs
+	configDir  string    // config directory passed to `nazim exec`
+	dataDir    string    // data directory passed to `nazim exec`
+	stateDir   string    // state directory passed to `nazim exec`, which also holds Windows wrappers
+	output     io.Writer // user-facing messages such as elevation prompts
+	progress   func(step string)
+	targetOf   func(name string) string       // target of a service by name, see WithTargets
+	fallback   func(name string, mode string) // see WithFallback
+}
+
+// WithConfigDir sets the config directory scheduled runs load services from.
+func WithConfigDir(dir string) Option {
+	return func(o *options) {
+		o.configDir = dir
+	}
+}
+
+// WithDataDir sets the directory scheduled runs find scripts in.
+func WithDataDir(dir string) Option {
+	return func(o *options) {
+		o.dataDir = dir
+	}
+}
+
+// WithStateDir sets the directory scheduled runs write logs and history to.
+func WithStateDir(dir string) Option {
+	return func(o *options) {
+		o.stateDir = dir
+	}
+}
+
+// WithOutput sets where user-facing messages are written (default os.Stderr).
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+	}
+}
+
+// WithProgress sets a callback invoked as slow operations move through their steps,
+// for example "Registering task" or "Reloading systemd".
+func WithProgress(step func(step string)) Option {
+	return func(o *options) {
+		o.progress = step
+	}
+}
+
+// Ways a service is installed when the usual way isn't available, reported
+// through WithFallback. A service may be installed in several at once,
+// joined by commas.
+const (
+	FallbackUserTask      = "user-task"      // A task of the user's own, which runs only while they are logged on
+	FallbackStartupFolder = "startup-folder" // A shortcut in the user's Startup folder, which runs at their logon
+)
+
+// WithFallback sets a callback invoked when a service is installed in a
+// degraded way because the usual way isn't available, such as when
+// administrator privileges are refused on Windows. mode tells how it runs.
+func WithFallback(report func(name string, mode string)) Option {
+	return func(o *options) {
+		o.fallback = report
+	}
+}
+
+// WithExecutable overrides the nazim binary invoked by scheduled runs.
+func WithExecutable(path string) Option {
+	return func(o *options) {
+		o.executable = path
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.output == nil {
+		o.output = os.Stderr
+	}
+	if o.executable == "" {
+		if exe, err := os.Executable(); err == nil {
+			if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+				exe = resolved
+			}
+			o.executable = exe
+		} else {
+			o.executable = "nazim"
+		}
+	}
+	return o
+}
+
+// step reports progress of the current operation.
+func (o options) step(message string) {
+	if o.progress != nil {
+		o.progress(message)
+	}
+}
+
+// execArgs returns the command line that runs a service through `nazim exec`.
+func (o options) execArgs(svc *service.Service) []string {
+	args := []string{o.executable, "exec"}
+	if o.configDir != "" {
+		args = append(args, "--config-dir", o.configDir)
+	}
+	// A config directory alone keeps everything in it
+	if o.dataDir != "" && o.dataDir != o.configDir {
+		args = append(args, "--data-dir", o.dataDir)
+	}
+	if o.stateDir != "" && o.stateDir != o.configDir {
+		args = append(args, "--state-dir", o.stateDir)
+	}
+	// Catch-up triggers fire at boot or load; exec skips them unless a run was missed
+	if svc.CatchUp {
+		args = append(args, "--catch-up")
+	}
+	// Polling triggers fire every minute; exec skips them unless a watched path changed
+	if svc.PollsWatchPaths(runtime.GOOS) {
+		args = append(args, "--poll")
+	}
+	return append(args, svc.Name)
 }
 
 // NewManager creates an appropriate platform manager for the current OS.
-func NewManager() (Manager, error) {
+func NewManager(opts ...Option) (Manager, error) {
 	switch runtime.GOOS {
 	case "windows":
-		return NewWindowsManager(), nil
+		return NewWindowsManager(opts...), nil
 	case "linux":
-		return NewLinuxManager()
+		if IsWSL() {
+			return newWSLManager(opts...)
+		}
+		return NewLinuxManager(opts...)
 	case "darwin":
-		return NewDarwinManager(), nil
+		return NewDarwinManager(opts...), nil
 	default:
 		return nil, fmt.Errorf("unsupported OS: %s", runtime.GOOS)
 	}
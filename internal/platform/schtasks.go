@@ -0,0 +1,254 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/schedule"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// The helpers below build and read schtasks command lines. Nazim on Windows
+// uses them, and so does nazim inside WSL, which schedules services in the
+// Task Scheduler of the Windows host through schtasks.exe.
+
+// taskIntervalMinutes returns the interval of a service in whole minutes, the
+// smallest step Task Scheduler repeats at. Shorter intervals trigger every
+// minute and repeat within it with `nazim exec --repeat`.
+func taskIntervalMinutes(svc *service.Service) int {
+	return max(1, int(svc.GetInterval().Minutes()))
+}
+
+// timedTaskArgs returns the schtasks trigger arguments of a service that runs
+// at an interval, not combined with a boot or logon trigger, or on a schedule.
+func timedTaskArgs(svc *service.Service) []string {
+	if sched := svc.GetSchedule(); sched != nil {
+		return scheduleTaskArgs(localSchedule(sched))
+	}
+	args := intervalTaskArgs(svc)
+	if offset, ok := svc.GetOffset(); ok {
+		// The trigger repeats from its start time, so starting it at the offset
+		// lines every run up with the clock
+		args = append(args, "/st", fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60))
+	}
+	return args
+}
+
+// intervalTaskArgs returns the schtasks arguments of a trigger repeating at a
+// service's interval. MINUTE takes at most 1439, so intervals of a day or more
+// repeat DAILY, in whole days.
+func intervalTaskArgs(svc *service.Service) []string {
+	minutes := taskIntervalMinutes(svc)
+	if minutes >= 24*60 {
+		return []string{"/sc", "daily", "/mo", fmt.Sprintf("%d", minutes/(24*60))}
+	}
+	return []string{"/sc", "minute", "/mo", fmt.Sprintf("%d", minutes)}
+}
+
+// pollTaskArgs are the schtasks trigger arguments of a service whose
+// watch_paths are polled, see service.PollsWatchPaths.
+var pollTaskArgs = []string{"/sc", "minute", "/mo", "1"}
+
+// eventTaskArgs returns the schtasks trigger arguments of a service started by
+// an event log event: the log to subscribe to and the query events must match.
+func eventTaskArgs(trigger *service.EventTrigger) []string {
+	return []string{"/sc", "onevent", "/ec", trigger.Log, "/mo", trigger.Query()}
+}
+
+// formatTaskDelay formats a duration in the mmmm:ss form expected by schtasks /delay.
+func formatTaskDelay(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%04d:%02d", seconds/60, seconds%60)
+}
+
+// scheduleTaskArgs converts a calendar schedule in local time to schtasks
+// trigger arguments.
+func scheduleTaskArgs(sched *schedule.Schedule) []string {
+	startTime := fmt.Sprintf("%02d:%02d", sched.Hour, sched.Minute)
+	if sched.Daily() {
+		return []string{"/sc", "daily", "/st", startTime}
+	}
+
+	days := make([]string, len(sched.Days))
+	for i, d := range sched.Days {
+		days[i] = strings.ToUpper(d.String()[:3])
+	}
+	return []string{"/sc", "weekly", "/d", strings.Join(days, ","), "/st", startTime}
+}
+
+// marshalWindowsArgs properly marshals command-line arguments using Windows escaping rules.
+// This implements the CommandLineToArgvW escaping algorithm.
+func marshalWindowsArgs(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	var quoted []string
+	for _, arg := range args {
+		quoted = append(quoted, quoteWindowsArg(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteWindowsArg quotes a single argument according to Windows CommandLineToArgvW rules.
+func quoteWindowsArg(arg string) string {
+	// Check if quoting is needed
+	if arg == "" {
+		return `""`
+	}
+
+	needsQuote := strings.ContainsAny(arg, " \t\"")
+	if !needsQuote {
+		return arg
+	}
+
+	// Build escaped argument
+	var result strings.Builder
+	result.WriteByte('"')
+
+	for i := 0; i < len(arg); i++ {
+		backslashes := 0
+
+		// Count consecutive backslashes
+		for i < len(arg) && arg[i] == '\\' {
+			backslashes++
+			i++
+		}
+
+		if i >= len(arg) {
+			// Backslashes at end - double them
+			for j := 0; j < backslashes*2; j++ {
+				result.WriteByte('\\')
+			}
+			break
+		} else if arg[i] == '"' {
+			// Backslashes before quote - double them + escape quote
+			for j := 0; j < backslashes*2+1; j++ {
+				result.WriteByte('\\')
+			}
+			result.WriteByte('"')
+		} else {
+			// Normal backslashes
+			for j := 0; j < backslashes; j++ {
+				result.WriteByte('\\')
+			}
+			result.WriteByte(arg[i])
+		}
+	}
+
+	result.WriteByte('"')
+	return result.String()
+}
+
+// taskDetails picks the fields of a task that describe its runs.
+func taskDetails(props map[string]string) map[string]string {
+	details := pickDetails(props, "Status", "Last Run Time", "Last Result", "Next Run Time")
+	if result, ok := details["Last Result"]; ok {
+		details["Last Result"] = formatTaskResult(result)
+	}
+	return details
+}
+
+// formatTaskResult formats the last result of a task, a decimal HRESULT or
+// exit code, with the hex Microsoft documents them in.
+func formatTaskResult(result string) string {
+	if code, err := strconv.ParseInt(result, 10, 64); err == nil && code != 0 {
+		return fmt.Sprintf("%d (0x%X)", code, uint32(code))
+	}
+	return result
+}
+
+// taskStateFields are the properties of a task taskStateScript prints.
+var taskStateFields = []string{"Enabled", "State", "LastRunTime", "LastTaskResult", "NextRunTime"}
+
+// taskStateScript returns a PowerShell script printing the state of a task,
+// one Field=value line for each of taskStateFields, or NotFound=1 if there is
+// no such task. It goes through the Task Scheduler COM API, whose values,
+// unlike the fields and values `schtasks /query` prints, are the same in
+// every language of Windows; times are printed in UTC.
+func taskStateScript(taskName string) string {
+	return fmt.Sprintf(
+		"$s = New-Object -ComObject Schedule.Service; $s.Connect(); "+
+			"try { $t = $s.GetFolder('\\').GetTask('%s') } catch { 'NotFound=1'; exit }; "+
+			"$c = [Globalization.CultureInfo]::InvariantCulture; "+
+			"'Enabled=' + $t.Enabled; 'State=' + [int]$t.State; "+
+			"'LastRunTime=' + $t.LastRunTime.ToUniversalTime().ToString('s', $c); "+
+			"'LastTaskResult=' + $t.LastTaskResult; "+
+			"'NextRunTime=' + $t.NextRunTime.ToUniversalTime().ToString('s', $c)",
+		escapePowerShellSingleQuoted(taskName))
+}
+
+// taskStates names the values of the State of a task, TASK_STATE in the Task
+// Scheduler API.
+var taskStates = map[string]string{"0": "Unknown", "1": "Disabled", "2": "Queued", "3": "Ready", "4": "Running"}
+
+// errTaskNotFound is the error of querying a task that doesn't exist.
+var errTaskNotFound = errors.New("task not found")
+
+// parseTaskState reads the output of taskStateScript.
+func parseTaskState(output string) (*ServiceState, error) {
+	props := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	if props["NotFound"] != "" {
+		return nil, errTaskNotFound
+	}
+	for _, field := range taskStateFields {
+		if _, ok := props[field]; !ok {
+			return nil, fmt.Errorf("unexpected task state %q", strings.TrimSpace(output))
+		}
+	}
+
+	state := &ServiceState{
+		Installed:     true,
+		Enabled:       strings.EqualFold(props["Enabled"], "True") && props["State"] != "1",
+		Running:       props["State"] == "4",
+		BackendDetail: taskStates[props["State"]],
+	}
+	// Times that never were are 1899-12-30, the zero of COM dates
+	if t, err := time.Parse("2006-01-02T15:04:05", props["NextRunTime"]); err == nil && t.Year() >= 2000 {
+		state.NextRun = t
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05", props["LastRunTime"]); err == nil && t.Year() >= 2000 {
+		state.LastRun = t
+		state.LastResult = formatTaskResult(props["LastTaskResult"])
+	}
+	return state, nil
+}
+
+// escapePowerShellSingleQuoted escapes a string for use inside PowerShell single-quoted strings.
+// In PowerShell single-quoted strings, only single quotes need escaping (by doubling).
+// This is used for embedding values in wrapper scripts.
+func escapePowerShellSingleQuoted(s string) string {
+	// Single quotes are the only characters that need escaping in single-quoted strings
+	// They are escaped by doubling them
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// parseTaskList reads the output of `schtasks /query /fo LIST` for one task
+// into its fields.
+func parseTaskList(output string) map[string]string {
+	props := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			key = strings.TrimSpace(key)
+			// Multiple triggers repeat keys; the first occurrence describes the task
+			if _, seen := props[key]; !seen {
+				props[key] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return props
+}
+
+// taskNotRunning reports whether schtasks /end failed because the task has no
+// running instance to end.
+func taskNotRunning(output []byte) bool {
+	return strings.Contains(strings.ToLower(string(output)), "not running")
+}
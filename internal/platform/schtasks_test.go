@@ -0,0 +1,87 @@
+package platform
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestParseTaskState(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   ServiceState
+	}{
+		{
+			name:   "ready",
+			output: "Enabled=True\r\nState=3\r\nLastRunTime=2026-10-16T13:00:00\r\nLastTaskResult=0\r\nNextRunTime=2026-10-16T14:00:00\r\n",
+			want: ServiceState{
+				Installed: true, Enabled: true, BackendDetail: "Ready",
+				LastRun: time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC), LastResult: "0",
+				NextRun: time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "running after a failure",
+			output: "Enabled=True\nState=4\nLastRunTime=2026-10-16T13:00:00\nLastTaskResult=1\nNextRunTime=2026-10-16T14:00:00\n",
+			want: ServiceState{
+				Installed: true, Enabled: true, Running: true, BackendDetail: "Running",
+				LastRun: time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC), LastResult: "1 (0x1)",
+				NextRun: time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "disabled, never run",
+			output: "Enabled=False\nState=1\nLastRunTime=1899-12-30T00:00:00\nLastTaskResult=267011\nNextRunTime=1899-12-30T00:00:00\n",
+			want:   ServiceState{Installed: true, BackendDetail: "Disabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTaskState(tt.output)
+			if err != nil {
+				t.Fatalf("parseTaskState() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseTaskState() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTaskStateErrors(t *testing.T) {
+	if _, err := parseTaskState("NotFound=1\r\n"); !errors.Is(err, errTaskNotFound) {
+		t.Errorf("parseTaskState(NotFound) error = %v, want %v", err, errTaskNotFound)
+	}
+	if _, err := parseTaskState("Enabled=True\nState=3\n"); err == nil {
+		t.Error("parseTaskState() of incomplete output succeeded, want an error")
+	}
+}
+
+func TestTimedTaskArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		offset   string
+		want     []string
+	}{
+		{"under a minute", 30 * time.Second, "", []string{"/sc", "minute", "/mo", "1"}},
+		{"minutes", 15 * time.Minute, "", []string{"/sc", "minute", "/mo", "15"}},
+		{"just under a day", 1439 * time.Minute, "", []string{"/sc", "minute", "/mo", "1439"}},
+		{"a day", 24 * time.Hour, "", []string{"/sc", "daily", "/mo", "1"}},
+		{"days", 72 * time.Hour, "", []string{"/sc", "daily", "/mo", "3"}},
+		{"hours at an offset", 6 * time.Hour, "30m", []string{"/sc", "minute", "/mo", "360", "/st", "00:30"}},
+		{"a day at an offset", 24 * time.Hour, "135m", []string{"/sc", "daily", "/mo", "1", "/st", "02:15"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &service.Service{Name: "job", Interval: service.Duration{Duration: tt.interval}, Offset: tt.offset}
+			if got := timedTaskArgs(svc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("timedTaskArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
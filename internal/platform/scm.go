@@ -0,0 +1,384 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/eventlog"
+	"github.com/calilkhalil/nazim/internal/service"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Long-running services are registered with the service control manager (SCM)
+// instead of Task Scheduler: the SCM starts them at boot, restarts them when
+// they fail, and stops them on `nazim stop` or shutdown. The registered binary
+// is `nazim exec`, which answers the SCM through RunAsService while the
+// service's command runs.
+
+// Restarts after a long-running service fails: soon at first, then backing
+// off, counting failures again once it ran a day without one.
+var (
+	recoveryActions = []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 30 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}
+	recoveryResetPeriod = 24 * time.Hour
+)
+
+// stopTimeout bounds how long stopping a long-running service may take.
+const stopTimeout = 30 * time.Second
+
+// scmName returns the name a long-running service is registered with.
+func scmName(normalizedName string) string {
+	return "nazim-" + normalizedName
+}
+
+// scmConfig returns the SCM settings of a long-running service. It runs as
+// LocalSystem, like startup tasks.
+func scmConfig(svc *service.Service) mgr.Config {
+	return mgr.Config{
+		DisplayName: "Nazim: " + svc.Name,
+		Description: strings.Join(taskDescription(svc), " / "),
+		StartType:   mgr.StartAutomatic,
+	}
+}
+
+// openService opens the registered service of a long-running service with the
+// given access rights. Unlike mgr.Connect, it only asks the SCM for the right
+// to connect, so querying works without administrator privileges.
+func openService(normalizedName string, access uint32) (*mgr.Service, error) {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	name := scmName(normalizedName)
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := windows.OpenService(scm, namePtr, access)
+	if err != nil {
+		return nil, err
+	}
+	return &mgr.Service{Name: name, Handle: handle}, nil
+}
+
+// isLongRunning reports whether a service is registered with the SCM.
+func isLongRunning(normalizedName string) bool {
+	s, err := openService(normalizedName, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return false
+	}
+	s.Close()
+	return true
+}
+
+// installLongRunning registers a long-running service with the SCM, sets it
+// to be restarted when it fails, and starts it if it is enabled.
+func (m *WindowsManager) installLongRunning(svc *service.Service) error {
+	m.opts.step("Connecting to the service control manager")
+	scm, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager: %w", err)
+	}
+	defer scm.Disconnect()
+
+	m.opts.step("Registering service")
+	args := m.opts.execArgs(svc)
+	s, err := scm.CreateService(scmName(normalizeServiceName(svc.Name)), args[0], scmConfig(svc), args[1:]...)
+	if err != nil {
+		return fmt.Errorf("failed to create service: %w", err)
+	}
+	defer s.Close()
+
+	m.opts.step("Setting recovery actions")
+	if err := s.SetRecoveryActions(recoveryActions, uint32(recoveryResetPeriod.Seconds())); err != nil {
+		return fmt.Errorf("failed to set recovery actions: %w", err)
+	}
+	// Without this, only a crash counts as a failure, not a command exiting with an error
+	if err := s.SetRecoveryActionsOnNonCrashFailures(true); err != nil {
+		return fmt.Errorf("failed to set recovery actions: %w", err)
+	}
+
+	// See Install
+	m.opts.step("Registering event source")
+	_ = eventlog.Register()
+
+	if svc.Enabled {
+		m.opts.step("Starting service")
+		if err := s.Start(); err != nil {
+			return fmt.Errorf("failed to start service: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteLongRunning stops and deletes the registered service of a
+// long-running service, treating one that doesn't exist as deleted.
+func deleteLongRunning(normalizedName string) error {
+	s, err := openService(normalizedName, windows.SERVICE_STOP|windows.SERVICE_QUERY_STATUS|windows.DELETE)
+	if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open service: %w", err)
+	}
+	defer s.Close()
+
+	if err := stopService(s); err != nil {
+		return err
+	}
+	if err := s.Delete(); err != nil && !errors.Is(err, windows.ERROR_SERVICE_MARKED_FOR_DELETE) {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+	return nil
+}
+
+// stopLongRunning stops a registered service.
+func stopLongRunning(normalizedName string) error {
+	s, err := openService(normalizedName, windows.SERVICE_STOP|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Errorf("failed to open service: %w", err)
+	}
+	defer s.Close()
+	return stopService(s)
+}
+
+// stopService asks a registered service to stop and waits until it has.
+func stopService(s *mgr.Service) error {
+	status, err := s.Control(svc.Stop)
+	if errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+	deadline := time.Now().Add(stopTimeout)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service did not stop within %s", stopTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return fmt.Errorf("failed to query service: %w", err)
+		}
+	}
+	return nil
+}
+
+// setLongRunningStart sets whether a registered service starts at boot, and
+// starts or stops it to match.
+func setLongRunningStart(normalizedName string, enabled bool) error {
+	s, err := openService(normalizedName, windows.SERVICE_CHANGE_CONFIG|windows.SERVICE_QUERY_CONFIG|
+		windows.SERVICE_START|windows.SERVICE_STOP|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Errorf("failed to open service: %w", err)
+	}
+	defer s.Close()
+
+	cfg, err := s.Config()
+	if err != nil {
+		return fmt.Errorf("failed to query service: %w", err)
+	}
+	cfg.StartType = mgr.StartDisabled
+	if enabled {
+		cfg.StartType = mgr.StartAutomatic
+	}
+	if err := s.UpdateConfig(cfg); err != nil {
+		return fmt.Errorf("failed to change service: %w", err)
+	}
+
+	if !enabled {
+		return stopService(s)
+	}
+	if err := s.Start(); err != nil && !errors.Is(err, windows.ERROR_SERVICE_ALREADY_RUNNING) {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	return nil
+}
+
+// startLongRunning starts a registered service.
+func startLongRunning(normalizedName string) error {
+	s, err := openService(normalizedName, windows.SERVICE_START)
+	if err != nil {
+		return fmt.Errorf("failed to open service: %w", err)
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		if errors.Is(err, windows.ERROR_SERVICE_ALREADY_RUNNING) {
+			return fmt.Errorf("service is already running")
+		}
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	return nil
+}
+
+// longRunningProps returns what the SCM reports about a registered service.
+func longRunningProps(normalizedName string) (map[string]string, error) {
+	s, err := openService(normalizedName, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open service: %w", err)
+	}
+	defer s.Close()
+
+	cfg, err := s.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service: %w", err)
+	}
+	status, err := s.Query()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service: %w", err)
+	}
+
+	props := map[string]string{
+		"Service Name": s.Name,
+		"Display Name": cfg.DisplayName,
+		"Binary Path":  cfg.BinaryPathName,
+		"Start Type":   startTypeName(cfg.StartType),
+		"Account":      cfg.ServiceStartName,
+		"State":        stateName(status.State),
+		"Exit Code":    fmt.Sprint(status.ServiceSpecificExitCode),
+	}
+	if status.ProcessId != 0 {
+		props["PID"] = fmt.Sprint(status.ProcessId)
+	}
+	if actions, err := s.RecoveryActions(); err == nil {
+		delays := make([]string, 0, len(actions))
+		for _, action := range actions {
+			if action.Type == mgr.ServiceRestart {
+				delays = append(delays, action.Delay.String())
+			}
+		}
+		if len(delays) > 0 {
+			props["Recovery"] = "restart after " + strings.Join(delays, ", ")
+		}
+	}
+	return props, nil
+}
+
+// longRunningPreview describes what installLongRunning does for a service,
+// as the equivalent sc.exe commands.
+func (m *WindowsManager) longRunningPreview(svc *service.Service) Definition {
+	cfg := scmConfig(svc)
+	name := scmName(normalizeServiceName(svc.Name))
+	delays := make([]string, len(recoveryActions))
+	for i, action := range recoveryActions {
+		delays[i] = fmt.Sprintf("restart/%d", action.Delay.Milliseconds())
+	}
+	lines := []string{
+		"sc.exe " + marshalWindowsArgs([]string{"create", name, "binPath=", marshalWindowsArgs(m.opts.execArgs(svc)),
+			"start=", "auto", "DisplayName=", cfg.DisplayName}),
+		"sc.exe " + marshalWindowsArgs([]string{"failure", name, "reset=", fmt.Sprint(int(recoveryResetPeriod.Seconds())),
+			"actions=", strings.Join(delays, "/")}),
+		"sc.exe " + marshalWindowsArgs([]string{"failureflag", name, "1"}),
+	}
+	if cfg.Description != "" {
+		lines = append(lines, "sc.exe "+marshalWindowsArgs([]string{"description", name, cfg.Description}))
+	}
+	if svc.Enabled {
+		lines = append(lines, "sc.exe "+marshalWindowsArgs([]string{"start", name}))
+	}
+	return Definition{Name: "Service control manager commands", Content: strings.Join(lines, "\n") + "\n"}
+}
+
+// startTypeName returns the name services.msc shows for a start type.
+func startTypeName(startType uint32) string {
+	switch startType {
+	case mgr.StartAutomatic:
+		return "Automatic"
+	case mgr.StartManual:
+		return "Manual"
+	case mgr.StartDisabled:
+		return "Disabled"
+	default:
+		return fmt.Sprint(startType)
+	}
+}
+
+// stateName returns the name services.msc shows for a service state.
+func stateName(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "Stopped"
+	case svc.StartPending:
+		return "Starting"
+	case svc.StopPending:
+		return "Stopping"
+	case svc.Running:
+		return "Running"
+	case svc.Paused:
+		return "Paused"
+	default:
+		return fmt.Sprint(state)
+	}
+}
+
+// IsWindowsService reports whether nazim was started by the SCM, as the
+// `nazim exec` of a long-running service.
+func IsWindowsService() bool {
+	running, err := svc.IsWindowsService()
+	return err == nil && running
+}
+
+// RunAsService runs a long-running service under the SCM: it reports the
+// service as running while run runs, cancels the context of run when the SCM
+// asks the service to stop, and reports the exit code of run once it returns,
+// a nonzero one as a failure the SCM recovers from by restarting the service.
+func RunAsService(ctx context.Context, run func(ctx context.Context) (int, error)) (int, error) {
+	h := &scmHandler{ctx: ctx, run: run}
+	if err := svc.Run("", h); err != nil {
+		return 1, fmt.Errorf("running as a Windows service: %w", err)
+	}
+	return h.code, h.err
+}
+
+// scmHandler answers the SCM for RunAsService.
+type scmHandler struct {
+	ctx  context.Context
+	run  func(ctx context.Context) (int, error)
+	code int
+	err  error
+}
+
+func (h *scmHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.code, h.err = h.run(ctx)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case <-done:
+			return h.code != 0, uint32(h.code)
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				// Stopped on request, not failed
+				return false, 0
+			}
+		}
+	}
+}
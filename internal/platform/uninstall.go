@@ -0,0 +1,70 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ArtifactError is an artifact of a service that Uninstall couldn't remove.
+type ArtifactError struct {
+	Artifact string // What it is and its name, such as "Timer unit nazim-backup.timer"
+	Err      error
+}
+
+func (e *ArtifactError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Artifact, e.Err)
+}
+
+func (e *ArtifactError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is the error of an Uninstall that left part of a service
+// behind: every artifact it couldn't remove, rather than only the first, so
+// that what is left can be told apart from what is gone.
+type MultiError struct {
+	Service string
+	Errors  []*ArtifactError
+}
+
+func (e *MultiError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("failed to remove %s", e.Errors[0])
+	}
+	parts := make([]string, len(e.Errors))
+	for i, artifactErr := range e.Errors {
+		parts[i] = artifactErr.Error()
+	}
+	return fmt.Sprintf("failed to remove %d artifacts of '%s': %s", len(e.Errors), e.Service, strings.Join(parts, "; "))
+}
+
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, artifactErr := range e.Errors {
+		errs[i] = artifactErr
+	}
+	return errs
+}
+
+// add records an artifact that couldn't be removed.
+func (e *MultiError) add(artifact string, err error) {
+	e.Errors = append(e.Errors, &ArtifactError{Artifact: artifact, Err: err})
+}
+
+// verify checks, once m removed what it could of the service, that the
+// scheduler no longer has it, recording what remains, and returns e, or nil
+// if nothing was left behind. Steps that failed are often only noise, such as
+// stopping a unit that wasn't running; this is what tells.
+func (e *MultiError) verify(m Manager) error {
+	if installed, err := m.IsInstalled(e.Service); err == nil && installed {
+		e.add("service", errors.New("the scheduler still has it"))
+	}
+	if state, err := m.GetTaskState(e.Service); err == nil {
+		e.add("trigger", fmt.Errorf("the scheduler still has it, %s", strings.ToLower(state.String())))
+	}
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,35 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// readUnits reads the hand-edited scheduler definitions of a service (see
+// service.Service.Units), returning each as the definition Install writes in
+// place of the one it would generate. target returns where a file named base
+// is installed, or "" if the platform installs no such file for the service;
+// they are named as Export names them.
+func readUnits(svc *service.Service, target func(base string) string) ([]Definition, error) {
+	var defs []Definition
+	seen := make(map[string]bool)
+	for _, path := range svc.Units {
+		dest := target(filepath.Base(path))
+		if dest == "" {
+			return nil, fmt.Errorf("unit file %s is not one nazim installs for '%s'; nazim export-unit %s shows their names", path, svc.Name, svc.Name)
+		}
+		if seen[dest] {
+			return nil, fmt.Errorf("unit file %s is given twice", filepath.Base(path))
+		}
+		seen[dest] = true
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read unit file: %w", err)
+		}
+		defs = append(defs, Definition{Name: dest, Content: string(content)})
+	}
+	return defs, nil
+}
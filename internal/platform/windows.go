@@ -6,23 +6,69 @@ package platform
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/calilkhalil/nazim/internal/eventlog"
 	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/trace"
 	"golang.org/x/sys/windows"
 )
 
 // WindowsManager manages services on Windows using Task Scheduler.
-type WindowsManager struct{}
+type WindowsManager struct {
+	opts options
+}
 
 // NewWindowsManager creates a new manager for Windows.
-func NewWindowsManager() *WindowsManager {
-	return &WindowsManager{}
+func NewWindowsManager(opts ...Option) *WindowsManager {
+	return &WindowsManager{opts: newOptions(opts)}
+}
+
+// taskFolder is the Task Scheduler folder nazim creates its tasks in.
+const taskFolder = `\Nazim`
+
+// taskPath returns the full path of the task for a service.
+func taskPath(normalizedName string) string {
+	return taskFolder + `\` + normalizedName
+}
+
+// legacyTaskName returns the root task name older versions installed a service as.
+func legacyTaskName(normalizedName string) string {
+	return "Nazim_" + normalizedName
+}
+
+// resolveTaskName returns the task a service is installed as, falling back to
+// the legacy root task for services installed by older versions. When neither
+// exists it returns the current path, so errors name the task Install creates.
+func resolveTaskName(normalizedName string) string {
+	current := taskPath(normalizedName)
+	if trace.Run(exec.Command("schtasks", "/query", "/tn", current)) == nil {
+		return current
+	}
+	legacy := legacyTaskName(normalizedName)
+	if trace.Run(exec.Command("schtasks", "/query", "/tn", legacy)) == nil {
+		return legacy
+	}
+	return current
+}
+
+// removeEmptyTaskFolder deletes the \Nazim folder once its last task is gone.
+// schtasks can't delete folders, so this goes through the Task Scheduler COM API.
+// Failures are ignored: an empty folder left behind is harmless.
+func removeEmptyTaskFolder() {
+	script := fmt.Sprintf(
+		"$s = New-Object -ComObject Schedule.Service; $s.Connect(); $root = $s.GetFolder('\\'); "+
+			"try { $f = $root.GetFolder('%[1]s') } catch { return }; "+
+			"if ($f.GetTasks(1).Count -eq 0 -and $f.GetFolders(0).Count -eq 0) { $root.DeleteFolder('%[1]s', 0) }",
+		strings.TrimPrefix(taskFolder, `\`))
+	_ = trace.Run(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script))
 }
 
 // getAppDataDir returns the APPDATA directory with proper fallback.
@@ -120,26 +166,46 @@ func requestElevation() error {
 	return nil
 }
 
-func checkAdminOrElevate() error {
+// RequiresElevation reports whether installing services will trigger a UAC prompt.
+// Install re-runs the whole command elevated, so commands that install several
+// services at once should ask to be started elevated instead.
+func RequiresElevation() bool {
+	return !isAdmin()
+}
+
+func checkAdminOrElevate(w io.Writer) error {
 	if isAdmin() {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Administrator privileges required for installing services.\n")
-	fmt.Fprintf(os.Stderr, "Requesting elevation (UAC prompt will appear)...\n")
+	fmt.Fprintf(w, "Administrator privileges required for installing services.\n")
+	fmt.Fprintf(w, "Requesting elevation (UAC prompt will appear)...\n")
 	return requestElevation()
 }
 
 // Install installs a service on Windows using schtasks.
 // On non-admin execution, this triggers UAC elevation and exits the parent process.
-// The elevated child process will complete the installation.
+// The elevated child process will complete the installation. If elevation fails,
+// the service is installed for the current user alone where it can be (see fallBack),
+// as is a service already installed that way.
 func (m *WindowsManager) Install(svc *service.Service) error {
+	if svc.Network == service.NetworkNone {
+		if _, err := isolatedProgram(svc); err != nil {
+			return err
+		}
+	}
 	if !isAdmin() {
+		if m.fallbackMode(normalizeServiceName(svc.Name)) != "" && userLevelProblem(svc) == nil {
+			return m.fallBack(svc, nil)
+		}
+		m.opts.step("Requesting elevation")
 		if err := requestElevation(); err != nil {
 			if strings.Contains(err.Error(), "cancelled") || strings.Contains(err.Error(), "denied") {
-				return fmt.Errorf("UAC prompt was cancelled or denied. Please approve the UAC prompt to install the service")
+				err = fmt.Errorf("UAC prompt was cancelled or denied")
+			} else {
+				err = fmt.Errorf("failed to request elevation: %w", err)
 			}
-			return fmt.Errorf("failed to request elevation: %w\nHint: Try running the command as administrator manually", err)
+			return m.fallBack(svc, err)
 		}
 		// Elevation triggered successfully - parent process should exit
 		// The elevated child process will handle the actual installation
@@ -153,65 +219,167 @@ func (m *WindowsManager) Install(svc *service.Service) error {
 		}
 	}
 
-	// Get APPDATA directory safely
-	appData, err := getAppDataDir()
+	normalizedName := normalizeServiceName(svc.Name)
+	if svc.Network == service.NetworkNone {
+		program, err := isolatedProgram(svc)
+		if err != nil {
+			return err
+		}
+		m.opts.step("Adding firewall rules")
+		if err := blockNetwork(normalizedName, program); err != nil {
+			return err
+		}
+	}
+
+	if svc.LongRunning {
+		return m.installLongRunning(svc)
+	}
+
+	// Create the wrapper that runs the service through `nazim exec`
+	m.opts.step("Creating wrapper")
+	wrapperPath, err := m.createWrapper(normalizedName, m.execArgs(svc))
+	if err != nil {
+		return fmt.Errorf("failed to create wrapper: %w", err)
+	}
+	if len(svc.Units) > 0 {
+		return m.installTaskXML(svc)
+	}
+
+	args := buildTaskArgs(svc, wrapperPath)
+
+	m.opts.step("Registering task")
+	cmd := exec.Command("schtasks", args...)
+	output, err := trace.CombinedOutput(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to get APPDATA directory: %w", err)
+		return fmt.Errorf("failed to create task: %s: %w", string(output), err)
+	}
+
+	taskName := taskPath(normalizedName)
+	if svc.RepeatsAfterStartup() {
+		m.opts.step("Adding startup trigger")
+		if err := addStartupTrigger(normalizedName, svc.OnLogon, svc.EffectiveStartupDelay()); err != nil {
+			return err
+		}
+	}
+	if svc.CatchUp {
+		m.opts.step("Enabling catch-up")
+		if err := enableStartWhenAvailable(normalizedName); err != nil {
+			return err
+		}
+	}
+
+	if lines := taskDescription(svc); len(lines) > 0 {
+		m.opts.step("Setting description")
+		if err := setTaskDescription(normalizedName, lines); err != nil {
+			return err
+		}
+	}
+
+	// Runs write their start, stop, and failure events as the Nazim source, which
+	// only an administrator can register. Events are written either way, just
+	// without a registered message file, so a failure here doesn't stop the install.
+	m.opts.step("Registering event source")
+	_ = eventlog.Register()
+
+	m.opts.step("Verifying installation")
+	if output, err := trace.CombinedOutput(exec.Command("schtasks", "/query", "/tn", taskName)); err != nil {
+		return fmt.Errorf("task was created but cannot be queried: %s: %w", strings.TrimSpace(string(output)), err)
 	}
 
-	// Create log directory if it doesn't exist
-	logDir := filepath.Join(appData, "nazim", "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
+	if svc.RepeatsAfterStartup() && svc.Enabled {
+		// The boot or logon run of this session has passed; run now instead, as
+		// launchd and systemd do
+		m.opts.step("Starting first run")
+		if output, err := trace.CombinedOutput(exec.Command("schtasks", "/run", "/tn", taskName)); err != nil {
+			return fmt.Errorf("failed to start task: %s: %w", strings.TrimSpace(string(output)), err)
+		}
 	}
 
+	return nil
+}
+
+// installTaskXML registers the hand-edited task of a service, instead of the
+// one schtasks would create from its settings. The task's triggers and
+// settings are all in its XML, so none are added afterwards; the wrapper it
+// runs is still written from the service.
+func (m *WindowsManager) installTaskXML(svc *service.Service) error {
 	normalizedName := normalizeServiceName(svc.Name)
-	logPath := filepath.Join(logDir, fmt.Sprintf("%s.log", normalizedName))
+	def, err := taskXML(svc)
+	if err != nil {
+		return err
+	}
 
-	// Build the actual command to execute
-	command := buildWindowsCommand(svc.Command, svc.Args)
-	if svc.WorkDir != "" {
-		// Prefix with cd command if WorkDir is specified
-		command = fmt.Sprintf(`cd /d %s && %s`, escapeWindowsPath(svc.WorkDir), command)
+	m.opts.step("Registering task")
+	script := fmt.Sprintf(
+		"Register-ScheduledTask -TaskPath '%s' -TaskName '%s' -Xml (Get-Content -Raw -LiteralPath '%s') -Force | Out-Null",
+		escapePowerShellSingleQuoted(taskFolder+`\`), escapePowerShellSingleQuoted(normalizedName), escapePowerShellSingleQuoted(svc.Units[0]))
+	if output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)); err != nil {
+		return fmt.Errorf("failed to register task from %s: %s: %w", def.Name, strings.TrimSpace(string(output)), err)
 	}
 
-	// Create logging wrapper that adds timestamps
-	wrapperPath, err := createLoggingWrapper(normalizedName, command, logPath)
+	m.opts.step("Registering event source")
+	_ = eventlog.Register()
+
+	m.opts.step("Verifying installation")
+	if output, err := trace.CombinedOutput(exec.Command("schtasks", "/query", "/tn", taskPath(normalizedName))); err != nil {
+		return fmt.Errorf("task was created but cannot be queried: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// taskXML returns the hand-edited task of a service, named as Export names it.
+func taskXML(svc *service.Service) (Definition, error) {
+	file := normalizeServiceName(svc.Name) + ".xml"
+	defs, err := readUnits(svc, func(base string) string {
+		if strings.EqualFold(base, file) {
+			return file
+		}
+		return ""
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create logging wrapper: %w", err)
+		return Definition{}, err
 	}
+	return defs[0], nil
+}
 
+// execArgs returns the command line a service's wrapper runs. Task Scheduler
+// can't repeat more often than every minute, so services with a shorter
+// interval are started every minute with --repeat, and `nazim exec` runs them
+// at their interval until the minute is over.
+func (m *WindowsManager) execArgs(svc *service.Service) []string {
+	args := m.opts.execArgs(svc)
+	if svc.RunsPerMinute() == 1 {
+		return args
+	}
+	name := args[len(args)-1]
+	return append(args[:len(args)-1], "--repeat", name)
+}
+
+// buildTaskArgs returns the schtasks /create arguments for a service whose
+// task launches the wrapper at wrapperPath.
+func buildTaskArgs(svc *service.Service, wrapperPath string) []string {
 	hasInterval := svc.GetInterval() > 0
 	hasStartup := svc.OnStartup
 	hasLogon := svc.OnLogon
 
-	// Use PowerShell wrapper for timestamp logging
-	// -WindowStyle Hidden prevents the black terminal window from appearing
-	commandWithLogs := fmt.Sprintf(`powershell -NoProfile -WindowStyle Hidden -ExecutionPolicy Bypass -File "%s"`, wrapperPath)
-
 	args := []string{
 		"/create",
-		"/tn", fmt.Sprintf("Nazim_%s", normalizedName),
-		"/tr", commandWithLogs,
+		"/tn", taskPath(normalizeServiceName(svc.Name)),
+		"/tr", quoteWindowsArg(wrapperHost(svc)) + " " + wrapperHostArgs(wrapperPath),
 		"/f",
 	}
 
-	if hasInterval {
-		minutes := int(svc.GetInterval().Minutes())
-		if minutes > 0 {
-			if hasLogon {
-				// ONLOGON runs as current user (has access to HKCU)
-				args = append(args, "/sc", "onlogon")
-				args = append(args, "/ri", fmt.Sprintf("%d", minutes))
-			} else if hasStartup {
-				// ONSTART runs as SYSTEM (before user login)
-				args = append(args, "/sc", "onstart")
-				args = append(args, "/ri", fmt.Sprintf("%d", minutes))
-				args = append(args, "/ru", "SYSTEM")
-			} else {
-				args = append(args, "/sc", "minute", "/mo", fmt.Sprintf("%d", minutes))
-			}
+	if hasInterval && (hasLogon || hasStartup) {
+		// A repeating boot or logon trigger would only start repeating at the
+		// next boot, so the interval gets a trigger of its own, and Install
+		// adds the boot or logon one (see addStartupTrigger)
+		args = append(args, intervalTaskArgs(svc)...)
+		if !hasLogon {
+			// Startup services run as SYSTEM (before user login)
+			args = append(args, "/ru", "SYSTEM")
 		}
+	} else if hasInterval || svc.GetSchedule() != nil {
+		args = append(args, timedTaskArgs(svc)...)
 	} else if hasLogon {
 		// ONLOGON runs as current user (has access to HKCU)
 		args = append(args, "/sc", "onlogon")
@@ -219,219 +387,289 @@ func (m *WindowsManager) Install(svc *service.Service) error {
 		// ONSTART runs as SYSTEM (before user login)
 		args = append(args, "/sc", "onstart")
 		args = append(args, "/ru", "SYSTEM")
+	} else if trigger := svc.GetEventTrigger(); trigger != nil {
+		args = append(args, eventTaskArgs(trigger)...)
+	} else if len(svc.WatchPaths) > 0 {
+		// Task Scheduler has no file trigger, so the paths are polled
+		args = append(args, pollTaskArgs...)
 	}
 
-	cmd := exec.Command("schtasks", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to create task: %s: %w", string(output), err)
+	// Highest privileges gives the task the user's full admin token instead of the
+	// filtered UAC one, so admin-only scripts work without running as SYSTEM
+	if svc.Elevated {
+		args = append(args, "/rl", "HIGHEST")
 	}
 
-	return nil
-}
+	// /delay is only valid for ONSTART and ONLOGON triggers
+	if delay := svc.EffectiveStartupDelay(); delay > 0 && (hasStartup || hasLogon) && !hasInterval {
+		args = append(args, "/delay", formatTaskDelay(delay))
+	}
 
-func buildWindowsCommand(cmd string, args []string) string {
-	escapedCmd := escapeWindowsCommand(cmd)
+	return args
+}
 
-	if len(args) == 0 {
-		return escapedCmd
+// wrapperHost returns the PowerShell that runs a service's wrapper: Windows
+// PowerShell, which every Windows has, unless the service picked a PowerShell
+// of its own, so pwsh-only setups also work.
+func wrapperHost(svc *service.Service) string {
+	if shell := svc.ForPlatform("windows").Shell; service.IsPowerShell(shell) {
+		return shell
 	}
+	return service.ShellPowerShell
+}
+
+// wrapperHostArgs returns the arguments wrapperHost runs the wrapper at
+// wrapperPath with. -WindowStyle Hidden prevents the black terminal window
+// from appearing.
+func wrapperHostArgs(wrapperPath string) string {
+	return fmt.Sprintf(`-NoProfile -WindowStyle Hidden -ExecutionPolicy Bypass -File "%s"`, wrapperPath)
+}
 
-	escapedArgs := make([]string, len(args))
-	for i, arg := range args {
-		escapedArgs[i] = escapeWindowsCommand(arg)
+// Preview returns the wrapper script and schtasks command Install would use
+// for a service, or for a long-running one, the commands registering it with
+// the service control manager.
+func (m *WindowsManager) Preview(svc *service.Service) ([]Definition, error) {
+	if svc.LongRunning {
+		return []Definition{m.longRunningPreview(svc)}, nil
+	}
+	normalizedName := normalizeServiceName(svc.Name)
+	wrapperPath, err := m.wrapperFilePath(normalizedName)
+	if err != nil {
+		return nil, err
+	}
+	wrapper := Definition{Name: wrapperPath, Content: buildWrapperContent(normalizedName, m.execArgs(svc))}
+	if len(svc.Units) > 0 {
+		def, err := taskXML(svc)
+		if err != nil {
+			return nil, err
+		}
+		def.Name = "Task Scheduler XML (registered as " + taskPath(normalizedName) + ")"
+		return []Definition{wrapper, def}, nil
 	}
 
-	return escapedCmd + " " + strings.Join(escapedArgs, " ")
+	command := "schtasks " + marshalWindowsArgs(buildTaskArgs(svc, wrapperPath))
+	if svc.RepeatsAfterStartup() {
+		trigger := "at startup"
+		if svc.OnLogon {
+			trigger = "at logon"
+		}
+		if delay := svc.EffectiveStartupDelay(); delay > 0 {
+			trigger += ", delayed " + formatTaskDelay(delay)
+		}
+		command += "\n(then adds a trigger " + trigger + ", and runs the task once)"
+	}
+	if svc.CatchUp {
+		command += "\n(then enables \"Run task as soon as possible after a scheduled start is missed\")"
+	}
+	if lines := taskDescription(svc); len(lines) > 0 {
+		command += "\n(then sets the task description to: " + strings.Join(lines, " / ") + ")"
+	}
+	return []Definition{wrapper, {Name: "Task Scheduler command", Content: command + "\n"}}, nil
 }
 
-func escapeWindowsCommand(s string) string {
-	if strings.ContainsAny(s, " \t\"&|<>()") {
-		escaped := strings.ReplaceAll(s, `"`, `""`)
-		return `"` + escaped + `"`
+// Export returns the XML of the task of a service. schtasks builds the task
+// from options rather than XML, and some settings are added afterwards, so
+// the XML is Task Scheduler's own, of the installed task.
+func (m *WindowsManager) Export(svc *service.Service) ([]Definition, error) {
+	if svc.LongRunning {
+		return nil, fmt.Errorf("'%s' is a long-running service, registered with the service control manager rather than as a task", svc.Name)
+	}
+	normalizedName := normalizeServiceName(svc.Name)
+	if m.fallbackMode(normalizedName) == FallbackStartupFolder {
+		return nil, fmt.Errorf("'%s' is installed as a Startup folder shortcut, which has no task", svc.Name)
+	}
+	script := fmt.Sprintf("Export-ScheduledTask -TaskPath '%s' -TaskName '%s'",
+		escapePowerShellSingleQuoted(taskFolder+`\`), escapePowerShellSingleQuoted(normalizedName))
+	output, err := trace.Output(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export the task of '%s', which must be installed: %w", svc.Name, err)
 	}
-	return s
+	return []Definition{{Name: normalizedName + ".xml", Content: string(output)}}, nil
 }
 
-func escapeWindowsPath(path string) string {
-	escaped := strings.ReplaceAll(path, `"`, `""`)
-	return `"` + escaped + `"`
+// Artifacts returns the task of a service and the wrapper it runs, with the
+// Startup shortcut of one installed at user level, or the registered service
+// of a long-running one, and the firewall rules of one with network none.
+func (m *WindowsManager) Artifacts(svc *service.Service) ([]Artifact, error) {
+	normalizedName := normalizeServiceName(svc.Name)
+	var firewall []Artifact
+	if svc.Network == service.NetworkNone {
+		firewall = []Artifact{{Kind: "Firewall rules", Name: firewallRuleName(normalizedName)}}
+	}
+	if svc.LongRunning {
+		return append([]Artifact{{Kind: "Windows service", Name: scmName(normalizedName)}}, firewall...), nil
+	}
+	wrapperPath, err := m.wrapperFilePath(normalizedName)
+	if err != nil {
+		return nil, err
+	}
+	// Tasks installed by older versions run a wrapper in %APPDATA% until reinstalled
+	if _, err := os.Stat(wrapperPath); err != nil {
+		if legacy, err := legacyWrapperPath(normalizedName); err == nil {
+			if _, err := os.Stat(legacy); err == nil {
+				wrapperPath = legacy
+			}
+		}
+	}
+	mode := m.fallbackMode(normalizedName)
+	var artifacts []Artifact
+	if mode != FallbackStartupFolder {
+		artifacts = append(artifacts, Artifact{Kind: "Task", Name: resolveTaskName(normalizedName)})
+	}
+	artifacts = append(artifacts, Artifact{Kind: "Wrapper", Path: wrapperPath})
+	if strings.Contains(mode, FallbackStartupFolder) {
+		enabled, disabled, err := startupShortcutPaths(normalizedName)
+		if err != nil {
+			return nil, err
+		}
+		if !fileExists(enabled) {
+			enabled = disabled
+		}
+		artifacts = append(artifacts, Artifact{Kind: "Startup shortcut", Path: enabled})
+	}
+	return append(artifacts, firewall...), nil
 }
 
-// escapePowerShellSingleQuoted escapes a string for use inside PowerShell single-quoted strings.
-// In PowerShell single-quoted strings, only single quotes need escaping (by doubling).
-// This is used for embedding values in wrapper scripts.
-func escapePowerShellSingleQuoted(s string) string {
-	// Single quotes are the only characters that need escaping in single-quoted strings
-	// They are escaped by doubling them
-	return strings.ReplaceAll(s, "'", "''")
-}
+// enableStartWhenAvailable turns on "Run task as soon as possible after a scheduled
+// start is missed". schtasks /create has no switch for it, so the task's settings
+// are updated through the ScheduledTasks PowerShell module.
+func enableStartWhenAvailable(normalizedName string) error {
+	script := fmt.Sprintf(
+		"$task = Get-ScheduledTask -TaskPath '%[1]s' -TaskName '%[2]s'; $task.Settings.StartWhenAvailable = $true; Set-ScheduledTask -TaskPath '%[1]s' -TaskName '%[2]s' -Settings $task.Settings | Out-Null",
+		escapePowerShellSingleQuoted(taskFolder+`\`), escapePowerShellSingleQuoted(normalizedName))
 
-// escapePowerShellLiteral escapes a string for use in PowerShell single-quoted (literal) strings.
-// Single-quoted strings in PowerShell are literal except for single quotes themselves.
-// Deprecated: Use escapePowerShellSingleQuoted instead.
-func escapePowerShellLiteral(s string) string {
-	// Escape single quotes by doubling them
-	escaped := strings.ReplaceAll(s, "'", "''")
-	return "'" + escaped + "'"
+	output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script))
+	if err != nil {
+		return fmt.Errorf("failed to enable catch-up: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
 }
 
-// marshalWindowsArgs properly marshals command-line arguments using Windows escaping rules.
-// This implements the CommandLineToArgvW escaping algorithm.
-func marshalWindowsArgs(args []string) string {
-	if len(args) == 0 {
-		return ""
+// addStartupTrigger adds a boot trigger, or with logon a logon trigger, to the
+// task of a service that also runs at an interval. schtasks /create takes a
+// single trigger, so the second one is added through the ScheduledTasks
+// PowerShell module.
+func addStartupTrigger(normalizedName string, logon bool, delay time.Duration) error {
+	trigger := "New-ScheduledTaskTrigger -AtStartup"
+	if logon {
+		trigger = "New-ScheduledTaskTrigger -AtLogOn"
 	}
+	script := fmt.Sprintf(
+		"$task = Get-ScheduledTask -TaskPath '%[1]s' -TaskName '%[2]s'; $trigger = %[3]s; $trigger.Delay = '%[4]s'; Set-ScheduledTask -TaskPath '%[1]s' -TaskName '%[2]s' -Trigger (@($task.Triggers) + $trigger) | Out-Null",
+		escapePowerShellSingleQuoted(taskFolder+`\`), escapePowerShellSingleQuoted(normalizedName), trigger, fmt.Sprintf("PT%dS", int(delay.Seconds())))
 
-	var quoted []string
-	for _, arg := range args {
-		quoted = append(quoted, quoteWindowsArg(arg))
+	output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script))
+	if err != nil {
+		return fmt.Errorf("failed to add startup trigger: %s: %w", strings.TrimSpace(string(output)), err)
 	}
-	return strings.Join(quoted, " ")
+	return nil
 }
 
-// quoteWindowsArg quotes a single argument according to Windows CommandLineToArgvW rules.
-func quoteWindowsArg(arg string) string {
-	// Check if quoting is needed
-	if arg == "" {
-		return `""`
+// taskDescription returns the lines of the description shown for a service's
+// task: its own description followed by its owner and labels.
+func taskDescription(svc *service.Service) []string {
+	var lines []string
+	if svc.Description != "" {
+		lines = append(lines, svc.Description)
 	}
+	return append(lines, metadataComments(svc)...)
+}
 
-	needsQuote := strings.ContainsAny(arg, " \t\"")
-	if !needsQuote {
-		return arg
+// setTaskDescription sets the description of a service's task, which
+// schtasks /create can't, through the ScheduledTasks PowerShell module.
+func setTaskDescription(normalizedName string, lines []string) error {
+	quoted := make([]string, len(lines))
+	for i, line := range lines {
+		quoted[i] = "'" + escapePowerShellSingleQuoted(line) + "'"
 	}
+	script := fmt.Sprintf(
+		"$task = Get-ScheduledTask -TaskPath '%s' -TaskName '%s'; $task.Description = (%s) -join \"`r`n\"; $task | Set-ScheduledTask | Out-Null",
+		escapePowerShellSingleQuoted(taskFolder+`\`), escapePowerShellSingleQuoted(normalizedName), strings.Join(quoted, ", "))
 
-	// Build escaped argument
-	var result strings.Builder
-	result.WriteByte('"')
-
-	for i := 0; i < len(arg); i++ {
-		backslashes := 0
-
-		// Count consecutive backslashes
-		for i < len(arg) && arg[i] == '\\' {
-			backslashes++
-			i++
-		}
-
-		if i >= len(arg) {
-			// Backslashes at end - double them
-			for j := 0; j < backslashes*2; j++ {
-				result.WriteByte('\\')
-			}
-			break
-		} else if arg[i] == '"' {
-			// Backslashes before quote - double them + escape quote
-			for j := 0; j < backslashes*2+1; j++ {
-				result.WriteByte('\\')
-			}
-			result.WriteByte('"')
-		} else {
-			// Normal backslashes
-			for j := 0; j < backslashes; j++ {
-				result.WriteByte('\\')
-			}
-			result.WriteByte(arg[i])
-		}
+	output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script))
+	if err != nil {
+		return fmt.Errorf("failed to set task description: %s: %w", strings.TrimSpace(string(output)), err)
 	}
+	return nil
+}
 
-	result.WriteByte('"')
-	return result.String()
+// escapePowerShellLiteral escapes a string for use in PowerShell single-quoted (literal) strings.
+// Single-quoted strings in PowerShell are literal except for single quotes themselves.
+// Deprecated: Use escapePowerShellSingleQuoted instead.
+func escapePowerShellLiteral(s string) string {
+	// Escape single quotes by doubling them
+	escaped := strings.ReplaceAll(s, "'", "''")
+	return "'" + escaped + "'"
 }
 
-// createLoggingWrapper creates a PowerShell wrapper script that adds timestamps to logs.
+// createWrapper creates the PowerShell wrapper a task launches.
 // Returns the wrapper path and an error if creation fails.
-func createLoggingWrapper(normalizedName, command, logPath string) (string, error) {
-	// Get APPDATA directory safely
-	appData, err := getAppDataDir()
+func (m *WindowsManager) createWrapper(normalizedName string, execArgs []string) (string, error) {
+	wrapperPath, err := m.wrapperFilePath(normalizedName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get APPDATA directory: %w", err)
+		return "", err
 	}
 
 	// Save wrapper script in dedicated wrappers directory
-	wrapperDir := filepath.Join(appData, "nazim", "wrappers")
-	if err := os.MkdirAll(wrapperDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(wrapperPath), 0755); err != nil {
 		return "", fmt.Errorf("failed to create wrappers directory: %w", err)
 	}
 
-	wrapperPath := filepath.Join(wrapperDir, fmt.Sprintf("%s-wrapper.ps1", normalizedName))
-
-	// Escape command for PowerShell single-quoted strings
-	// Must escape: single quotes, and prevent breaking out of the string
-	escapedCommand := escapePowerShellSingleQuoted(command)
-	escapedLogPath := escapePowerShellSingleQuoted(logPath)
-
-	// Create PowerShell wrapper with timestamp logging
-	wrapperContent := fmt.Sprintf(`# Nazim Logging Wrapper
-# Service: %s
-# Auto-generated - Do not edit manually
-
-$ErrorActionPreference = "Continue"
+	if err := os.WriteFile(wrapperPath, []byte(buildWrapperContent(normalizedName, execArgs)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write wrapper script: %w", err)
+	}
 
-function Get-Timestamp {
-    $local = Get-Date -Format "yyyy-MM-dd HH:mm:ss K"
-    $utc = (Get-Date).ToUniversalTime().ToString("yyyy-MM-dd HH:mm:ss")
-    return "[$local / $utc UTC]"
+	return wrapperPath, nil
 }
 
-$logFile = '%s'
-
-# Ensure log directory exists
-$logDir = Split-Path -Parent $logFile
-if (-not (Test-Path $logDir)) {
-    New-Item -ItemType Directory -Path $logDir -Force | Out-Null
+// wrapperFilePath returns where the wrapper script for a service lives: in the
+// state directory, or where older versions put it when there is none.
+func (m *WindowsManager) wrapperFilePath(normalizedName string) (string, error) {
+	if m.opts.stateDir != "" {
+		return filepath.Join(m.opts.stateDir, "wrappers", fmt.Sprintf("%s-wrapper.ps1", normalizedName)), nil
+	}
+	return legacyWrapperPath(normalizedName)
 }
 
-# Log start
-$timestamp = Get-Timestamp
-Add-Content -Path $logFile -Value "$timestamp Starting execution"
-
-# Execute command and capture all output
-try {
-    $output = & cmd /c '%s' 2>&1
-    $exitCode = $LASTEXITCODE
-
-    # Log each line of output with timestamp
-    if ($output) {
-        $timestamp = Get-Timestamp
-        if ($output -is [array]) {
-            foreach ($line in $output) {
-                Add-Content -Path $logFile -Value "$timestamp $line"
-            }
-        } else {
-            Add-Content -Path $logFile -Value "$timestamp $output"
-        }
-    }
-} catch {
-    $timestamp = Get-Timestamp
-    Add-Content -Path $logFile -Value "$timestamp ERROR: $_"
-    $exitCode = 1
+// legacyWrapperPath returns where older versions put the wrapper script for a
+// service, in %APPDATA%. Tasks installed by them still run it.
+func legacyWrapperPath(normalizedName string) (string, error) {
+	// Get APPDATA directory safely
+	appData, err := getAppDataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get APPDATA directory: %w", err)
+	}
+	return filepath.Join(appData, "nazim", "wrappers", fmt.Sprintf("%s-wrapper.ps1", normalizedName)), nil
 }
 
-# Log finish
-$timestamp = Get-Timestamp
-Add-Content -Path $logFile -Value "$timestamp Finished with exit code $exitCode"
-Add-Content -Path $logFile -Value ""
-
-exit $exitCode
-`, normalizedName, escapedLogPath, escapedCommand)
-
-	if err := os.WriteFile(wrapperPath, []byte(wrapperContent), 0644); err != nil {
-		return "", fmt.Errorf("failed to write wrapper script: %w", err)
+// buildWrapperContent generates the PowerShell wrapper script.
+// Running through a hidden PowerShell window keeps `nazim exec`, a console
+// program, from flashing a terminal; the runner itself records output and timestamps.
+func buildWrapperContent(normalizedName string, execArgs []string) string {
+	// Quote every argument as a PowerShell single-quoted literal
+	quoted := make([]string, len(execArgs))
+	for i, arg := range execArgs {
+		quoted[i] = "'" + escapePowerShellSingleQuoted(arg) + "'"
 	}
 
-	return wrapperPath, nil
+	return fmt.Sprintf(`# Nazim Wrapper
+# Service: %s
+# Auto-generated - Do not edit manually
+
+& %s
+exit $LASTEXITCODE
+`, normalizedName, strings.Join(quoted, " "))
 }
 
-// deleteLoggingWrapper removes the wrapper script for a service.
-func deleteLoggingWrapper(normalizedName string) {
-	appData, err := getAppDataDir()
-	if err != nil {
-		return // Silently fail if we can't get APPDATA
+// deleteWrapper removes the wrapper script for a service, wherever it was written.
+func (m *WindowsManager) deleteWrapper(normalizedName string, failed *MultiError) {
+	for _, path := range []func(string) (string, error){m.wrapperFilePath, legacyWrapperPath} {
+		if wrapperPath, err := path(normalizedName); err == nil {
+			if err := os.Remove(wrapperPath); err != nil && !os.IsNotExist(err) {
+				failed.add("wrapper "+wrapperPath, err)
+			}
+		}
 	}
-	wrapperPath := filepath.Join(appData, "nazim", "wrappers", fmt.Sprintf("%s-wrapper.ps1", normalizedName))
-	_ = os.Remove(wrapperPath)
 }
 
 // Uninstall removes a service from Windows.
@@ -439,36 +677,63 @@ func deleteLoggingWrapper(normalizedName string) {
 // The CLI layer detects this error and exits silently, allowing the elevated process to complete.
 func (m *WindowsManager) Uninstall(name string) error {
 	normalizedName := normalizeServiceName(name)
-	taskName := fmt.Sprintf("Nazim_%s", normalizedName)
 
-	// Require admin upfront for task deletion
+	// Require admin upfront for task deletion, unless the service was
+	// installed without it
 	if !isAdmin() {
-		if err := checkAdminOrElevate(); err != nil {
+		if m.fallbackMode(normalizedName) != "" {
+			return m.uninstallUserLevel(name, normalizedName)
+		}
+		if err := checkAdminOrElevate(m.opts.output); err != nil {
 			return fmt.Errorf("administrator privileges required: %w", err)
 		}
 		// Elevated process will handle deletion - return error to stop original process
 		return fmt.Errorf("Elevated process will handle deletion")
 	}
 
-	// We are admin - proceed with deletion
-	cmd := exec.Command("schtasks", "/delete", "/tn", taskName, "/f")
-	output, err := cmd.CombinedOutput()
+	// We are admin - proceed with deletion. A legacy root task is removed too,
+	// so reinstalling a service migrates it into the \Nazim folder
+	failed := &MultiError{Service: name}
+	m.opts.step("Removing task")
+	for _, taskName := range []string{taskPath(normalizedName), legacyTaskName(normalizedName)} {
+		if err := deleteTask(taskName); err != nil {
+			failed.add("task "+taskName, err)
+		}
+	}
+	removeEmptyTaskFolder()
+
+	// Delete the logging wrapper script, and what a user-level install left
+	m.deleteWrapper(normalizedName, failed)
+	removeStartupShortcut(normalizedName, failed)
+	if markerPath, err := m.fallbackMarkerPath(normalizedName); err == nil {
+		if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+			failed.add("fallback marker "+markerPath, err)
+		}
+	}
+	m.opts.step("Removing firewall rules")
+	unblockNetwork(normalizedName)
+
+	// A long-running service is registered with the SCM instead
+	m.opts.step("Removing service")
+	if err := deleteLongRunning(normalizedName); err != nil {
+		failed.add("service "+normalizedName, err)
+	}
+	return failed.verify(m)
+}
+
+// deleteTask deletes a task, treating a task that doesn't exist as deleted.
+func deleteTask(taskName string) error {
+	output, err := trace.CombinedOutput(exec.Command("schtasks", "/delete", "/tn", taskName, "/f"))
 	if err != nil {
 		outputStr := strings.ToLower(string(output))
 		// Task doesn't exist = success
 		if strings.Contains(outputStr, "does not exist") ||
 			strings.Contains(outputStr, "cannot find") ||
 			strings.Contains(outputStr, "not found") {
-			// Still delete wrapper even if task doesn't exist
-			deleteLoggingWrapper(normalizedName)
 			return nil
 		}
 		return fmt.Errorf("failed to delete task: %s: %w", string(output), err)
 	}
-
-	// Delete the logging wrapper script
-	deleteLoggingWrapper(normalizedName)
-
 	return nil
 }
 
@@ -477,20 +742,28 @@ func (m *WindowsManager) Uninstall(name string) error {
 // The CLI layer detects this error and exits silently, allowing the elevated process to complete.
 func (m *WindowsManager) Enable(name string) error {
 	normalizedName := normalizeServiceName(name)
-	taskName := fmt.Sprintf("Nazim_%s", normalizedName)
 
-	// Require admin upfront for task modification
+	// Require admin upfront for task modification, unless the service was
+	// installed without it
+	if mode := m.fallbackMode(normalizedName); mode != "" {
+		return m.setUserLevel(normalizedName, mode, true)
+	}
 	if !isAdmin() {
-		if err := checkAdminOrElevate(); err != nil {
+		if err := checkAdminOrElevate(m.opts.output); err != nil {
 			return fmt.Errorf("administrator privileges required: %w", err)
 		}
 		// Elevated process will handle enabling - return error to stop original process
 		return fmt.Errorf("Elevated process will handle enable")
 	}
 
+	// A long-running service starts at boot again, and now
+	if isLongRunning(normalizedName) {
+		return setLongRunningStart(normalizedName, true)
+	}
+
 	// Enable the task so it will run on schedule
-	cmd := exec.Command("schtasks", "/change", "/tn", taskName, "/enable")
-	output, err := cmd.CombinedOutput()
+	cmd := exec.Command("schtasks", "/change", "/tn", resolveTaskName(normalizedName), "/enable")
+	output, err := trace.CombinedOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to enable task: %s: %w", string(output), err)
 	}
@@ -502,20 +775,28 @@ func (m *WindowsManager) Enable(name string) error {
 // The CLI layer detects this error and exits silently, allowing the elevated process to complete.
 func (m *WindowsManager) Disable(name string) error {
 	normalizedName := normalizeServiceName(name)
-	taskName := fmt.Sprintf("Nazim_%s", normalizedName)
 
-	// Require admin upfront for task modification
+	// Require admin upfront for task modification, unless the service was
+	// installed without it
+	if mode := m.fallbackMode(normalizedName); mode != "" {
+		return m.setUserLevel(normalizedName, mode, false)
+	}
 	if !isAdmin() {
-		if err := checkAdminOrElevate(); err != nil {
+		if err := checkAdminOrElevate(m.opts.output); err != nil {
 			return fmt.Errorf("administrator privileges required: %w", err)
 		}
 		// Elevated process will handle disabling - return error to stop original process
 		return fmt.Errorf("Elevated process will handle disable")
 	}
 
+	// A long-running service stops, and doesn't start at boot anymore
+	if isLongRunning(normalizedName) {
+		return setLongRunningStart(normalizedName, false)
+	}
+
 	// Disable the task to stop future scheduled executions
-	cmd := exec.Command("schtasks", "/change", "/tn", taskName, "/disable")
-	output, err := cmd.CombinedOutput()
+	cmd := exec.Command("schtasks", "/change", "/tn", resolveTaskName(normalizedName), "/disable")
+	output, err := trace.CombinedOutput(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to disable task: %s: %w", string(output), err)
 	}
@@ -526,12 +807,18 @@ func (m *WindowsManager) Disable(name string) error {
 // This triggers immediate execution regardless of the task's enabled/disabled state.
 func (m *WindowsManager) Run(name string) error {
 	normalizedName := normalizeServiceName(name)
-	taskName := fmt.Sprintf("Nazim_%s", normalizedName)
+	if isLongRunning(normalizedName) {
+		return startLongRunning(normalizedName)
+	}
+	if m.startupOnly(normalizedName) {
+		return runStartupShortcut(normalizedName)
+	}
+	taskName := resolveTaskName(normalizedName)
 
 	// /run doesn't require admin privileges on already-created tasks
 	// It just triggers execution using the existing task definition
 	cmd := exec.Command("schtasks", "/run", "/tn", taskName)
-	output, err := cmd.CombinedOutput()
+	output, err := trace.CombinedOutput(cmd)
 	if err != nil {
 		outputStr := strings.ToLower(string(output))
 		// Provide helpful error messages
@@ -545,40 +832,143 @@ func (m *WindowsManager) Run(name string) error {
 	return nil
 }
 
+// Stop stops a running service: the registered service of a long-running one,
+// or the running instance of a task, which Task Scheduler ends.
+func (m *WindowsManager) Stop(name string) error {
+	normalizedName := normalizeServiceName(name)
+	if isLongRunning(normalizedName) {
+		return stopLongRunning(normalizedName)
+	}
+	if m.startupOnly(normalizedName) {
+		return fmt.Errorf("'%s' runs from a Startup folder shortcut, which can't be stopped; end its PowerShell process instead", name)
+	}
+
+	output, err := trace.CombinedOutput(exec.Command("schtasks", "/end", "/tn", resolveTaskName(normalizedName)))
+	if err != nil {
+		if taskNotRunning(output) {
+			return nil
+		}
+		if taskNotFound(output) {
+			return fmt.Errorf("task not found - service may not be installed")
+		}
+		return fmt.Errorf("failed to stop task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
 // IsInstalled checks if a service is installed.
 func (m *WindowsManager) IsInstalled(name string) (bool, error) {
 	normalizedName := normalizeServiceName(name)
-	taskName := fmt.Sprintf("Nazim_%s", normalizedName)
+	if isLongRunning(normalizedName) {
+		return true, nil
+	}
+	if m.startupOnly(normalizedName) {
+		_, err := startupShortcutState(normalizedName)
+		return err == nil, nil
+	}
+	taskName := resolveTaskName(normalizedName)
 
 	cmd := exec.Command("schtasks", "/query", "/tn", taskName)
-	err := cmd.Run()
+	err := trace.Run(cmd)
 	return err == nil, nil
 }
 
-// GetTaskState returns the state of a scheduled task ("Enabled" or "Disabled").
+// GetTaskState returns the state of a scheduled task, or of a long-running
+// service as the service control manager reports it.
 // Returns error if task is not found.
-func (m *WindowsManager) GetTaskState(name string) (string, error) {
+func (m *WindowsManager) GetTaskState(name string) (*ServiceState, error) {
 	normalizedName := normalizeServiceName(name)
-	taskName := fmt.Sprintf("Nazim_%s", normalizedName)
+	if isLongRunning(normalizedName) {
+		props, err := longRunningProps(normalizedName)
+		if err != nil {
+			return nil, err
+		}
+		state := &ServiceState{
+			Installed:     true,
+			Enabled:       props["Start Type"] != "Disabled",
+			Running:       props["State"] == "Running",
+			BackendDetail: props["State"],
+		}
+		if !state.Running && props["Exit Code"] != "0" {
+			state.LastResult = props["Exit Code"]
+		}
+		return state, nil
+	}
+	if m.startupOnly(normalizedName) {
+		return startupShortcutState(normalizedName)
+	}
+	return queryTaskState(resolveTaskName(normalizedName))
+}
 
-	cmd := exec.Command("schtasks", "/query", "/tn", taskName)
-	output, err := cmd.CombinedOutput()
+// queryTaskState returns the state of a task, see taskStateScript.
+func queryTaskState(taskName string) (*ServiceState, error) {
+	output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", taskStateScript(taskName)))
 	if err != nil {
-		outputStr := strings.ToLower(string(output))
-		if strings.Contains(outputStr, "does not exist") ||
-			strings.Contains(outputStr, "cannot find") {
-			return "", fmt.Errorf("task not found")
-		}
-		return "", fmt.Errorf("failed to query task: %w", err)
+		return nil, fmt.Errorf("failed to query task: %s: %w", strings.TrimSpace(string(output)), err)
 	}
+	return parseTaskState(string(output))
+}
 
-	outputStr := string(output)
+// Details returns Task Scheduler's record of the task's last run, or the
+// state of a long-running service.
+func (m *WindowsManager) Details(name string) (map[string]string, error) {
+	props, err := m.Raw(name)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if task is disabled by looking for "Disabled" in the Status column
-	if strings.Contains(outputStr, "Disabled") {
-		return "Disabled", nil
+	normalizedName := normalizeServiceName(name)
+	if isLongRunning(normalizedName) {
+		return pickDetails(props, "State", "PID", "Exit Code"), nil
 	}
+	if m.startupOnly(normalizedName) {
+		return props, nil
+	}
+	return taskDetails(props), nil
+}
 
-	// If not disabled and task exists, it's enabled (Ready, Running, etc.)
-	return "Enabled", nil
+// Raw returns every field `schtasks /query /v` reports for the task, or the
+// service control manager for a long-running service.
+func (m *WindowsManager) Raw(name string) (map[string]string, error) {
+	normalizedName := normalizeServiceName(name)
+	if isLongRunning(normalizedName) {
+		return longRunningProps(normalizedName)
+	}
+	if m.startupOnly(normalizedName) {
+		return startupShortcutProps(normalizedName)
+	}
+	taskName := resolveTaskName(normalizedName)
+
+	output, err := trace.CombinedOutput(exec.Command("schtasks", "/query", "/tn", taskName, "/fo", "LIST", "/v"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	return parseTaskList(string(output)), nil
+}
+
+// NextRun returns the Next Run Time Task Scheduler reports for the task.
+// Long-running services run until stopped, so they have none.
+func (m *WindowsManager) NextRun(svc *service.Service) (time.Time, error) {
+	if normalizedName := normalizeServiceName(svc.Name); isLongRunning(normalizedName) || m.startupOnly(normalizedName) {
+		return time.Time{}, nil
+	}
+	state, err := m.GetTaskState(svc.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return state.NextRun, nil
+}
+
+// LastRun returns the Last Run Time Task Scheduler reports for the task. The
+// service control manager doesn't record when long-running services started.
+func (m *WindowsManager) LastRun(name string) (time.Time, error) {
+	if normalizedName := normalizeServiceName(name); isLongRunning(normalizedName) || m.startupOnly(normalizedName) {
+		return time.Time{}, nil
+	}
+	state, err := m.GetTaskState(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return state.LastRun, nil
 }
@@ -0,0 +1,342 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// Installing a service the usual way takes administrator privileges. When the
+// UAC prompt is declined, or policy keeps it from showing, a service that
+// doesn't need them is installed for the current user alone instead (see
+// installUserLevel), and a marker next to its wrapper records how, so later
+// commands change it without asking to elevate again.
+
+// userLevelProblem returns why a service can't be installed without
+// administrator privileges, or nil if it can.
+func userLevelProblem(svc *service.Service) error {
+	switch {
+	case svc.LongRunning:
+		return fmt.Errorf("long-running services are registered with the service control manager, which takes them")
+	case svc.OnStartup:
+		return fmt.Errorf("startup tasks run as SYSTEM, which takes them; --on-logon runs at logon without")
+	case svc.Elevated:
+		return fmt.Errorf("--elevated runs the task with highest privileges, which takes them")
+	case svc.Network == service.NetworkNone:
+		return fmt.Errorf("--network none adds firewall rules, which takes them")
+	case len(svc.Units) > 0:
+		return fmt.Errorf("a hand-edited task is registered as written, which takes them")
+	}
+	return nil
+}
+
+// fallBack installs a service at user level after elevation failed with
+// elevationErr, or again when it was installed that way before and
+// elevationErr is nil, and reports how.
+func (m *WindowsManager) fallBack(svc *service.Service, elevationErr error) error {
+	if elevationErr != nil {
+		if problem := userLevelProblem(svc); problem != nil {
+			return fmt.Errorf("%w; '%s' can't be installed without administrator privileges either: %v\nHint: Try running the command as administrator manually", elevationErr, svc.Name, problem)
+		}
+		fmt.Fprintf(m.opts.output, "Elevation failed: %v\nInstalling '%s' for your user only, without administrator privileges.\n", elevationErr, svc.Name)
+	}
+	mode, err := m.installUserLevel(svc)
+	if err != nil {
+		return err
+	}
+	if m.opts.fallback != nil {
+		m.opts.fallback(svc.Name, mode)
+	}
+	return nil
+}
+
+// installUserLevel installs a service for the current user alone, without
+// administrator privileges: a task of theirs, which runs only while they are
+// logged on, for its interval, schedule, event, or watched paths, and a
+// shortcut in their Startup folder for its logon run, since standard users
+// can't create logon tasks. It returns the Fallback modes it used.
+func (m *WindowsManager) installUserLevel(svc *service.Service) (string, error) {
+	normalizedName := normalizeServiceName(svc.Name)
+	if err := m.uninstallUserLevel(svc.Name, normalizedName); err != nil {
+		return "", err
+	}
+
+	m.opts.step("Creating wrapper")
+	wrapperPath, err := m.createWrapper(normalizedName, m.execArgs(svc))
+	if err != nil {
+		return "", fmt.Errorf("failed to create wrapper: %w", err)
+	}
+
+	var modes []string
+	if args := userTaskArgs(svc, wrapperPath); args != nil {
+		m.opts.step("Registering task")
+		if output, err := trace.CombinedOutput(exec.Command("schtasks", args...)); err != nil {
+			return "", fmt.Errorf("failed to create task: %s: %w", strings.TrimSpace(string(output)), err)
+		}
+		if svc.CatchUp {
+			m.opts.step("Enabling catch-up")
+			if err := enableStartWhenAvailable(normalizedName); err != nil {
+				return "", err
+			}
+		}
+		if lines := taskDescription(svc); len(lines) > 0 {
+			m.opts.step("Setting description")
+			if err := setTaskDescription(normalizedName, lines); err != nil {
+				return "", err
+			}
+		}
+		modes = append(modes, FallbackUserTask)
+	}
+	if svc.OnLogon {
+		m.opts.step("Creating Startup shortcut")
+		if err := createStartupShortcut(normalizedName, svc, wrapperPath); err != nil {
+			return "", err
+		}
+		modes = append(modes, FallbackStartupFolder)
+	}
+
+	mode := strings.Join(modes, ",")
+	markerPath, err := m.fallbackMarkerPath(normalizedName)
+	if err == nil {
+		err = os.WriteFile(markerPath, []byte(mode+"\n"), 0644)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to record the user-level install: %w", err)
+	}
+
+	if svc.RepeatsAfterStartup() && svc.Enabled {
+		// This session's logon has passed; run now instead, as Install does
+		m.opts.step("Starting first run")
+		if err := m.Run(svc.Name); err != nil {
+			return "", err
+		}
+	}
+	return mode, nil
+}
+
+// userTaskArgs returns the schtasks /create arguments for the user-level task
+// of a service, or nil if it only runs at logon, which takes a shortcut
+// instead. The task runs in the user's session, with /it, so it needs no
+// stored password.
+func userTaskArgs(svc *service.Service, wrapperPath string) []string {
+	args := []string{
+		"/create",
+		"/tn", taskPath(normalizeServiceName(svc.Name)),
+		"/tr", quoteWindowsArg(wrapperHost(svc)) + " " + wrapperHostArgs(wrapperPath),
+		"/it", "/f",
+	}
+	if svc.GetInterval() > 0 || svc.GetSchedule() != nil {
+		return append(args, timedTaskArgs(svc)...)
+	} else if trigger := svc.GetEventTrigger(); trigger != nil {
+		return append(args, eventTaskArgs(trigger)...)
+	} else if len(svc.WatchPaths) > 0 {
+		return append(args, pollTaskArgs...)
+	}
+	return nil
+}
+
+// uninstallUserLevel removes what installUserLevel installed, without
+// administrator privileges.
+func (m *WindowsManager) uninstallUserLevel(name, normalizedName string) error {
+	failed := &MultiError{Service: name}
+	m.opts.step("Removing task")
+	if err := deleteTask(taskPath(normalizedName)); err != nil {
+		failed.add("task "+taskPath(normalizedName), err)
+	}
+	removeEmptyTaskFolder()
+	removeStartupShortcut(normalizedName, failed)
+	m.deleteWrapper(normalizedName, failed)
+	// The marker goes last, as it tells verify where to look for the service
+	if markerPath, err := m.fallbackMarkerPath(normalizedName); err == nil {
+		if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+			failed.add("fallback marker "+markerPath, err)
+		}
+	}
+	return failed.verify(m)
+}
+
+// setUserLevel enables or disables a service installed at user level in the
+// Fallback modes of mode: its task through schtasks, which its owner may
+// change, and its Startup shortcut by moving it.
+func (m *WindowsManager) setUserLevel(normalizedName, mode string, enable bool) error {
+	if strings.Contains(mode, FallbackUserTask) {
+		change := "/disable"
+		if enable {
+			change = "/enable"
+		}
+		if output, err := trace.CombinedOutput(exec.Command("schtasks", "/change", "/tn", taskPath(normalizedName), change)); err != nil {
+			return fmt.Errorf("failed to %s task: %s: %w", strings.TrimPrefix(change, "/"), strings.TrimSpace(string(output)), err)
+		}
+	}
+	if strings.Contains(mode, FallbackStartupFolder) {
+		return setStartupShortcut(normalizedName, enable)
+	}
+	return nil
+}
+
+// fallbackMarkerPath returns the file recording that a service was installed
+// at user level, and in which Fallback modes.
+func (m *WindowsManager) fallbackMarkerPath(normalizedName string) (string, error) {
+	wrapperPath, err := m.wrapperFilePath(normalizedName)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(wrapperPath, "-wrapper.ps1") + "-fallback", nil
+}
+
+// fallbackMode returns the Fallback modes of a service installed at user
+// level, or "" for one installed the usual way.
+func (m *WindowsManager) fallbackMode(normalizedName string) string {
+	markerPath, err := m.fallbackMarkerPath(normalizedName)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(markerPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// startupOnly reports whether a service installed at user level has no task,
+// only a Startup shortcut: Task Scheduler knows nothing of it.
+func (m *WindowsManager) startupOnly(normalizedName string) bool {
+	return m.fallbackMode(normalizedName) == FallbackStartupFolder
+}
+
+// startupShortcutPaths returns where the Startup shortcut of a service is
+// while it is enabled, in the user's Startup folder, and while it is
+// disabled, next to its wrapper, where Windows doesn't run it.
+func startupShortcutPaths(normalizedName string) (enabled, disabled string, err error) {
+	appData, err := getAppDataDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get APPDATA directory: %w", err)
+	}
+	enabled = filepath.Join(appData, "Microsoft", "Windows", "Start Menu", "Programs", "Startup", "nazim-"+normalizedName+".lnk")
+	disabled = filepath.Join(appData, "nazim", "wrappers", "nazim-"+normalizedName+".lnk")
+	return enabled, disabled, nil
+}
+
+// createStartupShortcut creates the Startup shortcut of a service, which runs
+// its wrapper as its task would. Shortcuts are written through the
+// WScript.Shell COM object.
+func createStartupShortcut(normalizedName string, svc *service.Service, wrapperPath string) error {
+	shortcutPath, _, err := startupShortcutPaths(normalizedName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(shortcutPath), 0755); err != nil {
+		return fmt.Errorf("failed to create Startup folder: %w", err)
+	}
+	// Window style 7 starts it minimized, so no window flashes at logon
+	script := fmt.Sprintf(
+		"$s = (New-Object -ComObject WScript.Shell).CreateShortcut('%s'); $s.TargetPath = (Get-Command '%s').Source; $s.Arguments = '%s'; $s.WindowStyle = 7; $s.Save()",
+		escapePowerShellSingleQuoted(shortcutPath), escapePowerShellSingleQuoted(wrapperHost(svc)), escapePowerShellSingleQuoted(wrapperHostArgs(wrapperPath)))
+	output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script))
+	if err != nil {
+		return fmt.Errorf("failed to create Startup shortcut: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// removeStartupShortcut removes the Startup shortcut of a service, enabled
+// or not. One that doesn't exist is already removed.
+func removeStartupShortcut(normalizedName string, failed *MultiError) {
+	enabled, disabled, err := startupShortcutPaths(normalizedName)
+	if err != nil {
+		return
+	}
+	for _, path := range []string{enabled, disabled} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			failed.add("Startup shortcut "+path, err)
+		}
+	}
+}
+
+// setStartupShortcut enables or disables the Startup shortcut of a service
+// by moving it into or out of the Startup folder.
+func setStartupShortcut(normalizedName string, enable bool) error {
+	enabled, disabled, err := startupShortcutPaths(normalizedName)
+	if err != nil {
+		return err
+	}
+	from, to := disabled, enabled
+	if !enable {
+		from, to = enabled, disabled
+	}
+	if fileExists(to) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return fmt.Errorf("failed to move Startup shortcut: %w", err)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("failed to move Startup shortcut: %w", err)
+	}
+	return nil
+}
+
+// startupShortcutState returns the state of the Startup shortcut of a
+// service, as GetTaskState does for a task: only whether it is enabled, since
+// Windows keeps no record of the runs it starts.
+func startupShortcutState(normalizedName string) (*ServiceState, error) {
+	enabled, disabled, err := startupShortcutPaths(normalizedName)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case fileExists(enabled):
+		return &ServiceState{Installed: true, Enabled: true, BackendDetail: "Enabled"}, nil
+	case fileExists(disabled):
+		return &ServiceState{Installed: true, BackendDetail: "Disabled"}, nil
+	}
+	return nil, fmt.Errorf("Startup shortcut not found")
+}
+
+// startupShortcutProps returns what Raw reports for a service that only has a
+// Startup shortcut, in place of the fields of a task.
+func startupShortcutProps(normalizedName string) (map[string]string, error) {
+	state, err := startupShortcutState(normalizedName)
+	if err != nil {
+		return nil, err
+	}
+	enabled, disabled, err := startupShortcutPaths(normalizedName)
+	if err != nil {
+		return nil, err
+	}
+	shortcutPath := enabled
+	if !state.Enabled {
+		shortcutPath = disabled
+	}
+	return map[string]string{
+		"Startup Shortcut": shortcutPath,
+		"Status":           state.String(),
+		"Trigger":          "At logon, from the Startup folder",
+	}, nil
+}
+
+// runStartupShortcut starts a service through its Startup shortcut, enabled
+// or not, as Windows does at logon.
+func runStartupShortcut(normalizedName string) error {
+	enabled, disabled, err := startupShortcutPaths(normalizedName)
+	if err != nil {
+		return err
+	}
+	shortcutPath := enabled
+	if !fileExists(shortcutPath) {
+		shortcutPath = disabled
+	}
+	script := fmt.Sprintf("Start-Process '%s'", escapePowerShellSingleQuoted(shortcutPath))
+	if output, err := trace.CombinedOutput(exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)); err != nil {
+		return fmt.Errorf("failed to run Startup shortcut: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
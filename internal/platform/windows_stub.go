@@ -4,7 +4,30 @@
 // Package platform provides Windows-specific service management stubs for non-Windows builds.
 package platform
 
-import "github.com/calilkhalil/nazim/internal/service"
+import (
+	"context"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// RequiresElevation reports whether installing services will trigger a UAC prompt.
+// Only Windows elevates.
+func RequiresElevation() bool {
+	return false
+}
+
+// IsWindowsService reports whether nazim was started by the Windows service
+// control manager. Only Windows has one.
+func IsWindowsService() bool {
+	return false
+}
+
+// RunAsService runs a long-running service under the Windows service control
+// manager. This is a stub for non-Windows builds and should never be called.
+func RunAsService(ctx context.Context, run func(ctx context.Context) (int, error)) (int, error) {
+	panic("RunAsService should not be called on non-Windows platforms")
+}
 
 // WindowsManager manages services on Windows using Task Scheduler.
 // This is a stub for non-Windows builds.
@@ -12,7 +35,7 @@ type WindowsManager struct{}
 
 // NewWindowsManager creates a new manager for Windows.
 // This is a stub for non-Windows builds and should never be called.
-func NewWindowsManager() *WindowsManager {
+func NewWindowsManager(opts ...Option) *WindowsManager {
 	panic("NewWindowsManager should not be called on non-Windows platforms")
 }
 
@@ -41,12 +64,52 @@ func (m *WindowsManager) Run(name string) error {
 	panic("WindowsManager.Run should not be called on non-Windows platforms")
 }
 
+// Stop stops a running service on Windows.
+func (m *WindowsManager) Stop(name string) error {
+	panic("WindowsManager.Stop should not be called on non-Windows platforms")
+}
+
 // IsInstalled checks if a service is installed.
 func (m *WindowsManager) IsInstalled(name string) (bool, error) {
 	panic("WindowsManager.IsInstalled should not be called on non-Windows platforms")
 }
 
 // GetTaskState returns the state of a scheduled task.
-func (m *WindowsManager) GetTaskState(name string) (string, error) {
+func (m *WindowsManager) GetTaskState(name string) (*ServiceState, error) {
 	panic("WindowsManager.GetTaskState should not be called on non-Windows platforms")
 }
+
+// Details returns Task Scheduler's record of the task's last run.
+func (m *WindowsManager) Details(name string) (map[string]string, error) {
+	panic("WindowsManager.Details should not be called on non-Windows platforms")
+}
+
+// Raw returns every field Task Scheduler reports for the task.
+func (m *WindowsManager) Raw(name string) (map[string]string, error) {
+	panic("WindowsManager.Raw should not be called on non-Windows platforms")
+}
+
+// Artifacts returns the task of a service and the wrapper it runs.
+func (m *WindowsManager) Artifacts(svc *service.Service) ([]Artifact, error) {
+	panic("WindowsManager.Artifacts should not be called on non-Windows platforms")
+}
+
+// Preview returns the wrapper script and schtasks command Install would use.
+func (m *WindowsManager) Preview(svc *service.Service) ([]Definition, error) {
+	panic("WindowsManager.Preview should not be called on non-Windows platforms")
+}
+
+// Export returns the XML of the task of a service.
+func (m *WindowsManager) Export(svc *service.Service) ([]Definition, error) {
+	panic("WindowsManager.Export should not be called on non-Windows platforms")
+}
+
+// NextRun returns the Next Run Time Task Scheduler reports for the task.
+func (m *WindowsManager) NextRun(svc *service.Service) (time.Time, error) {
+	panic("WindowsManager.NextRun should not be called on non-Windows platforms")
+}
+
+// LastRun returns the Last Run Time Task Scheduler reports for the task.
+func (m *WindowsManager) LastRun(name string) (time.Time, error) {
+	panic("WindowsManager.LastRun should not be called on non-Windows platforms")
+}
@@ -0,0 +1,65 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestBuildTaskArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  *service.Service
+		want []string // The arguments after /f
+	}{
+		{
+			name: "startup, high priority",
+			svc:  &service.Service{Name: "job", OnStartup: true, Priority: service.PriorityHigh},
+			want: []string{"/sc", "onstart", "/ru", "SYSTEM"},
+		},
+		{
+			name: "startup, delayed",
+			svc:  &service.Service{Name: "job", OnStartup: true, StartupDelay: service.Duration{Duration: 90 * time.Second}},
+			want: []string{"/sc", "onstart", "/ru", "SYSTEM", "/delay", "0001:45"},
+		},
+		{
+			name: "logon",
+			svc:  &service.Service{Name: "job", OnLogon: true},
+			want: []string{"/sc", "onlogon", "/delay", "0000:15"},
+		},
+		{
+			name: "interval",
+			svc:  &service.Service{Name: "job", Interval: service.Duration{Duration: time.Hour}},
+			want: []string{"/sc", "minute", "/mo", "60"},
+		},
+		{
+			name: "startup then interval",
+			svc:  &service.Service{Name: "job", OnStartup: true, Interval: service.Duration{Duration: time.Hour}},
+			want: []string{"/sc", "minute", "/mo", "60", "/ru", "SYSTEM"},
+		},
+		{
+			name: "startup then daily",
+			svc:  &service.Service{Name: "job", OnStartup: true, Interval: service.Duration{Duration: 24 * time.Hour}},
+			want: []string{"/sc", "daily", "/mo", "1", "/ru", "SYSTEM"},
+		},
+		{
+			name: "logon then interval, delayed",
+			svc: &service.Service{Name: "job", OnLogon: true, Interval: service.Duration{Duration: 15 * time.Minute},
+				StartupDelay: service.Duration{Duration: time.Minute}},
+			want: []string{"/sc", "minute", "/mo", "15"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildTaskArgs(tt.svc, `C:\nazim\job.ps1`)
+			if got := args[6:]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildTaskArgs() triggers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,547 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+	"github.com/calilkhalil/nazim/internal/trace"
+)
+
+// WSL runs Linux in a lightweight VM that Windows shuts down once no program
+// uses it, and the systemd timers of nazim stop with it. Services with the
+// windows-host target are scheduled in the Task Scheduler of the Windows host
+// instead, through schtasks.exe, and their tasks start the distribution with
+// wsl.exe to run `nazim exec` inside it.
+
+// wslTaskFolder is the Task Scheduler folder tasks of services inside WSL go
+// in, one subfolder per distribution. It is apart from \Nazim, which nazim on
+// Windows manages, since these tasks are created without elevation.
+const wslTaskFolder = `\NazimWSL`
+
+// maxTaskCommand is the longest command schtasks /create accepts for /tr.
+const maxTaskCommand = 261
+
+// IsWSL reports whether nazim is running inside WSL.
+func IsWSL() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	return err == nil && strings.Contains(strings.ToLower(string(release)), "microsoft")
+}
+
+// WithTargets sets how a manager finds the target of a service it is given by
+// name, so that inside WSL it reaches the scheduler the service is installed
+// in. Without it, services are taken to be scheduled by systemd.
+func WithTargets(targetOf func(name string) string) Option {
+	return func(o *options) {
+		o.targetOf = targetOf
+	}
+}
+
+// WSLHostManager manages services inside WSL that are scheduled in the Task
+// Scheduler of the Windows host.
+type WSLHostManager struct {
+	opts      options
+	schtasks  string // Path of schtasks.exe
+	distro    string // Distribution the tasks start
+	linuxUser string // User the tasks run nazim as inside it
+}
+
+// NewWSLHostManager creates a manager for the Windows host of the WSL
+// distribution nazim runs in.
+func NewWSLHostManager(opts ...Option) (*WSLHostManager, error) {
+	if !IsWSL() {
+		return nil, fmt.Errorf("target %s needs nazim running inside WSL", service.TargetWindowsHost)
+	}
+	distro := os.Getenv("WSL_DISTRO_NAME")
+	if distro == "" {
+		return nil, fmt.Errorf("WSL_DISTRO_NAME is not set, so the distribution tasks should start is unknown")
+	}
+	schtasks, err := windowsTool("schtasks.exe")
+	if err != nil {
+		return nil, err
+	}
+	current, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the current user: %w", err)
+	}
+	return &WSLHostManager{opts: newOptions(opts), schtasks: schtasks, distro: distro, linuxUser: current.Username}, nil
+}
+
+// windowsTool finds a program of the Windows host, on the PATH WSL shares
+// with Windows or, when that is turned off, in the System32 folder of C:.
+func windowsTool(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	path := filepath.Join("/mnt/c/Windows/System32", name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%s not found; check that WSL interop with Windows is enabled", name)
+	}
+	return path, nil
+}
+
+// taskName returns the name of the task of a service.
+func (m *WSLHostManager) taskName(name string) string {
+	return wslTaskFolder + `\` + normalizeServiceName(m.distro) + `\` + normalizeServiceName(name)
+}
+
+// schtasksCommand returns a command running schtasks.exe with args.
+func (m *WSLHostManager) schtasksCommand(args ...string) *exec.Cmd {
+	return exec.Command(m.schtasks, args...)
+}
+
+// Install writes the wrapper of a service and creates its task.
+func (m *WSLHostManager) Install(svc *service.Service) error {
+	if err := m.Uninstall(svc.Name); err != nil {
+		return err
+	}
+
+	m.opts.step("Creating wrapper")
+	wrapperPath, err := m.wrapperFilePath(svc.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(wrapperPath), 0755); err != nil {
+		return fmt.Errorf("failed to create wrappers directory: %w", err)
+	}
+	if err := os.WriteFile(wrapperPath, []byte(buildWSLWrapperContent(svc.Name, m.execArgs(svc))), 0755); err != nil {
+		return fmt.Errorf("failed to write wrapper script: %w", err)
+	}
+
+	args, err := m.buildTaskArgs(svc, wrapperPath)
+	if err != nil {
+		return err
+	}
+	m.opts.step("Registering task")
+	if output, err := trace.CombinedOutput(m.schtasksCommand(args...)); err != nil {
+		return fmt.Errorf("failed to create task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+
+	m.opts.step("Verifying installation")
+	if output, err := trace.CombinedOutput(m.schtasksCommand("/query", "/tn", m.taskName(svc.Name))); err != nil {
+		return fmt.Errorf("task was created but cannot be queried: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// execArgs returns the command line a service's wrapper runs. Task Scheduler
+// repeats at most every minute, so shorter intervals repeat within it, as on Windows.
+func (m *WSLHostManager) execArgs(svc *service.Service) []string {
+	args := m.opts.execArgs(svc)
+	if svc.RunsPerMinute() == 1 {
+		return args
+	}
+	name := args[len(args)-1]
+	return append(args[:len(args)-1], "--repeat", name)
+}
+
+// buildTaskArgs returns the schtasks /create arguments for a service whose
+// task runs the wrapper at wrapperPath inside the distribution.
+func (m *WSLHostManager) buildTaskArgs(svc *service.Service, wrapperPath string) ([]string, error) {
+	// wsl.exe is a console program; starting it from a hidden PowerShell keeps
+	// a terminal from staying open while the service runs
+	command := fmt.Sprintf(`powershell.exe -NoProfile -WindowStyle Hidden -Command wsl.exe -d '%s' -u '%s' --exec '%s'; exit $LASTEXITCODE`,
+		escapePowerShellSingleQuoted(m.distro), escapePowerShellSingleQuoted(m.linuxUser), escapePowerShellSingleQuoted(wrapperPath))
+	if len(command) > maxTaskCommand {
+		return nil, fmt.Errorf("the task command is %d characters long, over the %d schtasks allows; use a shorter --state-dir", len(command), maxTaskCommand)
+	}
+
+	args := []string{"/create", "/tn", m.taskName(svc.Name), "/tr", command, "/f"}
+	if svc.GetInterval() > 0 || svc.GetSchedule() != nil {
+		args = append(args, timedTaskArgs(svc)...)
+	} else if svc.OnLogon {
+		args = append(args, "/sc", "onlogon")
+		if delay := svc.EffectiveStartupDelay(); delay > 0 {
+			args = append(args, "/delay", formatTaskDelay(delay))
+		}
+	} else if trigger := svc.GetEventTrigger(); trigger != nil {
+		args = append(args, eventTaskArgs(trigger)...)
+	} else if len(svc.WatchPaths) > 0 {
+		args = append(args, pollTaskArgs...)
+	}
+	return args, nil
+}
+
+// wrapperFilePath returns where the wrapper script of a service lives, in the
+// wrappers folder of the state directory.
+func (m *WSLHostManager) wrapperFilePath(name string) (string, error) {
+	stateDir := m.opts.stateDir
+	if stateDir == "" {
+		home, err := getHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		stateDir = filepath.Join(home, ".local", "state", "nazim")
+	}
+	return filepath.Join(stateDir, "wrappers", fmt.Sprintf("%s-wsl.sh", normalizeServiceName(name))), nil
+}
+
+// buildWSLWrapperContent generates the shell script a task runs inside the
+// distribution. It keeps the task's command short, whatever the paths of the
+// config and state directories are.
+func buildWSLWrapperContent(name string, execArgs []string) string {
+	quoted := make([]string, len(execArgs))
+	for i, arg := range execArgs {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return fmt.Sprintf(`#!/bin/sh
+# Nazim Wrapper
+# Service: %s
+# Auto-generated - Do not edit manually
+
+exec %s
+`, name, strings.Join(quoted, " "))
+}
+
+// Uninstall deletes the task of a service and its wrapper.
+func (m *WSLHostManager) Uninstall(name string) error {
+	m.opts.step("Removing task")
+	output, err := trace.CombinedOutput(m.schtasksCommand("/delete", "/tn", m.taskName(name), "/f"))
+	if err != nil && !taskNotFound(output) {
+		return fmt.Errorf("failed to delete task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	if wrapperPath, err := m.wrapperFilePath(name); err == nil {
+		_ = os.Remove(wrapperPath)
+	}
+	return nil
+}
+
+// taskNotFound reports whether schtasks failed because the task doesn't exist.
+func taskNotFound(output []byte) bool {
+	outputStr := strings.ToLower(string(output))
+	return strings.Contains(outputStr, "does not exist") ||
+		strings.Contains(outputStr, "cannot find") ||
+		strings.Contains(outputStr, "not found")
+}
+
+// Enable enables the task of a service.
+func (m *WSLHostManager) Enable(name string) error {
+	if output, err := trace.CombinedOutput(m.schtasksCommand("/change", "/tn", m.taskName(name), "/enable")); err != nil {
+		return fmt.Errorf("failed to enable task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// Disable disables the task of a service.
+func (m *WSLHostManager) Disable(name string) error {
+	if output, err := trace.CombinedOutput(m.schtasksCommand("/change", "/tn", m.taskName(name), "/disable")); err != nil {
+		return fmt.Errorf("failed to disable task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// Run starts the task of a service now.
+func (m *WSLHostManager) Run(name string) error {
+	if output, err := trace.CombinedOutput(m.schtasksCommand("/run", "/tn", m.taskName(name))); err != nil {
+		if taskNotFound(output) {
+			return fmt.Errorf("task not found - service may not be installed")
+		}
+		return fmt.Errorf("failed to run task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// Stop ends the running instance of the task of a service.
+func (m *WSLHostManager) Stop(name string) error {
+	if output, err := trace.CombinedOutput(m.schtasksCommand("/end", "/tn", m.taskName(name))); err != nil {
+		if taskNotRunning(output) {
+			return nil
+		}
+		if taskNotFound(output) {
+			return fmt.Errorf("task not found - service may not be installed")
+		}
+		return fmt.Errorf("failed to stop task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// IsInstalled checks if the task of a service exists.
+func (m *WSLHostManager) IsInstalled(name string) (bool, error) {
+	err := trace.Run(m.schtasksCommand("/query", "/tn", m.taskName(name)))
+	return err == nil, nil
+}
+
+// GetTaskState returns the state of the task of a service, see
+// taskStateScript.
+func (m *WSLHostManager) GetTaskState(name string) (*ServiceState, error) {
+	powershell, err := windowsTool("powershell.exe")
+	if err != nil {
+		return nil, err
+	}
+	output, err := trace.CombinedOutput(exec.Command(powershell, "-NoProfile", "-NonInteractive", "-Command", taskStateScript(m.taskName(name))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return parseTaskState(string(output))
+}
+
+// Details returns Task Scheduler's record of the task's last run.
+func (m *WSLHostManager) Details(name string) (map[string]string, error) {
+	props, err := m.Raw(name)
+	if err != nil {
+		return nil, err
+	}
+	return taskDetails(props), nil
+}
+
+// Raw returns every field `schtasks.exe /query /v` reports for the task.
+func (m *WSLHostManager) Raw(name string) (map[string]string, error) {
+	output, err := trace.CombinedOutput(m.schtasksCommand("/query", "/tn", m.taskName(name), "/fo", "LIST", "/v"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task: %s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return parseTaskList(string(output)), nil
+}
+
+// Preview returns the wrapper script and schtasks.exe command Install would use.
+func (m *WSLHostManager) Preview(svc *service.Service) ([]Definition, error) {
+	wrapperPath, err := m.wrapperFilePath(svc.Name)
+	if err != nil {
+		return nil, err
+	}
+	args, err := m.buildTaskArgs(svc, wrapperPath)
+	if err != nil {
+		return nil, err
+	}
+	return []Definition{
+		{Name: wrapperPath, Content: buildWSLWrapperContent(svc.Name, m.execArgs(svc))},
+		{Name: "Task Scheduler command (Windows host)", Content: "schtasks.exe " + marshalWindowsArgs(args) + "\n"},
+	}, nil
+}
+
+// Export fails: the tasks of the Windows host are created from nazim's
+// settings only.
+func (m *WSLHostManager) Export(svc *service.Service) ([]Definition, error) {
+	return nil, fmt.Errorf("export-unit isn't supported with target %s", service.TargetWindowsHost)
+}
+
+// Artifacts returns the task of a service and the wrapper it runs.
+func (m *WSLHostManager) Artifacts(svc *service.Service) ([]Artifact, error) {
+	wrapperPath, err := m.wrapperFilePath(svc.Name)
+	if err != nil {
+		return nil, err
+	}
+	return []Artifact{
+		{Kind: "Task (Windows host)", Name: m.taskName(svc.Name)},
+		{Kind: "Wrapper", Path: wrapperPath},
+	}, nil
+}
+
+// NextRun returns the Next Run Time Task Scheduler reports for the task.
+func (m *WSLHostManager) NextRun(svc *service.Service) (time.Time, error) {
+	state, err := m.GetTaskState(svc.Name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return state.NextRun, nil
+}
+
+// LastRun returns the Last Run Time Task Scheduler reports for the task.
+func (m *WSLHostManager) LastRun(name string) (time.Time, error) {
+	state, err := m.GetTaskState(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return state.LastRun, nil
+}
+
+// wslManager schedules each service inside WSL with systemd, as on any
+// Linux, or in the Task Scheduler of the Windows host, as its target says.
+// Either may be unavailable: WSL runs without systemd unless it is turned on,
+// and without the Windows host when interop is turned off.
+type wslManager struct {
+	systemd    Manager
+	systemdErr error
+	host       Manager
+	hostErr    error
+	targetOf   func(name string) string
+}
+
+// newWSLManager creates the manager for nazim running inside WSL. It fails
+// only when neither scheduler is available.
+func newWSLManager(opts ...Option) (*wslManager, error) {
+	m := &wslManager{targetOf: newOptions(opts).targetOf}
+	if linux, err := NewLinuxManager(opts...); err != nil {
+		m.systemdErr = err
+	} else {
+		m.systemd = linux
+	}
+	if host, err := NewWSLHostManager(opts...); err != nil {
+		m.hostErr = err
+	} else {
+		m.host = host
+	}
+	if m.systemd == nil && m.host == nil {
+		return nil, fmt.Errorf("%w, and %w", m.systemdErr, m.hostErr)
+	}
+	return m, nil
+}
+
+// forTarget returns the manager of services with the given target.
+func (m *wslManager) forTarget(target string) (Manager, error) {
+	if target == service.TargetWindowsHost {
+		return m.host, m.hostErr
+	}
+	if m.systemd == nil {
+		return nil, fmt.Errorf("%w; use target %s to schedule it in the Task Scheduler of Windows instead", m.systemdErr, service.TargetWindowsHost)
+	}
+	return m.systemd, nil
+}
+
+// forName returns the manager of the service with the given name.
+func (m *wslManager) forName(name string) (Manager, error) {
+	target := ""
+	if m.targetOf != nil {
+		target = m.targetOf(name)
+	}
+	return m.forTarget(target)
+}
+
+// Install installs a service with the scheduler of its target, and removes
+// it from the other one, where it was before its target changed.
+func (m *wslManager) Install(svc *service.Service) error {
+	mgr, err := m.forTarget(svc.Target)
+	if err != nil {
+		return err
+	}
+	other := m.host
+	if svc.OnWindowsHost() {
+		other = m.systemd
+	}
+	if other != nil {
+		if err := other.Uninstall(svc.Name); err != nil {
+			return err
+		}
+	}
+	return mgr.Install(svc)
+}
+
+// Uninstall removes a service from both schedulers, since its target may
+// have changed in the config already.
+func (m *wslManager) Uninstall(name string) error {
+	for _, mgr := range []Manager{m.systemd, m.host} {
+		if mgr == nil {
+			continue
+		}
+		if err := mgr.Uninstall(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *wslManager) Enable(name string) error {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return err
+	}
+	return mgr.Enable(name)
+}
+
+func (m *wslManager) Disable(name string) error {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return err
+	}
+	return mgr.Disable(name)
+}
+
+func (m *wslManager) Run(name string) error {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return err
+	}
+	return mgr.Run(name)
+}
+
+func (m *wslManager) Stop(name string) error {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return err
+	}
+	return mgr.Stop(name)
+}
+
+func (m *wslManager) IsInstalled(name string) (bool, error) {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return false, err
+	}
+	return mgr.IsInstalled(name)
+}
+
+func (m *wslManager) GetTaskState(name string) (*ServiceState, error) {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.GetTaskState(name)
+}
+
+func (m *wslManager) Details(name string) (map[string]string, error) {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Details(name)
+}
+
+func (m *wslManager) Raw(name string) (map[string]string, error) {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Raw(name)
+}
+
+func (m *wslManager) Preview(svc *service.Service) ([]Definition, error) {
+	mgr, err := m.forTarget(svc.Target)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Preview(svc)
+}
+
+func (m *wslManager) Export(svc *service.Service) ([]Definition, error) {
+	mgr, err := m.forTarget(svc.Target)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Export(svc)
+}
+
+func (m *wslManager) Artifacts(svc *service.Service) ([]Artifact, error) {
+	mgr, err := m.forTarget(svc.Target)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Artifacts(svc)
+}
+
+func (m *wslManager) NextRun(svc *service.Service) (time.Time, error) {
+	mgr, err := m.forTarget(svc.Target)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return mgr.NextRun(svc)
+}
+
+func (m *wslManager) LastRun(name string) (time.Time, error) {
+	mgr, err := m.forName(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return mgr.LastRun(name)
+}
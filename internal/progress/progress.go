@@ -0,0 +1,131 @@
+// Package progress shows feedback while slow operations run.
+//
+// On a terminal a Spinner animates the current step on a single line. In verbose
+// mode each step is printed on its own line instead, and redirected output gets
+// no progress at all so scripts only see results.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/term"
+)
+
+// frames are the spinner animation frames; ASCII so every console renders them.
+var frames = []string{"|", "/", "-", "\\"}
+
+// interval is the time between animation frames.
+const interval = 100 * time.Millisecond
+
+// Mode selects how a Spinner reports.
+type Mode int
+
+const (
+	// Silent reports nothing.
+	Silent Mode = iota
+	// Animated redraws the current step on one terminal line.
+	Animated
+	// Steps prints every step on its own line.
+	Steps
+)
+
+// ModeFor picks the mode for writing to w: steps when verbose, an animation on
+// a terminal, and nothing when quiet or when output is redirected.
+func ModeFor(w io.Writer, verbose, quiet bool) Mode {
+	switch {
+	case quiet:
+		return Silent
+	case verbose:
+		return Steps
+	case term.IsTerminal(w):
+		return Animated
+	default:
+		return Silent
+	}
+}
+
+// Spinner reports the steps of one operation.
+type Spinner struct {
+	w    io.Writer
+	mode Mode
+
+	mu      sync.Mutex
+	message string
+	width   int // length of the last drawn line, for clearing it
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Start begins reporting an operation described by message.
+func Start(w io.Writer, mode Mode, message string) *Spinner {
+	s := &Spinner{w: w, mode: mode, message: message}
+	switch mode {
+	case Steps:
+		fmt.Fprintf(w, "%s...\n", message)
+	case Animated:
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.animate()
+	}
+	return s
+}
+
+// Step reports that the operation moved on to a new step.
+func (s *Spinner) Step(message string) {
+	switch s.mode {
+	case Steps:
+		fmt.Fprintf(s.w, "  %s...\n", message)
+	case Animated:
+		s.mu.Lock()
+		s.message = message
+		s.mu.Unlock()
+	}
+}
+
+// Stop ends reporting and clears the animation line.
+// It is safe to call more than once.
+func (s *Spinner) Stop() {
+	if s.mode != Animated || s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "\r%s\r", strings.Repeat(" ", s.width))
+}
+
+func (s *Spinner) animate() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		s.draw(frames[frame%len(frames)])
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Spinner) draw(frame string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line := fmt.Sprintf("%s %s...", frame, s.message)
+	padding := ""
+	if len(line) < s.width {
+		padding = strings.Repeat(" ", s.width-len(line))
+	}
+	fmt.Fprintf(s.w, "\r%s%s", line, padding)
+	s.width = len(line)
+}
@@ -0,0 +1,117 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// debugEnvVars are the environment variables a run records with debug_env:
+// those whose value under a scheduler most often differs from a terminal's.
+var debugEnvVars = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_ALL", "TZ", "TMPDIR",
+	"XDG_RUNTIME_DIR", "DISPLAY", "WAYLAND_DISPLAY", "DBUS_SESSION_BUS_ADDRESS", "SSH_AUTH_SOCK",
+}
+
+// command builds the process for a service.
+// One-liners such as "rm -rf /tmp/old_files" are passed to /bin/sh, or to the
+// service's shell; scripts and binaries are executed directly with their arguments.
+// PowerShell scripts and one-liners go through pwsh.
+func command(ctx context.Context, svc *service.Service) *exec.Cmd {
+	if svc.UsesPowerShell() {
+		return powerShellCommand(ctx, svc)
+	}
+	if svc.IsOneLiner() {
+		shell := svc.Shell
+		if shell == "" {
+			shell = "/bin/sh"
+		}
+		return exec.CommandContext(ctx, shell, "-c", svc.Command)
+	}
+	return exec.CommandContext(ctx, svc.Command, svc.Args...)
+}
+
+// stopWithChildren makes cancelling the context of cmd kill the processes it
+// started too, which would otherwise keep its output open and the run going,
+// by running it in a process group of its own.
+func stopWithChildren(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != syscall.ESRCH {
+			return err
+		}
+		return os.ErrProcessDone
+	}
+}
+
+// start starts cmd with the umask of the service, which the process inherits
+// when it is forked. The mask is process-wide, so it is restored right after.
+func start(cmd *exec.Cmd, svc *service.Service) error {
+	if svc.Umask == "" {
+		return cmd.Start()
+	}
+	mask, err := service.ParseUmask(svc.Umask)
+	if err != nil {
+		return err
+	}
+	old := syscall.Umask(int(mask))
+	defer syscall.Umask(old)
+	return cmd.Start()
+}
+
+// builtinCommand reports whether a bare command only exists inside a shell.
+// Commands other than one-liners are executed directly, so none qualify.
+func builtinCommand(command string) bool {
+	return false
+}
+
+// verifyExecutable checks that the kernel can execute path directly: it needs an
+// executable bit, and text scripts need a shebang line.
+func verifyExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("command %s does not exist", path)
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("command %s is not executable (run: chmod +x %s)", path, path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("command %s cannot be read: %w", path, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+	if bytes.HasPrefix(header, []byte("#!")) || isBinary(header) {
+		return nil
+	}
+	return fmt.Errorf("script %s has no shebang line (add e.g. #!/bin/sh as its first line)", path)
+}
+
+// isBinary recognizes the executable formats nazim can run: ELF and Mach-O.
+func isBinary(header []byte) bool {
+	magics := [][]byte{
+		{0x7f, 'E', 'L', 'F'},
+		{0xfe, 0xed, 0xfa, 0xce}, {0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit
+		{0xfe, 0xed, 0xfa, 0xcf}, {0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit
+		{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal
+	}
+	for _, magic := range magics {
+		if bytes.Equal(header, magic) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+//go:build windows
+// +build windows
+
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// debugEnvVars are the environment variables a run records with debug_env:
+// those whose value under a scheduler most often differs from a console's.
+var debugEnvVars = []string{
+	"PATH", "PATHEXT", "USERNAME", "USERDOMAIN", "USERPROFILE", "HOMEDRIVE", "HOMEPATH",
+	"APPDATA", "LOCALAPPDATA", "TEMP", "TMP", "ComSpec", "PSModulePath", "SystemRoot",
+}
+
+// command builds the process for a service.
+// Everything runs through cmd /c so one-liners, .bat files, and executables behave
+// as they do in a console. The command line is passed verbatim because cmd does
+// not follow the CommandLineToArgvW quoting rules Go applies by default.
+// PowerShell scripts and one-liners are the exception and run through PowerShell itself.
+func command(ctx context.Context, svc *service.Service) *exec.Cmd {
+	if svc.UsesPowerShell() {
+		cmd := powerShellCommand(ctx, svc)
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+		return cmd
+	}
+
+	commandLine := buildWindowsCommand(svc.Command, svc.Args)
+	// One-liners such as "del /Q C:\temp\*.tmp" are passed through unquoted
+	if svc.IsOneLiner() {
+		commandLine = svc.Command
+	}
+
+	cmd := exec.CommandContext(ctx, "cmd")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine:    "cmd /c " + commandLine,
+		HideWindow: true,
+	}
+	return cmd
+}
+
+// stopWithChildren makes cancelling the context of cmd end the processes it
+// started too, which would otherwise keep its output open and the run going:
+// cmd /c leaves the command running when only it is killed.
+func stopWithChildren(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
+
+// start starts cmd. Windows has no umask: files the command creates get the
+// permissions their folder passes on, so a service's umask is ignored.
+func start(cmd *exec.Cmd, svc *service.Service) error {
+	return cmd.Start()
+}
+
+// cmdBuiltins are cmd.exe internal commands, which have no executable of their own.
+var cmdBuiltins = map[string]bool{
+	"assoc": true, "call": true, "cd": true, "chdir": true, "cls": true, "copy": true,
+	"date": true, "del": true, "dir": true, "echo": true, "erase": true, "md": true,
+	"mkdir": true, "mklink": true, "move": true, "rd": true, "ren": true, "rename": true,
+	"rmdir": true, "set": true, "start": true, "time": true, "type": true, "ver": true,
+	"vol": true,
+}
+
+// builtinCommand reports whether a bare command is a cmd.exe internal command.
+// Everything runs through cmd /c, so these work without an executable.
+func builtinCommand(command string) bool {
+	return cmdBuiltins[strings.ToLower(command)]
+}
+
+// verifyExecutable checks that cmd /c can start path. cmd picks the interpreter
+// from the file extension, so any existing file is accepted.
+func verifyExecutable(path string) error {
+	return nil
+}
+
+func buildWindowsCommand(cmd string, args []string) string {
+	escapedCmd := escapeWindowsCommand(cmd)
+
+	if len(args) == 0 {
+		return escapedCmd
+	}
+
+	escapedArgs := make([]string, len(args))
+	for i, arg := range args {
+		escapedArgs[i] = escapeWindowsCommand(arg)
+	}
+
+	return escapedCmd + " " + strings.Join(escapedArgs, " ")
+}
+
+func escapeWindowsCommand(s string) string {
+	if strings.ContainsAny(s, " \t\"&|<>()") {
+		escaped := strings.ReplaceAll(s, `"`, `""`)
+		return `"` + escaped + `"`
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Journal logs a run made of other runs rather than of a command, such as
+// that of a pipeline running its steps: its lines go to the service's logs
+// with the same start and end markers as Run writes, following its
+// log_level, so `nazim logs` and the run history show it like any other.
+// Its methods may be called from several goroutines, such as those running
+// the members of a parallel group.
+type Journal struct {
+	mu     sync.Mutex
+	result *Result
+	logs   *runLogs
+	stamps stamper
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Begin starts the journal of a run of svc, writing its start marker.
+func Begin(svc *service.Service, opts Options) (*Journal, error) {
+	if err := os.MkdirAll(opts.LogsDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating logs directory: %w", err)
+	}
+	logs, err := openLogs(opts.LogsDir, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{logs: logs, stamps: stamper(svc.LogTimestamps), stdout: logs.stdout, stderr: logs.stderr}
+	if opts.Transcript != nil {
+		var mu sync.Mutex
+		j.stdout = io.MultiWriter(logs.stdout, &streamTagger{w: opts.Transcript, mu: &mu, stream: StreamStdout, stamps: j.stamps})
+		j.stderr = io.MultiWriter(logs.stderr, &streamTagger{w: opts.Transcript, mu: &mu, stream: StreamStderr, stamps: j.stamps})
+	}
+
+	j.result = &Result{Start: time.Now()}
+	j.result.ID = strconv.FormatInt(j.result.Start.UnixNano(), 36)
+	j.stamps.writeLine(j.stdout, j.result.Start, fmt.Sprintf("--- run %s started ---", j.result.ID))
+	return j, nil
+}
+
+// Printf logs a line to stdout.
+func (j *Journal) Printf(format string, args ...any) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.stamps.writeLine(j.stdout, time.Now(), fmt.Sprintf(format, args...))
+}
+
+// Errorf logs a line to stderr, which is kept among the run's last stderr
+// lines like those of a command.
+func (j *Journal) Errorf(format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.stamps.writeLine(j.stderr, time.Now(), line)
+	j.result.ErrorLines = append(j.result.ErrorLines, line)
+	if len(j.result.ErrorLines) > errorLineCount {
+		j.result.ErrorLines = j.result.ErrorLines[1:]
+	}
+}
+
+// Skip logs that a run of svc was skipped, with why, in place of its output.
+// Like the logs of a run, it is best effort.
+func Skip(svc *service.Service, opts Options, reason string) {
+	if svc.LogLevel != "" && svc.LogLevel != service.LogLevelAll {
+		return // Only runs that failed are kept
+	}
+	if err := os.MkdirAll(opts.LogsDir, 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(LogPath(opts.LogsDir, svc.Name, StreamStdout), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	stamper(svc.LogTimestamps).writeLine(f, time.Now(), fmt.Sprintf("--- run skipped: %s ---", reason))
+}
+
+// Finish ends the run with exitCode, writing its end marker, and returns it.
+func (j *Journal) Finish(exitCode int) *Result {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	defer j.logs.close()
+	result := j.result
+	result.End = time.Now()
+	result.ExitCode = exitCode
+	j.stamps.writeLine(j.stdout, result.End, fmt.Sprintf("--- run %s finished with exit code %d (%s) ---",
+		result.ID, result.ExitCode, result.Duration().Round(time.Millisecond)))
+	if result.Failed() {
+		j.logs.keep()
+	}
+	return result
+}
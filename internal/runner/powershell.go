@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// powerShellCommand builds the process for a service that runs through PowerShell:
+// .ps1 scripts are run with -File and one-liners with -Command. On Windows the
+// execution policy is bypassed, as it is for nazim's own wrappers, so unsigned
+// scripts run; elsewhere PowerShell has no execution policy.
+func powerShellCommand(ctx context.Context, svc *service.Service) *exec.Cmd {
+	args := []string{"-NoProfile", "-NonInteractive"}
+	if runtime.GOOS == "windows" {
+		args = append(args, "-ExecutionPolicy", "Bypass")
+	}
+	if svc.IsOneLiner() {
+		args = append(args, "-Command", svc.Command)
+	} else {
+		args = append(args, "-File", svc.Command)
+		args = append(args, svc.Args...)
+	}
+	return exec.CommandContext(ctx, powerShellInterpreter(svc.Shell), args...)
+}
+
+// powerShellInterpreter returns the PowerShell to run: the service's shell if it
+// names one, otherwise pwsh, falling back to Windows PowerShell where pwsh isn't installed.
+func powerShellInterpreter(shell string) string {
+	if service.IsPowerShell(shell) {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		if _, err := exec.LookPath(service.ShellPwsh); err != nil {
+			return service.ShellPowerShell
+		}
+	}
+	return service.ShellPwsh
+}
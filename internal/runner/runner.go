@@ -0,0 +1,624 @@
+// Package runner executes service commands and records their output.
+//
+// Every platform backend schedules `nazim exec <name>`, which calls Run. This keeps
+// output capture identical across Task Scheduler, systemd, and launchd: each run
+// appends timestamped lines to separate stdout and stderr logs.
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Log streams.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// TimestampLayout is the timestamp prefix of log lines, in local time unless
+// the service sets log_timestamps.
+const TimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Options configures a run.
+type Options struct {
+	LogsDir string // Directory holding the per-service stdout/stderr logs
+
+	// Stdout and Stderr, if set, also receive each output line as it arrives,
+	// without the timestamp prefix
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Transcript, if set, receives every line the run logs to either stream,
+	// as "<timestamp> [<stream>] <line>", including the start and end markers
+	Transcript io.Writer
+}
+
+// errorLineCount is how many trailing stderr lines a Result keeps.
+const errorLineCount = 10
+
+// Result describes a finished run.
+type Result struct {
+	ID         string
+	Start      time.Time
+	End        time.Time
+	ExitCode   int
+	Error      string   // Why the command could not be started, if it wasn't
+	ErrorLines []string // Last stderr lines, for failure diagnostics
+
+	// OutputError is why the output of a run that exited with 0 failed it,
+	// under the service's fail_pattern or success_pattern
+	OutputError string
+
+	// Env is what the run saw of its environment, if the service has debug_env
+	Env *Environment
+}
+
+// Environment describes the surroundings a command ran in, which under a
+// scheduler often differ from those of an interactive shell.
+type Environment struct {
+	User string
+	Dir  string            // Working directory
+	Vars map[string]string // The debugEnvVars that were set, by name
+}
+
+// Duration returns how long the run took.
+func (r *Result) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Failed reports whether the run failed: it couldn't start, exited with a
+// non-zero code, or its output failed it.
+func (r *Result) Failed() bool {
+	return r.ExitCode != 0 || r.Error != "" || r.OutputError != ""
+}
+
+// LogPath returns the log file for a service stream.
+func LogPath(logsDir, name, stream string) string {
+	return filepath.Join(logsDir, fmt.Sprintf("%s.%s.log", name, stream))
+}
+
+// Run executes the service command, appending its stdout and stderr to separate logs.
+// A non-zero exit code is reported in the Result, not as an error; errors are
+// reserved for failures to set up or start the run.
+func Run(ctx context.Context, svc *service.Service, opts Options) (*Result, error) {
+	// An invalid pattern fails the run before it leaves a marker in the logs
+	matcher, err := newOutputMatcher(svc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(opts.LogsDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating logs directory: %w", err)
+	}
+
+	logs, err := openLogs(opts.LogsDir, svc)
+	if err != nil {
+		return nil, err
+	}
+	defer logs.close()
+
+	stamps := stamper(svc.LogTimestamps)
+	stdoutW, stderrW := logs.stdout, logs.stderr
+	if opts.Transcript != nil {
+		var mu sync.Mutex
+		stdoutW = io.MultiWriter(logs.stdout, &streamTagger{w: opts.Transcript, mu: &mu, stream: StreamStdout, stamps: stamps})
+		stderrW = io.MultiWriter(logs.stderr, &streamTagger{w: opts.Transcript, mu: &mu, stream: StreamStderr, stamps: stamps})
+	}
+
+	result := &Result{Start: time.Now()}
+	result.ID = strconv.FormatInt(result.Start.UnixNano(), 36)
+
+	stamps.writeLine(stdoutW, result.Start, fmt.Sprintf("--- run %s started ---", result.ID))
+
+	cmd := command(ctx, svc)
+	if _, ok := ctx.Deadline(); ok {
+		stopWithChildren(cmd)
+	}
+	if svc.WorkDir != "" {
+		cmd.Dir = svc.WorkDir
+	}
+	if len(svc.Env) > 0 {
+		cmd.Env = append(os.Environ(), svc.Environ()...)
+	}
+	if svc.DebugEnv {
+		result.Env = captureEnvironment(cmd)
+		writeEnvironment(stdoutW, stamps, result.Start, result.Env)
+	}
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("capturing stdout: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("capturing stderr: %w", err)
+	}
+
+	if err := start(cmd, svc); err != nil {
+		result.End = time.Now()
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("failed to start command: %v", err)
+		stamps.writeLine(stderrW, result.End, result.Error)
+		stamps.writeLine(stdoutW, result.End, fmt.Sprintf("--- run %s failed to start ---", result.ID))
+		logs.keep()
+		return result, nil
+	}
+
+	stdoutLines := newLogWriter(stdoutW, svc)
+	stderrLines := newLogWriter(stderrW, svc)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		copyLines(stdoutLines, stdoutPipe, echo(opts.Stdout, matcher.see))
+	}()
+	go func() {
+		defer wg.Done()
+		copyLines(stderrLines, stderrPipe, echo(opts.Stderr, func(line string) {
+			result.ErrorLines = append(result.ErrorLines, line)
+			if len(result.ErrorLines) > errorLineCount {
+				result.ErrorLines = result.ErrorLines[1:]
+			}
+			matcher.see(line)
+		}))
+	}()
+	// Pipes must be drained before Wait closes them
+	wg.Wait()
+	stdoutLines.flush()
+	stderrLines.flush()
+
+	waitErr := cmd.Wait()
+	result.End = time.Now()
+	result.ExitCode = exitCode(waitErr)
+	// A context ended with a cause of its own says why the run was stopped
+	if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() && result.ExitCode != 0 {
+		result.Error = fmt.Sprintf("stopped: %v", cause)
+		stamps.writeLine(stderrW, result.End, result.Error)
+	}
+	if result.ExitCode == 0 {
+		if result.OutputError = matcher.verdict(); result.OutputError != "" {
+			stamps.writeLine(stderrW, result.End, result.OutputError)
+		}
+	}
+
+	stamps.writeLine(stdoutW, result.End, fmt.Sprintf("--- run %s finished with exit code %d (%s) ---",
+		result.ID, result.ExitCode, result.Duration().Round(time.Millisecond)))
+	if result.Failed() {
+		logs.keep()
+	}
+
+	return result, nil
+}
+
+// runLogs are the logs a run writes its output to, as the service's log_level
+// asks: the logs themselves, spool files whose output is appended to the logs
+// only if the run fails, or nothing.
+type runLogs struct {
+	stdout, stderr io.Writer
+	logsDir, name  string
+	files          []*os.File // The logs, or the spool files of errors-only
+	spooled        bool
+}
+
+func openLogs(logsDir string, svc *service.Service) (*runLogs, error) {
+	logs := &runLogs{stdout: io.Discard, stderr: io.Discard, logsDir: logsDir, name: svc.Name}
+	if svc.LogLevel == service.LogLevelOff {
+		return logs, nil
+	}
+	logs.spooled = svc.LogLevel == service.LogLevelErrorsOnly
+	for _, stream := range []string{StreamStdout, StreamStderr} {
+		var f *os.File
+		var err error
+		if logs.spooled {
+			// Named per run, so overlapping runs don't share a spool
+			f, err = os.CreateTemp(logsDir, fmt.Sprintf("%s.%s.*.spool", svc.Name, stream))
+		} else {
+			f, err = os.OpenFile(LogPath(logsDir, svc.Name, stream), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		}
+		if err != nil {
+			logs.close()
+			return nil, fmt.Errorf("opening %s log: %w", stream, err)
+		}
+		logs.files = append(logs.files, f)
+	}
+	logs.stdout, logs.stderr = logs.files[0], logs.files[1]
+	return logs, nil
+}
+
+// keep appends the spooled output of a run to the logs. Like closing them,
+// it is best effort: the run is recorded in the history either way.
+func (l *runLogs) keep() {
+	if !l.spooled {
+		return
+	}
+	for i, stream := range []string{StreamStdout, StreamStderr} {
+		spool := l.files[i]
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			continue
+		}
+		f, err := os.OpenFile(LogPath(l.logsDir, l.name, stream), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			continue
+		}
+		io.Copy(f, spool)
+		f.Close()
+	}
+}
+
+// close closes the logs and deletes the spool files.
+func (l *runLogs) close() {
+	for _, f := range l.files {
+		f.Close()
+		if l.spooled {
+			os.Remove(f.Name())
+		}
+	}
+}
+
+// outputMatcher applies the fail_pattern and success_pattern of a service to
+// the output lines of a run, from both streams, as they arrive.
+type outputMatcher struct {
+	fail, success *regexp.Regexp
+
+	mu        sync.Mutex
+	failedOn  string // The first line matching fail
+	succeeded bool   // Whether a line matched success
+}
+
+// newOutputMatcher compiles the patterns of svc. Without patterns, the matcher
+// passes every run.
+func newOutputMatcher(svc *service.Service) (*outputMatcher, error) {
+	m := &outputMatcher{}
+	var err error
+	if svc.FailPattern != "" {
+		if m.fail, err = regexp.Compile(svc.FailPattern); err != nil {
+			return nil, fmt.Errorf("invalid fail_pattern: %w", err)
+		}
+	}
+	if svc.SuccessPattern != "" {
+		if m.success, err = regexp.Compile(svc.SuccessPattern); err != nil {
+			return nil, fmt.Errorf("invalid success_pattern: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// see matches an output line against the patterns.
+func (m *outputMatcher) see(line string) {
+	if m.fail == nil && m.success == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail != nil && m.failedOn == "" && m.fail.MatchString(line) {
+		m.failedOn = line
+	}
+	if m.success != nil && !m.succeeded && m.success.MatchString(line) {
+		m.succeeded = true
+	}
+}
+
+// maxVerdictLine is how much of the matching line a verdict quotes.
+const maxVerdictLine = 200
+
+// verdict returns why the output failed the run, or "" if it didn't.
+func (m *outputMatcher) verdict() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failedOn != "" {
+		line := m.failedOn
+		if len(line) > maxVerdictLine {
+			line = line[:maxVerdictLine] + "..."
+		}
+		return fmt.Sprintf("output matched fail_pattern: %s", line)
+	}
+	if m.success != nil && !m.succeeded {
+		return "output never matched success_pattern"
+	}
+	return ""
+}
+
+// captureEnvironment returns the environment cmd will run in.
+func captureEnvironment(cmd *exec.Cmd) *Environment {
+	env := &Environment{Dir: cmd.Dir, Vars: map[string]string{}}
+	if u, err := user.Current(); err == nil {
+		env.User = u.Username
+	}
+	if env.Dir == "" {
+		env.Dir, _ = os.Getwd()
+	}
+	environ := cmd.Environ()
+	for _, name := range debugEnvVars {
+		// Later entries win, as they do for the command; Windows ignores case
+		for i := len(environ) - 1; i >= 0; i-- {
+			key, value, _ := strings.Cut(environ[i], "=")
+			if key == name || runtime.GOOS == "windows" && strings.EqualFold(key, name) {
+				env.Vars[name] = value
+				break
+			}
+		}
+	}
+	return env
+}
+
+// writeEnvironment logs env after the start marker of a run, one line per
+// variable, noting the variables that weren't set.
+func writeEnvironment(w io.Writer, stamps stamper, t time.Time, env *Environment) {
+	stamps.writeLine(w, t, fmt.Sprintf("--- environment: user %s, working dir %s ---", env.User, env.Dir))
+	for _, name := range debugEnvVars {
+		if value, ok := env.Vars[name]; ok {
+			stamps.writeLine(w, t, fmt.Sprintf("%s=%s", name, value))
+		} else {
+			stamps.writeLine(w, t, fmt.Sprintf("%s is not set", name))
+		}
+	}
+}
+
+// copyLines copies r to w line by line, stamping each line with its arrival time.
+// If seen is non-nil it is called with every line, including those the log drops.
+// r is read to its end even if reading a line fails, so that the command
+// never blocks writing to a pipe nobody reads.
+func copyLines(w *logWriter, r io.Reader, seen func(string)) {
+	err := readLines(r, func(line string) {
+		w.add(time.Now(), line)
+		if seen != nil {
+			seen(line)
+		}
+	})
+	if err != nil {
+		io.Copy(io.Discard, r)
+	}
+}
+
+// maxLineLength is the longest line read as one; longer lines are split into
+// lines of at most that many bytes.
+const maxLineLength = 1024 * 1024
+
+// readLines calls fn with every line of r, without its line ending, splitting
+// lines longer than maxLineLength. It returns the first error reading r
+// other than io.EOF.
+func readLines(r io.Reader, fn func(line string)) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if err == nil {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		}
+		for len(line) > maxLineLength {
+			fn(string(line[:maxLineLength]))
+			line = line[maxLineLength:]
+		}
+		switch err {
+		case bufio.ErrBufferFull:
+			continue
+		case nil:
+			fn(string(line))
+			line = line[:0]
+			continue
+		}
+		if len(line) > 0 {
+			fn(string(line))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// logWriter writes the output lines of a run to a log. When the service caps
+// its output, the first lines are written as they arrive and the last ones are
+// held back until the run ends; the lines in between are replaced by a marker.
+type logWriter struct {
+	w          io.Writer
+	stamps     stamper
+	head, tail int
+	capped     bool
+	written    int
+	held       []LogLine
+	dropped    int
+	droppedAt  time.Time
+}
+
+func newLogWriter(w io.Writer, svc *service.Service) *logWriter {
+	head, tail := svc.LogLineLimits()
+	return &logWriter{w: w, stamps: stamper(svc.LogTimestamps), head: head, tail: tail, capped: svc.MaxLogLines > 0}
+}
+
+func (l *logWriter) add(t time.Time, line string) {
+	if !l.capped || l.written < l.head {
+		l.stamps.writeLine(l.w, t, line)
+		l.written++
+		return
+	}
+	l.held = append(l.held, LogLine{Time: t, Text: line})
+	if len(l.held) > l.tail {
+		if l.dropped == 0 {
+			l.droppedAt = l.held[0].Time
+		}
+		l.dropped++
+		l.held = l.held[1:]
+	}
+}
+
+// flush writes the truncation marker, if lines were dropped, and the held lines.
+func (l *logWriter) flush() {
+	if l.dropped > 0 {
+		l.stamps.writeLine(l.w, l.droppedAt, fmt.Sprintf("--- %d lines truncated (max_log_lines_per_run is %d) ---", l.dropped, l.head+l.tail))
+	}
+	for _, line := range l.held {
+		l.stamps.writeLine(l.w, line.Time, line.Text)
+	}
+	l.held = nil
+}
+
+// echo wraps a line callback so that lines are also written to w.
+// Either may be nil.
+func echo(w io.Writer, seen func(string)) func(string) {
+	if w == nil {
+		return seen
+	}
+	return func(line string) {
+		fmt.Fprintln(w, line)
+		if seen != nil {
+			seen(line)
+		}
+	}
+}
+
+// streamTagger copies log lines to a transcript shared by both streams,
+// inserting the stream name after the timestamp. Each Write is one line.
+type streamTagger struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	stream string
+	stamps stamper
+}
+
+func (t *streamTagger) Write(p []byte) (int, error) {
+	stamp, line := t.stamps.cut(string(p))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := fmt.Fprintf(t.w, "%s [%s] %s", stamp, t.stream, line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// stamper formats the timestamps of log lines as a service's log_timestamps
+// asks; unset or unknown formats stamp lines in local time.
+type stamper string
+
+func (s stamper) format(t time.Time) string {
+	switch s {
+	case service.LogTimestampsUTC:
+		return t.UTC().Format(TimestampLayout)
+	case service.LogTimestampsBoth:
+		return t.Format(TimestampLayout) + " " + t.UTC().Format(TimestampLayout)
+	case service.LogTimestampsRFC3339:
+		return t.UTC().Format(time.RFC3339)
+	}
+	return t.Format(TimestampLayout)
+}
+
+// cut splits a log line into its timestamp and the rest.
+func (s stamper) cut(line string) (stamp, rest string) {
+	stamp, rest, _ = strings.Cut(line, " ")
+	if s == service.LogTimestampsBoth {
+		second, after, _ := strings.Cut(rest, " ")
+		stamp, rest = stamp+" "+second, after
+	}
+	return stamp, rest
+}
+
+func (s stamper) writeLine(w io.Writer, t time.Time, line string) {
+	fmt.Fprintf(w, "%s %s\n", s.format(t), line)
+}
+
+// exitCode extracts the process exit code from a Wait error.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// LogLine is a single captured output line.
+type LogLine struct {
+	Time    time.Time
+	Service string
+	Stream  string
+	Text    string
+}
+
+// ReadLogs reads the given streams of a service's logs and merges them in time order.
+// Missing log files are treated as empty.
+func ReadLogs(logsDir, name string, streams ...string) ([]LogLine, error) {
+	var lines []LogLine
+	for _, stream := range streams {
+		streamLines, err := readLog(LogPath(logsDir, name, stream), name, stream)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, streamLines...)
+	}
+
+	// Stable so lines with equal timestamps keep their per-stream order
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Time.Before(lines[j].Time)
+	})
+	return lines, nil
+}
+
+// parseStamp splits a log line into its timestamp and the rest. Lines are
+// stamped in any of the log_timestamps formats, and a service may have changed
+// formats between runs, so each line is parsed on its own; the UTC stamp of a
+// line stamped with both is dropped along with the local one.
+func parseStamp(text string) (time.Time, string, bool) {
+	stamp, rest, ok := strings.Cut(text, " ")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	t, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	if second, after, ok := strings.Cut(rest, " "); ok {
+		if utc, err := time.Parse(time.RFC3339Nano, second); err == nil && utc.Equal(t) {
+			rest = after
+		}
+	}
+	return t, rest, true
+}
+
+func readLog(path, name, stream string) ([]LogLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening log: %w", err)
+	}
+	defer f.Close()
+
+	var lines []LogLine
+	var last time.Time
+	err = readLines(f, func(text string) {
+		line := LogLine{Time: last, Service: name, Stream: stream, Text: text}
+		if t, rest, ok := parseStamp(text); ok {
+			line.Time = t
+			line.Text = rest
+			last = t
+		}
+		lines = append(lines, line)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading log: %w", err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("a", maxLineLength)
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"one line", "hello\n", []string{"hello"}},
+		{"no final newline", "hello\nworld", []string{"hello", "world"}},
+		{"crlf", "hello\r\nworld\r\n", []string{"hello", "world"}},
+		{"blank lines", "a\n\nb\n", []string{"a", "", "b"}},
+		{"exactly the limit", long + "\n", []string{long}},
+		{"over the limit", long + "bc\n", []string{long, "bc"}},
+		{"twice the limit", long + long + "\nnext\n", []string{long, long, "next"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			if err := readLines(strings.NewReader(tt.input), func(line string) { got = append(got, line) }); err != nil {
+				t.Fatalf("readLines() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readLines() = %d lines %.40q, want %d lines %.40q", len(got), got, len(tt.want), tt.want)
+			}
+		})
+	}
+}
+
+// failingReader returns its data, then an error.
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("broken pipe")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestReadLinesError(t *testing.T) {
+	var got []string
+	err := readLines(&failingReader{data: "first\npartial"}, func(line string) { got = append(got, line) })
+	if err == nil || err.Error() != "broken pipe" {
+		t.Errorf("readLines() error = %v, want broken pipe", err)
+	}
+	if want := []string{"first", "partial"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readLines() = %q, want %q", got, want)
+	}
+}
+
+func TestRunCapturesStreams(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	dir := t.TempDir()
+	svc := &service.Service{Name: "capture", Command: "echo out; echo err >&2; exit 3"}
+	var stdout, stderr strings.Builder
+	result, err := Run(context.Background(), svc, Options{LogsDir: dir, Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("echoed stdout %q, stderr %q, want %q and %q", stdout.String(), stderr.String(), "out\n", "err\n")
+	}
+	if want := []string{"err"}; !reflect.DeepEqual(result.ErrorLines, want) {
+		t.Errorf("ErrorLines = %q, want %q", result.ErrorLines, want)
+	}
+
+	lines, err := ReadLogs(dir, svc.Name, StreamStdout, StreamStderr)
+	if err != nil {
+		t.Fatalf("ReadLogs() error = %v", err)
+	}
+	streams := make(map[string][]string)
+	for _, line := range lines {
+		if !strings.HasPrefix(line.Text, "---") {
+			streams[line.Stream] = append(streams[line.Stream], line.Text)
+		}
+	}
+	if want := []string{"out"}; !reflect.DeepEqual(streams[StreamStdout], want) {
+		t.Errorf("stdout log = %q, want %q", streams[StreamStdout], want)
+	}
+	if want := []string{"err"}; !reflect.DeepEqual(streams[StreamStderr], want) {
+		t.Errorf("stderr log = %q, want %q", streams[StreamStderr], want)
+	}
+}
+
+// A line longer than any buffer must neither stop the capture nor leave the
+// command blocked writing to its pipe.
+func TestRunLongLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	if _, err := exec.LookPath("head"); err != nil {
+		t.Skip("needs head")
+	}
+	dir := t.TempDir()
+	svc := &service.Service{Name: "long", Command: "head -c 3000000 /dev/zero | tr '\\0' a; echo; echo done"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var stdout strings.Builder
+	result, err := Run(ctx, svc, Options{LogsDir: dir, Stdout: &stdout})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Run() didn't return before the timeout")
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 4 || lines[3] != "done" {
+		t.Fatalf("got %d lines ending %q, want the long line in 3 and done", len(lines), lines[len(lines)-1])
+	}
+	for i, want := range []int{maxLineLength, maxLineLength, 3000000 - 2*maxLineLength} {
+		if len(lines[i]) != want {
+			t.Errorf("line %d has %d bytes, want %d", i, len(lines[i]), want)
+		}
+	}
+
+	logged, err := ReadLogs(dir, svc.Name, StreamStdout)
+	if err != nil {
+		t.Fatalf("ReadLogs() error = %v", err)
+	}
+	if last := logged[len(logged)-2]; last.Text != "done" {
+		t.Errorf("log line before the end marker = %.40q, want done", last.Text)
+	}
+}
+
+// An invalid pattern fails the run before it writes anything to the logs.
+func TestRunInvalidPattern(t *testing.T) {
+	dir := t.TempDir()
+	svc := &service.Service{Name: "pattern", Command: "echo out", FailPattern: "("}
+	if _, err := Run(context.Background(), svc, Options{LogsDir: dir}); err == nil || !strings.Contains(err.Error(), "fail_pattern") {
+		t.Fatalf("Run() error = %v, want an invalid fail_pattern", err)
+	}
+	if lines, err := ReadLogs(dir, svc.Name, StreamStdout, StreamStderr); err != nil || len(lines) != 0 {
+		t.Errorf("ReadLogs() = %q, %v, want no lines", lines, err)
+	}
+}
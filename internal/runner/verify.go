@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+// Verify checks that a service's command can be started the way Run will start it.
+// Problems that certainly fail at runtime, such as a missing script or one without
+// an executable bit, are returned as an error. Problems that may be fine, such as a
+// one-liner starting with a shell builtin, are returned as warnings.
+func Verify(svc *service.Service) (warnings []string, err error) {
+	if svc.WorkDir != "" {
+		info, err := os.Stat(svc.WorkDir)
+		if err != nil {
+			return nil, fmt.Errorf("working directory %s does not exist", svc.WorkDir)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("working directory %s is not a directory", svc.WorkDir)
+		}
+	}
+
+	if svc.UsesPowerShell() {
+		return nil, verifyPowerShell(svc)
+	}
+
+	if svc.IsOneLiner() {
+		if svc.Shell != "" {
+			if _, err := resolveCommand(svc.Shell, svc.WorkDir); err != nil {
+				return nil, fmt.Errorf("shell: %w", err)
+			}
+		}
+		// The shell resolves the command; only the first word can be checked
+		first := strings.Fields(svc.Command)[0]
+		if _, err := resolveCommand(first, svc.WorkDir); err != nil && !builtinCommand(first) {
+			warnings = append(warnings, fmt.Sprintf("%s was not found in PATH; this is fine if it is a shell builtin", first))
+		}
+		return warnings, nil
+	}
+
+	path, err := resolveCommand(svc.Command, svc.WorkDir)
+	if err != nil {
+		if builtinCommand(svc.Command) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := verifyExecutable(path); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// verifyPowerShell checks that PowerShell and the script it runs exist.
+// Scripts need neither an executable bit nor a shebang line, as they're passed to PowerShell.
+func verifyPowerShell(svc *service.Service) error {
+	interpreter := powerShellInterpreter(svc.Shell)
+	if _, err := resolveCommand(interpreter, svc.WorkDir); err != nil {
+		if interpreter == service.ShellPwsh {
+			return fmt.Errorf("%w (install PowerShell 7 from https://aka.ms/powershell)", err)
+		}
+		return err
+	}
+	if svc.IsOneLiner() {
+		return nil
+	}
+
+	// PowerShell looks for the script in the working directory, not in PATH
+	path := svc.Command
+	if !filepath.IsAbs(path) && svc.WorkDir != "" {
+		path = filepath.Join(svc.WorkDir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("script %s does not exist", path)
+	}
+	return nil
+}
+
+// resolveCommand finds the file a command refers to. Paths are taken relative to
+// workDir, as the process starts there; bare names are looked up in PATH.
+func resolveCommand(command, workDir string) (string, error) {
+	if !strings.ContainsAny(command, `/\`) {
+		path, err := exec.LookPath(command)
+		if err != nil {
+			return "", fmt.Errorf("command %s was not found in PATH", command)
+		}
+		return path, nil
+	}
+
+	path := command
+	if !filepath.IsAbs(path) && workDir != "" {
+		path = filepath.Join(workDir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("command %s does not exist", path)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("command %s is a directory", path)
+	}
+	return path, nil
+}
@@ -0,0 +1,183 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of the dates of a blackout window.
+const dateLayout = "2006-01-02"
+
+// Blackout is a window during which runs are skipped, written as days,
+// dates, or either followed by a time of day:
+//
+//	sat,sun
+//	mon..fri
+//	weekends
+//	2024-12-24
+//	2024-12-24..2024-12-26
+//	sun 02:00..04:00
+//	22:00..06:00
+//
+// A time range ending before it starts runs past midnight, into the next day.
+type Blackout struct {
+	Days     []time.Weekday // Sorted; empty with no dates means every day
+	From, To string         // Inclusive range of dates, as YYYY-MM-DD; empty for days
+	Start    int            // Minutes past midnight the window starts at
+	End      int            // Minutes past midnight it ends at; equal to Start means all day
+}
+
+// ParseBlackout parses a blackout window such as "sat,sun" or
+// "2024-12-24..2024-12-26".
+func ParseBlackout(s string) (*Blackout, error) {
+	fields := strings.Fields(strings.ToLower(strings.TrimSpace(s)))
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid blackout %q: expected days or dates, optionally followed by HH:MM..HH:MM (e.g. \"sat,sun\", \"2024-12-24..2024-12-26\")", s)
+	}
+
+	b := &Blackout{}
+	if strings.Contains(fields[len(fields)-1], ":") {
+		start, end, ok := strings.Cut(fields[len(fields)-1], "..")
+		if !ok {
+			return nil, fmt.Errorf("invalid blackout %q: times are written HH:MM..HH:MM", s)
+		}
+		hour, minute, err := parseTimeOfDay(start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blackout %q: %w", s, err)
+		}
+		b.Start = hour*60 + minute
+		if hour, minute, err = parseTimeOfDay(end); err != nil {
+			return nil, fmt.Errorf("invalid blackout %q: %w", s, err)
+		}
+		b.End = hour*60 + minute
+		if b.Start == b.End {
+			return nil, fmt.Errorf("invalid blackout %q: the time range is empty", s)
+		}
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) == 0 {
+		return b, nil // A time of day, every day
+	}
+	if len(fields) > 1 {
+		return nil, fmt.Errorf("invalid blackout %q: expected days or dates, optionally followed by HH:MM..HH:MM", s)
+	}
+
+	if from, to, ranged := strings.Cut(fields[0], ".."); ranged {
+		// A range of days, such as mon..fri or fri..mon past the weekend
+		first, firstOK := weekdayNames[from]
+		last, lastOK := weekdayNames[to]
+		if firstOK && lastOK {
+			for day := first; ; day = (day + 1) % 7 {
+				b.Days = append(b.Days, day)
+				if day == last {
+					break
+				}
+			}
+			sort.Slice(b.Days, func(i, j int) bool { return b.Days[i] < b.Days[j] })
+			if len(b.Days) == 7 {
+				b.Days = nil
+			}
+			return b, nil
+		}
+	}
+	if from, to, ranged := strings.Cut(fields[0], ".."); ranged || strings.Count(from, "-") == 2 {
+		if !ranged {
+			to = from
+		}
+		for _, date := range []string{from, to} {
+			if _, err := time.Parse(dateLayout, date); err != nil {
+				return nil, fmt.Errorf("invalid blackout %q: invalid date %q, use YYYY-MM-DD", s, date)
+			}
+		}
+		if to < from {
+			return nil, fmt.Errorf("invalid blackout %q: %s is before %s", s, to, from)
+		}
+		b.From, b.To = from, to
+		return b, nil
+	}
+
+	switch fields[0] {
+	case "daily", "day":
+		// Every day
+	case "weekdays", "weekday":
+		b.Days = append([]time.Weekday(nil), weekdays...)
+	case "weekends", "weekend":
+		b.Days = append([]time.Weekday(nil), weekends...)
+	default:
+		seen := make(map[time.Weekday]bool)
+		for _, name := range strings.Split(fields[0], ",") {
+			day, ok := weekdayNames[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid blackout %q: unknown day %q", s, name)
+			}
+			if !seen[day] {
+				seen[day] = true
+				b.Days = append(b.Days, day)
+			}
+		}
+		sort.Slice(b.Days, func(i, j int) bool { return b.Days[i] < b.Days[j] })
+		if len(b.Days) == 7 {
+			b.Days = nil
+		}
+	}
+	return b, nil
+}
+
+// String returns the normalized form of the window.
+func (b *Blackout) String() string {
+	var parts []string
+	switch {
+	case b.From != "" && b.From == b.To:
+		parts = append(parts, b.From)
+	case b.From != "":
+		parts = append(parts, b.From+".."+b.To)
+	case len(b.Days) == 0 && b.Start == b.End:
+		parts = append(parts, "daily")
+	case sameDays(b.Days, weekdays):
+		parts = append(parts, "weekdays")
+	case sameDays(b.Days, weekends):
+		parts = append(parts, "weekends")
+	case len(b.Days) > 0:
+		names := make([]string, len(b.Days))
+		for i, d := range b.Days {
+			names[i] = strings.ToLower(d.String())[:3]
+		}
+		parts = append(parts, strings.Join(names, ","))
+	}
+	if b.Start != b.End {
+		parts = append(parts, fmt.Sprintf("%02d:%02d..%02d:%02d", b.Start/60, b.Start%60, b.End/60, b.End%60))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Contains reports whether t, in its own location, falls in the window.
+func (b *Blackout) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case b.Start == b.End:
+		return b.onDay(t)
+	case b.Start < b.End:
+		return b.onDay(t) && minute >= b.Start && minute < b.End
+	}
+	// Past midnight, the window belongs to the day it started on
+	return (b.onDay(t) && minute >= b.Start) || (b.onDay(t.AddDate(0, 0, -1)) && minute < b.End)
+}
+
+// onDay reports whether the window applies on the day of t.
+func (b *Blackout) onDay(t time.Time) bool {
+	if b.From != "" {
+		date := t.Format(dateLayout)
+		return date >= b.From && date <= b.To
+	}
+	if len(b.Days) == 0 {
+		return true
+	}
+	for _, d := range b.Days {
+		if d == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+// Package schedule parses human-readable calendar schedules, and the blackout
+// windows runs are skipped in (see Blackout).
+//
+// Supported forms:
+//
+//	daily 03:30
+//	weekdays 18:00
+//	weekends 10:00
+//	every monday 09:00
+//	every mon,wed,fri at 09:00
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a calendar schedule that fires at a fixed time of day on a set of weekdays.
+type Schedule struct {
+	Days   []time.Weekday // Sorted; empty means every day
+	Hour   int
+	Minute int
+
+	// Location is the time zone the time of day is in; nil means the zone of
+	// the times the schedule is given, usually the local one.
+	Location *time.Location
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var (
+	weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	weekends = []time.Weekday{time.Sunday, time.Saturday}
+)
+
+// Parse parses a human-readable schedule such as "every monday 09:00" or "daily 03:30".
+func Parse(s string) (*Schedule, error) {
+	fields := strings.Fields(strings.ToLower(strings.TrimSpace(s)))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("schedule cannot be empty")
+	}
+
+	if fields[0] == "every" {
+		fields = fields[1:]
+	}
+	// Drop an optional "at" before the time
+	if len(fields) == 3 && fields[1] == "at" {
+		fields = []string{fields[0], fields[2]}
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid schedule %q: expected '<days> <HH:MM>' (e.g. \"daily 03:30\", \"every monday 09:00\")", s)
+	}
+
+	hour, minute, err := parseTimeOfDay(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", s, err)
+	}
+
+	sched := &Schedule{Hour: hour, Minute: minute}
+
+	switch fields[0] {
+	case "daily", "day":
+		// Every day
+	case "weekdays", "weekday":
+		sched.Days = append([]time.Weekday(nil), weekdays...)
+	case "weekends", "weekend":
+		sched.Days = append([]time.Weekday(nil), weekends...)
+	default:
+		seen := make(map[time.Weekday]bool)
+		for _, name := range strings.Split(fields[0], ",") {
+			day, ok := weekdayNames[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid schedule %q: unknown day %q", s, name)
+			}
+			if !seen[day] {
+				seen[day] = true
+				sched.Days = append(sched.Days, day)
+			}
+		}
+		sort.Slice(sched.Days, func(i, j int) bool { return sched.Days[i] < sched.Days[j] })
+		if len(sched.Days) == 7 {
+			sched.Days = nil
+		}
+	}
+
+	return sched, nil
+}
+
+// parseTimeOfDay parses a time of day written HH:MM, or H:MM before 10:00.
+func parseTimeOfDay(s string) (int, int, error) {
+	hourField, minuteField, ok := strings.Cut(s, ":")
+	if !ok || !isDigits(hourField, 1, 2) || !isDigits(minuteField, 2, 2) {
+		return 0, 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	hour, _ := strconv.Atoi(hourField)
+	minute, _ := strconv.Atoi(minuteField)
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("time %q out of range", s)
+	}
+	return hour, minute, nil
+}
+
+// isDigits reports whether s is minLen to maxLen ASCII digits.
+func isDigits(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the normalized form of the schedule.
+func (s *Schedule) String() string {
+	clock := fmt.Sprintf("%02d:%02d", s.Hour, s.Minute)
+
+	switch {
+	case s.Daily():
+		return "daily " + clock
+	case sameDays(s.Days, weekdays):
+		return "weekdays " + clock
+	case sameDays(s.Days, weekends):
+		return "weekends " + clock
+	}
+
+	names := make([]string, len(s.Days))
+	for i, d := range s.Days {
+		names[i] = strings.ToLower(d.String())
+	}
+	return "every " + strings.Join(names, ",") + " " + clock
+}
+
+// Daily returns true if the schedule fires every day.
+func (s *Schedule) Daily() bool {
+	return len(s.Days) == 0
+}
+
+// Matches returns true if the schedule fires on the given weekday.
+func (s *Schedule) Matches(day time.Weekday) bool {
+	if s.Daily() {
+		return true
+	}
+	for _, d := range s.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the first fire time strictly after t, in t's location.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	if s.Location != nil {
+		loc = s.Location
+	}
+	zoned := t.In(loc)
+	for i := 0; i <= 7; i++ {
+		day := zoned.AddDate(0, 0, i)
+		candidate := time.Date(day.Year(), day.Month(), day.Day(), s.Hour, s.Minute, 0, 0, loc)
+		if candidate.After(t) && s.Matches(candidate.Weekday()) {
+			return candidate.In(t.Location())
+		}
+	}
+	// Unreachable for valid schedules: every schedule matches at least one weekday
+	return time.Time{}
+}
+
+// NextN returns the next n fire times after t.
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n)
+	for len(times) < n {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// In returns the schedule as the wall-clock times of loc it fires at after t,
+// for schedulers without time zones, which can move it to another day. It
+// also reports whether those times hold for the year after t: they don't
+// when daylight saving time starts or ends on different dates in the two
+// zones, or in only one of them.
+func (s *Schedule) In(loc *time.Location, t time.Time) (*Schedule, bool) {
+	if s.Location == nil || s.Location.String() == loc.String() {
+		return s, true
+	}
+
+	first := s.Next(t)
+	zoned, local := first.In(s.Location), first.In(loc)
+	converted := &Schedule{Hour: local.Hour(), Minute: local.Minute(), Location: loc}
+	// Days the time moves to, -1, 0, or 1
+	shift := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(zoned.Year(), zoned.Month(), zoned.Day(), 0, 0, 0, 0, time.UTC)) / (24 * time.Hour)
+	for _, day := range s.Days {
+		converted.Days = append(converted.Days, time.Weekday((int(day)+int(shift)+7)%7))
+	}
+	sort.Slice(converted.Days, func(i, j int) bool { return converted.Days[i] < converted.Days[j] })
+
+	stable := true
+	for week := 1; week <= 53 && stable; week++ {
+		fire := s.Next(t.AddDate(0, 0, 7*week))
+		stable = fire.In(loc).Hour() == converted.Hour && fire.In(loc).Minute() == converted.Minute
+	}
+	return converted, stable
+}
+
+func sameDays(a, b []time.Weekday) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	tests := []struct {
+		input        string
+		hour, minute int
+		wantErr      bool
+	}{
+		{"09:30", 9, 30, false},
+		{"9:30", 9, 30, false},
+		{"00:00", 0, 0, false},
+		{"23:59", 23, 59, false},
+		{"24:00", 0, 0, true},
+		{"12:60", 0, 0, true},
+		{"09:30pm", 0, 0, true},
+		{"9:30:99", 0, 0, true},
+		{"9:5", 0, 0, true},
+		{"930", 0, 0, true},
+		{"-1:30", 0, 0, true},
+		{"+9:30", 0, 0, true},
+		{"009:30", 0, 0, true},
+		{":30", 0, 0, true},
+		{"", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			hour, minute, err := parseTimeOfDay(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimeOfDay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if hour != tt.hour || minute != tt.minute {
+				t.Errorf("parseTimeOfDay() = %d:%d, want %d:%d", hour, minute, tt.hour, tt.minute)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		days    []time.Weekday
+		wantErr bool
+	}{
+		{"daily 03:30", "daily 03:30", nil, false},
+		{"weekdays 18:00", "weekdays 18:00", []time.Weekday{1, 2, 3, 4, 5}, false},
+		{"weekends 10:00", "weekends 10:00", []time.Weekday{0, 6}, false},
+		{"every monday 09:00", "every monday 09:00", []time.Weekday{1}, false},
+		{"every mon,wed,fri at 9:00", "every monday,wednesday,friday 09:00", []time.Weekday{1, 3, 5}, false},
+		{"Every FRI,Mon 09:00", "every monday,friday 09:00", []time.Weekday{1, 5}, false},
+		{"every sun,mon,tue,wed,thu,fri,sat 09:00", "daily 09:00", nil, false},
+		{"", "", nil, true},
+		{"daily", "", nil, true},
+		{"every funday 09:00", "", nil, true},
+		{"daily 09:30pm", "", nil, true},
+		{"daily 25:00", "", nil, true},
+		{"every monday at", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("Parse().String() = %q, want %q", got.String(), tt.want)
+			}
+			if !reflect.DeepEqual(got.Days, tt.days) {
+				t.Errorf("Parse().Days = %v, want %v", got.Days, tt.days)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Wednesday, 2024-05-15
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{"daily 11:00", time.Date(2024, 5, 15, 11, 0, 0, 0, time.UTC)},
+		{"daily 10:00", time.Date(2024, 5, 16, 10, 0, 0, 0, time.UTC)},
+		{"weekends 09:00", time.Date(2024, 5, 18, 9, 0, 0, 0, time.UTC)},
+		{"every wednesday 09:00", time.Date(2024, 5, 22, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			sched, err := Parse(tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sched.Next(now); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlackout(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    *Blackout
+		wantErr bool
+	}{
+		{"sat,sun", &Blackout{Days: []time.Weekday{0, 6}}, false},
+		{"fri..mon", &Blackout{Days: []time.Weekday{0, 1, 5, 6}}, false},
+		{"2024-12-24..2024-12-26", &Blackout{From: "2024-12-24", To: "2024-12-26"}, false},
+		{"sun 02:00..04:00", &Blackout{Days: []time.Weekday{0}, Start: 120, End: 240}, false},
+		{"22:00..06:00", &Blackout{Start: 22 * 60, End: 6 * 60}, false},
+		{"22:00", nil, true},
+		{"02:00..02:00", nil, true},
+		{"22:00pm..06:00", nil, true},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBlackout(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBlackout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBlackout() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
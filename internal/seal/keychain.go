@@ -0,0 +1,38 @@
+package seal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainService is the service keys are stored under in the keychain.
+const keychainService = "nazim"
+
+// runKeychain runs a keychain command with input on its stdin, returning its
+// output. It isn't traced: its input or output may be the key.
+func runKeychain(input string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s: %w", name, msg, err)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(output), nil
+}
+
+// decodeKey decodes a key stored in hex.
+func decodeKey(stored string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(stored))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("the stored key is invalid")
+	}
+	return key, nil
+}
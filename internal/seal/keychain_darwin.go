@@ -0,0 +1,25 @@
+//go:build darwin
+
+package seal
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// storeKey adds the key to the login keychain. It is given through security's
+// interactive mode, so that it doesn't show in the list of processes.
+func storeKey(account string, key []byte) ([]byte, error) {
+	command := fmt.Sprintf("add-generic-password -U -a %s -s %s -l \"nazim config key\" -w %s\n",
+		account, keychainService, hex.EncodeToString(key))
+	_, err := runKeychain(command, "security", "-i")
+	return nil, err
+}
+
+func lookupKey(account string, wrapped []byte) ([]byte, error) {
+	stored, err := runKeychain("", "security", "find-generic-password", "-a", account, "-s", keychainService, "-w")
+	if err != nil {
+		return nil, err
+	}
+	return decodeKey(stored)
+}
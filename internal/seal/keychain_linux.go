@@ -0,0 +1,22 @@
+//go:build linux
+
+package seal
+
+import "encoding/hex"
+
+// storeKey adds the key to the Secret Service (GNOME Keyring, KWallet)
+// through secret-tool, which reads it from its stdin. The keyring must be
+// unlocked for runs to read it back, as it is in a logged-in session.
+func storeKey(account string, key []byte) ([]byte, error) {
+	_, err := runKeychain(hex.EncodeToString(key), "secret-tool", "store", "--label=nazim config key",
+		"service", keychainService, "account", account)
+	return nil, err
+}
+
+func lookupKey(account string, wrapped []byte) ([]byte, error) {
+	stored, err := runKeychain("", "secret-tool", "lookup", "service", keychainService, "account", account)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKey(stored)
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !windows
+
+package seal
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func storeKey(account string, key []byte) ([]byte, error) {
+	return nil, fmt.Errorf("the keychain is not supported on %s, use a passphrase", runtime.GOOS)
+}
+
+func lookupKey(account string, wrapped []byte) ([]byte, error) {
+	return nil, fmt.Errorf("the keychain is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package seal
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// storeKey protects the key with DPAPI, which only the same user on the same
+// machine can undo. The protected key is kept in the sealed file itself, so
+// nothing is stored anywhere else.
+func storeKey(account string, key []byte) ([]byte, error) {
+	in := windows.DataBlob{Size: uint32(len(key)), Data: &key[0]}
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	return takeBlob(out), nil
+}
+
+func lookupKey(account string, wrapped []byte) ([]byte, error) {
+	if len(wrapped) == 0 {
+		return nil, windows.ERROR_INVALID_DATA
+	}
+	in := windows.DataBlob{Size: uint32(len(wrapped)), Data: &wrapped[0]}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	return takeBlob(out), nil
+}
+
+// takeBlob copies the data of a blob DPAPI allocated, and frees it.
+func takeBlob(blob windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(blob.Data)))
+	return append([]byte(nil), unsafe.Slice(blob.Data, blob.Size)...)
+}
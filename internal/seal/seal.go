@@ -0,0 +1,335 @@
+// Package seal encrypts the config file at rest.
+//
+// A sealed file is a YAML document holding a single nazim_encrypted block: the
+// original file encrypted with AES-256-GCM, and what is needed to get its key
+// back. The key is either derived from a passphrase with PBKDF2, or random and
+// kept by the OS: in the login keychain on macOS, in the Secret Service
+// through secret-tool on Linux, and protected with DPAPI on Windows.
+//
+//	nazim_encrypted:
+//	  version: 1
+//	  key: passphrase
+//	  passphrase_from: env:NAZIM_PASSPHRASE
+//	  iterations: 600000
+//	  salt: ...
+//	  nonce: ...
+//	  data: ...
+//
+// A passphrase is read from where passphrase_from says, so that scheduled
+// runs, which have no terminal, can decrypt the file too; without it, it is
+// asked for on the terminal.
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/calilkhalil/nazim/internal/term"
+	"gopkg.in/yaml.v3"
+)
+
+// Where the key of a sealed file comes from.
+const (
+	KeyPassphrase = "passphrase" // Derived from a passphrase
+	KeyKeychain   = "keychain"   // Random, kept by the OS
+)
+
+// Iterations is the number of PBKDF2-SHA256 iterations passphrases are
+// stretched with.
+const Iterations = 600000
+
+const version = 1
+
+// envelope is the layout of a sealed file.
+type envelope struct {
+	Sealed *header `yaml:"nazim_encrypted"`
+}
+
+type header struct {
+	Version        int    `yaml:"version"`
+	Key            string `yaml:"key"`                       // KeyPassphrase or KeyKeychain
+	PassphraseFrom string `yaml:"passphrase_from,omitempty"` // See ReadPassphrase
+	Iterations     int    `yaml:"iterations,omitempty"`
+	Salt           string `yaml:"salt,omitempty"`
+	Keychain       string `yaml:"keychain,omitempty"`    // Account the key is stored under
+	WrappedKey     string `yaml:"wrapped_key,omitempty"` // The key protected with DPAPI, on Windows
+	Nonce          string `yaml:"nonce"`
+	Data           string `yaml:"data"`
+}
+
+// Key is the key a file is sealed with, kept to seal it again once it changes.
+type Key struct {
+	header header // Without Nonce and Data
+	key    []byte
+}
+
+// Kind returns where the key comes from: KeyPassphrase or KeyKeychain.
+func (k *Key) Kind() string {
+	return k.header.Key
+}
+
+// PassphraseFrom returns where the passphrase is read from, or "" when it is
+// asked for on the terminal.
+func (k *Key) PassphraseFrom() string {
+	return k.header.PassphraseFrom
+}
+
+// IsSealed reports whether data is a sealed file.
+func IsSealed(data []byte) bool {
+	var env envelope
+	return yaml.Unmarshal(data, &env) == nil && env.Sealed != nil
+}
+
+// NewPassphraseKey derives a key from a passphrase read from source (see
+// ReadPassphrase), which is recorded in the files it seals.
+func NewPassphraseKey(source string) (*Key, error) {
+	passphrase, err := ReadPassphrase(source, true)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	k := &Key{header: header{
+		Version:        version,
+		Key:            KeyPassphrase,
+		PassphraseFrom: source,
+		Iterations:     Iterations,
+		Salt:           base64.StdEncoding.EncodeToString(salt),
+	}}
+	k.key, err = deriveKey(passphrase, salt, Iterations)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// NewKeychainKey generates a random key and hands it to the OS keychain.
+func NewKeychainKey() (*Key, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	k := &Key{header: header{Version: version, Key: KeyKeychain, Keychain: "config-" + hex.EncodeToString(id)}, key: key}
+	wrapped, err := storeKey(k.header.Keychain, key)
+	if err != nil {
+		return nil, fmt.Errorf("storing the key in the keychain: %w", err)
+	}
+	if wrapped != nil {
+		k.header.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	}
+	return k, nil
+}
+
+// Seal encrypts data with k.
+func Seal(data []byte, k *Key) ([]byte, error) {
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	h := k.header
+	h.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	h.Data = base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, data, nil))
+	return yaml.Marshal(envelope{Sealed: &h})
+}
+
+// Open decrypts a sealed file, returning its contents and the key it was
+// sealed with.
+func Open(data []byte) ([]byte, *Key, error) {
+	h, err := readHeader(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, sealed := h.Nonce, h.Data
+	h.Nonce, h.Data = "", ""
+
+	k := &Key{header: h}
+	switch h.Key {
+	case KeyPassphrase:
+		salt, err := base64.StdEncoding.DecodeString(h.Salt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid salt: %w", err)
+		}
+		passphrase, err := ReadPassphrase(h.PassphraseFrom, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if k.key, err = deriveKey(passphrase, salt, h.Iterations); err != nil {
+			return nil, nil, err
+		}
+	case KeyKeychain:
+		var wrapped []byte
+		if h.WrappedKey != "" {
+			if wrapped, err = base64.StdEncoding.DecodeString(h.WrappedKey); err != nil {
+				return nil, nil, fmt.Errorf("invalid wrapped key: %w", err)
+			}
+		}
+		if k.key, err = lookupKey(h.Keychain, wrapped); err != nil {
+			return nil, nil, fmt.Errorf("reading the key from the keychain: %w", err)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown key %q", h.Key)
+	}
+
+	if data, err = decrypt(k, nonce, sealed); err != nil {
+		return nil, nil, err
+	}
+	return data, k, nil
+}
+
+// OpenWith decrypts a sealed file with k, which is already known, such as the
+// key of the config file, so that the passphrase isn't asked for again. A file
+// sealed with another key is opened as Open does.
+func OpenWith(data []byte, k *Key) ([]byte, error) {
+	h, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil || h.Key != k.header.Key || h.Salt != k.header.Salt || h.Keychain != k.header.Keychain {
+		data, _, err := Open(data)
+		return data, err
+	}
+	return decrypt(k, h.Nonce, h.Data)
+}
+
+// readHeader returns the header of a sealed file.
+func readHeader(data []byte) (header, error) {
+	var env envelope
+	if err := yaml.Unmarshal(data, &env); err != nil || env.Sealed == nil {
+		return header{}, fmt.Errorf("not an encrypted file")
+	}
+	if env.Sealed.Version != version {
+		return header{}, fmt.Errorf("unsupported encryption version %d", env.Sealed.Version)
+	}
+	return *env.Sealed, nil
+}
+
+// decrypt decrypts the data of a sealed file with k, both base64-encoded as
+// they are in its header.
+func decrypt(k *Key, encodedNonce, encodedData string) ([]byte, error) {
+	nonce, err := base64.StdEncoding.DecodeString(encodedNonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+	data, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		if k.header.Key == KeyPassphrase {
+			return nil, fmt.Errorf("wrong passphrase, or the file was altered")
+		}
+		return nil, fmt.Errorf("the file was altered, or sealed with another key")
+	}
+	return data, nil
+}
+
+// CheckSource checks where a passphrase is read from: env:NAME for an
+// environment variable, or file:PATH for the first line of a file.
+func CheckSource(source string) error {
+	kind, value, _ := strings.Cut(source, ":")
+	if (kind != "env" && kind != "file") || value == "" {
+		return fmt.Errorf("invalid passphrase source %q, use env:NAME or file:PATH", source)
+	}
+	return nil
+}
+
+// ReadPassphrase reads a passphrase from source (see CheckSource), or asks
+// for it on the terminal when source is "", twice if confirm is set.
+func ReadPassphrase(source string, confirm bool) ([]byte, error) {
+	if source == "" {
+		return askPassphrase(confirm)
+	}
+	if err := CheckSource(source); err != nil {
+		return nil, err
+	}
+
+	var passphrase string
+	kind, value, _ := strings.Cut(source, ":")
+	if kind == "env" {
+		passphrase = os.Getenv(value)
+		if passphrase == "" {
+			return nil, fmt.Errorf("the passphrase variable %s is not set", value)
+		}
+	} else {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("reading the passphrase: %w", err)
+		}
+		passphrase, _, _ = strings.Cut(string(data), "\n")
+		passphrase = strings.TrimSuffix(passphrase, "\r")
+		if passphrase == "" {
+			return nil, fmt.Errorf("the passphrase file %s is empty", value)
+		}
+	}
+	return []byte(passphrase), nil
+}
+
+// askPassphrase asks for a passphrase on the terminal.
+func askPassphrase(confirm bool) ([]byte, error) {
+	passphrase, err := term.ReadPassword(os.Stdin, os.Stderr, "Passphrase: ")
+	if errors.Is(err, term.ErrNotTerminal) {
+		return nil, errors.New("there is no terminal to ask for the passphrase " +
+			"(encrypt the config with --passphrase-from env:NAME or file:PATH for runs without one)")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("the passphrase is empty")
+	}
+	if confirm {
+		again, err := term.ReadPassword(os.Stdin, os.Stderr, "Passphrase (again): ")
+		if err != nil {
+			return nil, fmt.Errorf("reading the passphrase: %w", err)
+		}
+		if string(again) != string(passphrase) {
+			return nil, errors.New("the passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+func deriveKey(passphrase, salt []byte, iterations int) ([]byte, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("invalid iterations %d", iterations)
+	}
+	return pbkdf2.Key(sha256.New, string(passphrase), salt, iterations, 32)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key length %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
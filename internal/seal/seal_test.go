@@ -0,0 +1,148 @@
+package seal
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSealRoundTrip(t *testing.T) {
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "correct horse")
+	k, err := NewPassphraseKey("env:NAZIM_TEST_PASSPHRASE")
+	if err != nil {
+		t.Fatalf("NewPassphraseKey() error = %v", err)
+	}
+	plain := []byte("services:\n  - name: backup\n    command: /opt/backup.sh\n")
+	sealed, err := Seal(plain, k)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !IsSealed(sealed) || IsSealed(plain) {
+		t.Fatalf("IsSealed() doesn't tell the sealed file from the plain one")
+	}
+	if strings.Contains(string(sealed), "backup") {
+		t.Fatalf("sealed file holds the plain text")
+	}
+
+	opened, key, err := Open(sealed)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if string(opened) != string(plain) {
+		t.Errorf("Open() = %q, want %q", opened, plain)
+	}
+	if key.Kind() != KeyPassphrase || key.PassphraseFrom() != "env:NAZIM_TEST_PASSPHRASE" {
+		t.Errorf("Open() key = %s from %q", key.Kind(), key.PassphraseFrom())
+	}
+
+	// Sealing again with the returned key gives a file that opens the same
+	resealed, err := Seal(opened, key)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if again, _, err := Open(resealed); err != nil || string(again) != string(plain) {
+		t.Errorf("Open() of the resealed file = %q, %v", again, err)
+	}
+
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "wrong")
+	if _, _, err := Open(sealed); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Open() with the wrong passphrase error = %v", err)
+	}
+}
+
+func TestOpenAltered(t *testing.T) {
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "correct horse")
+	k, err := NewPassphraseKey("env:NAZIM_TEST_PASSPHRASE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Seal([]byte("services: []\n"), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		alter   func(h *header)
+		wantErr string
+	}{
+		{"data", func(h *header) { h.Data = "AAAA" + h.Data[4:] }, "altered"},
+		{"nonce", func(h *header) { h.Nonce = "AAAA" }, "invalid nonce"},
+		{"version", func(h *header) { h.Version = 2 }, "unsupported encryption version"},
+		{"key", func(h *header) { h.Key = "hsm" }, "unknown key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var env envelope
+			if err := yaml.Unmarshal(sealed, &env); err != nil {
+				t.Fatal(err)
+			}
+			tt.alter(env.Sealed)
+			altered, err := yaml.Marshal(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := Open(altered); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Open() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, _, err := Open([]byte("services: []\n")); err == nil {
+		t.Errorf("Open() of a plain file succeeded")
+	}
+}
+
+func TestCheckSource(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{"env:NAZIM_PASSPHRASE", false},
+		{"file:/etc/nazim/passphrase", false},
+		{"env:", true},
+		{"vault:secret", true},
+		{"NAZIM_PASSPHRASE", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if err := CheckSource(tt.source); (err != nil) != tt.wantErr {
+				t.Errorf("CheckSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenWith(t *testing.T) {
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "correct horse")
+	k, err := NewPassphraseKey("env:NAZIM_TEST_PASSPHRASE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := []byte("[]\n")
+	sealed, err := Seal(plain, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The known key is used without reading the passphrase again
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "")
+	if opened, err := OpenWith(sealed, k); err != nil || string(opened) != string(plain) {
+		t.Errorf("OpenWith() = %q, %v, want %q", opened, err, plain)
+	}
+
+	// A file sealed with another key is opened with its own
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "another")
+	other, err := NewPassphraseKey("env:NAZIM_TEST_PASSPHRASE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := OpenWith(sealed, other); err == nil {
+		t.Errorf("OpenWith() of a file sealed with another key and passphrase = %q, want an error", opened)
+	}
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "correct horse")
+	if opened, err := OpenWith(sealed, other); err != nil || string(opened) != string(plain) {
+		t.Errorf("OpenWith() of a file sealed with another key = %q, %v, want %q", opened, err, plain)
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitArgs splits a command line into arguments the way a shell would, so
+// quoting keeps an argument with spaces whole: `script.py --name 'John Doe'`
+// gives script.py, --name, and John Doe. Single quotes keep everything up to
+// the next single quote as-is; double quotes do too, except that \" stands for
+// a quote. Outside quotes, a backslash keeps a following space or quote
+// literal. Other backslashes are kept, so Windows paths such as C:\jobs\run.py
+// need no escaping. An empty pair of quotes is an empty argument.
+func SplitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && escapable(runes[i+1], quote):
+			i++
+			current.WriteRune(runes[i])
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, s)
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// escapable reports whether a backslash before r escapes it, within quote, or
+// outside quotes when quote is 0.
+func escapable(r, quote rune) bool {
+	switch quote {
+	case '\'':
+		return false
+	case '"':
+		return r == '"'
+	default:
+		return r == ' ' || r == '\t' || r == '"' || r == '\''
+	}
+}
+
+// JoinArgs joins arguments into a command line that SplitArgs splits back
+// into them, quoting the ones that are empty, contain spaces or quotes, or end
+// with a backslash, which would otherwise escape the space after them.
+func JoinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == "":
+			quoted[i] = "''"
+		case !strings.ContainsAny(arg, " \t\n\r\"'") && !strings.HasSuffix(arg, `\`):
+			quoted[i] = arg
+		case !strings.Contains(arg, "'"):
+			quoted[i] = "'" + arg + "'"
+		default:
+			quoted[i] = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
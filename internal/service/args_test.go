@@ -0,0 +1,65 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"   ", nil, false},
+		{"a b  c", []string{"a", "b", "c"}, false},
+		{"script.py --name 'John Doe'", []string{"script.py", "--name", "John Doe"}, false},
+		{`--name "John Doe"`, []string{"--name", "John Doe"}, false},
+		{`say "a \"quoted\" word"`, []string{"say", `a "quoted" word`}, false},
+		{`'it\'s'`, nil, true},
+		{`"it's"`, []string{"it's"}, false},
+		{`a\ b c`, []string{"a b", "c"}, false},
+		{`C:\jobs\run.py`, []string{`C:\jobs\run.py`}, false},
+		{`--flag=''`, []string{"--flag="}, false},
+		{`'' x`, []string{"", "x"}, false},
+		{"a\tb\nc", []string{"a", "b", "c"}, false},
+		{`pre'fix'ed"one"`, []string{"prefixedone"}, false},
+		{`'unterminated`, nil, true},
+		{`"unterminated`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := SplitArgs(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinArgsRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"plain", "args"},
+		{"John Doe"},
+		{""},
+		{"it's", `say "hi"`},
+		{`both ' and "`},
+		{`C:\jobs\`, "next"},
+		{"tab\there", "line\nbreak"},
+	}
+	for _, args := range tests {
+		line := JoinArgs(args)
+		got, err := SplitArgs(line)
+		if err != nil {
+			t.Errorf("SplitArgs(JoinArgs(%q)) error = %v", args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Errorf("SplitArgs(%s) = %q, want %q", line, got, args)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/schedule"
+)
+
+// ParseBlackouts parses blackout windows, such as those of a service or of
+// the blackout block of a config file.
+func ParseBlackouts(windows []string) ([]*schedule.Blackout, error) {
+	var blackouts []*schedule.Blackout
+	for _, window := range windows {
+		b, err := schedule.ParseBlackout(window)
+		if err != nil {
+			return nil, err
+		}
+		blackouts = append(blackouts, b)
+	}
+	return blackouts, nil
+}
+
+// InBlackout returns the window of the service, or of global, that t falls
+// in, or nil if it is in none. Windows are in the time zone of the schedule,
+// the local one without it.
+func (s *Service) InBlackout(t time.Time, global []string) *schedule.Blackout {
+	if loc := s.Location(); loc != nil {
+		t = t.In(loc)
+	} else {
+		t = t.Local()
+	}
+	for _, window := range append(append([]string(nil), global...), s.Blackout...) {
+		// Windows are checked when they are configured
+		if b, err := schedule.ParseBlackout(window); err == nil && b.Contains(t) {
+			return b
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+// Defaults holds settings shared by every service in a config file.
+// A service's own setting takes precedence; environment variables are merged
+// key by key. Boolean defaults can only be turned on: a service can't opt out.
+type Defaults struct {
+	WorkDir       string            `yaml:"workdir,omitempty"`
+	Env           map[string]string `yaml:"env,omitempty"`
+	Shell         string            `yaml:"shell,omitempty"`
+	NotifyDesktop bool              `yaml:"notify_desktop,omitempty"`
+	SlowThreshold Duration          `yaml:"slow_threshold,omitempty"`
+	MaxLogLines   int               `yaml:"max_log_lines_per_run,omitempty"`
+	LogTimestamps string            `yaml:"log_timestamps,omitempty"`
+	Umask         string            `yaml:"umask,omitempty"`
+}
+
+// IsZero reports whether no default is set.
+func (d *Defaults) IsZero() bool {
+	return d == nil || (d.WorkDir == "" && len(d.Env) == 0 && d.Shell == "" &&
+		!d.NotifyDesktop && d.SlowThreshold.Duration == 0 && d.MaxLogLines == 0 &&
+		d.LogTimestamps == "" && d.Umask == "")
+}
+
+// Apply fills the settings svc leaves unset from the defaults.
+func (d *Defaults) Apply(svc *Service) {
+	if d.IsZero() {
+		return
+	}
+	if svc.WorkDir == "" {
+		svc.WorkDir = d.WorkDir
+	}
+	if svc.Shell == "" {
+		svc.Shell = d.Shell
+	}
+	if d.NotifyDesktop {
+		svc.NotifyDesktop = true
+	}
+	if svc.SlowThreshold.Duration == 0 {
+		svc.SlowThreshold = d.SlowThreshold
+	}
+	if svc.MaxLogLines == 0 {
+		svc.MaxLogLines = d.MaxLogLines
+	}
+	if svc.LogTimestamps == "" {
+		svc.LogTimestamps = d.LogTimestamps
+	}
+	if svc.Umask == "" {
+		svc.Umask = d.Umask
+	}
+	if len(d.Env) > 0 {
+		env := make(map[string]string, len(d.Env)+len(svc.Env))
+		for key, value := range d.Env {
+			env[key] = value
+		}
+		for key, value := range svc.Env {
+			env[key] = value
+		}
+		svc.Env = env
+	}
+}
+
+// Strip returns a copy of svc without the settings it inherits from the defaults,
+// undoing Apply so that saving a service doesn't copy the defaults into it.
+func (d *Defaults) Strip(svc *Service) *Service {
+	stripped := *svc
+	if d.IsZero() {
+		return &stripped
+	}
+	if stripped.WorkDir == d.WorkDir {
+		stripped.WorkDir = ""
+	}
+	if stripped.Shell == d.Shell {
+		stripped.Shell = ""
+	}
+	if d.NotifyDesktop {
+		stripped.NotifyDesktop = false
+	}
+	if stripped.SlowThreshold == d.SlowThreshold {
+		stripped.SlowThreshold = Duration{}
+	}
+	if stripped.MaxLogLines == d.MaxLogLines {
+		stripped.MaxLogLines = 0
+	}
+	if stripped.LogTimestamps == d.LogTimestamps {
+		stripped.LogTimestamps = ""
+	}
+	if stripped.Umask == d.Umask {
+		stripped.Umask = ""
+	}
+	if len(svc.Env) > 0 && len(d.Env) > 0 {
+		stripped.Env = make(map[string]string)
+		for key, value := range svc.Env {
+			if inherited, ok := d.Env[key]; !ok || inherited != value {
+				stripped.Env[key] = value
+			}
+		}
+	}
+	return &stripped
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// EventTrigger is a Windows event log event that starts a service, written as
+// Log=<log>;Source=<source>;ID=<id> in on_event. Only the log is required:
+// without a source or ID, any event of the log starts the service.
+type EventTrigger struct {
+	Log    string // Event log channel, such as System or Microsoft-Windows-TaskScheduler/Operational
+	Source string // Provider that wrote the event, such as Service Control Manager
+	ID     int    // Event ID; 0 matches any
+}
+
+// ParseEventTrigger parses an on_event value. Keys are case-insensitive.
+func ParseEventTrigger(s string) (*EventTrigger, error) {
+	trigger := &EventTrigger{}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid on_event %q: expected key=value pairs such as Log=System;Source=...;ID=...", s)
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			return nil, fmt.Errorf("invalid on_event %q: %s is set twice", s, name)
+		}
+		seen[key] = true
+		// The values end up quoted in an XPath query
+		if strings.ContainsAny(value, `'"<>&`) {
+			return nil, fmt.Errorf("invalid on_event %q: %s cannot contain quotes, <, >, or &", s, name)
+		}
+
+		switch key {
+		case "log":
+			trigger.Log = value
+		case "source":
+			trigger.Source = value
+		case "id":
+			id, err := strconv.Atoi(value)
+			if err != nil || id < 1 || id > 65535 {
+				return nil, fmt.Errorf("invalid on_event %q: ID must be a number from 1 to 65535", s)
+			}
+			trigger.ID = id
+		default:
+			return nil, fmt.Errorf("invalid on_event %q: unknown key %q, use Log, Source, and ID", s, name)
+		}
+	}
+	if trigger.Log == "" {
+		return nil, fmt.Errorf("invalid on_event %q: Log is required", s)
+	}
+	return trigger, nil
+}
+
+// String formats the trigger as an on_event value.
+func (t *EventTrigger) String() string {
+	parts := []string{"Log=" + t.Log}
+	if t.Source != "" {
+		parts = append(parts, "Source="+t.Source)
+	}
+	if t.ID != 0 {
+		parts = append(parts, "ID="+strconv.Itoa(t.ID))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Query returns the XPath query Task Scheduler matches the events of the log
+// against, such as *[System[Provider[@Name='Service Control Manager'] and EventID=7036]].
+func (t *EventTrigger) Query() string {
+	var conditions []string
+	if t.Source != "" {
+		conditions = append(conditions, fmt.Sprintf("Provider[@Name='%s']", t.Source))
+	}
+	if t.ID != 0 {
+		conditions = append(conditions, fmt.Sprintf("EventID=%d", t.ID))
+	}
+	if len(conditions) == 0 {
+		return "*"
+	}
+	return fmt.Sprintf("*[System[%s]]", strings.Join(conditions, " and "))
+}
+
+// GetEventTrigger returns the parsed on_event trigger, or nil if none is set.
+func (s *Service) GetEventTrigger() *EventTrigger {
+	if s.OnEvent == "" {
+		return nil
+	}
+	trigger, err := ParseEventTrigger(s.OnEvent)
+	if err != nil {
+		return nil
+	}
+	return trigger
+}
+
+// validateOnEvent checks the on_event trigger of the service. Only Task
+// Scheduler starts tasks on events, and schtasks.exe creates a task with a
+// single trigger, so it can't be combined with the others.
+func (s *Service) validateOnEvent() error {
+	if s.OnEvent == "" {
+		return nil
+	}
+	if _, err := ParseEventTrigger(s.OnEvent); err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && !s.OnWindowsHost() {
+		return fmt.Errorf("on_event triggers on Windows event log events and is not supported on %s", runtime.GOOS)
+	}
+	if s.OnStartup || s.OnLogon || s.Interval.Duration > 0 || s.Schedule != "" || len(s.WatchPaths) > 0 {
+		return fmt.Errorf("on_event can't be combined with other triggers; add a second service for those")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Failure policy actions, written as action:argument in on_failure.
+const (
+	failureRun          = "run"
+	failureDisableAfter = "disable-self-after"
+)
+
+// FailurePolicy is what happens after a failed run.
+type FailurePolicy struct {
+	Run          string // Service started after each failed run, such as a remediation job
+	DisableAfter int    // Failed runs in a row after which the service disables itself
+}
+
+// ParseFailurePolicy parses an on_failure value: run:<service> or
+// disable-self-after:<n>.
+func ParseFailurePolicy(s string) (*FailurePolicy, error) {
+	action, arg, ok := strings.Cut(strings.TrimSpace(s), ":")
+	arg = strings.TrimSpace(arg)
+	if ok && arg != "" {
+		switch action {
+		case failureRun:
+			return &FailurePolicy{Run: arg}, nil
+		case failureDisableAfter:
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid on_failure %q: disable-self-after needs a number of runs of at least 1", s)
+			}
+			return &FailurePolicy{DisableAfter: n}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid on_failure %q, use run:<service> or disable-self-after:<n>", s)
+}
+
+// String formats the policy as an on_failure value.
+func (p *FailurePolicy) String() string {
+	if p.Run != "" {
+		return failureRun + ":" + p.Run
+	}
+	return fmt.Sprintf("%s:%d", failureDisableAfter, p.DisableAfter)
+}
+
+// GetFailurePolicy returns the parsed on_failure policy, or nil if none is set.
+func (s *Service) GetFailurePolicy() *FailurePolicy {
+	if s.OnFailure == "" {
+		return nil
+	}
+	policy, err := ParseFailurePolicy(s.OnFailure)
+	if err != nil {
+		return nil
+	}
+	return policy
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// validateLongRunning checks a long-running service. Only the Windows service
+// control manager runs them, from boot until they are stopped, restarting them
+// when they fail, so other triggers and max_consecutive_failures don't apply.
+func (s *Service) validateLongRunning() error {
+	if !s.LongRunning {
+		return nil
+	}
+	if runtime.GOOS != "windows" {
+		return fmt.Errorf("long_running runs the service as a Windows service and is not supported on %s", runtime.GOOS)
+	}
+	if s.OnStartup || s.OnLogon || s.Interval.Duration > 0 || s.Schedule != "" || len(s.WatchPaths) > 0 || s.OnEvent != "" {
+		return fmt.Errorf("long_running services start at boot and run until stopped, so they can't have other triggers")
+	}
+	if s.MaxConsecutiveFailures > 0 {
+		return fmt.Errorf("long_running services are restarted by the service control manager when they fail; max_consecutive_failures doesn't apply")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// NetworkNone cuts a service off from the network: systemd runs its unit in a
+// network namespace of its own on Linux, and Windows Firewall blocks its
+// program on Windows.
+const NetworkNone = "none"
+
+// validateNetwork checks the network isolation of a service. macOS has no way
+// to isolate a launchd job, so rather than run one with network access that
+// was meant to have none, it is refused there.
+func (s *Service) validateNetwork() error {
+	switch s.Network {
+	case "":
+		return nil
+	case NetworkNone:
+	default:
+		return fmt.Errorf("invalid network %q, use %s", s.Network, NetworkNone)
+	}
+	if runtime.GOOS == "darwin" {
+		return fmt.Errorf("network %s can't be enforced on macOS, where launchd has no network isolation", NetworkNone)
+	}
+	if s.OnWindowsHost() {
+		return fmt.Errorf("network %s is not supported with target %s", NetworkNone, TargetWindowsHost)
+	}
+	return nil
+}
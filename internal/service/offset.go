@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// An offset aligns the runs of an interval to the clock: they start that long
+// past local midnight and repeat every interval from there, so a 1h interval
+// with a 15m offset runs at 00:15, 01:15, 02:15, and so on, rather than an hour
+// after whenever the scheduler happened to load it. Services sharing an
+// interval can be given different offsets to spread their runs out.
+
+// ParseOffset parses an offset such as "0m", "15m", or "1h". Unlike other
+// durations, an offset may be zero, which aligns runs to midnight itself.
+func ParseOffset(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if value := strings.TrimRight(s, "smhd"); value != "" && value != s && strings.Trim(value, "0") == "" {
+		return 0, nil
+	}
+	return parseDuration(s)
+}
+
+// FormatOffset formats an offset the way ParseOffset reads it, in whole hours
+// when it is one and in minutes otherwise, e.g. "0m", "45m", "90m", or "2h".
+func FormatOffset(d time.Duration) string {
+	if d > 0 && d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+// GetOffset returns the offset of the service's interval and whether it has one.
+func (s *Service) GetOffset() (time.Duration, bool) {
+	if s.Offset == "" {
+		return 0, false
+	}
+	offset, err := ParseOffset(s.Offset)
+	if err != nil {
+		return 0, false
+	}
+	return offset, true
+}
+
+// Alignable reports whether an interval can be aligned to the clock with an
+// offset: it must divide an hour, such as 5m or 15m, or divide a day in
+// whole hours, such as 2h or 6h. Calendar-based schedulers can't express
+// anything else, since the runs must fall on the same times every day.
+func Alignable(interval time.Duration) bool {
+	switch {
+	case interval < time.Minute || interval%time.Minute != 0:
+		return false
+	case interval <= time.Hour:
+		return time.Hour%interval == 0
+	default:
+		return interval%time.Hour == 0 && 24*time.Hour%interval == 0
+	}
+}
+
+// validateOffset checks the offset against the rest of the service.
+func (s *Service) validateOffset() error {
+	if s.Offset == "" {
+		return nil
+	}
+	offset, err := ParseOffset(s.Offset)
+	if err != nil {
+		return fmt.Errorf("invalid offset: %w", err)
+	}
+	interval := s.Interval.Duration
+	switch {
+	case interval == 0:
+		return fmt.Errorf("offset requires an interval")
+	case s.OnStartup || s.OnLogon:
+		return fmt.Errorf("offset cannot be combined with on_startup or on_logon")
+	case !Alignable(interval):
+		return fmt.Errorf("offset needs an interval that divides an hour (such as 5m, 15m, or 30m) or a day in whole hours (such as 2h or 6h), got %s", interval)
+	case offset%time.Minute != 0:
+		return fmt.Errorf("offset must be whole minutes, got %s", offset)
+	case offset >= interval:
+		return fmt.Errorf("offset %s must be shorter than the interval %s", FormatOffset(offset), FormatOffset(interval))
+	}
+	return nil
+}
+
+// AlignedTimes returns the times of day an interval with an offset runs at,
+// as durations past midnight. It's nil for a service without an offset.
+func (s *Service) AlignedTimes() []time.Duration {
+	offset, ok := s.GetOffset()
+	interval := s.GetInterval()
+	if !ok || interval <= 0 {
+		return nil
+	}
+	var times []time.Duration
+	for t := offset; t < 24*time.Hour; t += interval {
+		times = append(times, t)
+	}
+	return times
+}
+
+// NextAlignedRun returns the first time after t that a service with an offset
+// runs, in t's location. It's the zero time for a service without one.
+func (s *Service) NextAlignedRun(t time.Time) time.Time {
+	times := s.AlignedTimes()
+	if len(times) == 0 {
+		return time.Time{}
+	}
+	year, month, day := t.Date()
+	for d := 0; d <= 1; d++ {
+		for _, at := range times {
+			// Build each run from its wall-clock time, so DST changes don't shift it
+			run := time.Date(year, month, day+d, int(at/time.Hour), int(at%time.Hour/time.Minute), 0, 0, t.Location())
+			if run.After(t) {
+				return run
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// What a pipeline does when one of its steps fails.
+const (
+	StepFailureAbort    = "abort"    // Skip the remaining steps (the default)
+	StepFailureContinue = "continue" // Run the remaining steps anyway
+)
+
+// IsPipeline reports whether the service is a pipeline, which runs other
+// services one after another instead of a command of its own.
+func (s *Service) IsPipeline() bool {
+	return len(s.Steps) > 0
+}
+
+// IsGroup reports whether the service is a parallel group, which runs other
+// services all at once, or max_parallel at a time, instead of a command of
+// its own.
+func (s *Service) IsGroup() bool {
+	return len(s.ParallelGroup) > 0
+}
+
+// RunsServices reports whether the service runs other services, as a
+// pipeline or a parallel group, rather than a command.
+func (s *Service) RunsServices() bool {
+	return s.IsPipeline() || s.IsGroup()
+}
+
+// Members returns the services a pipeline or a parallel group runs.
+func (s *Service) Members() []string {
+	if s.IsGroup() {
+		return s.ParallelGroup
+	}
+	return s.Steps
+}
+
+// Concurrency returns how many members of a parallel group run at a time.
+func (s *Service) Concurrency() int {
+	if s.MaxParallel > 0 && s.MaxParallel < len(s.ParallelGroup) {
+		return s.MaxParallel
+	}
+	return len(s.ParallelGroup)
+}
+
+// ContinuesOnFailure reports whether a pipeline runs its remaining steps
+// after one of them failed.
+func (s *Service) ContinuesOnFailure() bool {
+	return s.OnStepFailure == StepFailureContinue
+}
+
+// DescribeSteps returns the steps of a pipeline in the order they run, such
+// as "db-backup -> compress -> upload".
+func (s *Service) DescribeSteps() string {
+	return strings.Join(s.Steps, " -> ")
+}
+
+// ParseSteps parses a comma-separated list of services, such as the steps
+// "db-backup,compress,upload" or the members of a parallel group.
+func ParseSteps(value string) ([]string, error) {
+	var steps []string
+	for _, step := range strings.Split(value, ",") {
+		step = strings.TrimSpace(step)
+		if step == "" {
+			return nil, fmt.Errorf("invalid list %q: expected comma-separated service names", value)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// validatePipeline checks the steps of a pipeline and the members of a
+// parallel group. Whether they exist is up to the config, as they may be
+// added after the pipeline or group.
+func (s *Service) validatePipeline() error {
+	if !s.IsPipeline() && s.OnStepFailure != "" {
+		return fmt.Errorf("on_step_failure requires steps")
+	}
+	if !s.IsGroup() && s.MaxParallel != 0 {
+		return fmt.Errorf("max_parallel requires parallel_group")
+	}
+	if !s.RunsServices() {
+		return nil
+	}
+
+	kind, member := "pipeline", "step"
+	if s.IsGroup() {
+		kind, member = "parallel group", "member"
+	}
+	if s.IsPipeline() && s.IsGroup() {
+		return fmt.Errorf("a service can have steps or a parallel_group, not both")
+	}
+	if s.Command != "" || len(s.Args) > 0 {
+		return fmt.Errorf("a %s runs other services, so it can't have a command or args as well", kind)
+	}
+	if s.LongRunning {
+		return fmt.Errorf("a %s runs other services once each, so it can't be long_running", kind)
+	}
+	if s.OnStepFailure != "" && s.OnStepFailure != StepFailureAbort && s.OnStepFailure != StepFailureContinue {
+		return fmt.Errorf("invalid on_step_failure %q, use abort or continue", s.OnStepFailure)
+	}
+	if s.MaxParallel < 0 {
+		return fmt.Errorf("invalid max_parallel %d, must be positive", s.MaxParallel)
+	}
+	seen := make(map[string]bool)
+	for _, name := range s.Members() {
+		if err := validateServiceName(name); err != nil {
+			return fmt.Errorf("invalid %s '%s': %w", member, name, err)
+		}
+		if name == s.Name {
+			return fmt.Errorf("%s '%s' can't be a %s of itself", kind, s.Name, member)
+		}
+		// A service that runs while it already is would trip over itself
+		if s.IsGroup() && seen[name] {
+			return fmt.Errorf("'%s' is in parallel group '%s' more than once", name, s.Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
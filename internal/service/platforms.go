@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+)
+
+// platformNames are the platforms a service can override settings for.
+var platformNames = map[string]bool{"windows": true, "linux": true, "darwin": true}
+
+// PlatformOverride holds settings a service uses instead of its own on one
+// platform, so a single config can be shared between operating systems.
+// Unset fields keep the service's own setting.
+type PlatformOverride struct {
+	Command string   `yaml:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty"`
+	WorkDir string   `yaml:"workdir,omitempty"`
+	Shell   string   `yaml:"shell,omitempty"`
+}
+
+// ForPlatform returns the service as it runs on goos, with the settings of its
+// override for goos in place of its own. A service without one is returned unchanged.
+func (s *Service) ForPlatform(goos string) *Service {
+	override := s.Platforms[goos]
+	if override == nil {
+		return s
+	}
+
+	resolved := *s
+	if override.Command != "" {
+		resolved.Command = override.Command
+		// Arguments belong to the command they were written for
+		resolved.Args = override.Args
+	} else if len(override.Args) > 0 {
+		resolved.Args = override.Args
+	}
+	if override.WorkDir != "" {
+		resolved.WorkDir = override.WorkDir
+	}
+	if override.Shell != "" {
+		resolved.Shell = override.Shell
+	}
+	return &resolved
+}
+
+// OverriddenPlatforms returns the platforms the service has overrides for, sorted.
+func (s *Service) OverriddenPlatforms() []string {
+	platforms := make([]string, 0, len(s.Platforms))
+	for goos := range s.Platforms {
+		platforms = append(platforms, goos)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// validatePlatforms checks the platform names of the overrides.
+func (s *Service) validatePlatforms() error {
+	for goos, override := range s.Platforms {
+		if !platformNames[goos] {
+			return fmt.Errorf("invalid platform %q in platforms, use windows, linux, or darwin", goos)
+		}
+		if override == nil {
+			return fmt.Errorf("platforms.%s is empty", goos)
+		}
+	}
+	return nil
+}
+
+// CheckPlatform returns an error if the service was set up on another platform
+// than goos and has no override for goos, since its command, paths, and shell
+// are then unlikely to work there. Services that don't record a platform pass.
+func (s *Service) CheckPlatform(goos string) error {
+	if s.Platform == "" || s.Platform == goos || s.Platforms[goos] != nil {
+		return nil
+	}
+	return fmt.Errorf("service '%s' was set up on %s and has no platforms.%s override, so it is unlikely to work on %s",
+		s.Name, s.Platform, goos, goos)
+}
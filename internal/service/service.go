@@ -3,9 +3,19 @@ package service
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/calilkhalil/nazim/internal/lock"
+	"github.com/calilkhalil/nazim/internal/schedule"
+	"github.com/calilkhalil/nazim/internal/watch"
 	"gopkg.in/yaml.v3"
 )
 
@@ -89,17 +99,131 @@ func parseDuration(s string) (time.Duration, error) {
 
 // Service represents a service managed by Nazim.
 type Service struct {
-	Name      string   `yaml:"name"`
-	Command   string   `yaml:"command"`
-	Args      []string `yaml:"args,omitempty"`
-	WorkDir   string   `yaml:"workdir,omitempty"`
-	OnStartup bool     `yaml:"on_startup,omitempty"` // Runs at system boot (as SYSTEM)
-	OnLogon   bool     `yaml:"on_logon,omitempty"`   // Runs at user logon (as current user)
-	Interval  Duration `yaml:"interval,omitempty"`
-	Enabled   bool     `yaml:"enabled"`
-	Platform  string   `yaml:"platform,omitempty"` // windows, linux, darwin
+	Name      string            `yaml:"name"`
+	Command   string            `yaml:"command,omitempty"`
+	Args      []string          `yaml:"args,omitempty"`
+	WorkDir   string            `yaml:"workdir,omitempty"`
+	Env       map[string]string `yaml:"env,omitempty"`        // Added to the environment the command runs in
+	Shell     string            `yaml:"shell,omitempty"`      // Shell that runs one-liners (default: sh, or cmd on Windows); a PowerShell shell also runs .ps1 scripts
+	OnStartup bool              `yaml:"on_startup,omitempty"` // Runs at system boot (as SYSTEM)
+	OnLogon   bool              `yaml:"on_logon,omitempty"`   // Runs at user logon (as current user)
+	Interval  Duration          `yaml:"interval,omitempty"`
+	Offset    string            `yaml:"offset,omitempty"`   // Aligns the interval to the clock: runs start this long past midnight, e.g. "15m"
+	Schedule  string            `yaml:"schedule,omitempty"` // Calendar schedule, e.g. "daily 03:30"
+	Timezone  string            `yaml:"timezone,omitempty"` // Time zone of the schedule, e.g. "Europe/Lisbon"; empty is the local one
+	Enabled   bool              `yaml:"enabled"`
+	Platform  string            `yaml:"platform,omitempty"` // windows, linux, darwin
+	Target    string            `yaml:"target,omitempty"`   // Where it is scheduled when not by this system's scheduler: windows-host, from inside WSL
+
+	WatchPaths []string `yaml:"watch_paths,omitempty"` // Files or directories whose changes start a run
+	OnEvent    string   `yaml:"on_event,omitempty"`    // Windows event log event that starts a run: Log=<log>;Source=<source>;ID=<id>
+
+	LongRunning bool `yaml:"long_running,omitempty"` // Runs continuously as a Windows service, restarted when it fails
+	System      bool `yaml:"system,omitempty"`       // Installs as a launch daemon, running as root from boot (macOS only)
+
+	Units []string `yaml:"units,omitempty"` // Hand-edited scheduler definitions installed instead of generated ones, as written by nazim export-unit
+
+	Steps         []string `yaml:"steps,omitempty"`           // Services a pipeline runs one after another, instead of a command
+	OnStepFailure string   `yaml:"on_step_failure,omitempty"` // What a pipeline does when a step fails: abort (the default) or continue
+	ParallelGroup []string `yaml:"parallel_group,omitempty"`  // Services run all at once, instead of a command
+	MaxParallel   int      `yaml:"max_parallel,omitempty"`    // How many of a parallel group run at a time; 0 runs all at once
+
+	Template string            `yaml:"template,omitempty"` // The template `nazim generate` made the service from
+	Matrix   map[string]string `yaml:"matrix,omitempty"`   // The values it was made with
+
+	StartupDelay Duration `yaml:"startup_delay,omitempty"` // Delay before on_startup/on_logon runs
+	Priority     string   `yaml:"priority,omitempty"`      // high, normal, low
+	CatchUp      bool     `yaml:"catch_up,omitempty"`      // Run missed scheduled runs once the machine is back
+	Portable     bool     `yaml:"portable,omitempty"`      // Relative command/workdir paths are relative to the config dir
+	Elevated     bool     `yaml:"elevated,omitempty"`      // Run with highest privileges (Windows only)
+	Umask        string   `yaml:"umask,omitempty"`         // File mode creation mask in octal, e.g. 0027 (Linux and macOS only)
+	Sandbox      string   `yaml:"sandbox,omitempty"`       // systemd hardening the unit gets: basic or strict (Linux only)
+	Network      string   `yaml:"network,omitempty"`       // none cuts the command off from the network (Linux and Windows)
+
+	NotifyDesktop bool     `yaml:"notify_desktop,omitempty"`        // Show a desktop notification when a run finishes
+	SingletonLock string   `yaml:"singleton_lock,omitempty"`        // Lock backend URL; runs are skipped while another host holds the lock
+	IfChanged     []string `yaml:"if_changed,omitempty"`            // Paths or globs; runs are skipped unless they changed since the last successful run
+	Blackout      []string `yaml:"blackout,omitempty"`              // Windows runs are skipped in, see schedule.Blackout
+	SlowThreshold Duration `yaml:"slow_threshold,omitempty"`        // Runs taking longer are marked slow
+	DailyBudget   Duration `yaml:"daily_budget,omitempty"`          // Total runtime allowed per day; runs past it are stopped, and later ones skipped
+	Timeout       Duration `yaml:"timeout,omitempty"`               // Runs taking longer are stopped and fail
+	MaxLogLines   int      `yaml:"max_log_lines_per_run,omitempty"` // Lines logged per run and stream; the middle of longer output is dropped
+	LogTimestamps string   `yaml:"log_timestamps,omitempty"`        // How log lines are timestamped: local, utc, both, or rfc3339
+	LogLevel      string   `yaml:"log_level,omitempty"`             // Which runs are logged: all (the default), errors-only, or off
+	OnFailure     string   `yaml:"on_failure,omitempty"`            // run:<service> or disable-self-after:<n>
+	DebugEnv      bool     `yaml:"debug_env,omitempty"`             // Record the user, working dir, and key environment variables of each run
+
+	FailPattern    string `yaml:"fail_pattern,omitempty"`    // Regular expression; a run with an output line matching it fails, even with exit code 0
+	SuccessPattern string `yaml:"success_pattern,omitempty"` // Regular expression; a run with no output line matching it fails, even with exit code 0
+
+	MaxConsecutiveFailures int `yaml:"max_consecutive_failures,omitempty"` // Failed runs in a row after which runs are paused, for longer with each further failure
+
+	Description string            `yaml:"description,omitempty"` // What the service does, shown in status and scheduler definitions
+	Owner       string            `yaml:"owner,omitempty"`       // Who to ask about the service
+	Labels      map[string]string `yaml:"labels,omitempty"`      // Free-form key=value metadata
+
+	Platforms map[string]*PlatformOverride `yaml:"platforms,omitempty"` // Settings used instead on windows, linux, or darwin
 }
 
+// Shells with their own handling. Any other shell runs one-liners with -c.
+const (
+	ShellCmd        = "cmd"
+	ShellPowerShell = "powershell" // Windows PowerShell 5.1
+	ShellPwsh       = "pwsh"       // PowerShell 7 and later, also on Linux and macOS
+)
+
+// catchUpSlack absorbs scheduler jitter when deciding whether a run was missed.
+const catchUpSlack = time.Minute
+
+// Priority classes. Lower classes start later at boot/logon and run with lower scheduling priority.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// priorityOffsets staggers startup services by priority class so boot isn't slammed.
+var priorityOffsets = map[string]time.Duration{
+	PriorityHigh:   0,
+	PriorityNormal: 15 * time.Second,
+	PriorityLow:    time.Minute,
+}
+
+// Sandbox levels, the systemd hardening a service's unit gets on Linux. Both
+// keep the command from gaining privileges, give it a /tmp of its own, and
+// make home directories read-only, except for nazim's state directory. basic
+// also makes system directories such as /usr and /etc read-only, and leaves
+// the service's working directory writable; strict makes the whole file
+// system read-only and hides devices and kernel settings.
+const (
+	SandboxBasic  = "basic"
+	SandboxStrict = "strict"
+)
+
+// SandboxLevels lists the valid values of sandbox.
+var SandboxLevels = []string{SandboxBasic, SandboxStrict}
+
+// Log timestamp formats. Every format is read back by `nazim logs`.
+const (
+	LogTimestampsLocal   = "local"   // Local time with its UTC offset, to the millisecond (the default)
+	LogTimestampsUTC     = "utc"     // UTC, to the millisecond
+	LogTimestampsBoth    = "both"    // The local stamp followed by the UTC one
+	LogTimestampsRFC3339 = "rfc3339" // RFC 3339 in UTC to the second, as log pipelines expect
+)
+
+// LogTimestampFormats lists the valid values of log_timestamps.
+var LogTimestampFormats = []string{LogTimestampsLocal, LogTimestampsUTC, LogTimestampsBoth, LogTimestampsRFC3339}
+
+// Log levels, which decide whether the output of a run is logged.
+const (
+	LogLevelAll        = "all"         // Every run (the default)
+	LogLevelErrorsOnly = "errors-only" // Only failed runs
+	LogLevelOff        = "off"         // No run
+)
+
+// LogLevels lists the valid values of log_level.
+var LogLevels = []string{LogLevelAll, LogLevelErrorsOnly, LogLevelOff}
+
 // Validate validates if the service is configured correctly.
 func (s *Service) Validate() error {
 	if s.Name == "" {
@@ -111,11 +235,29 @@ func (s *Service) Validate() error {
 		return fmt.Errorf("invalid service name: %w", err)
 	}
 
-	if s.Command == "" {
+	if err := s.validatePlatforms(); err != nil {
+		return err
+	}
+	// Validate the service as it runs here, with this platform's overrides
+	effective := s.ForPlatform(runtime.GOOS)
+
+	if effective.Command == "" && !s.RunsServices() {
 		return fmt.Errorf("service command is required")
 	}
-	if !s.OnStartup && !s.OnLogon && s.Interval.Duration == 0 {
-		return fmt.Errorf("service must have either on_startup=true, on_logon=true, or an interval")
+	if !s.HasOtherTrigger() && len(s.WatchPaths) == 0 {
+		return fmt.Errorf("service must have either on_startup=true, on_logon=true, an interval, a schedule, watch_paths, on_event, or long_running")
+	}
+
+	if s.Schedule != "" {
+		if _, err := schedule.Parse(s.Schedule); err != nil {
+			return err
+		}
+		if s.Interval.Duration > 0 {
+			return fmt.Errorf("schedule cannot be combined with an interval")
+		}
+		if s.OnStartup || s.OnLogon {
+			return fmt.Errorf("schedule cannot be combined with on_startup or on_logon")
+		}
 	}
 
 	// Validate interval is not negative
@@ -123,14 +265,181 @@ func (s *Service) Validate() error {
 		return fmt.Errorf("interval cannot be negative")
 	}
 
-	// Validate minimum interval (Windows Task Scheduler requires at least 1 minute)
-	if s.Interval.Duration > 0 && s.Interval.Duration < time.Minute {
-		return fmt.Errorf("interval must be at least 1 minute, got %s", s.Interval.Duration)
+	if err := s.validateOffset(); err != nil {
+		return err
+	}
+	if err := s.validateTarget(); err != nil {
+		return err
+	}
+	if err := s.validateWatchPaths(); err != nil {
+		return err
+	}
+	if err := s.validateOnEvent(); err != nil {
+		return err
+	}
+	if err := s.validateLongRunning(); err != nil {
+		return err
+	}
+	if err := s.validateSystem(); err != nil {
+		return err
+	}
+	if err := s.validateTimezone(); err != nil {
+		return err
+	}
+	if err := s.validateUnits(); err != nil {
+		return err
+	}
+	if _, err := ParseBlackouts(s.Blackout); err != nil {
+		return err
+	}
+	if err := s.validateNetwork(); err != nil {
+		return err
+	}
+	if err := s.validatePipeline(); err != nil {
+		return err
+	}
+
+	if s.StartupDelay.Duration > 0 && !s.OnStartup && !s.OnLogon {
+		return fmt.Errorf("startup_delay requires on_startup or on_logon")
+	}
+
+	if s.CatchUp && !s.RequiresScheduling() {
+		return fmt.Errorf("catch_up requires an interval or a schedule")
+	}
+
+	if s.SlowThreshold.Duration < 0 {
+		return fmt.Errorf("slow_threshold cannot be negative")
+	}
+
+	if s.DailyBudget.Duration < 0 {
+		return fmt.Errorf("daily_budget cannot be negative")
+	}
+	if s.DailyBudget.Duration > 0 && s.LongRunning {
+		return fmt.Errorf("daily_budget doesn't apply to long_running services, which run until stopped")
+	}
+
+	if s.Timeout.Duration < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	if s.Timeout.Duration > 0 && s.LongRunning {
+		return fmt.Errorf("timeout doesn't apply to long_running services, which run until stopped")
+	}
+
+	if s.MaxLogLines < 0 {
+		return fmt.Errorf("max_log_lines_per_run cannot be negative")
+	}
+
+	if s.MaxConsecutiveFailures < 0 {
+		return fmt.Errorf("max_consecutive_failures cannot be negative")
+	}
+
+	if s.Sandbox != "" && !slices.Contains(SandboxLevels, s.Sandbox) {
+		return fmt.Errorf("invalid sandbox %q, use basic or strict", s.Sandbox)
+	}
+	if s.LogTimestamps != "" && !slices.Contains(LogTimestampFormats, s.LogTimestamps) {
+		return fmt.Errorf("invalid log_timestamps %q, use local, utc, both, or rfc3339", s.LogTimestamps)
+	}
+	if s.LogLevel != "" && !slices.Contains(LogLevels, s.LogLevel) {
+		return fmt.Errorf("invalid log_level %q, use all, errors-only, or off", s.LogLevel)
+	}
+
+	for _, pattern := range s.IfChanged {
+		if err := watch.CheckPattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	if s.FailPattern != "" {
+		if _, err := regexp.Compile(s.FailPattern); err != nil {
+			return fmt.Errorf("invalid fail_pattern: %w", err)
+		}
+	}
+	if s.SuccessPattern != "" {
+		if _, err := regexp.Compile(s.SuccessPattern); err != nil {
+			return fmt.Errorf("invalid success_pattern: %w", err)
+		}
+	}
+
+	if s.Umask != "" {
+		if _, err := ParseUmask(s.Umask); err != nil {
+			return err
+		}
+	}
+
+	if s.OnFailure != "" {
+		policy, err := ParseFailurePolicy(s.OnFailure)
+		if err != nil {
+			return err
+		}
+		if policy.Run == s.Name {
+			return fmt.Errorf("on_failure cannot run the failing service itself")
+		}
+	}
+
+	if s.SingletonLock != "" {
+		if err := lock.CheckURL(s.SingletonLock); err != nil {
+			return fmt.Errorf("invalid singleton_lock: %w", err)
+		}
+	}
+
+	if strings.ContainsAny(s.Description, "\r\n") {
+		return fmt.Errorf("description must be a single line")
+	}
+	if strings.ContainsAny(s.Owner, "\r\n") {
+		return fmt.Errorf("owner must be a single line")
+	}
+	for key, value := range s.Labels {
+		if key == "" || strings.ContainsAny(key, "=, \t\r\n") {
+			return fmt.Errorf("invalid label name %q", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("label %q must be a single line", key)
+		}
+	}
+
+	for key := range s.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return fmt.Errorf("invalid environment variable name %q", key)
+		}
+	}
+
+	if shell := effective.Shell; shell != "" && runtime.GOOS == "windows" && !strings.EqualFold(shell, ShellCmd) && !IsPowerShell(shell) {
+		return fmt.Errorf("shell %q is not supported on Windows, use cmd, powershell, or pwsh", shell)
+	}
+
+	if _, ok := priorityOffsets[s.Priority]; s.Priority != "" && !ok {
+		return fmt.Errorf("invalid priority %q, use high, normal, or low", s.Priority)
+	}
+
+	// Task Scheduler can't repeat more often than every minute, so on Windows
+	// shorter intervals run repeatedly within each minute, which needs them to fit evenly
+	if s.Interval.Duration > 0 && s.Interval.Duration < time.Minute && time.Minute%s.Interval.Duration != 0 {
+		return fmt.Errorf("an interval under a minute must divide it evenly (such as 10s, 15s, or 30s), got %s", s.Interval.Duration)
+	}
+	if err := s.validateTaskInterval(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// validateTaskInterval checks that Task Scheduler, which schedules the
+// service on Windows and for target windows-host, can repeat it at its
+// interval: in whole minutes, and from a day up in whole days.
+func (s *Service) validateTaskInterval() error {
+	interval := s.Interval.Duration
+	if (runtime.GOOS != "windows" && !s.OnWindowsHost()) || interval < time.Minute {
+		return nil
+	}
+	if interval%time.Minute != 0 {
+		return fmt.Errorf("Task Scheduler repeats tasks in whole minutes, so an interval of %s can't be kept; use one such as 1m or 2m", interval)
+	}
+	if interval >= 24*time.Hour && interval%(24*time.Hour) != 0 {
+		return fmt.Errorf("Task Scheduler repeats tasks of a day or more in whole days, so an interval of %s can't be kept; use one such as 24h or 48h", interval)
+	}
+	return nil
+}
+
 // validateServiceName checks if a service name contains invalid characters.
 func validateServiceName(name string) error {
 	// Length check (filesystem compatibility and platform limits)
@@ -186,9 +495,143 @@ func validateServiceName(name string) error {
 	return nil
 }
 
+// LabelPairs returns the labels of a service as key=value pairs sorted by key.
+func (s *Service) LabelPairs() []string {
+	keys := make([]string, 0, len(s.Labels))
+	for key := range s.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + s.Labels[key]
+	}
+	return pairs
+}
+
 // RequiresScheduling returns true if the service needs scheduling.
 func (s *Service) RequiresScheduling() bool {
-	return s.Interval.Duration > 0
+	return s.Interval.Duration > 0 || s.Schedule != ""
+}
+
+// RunsPerMinute returns how many times a minute a service with an interval
+// under a minute runs, and 1 for any other service.
+func (s *Service) RunsPerMinute() int {
+	if interval := s.Interval.Duration; interval > 0 && interval < time.Minute {
+		return int(time.Minute / interval)
+	}
+	return 1
+}
+
+// GetSchedule returns the parsed calendar schedule, or nil if none is set.
+func (s *Service) GetSchedule() *schedule.Schedule {
+	if s.Schedule == "" {
+		return nil
+	}
+	sched, err := schedule.Parse(s.Schedule)
+	if err != nil {
+		return nil
+	}
+	sched.Location = s.Location()
+	return sched
+}
+
+// MissedRun reports whether a scheduled run fell due between lastRun and now.
+// A service that never ran has always missed its run.
+func (s *Service) MissedRun(lastRun, now time.Time) bool {
+	if lastRun.IsZero() {
+		return true
+	}
+	if sched := s.GetSchedule(); sched != nil {
+		// Schedules are wall-clock times in their time zone, the local one by default
+		return !sched.Next(lastRun.In(now.Location())).After(now.Add(catchUpSlack))
+	}
+	if interval := s.GetInterval(); interval > 0 {
+		return !lastRun.Add(interval).After(now.Add(catchUpSlack))
+	}
+	return false
+}
+
+// IsOneLiner reports whether the command is a shell one-liner such as
+// "rm -rf /tmp/old_files" rather than a script or binary.
+func (s *Service) IsOneLiner() bool {
+	if len(s.Args) > 0 || !strings.ContainsAny(s.Command, " \t") {
+		return false
+	}
+	_, err := os.Stat(s.Command)
+	return err != nil
+}
+
+// IsPowerShell reports whether shell names a PowerShell interpreter, by name or path.
+func IsPowerShell(shell string) bool {
+	base := strings.ToLower(filepath.Base(strings.ReplaceAll(shell, `\`, "/")))
+	base = strings.TrimSuffix(base, ".exe")
+	return base == ShellPowerShell || base == ShellPwsh
+}
+
+// UsesPowerShell reports whether the service runs through PowerShell: either
+// its command is a .ps1 script, or it is a one-liner and its shell is PowerShell.
+func (s *Service) UsesPowerShell() bool {
+	if s.IsOneLiner() {
+		return IsPowerShell(s.Shell)
+	}
+	return strings.EqualFold(filepath.Ext(s.Command), ".ps1")
+}
+
+// CommandIsPath reports whether the command names a file by path, as opposed
+// to a bare name looked up in PATH or a one-liner.
+func (s *Service) CommandIsPath() bool {
+	return strings.ContainsAny(s.Command, `/\`) && !s.IsOneLiner()
+}
+
+// ResolvePaths returns the service as it runs on this machine: with the
+// overrides for this platform applied, and for a portable service, relative
+// paths made absolute against configDir.
+func (s *Service) ResolvePaths(configDir string) *Service {
+	s = s.ForPlatform(runtime.GOOS)
+	if !s.Portable {
+		return s
+	}
+
+	resolved := *s
+	if s.WorkDir != "" && !filepath.IsAbs(s.WorkDir) {
+		resolved.WorkDir = filepath.Join(configDir, s.WorkDir)
+	}
+	resolved.IfChanged = resolveAll(s.IfChanged, configDir)
+	resolved.WatchPaths = resolveAll(s.WatchPaths, configDir)
+	if !filepath.IsAbs(s.Command) && strings.ContainsAny(s.Command, `/\`) {
+		candidate := filepath.Join(configDir, s.Command)
+		// A one-liner such as "scripts/run.sh --all" stays as written
+		if _, err := os.Stat(candidate); err == nil || !s.IsOneLiner() {
+			resolved.Command = candidate
+		}
+	}
+	return &resolved
+}
+
+// resolveAll returns paths with the relative ones made absolute against dir.
+func resolveAll(paths []string, dir string) []string {
+	if len(paths) == 0 {
+		return paths
+	}
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		resolved[i] = path
+	}
+	return resolved
+}
+
+// Environ returns the service's environment variables as sorted KEY=value pairs.
+func (s *Service) Environ() []string {
+	env := make([]string, 0, len(s.Env))
+	for key, value := range s.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
 }
 
 // GetInterval returns the interval as time.Duration.
@@ -196,6 +639,51 @@ func (s *Service) GetInterval() time.Duration {
 	return s.Interval.Duration
 }
 
+// EffectiveStartupDelay returns the delay before an on_startup/on_logon run,
+// including the stagger offset of the service's priority class.
+func (s *Service) EffectiveStartupDelay() time.Duration {
+	if !s.OnStartup && !s.OnLogon {
+		return 0
+	}
+	priority := s.Priority
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	return s.StartupDelay.Duration + priorityOffsets[priority]
+}
+
+// LogLineLimits splits MaxLogLines into the number of lines logged from the start
+// and from the end of a run's output. Both are zero when output isn't capped.
+func (s *Service) LogLineLimits() (head, tail int) {
+	if s.MaxLogLines <= 0 {
+		return 0, 0
+	}
+	head = (s.MaxLogLines + 1) / 2
+	return head, s.MaxLogLines - head
+}
+
+// ParseUmask parses a file mode creation mask written in octal, such as 022 or 0027.
+func ParseUmask(s string) (os.FileMode, error) {
+	mask, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || mask > 0777 {
+		return 0, fmt.Errorf("invalid umask %q, use an octal mask such as 022 or 0027", s)
+	}
+	return os.FileMode(mask), nil
+}
+
+// FormatUmask formats a file mode creation mask the way umask(1) prints it, as in 0027.
+func FormatUmask(mask os.FileMode) string {
+	return fmt.Sprintf("%04o", uint32(mask.Perm()))
+}
+
+// RepeatsAfterStartup reports whether the service runs at boot or logon and
+// then at its interval. Every backend runs such a service once its startup
+// delay has passed after boot or logon, then every interval after that run;
+// installing it starts the cycle right away instead of at the next boot.
+func (s *Service) RepeatsAfterStartup() bool {
+	return (s.OnStartup || s.OnLogon) && s.Interval.Duration > 0
+}
+
 // RequiresStartup returns true if the service should run on startup.
 func (s *Service) RequiresStartup() bool {
 	return s.OnStartup
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTaskInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		wantErr  bool
+	}{
+		{30 * time.Second, false}, // Repeated within each minute
+		{time.Minute, false},
+		{90 * time.Second, true},
+		{2 * time.Hour, false},
+		{1439 * time.Minute, false},
+		{24 * time.Hour, false},
+		{36 * time.Hour, true},
+		{48 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.interval.String(), func(t *testing.T) {
+			svc := &Service{Interval: Duration{Duration: tt.interval}, Target: TargetWindowsHost}
+			if err := svc.validateTaskInterval(); (err != nil) != tt.wantErr {
+				t.Errorf("validateTaskInterval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEffectiveStartupDelay(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  Service
+		want time.Duration
+	}{
+		{"interval only", Service{Interval: Duration{Duration: time.Hour}}, 0},
+		{"high", Service{OnStartup: true, Priority: PriorityHigh}, 0},
+		{"normal", Service{OnStartup: true, Priority: PriorityNormal}, 15 * time.Second},
+		{"no priority", Service{OnStartup: true}, 15 * time.Second},
+		{"low, delayed", Service{OnLogon: true, Priority: PriorityLow, StartupDelay: Duration{Duration: time.Minute}}, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.svc.EffectiveStartupDelay(); got != tt.want {
+				t.Errorf("EffectiveStartupDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// validateSystem checks a system service, which macOS runs as a launch daemon:
+// as root, from boot, outside any login session.
+func (s *Service) validateSystem() error {
+	if !s.System {
+		return nil
+	}
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("system installs the service as a macOS launch daemon and is not supported on %s; use on_startup instead", runtime.GOOS)
+	}
+	if s.OnLogon {
+		return fmt.Errorf("system services run outside any login session, so they can't have on_logon; use on_startup")
+	}
+	if s.NotifyDesktop {
+		return fmt.Errorf("system services run outside any login session, where desktop notifications can't be shown")
+	}
+	return nil
+}
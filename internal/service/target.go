@@ -0,0 +1,40 @@
+package service
+
+import "fmt"
+
+// TargetWindowsHost schedules a service defined inside WSL in the Task
+// Scheduler of the Windows host, whose task starts the distribution through
+// wsl.exe to run it. WSL shuts down once no program uses it, and systemd's
+// timers stop with it, while the Windows task fires whether WSL is running or not.
+const TargetWindowsHost = "windows-host"
+
+// OnWindowsHost reports whether the service is scheduled on the Windows host of WSL.
+func (s *Service) OnWindowsHost() bool {
+	return s.Target == TargetWindowsHost
+}
+
+// validateTarget checks the target against the rest of the service. The task
+// runs as the Windows user, whose distributions only exist for them, so it
+// can't start at boot, and the triggers schtasks.exe can't set are refused
+// rather than dropped.
+func (s *Service) validateTarget() error {
+	switch s.Target {
+	case "":
+		return nil
+	case TargetWindowsHost:
+	default:
+		return fmt.Errorf("invalid target %q, use %s", s.Target, TargetWindowsHost)
+	}
+
+	switch {
+	case s.OnStartup:
+		return fmt.Errorf("target %s runs as your Windows user and can't start at boot; use on_logon instead", TargetWindowsHost)
+	case s.OnLogon && (s.Interval.Duration > 0 || s.Schedule != ""):
+		return fmt.Errorf("target %s can't combine on_logon with an interval or a schedule", TargetWindowsHost)
+	case s.CatchUp:
+		return fmt.Errorf("catch_up is not supported with target %s", TargetWindowsHost)
+	case s.Elevated:
+		return fmt.Errorf("elevated is not supported with target %s", TargetWindowsHost)
+	}
+	return nil
+}
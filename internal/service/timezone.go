@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	// Windows has no time zone database of its own
+	_ "time/tzdata"
+)
+
+// validateTimezone checks the time zone of a schedule, an IANA name such as
+// "Europe/Lisbon".
+func (s *Service) validateTimezone() error {
+	if s.Timezone == "" {
+		return nil
+	}
+	if s.Schedule == "" {
+		return fmt.Errorf("timezone only applies to a schedule")
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "Local" {
+		return fmt.Errorf("invalid timezone %q, use a name such as Europe/Lisbon or UTC", s.Timezone)
+	}
+	return nil
+}
+
+// Location returns the time zone of the schedule, or nil for the local one.
+func (s *Service) Location() *time.Location {
+	if s.Timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
@@ -0,0 +1,24 @@
+package service
+
+import "fmt"
+
+// validateUnits checks that the scheduler of a service takes hand-edited
+// definitions. Whether the files fit the service is checked when installing
+// it, by the scheduler's manager.
+func (s *Service) validateUnits() error {
+	if len(s.Units) == 0 {
+		return nil
+	}
+	if s.LongRunning {
+		return fmt.Errorf("units don't apply to long_running services, which the service control manager registers from nazim's settings")
+	}
+	if s.OnWindowsHost() {
+		return fmt.Errorf("units aren't supported with target %s", TargetWindowsHost)
+	}
+	for _, path := range s.Units {
+		if path == "" {
+			return fmt.Errorf("units can't have an empty path")
+		}
+	}
+	return nil
+}
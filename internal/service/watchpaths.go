@@ -0,0 +1,40 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// HasOtherTrigger reports whether the service has a trigger besides its
+// watch_paths: boot, logon, an interval, a schedule, an event, or running
+// continuously.
+func (s *Service) HasOtherTrigger() bool {
+	return s.OnStartup || s.OnLogon || s.Interval.Duration > 0 || s.Schedule != "" || s.OnEvent != "" || s.LongRunning
+}
+
+// PollsWatchPaths reports whether the watch_paths of the service are polled
+// rather than watched. Task Scheduler has no file trigger, so on Windows, and
+// for target windows-host, a task starts `nazim exec --poll` every minute and
+// the run only happens if a watched path changed since the last poll.
+func (s *Service) PollsWatchPaths(goos string) bool {
+	return len(s.WatchPaths) > 0 && (goos == "windows" || s.OnWindowsHost())
+}
+
+// validateWatchPaths checks the watch_paths of the service. File watchers take
+// paths, not patterns, and a polled service's task can't also carry the
+// triggers of the rest of the service.
+func (s *Service) validateWatchPaths() error {
+	for _, path := range s.WatchPaths {
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("watch_paths cannot be empty")
+		}
+		if strings.ContainsAny(path, "*?[") {
+			return fmt.Errorf("watch_paths takes files or directories, not patterns such as %q; watch the directory instead", path)
+		}
+	}
+	if s.PollsWatchPaths(runtime.GOOS) && s.HasOtherTrigger() {
+		return fmt.Errorf("watch_paths are polled by a task of their own on Windows and can't be combined with other triggers; add a second service for those")
+	}
+	return nil
+}
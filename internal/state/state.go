@@ -0,0 +1,147 @@
+// Package state keeps what nazim learns about each service while it runs,
+// apart from the config that describes it: how the last run went, how many
+// runs in a row failed, until when the service is paused, and when and how it
+// was installed or adopted.
+//
+// The config is written by the user and by commands such as add and edit, and
+// may be kept under version control; state changes after every run, so it
+// lives in the state directory instead, one JSON file per service, and runs of
+// different services never contend for the same file. Missing state is the
+// zero State: a service that never ran, isn't paused, and wasn't adopted.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/atomicfile"
+	"github.com/calilkhalil/nazim/internal/lock"
+)
+
+// State is the runtime state of a service.
+type State struct {
+	LastRun       time.Time `json:"last_run,omitzero"`        // When the last run finished
+	LastRunID     string    `json:"last_run_id,omitempty"`    // Its ID in the run history
+	LastExitCode  int       `json:"last_exit_code"`           // Its exit code
+	LastSuccess   time.Time `json:"last_success,omitzero"`    // When the last successful run finished
+	FailureStreak int       `json:"failure_streak,omitempty"` // Failed runs in a row, up to the last one
+	LastSkipped   time.Time `json:"last_skipped,omitzero"`    // When a run was last skipped, for a blackout window or the daily budget
+
+	RuntimeDay   string  `json:"runtime_day,omitempty"`           // The local day RuntimeToday is for, as YYYY-MM-DD
+	RuntimeToday float64 `json:"runtime_today_seconds,omitempty"` // Total runtime of the runs that finished that day
+
+	PausedUntil time.Time `json:"paused_until,omitzero"` // Runs are skipped until then
+	InstalledAt time.Time `json:"installed_at,omitzero"` // When nazim last installed the service in the scheduler
+	AdoptedAt   time.Time `json:"adopted_at,omitzero"`   // When `nazim adopt` added the service back to the config
+
+	// Fallback is how the service was installed when the usual way wasn't
+	// available, such as platform.FallbackUserTask, or "" if it was installed
+	// the usual way.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// Path returns the state file of a service.
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+".json")
+}
+
+// Load returns the state of a service, the zero State if it has none yet.
+func Load(dir, name string) (*State, error) {
+	s := &State{}
+	data, err := os.ReadFile(Path(dir, name))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("reading state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return &State{}, fmt.Errorf("parsing state %s: %w", Path(dir, name), err)
+	}
+	return s, nil
+}
+
+// Save writes the state of a service, replacing the file in one step.
+func (s *State) Save(dir, name string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	if err := atomicfile.Write(Path(dir, name), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
+
+// lockWait is how long Update waits for another update of the same state.
+const lockWait = 10 * time.Second
+
+// Update loads the state of a service, applies change, and saves it, holding
+// the service's lock file in dir so concurrent updates don't lose each other's
+// changes. The changed state is returned even if it couldn't be loaded or
+// saved, starting from the zero State in the first case, so callers can act
+// on it regardless.
+func Update(dir, name string, change func(*State)) (*State, error) {
+	held, err := lock.AcquireFile(context.Background(), dir, name, lockWait)
+	if err != nil {
+		s, _ := Load(dir, name)
+		change(s)
+		return s, fmt.Errorf("locking state: %w", err)
+	}
+	defer held.Release(context.Background())
+
+	s, loadErr := Load(dir, name)
+	change(s)
+	if err := s.Save(dir, name); err != nil {
+		return s, err
+	}
+	return s, loadErr
+}
+
+// Remove deletes the state of a service, if it has any.
+func Remove(dir, name string) error {
+	if err := os.Remove(Path(dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing state: %w", err)
+	}
+	return nil
+}
+
+// RecordRun records a finished run.
+func (s *State) RecordRun(id string, end time.Time, exitCode int, failed bool) {
+	s.LastRun = end
+	s.LastRunID = id
+	s.LastExitCode = exitCode
+	if failed {
+		s.FailureStreak++
+	} else {
+		s.FailureStreak = 0
+		s.LastSuccess = end
+	}
+}
+
+// Runtime returns the total runtime of the runs that finished on the local
+// day of now.
+func (s *State) Runtime(now time.Time) time.Duration {
+	if s.RuntimeDay != now.Local().Format(time.DateOnly) {
+		return 0
+	}
+	return time.Duration(s.RuntimeToday * float64(time.Second))
+}
+
+// AddRuntime adds the runtime of a run that finished at end to that of its day.
+func (s *State) AddRuntime(end time.Time, runtime time.Duration) {
+	s.RuntimeToday = (s.Runtime(end) + runtime).Seconds()
+	s.RuntimeDay = end.Local().Format(time.DateOnly)
+}
+
+// Paused reports whether runs are skipped at now.
+func (s *State) Paused(now time.Time) bool {
+	return now.Before(s.PausedUntil)
+}
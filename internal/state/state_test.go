@@ -0,0 +1,45 @@
+package state
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestUpdateConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	const runs = 20
+	var wg sync.WaitGroup
+	for range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Update(dir, "job", func(s *State) { s.FailureStreak++ }); err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s, err := Load(dir, "job")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.FailureStreak != runs {
+		t.Errorf("FailureStreak = %d, want %d", s.FailureStreak, runs)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("state directory holds %d files, want only the state file", len(entries))
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	s, err := Load(t.TempDir(), "job")
+	if err != nil || s.FailureStreak != 0 || !s.LastRun.IsZero() {
+		t.Errorf("Load() of a missing state = %+v, %v, want the zero State", s, err)
+	}
+}
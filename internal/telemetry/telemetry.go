@@ -0,0 +1,314 @@
+// Package telemetry reports runs to an OpenTelemetry collector, so scheduled
+// jobs show up in the observability stack that watches everything else.
+//
+// Each run is sent over OTLP/HTTP, as JSON, as a span from its start to its
+// end, carrying the service, the run ID, and the exit code, and as two
+// metrics: the nazim.runs counter and the nazim.run.duration histogram. Every
+// run is a process of its own, so metrics are sent with delta temporality: a
+// run adds 1 to the counter, and the collector or backend sums them up.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outcomes of a run, as the outcome attribute of the nazim.runs counter
+// records them.
+const (
+	OutcomeSuccess      = "success"
+	OutcomeFailure      = "failure"
+	OutcomeStartFailure = "start_failure" // The command couldn't be started
+)
+
+// durationBounds are the upper bounds, in seconds, of the buckets of the
+// nazim.run.duration histogram: from quick jobs to hour-long backups.
+var durationBounds = []float64{1, 5, 10, 30, 60, 300, 900, 1800, 3600}
+
+// OTLP span kinds and status codes.
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+// aggregationDelta is the OTLP delta aggregation temporality.
+const aggregationDelta = 1
+
+// Run is a finished run to report.
+type Run struct {
+	Service  string
+	ID       string
+	Command  string
+	Trigger  string // What starts the service, such as "every 1h"
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Outcome  string // One of the Outcome constants
+	Error    string // Why the run failed, if it did
+	Slow     bool   // The run took longer than the service's slow threshold
+
+	// Attributes describe the service, such as its owner and labels.
+	Attributes map[string]string
+}
+
+// Exporter sends runs to a collector.
+type Exporter struct {
+	endpoint *url.URL
+	headers  map[string]string
+	version  string
+}
+
+// CheckEndpoint checks that endpoint is the base URL of an OTLP/HTTP
+// collector, such as http://localhost:4318.
+func CheckEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid telemetry endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("telemetry endpoint %q must be an http or https URL, such as http://localhost:4318", endpoint)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("telemetry endpoint %q has no host", endpoint)
+	}
+	return nil
+}
+
+// New returns an exporter to the collector at endpoint, sending headers with
+// every request, such as the API key of a hosted backend. Spans and metrics
+// go to the endpoint's /v1/traces and /v1/metrics, as with the
+// OTEL_EXPORTER_OTLP_ENDPOINT of OpenTelemetry SDKs. version is nazim's own,
+// reported as the instrumentation scope's.
+func New(endpoint string, headers map[string]string, version string) (*Exporter, error) {
+	if err := CheckEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(endpoint)
+	return &Exporter{endpoint: u, headers: headers, version: version}, nil
+}
+
+// Export sends a run as a span and as metrics.
+func (e *Exporter) Export(ctx context.Context, run *Run) error {
+	resource := e.resource()
+	scope := scope{Name: "nazim", Version: e.version}
+
+	traces := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": resource,
+			"scopeSpans": []any{map[string]any{
+				"scope": scope,
+				"spans": []any{e.span(run)},
+			}},
+		}},
+	}
+	if err := e.post(ctx, "/v1/traces", traces); err != nil {
+		return fmt.Errorf("sending span: %w", err)
+	}
+
+	metrics := map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource": resource,
+			"scopeMetrics": []any{map[string]any{
+				"scope":   scope,
+				"metrics": metricsOf(run),
+			}},
+		}},
+	}
+	if err := e.post(ctx, "/v1/metrics", metrics); err != nil {
+		return fmt.Errorf("sending metrics: %w", err)
+	}
+	return nil
+}
+
+// scope is the OTLP instrumentation scope.
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// attribute is an OTLP key-value pair. Integers are strings in OTLP/JSON.
+type attribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func stringAttr(key, value string) attribute {
+	return attribute{Key: key, Value: map[string]any{"stringValue": value}}
+}
+
+func intAttr(key string, value int) attribute {
+	return attribute{Key: key, Value: map[string]any{"intValue": strconv.Itoa(value)}}
+}
+
+func boolAttr(key string, value bool) attribute {
+	return attribute{Key: key, Value: map[string]any{"boolValue": value}}
+}
+
+// resource describes the machine the runs happen on.
+func (e *Exporter) resource() map[string]any {
+	attrs := []attribute{
+		stringAttr("service.name", "nazim"),
+		stringAttr("os.type", runtime.GOOS),
+	}
+	if e.version != "" {
+		attrs = append(attrs, stringAttr("service.version", e.version))
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, stringAttr("host.name", host))
+	}
+	return map[string]any{"attributes": attrs}
+}
+
+// serviceAttrs returns the attributes identifying the service of a run, which
+// its span and its metrics share.
+func serviceAttrs(run *Run) []attribute {
+	attrs := []attribute{stringAttr("nazim.service", run.Service)}
+	keys := make([]string, 0, len(run.Attributes))
+	for key := range run.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attrs = append(attrs, stringAttr(key, run.Attributes[key]))
+	}
+	return attrs
+}
+
+// span returns the span of a run, under a trace of its own.
+func (e *Exporter) span(run *Run) map[string]any {
+	attrs := append(serviceAttrs(run),
+		stringAttr("nazim.run.id", run.ID),
+		stringAttr("process.command_line", run.Command),
+		intAttr("process.exit.code", run.ExitCode),
+		stringAttr("nazim.run.outcome", run.Outcome),
+	)
+	if run.Trigger != "" {
+		attrs = append(attrs, stringAttr("nazim.service.trigger", run.Trigger))
+	}
+	if run.Slow {
+		attrs = append(attrs, boolAttr("nazim.run.slow", true))
+	}
+
+	status := map[string]any{"code": statusOK}
+	if run.Outcome != OutcomeSuccess {
+		status = map[string]any{"code": statusError, "message": run.Error}
+	}
+	return map[string]any{
+		"traceId":           randomID(16),
+		"spanId":            randomID(8),
+		"name":              "run " + run.Service,
+		"kind":              spanKindInternal,
+		"startTimeUnixNano": unixNano(run.Start),
+		"endTimeUnixNano":   unixNano(run.End),
+		"attributes":        attrs,
+		"status":            status,
+	}
+}
+
+// metricsOf returns the metrics of a run: 1 for the nazim.runs counter, and
+// its duration for the nazim.run.duration histogram.
+func metricsOf(run *Run) []any {
+	attrs := append(serviceAttrs(run), stringAttr("nazim.run.outcome", run.Outcome))
+	seconds := run.End.Sub(run.Start).Seconds()
+
+	buckets := make([]string, len(durationBounds)+1)
+	bucket := sort.SearchFloat64s(durationBounds, seconds)
+	for i := range buckets {
+		buckets[i] = "0"
+	}
+	buckets[bucket] = "1"
+
+	return []any{
+		map[string]any{
+			"name":        "nazim.runs",
+			"description": "Runs of services, by outcome",
+			"unit":        "{run}",
+			"sum": map[string]any{
+				"aggregationTemporality": aggregationDelta,
+				"isMonotonic":            true,
+				"dataPoints": []any{map[string]any{
+					"attributes":        attrs,
+					"startTimeUnixNano": unixNano(run.Start),
+					"timeUnixNano":      unixNano(run.End),
+					"asInt":             "1",
+				}},
+			},
+		},
+		map[string]any{
+			"name":        "nazim.run.duration",
+			"description": "How long runs of services took",
+			"unit":        "s",
+			"histogram": map[string]any{
+				"aggregationTemporality": aggregationDelta,
+				"dataPoints": []any{map[string]any{
+					"attributes":        attrs,
+					"startTimeUnixNano": unixNano(run.Start),
+					"timeUnixNano":      unixNano(run.End),
+					"count":             "1",
+					"sum":               seconds,
+					"min":               seconds,
+					"max":               seconds,
+					"bucketCounts":      buckets,
+					"explicitBounds":    durationBounds,
+				}},
+			},
+		},
+	}
+}
+
+// post sends an OTLP/JSON request to the endpoint's path.
+func (e *Exporter) post(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	target := *e.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// unixNano returns t as OTLP/JSON times are written: nanoseconds since the
+// epoch, as a string.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// randomID returns n random bytes in hex, for trace and span IDs.
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,220 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		wantErr  bool
+	}{
+		{"http://localhost:4318", false},
+		{"https://otlp.example.com/otlp", false},
+		{"grpc://localhost:4317", true},
+		{"localhost:4318", true},
+		{"http://", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			if err := CheckEndpoint(tt.endpoint); (err != nil) != tt.wantErr {
+				t.Errorf("CheckEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testRun returns a run of backup that took d.
+func testRun(d time.Duration, outcome string) *Run {
+	start := time.Date(2026, 3, 4, 5, 0, 0, 0, time.UTC)
+	return &Run{
+		Service:    "backup",
+		ID:         "run1",
+		Command:    "/opt/backup.sh --full",
+		Trigger:    "every 1h",
+		Start:      start,
+		End:        start.Add(d),
+		ExitCode:   3,
+		Outcome:    outcome,
+		Error:      "exit code 3",
+		Attributes: map[string]string{"team": "infra", "owner": "ops"},
+	}
+}
+
+// attrs returns the values of OTLP attributes by key.
+func attrs(list []attribute) map[string]any {
+	m := make(map[string]any)
+	for _, a := range list {
+		for _, v := range a.Value {
+			m[a.Key] = v
+		}
+	}
+	return m
+}
+
+func TestSpan(t *testing.T) {
+	tests := []struct {
+		name       string
+		outcome    string
+		slow       bool
+		wantStatus map[string]any
+	}{
+		{"success", OutcomeSuccess, false, map[string]any{"code": statusOK}},
+		{"failure", OutcomeFailure, true, map[string]any{"code": statusError, "message": "exit code 3"}},
+		{"start failure", OutcomeStartFailure, false, map[string]any{"code": statusError, "message": "exit code 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := testRun(90*time.Second, tt.outcome)
+			run.Slow = tt.slow
+			span := (&Exporter{}).span(run)
+
+			if !reflect.DeepEqual(span["status"], tt.wantStatus) {
+				t.Errorf("status = %v, want %v", span["status"], tt.wantStatus)
+			}
+			if span["name"] != "run backup" {
+				t.Errorf("name = %v, want run backup", span["name"])
+			}
+			if span["startTimeUnixNano"] != "1772600400000000000" || span["endTimeUnixNano"] != "1772600490000000000" {
+				t.Errorf("times = %v to %v", span["startTimeUnixNano"], span["endTimeUnixNano"])
+			}
+			if id := span["traceId"].(string); len(id) != 32 {
+				t.Errorf("traceId = %q, want 16 bytes in hex", id)
+			}
+			if id := span["spanId"].(string); len(id) != 16 {
+				t.Errorf("spanId = %q, want 8 bytes in hex", id)
+			}
+
+			want := map[string]any{
+				"nazim.service":         "backup",
+				"owner":                 "ops",
+				"team":                  "infra",
+				"nazim.run.id":          "run1",
+				"process.command_line":  "/opt/backup.sh --full",
+				"process.exit.code":     "3",
+				"nazim.run.outcome":     tt.outcome,
+				"nazim.service.trigger": "every 1h",
+			}
+			if tt.slow {
+				want["nazim.run.slow"] = true
+			}
+			if got := attrs(span["attributes"].([]attribute)); !reflect.DeepEqual(got, want) {
+				t.Errorf("attributes = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMetricsOf(t *testing.T) {
+	tests := []struct {
+		duration   time.Duration
+		wantBucket int
+	}{
+		{500 * time.Millisecond, 0},
+		{time.Second, 0}, // Bounds are inclusive
+		{7 * time.Second, 2},
+		{20 * time.Minute, 7},
+		{2 * time.Hour, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.duration.String(), func(t *testing.T) {
+			metrics := metricsOf(testRun(tt.duration, OutcomeFailure))
+			if len(metrics) != 2 {
+				t.Fatalf("metricsOf() = %d metrics, want 2", len(metrics))
+			}
+
+			runs := metrics[0].(map[string]any)
+			sum := runs["sum"].(map[string]any)
+			point := sum["dataPoints"].([]any)[0].(map[string]any)
+			if runs["name"] != "nazim.runs" || point["asInt"] != "1" || sum["aggregationTemporality"] != aggregationDelta {
+				t.Errorf("nazim.runs = %v", runs)
+			}
+			if got := attrs(point["attributes"].([]attribute)); got["nazim.run.outcome"] != OutcomeFailure || got["nazim.service"] != "backup" {
+				t.Errorf("nazim.runs attributes = %v", got)
+			}
+
+			duration := metrics[1].(map[string]any)
+			point = duration["histogram"].(map[string]any)["dataPoints"].([]any)[0].(map[string]any)
+			if duration["name"] != "nazim.run.duration" || point["sum"] != tt.duration.Seconds() {
+				t.Errorf("nazim.run.duration = %v", duration)
+			}
+			buckets := point["bucketCounts"].([]string)
+			if len(buckets) != len(durationBounds)+1 {
+				t.Fatalf("%d buckets, want %d", len(buckets), len(durationBounds)+1)
+			}
+			for i, count := range buckets {
+				if want := map[bool]string{true: "1", false: "0"}[i == tt.wantBucket]; count != want {
+					t.Errorf("bucket %d = %s, want %s", i, count, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]map[string]any)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("%s headers = %v", r.URL.Path, r.Header)
+		}
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("%s body is not JSON: %v", r.URL.Path, err)
+		}
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	e, err := New(srv.URL+"/otlp/", map[string]string{"X-Api-Key": "secret"}, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Export(context.Background(), testRun(time.Minute, OutcomeSuccess)); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	for path, key := range map[string]string{"/otlp/v1/traces": "resourceSpans", "/otlp/v1/metrics": "resourceMetrics"} {
+		body, ok := bodies[path]
+		if !ok {
+			t.Errorf("nothing was sent to %s", path)
+			continue
+		}
+		resource := body[key].([]any)[0].(map[string]any)["resource"].(map[string]any)
+		var names []string
+		for _, a := range resource["attributes"].([]any) {
+			names = append(names, a.(map[string]any)["key"].(string))
+		}
+		if got := strings.Join(names, ","); !strings.HasPrefix(got, "service.name,os.type,service.version") {
+			t.Errorf("%s resource attributes = %s", path, got)
+		}
+	}
+}
+
+func TestExportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	e, err := New(srv.URL, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.Export(context.Background(), testRun(time.Minute, OutcomeSuccess))
+	if err == nil || !strings.Contains(err.Error(), "sending span: 429") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Export() error = %v, want the collector's", err)
+	}
+}
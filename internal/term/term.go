@@ -0,0 +1,103 @@
+// Package term detects terminals, colors text for them, and reads passphrases
+// from them.
+//
+// Colors follow the NO_COLOR convention (https://no-color.org): they are off
+// when NO_COLOR is set to any non-empty value or when output is redirected.
+package term
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Color is an ANSI text attribute, usually a foreground color.
+type Color string
+
+// Colors and styles used by nazim's output.
+const (
+	Bold    Color = "1"
+	Red     Color = "31"
+	Green   Color = "32"
+	Yellow  Color = "33"
+	Blue    Color = "34"
+	Magenta Color = "35"
+	Cyan    Color = "36"
+)
+
+// IsTerminal reports whether w is a character device such as a console.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorEnabled reports whether colored text can be written to w: it must be a
+// terminal that understands ANSI escapes, and NO_COLOR must not be set.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || !IsTerminal(w) {
+		return false
+	}
+	return enableVirtualTerminal(w.(*os.File))
+}
+
+// Width returns the number of columns available on w: the terminal's width,
+// or the COLUMNS environment variable when w isn't a terminal. It returns 0
+// when neither is known.
+func Width(w io.Writer) int {
+	if f, ok := w.(*os.File); ok && IsTerminal(w) {
+		if width := terminalWidth(f); width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+// Paint wraps s in the escapes for color.
+func Paint(color Color, s string) string {
+	return "\x1b[" + string(color) + "m" + s + "\x1b[0m"
+}
+
+// ErrNotTerminal is returned by ReadPassword when its input isn't a terminal.
+var ErrNotTerminal = errors.New("not a terminal")
+
+// ReadPassword shows prompt on out and reads a line from the terminal in
+// without echoing it, as for a passphrase, returning it without the line
+// ending.
+func ReadPassword(in *os.File, out io.Writer, prompt string) ([]byte, error) {
+	restore, err := disableEcho(in)
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+	defer restore()
+	fmt.Fprint(out, prompt)
+	defer fmt.Fprintln(out)
+
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bytes.TrimSuffix(line, []byte("\r")), nil
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package term
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// enableVirtualTerminal reports whether f understands ANSI escapes, which every
+// Unix terminal does.
+func enableVirtualTerminal(f *os.File) bool {
+	return true
+}
+
+// terminalWidth returns the number of columns of the terminal f, or 0.
+func terminalWidth(f *os.File) int {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Col)
+}
+
+// disableEcho turns off echo on the terminal f, returning how to turn it back on.
+func disableEcho(f *os.File) (func(), error) {
+	fd := int(f.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	saved := *termios
+	termios.Lflag &^= unix.ECHO
+	termios.Lflag |= unix.ICANON | unix.ISIG
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+	return func() { unix.IoctlSetTermios(fd, ioctlSetTermios, &saved) }, nil
+}
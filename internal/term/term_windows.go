@@ -0,0 +1,46 @@
+//go:build windows
+
+package term
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVirtualTerminal turns on ANSI escape processing for a Windows console,
+// reporting whether it is available (Windows 10 and later).
+func enableVirtualTerminal(f *os.File) bool {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
+
+// terminalWidth returns the number of columns of the console window f, or 0.
+func terminalWidth(f *os.File) int {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(f.Fd()), &info); err != nil {
+		return 0
+	}
+	return int(info.Window.Right-info.Window.Left) + 1
+}
+
+// disableEcho turns off echo on the console f, returning how to turn it back on.
+func disableEcho(f *os.File) (func(), error) {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
+	echoless := mode&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT
+	if err := windows.SetConsoleMode(handle, echoless); err != nil {
+		return nil, err
+	}
+	return func() { windows.SetConsoleMode(handle, mode) }, nil
+}
@@ -0,0 +1,179 @@
+// Package trace reports the external commands nazim runs, such as schtasks,
+// systemctl, and launchctl, for diagnosing platform issues.
+//
+// With an output set, each command is reported on one line with how long it
+// took and how it exited. With a file open, its full output is written there
+// as well. Values that look like secrets are redacted from both.
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mu   sync.Mutex
+	out  io.Writer // One line per command, or nil
+	file io.Writer // Commands with their output, or nil
+)
+
+// SetOutput sets where a line is written for each command; nil turns it off.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// OpenFile appends the full trace of every command to the file at path,
+// until the returned function is called.
+func OpenFile(path string) (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating trace directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening trace file: %w", err)
+	}
+
+	mu.Lock()
+	file = f
+	mu.Unlock()
+	return func() error {
+		mu.Lock()
+		file = nil
+		mu.Unlock()
+		return f.Close()
+	}, nil
+}
+
+// Run runs cmd like cmd.Run and traces it. When tracing to a file, output
+// that would be discarded is captured for it.
+func Run(cmd *exec.Cmd) error {
+	var output bytes.Buffer
+	if tracingFile() && cmd.Stdout == nil && cmd.Stderr == nil {
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+	}
+	start := time.Now()
+	err := cmd.Run()
+	record(cmd, time.Since(start), err, output.Bytes())
+	return err
+}
+
+// Output runs cmd like cmd.Output and traces it.
+func Output(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	output, err := cmd.Output()
+	traced := output
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		traced = append(append([]byte{}, output...), exitErr.Stderr...)
+	}
+	record(cmd, time.Since(start), err, traced)
+	return output, err
+}
+
+// CombinedOutput runs cmd like cmd.CombinedOutput and traces it.
+func CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	record(cmd, time.Since(start), err, output)
+	return output, err
+}
+
+func tracingFile() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return file != nil
+}
+
+// record reports a finished command.
+func record(cmd *exec.Cmd, took time.Duration, err error, output []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	if out == nil && file == nil {
+		return
+	}
+
+	status := "exit 0"
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		status = fmt.Sprintf("exit %d", exitErr.ExitCode())
+	case err != nil:
+		status = "failed: " + Redact(err.Error())
+	}
+	summary := fmt.Sprintf("(%s, %s)", took.Round(time.Millisecond), status)
+
+	if out != nil {
+		fmt.Fprintf(out, "[exec] %s %s\n", commandLine(cmd.Args, 120), summary)
+	}
+	if file != nil {
+		fmt.Fprintf(file, "=== %s %s %s\n", time.Now().Format(time.RFC3339), commandLine(cmd.Args, 0), summary)
+		if len(output) > 0 {
+			text := Redact(string(output))
+			fmt.Fprint(file, text)
+			if !strings.HasSuffix(text, "\n") {
+				fmt.Fprintln(file)
+			}
+		}
+	}
+}
+
+// commandLine formats args for display, redacted, quoting those with spaces and
+// shortening those longer than max when max isn't 0.
+func commandLine(args []string, max int) string {
+	args = redactArgs(args)
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		if max > 0 && len(arg) > max {
+			arg = arg[:max-3] + "..."
+		}
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			arg = strconv.Quote(arg)
+		}
+		parts[i] = arg
+	}
+	return strings.Join(parts, " ")
+}
+
+// secretFlags take a secret as their next argument, like the password of schtasks /rp.
+var secretFlags = map[string]bool{
+	"/rp": true, "/p": true, "-password": true, "--password": true, "-token": true, "--token": true,
+}
+
+// redactArgs returns args with secrets redacted.
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if i > 0 && secretFlags[strings.ToLower(args[i-1])] {
+			redacted[i] = "xxxxx"
+			continue
+		}
+		redacted[i] = Redact(arg)
+	}
+	return redacted
+}
+
+var (
+	// URL passwords, as in redis://:secret@host
+	urlPassword = regexp.MustCompile(`(\w+://[^/\s:@]*:)[^@\s]*@`)
+	// Assignments of secrets, as in ?token=... or PASSWORD=...
+	secretValue = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key)\w*\s*[=:]\s*)[^\s&'";]+`)
+)
+
+// Redact hides passwords in URLs and the values of settings named like secrets.
+func Redact(s string) string {
+	s = urlPassword.ReplaceAllString(s, "${1}xxxxx@")
+	return secretValue.ReplaceAllString(s, "${1}xxxxx")
+}
@@ -0,0 +1,92 @@
+// Package update finds out whether a newer release of nazim is out, from the
+// releases of its GitHub repository.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// latestURL answers with the newest release that isn't a draft or pre-release.
+const latestURL = "https://api.github.com/repos/calilkhalil/nazim/releases/latest"
+
+// Release is a published release of nazim.
+type Release struct {
+	Version string `json:"tag_name"` // e.g. v1.4.0
+	URL     string `json:"html_url"` // Its page, with release notes and binaries
+}
+
+// Latest returns the newest release, or nil if none has been published yet.
+func Latest(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying the latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("querying the latest release: %s", resp.Status)
+	}
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("querying the latest release: %w", err)
+	}
+	return &release, nil
+}
+
+// Newer reports whether version latest comes after current. ok is false when
+// current isn't a release version, such as a development build, so the two
+// can't be compared. Anything after the release numbers, such as the commits
+// git describe adds to builds between releases, is ignored.
+func Newer(latest, current string) (newer, ok bool) {
+	l, lok := parse(latest)
+	c, cok := parse(current)
+	if !lok || !cok {
+		return false, false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i], true
+		}
+	}
+	return false, true
+}
+
+// IsRelease reports whether version is a release version Newer can compare,
+// rather than a development build.
+func IsRelease(version string) bool {
+	_, ok := parse(version)
+	return ok
+}
+
+// parse reads the major, minor, and patch numbers of a version such as
+// v1.4.0, 1.4, or v1.4.0-3-gabc1234.
+func parse(version string) (numbers [3]int, ok bool) {
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "-")
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return numbers, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return numbers, false
+		}
+		numbers[i] = n
+	}
+	return numbers, true
+}
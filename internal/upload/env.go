@@ -0,0 +1,51 @@
+package upload
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Getenv returns a lookup of credentials in the environment, falling back to
+// the KEY=VALUE lines of envFile when it isn't empty. Blank lines and lines
+// starting with # are ignored, and values may be quoted.
+func Getenv(envFile string) (func(string) string, error) {
+	if envFile == "" {
+		return os.Getenv, nil
+	}
+
+	f, err := os.Open(envFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("reading credentials: %s:%d: expected KEY=VALUE", envFile, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+
+	return func(key string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return values[key]
+	}, nil
+}
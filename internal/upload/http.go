@@ -0,0 +1,44 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// httpBackend puts each object at the URL's path joined with its key, keeping
+// the URL's query, so a shared access signature applies to every object.
+// Azure Blob Storage is recognized by its host and sent the headers it needs.
+type httpBackend struct {
+	u     *url.URL
+	token string
+	azure bool
+}
+
+func newHTTPBackend(u *url.URL, getenv func(string) string) (*httpBackend, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("upload URL %q has no host", u.Redacted())
+	}
+	return &httpBackend{
+		u:     u,
+		token: getenv("NAZIM_UPLOAD_TOKEN"),
+		azure: strings.HasSuffix(u.Hostname(), ".blob.core.windows.net"),
+	}, nil
+}
+
+func (b *httpBackend) put(ctx context.Context, key string, data []byte) error {
+	target := *b.u
+	target.Path = "/" + joinKey(b.u.Path, key)
+	target.RawPath = escapePath(target.Path)
+	return send(ctx, target.String(), data, func(req *http.Request) {
+		if b.token != "" {
+			req.Header.Set("Authorization", "Bearer "+b.token)
+		}
+		if b.azure {
+			req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+			req.Header.Set("X-Ms-Version", "2021-08-06")
+		}
+	})
+}
@@ -0,0 +1,141 @@
+// Package upload copies the output of runs to remote storage, so it outlives
+// the machine that produced it and can be kept for audits.
+//
+// A destination is identified by a URL:
+//
+//	s3://bucket/prefix?region=eu-west-1     an Amazon S3 bucket; add endpoint=host for S3-compatible stores
+//	gs://bucket/prefix                      a Google Cloud Storage bucket, through its S3-compatible API
+//	https://host/path?query                 an HTTP PUT, such as an Azure Blob container SAS URL
+//
+// Apart from the signature of a shared access URL, which is kept out of errors,
+// credentials are never part of the URL. They are read from the environment:
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN for s3://,
+// GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET for gs://, and NAZIM_UPLOAD_TOKEN,
+// sent as a bearer token, for http(s)://.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// backend stores objects in one kind of storage.
+type backend interface {
+	put(ctx context.Context, key string, data []byte) error
+}
+
+// Target is a destination for uploads.
+type Target struct {
+	backend backend
+}
+
+// CheckURL reports whether rawURL names a supported destination.
+func CheckURL(rawURL string) error {
+	_, err := New(rawURL, func(string) string { return "" })
+	return err
+}
+
+// New returns the destination at rawURL, taking credentials from getenv when
+// uploading.
+func New(rawURL string, getenv func(string) string) (*Target, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload URL: %w", redactError(err))
+	}
+
+	var b backend
+	switch u.Scheme {
+	case "s3":
+		b, err = newS3Backend(u, getenv)
+	case "gs":
+		b, err = newGCSBackend(u, getenv)
+	case "http", "https":
+		b, err = newHTTPBackend(u, getenv)
+	case "":
+		return nil, fmt.Errorf("upload URL %q has no scheme; use s3, gs, or https", rawURL)
+	default:
+		return nil, fmt.Errorf("unsupported upload destination %q; use s3, gs, or https", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Target{backend: b}, nil
+}
+
+// Put stores data under key, a slash-separated path below the URL's prefix.
+func (t *Target) Put(ctx context.Context, key string, data []byte) error {
+	return t.backend.put(ctx, key, data)
+}
+
+// joinKey joins a URL path prefix and a key with single slashes.
+func joinKey(prefix, key string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
+
+// escapePath percent-encodes each segment of a slash-separated path, leaving
+// only unreserved characters as they are, as S3 request signing expects.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/', c == '-', c == '_', c == '.', c == '~',
+			'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// send performs a PUT of data to rawURL, with the headers set by prepare.
+func send(ctx context.Context, rawURL string, data []byte, prepare func(*http.Request)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return redactError(err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if prepare != nil {
+		prepare(req)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return redactError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// redactError removes the query and password from the URL in a *url.Error,
+// since the query of a shared access signature URL is its credential, and
+// errors end up in the terminal and the journal.
+func redactError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	redacted := *urlErr
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		u.RawQuery, u.ForceQuery = "", false
+		redacted.URL = u.Redacted()
+	} else {
+		redacted.URL = "upload URL"
+	}
+	return &redacted
+}
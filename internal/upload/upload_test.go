@@ -0,0 +1,34 @@
+package upload
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorsLeaveOutSignature(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	addr := srv.URL
+	srv.Close() // Uploads fail to connect
+
+	target, err := New(addr+"/container?sv=2021-08-06&sig=s3cret", func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = target.Put(context.Background(), "host/job/run.log", []byte("output"))
+	if err == nil {
+		t.Fatal("Put() to a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Put() error = %v, which holds the signature", err)
+	}
+	if !strings.Contains(err.Error(), "/container/host/job/run.log") {
+		t.Errorf("Put() error = %v, want it to name the object", err)
+	}
+
+	if _, err := New("https://host/%zz?sig=s3cret", nil); err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("New() of an invalid URL error = %v, want one without the signature", err)
+	}
+}
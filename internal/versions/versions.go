@@ -0,0 +1,207 @@
+// Package versions keeps the versions of each service nazim installed: the
+// service as it was configured, and the scheduler definitions generated from
+// it, such as unit files, a plist, or a wrapper script, so that a bad edit can
+// be rolled back.
+//
+// A version is recorded whenever a service is installed with settings or
+// definitions that differ from its latest one, and numbered from 1 on; the
+// last MaxVersions are kept. Like state, versions live in the state
+// directory, one JSON file per service, readable only by its owner. Services
+// hold the same secrets as the config file, so when it is encrypted (see
+// package seal), so are their versions, with its key.
+package versions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/atomicfile"
+	"github.com/calilkhalil/nazim/internal/seal"
+	"github.com/calilkhalil/nazim/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxVersions is how many versions of a service are kept.
+const MaxVersions = 10
+
+// Version is a service as it was installed.
+type Version struct {
+	Number    int       `json:"number"`
+	Installed time.Time `json:"installed"`
+	Config    string    `json:"config"`          // The service, in the layout of the config file
+	Files     []File    `json:"files,omitempty"` // The scheduler definitions generated for it
+}
+
+// File is a scheduler definition generated for a service.
+type File struct {
+	Name    string `json:"name"` // File path, or a description when nothing was written to disk
+	Content string `json:"content"`
+}
+
+// String returns the name of the version, such as v3.
+func (v *Version) String() string {
+	return "v" + strconv.Itoa(v.Number)
+}
+
+// Service returns the service of the version.
+func (v *Version) Service() (*service.Service, error) {
+	svc := &service.Service{}
+	if err := yaml.Unmarshal([]byte(v.Config), svc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", v, err)
+	}
+	return svc, nil
+}
+
+// ParseNumber parses the name of a version, such as v3 or 3.
+func ParseNumber(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "v"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid version %q, use a version such as v3", s)
+	}
+	return n, nil
+}
+
+// Path returns the versions file of a service.
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+".json")
+}
+
+// Load returns the kept versions of a service, oldest first; none if it has
+// none yet. Encrypted versions are decrypted with key, the key of the config
+// file, or as seal.Open does if they were encrypted with another one.
+func Load(dir, name string, key *seal.Key) ([]*Version, error) {
+	data, err := os.ReadFile(Path(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading versions: %w", err)
+	}
+	if seal.IsSealed(data) {
+		if data, err = seal.OpenWith(data, key); err != nil {
+			return nil, fmt.Errorf("decrypting versions %s: %w", Path(dir, name), err)
+		}
+	}
+	var kept []*Version
+	if err := json.Unmarshal(data, &kept); err != nil {
+		return nil, fmt.Errorf("parsing versions %s: %w", Path(dir, name), err)
+	}
+	return kept, nil
+}
+
+// Find returns version number of kept, or nil if it isn't kept.
+func Find(kept []*Version, number int) *Version {
+	for _, v := range kept {
+		if v.Number == number {
+			return v
+		}
+	}
+	return nil
+}
+
+// Record records svc, installed at the given time with files, as the latest
+// version of the service and returns it. If the latest version already has
+// the same settings and files, it is returned instead. The versions are
+// encrypted with key, the key of the config file, unless it is nil.
+func Record(dir string, svc *service.Service, files []File, installed time.Time, key *seal.Key) (*Version, error) {
+	config, err := yaml.Marshal(svc)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling service: %w", err)
+	}
+	kept, err := Load(dir, svc.Name, key)
+	if err != nil {
+		return nil, err
+	}
+	v := &Version{Number: 1, Installed: installed, Config: string(config), Files: files}
+	if len(kept) > 0 {
+		latest := kept[len(kept)-1]
+		if latest.Config == v.Config && sameFiles(latest.Files, v.Files) {
+			return latest, nil
+		}
+		v.Number = latest.Number + 1
+	}
+	kept = append(kept, v)
+	if len(kept) > MaxVersions {
+		kept = kept[len(kept)-MaxVersions:]
+	}
+	return v, save(dir, svc.Name, kept, key)
+}
+
+// save writes the versions of a service, encrypted with key unless it is nil,
+// replacing the file in one step.
+func save(dir, name string, kept []*Version, key *seal.Key) error {
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling versions: %w", err)
+	}
+	data = append(data, '\n')
+	if key != nil {
+		if data, err = seal.Seal(data, key); err != nil {
+			return fmt.Errorf("encrypting versions: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating versions directory: %w", err)
+	}
+	if err := atomicfile.Write(Path(dir, name), data, 0600); err != nil {
+		return fmt.Errorf("writing versions: %w", err)
+	}
+	return nil
+}
+
+// Reseal rewrites the versions of every service in dir, decrypting them with
+// from and encrypting them with to, for when the config file is encrypted with
+// another key or decrypted; either key may be nil for plain text.
+func Reseal(dir string, from, to *seal.Key) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		kept, err := Load(dir, name, from)
+		if err != nil {
+			return err
+		}
+		if err := save(dir, name, kept, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove deletes the versions of a service, and the definitions pinned from
+// them, if it has any.
+func Remove(dir, name string) error {
+	if err := os.Remove(Path(dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing versions: %w", err)
+	}
+	if err := os.RemoveAll(PinDir(dir, name)); err != nil {
+		return fmt.Errorf("removing pinned definitions: %w", err)
+	}
+	return nil
+}
+
+// PinDir returns the directory the definitions of a service's versions are
+// written to when it is pinned to one, to be installed as they are.
+func PinDir(dir, name string) string {
+	return filepath.Join(dir, name)
+}
+
+// sameFiles reports whether a and b are the same files.
+func sameFiles(a, b []File) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
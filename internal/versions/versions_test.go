@@ -0,0 +1,85 @@
+package versions
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/seal"
+	"github.com/calilkhalil/nazim/internal/service"
+)
+
+func TestRecordSealed(t *testing.T) {
+	t.Setenv("NAZIM_TEST_PASSPHRASE", "correct horse")
+	key, err := seal.NewPassphraseKey("env:NAZIM_TEST_PASSPHRASE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	svc := &service.Service{Name: "backup", Command: "/opt/backup.sh", Env: map[string]string{"TOKEN": "s3cret"}}
+	files := []File{{Name: "backup.service", Content: "Environment=TOKEN=s3cret\n"}}
+
+	if _, err := Record(dir, svc, files, time.Now(), key); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	data, err := os.ReadFile(Path(dir, "backup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") || !seal.IsSealed(data) {
+		t.Errorf("versions of a sealed config are stored in plain text:\n%s", data)
+	}
+
+	kept, err := Load(dir, "backup", key)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(kept) != 1 {
+		t.Fatalf("Load() = %d versions, want 1", len(kept))
+	}
+	got, err := kept[0].Service()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Env["TOKEN"] != "s3cret" {
+		t.Errorf("Service().Env = %v, want the recorded one", got.Env)
+	}
+
+	// Decrypting the config writes them back in plain text
+	if err := Reseal(dir, key, nil); err != nil {
+		t.Fatalf("Reseal() error = %v", err)
+	}
+	data, err = os.ReadFile(Path(dir, "backup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seal.IsSealed(data) || !strings.Contains(string(data), "s3cret") {
+		t.Errorf("Reseal() to plain text left:\n%s", data)
+	}
+}
+
+func TestRecordPrivate(t *testing.T) {
+	dir := t.TempDir()
+	svc := &service.Service{Name: "backup", Command: "/opt/backup.sh"}
+	for i := range 2 {
+		svc.Args = []string{strings.Repeat("x", i)}
+		if _, err := Record(dir, svc, nil, time.Now(), nil); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	info, err := os.Stat(Path(dir, "backup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 && os.PathSeparator == '/' {
+		t.Errorf("versions file mode = %v, want it readable only by its owner", perm)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("versions directory holds %d files, want only the versions file", len(entries))
+	}
+}
@@ -0,0 +1,183 @@
+// Package watch tells whether the files a service watches with if_changed
+// changed since its last successful run.
+//
+// Each service has a snapshot of its watched files, one JSON file per service
+// in the state directory, holding the size, modification time, and SHA-256 of
+// each file. Files whose size and modification time are unchanged keep their
+// recorded checksum instead of being read again, and a file that was only
+// touched still counts as unchanged, since its checksum is the same.
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/calilkhalil/nazim/internal/atomicfile"
+	"github.com/calilkhalil/nazim/internal/integrity"
+)
+
+// File is what a snapshot records of a watched file.
+type File struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Sum     string    `json:"sha256"`
+}
+
+// Snapshot holds the watched files of a service by path.
+type Snapshot map[string]File
+
+// Path returns the snapshot file of a service.
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+".json")
+}
+
+// Load returns the snapshot of a service, or nil if it has none yet.
+func Load(dir, name string) (Snapshot, error) {
+	data, err := os.ReadFile(Path(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	s := Snapshot{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", Path(dir, name), err)
+	}
+	return s, nil
+}
+
+// Save writes the snapshot of a service, replacing the file in one step.
+func (s Snapshot) Save(dir, name string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating snapshots directory: %w", err)
+	}
+	if err := atomicfile.Write(Path(dir, name), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the snapshot of a service, if it has one.
+func Remove(dir, name string) error {
+	if err := os.Remove(Path(dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing snapshot: %w", err)
+	}
+	return nil
+}
+
+// Take returns a snapshot of the files matching patterns, which are paths or
+// globs as filepath.Match reads them. A matching directory stands for every
+// file below it. Checksums are reused from previous, which may be nil, for
+// files whose size and modification time haven't changed.
+func Take(patterns []string, previous Snapshot) (Snapshot, error) {
+	s := Snapshot{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					// Files can come and go while the tree is walked
+					if os.IsNotExist(err) {
+						return nil
+					}
+					return err
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				info, err := d.Info()
+				if os.IsNotExist(err) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				file := File{Size: info.Size(), ModTime: info.ModTime().UTC()}
+				if old, ok := previous[path]; ok && old.Size == file.Size && old.ModTime.Equal(file.ModTime) {
+					file.Sum = old.Sum
+				} else if file.Sum, err = integrity.Sum(path); os.IsNotExist(err) {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				s[path] = file
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("reading watched files: %w", err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Changed reports whether any file was added, removed, or modified since
+// previous. Without a previous snapshot, everything counts as changed.
+func (s Snapshot) Changed(previous Snapshot) bool {
+	if previous == nil || len(s) != len(previous) {
+		return true
+	}
+	for path, file := range s {
+		if old, ok := previous[path]; !ok || old.Sum != file.Sum {
+			return true
+		}
+	}
+	return false
+}
+
+// Refreshed reports whether s records the same files as previous but with
+// different sizes or modification times, so saving it saves reading them again.
+func (s Snapshot) Refreshed(previous Snapshot) bool {
+	for path, file := range s {
+		if old := previous[path]; old.Size != file.Size || !old.ModTime.Equal(file.ModTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPattern returns an error if pattern isn't a valid path or glob.
+func CheckPattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("if_changed patterns cannot be empty")
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid if_changed pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// Roots returns the paths a file watcher should watch for patterns: a path
+// without glob characters as is, and for a glob, the directory above its
+// first glob character. Watchers such as systemd path units only see changes
+// directly in a directory, not below it, so these are a hint to run early;
+// Take is what decides whether anything changed.
+func Roots(patterns []string) []string {
+	var roots []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		root := pattern
+		if i := strings.IndexAny(pattern, `*?[`); i >= 0 {
+			root = filepath.Dir(pattern[:i+1])
+		}
+		root = filepath.Clean(root)
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
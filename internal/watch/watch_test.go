@@ -0,0 +1,210 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var (
+	epoch = time.Date(2026, 3, 4, 5, 0, 0, 0, time.UTC)
+	fileA = File{Size: 1, ModTime: epoch, Sum: "a"}
+	fileB = File{Size: 2, ModTime: epoch, Sum: "b"}
+)
+
+func TestChanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  Snapshot
+		previous Snapshot
+		want     bool
+	}{
+		{"no previous snapshot", Snapshot{"a": fileA}, nil, true},
+		{"nothing watched yet", Snapshot{}, nil, true},
+		{"same", Snapshot{"a": fileA, "b": fileB}, Snapshot{"a": fileA, "b": fileB}, false},
+		{"empty both times", Snapshot{}, Snapshot{}, false},
+		{"added", Snapshot{"a": fileA, "b": fileB}, Snapshot{"a": fileA}, true},
+		{"removed", Snapshot{"a": fileA}, Snapshot{"a": fileA, "b": fileB}, true},
+		{"renamed", Snapshot{"c": fileA}, Snapshot{"a": fileA}, true},
+		{"modified", Snapshot{"a": {Size: 1, ModTime: epoch, Sum: "x"}}, Snapshot{"a": fileA}, true},
+		{"touched", Snapshot{"a": {Size: 1, ModTime: epoch.Add(time.Hour), Sum: "a"}}, Snapshot{"a": fileA}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.current.Changed(tt.previous); got != tt.want {
+				t.Errorf("Changed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshed(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  Snapshot
+		previous Snapshot
+		want     bool
+	}{
+		{"same", Snapshot{"a": fileA}, Snapshot{"a": fileA}, false},
+		{"touched", Snapshot{"a": {Size: 1, ModTime: epoch.Add(time.Hour), Sum: "a"}}, Snapshot{"a": fileA}, true},
+		{"new file", Snapshot{"a": fileA, "b": fileB}, Snapshot{"a": fileA}, true},
+		{"removed file", Snapshot{}, Snapshot{"a": fileA}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.current.Refreshed(tt.previous); got != tt.want {
+				t.Errorf("Refreshed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTake(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"docs/index.md":       "# Index",
+		"docs/guide/setup.md": "# Setup",
+		"src/main.go":         "package main",
+		"src/notes.txt":       "notes",
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{"directory", []string{"docs"}, []string{"docs/guide/setup.md", "docs/index.md"}},
+		{"glob", []string{"src/*.go"}, []string{"src/main.go"}},
+		{"file", []string{"src/notes.txt"}, []string{"src/notes.txt"}},
+		{"several", []string{"src/*.go", "docs/index.md"}, []string{"docs/index.md", "src/main.go"}},
+		{"missing", []string{"build", "*.none"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patterns []string
+			for _, p := range tt.patterns {
+				patterns = append(patterns, filepath.Join(dir, p))
+			}
+			s, err := Take(patterns, nil)
+			if err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+			var got []string
+			for path, file := range s {
+				rel, _ := filepath.Rel(dir, path)
+				got = append(got, filepath.ToSlash(rel))
+				if file.Sum == "" || file.Size != int64(len(files[filepath.ToSlash(rel)])) {
+					t.Errorf("%s = %+v", rel, file)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Take() files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Take([]string{filepath.Join(dir, "[")}, nil); err == nil {
+		t.Errorf("Take() with an invalid pattern succeeded")
+	}
+}
+
+func TestTakeReusesSums(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := Take([]string{path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file with the same size and modification time isn't read again
+	recorded := Snapshot{path: {Size: first[path].Size, ModTime: first[path].ModTime, Sum: "recorded"}}
+	second, err := Take([]string{path}, recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second[path].Sum != "recorded" {
+		t.Errorf("Sum = %q, want the recorded one", second[path].Sum)
+	}
+
+	// Touched with the same contents, it doesn't count as changed
+	later := first[path].ModTime.Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	touched, err := Take([]string{path}, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if touched.Changed(first) || !touched.Refreshed(first) {
+		t.Errorf("touched file: Changed() = %v, Refreshed() = %v, want false and true", touched.Changed(first), touched.Refreshed(first))
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	if s, err := Load(dir, "docs"); err != nil || s != nil {
+		t.Fatalf("Load() without a snapshot = %v, %v, want nil", s, err)
+	}
+
+	saved := Snapshot{"/src/a": fileA, "/src/b": fileB}
+	if err := saved.Save(dir, "docs"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(dir, "docs")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, saved) {
+		t.Errorf("Load() = %v, want %v", loaded, saved)
+	}
+
+	if err := Remove(dir, "docs"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := Remove(dir, "docs"); err != nil {
+		t.Errorf("Remove() of a removed snapshot error = %v", err)
+	}
+}
+
+func TestCheckPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"docs", false},
+		{"src/**/*.go", false},
+		{" ", true},
+		{"src/[a-", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if err := CheckPattern(tt.pattern); (err != nil) != tt.wantErr {
+				t.Errorf("CheckPattern() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoots(t *testing.T) {
+	patterns := []string{"/srv/docs", "/srv/src/*.go", "/srv/src/*.md", "/srv/a?/b", "/srv/docs/"}
+	want := []string{filepath.Clean("/srv/docs"), filepath.Clean("/srv/src"), filepath.Clean("/srv")}
+	if got := Roots(patterns); !reflect.DeepEqual(got, want) {
+		t.Errorf("Roots() = %q, want %q", got, want)
+	}
+}